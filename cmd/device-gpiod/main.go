@@ -9,7 +9,6 @@
 package main
 
 import (
-	"encoding/json"
 	"flag"
 	"log"
 	"os"
@@ -33,6 +32,20 @@ var (
 
 func main() {
 
+	// device-gpiod init writes a first configuration instead of starting the service
+	if len(os.Args) > 1 && os.Args[1] == "init" {
+		os.Exit(driver.RunInit(os.Args[2:]))
+	}
+	// device-gpiod validate-config checks the configuration file and prints the lint warnings
+	if len(os.Args) > 1 && os.Args[1] == "validate-config" {
+		os.Exit(driver.RunValidateConfig(os.Args[2:]))
+	}
+
+	// The fleet manifest may set any of the env vars below
+	if err := driver.ApplyFleetManifest(); err != nil {
+		log.Printf("Error applying fleet manifest. Error: %s", err)
+	}
+
 	// Get env vars
 	*verbose, err = strconv.ParseBool(os.Getenv("VERBOSE"))
 	if err != nil {
@@ -46,16 +59,7 @@ func main() {
 
 	err = sd.GpioList.Parse(os.Getenv("GPIO_CONFIG_FILE"), *verbose)
 	if err != nil {
-		log.Printf("Error parsing GPIO configuration. Error: %s", err)
-	}
-
-	if *verbose {
-		prettyprint, err := json.MarshalIndent(&sd.GpioList, "", "\t")
-		if err != nil {
-			log.Printf("Failed to pretty print modbus configration file. Error: %s", err)
-			prettyprint = []byte("ERROR")
-		}
-		log.Printf("Pretty print MODBUS configuration file:\n%s", string(prettyprint))
+		log.Fatalf("Error parsing GPIO configuration. Error: %s", err)
 	}
 
 	startup.Bootstrap(serviceName, device.Version, &sd)
@@ -18,30 +18,101 @@ package config
 
 import (
 	"errors"
+	"fmt"
+	"strings"
 )
 
-// This file contains example of custom configuration that can be loaded from the service's configuration.toml
-// and/or the Configuration Provider, aka Consul (if enabled).
+// This file contains the custom configuration loaded from the service's configuration.toml and/or the
+// Configuration Provider, aka Consul (if enabled).
 // For more details see https://docs.edgexfoundry.org/2.0/microservices/device/Ch-DeviceServices/#custom-configuration
 
-// Example structured custom configuration types. Must be wrapped with an outer struct with
-// single element that matches the top level custom configuration element in your configuration.toml file,
-// 'SimpleCustom' in this example.
+// ServiceConfig wraps the custom configuration with a single element matching its top level element
+// in configuration.toml, 'GpiodCustom'.
 type ServiceConfig struct {
-	SimpleCustom SimpleCustomConfig
+	GpiodCustom GpiodCustomConfig
 }
 
-// SimpleCustomConfig is example of service's custom structured configuration that is specified in the service's
-// configuration.toml file and Configuration Provider (aka Consul), if enabled.
-type SimpleCustomConfig struct {
-	OffImageLocation string
-	OnImageLocation  string
-	Writable         SimpleWritable
+// GpiodCustomConfig is the custom configuration of the service. Its settings are all writable, applied
+// at runtime when they change in the Configuration Provider.
+type GpiodCustomConfig struct {
+	Writable GpiodWritable
 }
 
-// SimpleWritable defines the service's custom configuration writable section, i.e. can be updated from Consul
-type SimpleWritable struct {
+// Triggers names the lines driven by the pipeline. An empty one falls back to its environment variable.
+type Triggers struct {
+	Start          string // START_TRIGGER
+	Standby        string // STANDBY_TRIGGER
+	Reverse        string // REVERSE_TRIGGER
+	Clean          string // CLEAN_TRIGGER
+	OpenValve      string // OPEN_VALVE
+	SwitchingValve string // SWITCHING_VALVE
+}
+
+// GpiodWritable defines the service's custom configuration writable section, i.e. can be updated from Consul.
+// The environment variables of the settings are only a fallback, used while a setting is left out.
+type GpiodWritable struct {
 	DiscoverSleepDurationSecs int64
+	// ActiveProfile selects one of the profiles of the GPIO configuration file, "default" when empty
+	ActiveProfile string
+	// Cycle timers in seconds; 0 falls back to PUMP_TIMEOUT, COMMAND_GAP, CLEAN_TIMEOUT, REVERSE_TIMEOUT
+	// and GRAVITY_TIMEOUT
+	PumpTimeoutSecs    int64
+	CommandGapSecs     int64
+	CleanTimeoutSecs   int64
+	ReverseTimeoutSecs int64
+	GravityTimeoutSecs int64
+	// Feature flags; left out, they fall back to ENABLE_CLEAN and ENABLE_REVERSE
+	EnableClean   *bool
+	EnableReverse *bool
+	// Triggers maps the lines of the pipeline, a new pump taking over from the next cycle
+	Triggers Triggers
+	// ModbusEndpoint is the Modbus device the pipeline depends on, MODBUS_DEVICE_ENDPOINT when empty
+	ModbusEndpoint string
+	// LogLevels sets the log level of modules, e.g. "gpio=debug,bridges=warn"; "all" names every module.
+	// Empty keeps LOG_LEVELS, or debug everywhere with VERBOSE.
+	LogLevels string
+}
+
+// LogModules are the modules with their own log level.
+var LogModules = []string{"gpio", "statemachine", "indicators", "connectivity", "bridges"}
+
+// LogLevelNames are the log levels, from the most verbose.
+var LogLevelNames = []string{"debug", "info", "warn", "error"}
+
+// ParseLogLevels parses a comma separated list of module=level pairs into the level of each module,
+// as an index of LogLevelNames.
+func ParseLogLevels(value string) (map[string]int, error) {
+	levels := make(map[string]int)
+	for _, pair := range strings.Split(value, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		module, name, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		level := indexOf(LogLevelNames, name)
+		if !ok || level < 0 {
+			return nil, fmt.Errorf("invalid log level %q, expected module=%s", pair, strings.Join(LogLevelNames, "|"))
+		}
+		if module == "all" {
+			for _, m := range LogModules {
+				levels[m] = level
+			}
+			continue
+		}
+		if indexOf(LogModules, module) < 0 {
+			return nil, fmt.Errorf("unknown log module %q, expected one of %s", module, strings.Join(LogModules, ", "))
+		}
+		levels[module] = level
+	}
+	return levels, nil
+}
+
+func indexOf(values []string, value string) int {
+	for i, v := range values {
+		if v == value {
+			return i
+		}
+	}
+	return -1
 }
 
 // UpdateFromRaw updates the service's full configuration from raw data received from
@@ -57,20 +128,31 @@ func (sw *ServiceConfig) UpdateFromRaw(rawConfig interface{}) bool {
 	return true
 }
 
-// Validate ensures your custom configuration has proper values.
-// Example of validating the sample custom configuration
-func (scc *SimpleCustomConfig) Validate() error {
-	if len(scc.OnImageLocation) == 0 {
-		return errors.New("SimpleCustom.OnImageLocation configuration setting can not be blank")
+// Validate ensures the custom configuration has proper values.
+func (scc *GpiodCustomConfig) Validate() error {
+	if scc.Writable.DiscoverSleepDurationSecs < 10 {
+		return errors.New("GpiodCustom.Writable.DiscoverSleepDurationSecs configuration setting must be 10 or greater")
 	}
 
-	if len(scc.OffImageLocation) == 0 {
-		return errors.New("SimpleCustom.OffImageLocation configuration setting can not be blank")
-	}
+	return scc.Writable.Validate()
+}
 
-	if scc.Writable.DiscoverSleepDurationSecs < 10 {
-		return errors.New("SimpleCustom.Writable.DiscoverSleepDurationSecs configuration setting must be 10 or greater")
+// Validate checks the cycle timers and the log levels; the bounds of the timers and the trigger lines
+// are checked when applied.
+func (sw *GpiodWritable) Validate() error {
+	for name, value := range map[string]int64{
+		"PumpTimeoutSecs":    sw.PumpTimeoutSecs,
+		"CommandGapSecs":     sw.CommandGapSecs,
+		"CleanTimeoutSecs":   sw.CleanTimeoutSecs,
+		"ReverseTimeoutSecs": sw.ReverseTimeoutSecs,
+		"GravityTimeoutSecs": sw.GravityTimeoutSecs,
+	} {
+		if value < 0 {
+			return fmt.Errorf("GpiodCustom.Writable.%s configuration setting must not be negative", name)
+		}
+	}
+	if _, err := ParseLogLevels(sw.LogLevels); err != nil {
+		return fmt.Errorf("GpiodCustom.Writable.LogLevels configuration setting: %s", err)
 	}
-
 	return nil
 }
@@ -0,0 +1,126 @@
+package driver
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	sdkModels "github.com/edgexfoundry/device-sdk-go/v2/pkg/models"
+)
+
+const (
+	abPolicyAlternate = "alternate"
+	abPolicySchedule  = "schedule"
+	abSetTag          = "abSet"
+)
+
+// ABTest alternates the cycle parameters between two profiles, every other cycle or every period
+// with the schedule policy, tagging the readings with the set in use so that process engineers can
+// compare them without reconfiguring the service by hand.
+type ABTest struct {
+	A      string `yaml:"a"`
+	B      string `yaml:"b"`
+	Policy string `yaml:"policy"`
+	Period string `yaml:"period"`
+	period time.Duration
+}
+
+var (
+	abMutex  = sync.Mutex{}
+	abCycles = 0
+	abSet    = ""
+	abEpoch  = time.Now()
+)
+
+// validateABTest checks the ab_test section of the configuration file.
+func validateABTest() error {
+	t := driverConfig.ABTest
+	if t == nil {
+		return nil
+	}
+	for _, name := range []string{t.A, t.B} {
+		if _, ok := driverConfig.Profiles[name]; !ok && name != defaultProfile {
+			return fmt.Errorf("unknown profile %q", name)
+		}
+	}
+	if t.A == t.B {
+		return fmt.Errorf("a and b must be different profiles")
+	}
+	switch t.Policy {
+	case "":
+		t.Policy = abPolicyAlternate
+	case abPolicyAlternate:
+	case abPolicySchedule:
+		period, err := time.ParseDuration(t.Period)
+		if err != nil || period <= 0 {
+			return fmt.Errorf("invalid period %q", t.Period)
+		}
+		t.period = period
+	default:
+		return fmt.Errorf("unknown policy %q", t.Policy)
+	}
+	return nil
+}
+
+// nextABSet activates the parameter set of the cycle about to start.
+func (s *SimpleDriver) nextABSet() {
+	t := driverConfig.ABTest
+	if t == nil {
+		return
+	}
+	abMutex.Lock()
+	set := "A"
+	switch t.Policy {
+	case abPolicyAlternate:
+		if abCycles%2 == 1 {
+			set = "B"
+		}
+	case abPolicySchedule:
+		if int64(time.Since(abEpoch)/t.period)%2 == 1 {
+			set = "B"
+		}
+	}
+	abCycles++
+	changed := set != abSet
+	abSet = set
+	abMutex.Unlock()
+	if !changed {
+		return
+	}
+
+	profile := t.A
+	if set == "B" {
+		profile = t.B
+	}
+	if err := s.activateProfile(profile, "ab-test"); err != nil {
+		log.Printf("Cannot activate A/B test set %s. Error: %s", set, err)
+	}
+}
+
+// currentABSet returns the set of the A/B test in use, empty when no test is configured.
+func currentABSet() string {
+	if driverConfig.ABTest == nil {
+		return ""
+	}
+	abMutex.Lock()
+	defer abMutex.Unlock()
+	return abSet
+}
+
+// tagABSet tags the readings with the set of the A/B test in use.
+func tagABSet(values []*sdkModels.CommandValue) {
+	set := currentABSet()
+	if set == "" {
+		return
+	}
+	for _, cv := range values {
+		if cv == nil {
+			continue
+		}
+		if cv.Tags == nil {
+			cv.Tags = make(map[string]string)
+		}
+		cv.Tags[abSetTag] = set
+	}
+}
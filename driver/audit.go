@@ -0,0 +1,82 @@
+package driver
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+var maxAuditEntries = defaultAuditEntries
+
+type AuditEntry struct {
+	Timestamp int64  `json:"timestamp"`
+	Action    string `json:"action"`
+	Resource  string `json:"resource"`
+	Detail    string `json:"detail"`
+}
+
+var (
+	auditMutex   = sync.Mutex{}
+	auditEntries []AuditEntry
+)
+
+// audit records an operator relevant action. Entries are kept in memory for the status routes,
+// hash chained, appended as JSON lines to AUDIT_LOG_FILE when set and published as Audit readings
+// with AUDIT_READINGS. The file is only ever appended to; it can be made append-only for root as well
+// with chattr +a.
+func audit(action string, resource string, detail string) {
+	entry := AuditEntry{
+		Timestamp: time.Now().UnixNano(),
+		Action:    action,
+		Resource:  resource,
+		Detail:    detail,
+	}
+	log.Printf("AUDIT %s %s: %s", action, resource, detail)
+	queueSyslog(entry)
+
+	auditMutex.Lock()
+	defer auditMutex.Unlock()
+
+	chained, err := chainAuditEntry(entry)
+	if err != nil {
+		log.Printf("Cannot chain audit entry. Error: %s", err)
+		return
+	}
+	queueAuditReading(chained)
+
+	auditEntries = append(auditEntries, entry)
+	if len(auditEntries) > maxAuditEntries {
+		countEvictions("audit", len(auditEntries)-maxAuditEntries)
+		auditEntries = auditEntries[len(auditEntries)-maxAuditEntries:]
+	}
+
+	fileName := os.Getenv("AUDIT_LOG_FILE")
+	if fileName == "" {
+		return
+	}
+	data, err := json.Marshal(chained)
+	if err != nil {
+		log.Printf("Cannot marshal audit entry. Error: %s", err)
+		return
+	}
+	f, err := os.OpenFile(fileName, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		log.Printf("Cannot open audit log %s. Error: %s", fileName, err)
+		return
+	}
+	defer f.Close()
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		log.Printf("Cannot write audit log %s. Error: %s", fileName, err)
+	}
+}
+
+// recentAudit returns a copy of the audit entries kept in memory.
+func recentAudit() []AuditEntry {
+	auditMutex.Lock()
+	defer auditMutex.Unlock()
+	entries := make([]AuditEntry, len(auditEntries))
+	copy(entries, auditEntries)
+	return entries
+}
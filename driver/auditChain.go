@@ -0,0 +1,242 @@
+package driver
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/common"
+
+	sdkModels "github.com/edgexfoundry/device-sdk-go/v2/pkg/models"
+)
+
+const (
+	auditCheckpointResource = "AuditCheckpoint"
+	auditVerifyRoute        = common.ApiBase + "/audit/verify"
+
+	systemEventTypeAudit        = "audit"
+	systemEventActionCheckpoint = "checkpoint"
+
+	DEFAULT_AUDIT_CHECKPOINT = time.Duration(1) * time.Hour
+	// auditTail is the end of the audit log read for its last entry
+	auditTail = 64 * 1024
+)
+
+// ChainedAuditEntry is an audit entry as written to AUDIT_LOG_FILE and published as an Audit
+// reading. Seq numbers the entries and Hash is the SHA-256 of the entry with an empty Hash, Prev
+// being the hash of the previous entry, so altering, removing or reordering entries breaks the chain.
+// The chain goes on across restarts from the last entry of the log; an empty Prev starts a new one.
+type ChainedAuditEntry struct {
+	AuditEntry
+	Seq  uint64 `json:"seq"`
+	Prev string `json:"prev"`
+	Hash string `json:"hash"`
+}
+
+// AuditCheckpoint is the head of the audit chain, published every AUDIT_CHECKPOINT_INTERVAL so the
+// log kept on the gateway can later be checked against copies held elsewhere.
+type AuditCheckpoint struct {
+	Seq       uint64 `json:"seq"`
+	Hash      string `json:"hash"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+// AuditVerification is the result of the verification of the audit log.
+type AuditVerification struct {
+	File      string `json:"file"`
+	Entries   int    `json:"entries"`
+	Unchained int    `json:"unchained"`
+	Restarts  int    `json:"restarts"`
+	LastSeq   uint64 `json:"lastSeq"`
+	LastHash  string `json:"lastHash"`
+	Valid     bool   `json:"valid"`
+	// BrokenAt is the line of the first entry breaking the chain
+	BrokenAt int    `json:"brokenAt,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+var (
+	auditSeq  uint64
+	auditHash string
+)
+
+// hashAuditEntry returns the hash of the entry, computed with an empty Hash.
+func hashAuditEntry(entry ChainedAuditEntry) (string, error) {
+	entry.Hash = ""
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// chainAuditEntry links the entry to the chain. Must be called holding auditMutex.
+func chainAuditEntry(entry AuditEntry) (ChainedAuditEntry, error) {
+	chained := ChainedAuditEntry{AuditEntry: entry, Seq: auditSeq + 1, Prev: auditHash}
+	hash, err := hashAuditEntry(chained)
+	if err != nil {
+		return chained, err
+	}
+	chained.Hash = hash
+	auditSeq, auditHash = chained.Seq, hash
+	return chained, nil
+}
+
+// restoreAuditChain continues the chain from the last entry of AUDIT_LOG_FILE.
+func restoreAuditChain() {
+	fileName := os.Getenv("AUDIT_LOG_FILE")
+	if fileName == "" {
+		return
+	}
+	f, err := os.Open(fileName)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	if info, err := f.Stat(); err == nil && info.Size() > auditTail {
+		f.Seek(-auditTail, io.SeekEnd)
+	}
+	data, err := io.ReadAll(f)
+	if err != nil {
+		log.Printf("Cannot read audit log %s, starting a new chain. Error: %s", fileName, err)
+		return
+	}
+	lines := bytes.Split(bytes.TrimSpace(data), []byte("\n"))
+	var last ChainedAuditEntry
+	if err := json.Unmarshal(lines[len(lines)-1], &last); err != nil || last.Hash == "" {
+		log.Printf("No chained entry at the end of the audit log %s, starting a new chain", fileName)
+		return
+	}
+	auditMutex.Lock()
+	auditSeq, auditHash = last.Seq, last.Hash
+	auditMutex.Unlock()
+	log.Printf("Audit chain continued from entry %d", last.Seq)
+}
+
+// verifyAuditLog checks the chain of every entry of the audit log. The entries written before the
+// log was chained are counted as unchained.
+func verifyAuditLog(fileName string) (AuditVerification, error) {
+	result := AuditVerification{File: fileName, Valid: true}
+	f, err := os.Open(fileName)
+	if err != nil {
+		return result, err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	chained := false
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		result.Entries++
+		var entry ChainedAuditEntry
+		err := json.Unmarshal(scanner.Bytes(), &entry)
+		switch {
+		case err != nil:
+			result.Error = fmt.Sprintf("invalid entry: %s", err)
+		case entry.Hash == "" && !chained:
+			result.Unchained++
+			continue
+		case entry.Hash == "":
+			result.Error = "entry without hash in the chain"
+		case entry.Prev == "":
+			if chained {
+				result.Restarts++
+			}
+		case entry.Prev != result.LastHash || entry.Seq != result.LastSeq+1:
+			result.Error = fmt.Sprintf("entry %d does not follow entry %d", entry.Seq, result.LastSeq)
+		}
+		if result.Error == "" {
+			if hash, err := hashAuditEntry(entry); err != nil || hash != entry.Hash {
+				result.Error = fmt.Sprintf("entry %d was altered", entry.Seq)
+			}
+		}
+		if result.Error != "" {
+			result.Valid, result.BrokenAt = false, line
+			return result, nil
+		}
+		chained = true
+		result.LastSeq, result.LastHash = entry.Seq, entry.Hash
+	}
+	return result, scanner.Err()
+}
+
+// startAuditCheckpoints publishes the head of the audit chain every AUDIT_CHECKPOINT_INTERVAL
+// (default 1h, 0 disables it) as an AuditCheckpoint reading and a system event, when it moved.
+func (s *SimpleDriver) startAuditCheckpoints() {
+	interval := DEFAULT_AUDIT_CHECKPOINT
+	if value := os.Getenv("AUDIT_CHECKPOINT_INTERVAL"); value != "" {
+		d, err := time.ParseDuration(value)
+		if err != nil || d < 0 {
+			log.Printf("Cannot parse AUDIT_CHECKPOINT_INTERVAL. Picking default value %s...", interval)
+		} else {
+			interval = d
+		}
+	}
+	if interval == 0 {
+		return
+	}
+	goBackground(func() {
+		var published uint64
+		for {
+			supervisedSleep("audit-checkpoint", interval)
+			auditMutex.Lock()
+			checkpoint := AuditCheckpoint{Seq: auditSeq, Hash: auditHash, Timestamp: time.Now().UnixNano()}
+			auditMutex.Unlock()
+			if checkpoint.Seq == published {
+				continue
+			}
+			published = checkpoint.Seq
+			s.publishAuditCheckpoint(checkpoint)
+		}
+	})
+}
+
+func (s *SimpleDriver) publishAuditCheckpoint(checkpoint AuditCheckpoint) {
+	log.Printf("Audit checkpoint: entry %d, hash %s", checkpoint.Seq, checkpoint.Hash)
+	publishSystemEvent(systemEventTypeAudit, systemEventActionCheckpoint, checkpoint)
+	payload, err := shapePayload(auditCheckpointResource, checkpoint)
+	if err != nil {
+		log.Printf("Cannot marshal audit checkpoint. Error: %s", err)
+		return
+	}
+	cv, err := sdkModels.NewCommandValue(auditCheckpointResource, common.ValueTypeString, string(payload))
+	if err != nil {
+		log.Printf("Cannot create audit checkpoint reading. Error: %s", err)
+		return
+	}
+	s.asyncCh <- &sdkModels.AsyncValues{
+		DeviceName:    deviceName(),
+		CommandValues: []*sdkModels.CommandValue{cv},
+	}
+}
+
+// handleAuditVerify verifies the chain of the audit log.
+func (s *SimpleDriver) handleAuditVerify(w http.ResponseWriter, r *http.Request) {
+	fileName := os.Getenv("AUDIT_LOG_FILE")
+	if fileName == "" {
+		writeError(w, http.StatusNotFound, fmt.Errorf("AUDIT_LOG_FILE is not set"))
+		return
+	}
+	result, err := verifyAuditLog(fileName)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if !result.Valid {
+		audit("audit-verify", fileName, fmt.Sprintf("chain broken at line %d: %s", result.BrokenAt, result.Error))
+	}
+	writeJSON(w, http.StatusOK, result)
+}
@@ -0,0 +1,61 @@
+package driver
+
+import (
+	"log"
+	"os"
+	"strconv"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/common"
+
+	sdkModels "github.com/edgexfoundry/device-sdk-go/v2/pkg/models"
+)
+
+const (
+	auditResource     = "Audit"
+	auditReadingQueue = 256
+)
+
+var (
+	auditReadings chan ChainedAuditEntry
+)
+
+// startAuditReadings publishes every audit entry as a reading of the Audit resource when
+// AUDIT_READINGS is set, for the deployments collecting the record of the actuations centrally.
+func (s *SimpleDriver) startAuditReadings() {
+	if enabled, _ := strconv.ParseBool(os.Getenv("AUDIT_READINGS")); !enabled {
+		return
+	}
+	auditReadings = make(chan ChainedAuditEntry, auditReadingQueue)
+	go func() {
+		for entry := range auditReadings {
+			payload, err := shapePayload(auditResource, entry)
+			if err != nil {
+				log.Printf("Cannot marshal audit reading. Error: %s", err)
+				continue
+			}
+			cv, err := sdkModels.NewCommandValue(auditResource, common.ValueTypeString, string(payload))
+			if err != nil {
+				log.Printf("Cannot create audit reading. Error: %s", err)
+				continue
+			}
+			s.asyncCh <- &sdkModels.AsyncValues{
+				DeviceName:    deviceName(),
+				CommandValues: []*sdkModels.CommandValue{cv},
+			}
+		}
+	}()
+	log.Printf("Publishing audit entries as %s readings", auditResource)
+}
+
+// queueAuditReading queues the chained entry for publication. An entry dropped on a full queue
+// leaves a gap in the sequence.
+func queueAuditReading(entry ChainedAuditEntry) {
+	if auditReadings == nil {
+		return
+	}
+	select {
+	case auditReadings <- entry:
+	default:
+		countEvictions("audit-readings", 1)
+	}
+}
@@ -0,0 +1,296 @@
+package driver
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/edgexfoundry/device-gpiod/gpio"
+	"github.com/edgexfoundry/device-sdk-go/v2/pkg/service"
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/common"
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/models"
+)
+
+const (
+	gpioResourceName = "GPIO"
+	provisionLabel   = "auto-provisioned"
+)
+
+var (
+	autoProvision = true
+)
+
+// parseAutoProvision reads the AUTO_PROVISION env var. Provisioning is enabled unless explicitly disabled.
+func parseAutoProvision() {
+	var err error
+	autoProvision, err = strconv.ParseBool(os.Getenv("AUTO_PROVISION"))
+	if err != nil {
+		log.Printf("Cannot parse auto provision. Picking default value...")
+		autoProvision = true
+	}
+}
+
+// provisionDevice creates the device profile and the device instance in core-metadata from the parsed
+// GPIO configuration. The device is only created when missing; an existing profile is updated when
+// its resources differ from the configuration, so the call is safe on every startup.
+func (s *SimpleDriver) provisionDevice() error {
+	ds := service.RunningService()
+	name := deviceName()
+
+	profile := buildDeviceProfile(name, s.GpioList)
+	if existing, err := ds.GetProfileByName(name); err != nil {
+		log.Printf("Device profile '%s' not found. Creating it from GPIO configuration...", name)
+		if _, err := ds.AddDeviceProfile(profile); err != nil {
+			return fmt.Errorf("cannot add device profile '%s': %s", name, err)
+		}
+	} else if changes := profileChanges(existing, profile); len(changes) > 0 {
+		log.Printf("Device profile '%s' differs from GPIO configuration (%s). Updating it...", name, strings.Join(changes, ", "))
+		profile.Id = existing.Id
+		profile.DeviceCommands = keptCommands(existing, profile)
+		if err := ds.UpdateDeviceProfile(profile); err != nil {
+			return fmt.Errorf("cannot update device profile '%s': %s", name, err)
+		}
+	}
+
+	if err := provisionLineProfile(); err != nil {
+		return err
+	}
+
+	if err := provisionWatchers(); err != nil {
+		return err
+	}
+
+	if _, err := ds.GetDeviceByName(name); err != nil {
+		log.Printf("Device '%s' not found. Creating it...", name)
+		device := models.Device{
+			Name:           name,
+			Description:    "gpiod handler",
+			AdminState:     models.Unlocked,
+			OperatingState: models.Up,
+			Labels:         []string{"gpiod", provisionLabel},
+			ServiceName:    ds.Name(),
+			ProfileName:    name,
+			Protocols: map[string]models.ProtocolProperties{
+				"other": {"Address": "gpiod", "Port": "300"},
+			},
+		}
+		if _, err := ds.AddDevice(device); err != nil {
+			return fmt.Errorf("cannot add device '%s': %s", name, err)
+		}
+	}
+
+	return nil
+}
+
+// buildDeviceProfile generates one RW Bool resource per configured line (Float32 duty cycle for pwm
+// lines) plus the GPIO resource used for async readings.
+func buildDeviceProfile(name string, gpioList *gpio.GPIOList) models.DeviceProfile {
+	resources := []models.DeviceResource{
+		readOnlyStringResource(gpioResourceName, "GPIO status update"),
+		readOnlyStringResource(inputEventResource, "Edges detected on input lines"),
+		readOnlyStringResource(spareActivityResource, "Unexpected activity on spare lines"),
+		readOnlyStringResource(securityAlarmResource, "Latching tamper/door alarms"),
+		readOnlyStringResource(powerFailResource, "Power-fail events with safe state latency"),
+		readOnlyStringResource(lineInfoResource, "Line requests by other processes conflicting with this service"),
+		readOnlyStringResource(cyclePlanResource, "Intended actuations of a cycle, published in plan mode"),
+		readOnlyStringResource(overrideResource, "Timed overrides of lines, null once reverted"),
+		readOnlyStringResource(commandQueueResource, "Commands queued for execution at a given time"),
+		readOnlyStringResource(rampProgressResource, "Progress of the duty cycle ramps of the pwm outputs"),
+		readOnlyStringResource(thresholdCrossingResource, "Threshold crossings of the derived resources"),
+		readOnlyStringResource(actuationLatencyResource, "Observed latency between driving a line and its feedback"),
+		readOnlyStringResource(timingViolationResource, "Feedback confirmations slower than the timing assertions"),
+		readOnlyStringResource(dutyLimitResource, "Actuations refused or deferred by a duty cycle limit"),
+		readOnlyStringResource(dailySummaryResource, "Cycles, pump time, reverses, cleans and faults of the day, published at the daily report time"),
+		readOnlyStringResource(weeklyStatsResource, "Daily statistics of the last seven days"),
+		readOnlyStringResource(maintenanceResource, "Actuators drifting from their baseline: feedback latency, verification failures, phase overruns"),
+		readOnlyStringResource(scheduleNextRunResource, "Start of the next scheduled cycle"),
+		readOnlyStringResource(scheduleLastRunResource, "Start of the last scheduled cycle"),
+		{
+			Name:        scheduleSkipResource,
+			Description: "Skip the next scheduled cycle",
+			Properties: models.ResourceProperties{
+				ValueType: common.ValueTypeBool,
+				ReadWrite: common.ReadWrite_RW,
+			},
+		},
+		readOnlyStringResource(metricsResource, "Counters of line toggles, on time, input events, line errors, phases, watchdog trips and reconnections"),
+		readOnlyStringResource(powerModeResource, "Power mode: grid, battery (cleans deferred) or low (cycles held)"),
+		readOnlyStringResource(healthResource, "Status of the health probes"),
+		{
+			Name:        deratingResource,
+			Description: "Operation derated for the temperature of the electronics",
+			Properties: models.ResourceProperties{
+				ValueType: common.ValueTypeBool,
+				ReadWrite: common.ReadWrite_R,
+			},
+		},
+		{
+			Name:        loadShedResource,
+			Description: "Optional work shed under pressure: 0 none, 1 recorders, 2 reporting, 3 clients",
+			Properties: models.ResourceProperties{
+				ValueType: common.ValueTypeInt8,
+				ReadWrite: common.ReadWrite_R,
+			},
+		},
+		readOnlyStringResource(auditResource, "Audit entries, hash chained, published with AUDIT_READINGS"),
+		readOnlyStringResource(auditCheckpointResource, "Head of the audit chain, published every AUDIT_CHECKPOINT_INTERVAL"),
+		{
+			Name:        leaderResource,
+			Description: "Whether this instance is the leader of the active/standby pair",
+			Properties: models.ResourceProperties{
+				ValueType: common.ValueTypeBool,
+				ReadWrite: common.ReadWrite_R,
+			},
+		},
+		readOnlyStringResource(reportingProfileResource, "Reporting profile applied: normal or metered (readings batched, periodic ones slowed)"),
+		readOnlyStringResource(capabilitiesResource, "Optional subsystems available and enabled, with their versions"),
+		readOnlyStringResource(stateDiscrepancyResource, "Output lines whose level at startup differs from the assumed state"),
+		readOnlyStringResource(lastShutdownResource, "Reason of the previous shutdown, published at startup"),
+		{
+			Name:        runtimeSinceReverseResource,
+			Description: "Pump runtime since the last reverse phase; write 0 to reset",
+			Properties: models.ResourceProperties{
+				ValueType: common.ValueTypeFloat32,
+				ReadWrite: common.ReadWrite_RW,
+				Units:     "h",
+			},
+		},
+		readOnlyStringResource(cleanStageResource, "Progress of the stages of the clean recipe"),
+		readOnlyStringResource(consumablesResource, "Estimated inventory of the cleaning agents"),
+		{
+			Name:        cyclesSinceCleanResource,
+			Description: "Pump cycles run since the last clean phase",
+			Properties: models.ResourceProperties{
+				ValueType: common.ValueTypeUint32,
+				ReadWrite: common.ReadWrite_R,
+			},
+		},
+		readOnlyStringResource(failSafeResource, "Outputs forced to their safe state by the fail-safe"),
+		readOnlyStringResource(configWarningResource, "Configuration warnings raised at startup"),
+	}
+	for _, g := range gpioList.Gpio {
+		if g.Exposure == gpio.ExposureLocal {
+			continue
+		}
+		attributes := map[string]interface{}{
+			"name": g.Name,
+			"chip": g.Chip,
+			"line": g.Line,
+		}
+		if len(g.Labels) > 0 {
+			attributes["labels"] = g.Labels
+		}
+		properties := models.ResourceProperties{
+			ValueType:    common.ValueTypeBool,
+			ReadWrite:    common.ReadWrite_RW,
+			DefaultValue: "false",
+		}
+		if g.Role == RolePwm {
+			// Duty cycle in percent
+			properties = models.ResourceProperties{
+				ValueType:    common.ValueTypeFloat32,
+				ReadWrite:    common.ReadWrite_RW,
+				Units:        "%",
+				Minimum:      "0",
+				Maximum:      "100",
+				DefaultValue: "0",
+			}
+		}
+		resources = append(resources, models.DeviceResource{
+			Name:        g.Name,
+			Description: lineDescription(g),
+			Attributes:  attributes,
+			Properties:  properties,
+		})
+		resources = append(resources, derivedProfileResources(g)...)
+		resources = append(resources, counterProfileResources(g)...)
+		resources = append(resources, valveProfileResources(g)...)
+	}
+	resources = append(resources, groupProfileResources(gpioList)...)
+	resources = append(resources, timerProfileResources()...)
+	resources = append(resources, virtualProfileResources()...)
+	resources = append(resources, statisticsProfileResources()...)
+
+	return models.DeviceProfile{
+		Name:            name,
+		Manufacturer:    "Concept Reply",
+		Model:           "SP-01",
+		Description:     "gpiod Handler Device Service",
+		Labels:          []string{"gpiod", provisionLabel},
+		DeviceResources: resources,
+	}
+}
+
+// lineDescription returns the user supplied description of a line, falling back to its chip/offset.
+func lineDescription(g gpio.GPIO) string {
+	if g.Description != "" {
+		return g.Description
+	}
+	return fmt.Sprintf("Line %d of %s", g.Line, g.Chip)
+}
+
+// readOnlyStringResource returns a read-only String resource, the shape of the event and report
+// resources of the profile.
+func readOnlyStringResource(name string, description string) models.DeviceResource {
+	return models.DeviceResource{
+		Name:        name,
+		Description: description,
+		Properties: models.ResourceProperties{
+			ValueType: common.ValueTypeString,
+			ReadWrite: common.ReadWrite_R,
+		},
+	}
+}
+
+// profileChanges lists the resources added, removed or changed in profile compared to existing.
+// Attributes are compared as printed, metadata returning their numbers as float64.
+func profileChanges(existing models.DeviceProfile, profile models.DeviceProfile) []string {
+	current := make(map[string]models.DeviceResource)
+	for _, r := range existing.DeviceResources {
+		current[r.Name] = r
+	}
+	var changes []string
+	for _, r := range profile.DeviceResources {
+		old, ok := current[r.Name]
+		delete(current, r.Name)
+		switch {
+		case !ok:
+			changes = append(changes, "added "+r.Name)
+		case old.Description != r.Description || !reflect.DeepEqual(old.Properties, r.Properties) ||
+			fmt.Sprint(old.Attributes) != fmt.Sprint(r.Attributes):
+			changes = append(changes, "changed "+r.Name)
+		}
+	}
+	for _, r := range existing.DeviceResources {
+		if _, ok := current[r.Name]; ok {
+			changes = append(changes, "removed "+r.Name)
+		}
+	}
+	return changes
+}
+
+// keptCommands returns the device commands of existing whose resources are all still in profile.
+func keptCommands(existing models.DeviceProfile, profile models.DeviceProfile) []models.DeviceCommand {
+	resources := make(map[string]bool)
+	for _, r := range profile.DeviceResources {
+		resources[r.Name] = true
+	}
+	var commands []models.DeviceCommand
+	for _, command := range existing.DeviceCommands {
+		kept := true
+		for _, operation := range command.ResourceOperations {
+			if !resources[operation.DeviceResource] {
+				kept = false
+			}
+		}
+		if kept {
+			commands = append(commands, command)
+		} else {
+			log.Printf("Device command '%s' dropped from profile '%s', its resources are gone", command.Name, profile.Name)
+		}
+	}
+	return commands
+}
@@ -0,0 +1,286 @@
+package driver
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/common"
+)
+
+const (
+	budgetsRoute            = common.ApiBase + "/budgets"
+	pressureGC              = "gc"
+	pressureShed            = "shed"
+	DEFAULT_BUDGET_INTERVAL = time.Duration(10) * time.Second
+
+	defaultAuditEntries       = 256
+	defaultTimelineRecords    = 1024
+	defaultFinishedOperations = 64
+	defaultStreamSubscribers  = 16
+	defaultStreamBuffer       = 64
+)
+
+// Budgets bounds what the service keeps in memory, so it stays within the RSS of a small gateway even
+// under event storms. The histories (audit, timelines, finished operations, idempotency keys) evict
+// their oldest entries once full. Past the Memory soft limit (e.g. "256MB") the heap is collected and
+// returned to the OS, and with on_pressure shed the histories are halved too. Past the Goroutines
+// limit new stream subscribers are refused and the service reports a warning.
+type Budgets struct {
+	Memory             string `yaml:"memory"`
+	Goroutines         int    `yaml:"goroutines"`
+	AuditEntries       int    `yaml:"audit_entries"`
+	TimelineRecords    int    `yaml:"timeline_records"`
+	IdempotencyKeys    int    `yaml:"idempotency_keys"`
+	FinishedOperations int    `yaml:"finished_operations"`
+	StreamSubscribers  int    `yaml:"stream_subscribers"`
+	StreamBuffer       int    `yaml:"stream_buffer"`
+	OnPressure         string `yaml:"on_pressure"`
+	memory             uint64
+}
+
+// BudgetUsage is the use of a budget and the entries it evicted so far.
+type BudgetUsage struct {
+	Limit   uint64 `json:"limit"`
+	Used    uint64 `json:"used"`
+	Evicted uint64 `json:"evicted"`
+}
+
+var (
+	budgetMutex      = sync.Mutex{}
+	budgetEvictions  = make(map[string]uint64)
+	memoryPressure   = uint64(0)
+	heapInUse        = uint64(0)
+	overGoroutines   = false
+	budgetInterval   = DEFAULT_BUDGET_INTERVAL
+	memoryBudget     = uint64(0)
+	goroutineBudget  = 0
+	maxStreamClients = defaultStreamSubscribers
+	errStreamBudget  = errors.New("no capacity for stream clients")
+)
+
+// parseSize reads a byte size with an optional B, KB, MB or GB (binary) suffix.
+func parseSize(value string) (uint64, error) {
+	value = strings.ToUpper(strings.TrimSpace(value))
+	unit := uint64(1)
+	for _, suffix := range []struct {
+		name string
+		size uint64
+	}{{"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10}, {"B", 1}} {
+		if strings.HasSuffix(value, suffix.name) {
+			unit = suffix.size
+			value = strings.TrimSpace(strings.TrimSuffix(value, suffix.name))
+			break
+		}
+	}
+	size, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size %q", value)
+	}
+	return size * unit, nil
+}
+
+// validateBudgets checks the budgets section and applies its limits; unset limits are the defaults.
+func validateBudgets() error {
+	b := driverConfig.Budgets
+	if b == nil {
+		b = &Budgets{}
+	}
+	for name, limit := range map[string]int{
+		"goroutines":          b.Goroutines,
+		"audit_entries":       b.AuditEntries,
+		"timeline_records":    b.TimelineRecords,
+		"idempotency_keys":    b.IdempotencyKeys,
+		"finished_operations": b.FinishedOperations,
+		"stream_subscribers":  b.StreamSubscribers,
+		"stream_buffer":       b.StreamBuffer,
+	} {
+		if limit < 0 {
+			return fmt.Errorf("%s cannot be negative", name)
+		}
+	}
+	switch b.OnPressure {
+	case "", pressureGC, pressureShed:
+	default:
+		return fmt.Errorf("unknown on_pressure %q, expected %s or %s", b.OnPressure, pressureGC, pressureShed)
+	}
+	if b.Memory != "" {
+		memory, err := parseSize(b.Memory)
+		if err != nil || memory == 0 {
+			return fmt.Errorf("invalid memory %q", b.Memory)
+		}
+		b.memory = memory
+	}
+
+	budgetMutex.Lock()
+	memoryBudget = b.memory
+	goroutineBudget = b.Goroutines
+	budgetMutex.Unlock()
+	auditMutex.Lock()
+	maxAuditEntries = orDefault(b.AuditEntries, defaultAuditEntries)
+	auditMutex.Unlock()
+	timelineMutex.Lock()
+	maxTimelineRecord = orDefault(b.TimelineRecords, defaultTimelineRecords)
+	timelineMutex.Unlock()
+	operationsMutex.Lock()
+	maxFinishedOperation = orDefault(b.FinishedOperations, defaultFinishedOperations)
+	operationsMutex.Unlock()
+	streamMutex.Lock()
+	maxStreamClients = orDefault(b.StreamSubscribers, defaultStreamSubscribers)
+	streamBuffer = orDefault(b.StreamBuffer, defaultStreamBuffer)
+	streamMutex.Unlock()
+	if b.IdempotencyKeys > 0 {
+		idempotencyMutex.Lock()
+		idempotencySize = b.IdempotencyKeys
+		idempotencyMutex.Unlock()
+	}
+	return nil
+}
+
+func orDefault(value int, def int) int {
+	if value > 0 {
+		return value
+	}
+	return def
+}
+
+// countEvictions records n entries evicted to keep the named budget.
+func countEvictions(name string, n int) {
+	if n <= 0 {
+		return
+	}
+	budgetMutex.Lock()
+	defer budgetMutex.Unlock()
+	budgetEvictions[name] += uint64(n)
+}
+
+// overBudget reports whether the service runs past its goroutine budget.
+func overBudget() bool {
+	budgetMutex.Lock()
+	defer budgetMutex.Unlock()
+	return overGoroutines
+}
+
+// startBudgetMonitoring checks the heap and the goroutines every BUDGET_INTERVAL (default 10s). It is
+// a no-op when neither budget is set.
+func startBudgetMonitoring() {
+	budgetMutex.Lock()
+	enabled := memoryBudget > 0 || goroutineBudget > 0
+	budgetMutex.Unlock()
+	if !enabled {
+		return
+	}
+	if d, err := time.ParseDuration(os.Getenv("BUDGET_INTERVAL")); err == nil && d > 0 {
+		budgetInterval = d
+	} else {
+		log.Printf("Cannot parse BUDGET_INTERVAL. Picking default value %s...", budgetInterval)
+	}
+	goBackground(func() {
+		for {
+			supervisedSleep("budgets", budgetInterval)
+			enforceBudgets()
+		}
+	})
+}
+
+func enforceBudgets() {
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	goroutines := runtime.NumGoroutine()
+
+	budgetMutex.Lock()
+	heapInUse = stats.HeapInuse
+	pressure := memoryBudget > 0 && stats.HeapInuse > memoryBudget
+	if pressure {
+		memoryPressure++
+	}
+	wasOver := overGoroutines
+	overGoroutines = goroutineBudget > 0 && goroutines > goroutineBudget
+	over, limit := overGoroutines, goroutineBudget
+	budgetMutex.Unlock()
+
+	if pressure {
+		log.Printf("Heap in use %d bytes exceeds the memory budget, reclaiming", stats.HeapInuse)
+		if b := driverConfig.Budgets; b != nil && b.OnPressure == pressureShed {
+			shedHistories()
+		}
+		debug.FreeOSMemory()
+	}
+	if over != wasOver {
+		if over {
+			audit("budget-exceeded", "goroutines", fmt.Sprintf("%d running, budget %d", goroutines, limit))
+		} else {
+			audit("budget-restored", "goroutines", fmt.Sprintf("%d running, budget %d", goroutines, limit))
+		}
+	}
+}
+
+// shedHistories halves the in-memory histories under memory pressure.
+func shedHistories() {
+	auditMutex.Lock()
+	n := len(auditEntries) / 2
+	auditEntries = append([]AuditEntry(nil), auditEntries[n:]...)
+	auditMutex.Unlock()
+	countEvictions("audit", n)
+
+	timelineMutex.Lock()
+	evicted := 0
+	for _, t := range timeline {
+		n := len(t.Intervals) / 2
+		t.Intervals = append([]TimelineInterval(nil), t.Intervals[n:]...)
+		m := len(t.Events) / 2
+		t.Events = append([]TimelineEvent(nil), t.Events[m:]...)
+		evicted += n + m
+	}
+	timelineMutex.Unlock()
+	countEvictions("timeline", evicted)
+}
+
+// budgetReport returns the use of every budget.
+func budgetReport() map[string]interface{} {
+	auditMutex.Lock()
+	entries := BudgetUsage{Limit: uint64(maxAuditEntries), Used: uint64(len(auditEntries))}
+	auditMutex.Unlock()
+	timelineMutex.Lock()
+	records := 0
+	for _, t := range timeline {
+		records += len(t.Intervals) + len(t.Events)
+	}
+	timelines := BudgetUsage{Limit: uint64(maxTimelineRecord), Used: uint64(records)}
+	timelineMutex.Unlock()
+	idempotencyMutex.Lock()
+	keys := BudgetUsage{Limit: uint64(idempotencySize), Used: uint64(idempotencyOrder.Len())}
+	idempotencyMutex.Unlock()
+	streamMutex.Lock()
+	subscribers := BudgetUsage{Limit: uint64(maxStreamClients), Used: uint64(len(streamSubscribers))}
+	streamMutex.Unlock()
+
+	budgetMutex.Lock()
+	defer budgetMutex.Unlock()
+	entries.Evicted = budgetEvictions["audit"]
+	timelines.Evicted = budgetEvictions["timeline"]
+	keys.Evicted = budgetEvictions["idempotency"]
+	subscribers.Evicted = budgetEvictions["stream-subscribers"]
+	return map[string]interface{}{
+		"memory":            map[string]uint64{"limit": memoryBudget, "used": heapInUse, "pressure": memoryPressure},
+		"goroutines":        BudgetUsage{Limit: uint64(goroutineBudget), Used: uint64(runtime.NumGoroutine())},
+		"audit":             entries,
+		"timeline":          timelines,
+		"idempotency":       keys,
+		"operations":        BudgetUsage{Limit: uint64(maxFinishedOperation), Evicted: budgetEvictions["operations"]},
+		"streamSubscribers": subscribers,
+		"streamDropped":     budgetEvictions["stream"],
+	}
+}
+
+func (s *SimpleDriver) handleBudgets(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, budgetReport())
+}
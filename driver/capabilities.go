@@ -0,0 +1,81 @@
+package driver
+
+import (
+	"log"
+	"net/http"
+	"os"
+
+	"github.com/edgexfoundry/device-gpiod"
+	"github.com/edgexfoundry/device-gpiod/gpio"
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/common"
+
+	sdkModels "github.com/edgexfoundry/device-sdk-go/v2/pkg/models"
+)
+
+const (
+	capabilitiesResource = "Capabilities"
+	capabilitiesRoute    = common.ApiBase + "/capabilities"
+)
+
+// Capability tells whether an optional subsystem is available in this build and enabled by the
+// configuration, and the version of the protocol or format it speaks.
+type Capability struct {
+	Compiled bool   `json:"compiled"`
+	Enabled  bool   `json:"enabled"`
+	Version  string `json:"version,omitempty"`
+}
+
+// capabilities lists the optional subsystems, so orchestration layers can adapt to this instance
+// without trial-and-error commands.
+func (s *SimpleDriver) capabilities() map[string]Capability {
+	pwmLines := false
+	for _, g := range s.GpioList.Gpio {
+		if g.Role == RolePwm {
+			pwmLines = true
+			break
+		}
+	}
+	return map[string]Capability{
+		"service":      {Compiled: true, Enabled: true, Version: device.Version},
+		"api":          {Compiled: true, Enabled: true, Version: common.ApiVersion},
+		"openapi":      {Compiled: true, Enabled: true, Version: "3.0.3"},
+		"pwm":          {Compiled: true, Enabled: pwmLines, Version: "software"},
+		"payloads":     {Compiled: true, Enabled: true, Version: schemaLegacy + "," + schemaV2},
+		"grpc":         {Compiled: grpcCompiled, Enabled: grpcCompiled && os.Getenv("GRPC_ADDRESS") != "", Version: "edgex.gpiod.v1"},
+		"modbusFacade": {Compiled: modbusCompiled, Enabled: modbusCompiled && os.Getenv("MODBUS_SERVER_ADDRESS") != "", Version: "modbus-tcp"},
+		"opcuaFacade":  {Compiled: opcuaCompiled, Enabled: opcuaCompiled && os.Getenv("OPCUA_SERVER_ADDRESS") != "", Version: "opc.tcp"},
+		"cloudTwin":    {Compiled: mqttCompiled, Enabled: mqttCompiled && os.Getenv("TWIN_PROVIDER") != "", Version: "mqtt-3.1.1"},
+		"coordination": {Compiled: mqttCompiled, Enabled: mqttCompiled && os.Getenv("COORDINATION_BROKER") != "", Version: "mqtt-3.1.1"},
+		"systemEvents": {Compiled: mqttCompiled, Enabled: mqttCompiled && os.Getenv("SYSTEM_EVENTS_BROKER") != "", Version: "mqtt-3.1.1"},
+		"syslog":       {Compiled: true, Enabled: os.Getenv("SYSLOG_ADDRESS") != "", Version: "rfc5424"},
+		"snmpTraps":    {Compiled: true, Enabled: snmpManager != "", Version: "v" + snmpVersion},
+		"dashboard":    {Compiled: dashboardCompiled, Enabled: dashboardEnabled()},
+		"planMode":     {Compiled: true, Enabled: planMode},
+		"simulation":   {Compiled: true, Enabled: gpio.ActiveSimulator() != nil, Version: "memory"},
+		"timeSeries":   {Compiled: true, Enabled: os.Getenv("TSDB_DIR") != "", Version: "jsonl"},
+		"auditLog":     {Compiled: true, Enabled: os.Getenv("AUDIT_LOG_FILE") != "", Version: "jsonl"},
+		"journal":      {Compiled: true, Enabled: os.Getenv("JOURNAL_FILE") != "", Version: "jsonl"},
+	}
+}
+
+// pushCapabilities publishes the capabilities as a reading at startup.
+func (s *SimpleDriver) pushCapabilities() {
+	payload, err := shapePayload(capabilitiesResource, s.capabilities())
+	if err != nil {
+		log.Printf("Cannot marshal capabilities. Error: %s", err)
+		return
+	}
+	cv, err := sdkModels.NewCommandValue(capabilitiesResource, common.ValueTypeString, string(payload))
+	if err != nil {
+		log.Printf("Cannot create capabilities reading. Error: %s", err)
+		return
+	}
+	s.asyncCh <- &sdkModels.AsyncValues{
+		DeviceName:    deviceName(),
+		CommandValues: []*sdkModels.CommandValue{cv},
+	}
+}
+
+func (s *SimpleDriver) handleCapabilities(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.capabilities())
+}
@@ -0,0 +1,141 @@
+package driver
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/edgexfoundry/device-gpiod/gpio"
+)
+
+const (
+	systemEventTypeChip        = "chip"
+	systemEventActionGone      = "gone"
+	systemEventActionRecovered = "recovered"
+
+	DEFAULT_CHIP_WATCH_INTERVAL = time.Duration(2) * time.Second
+)
+
+// startChipRecovery watches the device nodes of the chips in use every CHIP_WATCH_INTERVAL (default
+// 2s, 0 disables it). When a node goes away and comes back, or comes back as a new node between two
+// checks, the driver of the chip was reloaded and every handle on it is dead: the held outputs are
+// requested again at their journaled value, the last set by the service without a journal, and the
+// watched lines are watched again, without a restart of the service.
+func (s *SimpleDriver) startChipRecovery() {
+	if gpio.ActiveSimulator() != nil {
+		return
+	}
+	interval := chipWatchInterval()
+	if interval == 0 {
+		return
+	}
+	identities := make(map[string]uint64)
+	for _, g := range s.GpioList.Gpio {
+		if id, ok := gpio.ChipIdentity(g.Chip); ok {
+			identities[g.Chip] = id
+		}
+	}
+	goBackground(func() {
+		gone := make(map[string]bool)
+		for {
+			supervisedSleep("chip-recovery", interval)
+			for chip, known := range identities {
+				id, ok := gpio.ChipIdentity(chip)
+				switch {
+				case !ok && !gone[chip]:
+					gone[chip] = true
+					logf(moduleGpio, levelError, "gpiochip %s is gone, waiting for it to come back", chip)
+					audit("chip-gone", chip, "device node removed")
+					publishSystemEvent(systemEventTypeChip, systemEventActionGone, map[string]string{"chip": chip})
+				case ok && (gone[chip] || id != known):
+					delete(gone, chip)
+					identities[chip] = id
+					s.recoverChip(chip)
+				}
+			}
+		}
+	})
+}
+
+// chipWatchInterval reads CHIP_WATCH_INTERVAL, the period of the checks of the gpiochips.
+func chipWatchInterval() time.Duration {
+	interval := DEFAULT_CHIP_WATCH_INTERVAL
+	if value := os.Getenv("CHIP_WATCH_INTERVAL"); value != "" {
+		d, err := time.ParseDuration(value)
+		if err != nil || d < 0 {
+			log.Printf("Cannot parse CHIP_WATCH_INTERVAL. Picking default value %s...", interval)
+		} else {
+			interval = d
+		}
+	}
+	return interval
+}
+
+// recoverChip requests again the lines of a reloaded chip.
+func (s *SimpleDriver) recoverChip(chip string) {
+	values := journalValues()
+	outputs, failed := gpio.RecoverHeld(chip, values)
+	for _, name := range outputs {
+		if g, ok := s.findGpio(name); ok {
+			if value, ok := values[name]; ok {
+				g.State = value == 1
+			}
+			recordTimelineEvent(name, "reacquired", "chip reloaded, requested again")
+		}
+	}
+
+	var watched []*gpio.GPIO
+	for i := range s.GpioList.Gpio {
+		if g := &s.GpioList.Gpio[i]; g.Chip == chip && g.Watched() {
+			watched = append(watched, g)
+		}
+	}
+	devicesMutex.Lock()
+	for _, d := range deviceLines {
+		if d.line.Chip == chip && d.line.Watched() {
+			watched = append(watched, d.line)
+		}
+	}
+	devicesMutex.Unlock()
+	var rewatched []string
+	for _, g := range watched {
+		if err := g.Rewatch(); err != nil {
+			logf(moduleGpio, levelError, "Cannot watch gpio %s again after the reload of %s. Error: %s", g.Name, chip, err)
+			failed = append(failed, g.Name)
+			continue
+		}
+		rewatched = append(rewatched, g.Name)
+	}
+
+	sort.Strings(failed)
+	detail := fmt.Sprintf("%d outputs and %d watched lines requested again", len(outputs), len(rewatched))
+	if len(failed) > 0 {
+		detail += ", failed: " + strings.Join(failed, ", ")
+	}
+	logf(moduleGpio, levelWarn, "gpiochip %s was reloaded: %s", chip, detail)
+	audit("chip-recovered", chip, detail)
+	publishSystemEvent(systemEventTypeChip, systemEventActionRecovered, map[string]interface{}{
+		"chip":    chip,
+		"outputs": outputs,
+		"watched": rewatched,
+		"failed":  failed,
+	})
+}
+
+// journalValues returns the last value journaled for every line, none without a journal.
+func journalValues() map[string]int {
+	journalMutex.Lock()
+	defer journalMutex.Unlock()
+	if journalFile == nil {
+		return nil
+	}
+	_, values, err := readJournal(journalFile.Name())
+	if err != nil {
+		log.Printf("Cannot read actuation journal. Error: %s", err)
+		return nil
+	}
+	return values
+}
@@ -0,0 +1,118 @@
+package driver
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/edgexfoundry/device-gpiod/sequence"
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/common"
+
+	sdkModels "github.com/edgexfoundry/device-sdk-go/v2/pkg/models"
+)
+
+const (
+	cleanStageResource = "CleanStage"
+	// cleanRecipeInclude is the include step of the cycle sequence running the clean recipe
+	cleanRecipeInclude = "clean-recipe"
+	cleanStagePrefix   = phaseClean + "-"
+)
+
+// CleanStage is a stage of the clean recipe (detergent, rinse, sanitize...): the valve letting its
+// agent in is opened for dwell. Each stage runs as the phase clean-<name>. Rate is the agent used per
+// minute of dwell, tracked in the consumables.
+type CleanStage struct {
+	Name  string  `yaml:"name"`
+	Agent string  `yaml:"agent"`
+	Valve string  `yaml:"valve"`
+	Dwell string  `yaml:"dwell"`
+	Rate  float64 `yaml:"rate"`
+	dwell time.Duration
+}
+
+// CleanStageReport is published when a stage of the clean recipe starts and ends.
+type CleanStageReport struct {
+	Stage string    `json:"stage"`
+	Agent string    `json:"agent,omitempty"`
+	Valve string    `json:"valve"`
+	Dwell string    `json:"dwell"`
+	State string    `json:"state"`
+	Error string    `json:"error,omitempty"`
+	At    time.Time `json:"at"`
+}
+
+// validateCleanRecipe checks the clean_recipe section of the configuration file and defines its
+// stages in the cycle sequence.
+func validateCleanRecipe() error {
+	names := make(map[string]bool)
+	var steps []sequence.Step
+	for i := range driverConfig.CleanRecipe {
+		stage := &driverConfig.CleanRecipe[i]
+		if stage.Name == "" || names[stage.Name] {
+			return fmt.Errorf("stage names must be unique and not empty")
+		}
+		names[stage.Name] = true
+		if stage.Valve == "" {
+			return fmt.Errorf("stage %s: missing valve", stage.Name)
+		}
+		d, err := time.ParseDuration(stage.Dwell)
+		if err != nil || d <= 0 {
+			return fmt.Errorf("stage %s: invalid dwell %q", stage.Name, stage.Dwell)
+		}
+		stage.dwell = d
+		steps = append(steps, sequence.Step{
+			Phase: cleanStagePrefix + stage.Name,
+			Steps: []sequence.Step{
+				{Log: fmt.Sprintf("Clean stage %s: opening %s for %s", stage.Name, stage.Valve, d)},
+				{Set: stage.Valve},
+				{Sleep: d.String()},
+				{Clear: stage.Valve},
+			},
+		})
+	}
+	if cycleSequence == nil {
+		return nil
+	}
+	return cycleSequence.Define(cleanRecipeInclude, steps)
+}
+
+// cleanStage returns the stage of the clean recipe run as the given phase.
+func cleanStage(phase string) (CleanStage, bool) {
+	if !strings.HasPrefix(phase, cleanStagePrefix) {
+		return CleanStage{}, false
+	}
+	for _, stage := range driverConfig.CleanRecipe {
+		if stage.Name == strings.TrimPrefix(phase, cleanStagePrefix) {
+			return stage, true
+		}
+	}
+	return CleanStage{}, false
+}
+
+// pushCleanStage publishes the progress of a stage of the clean recipe; phases of other kinds are
+// ignored.
+func (s *SimpleDriver) pushCleanStage(phase string, state string, err error) {
+	stage, ok := cleanStage(phase)
+	if !ok {
+		return
+	}
+	report := CleanStageReport{Stage: stage.Name, Agent: stage.Agent, Valve: stage.Valve, Dwell: stage.dwell.String(), State: state, At: time.Now()}
+	if err != nil {
+		report.Error = err.Error()
+	}
+	payload, err := shapePayload(cleanStageResource, report)
+	if err != nil {
+		log.Printf("Cannot marshal clean stage. Error: %s", err)
+		return
+	}
+	cv, err := sdkModels.NewCommandValue(cleanStageResource, common.ValueTypeString, string(payload))
+	if err != nil {
+		log.Printf("Cannot create clean stage reading. Error: %s", err)
+		return
+	}
+	s.asyncCh <- &sdkModels.AsyncValues{
+		DeviceName:    deviceName(),
+		CommandValues: []*sdkModels.CommandValue{cv},
+	}
+}
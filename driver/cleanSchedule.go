@@ -0,0 +1,58 @@
+package driver
+
+import (
+	"log"
+	"os"
+	"strconv"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/common"
+
+	sdkModels "github.com/edgexfoundry/device-sdk-go/v2/pkg/models"
+)
+
+const cyclesSinceCleanResource = "CyclesSinceClean"
+
+var (
+	// cleanEvery runs the clean phase on one cycle out of cleanEvery, counted by sinceClean
+	cleanEvery = 1
+	sinceClean = 0
+)
+
+// loadCleanSchedule reads CLEAN_EVERY (default 1, every cycle). The count of cycles since the last
+// clean is restored from the pipeline state, so a restart does not postpone the next clean.
+func loadCleanSchedule() {
+	if n, err := strconv.Atoi(os.Getenv("CLEAN_EVERY")); err == nil && n > 0 {
+		cleanEvery = n
+	} else {
+		log.Printf("Cannot parse CLEAN_EVERY. Picking default value %d...", cleanEvery)
+	}
+	if restoredPipeline != nil {
+		sinceClean = restoredPipeline.CyclesSinceClean
+	}
+}
+
+// cleanDue tells whether the next cycle is due to clean. Must be called holding nextCycleMutex.
+func cleanDue() bool {
+	return *enableClean && sinceClean+1 >= cleanEvery
+}
+
+// countCleanCycle counts a finished cycle, restarting from zero when it cleaned. Must be called
+// holding nextCycleMutex.
+func countCleanCycle(cleaned bool) {
+	if cleaned {
+		sinceClean = 0
+	} else {
+		sinceClean++
+	}
+	notePipelineCleanCount(sinceClean)
+}
+
+func cyclesSinceClean() int {
+	nextCycleMutex.Lock()
+	defer nextCycleMutex.Unlock()
+	return sinceClean
+}
+
+func readCyclesSinceClean(req sdkModels.CommandRequest) (*sdkModels.CommandValue, error) {
+	return sdkModels.NewCommandValue(req.DeviceResourceName, common.ValueTypeUint32, uint32(cyclesSinceClean()))
+}
@@ -0,0 +1,203 @@
+//go:build !nomqtt
+
+package driver
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+const (
+	twinProviderAWS   = "aws"
+	twinProviderAzure = "azure"
+	twinSecretName    = "twin"
+	twinTimeout       = time.Duration(10) * time.Second
+)
+
+// twinState is the part of the shadow/twin document owned by this service.
+type twinState struct {
+	Lines map[string]int `json:"lines"`
+}
+
+// cloudTwin mirrors the line states to an AWS IoT shadow or an Azure IoT Hub device twin, and applies
+// the desired states set from the cloud.
+type cloudTwin struct {
+	s         *SimpleDriver
+	provider  string
+	thing     string
+	client    mqtt.Client
+	mutex     sync.Mutex
+	reported  map[string]int
+	requestID int
+}
+
+// startCloudTwin connects to TWIN_BROKER (e.g. tls://<endpoint>:8883) when TWIN_PROVIDER is aws or
+// azure. AWS authenticates with the TWIN_CERT_FILE/TWIN_KEY_FILE client certificate, Azure with the
+// SAS token of the "twin" secret (key sasToken) or TWIN_SAS_TOKEN. TWIN_THING is the thing name or
+// device id, the device name by default.
+func (s *SimpleDriver) startCloudTwin() error {
+	provider := os.Getenv("TWIN_PROVIDER")
+	if provider == "" {
+		return nil
+	}
+	if provider != twinProviderAWS && provider != twinProviderAzure {
+		return fmt.Errorf("unknown TWIN_PROVIDER %q", provider)
+	}
+	broker := os.Getenv("TWIN_BROKER")
+	if broker == "" {
+		return fmt.Errorf("TWIN_BROKER is required with TWIN_PROVIDER %s", provider)
+	}
+	twin := &cloudTwin{s: s, provider: provider, thing: os.Getenv("TWIN_THING"), reported: make(map[string]int)}
+	if twin.thing == "" {
+		twin.thing = deviceName()
+	}
+
+	tlsConfig := &tls.Config{}
+	if caFile := os.Getenv("TWIN_CA_FILE"); caFile != "" {
+		ca, err := os.ReadFile(caFile)
+		if err != nil {
+			return fmt.Errorf("cannot read TWIN_CA_FILE: %s", err)
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		tlsConfig.RootCAs.AppendCertsFromPEM(ca)
+	}
+	options := mqtt.NewClientOptions().
+		AddBroker(broker).
+		SetClientID(twin.thing).
+		SetAutoReconnect(true).
+		SetReconnectingHandler(countReconnect("twin")).
+		SetOnConnectHandler(twin.onConnect)
+
+	switch provider {
+	case twinProviderAWS:
+		cert, err := tls.LoadX509KeyPair(os.Getenv("TWIN_CERT_FILE"), os.Getenv("TWIN_KEY_FILE"))
+		if err != nil {
+			return fmt.Errorf("cannot load twin client certificate: %s", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	case twinProviderAzure:
+		hub := strings.TrimSuffix(strings.TrimPrefix(strings.TrimPrefix(broker, "tls://"), "ssl://"), ":8883")
+		options.SetUsername(fmt.Sprintf("%s/%s/?api-version=2021-04-12", hub, twin.thing))
+		options.SetPassword(twinSasToken())
+		options.SetProtocolVersion(4)
+	}
+	options.SetTLSConfig(tlsConfig)
+
+	twin.client = mqtt.NewClient(options)
+	token := twin.client.Connect()
+	if !token.WaitTimeout(twinTimeout) || token.Error() != nil {
+		logf(moduleBridges, levelWarn, "Cannot connect to the %s twin, retrying in background. Error: %v", provider, token.Error())
+	}
+	go twin.mirror()
+	return nil
+}
+
+func twinSasToken() string {
+	if secrets, err := readSecret(twinSecretName, "sasToken"); err == nil && secrets["sasToken"] != "" {
+		return secrets["sasToken"]
+	}
+	return os.Getenv("TWIN_SAS_TOKEN")
+}
+
+func (t *cloudTwin) desiredTopic() string {
+	if t.provider == twinProviderAWS {
+		return fmt.Sprintf("$aws/things/%s/shadow/update/delta", t.thing)
+	}
+	return "$iothub/twin/PATCH/properties/desired/#"
+}
+
+func (t *cloudTwin) reportedTopic() string {
+	if t.provider == twinProviderAWS {
+		return fmt.Sprintf("$aws/things/%s/shadow/update", t.thing)
+	}
+	t.requestID++
+	return fmt.Sprintf("$iothub/twin/PATCH/properties/reported/?$rid=%d", t.requestID)
+}
+
+// onConnect subscribes to the desired state changes and reports the full state again, as updates may
+// have been missed while disconnected.
+func (t *cloudTwin) onConnect(client mqtt.Client) {
+	logf(moduleBridges, levelInfo, "Connected to the %s twin of %s", t.provider, t.thing)
+	client.Subscribe(t.desiredTopic(), 1, t.onDesired)
+	t.mutex.Lock()
+	lines := make(map[string]int, len(t.reported))
+	for name, value := range t.reported {
+		lines[name] = value
+	}
+	t.mutex.Unlock()
+	t.report(lines)
+}
+
+// mirror reports every line change to the twin.
+func (t *cloudTwin) mirror() {
+	ch := subscribeStream()
+	for evt := range ch {
+		if evt.Kind != "line" {
+			continue
+		}
+		data, ok := evt.Data.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name, _ := data["name"].(string)
+		value, _ := data["value"].(int)
+		if g, ok := t.s.findGpio(name); ok && checkExposure(g, "twin") != nil {
+			continue
+		}
+		t.mutex.Lock()
+		t.reported[name] = value
+		t.mutex.Unlock()
+		t.report(map[string]int{name: value})
+	}
+}
+
+func (t *cloudTwin) report(lines map[string]int) {
+	if t.client == nil || !t.client.IsConnected() {
+		return
+	}
+	var document interface{} = twinState{Lines: lines}
+	if t.provider == twinProviderAWS {
+		document = map[string]interface{}{"state": map[string]interface{}{"reported": document}}
+	}
+	payload, err := json.Marshal(document)
+	if err != nil {
+		logf(moduleBridges, levelError, "Cannot marshal twin report. Error: %s", err)
+		return
+	}
+	t.mutex.Lock()
+	topic := t.reportedTopic()
+	t.mutex.Unlock()
+	t.client.Publish(topic, 1, false, payload)
+}
+
+// onDesired applies the desired line states through the same rules as the other control APIs: lines
+// owned by the service are refused and stay as reported.
+func (t *cloudTwin) onDesired(client mqtt.Client, msg mqtt.Message) {
+	var desired twinState
+	if t.provider == twinProviderAWS {
+		var delta struct {
+			State twinState `json:"state"`
+		}
+		if err := json.Unmarshal(msg.Payload(), &delta); err != nil {
+			logf(moduleBridges, levelError, "Cannot parse shadow delta. Error: %s", err)
+			return
+		}
+		desired = delta.State
+	} else if err := json.Unmarshal(msg.Payload(), &desired); err != nil {
+		logf(moduleBridges, levelError, "Cannot parse twin desired properties. Error: %s", err)
+		return
+	}
+	for name, value := range desired.Lines {
+		if err := t.s.writeLine(name, value != 0, "twin"); err != nil {
+			logf(moduleBridges, levelError, "Cannot apply desired state of gpio %s. Error: %s", name, err)
+		}
+	}
+}
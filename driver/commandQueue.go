@@ -0,0 +1,226 @@
+package driver
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/common"
+
+	sdkModels "github.com/edgexfoundry/device-sdk-go/v2/pkg/models"
+)
+
+const (
+	executeAtAttribute   = "executeAt"
+	commandQueueResource = "CommandQueue"
+	scheduleRoute        = common.ApiBase + "/schedule"
+	scheduleCancelRoute  = common.ApiBase + "/schedule/cancel"
+	defaultMaxDrift      = time.Duration(5) * time.Second
+
+	queuedPending   = "pending"
+	queuedExecuted  = "executed"
+	queuedFailed    = "failed"
+	queuedMissed    = "missed"
+	queuedCancelled = "cancelled"
+)
+
+// QueuedCommand is an actuation to be fired at ExecuteAt. When the service cannot fire it within
+// MaxDrift of the requested time (e.g. after a clock jump or a suspend) it is dropped as missed.
+type QueuedCommand struct {
+	ID         string    `json:"id"`
+	Name       string    `json:"name"`
+	Value      int       `json:"value"`
+	ExecuteAt  time.Time `json:"executeAt"`
+	MaxDrift   string    `json:"maxDrift"`
+	State      string    `json:"state"`
+	ExecutedAt time.Time `json:"executedAt,omitempty"`
+	Result     string    `json:"result,omitempty"`
+	timer      *time.Timer
+	maxDrift   time.Duration
+}
+
+var (
+	queueMutex = sync.Mutex{}
+	queue      = make(map[string]*QueuedCommand)
+	queueSeq   = 0
+)
+
+type scheduleRequest struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Value     int    `json:"value"`
+	ExecuteAt string `json:"executeAt"`
+	MaxDrift  string `json:"maxDrift"`
+}
+
+// enqueueCommand schedules the write of value on the named line at executeAt.
+func (s *SimpleDriver) enqueueCommand(name string, value int, executeAt time.Time, maxDrift time.Duration) (*QueuedCommand, error) {
+	g, ok := s.findGpio(name)
+	if !ok {
+		return nil, fmt.Errorf("%w %s", errUnknownLine, name)
+	}
+	if !writable(g.Role) {
+		return nil, fmt.Errorf("%w: %s", errNotWritable, name)
+	}
+	if time.Until(executeAt) < -maxDrift {
+		return nil, fmt.Errorf("executeAt %s is in the past", executeAt.Format(time.RFC3339))
+	}
+
+	queueMutex.Lock()
+	queueSeq++
+	cmd := &QueuedCommand{
+		ID:        fmt.Sprintf("cmd-%d-%d", time.Now().Unix(), queueSeq),
+		Name:      name,
+		Value:     value,
+		ExecuteAt: executeAt,
+		MaxDrift:  maxDrift.String(),
+		State:     queuedPending,
+		maxDrift:  maxDrift,
+	}
+	cmd.timer = time.AfterFunc(time.Until(executeAt), func() { s.fireCommand(cmd) })
+	queue[cmd.ID] = cmd
+	queueMutex.Unlock()
+
+	audit("command-queued", name, fmt.Sprintf("%s: set to %d at %s", cmd.ID, value, executeAt.Format(time.RFC3339)))
+	s.pushCommandQueue()
+	return cmd, nil
+}
+
+func (s *SimpleDriver) fireCommand(cmd *QueuedCommand) {
+	queueMutex.Lock()
+	if cmd.State != queuedPending {
+		queueMutex.Unlock()
+		return
+	}
+	now := time.Now()
+	drift := now.Sub(cmd.ExecuteAt)
+	queueMutex.Unlock()
+
+	state, result := queuedExecuted, fmt.Sprintf("drift %s", drift)
+	if drift > cmd.maxDrift {
+		state, result = queuedMissed, fmt.Sprintf("drift %s exceeds %s", drift, cmd.maxDrift)
+	} else if err := s.writeLine(cmd.Name, cmd.Value == 1, "schedule"); err != nil {
+		state, result = queuedFailed, err.Error()
+	}
+
+	queueMutex.Lock()
+	cmd.State = state
+	cmd.ExecutedAt = now
+	cmd.Result = result
+	queueMutex.Unlock()
+	if state != queuedExecuted {
+		log.Printf("Queued command %s on gpio %s %s: %s", cmd.ID, cmd.Name, state, result)
+		audit("command-"+state, cmd.Name, cmd.ID+": "+result)
+	}
+	s.pushCommandQueue()
+}
+
+func (s *SimpleDriver) cancelCommand(id string) error {
+	queueMutex.Lock()
+	cmd, ok := queue[id]
+	if !ok {
+		queueMutex.Unlock()
+		return fmt.Errorf("unknown queued command %s", id)
+	}
+	if cmd.State != queuedPending {
+		queueMutex.Unlock()
+		return fmt.Errorf("queued command %s is %s", id, cmd.State)
+	}
+	cmd.timer.Stop()
+	cmd.State = queuedCancelled
+	queueMutex.Unlock()
+	audit("command-cancelled", cmd.Name, id)
+	s.pushCommandQueue()
+	return nil
+}
+
+// queueSnapshot returns the pending commands and the last finished ones, by execution time.
+func queueSnapshot() []QueuedCommand {
+	queueMutex.Lock()
+	defer queueMutex.Unlock()
+	list := make([]QueuedCommand, 0, len(queue))
+	for id, cmd := range queue {
+		if cmd.State != queuedPending && time.Since(cmd.ExecuteAt) > time.Duration(24)*time.Hour {
+			delete(queue, id)
+			continue
+		}
+		list = append(list, *cmd)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ExecuteAt.Before(list[j].ExecuteAt) })
+	return list
+}
+
+// pushCommandQueue publishes the queue state as the CommandQueue diagnostics reading.
+func (s *SimpleDriver) pushCommandQueue() {
+	payload, err := shapePayload(commandQueueResource, queueSnapshot())
+	if err != nil {
+		log.Printf("Cannot marshal command queue. Error: %s", err)
+		return
+	}
+	cv, err := sdkModels.NewCommandValue(commandQueueResource, common.ValueTypeString, string(payload))
+	if err != nil {
+		log.Printf("Cannot create command queue reading. Error: %s", err)
+		return
+	}
+	s.asyncCh <- &sdkModels.AsyncValues{
+		DeviceName:    deviceName(),
+		CommandValues: []*sdkModels.CommandValue{cv},
+	}
+}
+
+func (s *SimpleDriver) handleSchedule(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodGet {
+		writeJSON(w, http.StatusOK, queueSnapshot())
+		return
+	}
+	var req scheduleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	executeAt, err := time.Parse(time.RFC3339, req.ExecuteAt)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid %s %q", executeAtAttribute, req.ExecuteAt))
+		return
+	}
+	maxDrift := defaultMaxDrift
+	if req.MaxDrift != "" {
+		if maxDrift, err = time.ParseDuration(req.MaxDrift); err != nil || maxDrift < 0 {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid maxDrift %q", req.MaxDrift))
+			return
+		}
+	}
+	if req.Value != 0 && req.Value != 1 {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("value must be 0 or 1"))
+		return
+	}
+	cmd, err := s.enqueueCommand(req.Name, req.Value, executeAt, maxDrift)
+	switch {
+	case errors.Is(err, errUnknownLine):
+		writeError(w, http.StatusNotFound, err)
+	case err != nil:
+		writeError(w, http.StatusConflict, err)
+	default:
+		queueMutex.Lock()
+		defer queueMutex.Unlock()
+		writeJSON(w, http.StatusOK, cmd)
+	}
+}
+
+func (s *SimpleDriver) handleScheduleCancel(w http.ResponseWriter, r *http.Request) {
+	var req scheduleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := s.cancelCommand(req.ID); err != nil {
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"id": req.ID, "state": queuedCancelled})
+}
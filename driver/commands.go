@@ -0,0 +1,280 @@
+package driver
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/edgexfoundry/device-gpiod/gpio"
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/common"
+
+	sdkModels "github.com/edgexfoundry/device-sdk-go/v2/pkg/models"
+)
+
+const (
+	nameAttribute     = "name"
+	chipAttribute     = "chip"
+	lineAttribute     = "line"
+	pulseAttribute    = "pulse"
+	overrideAttribute = "override"
+)
+
+// commandGpio resolves the line a command request targets: the "name" attribute of the device
+// resource, as set by auto provisioning, else its "chip" and "line" attributes, else the resource
+// name itself.
+func (s *SimpleDriver) commandGpio(req sdkModels.CommandRequest) (*gpio.GPIO, bool) {
+	if value, ok := req.Attributes[nameAttribute]; ok {
+		return s.findGpio(fmt.Sprintf("%v", value))
+	}
+	chip, hasChip := req.Attributes[chipAttribute]
+	line, hasLine := req.Attributes[lineAttribute]
+	if hasChip && hasLine {
+		for i, g := range s.GpioList.Gpio {
+			if g.Chip == fmt.Sprintf("%v", chip) && fmt.Sprintf("%d", g.Line) == fmt.Sprintf("%v", line) {
+				return &s.GpioList.Gpio[i], true
+			}
+		}
+		return nil, false
+	}
+	return s.findGpio(req.DeviceResourceName)
+}
+
+// readResource reads the current value of a device resource. Lines are read as Bool, or as Int8 (0/1)
+// when the profile declares so; outputs are read back rather than reconfigured as inputs, so the
+// read does not disturb the driven state.
+func (s *SimpleDriver) readResource(req sdkModels.CommandRequest) (*sdkModels.CommandValue, error) {
+	if req.DeviceResourceName == capabilitiesResource {
+		payload, err := shapePayload(capabilitiesResource, s.capabilities())
+		if err != nil {
+			return nil, err
+		}
+		return sdkModels.NewCommandValue(req.DeviceResourceName, common.ValueTypeString, string(payload))
+	}
+	if req.DeviceResourceName == weeklyStatsResource {
+		payload, err := shapePayload(weeklyStatsResource, weeklyStats())
+		if err != nil {
+			return nil, err
+		}
+		return sdkModels.NewCommandValue(req.DeviceResourceName, common.ValueTypeString, string(payload))
+	}
+	if t, ok := findTimer(req.DeviceResourceName); ok {
+		return readTimer(t, req)
+	}
+	if req.DeviceResourceName == runtimeSinceReverseResource {
+		return readRuntimeSinceReverse(req)
+	}
+	if req.DeviceResourceName == consumablesResource {
+		return consumablesCommandValue()
+	}
+	if req.DeviceResourceName == cyclesSinceCleanResource {
+		return readCyclesSinceClean(req)
+	}
+	if isScheduleResource(req.DeviceResourceName) {
+		return readSchedule(req)
+	}
+	if req.DeviceResourceName == metricsResource {
+		payload, err := shapePayload(metricsResource, metricsSnapshot())
+		if err != nil {
+			return nil, err
+		}
+		return sdkModels.NewCommandValue(req.DeviceResourceName, common.ValueTypeString, string(payload))
+	}
+	if req.DeviceResourceName == powerModeResource {
+		return sdkModels.NewCommandValue(req.DeviceResourceName, common.ValueTypeString, powerMode())
+	}
+	if req.DeviceResourceName == healthResource {
+		return healthCommandValue()
+	}
+	if req.DeviceResourceName == deratingResource {
+		return sdkModels.NewCommandValue(req.DeviceResourceName, common.ValueTypeBool, derated())
+	}
+	if req.DeviceResourceName == loadShedResource {
+		return sdkModels.NewCommandValue(req.DeviceResourceName, common.ValueTypeInt8, int8(currentShedLevel()))
+	}
+	if req.DeviceResourceName == leaderResource {
+		return sdkModels.NewCommandValue(req.DeviceResourceName, common.ValueTypeBool, !standby())
+	}
+	if req.DeviceResourceName == reportingProfileResource {
+		profile := profileNormal
+		if metered() {
+			profile = profileMetered
+		}
+		return sdkModels.NewCommandValue(req.DeviceResourceName, common.ValueTypeString, profile)
+	}
+	if group, ok := req.Attributes[groupAttribute]; ok {
+		return s.readGroup(req, fmt.Sprintf("%v", group))
+	}
+	g, ok := s.commandGpio(req)
+	if !ok {
+		return nil, fmt.Errorf("%w %s", errUnknownLine, req.DeviceResourceName)
+	}
+	if err := checkExposure(g, "core-command"); err != nil {
+		return nil, err
+	}
+	if suffix, ok := req.Attributes[counterAttribute]; ok {
+		return counterCommandValue(g.Name, fmt.Sprintf("%v", suffix))
+	}
+	if _, ok := req.Attributes[valvePositionAttribute]; ok {
+		return valvePositionCommandValue(g.Name)
+	}
+	if g.Role == RolePwm {
+		duty, _ := g.Duty()
+		return sdkModels.NewCommandValue(req.DeviceResourceName, common.ValueTypeFloat32, float32(duty*100))
+	}
+	read := g.ReadBack
+	if !isOutputRole(g.Role) {
+		read = g.Value
+	}
+	value, err := read()
+	if err != nil {
+		return nil, fmt.Errorf("cannot read %s: %s", g.Name, err)
+	}
+	switch req.Type {
+	case common.ValueTypeInt8:
+		return sdkModels.NewCommandValue(req.DeviceResourceName, common.ValueTypeInt8, int8(value))
+	case common.ValueTypeBool, "":
+		return sdkModels.NewCommandValue(req.DeviceResourceName, common.ValueTypeBool, value == 1)
+	}
+	return nil, fmt.Errorf("unsupported value type %s for %s", req.Type, req.DeviceResourceName)
+}
+
+func lineLevel(on bool) int {
+	if on {
+		return 1
+	}
+	return 0
+}
+
+// commandLevel converts the value of a write command to a line level: Bool, or an integer where any
+// non-zero value is on.
+func commandLevel(param *sdkModels.CommandValue) (bool, error) {
+	switch param.Type {
+	case common.ValueTypeBool:
+		return param.BoolValue()
+	case common.ValueTypeInt8:
+		value, err := param.Int8Value()
+		return value != 0, err
+	}
+	return false, fmt.Errorf("unsupported value type %s for %s", param.Type, param.DeviceResourceName)
+}
+
+// commandDuty converts the value of a write command to a pwm line to a duty cycle percentage.
+func commandDuty(param *sdkModels.CommandValue) (float64, error) {
+	var duty float64
+	switch param.Type {
+	case common.ValueTypeFloat32:
+		value, err := param.Float32Value()
+		if err != nil {
+			return 0, err
+		}
+		duty = float64(value)
+	case common.ValueTypeFloat64:
+		value, err := param.Float64Value()
+		if err != nil {
+			return 0, err
+		}
+		duty = value
+	case common.ValueTypeUint8:
+		value, err := param.Uint8Value()
+		if err != nil {
+			return 0, err
+		}
+		duty = float64(value)
+	default:
+		return 0, fmt.Errorf("unsupported value type %s for %s", param.Type, param.DeviceResourceName)
+	}
+	if duty < 0 || duty > 100 {
+		return 0, fmt.Errorf("duty cycle %.1f%% out of range [0, 100]", duty)
+	}
+	return duty, nil
+}
+
+// writeResource actuates the line of a device resource. Pwm lines take a duty cycle from 0 to 100%. The
+// attributes of the resource select how:
+//
+//	pulse         drive the line for the given duration, then back (e.g. "500ms")
+//	override      force the line for the given duration, as POST /api/v2/override
+//	executeAt     queue the write for an RFC3339 time
+//	rampDuration  ramp a pwm line to the written duty over the given duration, with rampCurve
+//	sync, timeout, verify, idempotencyKey  as for the REST routes
+func (s *SimpleDriver) writeResource(req sdkModels.CommandRequest, param *sdkModels.CommandValue) error {
+	if t, ok := findTimer(req.DeviceResourceName); ok {
+		return writeTimer(t, req, param)
+	}
+	if req.DeviceResourceName == runtimeSinceReverseResource {
+		return writeRuntimeSinceReverse(param)
+	}
+	if req.DeviceResourceName == scheduleSkipResource {
+		return writeScheduleSkip(param)
+	}
+	if group, ok := req.Attributes[groupAttribute]; ok {
+		on, err := param.BoolArrayValue()
+		if err != nil {
+			return err
+		}
+		return s.writeGroup(fmt.Sprintf("%v", group), on, "core-command")
+	}
+	g, ok := s.commandGpio(req)
+	if !ok {
+		return fmt.Errorf("%w %s", errUnknownLine, req.DeviceResourceName)
+	}
+	if err := checkExposure(g, "core-command"); err != nil {
+		return err
+	}
+	mode, err := parseWriteMode(req.Attributes)
+	if err != nil {
+		return err
+	}
+	key := ""
+	if value, ok := req.Attributes[idempotencyKeyAttribute]; ok {
+		key = fmt.Sprintf("core-command %v", value)
+	}
+
+	_, err = idempotent(key, func() (interface{}, error) {
+		if g.Role == RolePwm {
+			duty, err := commandDuty(param)
+			if err != nil {
+				return nil, err
+			}
+			if value, ok := req.Attributes[rampDurationAttribute]; ok {
+				duration, err := time.ParseDuration(fmt.Sprintf("%v", value))
+				if err != nil || duration < 0 {
+					return nil, fmt.Errorf("invalid %s attribute %v", rampDurationAttribute, value)
+				}
+				curve, _ := req.Attributes[rampCurveAttribute].(string)
+				return s.startRamp(g.Name, duty/100, duration, curve)
+			}
+			return nil, s.setDuty(g.Name, duty/100, "core-command")
+		}
+
+		on, err := commandLevel(param)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := s.writableGpio(g.Name); err != nil {
+			return nil, err
+		}
+		if value, ok := req.Attributes[executeAtAttribute]; ok {
+			executeAt, err := time.Parse(time.RFC3339, fmt.Sprintf("%v", value))
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s attribute %v", executeAtAttribute, value)
+			}
+			return s.enqueueCommand(g.Name, lineLevel(on), executeAt, defaultMaxDrift)
+		}
+		if value, ok := req.Attributes[overrideAttribute]; ok {
+			duration, err := time.ParseDuration(fmt.Sprintf("%v", value))
+			if err != nil {
+				return nil, fmt.Errorf("%w: %s attribute %v", errOverrideDuration, overrideAttribute, value)
+			}
+			return s.overrideLine(g, lineLevel(on), duration, "core-command", "core-command")
+		}
+		if value, ok := req.Attributes[pulseAttribute]; ok {
+			duration, err := time.ParseDuration(fmt.Sprintf("%v", value))
+			if err != nil || duration <= 0 {
+				return nil, fmt.Errorf("invalid %s attribute %v", pulseAttribute, value)
+			}
+			return nil, mode.execute(g, lineLevel(!on), func() error { return s.pulseLine(g.Name, on, duration, "core-command") })
+		}
+		return nil, mode.execute(g, lineLevel(on), func() error { return s.writeLine(g.Name, on, "core-command") })
+	})
+	return err
+}
@@ -0,0 +1,337 @@
+package driver
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/edgexfoundry/device-gpiod/gpio"
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/common"
+)
+
+const (
+	concurrencyRoute = common.ApiBase + "/concurrency"
+
+	concurrencyFifo       = "fifo"
+	concurrencyPriority   = "priority"
+	concurrencyRoundRobin = "round_robin"
+
+	// Period of the scheduler granting the queued starts as running lines go off
+	concurrencyTick = time.Second
+
+	// Source of the starts of the cycle pipeline, which asks again for its queued starts itself
+	pipelineSource = "pipeline"
+)
+
+var errConcurrencyLimit = errors.New("concurrency limit reached")
+
+// ConcurrencyLimit caps the lines of a set on at the same time, across the lines of the service and
+// the devices onboarded with the gpio protocol, e.g. one pump of several skids running at a time to
+// respect the supply capacity. A start beyond Max is refused; with MaxWait it is queued and started
+// when a line of the set goes off, in the order of Policy: fifo, priority (Priorities, highest first,
+// then fifo) or round_robin (the line started least recently first). A queued start not granted
+// within MaxWait is dropped.
+type ConcurrencyLimit struct {
+	Name       string         `yaml:"name"`
+	Lines      []string       `yaml:"lines"`
+	Max        int            `yaml:"max"`
+	Policy     string         `yaml:"policy"`
+	Priorities map[string]int `yaml:"priorities"`
+	MaxWait    string         `yaml:"max_wait"`
+	maxWait    time.Duration
+}
+
+// QueuedStart is a start waiting for a slot of a concurrency limit.
+type QueuedStart struct {
+	Line     string    `json:"line"`
+	Source   string    `json:"source"`
+	Priority int       `json:"priority"`
+	Queued   time.Time `json:"queued"`
+	Expires  time.Time `json:"expires"`
+}
+
+type concurrencyState struct {
+	queue     []QueuedStart
+	lastStart map[string]time.Time
+	granted   int
+	expired   int
+	refused   int
+}
+
+var (
+	// concurrencyMutex serializes the admission and the write of the lines of concurrency limits, so
+	// concurrent starts do not exceed them
+	concurrencyMutex  = sync.Mutex{}
+	concurrencyStates = make(map[string]*concurrencyState)
+)
+
+// validateConcurrency checks the concurrency section of the configuration file.
+func validateConcurrency() error {
+	names := make(map[string]bool)
+	for i := range driverConfig.Concurrency {
+		l := &driverConfig.Concurrency[i]
+		if l.Name == "" || names[l.Name] {
+			return fmt.Errorf("limit %d: empty or repeated name %q", i, l.Name)
+		}
+		names[l.Name] = true
+		if len(l.Lines) < 2 {
+			return fmt.Errorf("limit %s: at least two lines are required", l.Name)
+		}
+		if l.Max == 0 {
+			l.Max = 1
+		}
+		if l.Max < 0 || l.Max >= len(l.Lines) {
+			return fmt.Errorf("limit %s: max must be between 1 and the number of lines minus one", l.Name)
+		}
+		switch l.Policy {
+		case "":
+			l.Policy = concurrencyFifo
+		case concurrencyFifo, concurrencyPriority, concurrencyRoundRobin:
+		default:
+			return fmt.Errorf("limit %s: unknown policy %q", l.Name, l.Policy)
+		}
+		for line := range l.Priorities {
+			if !containsString(l.Lines, line) {
+				return fmt.Errorf("limit %s: priority of %s, not a line of the limit", l.Name, line)
+			}
+		}
+		l.maxWait = 0
+		if l.MaxWait != "" {
+			wait, err := time.ParseDuration(l.MaxWait)
+			if err != nil || wait < 0 {
+				return fmt.Errorf("limit %s: invalid max_wait %q", l.Name, l.MaxWait)
+			}
+			l.maxWait = wait
+		}
+	}
+	return nil
+}
+
+// concurrencyLimitsOf returns the concurrency limits of the named line.
+func concurrencyLimitsOf(name string) []ConcurrencyLimit {
+	var limits []ConcurrencyLimit
+	for _, l := range driverConfig.Concurrency {
+		if containsString(l.Lines, name) {
+			limits = append(limits, l)
+		}
+	}
+	return limits
+}
+
+func stateOf(l ConcurrencyLimit) *concurrencyState {
+	state, ok := concurrencyStates[l.Name]
+	if !ok {
+		state = &concurrencyState{lastStart: make(map[string]time.Time)}
+		concurrencyStates[l.Name] = state
+	}
+	return state
+}
+
+// concurrentLine finds a line of a concurrency limit, of the service or of a device.
+func (s *SimpleDriver) concurrentLine(name string) (*gpio.GPIO, bool) {
+	if g, ok := s.findGpio(name); ok {
+		return g, true
+	}
+	if d, ok := findDeviceLine(name); ok {
+		return d.line, true
+	}
+	return nil, false
+}
+
+// runningLines returns the lines of the limit on, but the named one.
+func (s *SimpleDriver) runningLines(l ConcurrencyLimit, but string) []string {
+	var running []string
+	for _, name := range l.Lines {
+		g, ok := s.concurrentLine(name)
+		if !ok || name == but {
+			continue
+		}
+		on := g.State
+		if value, err := g.ReadBack(); err == nil {
+			on = value == 1
+		}
+		if on {
+			running = append(running, name)
+		}
+	}
+	return running
+}
+
+// admitConcurrent lets the named line start when every limit it is part of has a free slot and no
+// other line queued before it. Otherwise the start is refused and, for the limits with a max_wait,
+// queued. Must be called holding concurrencyMutex.
+func (s *SimpleDriver) admitConcurrent(name string, source string) error {
+	limits := concurrencyLimitsOf(name)
+	for _, l := range limits {
+		state := stateOf(l)
+		running := s.runningLines(l, name)
+		next, queued := nextQueued(l, state)
+		if len(running) < l.Max && (!queued || next.Line == name) {
+			continue
+		}
+		state.refused++
+		if l.maxWait == 0 {
+			return fmt.Errorf("%w: %s, running %v", errConcurrencyLimit, l.Name, running)
+		}
+		position := enqueueStart(l, state, name, source)
+		return fmt.Errorf("%w: %s, running %v, queued at position %d", errConcurrencyLimit, l.Name, running, position)
+	}
+	now := time.Now()
+	for _, l := range limits {
+		state := stateOf(l)
+		state.lastStart[name] = now
+		for i, q := range state.queue {
+			if q.Line == name {
+				state.queue = append(state.queue[:i], state.queue[i+1:]...)
+				state.granted++
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// enqueueStart queues a start, once per line, and returns its position in the order of the policy.
+func enqueueStart(l ConcurrencyLimit, state *concurrencyState, name string, source string) int {
+	found := false
+	for _, q := range state.queue {
+		found = found || q.Line == name
+	}
+	if !found {
+		now := time.Now()
+		state.queue = append(state.queue, QueuedStart{
+			Line:     name,
+			Source:   source,
+			Priority: l.Priorities[name],
+			Queued:   now,
+			Expires:  now.Add(l.maxWait),
+		})
+		audit("concurrency-queued", name, fmt.Sprintf("%s, %s", l.Name, source))
+	}
+	for i, q := range orderedQueue(l, state) {
+		if q.Line == name {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// orderedQueue returns the queue of a limit in the order its starts are granted.
+func orderedQueue(l ConcurrencyLimit, state *concurrencyState) []QueuedStart {
+	queue := append([]QueuedStart(nil), state.queue...)
+	switch l.Policy {
+	case concurrencyPriority:
+		sort.SliceStable(queue, func(i, j int) bool { return queue[i].Priority > queue[j].Priority })
+	case concurrencyRoundRobin:
+		sort.SliceStable(queue, func(i, j int) bool {
+			return state.lastStart[queue[i].Line].Before(state.lastStart[queue[j].Line])
+		})
+	}
+	return queue
+}
+
+func nextQueued(l ConcurrencyLimit, state *concurrencyState) (QueuedStart, bool) {
+	queue := orderedQueue(l, state)
+	if len(queue) == 0 {
+		return QueuedStart{}, false
+	}
+	return queue[0], true
+}
+
+// startConcurrencyScheduler grants the queued starts every second: the expired ones are dropped and
+// the next of every limit with a free slot is started, through the path of its original write.
+func (s *SimpleDriver) startConcurrencyScheduler() {
+	if len(driverConfig.Concurrency) == 0 {
+		return
+	}
+	goBackground(func() {
+		for {
+			supervisedSleep("concurrency", concurrencyTick)
+			for _, start := range s.grantableStarts() {
+				if err := s.startQueued(start); err != nil && !errors.Is(err, errConcurrencyLimit) {
+					logf(moduleStateMachine, levelWarn, "Queued start of %s dropped. Error: %s", start.Line, err)
+					s.dropQueued(start.Line, fmt.Sprintf("start failed: %s", err))
+				}
+			}
+		}
+	})
+}
+
+// grantableStarts drops the expired starts and returns the next start of the limits with a free slot.
+func (s *SimpleDriver) grantableStarts() []QueuedStart {
+	concurrencyMutex.Lock()
+	defer concurrencyMutex.Unlock()
+	now := time.Now()
+	var starts []QueuedStart
+	for _, l := range driverConfig.Concurrency {
+		state := stateOf(l)
+		kept := state.queue[:0]
+		for _, q := range state.queue {
+			if now.After(q.Expires) {
+				state.expired++
+				audit("concurrency-expired", q.Line, fmt.Sprintf("%s, not started within %s", l.Name, l.MaxWait))
+				continue
+			}
+			kept = append(kept, q)
+		}
+		state.queue = kept
+		next, ok := nextQueued(l, state)
+		if ok && len(s.runningLines(l, next.Line)) < l.Max {
+			starts = append(starts, next)
+		}
+	}
+	return starts
+}
+
+// startQueued turns on the line of a granted start. A start of the pipeline is left queued, the
+// pipeline starting the line on its next attempt so the cycle runs from its own loop.
+func (s *SimpleDriver) startQueued(start QueuedStart) error {
+	if start.Source == pipelineSource {
+		return nil
+	}
+	if d, ok := findDeviceLine(start.Line); ok {
+		return s.driveDeviceLine(d, true)
+	}
+	return s.writeLine(start.Line, true, start.Source)
+}
+
+// dropQueued removes the starts of a line from every queue.
+func (s *SimpleDriver) dropQueued(name string, reason string) {
+	concurrencyMutex.Lock()
+	defer concurrencyMutex.Unlock()
+	for _, l := range driverConfig.Concurrency {
+		state := stateOf(l)
+		for i, q := range state.queue {
+			if q.Line == name {
+				state.queue = append(state.queue[:i], state.queue[i+1:]...)
+				audit("concurrency-dropped", name, fmt.Sprintf("%s, %s", l.Name, reason))
+				break
+			}
+		}
+	}
+}
+
+// handleConcurrency returns the lines running and the queue of every concurrency limit.
+func (s *SimpleDriver) handleConcurrency(w http.ResponseWriter, r *http.Request) {
+	concurrencyMutex.Lock()
+	defer concurrencyMutex.Unlock()
+	limits := []map[string]interface{}{}
+	for _, l := range driverConfig.Concurrency {
+		state := stateOf(l)
+		limits = append(limits, map[string]interface{}{
+			"name":    l.Name,
+			"max":     l.Max,
+			"policy":  l.Policy,
+			"maxWait": l.MaxWait,
+			"running": s.runningLines(l, ""),
+			"queue":   orderedQueue(l, state),
+			"granted": state.granted,
+			"expired": state.expired,
+			"refused": state.refused,
+		})
+	}
+	writeJSON(w, http.StatusOK, limits)
+}
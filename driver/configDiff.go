@@ -0,0 +1,118 @@
+package driver
+
+import (
+	"log"
+
+	"github.com/edgexfoundry/device-gpiod/gpio"
+)
+
+// ApplyReport describes how a reloaded gpio list was applied: unchanged lines keep their request, only
+// the delta is re-requested.
+type ApplyReport struct {
+	gpio.ListDiff
+	Disturbed       []string `json:"disturbed"`
+	RestartRequired []string `json:"restartRequired"`
+}
+
+// watchHandler returns the event handler of the lines held by a watch, nil for the others.
+func (s *SimpleDriver) watchHandler(role string) func(gpio.Event) {
+	switch role {
+	case RoleSpare:
+		return s.handleSpareEvent
+	case RoleTamper:
+		return s.handleTamperEvent
+	case RolePowerFail:
+		return s.handlePowerFailEvent
+	case RoleFeedback:
+		return s.handleFeedbackEvent
+	case RoleLockout:
+		return s.handleLockoutEvent
+	case RoleInput:
+		return s.handleInputEvent
+	}
+	return nil
+}
+
+// pinnedAtStartup reports whether the line is bound once at startup (triggers, heartbeat, watchdog),
+// in which case a change only takes effect after a restart.
+func pinnedAtStartup(g *gpio.GPIO) bool {
+	if g.Role == RoleHeartbeat || g.Role == RoleWatchdog {
+		return true
+	}
+	for _, role := range []string{"START_TRIGGER", "STANDBY_TRIGGER", "REVERSE_TRIGGER", "CLEAN_TRIGGER"} {
+		if g.Name == triggerLine(role) {
+			return true
+		}
+	}
+	return false
+}
+
+// applyGpioList makes next the active gpio list. Unchanged watched lines are adopted as they are,
+// removed or changed ones are released and the added or changed ones requested again. Running cycles
+// work on their own copy of the lines and are not affected.
+func (s *SimpleDriver) applyGpioList(next *gpio.GPIOList) ApplyReport {
+	report := ApplyReport{ListDiff: gpio.Diff(s.GpioList, next)}
+	previous := make(map[string]*gpio.GPIO)
+	for i := range s.GpioList.Gpio {
+		previous[s.GpioList.Gpio[i].Name] = &s.GpioList.Gpio[i]
+	}
+	unchanged := make(map[string]bool)
+	for _, name := range report.Unchanged {
+		unchanged[name] = true
+	}
+
+	for name, old := range previous {
+		if unchanged[name] {
+			continue
+		}
+		if pinnedAtStartup(old) {
+			report.RestartRequired = append(report.RestartRequired, name)
+		}
+		if s.watchHandler(old.Role) == nil {
+			// Held outputs are requested again with the new settings on their next write
+			if old.Held() {
+				if err := old.Release(); err != nil {
+					log.Printf("Cannot release gpio %s. Error: %s", name, err)
+				}
+				report.Disturbed = append(report.Disturbed, name)
+			}
+			continue
+		}
+		if err := old.Unwatch(); err != nil {
+			log.Printf("Cannot release gpio %s. Error: %s", name, err)
+		}
+		report.Disturbed = append(report.Disturbed, name)
+	}
+
+	for i := range next.Gpio {
+		g := &next.Gpio[i]
+		if unchanged[g.Name] {
+			g.Adopt(previous[g.Name])
+			continue
+		}
+		if _, ok := previous[g.Name]; !ok && pinnedAtStartup(g) {
+			report.RestartRequired = append(report.RestartRequired, g.Name)
+		}
+		handler := s.watchHandler(g.Role)
+		if handler == nil {
+			continue
+		}
+		if g.Role == RoleTamper {
+			alarmsMutex.Lock()
+			if _, ok := alarms[g.Name]; !ok {
+				alarms[g.Name] = &SecurityAlarm{Name: g.Name, Severity: severityHigh}
+			}
+			alarmsMutex.Unlock()
+		}
+		if err := g.Watch(handler); err != nil {
+			log.Printf("Cannot monitor %s gpio %s. Error: %s", g.Role, g.Name, err)
+			continue
+		}
+		if _, ok := previous[g.Name]; !ok {
+			report.Disturbed = append(report.Disturbed, g.Name)
+		}
+	}
+
+	s.GpioList = next
+	return report
+}
@@ -0,0 +1,158 @@
+package driver
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/edgexfoundry/device-gpiod/gpio"
+)
+
+// antonyms are the name parts telling apart the two lines of a pair that should never be on together,
+// such as valve_open and valve_close.
+var antonyms = map[string]string{
+	"open": "close", "close": "open",
+	"up": "down", "down": "up",
+	"forward": "reverse", "reverse": "forward",
+	"fwd": "rev", "rev": "fwd",
+	"extend": "retract", "retract": "extend",
+	"raise": "lower", "lower": "raise",
+	"in": "out", "out": "in",
+	"left": "right", "right": "left",
+}
+
+// lintConfig returns the warnings about risky setups that pass validation: driven lines without an
+// explicit safe state, no interlock at all, lines named like a pair of opposite motions and not
+// declared exclusive, and mechanical contacts (inputs, lockout and tamper switches, limit switches)
+// without debounce. Must be called with the driver configuration applied.
+func lintConfig(list *gpio.GPIOList) []string {
+	var warnings []string
+	known := make(map[string]bool)
+	switches := make(map[string]bool)
+	interlocked := os.Getenv("LOCKOUT_FILE") != "" || driverConfig.TwoPerson != nil || len(driverConfig.Exclusive) > 0
+	for _, g := range list.Gpio {
+		known[g.Name] = true
+		if g.OpenSwitch != "" {
+			switches[g.OpenSwitch], switches[g.ClosedSwitch] = true, true
+			interlocked = true
+		}
+		if g.Role == RoleLockout {
+			interlocked = true
+		}
+	}
+
+	var outputs []string
+	for _, g := range list.Gpio {
+		if isOutputRole(g.Role) && g.Role != RoleHeartbeat && g.Role != RoleWatchdog {
+			outputs = append(outputs, g.Name)
+			if g.SafeState == "" {
+				warnings = append(warnings, fmt.Sprintf("gpio %s: no safe_state, driven low on faults and shutdown", g.Name))
+			}
+		}
+		mechanical := (g.Role == RoleInput && g.Mode == "") || g.Role == RoleLockout || g.Role == RoleTamper || switches[g.Name]
+		if mechanical && g.Debounce == "" && g.SoftDebounce == "" {
+			warnings = append(warnings, fmt.Sprintf("gpio %s: mechanical contact without debounce or soft_debounce", g.Name))
+		}
+	}
+	if !interlocked {
+		warnings = append(warnings, "no interlock configured: no lockout line or LOCKOUT_FILE, limit switches, exclusive sets or two_person rule")
+	}
+
+	for i, a := range outputs {
+		for _, b := range outputs[i+1:] {
+			if opposite(a, b) && !declaredExclusive(a, b) {
+				warnings = append(warnings, fmt.Sprintf("gpio %s and %s look like a pair of opposite motions but are not declared exclusive", a, b))
+			}
+		}
+	}
+	for i, set := range driverConfig.Exclusive {
+		for _, name := range set {
+			if !known[name] {
+				warnings = append(warnings, fmt.Sprintf("exclusive set %d: unknown gpio %s", i, name))
+			}
+		}
+	}
+	return warnings
+}
+
+// opposite reports whether the names differ only by a pair of antonyms, e.g. valve1_open and valve1_close.
+func opposite(a string, b string) bool {
+	split := func(name string) []string {
+		return strings.FieldsFunc(strings.ToLower(name), func(r rune) bool { return r == '_' || r == '-' || r == '.' || r == ' ' })
+	}
+	partsA, partsB := split(a), split(b)
+	if len(partsA) != len(partsB) {
+		return false
+	}
+	differ := 0
+	for i := range partsA {
+		if partsA[i] == partsB[i] {
+			continue
+		}
+		if antonyms[partsA[i]] != partsB[i] {
+			return false
+		}
+		differ++
+	}
+	return differ == 1
+}
+
+func declaredExclusive(a string, b string) bool {
+	for _, partner := range exclusivePartners(a) {
+		if partner == b {
+			return true
+		}
+	}
+	return false
+}
+
+// lintWarnings reports the lint warnings at startup as configuration warnings.
+func (s *SimpleDriver) lintWarnings() {
+	for _, warning := range lintConfig(s.GpioList) {
+		configWarning("lint: " + warning)
+	}
+}
+
+// RunValidateConfig is the validate-config subcommand: it validates the configuration file like the
+// service does at startup, then prints the lint warnings. It returns 1 when the configuration is
+// invalid, or with -strict when there are warnings, so it can gate a deployment.
+func RunValidateConfig(args []string) int {
+	flags := flag.NewFlagSet("validate-config", flag.ContinueOnError)
+	file := flags.String("file", os.Getenv("GPIO_CONFIG_FILE"), "Gpio configuration file to validate")
+	strict := flags.Bool("strict", false, "Fail on lint warnings too")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *file == "" {
+		fmt.Fprintln(os.Stderr, "validate-config: no configuration file, set -file or GPIO_CONFIG_FILE")
+		return 2
+	}
+
+	list := &gpio.GPIOList{}
+	cfg := &DriverConfig{}
+	err := verifyConfigFile(*file)
+	if err == nil {
+		err = parseDriverConfig(*file, cfg)
+	}
+	if err == nil {
+		err = list.Parse(*file, false)
+	}
+	if err == nil {
+		err = applyDriverConfig(cfg)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "validate-config: %s: %s\n", *file, err)
+		return 1
+	}
+
+	warnings := lintConfig(list)
+	for _, warning := range warnings {
+		fmt.Printf("warning: %s\n", warning)
+	}
+	fmt.Printf("%s: valid, %d lines, %d warnings\n", *file, len(list.Gpio), len(warnings))
+	if *strict && len(warnings) > 0 {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,208 @@
+package driver
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/edgexfoundry/device-gpiod/gpio"
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/common"
+)
+
+const (
+	configReloadRoute  = common.ApiBase + "/config/reload"
+	configConfirmRoute = common.ApiBase + "/config/confirm"
+	configStatusRoute  = common.ApiBase + "/config/status"
+)
+
+var (
+	rolloutMutex   = sync.Mutex{}
+	confirmWindow  = time.Duration(2) * time.Minute
+	knownGoodBytes []byte
+	staged         *stagedConfig
+)
+
+// stagedConfig is a configuration applied but not yet confirmed. Unless it is confirmed, or the service
+// is healthy when the confirmation window expires, rollback restores the previous known-good config.
+type stagedConfig struct {
+	Source    string    `json:"source"`
+	AppliedAt time.Time `json:"appliedAt"`
+	Deadline  time.Time `json:"deadline"`
+	rollback  func()
+	timer     *time.Timer
+}
+
+// loadKnownGoodConfig remembers the configuration file the service started with as the first known-good
+// configuration, and reads the confirmation window from CONFIG_CONFIRM_WINDOW.
+func loadKnownGoodConfig() {
+	if d, err := time.ParseDuration(os.Getenv("CONFIG_CONFIRM_WINDOW")); err == nil && d > 0 {
+		confirmWindow = d
+	} else {
+		log.Printf("Cannot parse CONFIG_CONFIRM_WINDOW. Picking default value %s...", confirmWindow)
+	}
+	data, err := os.ReadFile(os.Getenv("GPIO_CONFIG_FILE"))
+	if err != nil {
+		log.Printf("Cannot read known-good configuration. Error: %s", err)
+		return
+	}
+	knownGoodBytes = data
+}
+
+// stagedPending returns an error while a staged configuration waits for confirmation.
+func stagedPending() error {
+	rolloutMutex.Lock()
+	defer rolloutMutex.Unlock()
+	if staged != nil {
+		return fmt.Errorf("a configuration from %s is already staged until %s", staged.Source, staged.Deadline.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// stageConfig marks a freshly applied configuration as staged. rollback is called to restore the
+// previous configuration if the staged one is not confirmed in time.
+func stageConfig(source string, rollback func()) error {
+	if err := stagedPending(); err != nil {
+		return err
+	}
+	rolloutMutex.Lock()
+	defer rolloutMutex.Unlock()
+	now := time.Now()
+	staged = &stagedConfig{
+		Source:    source,
+		AppliedAt: now,
+		Deadline:  now.Add(confirmWindow),
+		rollback:  rollback,
+	}
+	staged.timer = time.AfterFunc(confirmWindow, expireStagedConfig)
+	audit("config-staged", source, fmt.Sprintf("confirmation window %s", confirmWindow))
+	return nil
+}
+
+// expireStagedConfig commits the staged configuration if the service reached a healthy state,
+// otherwise it rolls back to the previous known-good configuration.
+func expireStagedConfig() {
+	rolloutMutex.Lock()
+	defer rolloutMutex.Unlock()
+	if staged == nil {
+		return
+	}
+	if serviceHealthy() {
+		commitStagedConfig("healthy at the end of the confirmation window")
+		return
+	}
+	log.Printf("WARNING: staged configuration from %s not confirmed and service unhealthy (faults: %v, stalled: %v), rolling back",
+		staged.Source, activeFaults(), stalledGoroutines())
+	staged.rollback()
+	audit("config-rollback", staged.Source, "service unhealthy at the end of the confirmation window")
+	staged = nil
+}
+
+// commitStagedConfig must be called holding rolloutMutex.
+func commitStagedConfig(reason string) {
+	staged.timer.Stop()
+	if staged.Source == "file" {
+		if data, err := os.ReadFile(os.Getenv("GPIO_CONFIG_FILE")); err == nil {
+			knownGoodBytes = data
+		}
+	}
+	audit("config-committed", staged.Source, reason)
+	staged = nil
+}
+
+// reloadConfigFile parses GPIO_CONFIG_FILE again and applies it as a staged configuration, touching
+// only the lines that changed.
+func (s *SimpleDriver) reloadConfigFile() (ApplyReport, error) {
+	if err := stagedPending(); err != nil {
+		return ApplyReport{}, err
+	}
+	fileName := os.Getenv("GPIO_CONFIG_FILE")
+	if err := verifyConfigFile(fileName); err != nil {
+		return ApplyReport{}, err
+	}
+	gpioList := &gpio.GPIOList{}
+	if err := gpioList.Parse(fileName, false); err != nil {
+		return ApplyReport{}, fmt.Errorf("invalid gpio configuration: %s", err)
+	}
+	if err := gpioList.ResolveLineNames(); err != nil {
+		return ApplyReport{}, fmt.Errorf("invalid gpio configuration: %s", err)
+	}
+	cfg := &DriverConfig{}
+	if err := parseDriverConfig(fileName, cfg); err != nil {
+		return ApplyReport{}, fmt.Errorf("invalid driver configuration: %s", err)
+	}
+
+	previousList, previousConfig := s.GpioList, driverConfig
+	if err := applyDriverConfig(cfg); err != nil {
+		if err := applyDriverConfig(previousConfig); err != nil {
+			log.Printf("Cannot restore previous driver configuration. Error: %s", err)
+		}
+		return ApplyReport{}, err
+	}
+	report := s.applyGpioList(gpioList)
+	s.startStatistics()
+	restore := func() {
+		s.applyGpioList(previousList)
+		if err := applyDriverConfig(previousConfig); err != nil {
+			log.Printf("Cannot restore previous driver configuration. Error: %s", err)
+		}
+		s.startStatistics()
+	}
+
+	rollback := func() {
+		restore()
+		if knownGoodBytes == nil {
+			return
+		}
+		trustConfig(knownGoodBytes)
+		if err := os.WriteFile(fileName, knownGoodBytes, 0644); err != nil {
+			log.Printf("Cannot restore known-good configuration file. Error: %s", err)
+		}
+	}
+	if err := stageConfig("file", rollback); err != nil {
+		restore()
+		return ApplyReport{}, err
+	}
+	audit("config-applied", "file", fmt.Sprintf("added %v, removed %v, changed %v, disturbed %v",
+		report.Added, report.Removed, report.Changed, report.Disturbed))
+	publishSystemEvent(systemEventTypeConfig, systemEventActionReload, report)
+	return report, nil
+}
+
+func (s *SimpleDriver) handleConfigReload(w http.ResponseWriter, r *http.Request) {
+	report, err := s.reloadConfigFile()
+	if err != nil {
+		audit("config-rejected", "file", err.Error())
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+	rolloutMutex.Lock()
+	defer rolloutMutex.Unlock()
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"staged": staged,
+		"apply":  report,
+	})
+}
+
+func (s *SimpleDriver) handleConfigConfirm(w http.ResponseWriter, r *http.Request) {
+	rolloutMutex.Lock()
+	defer rolloutMutex.Unlock()
+	if staged == nil {
+		writeError(w, http.StatusNotFound, errors.New("no staged configuration to confirm"))
+		return
+	}
+	commitStagedConfig("confirmed by operator")
+	writeJSON(w, http.StatusOK, map[string]interface{}{"staged": nil})
+}
+
+func (s *SimpleDriver) handleConfigStatus(w http.ResponseWriter, r *http.Request) {
+	rolloutMutex.Lock()
+	defer rolloutMutex.Unlock()
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"staged":        staged,
+		"confirmWindow": confirmWindow.String(),
+	})
+}
@@ -0,0 +1,106 @@
+package driver
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+const signatureSuffix = ".sig"
+
+var (
+	errUnsignedConfig = errors.New("configuration signature verification failed")
+
+	signatureMutex = sync.Mutex{}
+	configKeys     []ed25519.PublicKey
+	configKeysRead bool
+	// trustedConfigs are the digests of configurations written by the service itself from verified ones,
+	// the fleet overlay and the restored known-good configuration
+	trustedConfigs = make(map[[sha256.Size]byte]bool)
+)
+
+// loadConfigKeys reads CONFIG_PUBLIC_KEYS, the base64 ed25519 public keys, comma separated, allowed to
+// sign the configuration files, or a file holding them one per line. Unset, configurations are not
+// verified.
+func loadConfigKeys() ([]ed25519.PublicKey, error) {
+	signatureMutex.Lock()
+	defer signatureMutex.Unlock()
+	if configKeysRead {
+		return configKeys, nil
+	}
+	value := os.Getenv("CONFIG_PUBLIC_KEYS")
+	if data, err := os.ReadFile(value); err == nil {
+		value = strings.Join(strings.Fields(string(data)), ",")
+	}
+	var keys []ed25519.PublicKey
+	for _, encoded := range strings.Split(value, ",") {
+		if encoded = strings.TrimSpace(encoded); encoded == "" {
+			continue
+		}
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil || len(key) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid ed25519 public key %q in CONFIG_PUBLIC_KEYS", encoded)
+		}
+		keys = append(keys, ed25519.PublicKey(key))
+	}
+	configKeys, configKeysRead = keys, true
+	return keys, nil
+}
+
+// verifyConfig checks that data, the content of the configuration read from source, is signed by one
+// of the configured keys: signature is the base64 ed25519 signature of the content, published next to
+// it with the .sig suffix. Every configuration passes when no key is configured.
+func verifyConfig(source string, data []byte, signature []byte) error {
+	keys, err := loadConfigKeys()
+	if err != nil || len(keys) == 0 {
+		return err
+	}
+	signatureMutex.Lock()
+	trusted := trustedConfigs[sha256.Sum256(data)]
+	signatureMutex.Unlock()
+	if trusted {
+		return nil
+	}
+	if signature == nil {
+		return fmt.Errorf("%w: %s is not signed", errUnsignedConfig, source)
+	}
+	decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(signature)))
+	if err != nil {
+		return fmt.Errorf("%w: invalid signature of %s", errUnsignedConfig, source)
+	}
+	for _, key := range keys {
+		if ed25519.Verify(key, data, decoded) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s is not signed by a trusted key", errUnsignedConfig, source)
+}
+
+// verifyConfigFile verifies the configuration file against its .sig file, see verifyConfig.
+func verifyConfigFile(fileName string) error {
+	if fileName == "" {
+		return nil
+	}
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		// Reported by the parser
+		return nil
+	}
+	signature, err := os.ReadFile(fileName + signatureSuffix)
+	if err != nil {
+		signature = nil
+	}
+	return verifyConfig(fileName, data, signature)
+}
+
+// trustConfig accepts data, written by the service from verified configurations, without signature.
+func trustConfig(data []byte) {
+	signatureMutex.Lock()
+	defer signatureMutex.Unlock()
+	trustedConfigs[sha256.Sum256(data)] = true
+}
@@ -0,0 +1,238 @@
+package driver
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/common"
+
+	sdkModels "github.com/edgexfoundry/device-sdk-go/v2/pkg/models"
+)
+
+const (
+	consumablesResource    = "Consumables"
+	consumablesRoute       = common.ApiBase + "/consumables"
+	consumablesRefillRoute = common.ApiBase + "/consumables/refill"
+)
+
+// Consumable is a cleaning agent tank. Its level is estimated from the rate of the clean stages
+// using it, in the unit of capacity (liters by default) per minute of dwell.
+type Consumable struct {
+	Agent    string  `yaml:"agent"`
+	Capacity float64 `yaml:"capacity"`
+	LowLevel float64 `yaml:"low_level"`
+	Unit     string  `yaml:"unit"`
+}
+
+// ConsumableLevel is the estimated inventory of an agent.
+type ConsumableLevel struct {
+	Agent     string    `json:"agent"`
+	Level     float64   `json:"level"`
+	Capacity  float64   `json:"capacity"`
+	Unit      string    `json:"unit"`
+	Low       bool      `json:"low"`
+	Refilled  time.Time `json:"refilled,omitempty"`
+	UsedSince float64   `json:"usedSinceRefill"`
+}
+
+type refillRequest struct {
+	Agent string   `json:"agent"`
+	Level *float64 `json:"level"`
+}
+
+var (
+	consumablesMutex = sync.Mutex{}
+	inventory        = make(map[string]*ConsumableLevel)
+)
+
+// validateConsumables checks the consumables section of the configuration file and that every clean
+// stage with a rate uses one of its agents.
+func validateConsumables() error {
+	agents := make(map[string]bool)
+	for i := range driverConfig.Consumables {
+		c := &driverConfig.Consumables[i]
+		if c.Agent == "" || agents[c.Agent] {
+			return fmt.Errorf("agents must be unique and not empty")
+		}
+		agents[c.Agent] = true
+		if c.Capacity <= 0 || c.LowLevel < 0 || c.LowLevel >= c.Capacity {
+			return fmt.Errorf("agent %s: capacity must be positive and low_level below it", c.Agent)
+		}
+		if c.Unit == "" {
+			c.Unit = "L"
+		}
+	}
+	for _, stage := range driverConfig.CleanRecipe {
+		if stage.Rate < 0 {
+			return fmt.Errorf("clean stage %s: rate must not be negative", stage.Name)
+		}
+		if stage.Rate > 0 && !agents[stage.Agent] {
+			return fmt.Errorf("clean stage %s: agent %q is not a consumable", stage.Name, stage.Agent)
+		}
+	}
+	return nil
+}
+
+// loadConsumables restores the levels kept in CONSUMABLES_FILE, when set. Agents without a known
+// level start full.
+func loadConsumables() {
+	levels := make(map[string]*ConsumableLevel)
+	if fileName := os.Getenv("CONSUMABLES_FILE"); fileName != "" {
+		data, err := os.ReadFile(fileName)
+		if err == nil {
+			err = json.Unmarshal(data, &levels)
+		}
+		if err != nil && !os.IsNotExist(err) {
+			log.Printf("Cannot read consumables file %s. Error: %s", fileName, err)
+		}
+	}
+	consumablesMutex.Lock()
+	defer consumablesMutex.Unlock()
+	inventory = make(map[string]*ConsumableLevel)
+	for _, c := range driverConfig.Consumables {
+		level, ok := levels[c.Agent]
+		if !ok || level == nil {
+			level = &ConsumableLevel{Agent: c.Agent, Level: c.Capacity}
+		}
+		level.Capacity, level.Unit = c.Capacity, c.Unit
+		level.Low = level.Level <= c.LowLevel
+		inventory[c.Agent] = level
+	}
+}
+
+// saveConsumables writes the levels to CONSUMABLES_FILE. Called with consumablesMutex held.
+func saveConsumables() {
+	fileName := os.Getenv("CONSUMABLES_FILE")
+	if fileName == "" {
+		return
+	}
+	data, err := json.Marshal(inventory)
+	if err != nil {
+		log.Printf("Cannot marshal consumables. Error: %s", err)
+		return
+	}
+	if err := os.WriteFile(fileName+".tmp", data, 0644); err != nil {
+		log.Printf("Cannot write consumables file %s. Error: %s", fileName, err)
+		return
+	}
+	if err := os.Rename(fileName+".tmp", fileName); err != nil {
+		log.Printf("Cannot write consumables file %s. Error: %s", fileName, err)
+	}
+}
+
+// consumeCleanStage accounts for the agent used by a completed clean stage and raises a low-level
+// alert when its tank falls to low_level.
+func (s *SimpleDriver) consumeCleanStage(phase string) {
+	stage, ok := cleanStage(phase)
+	if !ok || stage.Rate == 0 {
+		return
+	}
+	used := stage.Rate * stage.dwell.Minutes()
+	consumablesMutex.Lock()
+	level, ok := inventory[stage.Agent]
+	if !ok {
+		consumablesMutex.Unlock()
+		return
+	}
+	level.Level -= used
+	if level.Level < 0 {
+		level.Level = 0
+	}
+	level.UsedSince += used
+	alert := false
+	for _, c := range driverConfig.Consumables {
+		if c.Agent == stage.Agent && level.Level <= c.LowLevel && !level.Low {
+			level.Low, alert = true, true
+		}
+	}
+	snapshot := *level
+	saveConsumables()
+	consumablesMutex.Unlock()
+
+	if alert {
+		log.Printf("Consumable %s low: %.1f %s left", snapshot.Agent, snapshot.Level, snapshot.Unit)
+		audit("consumable-low", snapshot.Agent, fmt.Sprintf("%.1f %s left", snapshot.Level, snapshot.Unit))
+		sendNotification("consumable", notificationSeverityCritical, tr("notification.consumable-low", snapshot.Agent, snapshot.Level, snapshot.Unit))
+	}
+	s.pushConsumables()
+}
+
+// consumableLevels returns the levels sorted by agent.
+func consumableLevels() []ConsumableLevel {
+	consumablesMutex.Lock()
+	defer consumablesMutex.Unlock()
+	levels := make([]ConsumableLevel, 0, len(inventory))
+	for _, level := range inventory {
+		levels = append(levels, *level)
+	}
+	sort.Slice(levels, func(i, j int) bool { return levels[i].Agent < levels[j].Agent })
+	return levels
+}
+
+func (s *SimpleDriver) pushConsumables() {
+	cv, err := consumablesCommandValue()
+	if err != nil {
+		log.Printf("Cannot create consumables reading. Error: %s", err)
+		return
+	}
+	s.asyncCh <- &sdkModels.AsyncValues{
+		DeviceName:    deviceName(),
+		CommandValues: []*sdkModels.CommandValue{cv},
+	}
+}
+
+func consumablesCommandValue() (*sdkModels.CommandValue, error) {
+	payload, err := shapePayload(consumablesResource, consumableLevels())
+	if err != nil {
+		return nil, err
+	}
+	return sdkModels.NewCommandValue(consumablesResource, common.ValueTypeString, string(payload))
+}
+
+func (s *SimpleDriver) handleConsumables(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{"consumables": consumableLevels()})
+}
+
+// handleConsumablesRefill acknowledges the refill of a tank, to its capacity unless a level is given.
+func (s *SimpleDriver) handleConsumablesRefill(w http.ResponseWriter, r *http.Request) {
+	var req refillRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	consumablesMutex.Lock()
+	level, ok := inventory[req.Agent]
+	if !ok {
+		consumablesMutex.Unlock()
+		writeError(w, http.StatusNotFound, fmt.Errorf("unknown agent %s", req.Agent))
+		return
+	}
+	value := level.Capacity
+	if req.Level != nil {
+		value = *req.Level
+	}
+	if value < 0 || value > level.Capacity {
+		consumablesMutex.Unlock()
+		writeError(w, http.StatusBadRequest, fmt.Errorf("level must be between 0 and %g", level.Capacity))
+		return
+	}
+	level.Level, level.UsedSince, level.Refilled = value, 0, time.Now()
+	for _, c := range driverConfig.Consumables {
+		if c.Agent == req.Agent {
+			level.Low = value <= c.LowLevel
+		}
+	}
+	snapshot := *level
+	saveConsumables()
+	consumablesMutex.Unlock()
+
+	audit("consumable-refill", req.Agent, fmt.Sprintf("refilled to %.1f %s", value, snapshot.Unit))
+	s.pushConsumables()
+	writeJSON(w, http.StatusOK, snapshot)
+}
@@ -0,0 +1,207 @@
+package driver
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/common"
+)
+
+const (
+	coordinationRoute = common.ApiBase + "/coordination"
+
+	DEFAULT_CLEAN_LEASE  = time.Duration(30) * time.Minute
+	DEFAULT_LEASE_SETTLE = time.Duration(3) * time.Second
+	DEFAULT_CLOCK_SKEW   = time.Duration(5) * time.Second
+)
+
+// busLease is a lease shared by the gateways on the message bus, the clean lock of a hydraulic system
+// or the leadership of a redundant pair. The lease ends at Expires, on the synchronized wall clock of
+// the gateways, unless renewed by its holder.
+type busLease struct {
+	Holder   string    `json:"holder"`
+	Expires  time.Time `json:"expires"`
+	Released bool      `json:"released,omitempty"`
+}
+
+// free tells whether the lease may be claimed: never held, released, or expired for longer than the
+// clock skew, its holder presumed dead.
+func (l busLease) free(now time.Time) bool {
+	return l.Holder == "" || l.Released || now.After(l.Expires.Add(clockSkew))
+}
+
+// observe applies a lease seen on the message bus, ours included, and reports whether it did. All the
+// gateways see the claims in the order of the broker: the first claim of a free lease wins, and the
+// later claims of other gateways are ignored until it is released or expires.
+func (l *busLease) observe(seen busLease) bool {
+	if seen.Holder != l.Holder && !l.free(time.Now()) {
+		return false
+	}
+	*l = seen
+	return true
+}
+
+var (
+	coordinationMutex = sync.Mutex{}
+	currentLease      busLease
+	// holdingLease is closed when this service releases the lease it holds
+	holdingLease  chan struct{}
+	coordinatorID string
+	leaseDuration = DEFAULT_CLEAN_LEASE
+	leaseSettle   = DEFAULT_LEASE_SETTLE
+	clockSkew     = DEFAULT_CLOCK_SKEW
+	// publishLease sends a lease to the other gateways, set while coordination is enabled
+	publishLease func(lease busLease) error
+)
+
+// parseCoordination reads the identity of the service, COORDINATION_ID (the hostname and device name
+// by default), the lease duration COORDINATION_LEASE, which must cover a cycle, the time waited for
+// competing claims COORDINATION_SETTLE and the clock skew tolerated between gateways
+// COORDINATION_CLOCK_SKEW.
+func parseCoordination() {
+	coordinatorID = os.Getenv("COORDINATION_ID")
+	if coordinatorID == "" {
+		hostname, _ := os.Hostname()
+		coordinatorID = fmt.Sprintf("%s/%s", hostname, deviceName())
+	}
+	for _, d := range []struct {
+		name  string
+		value *time.Duration
+	}{
+		{"COORDINATION_LEASE", &leaseDuration},
+		{"COORDINATION_SETTLE", &leaseSettle},
+		{"COORDINATION_CLOCK_SKEW", &clockSkew},
+	} {
+		env := os.Getenv(d.name)
+		if env == "" {
+			continue
+		}
+		if value, err := time.ParseDuration(env); err == nil && value > 0 {
+			*d.value = value
+		} else {
+			log.Printf("Cannot parse %s. Picking default value %s...", d.name, *d.value)
+		}
+	}
+}
+
+// observeLease applies a clean lock lease seen on the message bus.
+func observeLease(lease busLease) {
+	coordinationMutex.Lock()
+	defer coordinationMutex.Unlock()
+	if !currentLease.observe(lease) {
+		return
+	}
+	if holdingLease != nil && (lease.Holder != coordinatorID || lease.Released) {
+		// Taken over, e.g. after a renewal missed for longer than the lease
+		logf(moduleStateMachine, levelWarn, "Clean lock lost to %s", lease.Holder)
+		close(holdingLease)
+		holdingLease = nil
+	}
+}
+
+// acquireCleanLock claims the clean lock before a cycle that cleans and reports whether this service
+// holds it. The claim is decided after the settle time, once the competing claims are seen. Without
+// coordination the clean is always allowed; the lock is denied when the bus cannot be reached.
+func acquireCleanLock() bool {
+	if publishLease == nil {
+		return true
+	}
+	coordinationMutex.Lock()
+	if holdingLease != nil {
+		coordinationMutex.Unlock()
+		return true
+	}
+	if !currentLease.free(time.Now()) {
+		holder := currentLease.Holder
+		coordinationMutex.Unlock()
+		debugf(moduleStateMachine, "Clean lock held by %s", holder)
+		return false
+	}
+	coordinationMutex.Unlock()
+
+	if err := publishLease(busLease{Holder: coordinatorID, Expires: time.Now().Add(leaseDuration)}); err != nil {
+		logSampledf(moduleStateMachine, levelError, "clean lock", "Cannot claim the clean lock. Error: %s", err)
+		return false
+	}
+	logRecovered("clean lock")
+	if !sleepUntilStop(leaseSettle) {
+		return false
+	}
+	coordinationMutex.Lock()
+	won := currentLease.Holder == coordinatorID && !currentLease.Released
+	if won {
+		holdingLease = make(chan struct{})
+		go renewCleanLock(holdingLease)
+	}
+	holder := currentLease.Holder
+	coordinationMutex.Unlock()
+	if won {
+		audit("clean-lock", coordinatorID, fmt.Sprintf("acquired for %s", leaseDuration))
+	} else {
+		debugf(moduleStateMachine, "Clean lock claim lost to %s", holder)
+	}
+	return won
+}
+
+// renewCleanLock extends the lease every third of its duration until released.
+func renewCleanLock(released chan struct{}) {
+	for {
+		select {
+		case <-released:
+			return
+		case <-stopping:
+			return
+		case <-time.After(leaseDuration / 3):
+		}
+		if err := publishLease(busLease{Holder: coordinatorID, Expires: time.Now().Add(leaseDuration)}); err != nil {
+			logSampledf(moduleStateMachine, levelError, "clean lock renewal", "Cannot renew the clean lock. Error: %s", err)
+		} else {
+			logRecovered("clean lock renewal")
+		}
+	}
+}
+
+// releaseCleanLock releases the clean lock when this service holds it.
+func releaseCleanLock() {
+	coordinationMutex.Lock()
+	held := holdingLease != nil
+	if held {
+		close(holdingLease)
+		holdingLease = nil
+	}
+	coordinationMutex.Unlock()
+	if !held {
+		return
+	}
+	if err := publishLease(busLease{Holder: coordinatorID, Expires: time.Now(), Released: true}); err != nil {
+		logf(moduleStateMachine, levelError, "Cannot release the clean lock, it expires in %s. Error: %s", leaseDuration, err)
+		return
+	}
+	audit("clean-lock", coordinatorID, "released")
+}
+
+// cleanLockHolder returns the gateway holding the clean lock, empty when free.
+func cleanLockHolder() string {
+	coordinationMutex.Lock()
+	defer coordinationMutex.Unlock()
+	if currentLease.free(time.Now()) {
+		return ""
+	}
+	return currentLease.Holder
+}
+
+func (s *SimpleDriver) handleCoordination(w http.ResponseWriter, r *http.Request) {
+	coordinationMutex.Lock()
+	defer coordinationMutex.Unlock()
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"enabled": publishLease != nil,
+		"id":      coordinatorID,
+		"lease":   currentLease,
+		"free":    currentLease.free(time.Now()),
+		"holding": holdingLease != nil,
+	})
+}
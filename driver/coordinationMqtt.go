@@ -0,0 +1,117 @@
+//go:build !nomqtt
+
+package driver
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+const (
+	defaultCoordinationTopic = "edgex/coordination/clean-lock"
+	defaultLeaderTopic       = "edgex/coordination/leader"
+)
+
+// startCoordination shares the clean lock with the other gateways of the hydraulic system through
+// the message bus broker COORDINATION_BROKER (e.g. tcp://edgex-mqtt-broker:1883), as a retained
+// lease on COORDINATION_TOPIC, the same for all of them. With LEADER_ELECTION, the instances of an
+// active/standby pair also elect their leader on LEADER_TOPIC, the leader mirroring its pipeline
+// state to the standby on <LEADER_TOPIC>/state. The wall clocks of the gateways must be synchronized,
+// e.g. by NTP, within COORDINATION_CLOCK_SKEW. It is a no-op when the broker is unset.
+func startCoordination() error {
+	broker := os.Getenv("COORDINATION_BROKER")
+	if broker == "" {
+		return nil
+	}
+	parseCoordination()
+	topic := os.Getenv("COORDINATION_TOPIC")
+	if topic == "" {
+		topic = defaultCoordinationTopic
+	}
+	leaderTopic := os.Getenv("LEADER_TOPIC")
+	if leaderTopic == "" {
+		leaderTopic = defaultLeaderTopic
+	}
+	stateTopic := leaderTopic + "/state"
+	handlers := map[string]func(payload []byte) error{
+		topic: func(payload []byte) error {
+			var lease busLease
+			err := json.Unmarshal(payload, &lease)
+			if err == nil {
+				observeLease(lease)
+			}
+			return err
+		},
+	}
+	if electionEnabled {
+		handlers[leaderTopic] = func(payload []byte) error {
+			var lease busLease
+			err := json.Unmarshal(payload, &lease)
+			if err == nil {
+				observeLeader(lease)
+			}
+			return err
+		}
+		handlers[stateTopic] = func(payload []byte) error {
+			var state PipelineState
+			err := json.Unmarshal(payload, &state)
+			if err == nil {
+				observePipeline(state)
+			}
+			return err
+		}
+	}
+	subscribe := func(client mqtt.Client) {
+		for t, handle := range handlers {
+			t, handle := t, handle
+			token := client.Subscribe(t, 1, func(_ mqtt.Client, msg mqtt.Message) {
+				if err := handle(msg.Payload()); err != nil {
+					logf(moduleBridges, levelWarn, "Invalid coordination message on %s. Error: %s", t, err)
+				}
+			})
+			if !token.WaitTimeout(twinTimeout) || token.Error() != nil {
+				logf(moduleBridges, levelError, "Cannot subscribe to %s. Error: %v", t, token.Error())
+			}
+		}
+	}
+	options := mqtt.NewClientOptions().
+		AddBroker(broker).
+		// Unique per gateway, the broker drops a client when another connects with its ID
+		SetClientID(strings.ReplaceAll("device-gpiod-coordination-"+coordinatorID, "/", "-")).
+		SetAutoReconnect(true).
+		SetReconnectingHandler(countReconnect("coordination")).
+		SetOnConnectHandler(subscribe)
+	client := mqtt.NewClient(options)
+	token := client.Connect()
+	if !token.WaitTimeout(twinTimeout) || token.Error() != nil {
+		logf(moduleBridges, levelWarn, "Cannot connect to the coordination broker, retrying in background. Error: %v", token.Error())
+	}
+	publish := func(topic string, v interface{}) error {
+		payload, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		token := client.Publish(topic, 1, true, payload)
+		if !token.WaitTimeout(twinTimeout) {
+			return fmt.Errorf("timeout publishing on %s", topic)
+		}
+		return token.Error()
+	}
+	publishLease = func(lease busLease) error {
+		return publish(topic, lease)
+	}
+	if electionEnabled {
+		publishLeader = func(lease busLease) error {
+			return publish(leaderTopic, lease)
+		}
+		publishPipeline = func(state PipelineState) error {
+			return publish(stateTopic, state)
+		}
+	}
+	logf(moduleBridges, levelInfo, "Clean cycles coordinated on %s as %s", topic, coordinatorID)
+	return nil
+}
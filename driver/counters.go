@@ -0,0 +1,176 @@
+package driver
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/edgexfoundry/device-gpiod/gpio"
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/common"
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/models"
+
+	sdkModels "github.com/edgexfoundry/device-sdk-go/v2/pkg/models"
+)
+
+const (
+	DEFAULT_COUNT_INTERVAL = time.Duration(10) * time.Second
+	counterAttribute       = "counter"
+)
+
+var (
+	counterPulses    = derivedKind{"Pulses", common.ValueTypeUint64, "pulses", "Pulses counted in the last interval"}
+	counterFrequency = derivedKind{"FrequencyHz", common.ValueTypeFloat64, "Hz", "Pulse frequency over the last interval"}
+	counterTotal     = derivedKind{"PulseTotal", common.ValueTypeUint64, "pulses", "Pulses counted since startup"}
+	counterKinds     = []derivedKind{counterPulses, counterFrequency, counterTotal}
+)
+
+// pulseCounter counts the edges of a counter line. The frequency is measured on the kernel timestamps
+// of the edges, so it does not depend on when the events are delivered to the service.
+type pulseCounter struct {
+	total     uint64
+	edges     uint64
+	first     time.Duration
+	last      time.Duration
+	pulses    uint64
+	frequency float64
+}
+
+var (
+	counterMutex = sync.Mutex{}
+	counters     = make(map[string]*pulseCounter)
+)
+
+// counterProfileResources returns the device resources of a counter line, to be included in the
+// generated device profile.
+func counterProfileResources(g gpio.GPIO) []models.DeviceResource {
+	if g.Mode != gpio.ModeCounter {
+		return nil
+	}
+	var resources []models.DeviceResource
+	for _, kind := range counterKinds {
+		resources = append(resources, models.DeviceResource{
+			Name:        derivedResourceName(g.Name, kind),
+			Description: fmt.Sprintf("%s of %s", kind.Description, lineDescription(g)),
+			Attributes: map[string]interface{}{
+				"name":           g.Name,
+				counterAttribute: kind.Suffix,
+			},
+			Properties: models.ResourceProperties{
+				ValueType: kind.ValueType,
+				ReadWrite: common.ReadWrite_R,
+				Units:     kind.Units,
+			},
+		})
+	}
+	return resources
+}
+
+// startCounter watches a counter line and publishes its readings every count_interval (default 10s).
+// Every accepted edge is a pulse: lines on which a pulse is a single transition set edge rising or
+// falling.
+func (s *SimpleDriver) startCounter(g *gpio.GPIO) error {
+	name := g.Name
+	counterMutex.Lock()
+	counters[name] = &pulseCounter{}
+	counterMutex.Unlock()
+	if err := g.Watch(countPulse); err != nil {
+		return err
+	}
+	interval := g.CountPeriod(DEFAULT_COUNT_INTERVAL)
+	goBackground(func() {
+		for {
+			period := reportingInterval(interval)
+			supervisedSleep("counter-"+name, period)
+			closeCountInterval(name, period)
+			values := counterCommandValues(name)
+			for _, cv := range values {
+				if value, ok := numericValue(cv); ok {
+					recordSample(cv.DeviceResourceName, value)
+				}
+			}
+			s.asyncCh <- &sdkModels.AsyncValues{
+				DeviceName:    deviceName(),
+				CommandValues: values,
+			}
+		}
+	})
+	return nil
+}
+
+func countPulse(evt gpio.Event) {
+	counterMutex.Lock()
+	defer counterMutex.Unlock()
+	c, ok := counters[evt.Name]
+	if !ok {
+		return
+	}
+	if c.edges == 0 {
+		c.first = evt.Timestamp
+	}
+	c.last = evt.Timestamp
+	c.edges++
+	c.total++
+}
+
+// closeCountInterval stores the pulses and the frequency of the interval just elapsed and starts a
+// new one. The frequency spans the first to the last edge of the interval; with fewer than two edges
+// it falls back to the pulses over the interval.
+func closeCountInterval(name string, interval time.Duration) {
+	counterMutex.Lock()
+	defer counterMutex.Unlock()
+	c, ok := counters[name]
+	if !ok {
+		return
+	}
+	c.pulses = c.edges
+	c.frequency = float64(c.edges) / interval.Seconds()
+	if c.edges > 1 && c.last > c.first {
+		c.frequency = float64(c.edges-1) / (c.last - c.first).Seconds()
+	}
+	c.edges = 0
+}
+
+// counterCommandValues returns typed readings for the counter resources of a line.
+func counterCommandValues(name string) []*sdkModels.CommandValue {
+	var values []*sdkModels.CommandValue
+	for _, kind := range counterKinds {
+		cv, err := counterCommandValue(name, kind.Suffix)
+		if err != nil {
+			log.Printf("Cannot create %s reading for gpio %s. Error: %s", kind.Suffix, name, err)
+			continue
+		}
+		values = append(values, cv)
+	}
+	return values
+}
+
+// counterCommandValue returns the reading of the counter resource of a line with the given suffix.
+func counterCommandValue(name string, suffix string) (*sdkModels.CommandValue, error) {
+	counterMutex.Lock()
+	c, ok := counters[name]
+	var snapshot pulseCounter
+	if ok {
+		snapshot = *c
+	}
+	counterMutex.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("gpio %s is not counting", name)
+	}
+	for _, kind := range counterKinds {
+		if kind.Suffix != suffix {
+			continue
+		}
+		var value interface{}
+		switch kind {
+		case counterPulses:
+			value = snapshot.pulses
+		case counterFrequency:
+			value = snapshot.frequency
+		case counterTotal:
+			value = snapshot.total
+		}
+		return sdkModels.NewCommandValue(derivedResourceName(name, kind), kind.ValueType, value)
+	}
+	return nil, fmt.Errorf("unknown counter %q", suffix)
+}
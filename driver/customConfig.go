@@ -0,0 +1,157 @@
+package driver
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/edgexfoundry/device-gpiod/config"
+	"github.com/edgexfoundry/device-gpiod/gpio"
+)
+
+var (
+	customConfigMutex = sync.Mutex{}
+	triggers          config.Triggers
+	modbusEndpoint    string
+	// envTimers and the env flags are the settings of the environment variables, the fallback of the
+	// ones the custom configuration leaves out
+	envTimers        timerValues
+	envEnableClean   bool
+	envEnableReverse bool
+)
+
+// rememberEnvSettings keeps the cycle settings parsed from the environment at startup.
+func rememberEnvSettings() {
+	envTimers = startupTimers
+	envEnableClean = *enableClean
+	envEnableReverse = *enableReverse
+}
+
+// triggerLine returns the line mapped to a pipeline function by the custom configuration, the one of
+// the environment variable env otherwise.
+func triggerLine(env string) string {
+	customConfigMutex.Lock()
+	current := triggers
+	customConfigMutex.Unlock()
+	var line string
+	switch env {
+	case "START_TRIGGER":
+		line = current.Start
+	case "STANDBY_TRIGGER":
+		line = current.Standby
+	case "REVERSE_TRIGGER":
+		line = current.Reverse
+	case "CLEAN_TRIGGER":
+		line = current.Clean
+	case "OPEN_VALVE":
+		line = current.OpenValve
+	case "SWITCHING_VALVE":
+		line = current.SwitchingValve
+	}
+	if line != "" {
+		return line
+	}
+	return os.Getenv(env)
+}
+
+// modbusDeviceEndpoint returns the endpoint of the Modbus device the pipeline depends on.
+func modbusDeviceEndpoint() string {
+	customConfigMutex.Lock()
+	endpoint := modbusEndpoint
+	customConfigMutex.Unlock()
+	if endpoint != "" {
+		return endpoint
+	}
+	return os.Getenv("MODBUS_DEVICE_ENDPOINT")
+}
+
+// cycleSettingsChanged tells whether an update of the writable section touches the cycle settings,
+// the trigger lines or the Modbus endpoint.
+func cycleSettingsChanged(previous config.GpiodWritable, updated config.GpiodWritable) bool {
+	previous.DiscoverSleepDurationSecs, updated.DiscoverSleepDurationSecs = 0, 0
+	previous.LogLevels, updated.LogLevels = "", ""
+	return !reflect.DeepEqual(previous, updated)
+}
+
+// applyWritable applies the cycle timers and feature flags of the writable section on top of the
+// environment ones, then the active profile on top of them, and takes its trigger lines and Modbus
+// endpoint. Timers are bounded like the cycle timer resources. Nothing is changed when a setting is
+// rejected.
+func (s *SimpleDriver) applyWritable(w config.GpiodWritable, source string) error {
+	if err := w.Validate(); err != nil {
+		return err
+	}
+	values := envTimers
+	for _, setting := range []struct {
+		resource string
+		secs     int64
+		dst      *time.Duration
+	}{
+		{"PumpTimer", w.PumpTimeoutSecs, &values.pump},
+		{"CommandGap", w.CommandGapSecs, &values.commandGap},
+		{"CleanTimer", w.CleanTimeoutSecs, &values.clean},
+		{"ReverseTimer", w.ReverseTimeoutSecs, &values.reverse},
+		{"GravityTimer", w.GravityTimeoutSecs, &values.gravity},
+	} {
+		if setting.secs == 0 {
+			continue
+		}
+		d := time.Duration(setting.secs) * time.Second
+		t, _ := findTimer(setting.resource)
+		if min, max := t.bounds(); d < min || d > max {
+			return fmt.Errorf("%s %s out of range [%s, %s]", t.env, d, min, max)
+		}
+		*setting.dst = d
+	}
+	clean, reverse := envEnableClean, envEnableReverse
+	if w.EnableClean != nil {
+		clean = *w.EnableClean
+	}
+	if w.EnableReverse != nil {
+		reverse = *w.EnableReverse
+	}
+	for _, line := range []string{w.Triggers.Start, w.Triggers.Standby, w.Triggers.Reverse, w.Triggers.Clean,
+		w.Triggers.OpenValve, w.Triggers.SwitchingValve} {
+		if _, ok := s.findGpio(line); line != "" && !ok {
+			return fmt.Errorf("trigger line %s is not configured", line)
+		}
+	}
+
+	previousTimers := startupTimers
+	startupTimers = values
+	if err := s.activateProfile(w.ActiveProfile, source); err != nil {
+		startupTimers = previousTimers
+		return err
+	}
+	profileMutex.Lock()
+	*enableClean, *enableReverse = clean, reverse
+	gpioConfig.EnableClean, gpioConfig.EnableReverse = clean, reverse
+	profileMutex.Unlock()
+
+	customConfigMutex.Lock()
+	pumpsChanged := w.Triggers.Start != triggers.Start || w.Triggers.Standby != triggers.Standby
+	triggers, modbusEndpoint = w.Triggers, w.ModbusEndpoint
+	customConfigMutex.Unlock()
+	if pumpsChanged {
+		s.bindPumps()
+	}
+	return nil
+}
+
+// bindPumps takes the primary and standby pumps of the trigger lines, used from the next cycle, and
+// returns the primary one.
+func (s *SimpleDriver) bindPumps() gpio.GPIO {
+	var primary, standby gpio.GPIO
+	for _, g := range s.GpioList.Gpio {
+		switch g.Name {
+		case triggerLine("START_TRIGGER"):
+			primary = g
+		case triggerLine("STANDBY_TRIGGER"):
+			standby = g
+		}
+	}
+	setPumps(primary, standby)
+	return primary
+}
@@ -0,0 +1,201 @@
+package driver
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/common"
+
+	sdkModels "github.com/edgexfoundry/device-sdk-go/v2/pkg/models"
+)
+
+const (
+	dailySummaryResource = "DailySummary"
+	weeklyStatsResource  = "WeeklyStats"
+	statsDays            = 7
+)
+
+// DayStats aggregates the activity of one statistics day. A statistics day starts at the daily report
+// time (DAILY_REPORT_TIME, midnight by default) and is named after the date it starts on.
+type DayStats struct {
+	Date        string  `json:"date"`
+	Cycles      int     `json:"cycles"`
+	PumpSeconds float64 `json:"pumpSeconds"`
+	Reverses    int     `json:"reverses"`
+	Cleans      int     `json:"cleans"`
+	Faults      int     `json:"faults"`
+}
+
+var (
+	dailyStatsMutex = sync.Mutex{}
+	dailyStats      = make(map[string]*DayStats)
+	reportTime      = time.Duration(0)
+)
+
+// statsDay returns the statistics day t falls in.
+func statsDay(t time.Time) string {
+	return t.Add(-reportTime).Format("2006-01-02")
+}
+
+// countDay applies update to the statistics of the current day and drops the days older than a week.
+func countDay(update func(day *DayStats)) {
+	dailyStatsMutex.Lock()
+	defer dailyStatsMutex.Unlock()
+	now := time.Now()
+	date := statsDay(now)
+	day, ok := dailyStats[date]
+	if !ok {
+		day = &DayStats{Date: date}
+		dailyStats[date] = day
+		oldest := statsDay(now.AddDate(0, 0, -(statsDays - 1)))
+		for d := range dailyStats {
+			if d < oldest {
+				delete(dailyStats, d)
+			}
+		}
+	}
+	update(day)
+	saveDailyStats()
+}
+
+func countCycle(pumpSeconds int64) {
+	countDay(func(day *DayStats) {
+		day.Cycles++
+		day.PumpSeconds += float64(pumpSeconds)
+	})
+}
+
+// countPhase counts the reverse and clean phases completed.
+func countPhase(name string) {
+	switch name {
+	case phaseReverse:
+		countDay(func(day *DayStats) { day.Reverses++ })
+	case phaseClean:
+		countDay(func(day *DayStats) { day.Cleans++ })
+	}
+}
+
+func countFault() {
+	countDay(func(day *DayStats) { day.Faults++ })
+}
+
+// weeklyStats returns the statistics of the last seven days, oldest first, with the days without
+// activity included.
+func weeklyStats() []DayStats {
+	dailyStatsMutex.Lock()
+	defer dailyStatsMutex.Unlock()
+	now := time.Now()
+	week := make([]DayStats, 0, statsDays)
+	for i := statsDays - 1; i >= 0; i-- {
+		date := statsDay(now.AddDate(0, 0, -i))
+		if day, ok := dailyStats[date]; ok {
+			week = append(week, *day)
+		} else {
+			week = append(week, DayStats{Date: date})
+		}
+	}
+	return week
+}
+
+// loadDailyStats reads DAILY_REPORT_TIME (HH:MM, local time) and the statistics kept in
+// DAILY_STATS_FILE, when set, so a restart does not reset the week.
+func loadDailyStats() {
+	if value := os.Getenv("DAILY_REPORT_TIME"); value != "" {
+		if offset, err := timeOfDay(value); err == nil {
+			reportTime = offset
+		} else {
+			log.Printf("Cannot parse DAILY_REPORT_TIME. Picking default value 00:00...")
+		}
+	}
+	fileName := os.Getenv("DAILY_STATS_FILE")
+	if fileName == "" {
+		return
+	}
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("Cannot read daily statistics file %s. Error: %s", fileName, err)
+		}
+		return
+	}
+	var days []DayStats
+	if err := json.Unmarshal(data, &days); err != nil {
+		log.Printf("Cannot parse daily statistics file %s. Error: %s", fileName, err)
+		return
+	}
+	dailyStatsMutex.Lock()
+	defer dailyStatsMutex.Unlock()
+	for i := range days {
+		dailyStats[days[i].Date] = &days[i]
+	}
+}
+
+// saveDailyStats writes the statistics to DAILY_STATS_FILE. Called with dailyStatsMutex held.
+func saveDailyStats() {
+	fileName := os.Getenv("DAILY_STATS_FILE")
+	if fileName == "" {
+		return
+	}
+	days := make([]DayStats, 0, len(dailyStats))
+	for _, day := range dailyStats {
+		days = append(days, *day)
+	}
+	sort.Slice(days, func(i, j int) bool { return days[i].Date < days[j].Date })
+	data, err := json.Marshal(days)
+	if err != nil {
+		log.Printf("Cannot marshal daily statistics. Error: %s", err)
+		return
+	}
+	if err := os.WriteFile(fileName+".tmp", data, 0644); err != nil {
+		log.Printf("Cannot write daily statistics file %s. Error: %s", fileName, err)
+		return
+	}
+	if err := os.Rename(fileName+".tmp", fileName); err != nil {
+		log.Printf("Cannot write daily statistics file %s. Error: %s", fileName, err)
+	}
+}
+
+// startDailyReport publishes the summary of the statistics day that just ended at every report time.
+func (s *SimpleDriver) startDailyReport() {
+	goBackground(func() {
+		for {
+			now := time.Now()
+			midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+			next := midnight.Add(reportTime)
+			if !next.After(now) {
+				next = midnight.AddDate(0, 0, 1).Add(reportTime)
+			}
+			supervisedSleep("daily-report", time.Until(next))
+			s.pushDailySummary(statsDay(next.Add(-time.Minute)))
+		}
+	})
+}
+
+func (s *SimpleDriver) pushDailySummary(date string) {
+	summary := DayStats{Date: date}
+	dailyStatsMutex.Lock()
+	if day, ok := dailyStats[date]; ok {
+		summary = *day
+	}
+	dailyStatsMutex.Unlock()
+	log.Printf("Daily summary of %s: %d cycles, %.0f s of pumping, %d reverses, %d cleans, %d faults",
+		date, summary.Cycles, summary.PumpSeconds, summary.Reverses, summary.Cleans, summary.Faults)
+	payload, err := shapePayload(dailySummaryResource, summary)
+	if err != nil {
+		log.Printf("Cannot marshal daily summary. Error: %s", err)
+		return
+	}
+	cv, err := sdkModels.NewCommandValue(dailySummaryResource, common.ValueTypeString, string(payload))
+	if err != nil {
+		log.Printf("Cannot create daily summary reading. Error: %s", err)
+		return
+	}
+	s.asyncCh <- &sdkModels.AsyncValues{
+		DeviceName:    deviceName(),
+		CommandValues: []*sdkModels.CommandValue{cv},
+	}
+}
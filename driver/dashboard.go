@@ -0,0 +1,43 @@
+//go:build !nodashboard
+
+package driver
+
+import (
+	_ "embed"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/common"
+)
+
+const dashboardRoute = common.ApiBase + "/dashboard"
+
+// dashboardCompiled tells whether the dashboard page is embedded in the build. It is left out with
+// the nodashboard build tag.
+const dashboardCompiled = true
+
+//go:embed dashboard.html
+var dashboardPage []byte
+
+// dashboardEnabled reads DASHBOARD_ENABLED. The dashboard is off unless explicitly enabled, as it
+// exposes manual controls to anyone reaching the service port.
+func dashboardEnabled() bool {
+	enabled, err := strconv.ParseBool(os.Getenv("DASHBOARD_ENABLED"))
+	return err == nil && enabled
+}
+
+// handleDashboard serves the single-page dashboard, which talks to the routes of the service itself.
+func (s *SimpleDriver) handleDashboard(w http.ResponseWriter, r *http.Request) {
+	if shedding(shedClients) {
+		writeError(w, http.StatusServiceUnavailable, errors.New("dashboard shed under load"))
+		return
+	}
+	w.Header().Set(common.ContentType, "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(dashboardPage); err != nil {
+		log.Printf("Cannot write dashboard page. Error: %s", err)
+	}
+}
@@ -0,0 +1,202 @@
+package driver
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	dependencyWait    = "wait"
+	dependencySkip    = "skip"
+	dependencyDegrade = "degrade"
+
+	defaultCoreMetadataURL = "http://edgex-core-metadata:59881"
+	legacyModbusDevice     = "Modbus-Device"
+)
+
+// Dependency is another EdgeX device this service relies on. Policy tells what to do while it is
+// unavailable: wait holds the pump cycles, degrade runs them but reports the service degraded, skip
+// only reports it. A device is available when core-metadata knows it UP and UNLOCKED, or, when
+// Endpoint is set, when the endpoint answers.
+type Dependency struct {
+	Device   string `yaml:"device"`
+	Policy   string `yaml:"policy"`
+	Endpoint string `yaml:"endpoint"`
+}
+
+// DependencyStatus is the last known availability of a dependency.
+type DependencyStatus struct {
+	Device    string    `json:"device"`
+	Policy    string    `json:"policy"`
+	Available bool      `json:"available"`
+	Detail    string    `json:"detail,omitempty"`
+	CheckedAt time.Time `json:"checkedAt"`
+}
+
+var (
+	dependencyMutex    = sync.Mutex{}
+	dependencyStatus   = make(map[string]DependencyStatus)
+	dependencyInterval = time.Duration(30) * time.Second
+	dependencyClient   = &http.Client{Timeout: time.Duration(5) * time.Second}
+)
+
+// validateDependencies checks the dependencies section of the configuration file.
+func validateDependencies() error {
+	for i := range driverConfig.Dependencies {
+		d := &driverConfig.Dependencies[i]
+		if d.Device == "" {
+			return fmt.Errorf("dependency %d has no device", i)
+		}
+		switch d.Policy {
+		case "":
+			d.Policy = dependencyWait
+		case dependencyWait, dependencySkip, dependencyDegrade:
+		default:
+			return fmt.Errorf("dependency %s: unknown policy %q", d.Device, d.Policy)
+		}
+	}
+	return nil
+}
+
+// dependencies returns the configured dependencies. Without any, the ModbusEndpoint of the custom
+// configuration or MODBUS_DEVICE_ENDPOINT keeps declaring
+// the Modbus device the pipeline historically waited for.
+func dependencies() []Dependency {
+	if len(driverConfig.Dependencies) > 0 {
+		return driverConfig.Dependencies
+	}
+	if endpoint := modbusDeviceEndpoint(); endpoint != "" {
+		return []Dependency{{Device: legacyModbusDevice, Policy: dependencyWait, Endpoint: endpoint}}
+	}
+	return nil
+}
+
+// checkDependency resolves the availability of a dependency.
+func checkDependency(d Dependency) DependencyStatus {
+	status := DependencyStatus{Device: d.Device, Policy: d.Policy, CheckedAt: time.Now()}
+	if d.Endpoint != "" {
+		response, err := dependencyClient.Get(d.Endpoint)
+		if err != nil {
+			status.Detail = err.Error()
+			return status
+		}
+		response.Body.Close()
+		status.Available = true
+		return status
+	}
+
+	base := os.Getenv("CORE_METADATA_URL")
+	if base == "" {
+		base = defaultCoreMetadataURL
+	}
+	response, err := dependencyClient.Get(base + "/api/v2/device/name/" + url.PathEscape(d.Device))
+	if err != nil {
+		status.Detail = err.Error()
+		return status
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		status.Detail = fmt.Sprintf("core-metadata answered %s", response.Status)
+		return status
+	}
+	var body struct {
+		Device struct {
+			AdminState     string `json:"adminState"`
+			OperatingState string `json:"operatingState"`
+		} `json:"device"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&body); err != nil {
+		status.Detail = err.Error()
+		return status
+	}
+	status.Available = body.Device.OperatingState == "UP" && body.Device.AdminState == "UNLOCKED"
+	if !status.Available {
+		status.Detail = fmt.Sprintf("operating state %s, admin state %s", body.Device.OperatingState, body.Device.AdminState)
+	}
+	return status
+}
+
+// refreshDependencies checks every dependency and records the changes of availability.
+func refreshDependencies() {
+	for _, d := range dependencies() {
+		status := checkDependency(d)
+		dependencyMutex.Lock()
+		previous, known := dependencyStatus[d.Device]
+		dependencyStatus[d.Device] = status
+		dependencyMutex.Unlock()
+		if known && previous.Available == status.Available {
+			continue
+		}
+		if status.Available {
+			audit("dependency-available", d.Device, "policy "+d.Policy)
+		} else {
+			audit("dependency-unavailable", d.Device, fmt.Sprintf("policy %s: %s", d.Policy, status.Detail))
+		}
+	}
+}
+
+// startDependencyMonitoring checks the dependencies every DEPENDENCY_INTERVAL (default 30s), for the
+// whole life of the service rather than only at startup.
+func startDependencyMonitoring() {
+	if len(dependencies()) == 0 {
+		return
+	}
+	if d, err := time.ParseDuration(os.Getenv("DEPENDENCY_INTERVAL")); err == nil && d > 0 {
+		dependencyInterval = d
+	} else {
+		log.Printf("Cannot parse DEPENDENCY_INTERVAL. Picking default value %s...", dependencyInterval)
+	}
+	refreshDependencies()
+	goBackground(func() {
+		for {
+			if !sleepUntilStop(dependencyInterval) {
+				return
+			}
+			refreshDependencies()
+		}
+	})
+}
+
+// unavailableDependencies returns the unavailable dependencies with the given policy.
+func unavailableDependencies(policy string) []string {
+	dependencyMutex.Lock()
+	defer dependencyMutex.Unlock()
+	var devices []string
+	for _, status := range dependencyStatus {
+		if status.Policy == policy && !status.Available {
+			devices = append(devices, status.Device)
+		}
+	}
+	sort.Strings(devices)
+	return devices
+}
+
+// waitForDependencies blocks until every dependency with the wait policy is available.
+func waitForDependencies() {
+	for {
+		waiting := unavailableDependencies(dependencyWait)
+		if len(waiting) == 0 {
+			return
+		}
+		logf(moduleConnectivity, levelInfo, "Waiting for devices %v", waiting)
+		supervisedSleep("pipeline", dependencyInterval)
+	}
+}
+
+func dependencyReport() []DependencyStatus {
+	dependencyMutex.Lock()
+	defer dependencyMutex.Unlock()
+	report := make([]DependencyStatus, 0, len(dependencyStatus))
+	for _, status := range dependencyStatus {
+		report = append(report, status)
+	}
+	sort.Slice(report, func(i, j int) bool { return report[i].Device < report[j].Device })
+	return report
+}
@@ -0,0 +1,257 @@
+package driver
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/edgexfoundry/device-gpiod/gpio"
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/common"
+
+	sdkModels "github.com/edgexfoundry/device-sdk-go/v2/pkg/models"
+)
+
+const (
+	deratingResource = "Derated"
+	deratingRoute    = common.ApiBase + "/derating"
+
+	edgexSourcePrefix = "edgex:"
+
+	DEFAULT_DERATING_INTERVAL   = time.Duration(30) * time.Second
+	DEFAULT_DERATING_HYSTERESIS = 5.0
+	DEFAULT_DERATING_GAP_FACTOR = 2.0
+	DEFAULT_DERATING_MAX_DUTY   = 50.0
+	// hwmon and iio report millidegrees Celsius
+	DEFAULT_DERATING_SCALE = 0.001
+	defaultCoreDataURL     = "http://edgex-core-data:59880"
+)
+
+// Derating protects the electronics of hot cabinets. The temperature is read every interval from
+// source: a sysfs file such as /sys/class/hwmon/hwmon0/temp1_input or an iio in_temp_input, scaled to
+// degrees Celsius by scale, or the last reading of an EdgeX resource, as edgex:<device>/<resource>.
+// Above threshold the command gap is stretched by gap_factor and the PWM duty cycles are capped to
+// max_duty percent, until the temperature falls hysteresis degrees below the threshold.
+type Derating struct {
+	Source     string  `yaml:"source"`
+	Scale      float64 `yaml:"scale"`
+	Threshold  float64 `yaml:"threshold"`
+	Hysteresis float64 `yaml:"hysteresis"`
+	GapFactor  float64 `yaml:"gap_factor"`
+	MaxDuty    float64 `yaml:"max_duty"`
+	Interval   string  `yaml:"interval"`
+	interval   time.Duration
+}
+
+// DeratingStatus is the last temperature read and whether the operation is derated.
+type DeratingStatus struct {
+	Temperature float64   `json:"temperature"`
+	Derated     bool      `json:"derated"`
+	Since       time.Time `json:"since"`
+	Error       string    `json:"error,omitempty"`
+}
+
+var (
+	deratingMutex  = sync.Mutex{}
+	deratingStatus = DeratingStatus{}
+)
+
+func validateDerating() error {
+	d := driverConfig.Derating
+	if d == nil {
+		return nil
+	}
+	if d.Source == "" {
+		return fmt.Errorf("missing source")
+	}
+	if err := validateSource(d.Source); err != nil {
+		return err
+	}
+	if d.Scale == 0 {
+		d.Scale = DEFAULT_DERATING_SCALE
+		if strings.HasPrefix(d.Source, edgexSourcePrefix) {
+			d.Scale = 1
+		}
+	}
+	if d.Hysteresis == 0 {
+		d.Hysteresis = DEFAULT_DERATING_HYSTERESIS
+	}
+	if d.GapFactor == 0 {
+		d.GapFactor = DEFAULT_DERATING_GAP_FACTOR
+	}
+	if d.MaxDuty == 0 {
+		d.MaxDuty = DEFAULT_DERATING_MAX_DUTY
+	}
+	if d.Hysteresis < 0 || d.GapFactor < 1 || d.MaxDuty < 0 || d.MaxDuty > 100 {
+		return fmt.Errorf("hysteresis must be positive, gap_factor at least 1 and max_duty a percentage")
+	}
+	d.interval = DEFAULT_DERATING_INTERVAL
+	if d.Interval != "" {
+		interval, err := time.ParseDuration(d.Interval)
+		if err != nil || interval <= 0 {
+			return fmt.Errorf("invalid interval %q", d.Interval)
+		}
+		d.interval = interval
+	}
+	return nil
+}
+
+// derated reports whether the operation is derated.
+func derated() bool {
+	deratingMutex.Lock()
+	defer deratingMutex.Unlock()
+	return deratingStatus.Derated
+}
+
+// deratedGap stretches the command gap while the operation is derated.
+func deratedGap(gap time.Duration) time.Duration {
+	if d := driverConfig.Derating; d != nil && derated() {
+		return time.Duration(float64(gap) * d.GapFactor)
+	}
+	return gap
+}
+
+// validateSource checks a source read by readSource.
+func validateSource(source string) error {
+	if strings.HasPrefix(source, edgexSourcePrefix) && !strings.Contains(strings.TrimPrefix(source, edgexSourcePrefix), "/") {
+		return fmt.Errorf("source %q must be %s<device>/<resource>", source, edgexSourcePrefix)
+	}
+	return nil
+}
+
+// readSource reads the value of a sysfs file, or of the last reading of an EdgeX resource given as
+// edgex:<device>/<resource>, multiplied by scale. Bool readings read as 0 or 1.
+func readSource(source string, scale float64) (float64, error) {
+	var raw string
+	if strings.HasPrefix(source, edgexSourcePrefix) {
+		value, err := lastEdgexReading(strings.TrimPrefix(source, edgexSourcePrefix))
+		if err != nil {
+			return 0, err
+		}
+		raw = value
+	} else {
+		content, err := os.ReadFile(source)
+		if err != nil {
+			return 0, err
+		}
+		raw = string(content)
+	}
+	raw = strings.TrimSpace(raw)
+	value, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		on, boolErr := strconv.ParseBool(raw)
+		if boolErr != nil {
+			return 0, fmt.Errorf("cannot parse value %q of %s", raw, source)
+		}
+		value = float64(lineLevel(on))
+	}
+	return value * scale, nil
+}
+
+// lastEdgexReading returns the value of the last reading of device/resource stored by core-data.
+func lastEdgexReading(source string) (string, error) {
+	device, resource, _ := strings.Cut(source, "/")
+	base := os.Getenv("CORE_DATA_URL")
+	if base == "" {
+		base = defaultCoreDataURL
+	}
+	response, err := dependencyClient.Get(fmt.Sprintf("%s/api/v2/reading/device/name/%s/resourceName/%s?limit=1",
+		base, url.PathEscape(device), url.PathEscape(resource)))
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("core-data answered %s", response.Status)
+	}
+	var body struct {
+		Readings []struct {
+			Value string `json:"value"`
+		} `json:"readings"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&body); err != nil {
+		return "", err
+	}
+	if len(body.Readings) == 0 {
+		return "", fmt.Errorf("no reading of %s", source)
+	}
+	return body.Readings[0].Value, nil
+}
+
+// startDerating reads the temperature every interval when the derating section is set. An unreadable
+// source keeps the previous state, so a flaky sensor neither starts nor lifts the derating.
+func (s *SimpleDriver) startDerating() {
+	d := driverConfig.Derating
+	if d == nil {
+		return
+	}
+	goBackground(func() {
+		for {
+			temperature, err := readSource(d.Source, d.Scale)
+			deratingMutex.Lock()
+			previous := deratingStatus.Derated
+			if err != nil {
+				deratingStatus.Error = err.Error()
+			} else {
+				deratingStatus.Temperature, deratingStatus.Error = temperature, ""
+				if temperature >= d.Threshold {
+					deratingStatus.Derated = true
+				} else if temperature <= d.Threshold-d.Hysteresis {
+					deratingStatus.Derated = false
+				}
+			}
+			current := deratingStatus.Derated
+			if current != previous {
+				deratingStatus.Since = time.Now()
+			}
+			deratingMutex.Unlock()
+
+			if err != nil {
+				gpio.SampledLogf("temperature read", "Cannot read temperature from %s. Error: %s", d.Source, err)
+			} else {
+				logRecovered("temperature read")
+			}
+			if current != previous {
+				s.applyDerating(d, current, temperature)
+			}
+			supervisedSleep("derating", d.interval)
+		}
+	})
+}
+
+// applyDerating caps or releases the PWM duty cycles and publishes the new state.
+func (s *SimpleDriver) applyDerating(d *Derating, on bool, temperature float64) {
+	limit := 1.0
+	detail := fmt.Sprintf("lifted at %.1f°C", temperature)
+	if on {
+		limit = d.MaxDuty / 100
+		detail = fmt.Sprintf("%.1f°C over %.1f°C: command gap x%.1f, duty cycles capped to %.0f%%", temperature, d.Threshold, d.GapFactor, d.MaxDuty)
+	}
+	if err := gpio.SetDutyCap(limit); err != nil {
+		log.Printf("Cannot cap duty cycles. Error: %s", err)
+	}
+	log.Printf("Derating %s", detail)
+	audit("derating", deviceName(), detail)
+	cv, err := sdkModels.NewCommandValue(deratingResource, common.ValueTypeBool, on)
+	if err != nil {
+		log.Printf("Cannot create derating reading. Error: %s", err)
+		return
+	}
+	s.asyncCh <- &sdkModels.AsyncValues{
+		DeviceName:    deviceName(),
+		CommandValues: []*sdkModels.CommandValue{cv},
+	}
+}
+
+func (s *SimpleDriver) handleDerating(w http.ResponseWriter, r *http.Request) {
+	deratingMutex.Lock()
+	status := deratingStatus
+	deratingMutex.Unlock()
+	writeJSON(w, http.StatusOK, status)
+}
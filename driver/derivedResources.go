@@ -0,0 +1,150 @@
+package driver
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/edgexfoundry/device-gpiod/gpio"
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/common"
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/models"
+
+	sdkModels "github.com/edgexfoundry/device-sdk-go/v2/pkg/models"
+)
+
+// derivedKind describes a numeric resource computed from the activity of a line.
+type derivedKind struct {
+	Suffix      string
+	ValueType   string
+	Units       string
+	Description string
+}
+
+var (
+	derivedCount   = derivedKind{"Count", common.ValueTypeUint64, "count", "Number of activations"}
+	derivedRuntime = derivedKind{"RuntimeHours", common.ValueTypeFloat64, "h", "Accumulated active time"}
+	derivedEnergy  = derivedKind{"EnergyKWh", common.ValueTypeFloat64, "kWh", "Estimated energy consumption"}
+)
+
+type lineStats struct {
+	count   uint64
+	runtime time.Duration
+	onSince time.Time
+}
+
+var (
+	statsMutex = sync.Mutex{}
+	stats      = make(map[string]*lineStats)
+)
+
+func derivedResourceName(line string, kind derivedKind) string {
+	return fmt.Sprintf("%s-%s", line, kind.Suffix)
+}
+
+// derivedKinds returns the derived resources available for a line. Energy is only estimated when
+// the line declares the power rating of the load it drives.
+func derivedKinds(g gpio.GPIO) []derivedKind {
+	kinds := []derivedKind{derivedCount, derivedRuntime}
+	if g.Power > 0 {
+		kinds = append(kinds, derivedEnergy)
+	}
+	return kinds
+}
+
+// derivedProfileResources returns the device resources of the derived values of a line, to be
+// included in the generated device profile.
+func derivedProfileResources(g gpio.GPIO) []models.DeviceResource {
+	var resources []models.DeviceResource
+	for _, kind := range derivedKinds(g) {
+		name := derivedResourceName(g.Name, kind)
+		resources = append(resources, models.DeviceResource{
+			Name:        name,
+			Description: fmt.Sprintf("%s of %s", kind.Description, lineDescription(g)),
+			Attributes: map[string]interface{}{
+				"name":    g.Name,
+				"derived": kind.Suffix,
+			},
+			Properties: transformProperties(name, models.ResourceProperties{
+				ValueType: kind.ValueType,
+				ReadWrite: common.ReadWrite_R,
+				Units:     kind.Units,
+			}),
+		})
+	}
+	return resources
+}
+
+// updateLineStats accounts for a state change of a line.
+func updateLineStats(g gpio.GPIO) {
+	statsMutex.Lock()
+	defer statsMutex.Unlock()
+
+	st, ok := stats[g.Name]
+	if !ok {
+		st = &lineStats{}
+		stats[g.Name] = st
+	}
+	now := time.Now()
+	if g.State {
+		if st.onSince.IsZero() {
+			st.count++
+			st.onSince = now
+		}
+	} else if !st.onSince.IsZero() {
+		st.runtime += now.Sub(st.onSince)
+		st.onSince = time.Time{}
+	}
+}
+
+// derivedCommandValues returns typed readings for the derived resources of a line.
+func derivedCommandValues(g gpio.GPIO) []*sdkModels.CommandValue {
+	statsMutex.Lock()
+	st, ok := stats[g.Name]
+	var count uint64
+	var runtime time.Duration
+	if ok {
+		count = st.count
+		runtime = st.runtime
+		if !st.onSince.IsZero() {
+			runtime += time.Since(st.onSince)
+		}
+	}
+	statsMutex.Unlock()
+
+	var values []*sdkModels.CommandValue
+	for _, kind := range derivedKinds(g) {
+		var value interface{}
+		switch kind {
+		case derivedCount:
+			value = count
+		case derivedRuntime:
+			value = runtime.Hours()
+		case derivedEnergy:
+			value = runtime.Hours() * g.Power / 1000
+		}
+		name := derivedResourceName(g.Name, kind)
+		valueType, value, ok := transformValue(name, kind.ValueType, value)
+		if !ok {
+			continue
+		}
+		cv, err := sdkModels.NewCommandValue(name, valueType, value)
+		if err != nil {
+			log.Printf("Cannot create %s reading for gpio %s. Error: %s", kind.Suffix, g.Name, err)
+			continue
+		}
+		values = append(values, cv)
+	}
+	return values
+}
+
+// numericValue returns the value of a numeric derived reading as a float.
+func numericValue(cv *sdkModels.CommandValue) (float64, bool) {
+	switch v := cv.Value.(type) {
+	case uint64:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
@@ -0,0 +1,26 @@
+package driver
+
+import (
+	"log"
+
+	"github.com/edgexfoundry/device-gpiod/gpio"
+)
+
+// checkDeviceAccess verifies the configured chips can be opened before any line is requested, turning
+// opaque EPERM/ENOENT failures into remediation hints for the detected confinement.
+func (s *SimpleDriver) checkDeviceAccess() {
+	confinement := gpio.Confinement()
+	log.Printf("Running with %s confinement", confinement)
+	startupReport.Confinement = confinement
+	if gpio.ActiveSimulator() != nil {
+		return
+	}
+
+	chips := make([]string, 0, len(s.GpioList.Gpio))
+	for _, g := range s.GpioList.Gpio {
+		chips = append(chips, g.Chip)
+	}
+	for _, hint := range gpio.CheckAccess(chips) {
+		configWarning(hint)
+	}
+}
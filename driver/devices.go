@@ -0,0 +1,308 @@
+package driver
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+
+	"github.com/edgexfoundry/device-gpiod/gpio"
+	"github.com/edgexfoundry/device-sdk-go/v2/pkg/service"
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/common"
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/models"
+
+	sdkModels "github.com/edgexfoundry/device-sdk-go/v2/pkg/models"
+)
+
+const (
+	// lineProfileName is the generic profile of the devices with the gpio protocol
+	lineProfileName = "device-gpiod-line"
+	// defaultDeviceResource is the resource of the edges of an input device without a resource property
+	defaultDeviceResource = "Value"
+)
+
+// deviceLine is a line modelled as a device of its own through the properties of its gpio protocol,
+// e.g. one discovered and onboarded by a provision watcher. The cycle pipeline keeps running on the
+// lines of the gpio list of the service device only.
+type deviceLine struct {
+	line     *gpio.GPIO
+	resource string
+}
+
+var (
+	devicesMutex = sync.Mutex{}
+	deviceLines  = make(map[string]*deviceLine)
+)
+
+// gpioProtocol is the definition of the gpio protocol of a device, by property. A device with it is
+// onboarded from core-metadata alone, without being in the gpio configuration file.
+var gpioProtocol = map[string]string{
+	"chip":          "Chip of the line, e.g. gpiochip0; with line_name, the chip searched (all when unset)",
+	"offset":        "Offset of the line on the chip",
+	"line":          "Alias of offset",
+	"line_name":     "Kernel name of the line, instead of the offset",
+	"direction":     "input or output (default)",
+	"edge":          "Edges of an input published as readings: rising, falling or both (default)",
+	"bias":          "pull-up, pull-down or disabled",
+	"drive":         "push-pull (default), open-drain or open-source",
+	"active_low":    "true when the line is active low",
+	"consumer":      "Consumer label the line is requested with",
+	"debounce":      "Hardware debounce period of an input, e.g. 10ms",
+	"soft_debounce": "Software debounce period of an input, for chips without hardware debounce",
+	"resource":      "Resource of the readings and commands of the device (default Value)",
+	// Set by discovery, informational
+	"chipLabel": "Label of the chip",
+	"name":      "Kernel name of the line found by discovery",
+	"used":      "Whether the line was used when discovered",
+	"lines":     "Number of lines of the chip",
+	"parent":    "Device providing the chip, e.g. platform/soc/fe804000.i2c/i2c-1/1-0020",
+	"bus":       "Bus of the device providing the chip: i2c, spi, usb or platform",
+}
+
+// lineFromProtocol builds the line of a device from its gpio protocol properties, see gpioProtocol.
+func lineFromProtocol(name string, properties models.ProtocolProperties) (*gpio.GPIO, error) {
+	for key := range properties {
+		if _, ok := gpioProtocol[key]; !ok {
+			return nil, fmt.Errorf("device %s: unknown gpio protocol property %q", name, key)
+		}
+	}
+	offset := properties["offset"]
+	if offset == "" {
+		offset = properties["line"]
+	}
+	line, err := strconv.Atoi(offset)
+	switch {
+	case properties["line_name"] != "" && offset == "":
+		line = -1
+	case err != nil || properties["chip"] == "":
+		return nil, fmt.Errorf("device %s: gpio protocol needs a chip and an offset, or a line_name", name)
+	}
+	g := &gpio.GPIO{
+		Name:         name,
+		Chip:         properties["chip"],
+		Line:         line,
+		LineName:     properties["line_name"],
+		Direction:    properties["direction"],
+		Edge:         properties["edge"],
+		Bias:         properties["bias"],
+		Drive:        properties["drive"],
+		Consumer:     properties["consumer"],
+		Debounce:     properties["debounce"],
+		SoftDebounce: properties["soft_debounce"],
+	}
+	if g.Direction == gpio.DirectionInput {
+		g.Role = RoleInput
+	}
+	if activeLow, err := strconv.ParseBool(properties["active_low"]); err == nil {
+		g.ActiveLow = &activeLow
+	}
+	if err := g.Validate(); err != nil {
+		return nil, err
+	}
+	if g.LineName != "" {
+		list := &gpio.GPIOList{Gpio: []gpio.GPIO{*g}}
+		if err := list.ResolveLineNames(); err != nil {
+			return nil, fmt.Errorf("device %s: %s", name, err)
+		}
+		*g = list.Gpio[0]
+	}
+	if g.Line < 0 {
+		return nil, fmt.Errorf("device %s: line %s not found", name, g.LineName)
+	}
+	return g, nil
+}
+
+// addGpioDevice takes the line of a device with the gpio protocol. Input lines are watched and their
+// edges published as readings of the device. Devices without the gpio protocol, the service device
+// included, are left alone.
+func (s *SimpleDriver) addGpioDevice(name string, protocols map[string]models.ProtocolProperties) error {
+	properties, ok := protocols[discoveryProtocol]
+	if !ok || isServiceDevice(name) {
+		return nil
+	}
+	g, err := lineFromProtocol(name, properties)
+	if err != nil {
+		return err
+	}
+	for _, configured := range s.GpioList.Gpio {
+		if configured.Chip == g.Chip && configured.Line == g.Line {
+			return fmt.Errorf("device %s: line %d of %s is already used by gpio %s", name, g.Line, g.Chip, configured.Name)
+		}
+	}
+	devicesMutex.Lock()
+	defer devicesMutex.Unlock()
+	for other, d := range deviceLines {
+		if other != name && d.line.Chip == g.Chip && d.line.Line == g.Line {
+			return fmt.Errorf("device %s: line %d of %s is already used by device %s", name, g.Line, g.Chip, other)
+		}
+	}
+	d := &deviceLine{line: g, resource: properties["resource"]}
+	if d.resource == "" {
+		d.resource = defaultDeviceResource
+	}
+	if g.Role == RoleInput {
+		if err := g.Watch(func(evt gpio.Event) { s.pushDeviceEdge(name, d.resource, evt) }); err != nil {
+			return fmt.Errorf("device %s: %s", name, err)
+		}
+	}
+	deviceLines[name] = d
+	log.Printf("Device %s bound to line %d of %s", name, g.Line, g.Chip)
+	if autoProvision {
+		go provisionDeviceProfile(name, d)
+	}
+	return nil
+}
+
+// lineProfile builds the profile of a device with the gpio protocol: the resource of the line, read
+// only for an input.
+func lineProfile(name string, resource string, input bool) models.DeviceProfile {
+	readWrite := common.ReadWrite_RW
+	if input {
+		readWrite = common.ReadWrite_R
+	}
+	return models.DeviceProfile{
+		Name:         name,
+		Manufacturer: "Concept Reply",
+		Model:        "gpio-line",
+		Description:  "Line of a device onboarded with the gpio protocol",
+		Labels:       []string{"gpiod", discoveryProtocol, provisionLabel},
+		DeviceResources: []models.DeviceResource{
+			{
+				Name:        resource,
+				Description: "Level of the line",
+				Properties: models.ResourceProperties{
+					ValueType: common.ValueTypeBool,
+					ReadWrite: readWrite,
+				},
+			},
+		},
+	}
+}
+
+// provisionLineProfile creates the generic profile of the devices with the gpio protocol, so they can
+// be added to core-metadata with it and no other file.
+func provisionLineProfile() error {
+	ds := service.RunningService()
+	if _, err := ds.GetProfileByName(lineProfileName); err == nil {
+		return nil
+	}
+	log.Printf("Device profile '%s' not found. Creating it...", lineProfileName)
+	if _, err := ds.AddDeviceProfile(lineProfile(lineProfileName, defaultDeviceResource, false)); err != nil {
+		return fmt.Errorf("cannot add device profile '%s': %s", lineProfileName, err)
+	}
+	return nil
+}
+
+// provisionDeviceProfile builds the resources of a device from its gpio protocol when its profile
+// does not have them, e.g. a custom resource or an input added with the generic profile: a profile
+// of the name of the device is created and the device moved to it.
+func provisionDeviceProfile(name string, d *deviceLine) {
+	ds := service.RunningService()
+	input := d.line.Role == RoleInput
+	if resource, ok := ds.DeviceResource(name, d.resource); ok {
+		if !input || resource.Properties.ReadWrite == common.ReadWrite_R {
+			return
+		}
+	}
+	if _, err := ds.GetProfileByName(name); err != nil {
+		if _, err := ds.AddDeviceProfile(lineProfile(name, d.resource, input)); err != nil {
+			log.Printf("Cannot add device profile '%s'. Error: %s", name, err)
+			return
+		}
+	}
+	device, err := ds.GetDeviceByName(name)
+	if err != nil || device.ProfileName == name {
+		return
+	}
+	device.ProfileName = name
+	if err := ds.UpdateDevice(device); err != nil {
+		log.Printf("Cannot move device %s to profile '%s'. Error: %s", name, name, err)
+		return
+	}
+	log.Printf("Device %s moved to the profile '%s' built from its gpio protocol", name, name)
+}
+
+// removeGpioDevice releases the line of a device.
+func removeGpioDevice(name string) {
+	devicesMutex.Lock()
+	d, ok := deviceLines[name]
+	delete(deviceLines, name)
+	devicesMutex.Unlock()
+	if !ok {
+		return
+	}
+	if err := d.line.Release(); err != nil {
+		log.Printf("Cannot release line of device %s. Error: %s", name, err)
+	}
+}
+
+// isServiceDevice tells whether name is the device of the service, running the cycle pipeline.
+func isServiceDevice(name string) bool {
+	return name == deviceName()
+}
+
+func findDeviceLine(name string) (*deviceLine, bool) {
+	devicesMutex.Lock()
+	defer devicesMutex.Unlock()
+	d, ok := deviceLines[name]
+	return d, ok
+}
+
+func (s *SimpleDriver) pushDeviceEdge(name string, resource string, evt gpio.Event) {
+	cv, err := sdkModels.NewCommandValue(resource, common.ValueTypeBool, evt.Value == 1)
+	if err != nil {
+		log.Printf("Cannot create reading of device %s. Error: %s", name, err)
+		return
+	}
+	s.asyncCh <- &sdkModels.AsyncValues{
+		DeviceName:    name,
+		CommandValues: []*sdkModels.CommandValue{cv},
+	}
+}
+
+// readDeviceLine reads the line of a device, whatever the resource, as Bool.
+func readDeviceLine(d *deviceLine, req sdkModels.CommandRequest) (*sdkModels.CommandValue, error) {
+	read := d.line.ReadBack
+	if d.line.Role == RoleInput {
+		read = d.line.Value
+	}
+	value, err := read()
+	if err != nil {
+		return nil, fmt.Errorf("cannot read %s: %s", d.line.Name, err)
+	}
+	return sdkModels.NewCommandValue(req.DeviceResourceName, common.ValueTypeBool, value == 1)
+}
+
+// writeDeviceLine drives the output line of a device.
+func (s *SimpleDriver) writeDeviceLine(d *deviceLine, param *sdkModels.CommandValue) error {
+	if d.line.Role == RoleInput {
+		return fmt.Errorf("device %s is an input", d.line.Name)
+	}
+	on, err := param.BoolValue()
+	if err != nil {
+		return err
+	}
+	return s.driveDeviceLine(d, on)
+}
+
+func (s *SimpleDriver) driveDeviceLine(d *deviceLine, on bool) error {
+	if on && len(concurrencyLimitsOf(d.line.Name)) > 0 {
+		concurrencyMutex.Lock()
+		defer concurrencyMutex.Unlock()
+		if err := s.admitConcurrent(d.line.Name, "device"); err != nil {
+			return err
+		}
+	}
+	var err error
+	if on {
+		err = d.line.Up()
+	} else {
+		err = d.line.Down()
+	}
+	if err != nil {
+		return fmt.Errorf("cannot set %s to %t: %s", d.line.Name, on, err)
+	}
+	d.line.State = on
+	audit("device-write", d.line.Name, fmt.Sprintf("set to %t", on))
+	return nil
+}
@@ -0,0 +1,75 @@
+package driver
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/edgexfoundry/device-gpiod/gpio"
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/models"
+
+	sdkModels "github.com/edgexfoundry/device-sdk-go/v2/pkg/models"
+)
+
+const (
+	discoveryProtocol = "gpio"
+	discoveryLabel    = "auto-discovery"
+)
+
+// discoveredDevices describes every line present on the board as a device, so provision watchers can
+// onboard them by chip, offset or kernel name, and the lines of an expander by the bus, parent device
+// and line count of its chip. Lines already in the gpio list carry their configured name and labels.
+func (s *SimpleDriver) discoveredDevices() []sdkModels.DiscoveredDevice {
+	configured := make(map[string]*gpio.GPIO)
+	for i := range s.GpioList.Gpio {
+		g := &s.GpioList.Gpio[i]
+		configured[fmt.Sprintf("%s/%d", g.Chip, g.Line)] = g
+	}
+
+	lines := gpio.Enumerate()
+	chipLines := make(map[string]int)
+	parents := make(map[string]string)
+	for _, line := range lines {
+		chipLines[line.Chip]++
+		if _, ok := parents[line.Chip]; !ok {
+			parents[line.Chip] = gpio.ChipParent(line.Chip)
+		}
+	}
+
+	var devices []sdkModels.DiscoveredDevice
+	for _, line := range lines {
+		name := fmt.Sprintf("%s-%d", line.Chip, line.Line)
+		if line.Name != "" {
+			name = fmt.Sprintf("%s-%s", line.Chip, line.Name)
+		}
+		description := fmt.Sprintf("line %d of %s (%s)", line.Line, line.Chip, line.ChipLabel)
+		labels := []string{discoveryLabel, line.Chip}
+		if line.Used {
+			labels = append(labels, "used")
+		}
+		if g, ok := configured[fmt.Sprintf("%s/%d", line.Chip, line.Line)]; ok {
+			labels = append(append(labels, "configured", g.Name), g.Labels...)
+			if g.Description != "" {
+				description = g.Description
+			}
+		}
+		devices = append(devices, sdkModels.DiscoveredDevice{
+			Name: name,
+			Protocols: map[string]models.ProtocolProperties{
+				discoveryProtocol: {
+					"chip":      line.Chip,
+					"chipLabel": line.ChipLabel,
+					"offset":    strconv.Itoa(line.Line),
+					"name":      line.Name,
+					"consumer":  line.Consumer,
+					"used":      strconv.FormatBool(line.Used),
+					"lines":     strconv.Itoa(chipLines[line.Chip]),
+					"parent":    parents[line.Chip],
+					"bus":       gpio.ChipBus(parents[line.Chip]),
+				},
+			},
+			Description: description,
+			Labels:      labels,
+		})
+	}
+	return devices
+}
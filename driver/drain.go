@@ -0,0 +1,73 @@
+package driver
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	drainPoll = time.Duration(250) * time.Millisecond
+)
+
+var (
+	drainMutex  = sync.Mutex{}
+	drainPeriod = time.Duration(0)
+	drainActive = false
+	errDraining = errors.New("service is draining for shutdown")
+)
+
+// loadDrainPeriod reads DRAIN_PERIOD, how long a graceful stop waits for the running cycle step to
+// complete. Unset, the service stops right away as before.
+func loadDrainPeriod() {
+	value := os.Getenv("DRAIN_PERIOD")
+	if value == "" {
+		return
+	}
+	if d, err := time.ParseDuration(value); err == nil && d >= 0 {
+		drainPeriod = d
+	} else {
+		log.Printf("Cannot parse DRAIN_PERIOD. Picking default value %s...", drainPeriod)
+	}
+}
+
+// draining reports whether a graceful stop is in progress, in which case no new step is started.
+func draining() bool {
+	drainMutex.Lock()
+	defer drainMutex.Unlock()
+	return drainActive
+}
+
+// drain lets the running cycle step (pump, reverse, clean, ...) complete within the drain period, before
+// Stop drives the safe state. It is a no-op when no drain period is configured.
+func (s *SimpleDriver) drain() {
+	drainMutex.Lock()
+	period := drainPeriod
+	drainActive = period > 0
+	drainMutex.Unlock()
+	if period <= 0 {
+		return
+	}
+
+	step := currentPhase()
+	if step.Name != phaseIdle && step.Name != phaseGap {
+		log.Printf("Draining: waiting up to %s for the %s step to complete", period, step.Name)
+		deadline := time.Now().Add(period)
+		for {
+			current := currentPhase()
+			if current.Name != step.Name || !current.Since.Equal(step.Since) {
+				log.Printf("Draining: %s step completed", step.Name)
+				break
+			}
+			if time.Now().After(deadline) {
+				log.Printf("Draining: %s step still running after %s, forcing safe state", step.Name, period)
+				audit("drain-expired", step.Name, fmt.Sprintf("step interrupted after %s", period))
+				break
+			}
+			time.Sleep(drainPoll)
+		}
+	}
+}
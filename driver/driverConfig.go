@@ -0,0 +1,189 @@
+package driver
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/edgexfoundry/device-gpiod/gpio"
+	"gopkg.in/yaml.v3"
+)
+
+// DriverConfig holds the driver level sections of the GPIO configuration file, next to the gpio list
+// parsed by the gpio package.
+type DriverConfig struct {
+	Hooks             []PhaseHook                `yaml:"hooks"`
+	Scripts           Scripts                    `yaml:"scripts"`
+	Lights            []RolePattern              `yaml:"lights"`
+	Groups            []LineGroup                `yaml:"groups"`
+	Virtual           []VirtualResource          `yaml:"virtual"`
+	Transforms        []ResourceTransform        `yaml:"transforms"`
+	Thresholds        []Threshold                `yaml:"thresholds"`
+	Statistics        []Statistic                `yaml:"statistics"`
+	Assertions        []TimingAssertion          `yaml:"assertions"`
+	Limits            []DutyLimit                `yaml:"limits"`
+	Profiles          map[string]ConfigProfile   `yaml:"profiles"`
+	ABTest            *ABTest                    `yaml:"ab_test"`
+	Indicator         *Indicator                 `yaml:"indicator"`
+	QuietHours        *QuietHours                `yaml:"quiet_hours"`
+	TwoPerson         *TwoPersonRule             `yaml:"two_person"`
+	Payloads          *PayloadShape              `yaml:"payloads"`
+	WarmUp            *WarmUp                    `yaml:"warmup"`
+	Dependencies      []Dependency               `yaml:"dependencies"`
+	Budgets           *Budgets                   `yaml:"budgets"`
+	LoadShedding      *LoadShedding              `yaml:"load_shedding"`
+	FaultInjection    *gpio.FaultProfile         `yaml:"fault_injection"`
+	Maintenance       *Maintenance               `yaml:"maintenance"`
+	CleanRecipe       []CleanStage               `yaml:"clean_recipe"`
+	Consumables       []Consumable               `yaml:"consumables"`
+	Derating          *Derating                  `yaml:"derating"`
+	Health            []HealthProbe              `yaml:"health"`
+	Schedule          *Schedule                  `yaml:"schedule"`
+	Power             *PowerSupply               `yaml:"power"`
+	Metered           *MeteredLink               `yaml:"metered"`
+	Exclusive         [][]string                 `yaml:"exclusive"`
+	ProvisionWatchers []ProvisionWatcherSpec     `yaml:"provision_watchers"`
+	Concurrency       []ConcurrencyLimit         `yaml:"concurrency"`
+	Verbosity         map[string]*PhaseVerbosity `yaml:"verbosity"`
+}
+
+var (
+	driverConfig = &DriverConfig{}
+)
+
+func parseDriverConfig(fileName string, cfg *DriverConfig) error {
+	yamlFile, err := os.ReadFile(fileName)
+	if err != nil {
+		log.Printf("Cannot read driver configuration file %s. Error: %s", fileName, err)
+		return err
+	}
+
+	err = yaml.Unmarshal(yamlFile, cfg)
+	if err != nil {
+		log.Printf("Cannot parse driver configuration file %s. Error: %s", fileName, err)
+		return err
+	}
+
+	return nil
+}
+
+// marshalYaml marshals v as the configuration files are written, indented by two spaces.
+func marshalYaml(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(v); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// applyDriverConfig makes cfg the active driver configuration, validating and compiling its sections.
+func applyDriverConfig(cfg *DriverConfig) error {
+	driverConfig = cfg
+	if err := validateHooks(); err != nil {
+		return fmt.Errorf("hooks configuration validation failed: %s", err.Error())
+	}
+	if err := compileScripts(); err != nil {
+		return fmt.Errorf("scripts configuration validation failed: %s", err.Error())
+	}
+	if err := compileLightPatterns(); err != nil {
+		return fmt.Errorf("lights configuration validation failed: %s", err.Error())
+	}
+	if err := validateGroups(); err != nil {
+		return fmt.Errorf("groups configuration validation failed: %s", err.Error())
+	}
+	if err := compileVirtualResources(); err != nil {
+		return fmt.Errorf("virtual configuration validation failed: %s", err.Error())
+	}
+	if err := validateTransforms(); err != nil {
+		return fmt.Errorf("transforms configuration validation failed: %s", err.Error())
+	}
+	if err := validateThresholds(); err != nil {
+		return fmt.Errorf("thresholds configuration validation failed: %s", err.Error())
+	}
+	if err := validateStatistics(); err != nil {
+		return fmt.Errorf("statistics configuration validation failed: %s", err.Error())
+	}
+	if err := validateAssertions(); err != nil {
+		return fmt.Errorf("assertions configuration validation failed: %s", err.Error())
+	}
+	if err := validateLimits(); err != nil {
+		return fmt.Errorf("limits configuration validation failed: %s", err.Error())
+	}
+	if err := validateProfiles(); err != nil {
+		return fmt.Errorf("profiles configuration validation failed: %s", err.Error())
+	}
+	if err := validateABTest(); err != nil {
+		return fmt.Errorf("ab_test configuration validation failed: %s", err.Error())
+	}
+	if err := validateIndicator(); err != nil {
+		return fmt.Errorf("indicator configuration validation failed: %s", err.Error())
+	}
+	if err := validateQuietHours(); err != nil {
+		return fmt.Errorf("quiet_hours configuration validation failed: %s", err.Error())
+	}
+	if err := validateTwoPerson(); err != nil {
+		return fmt.Errorf("two_person configuration validation failed: %s", err.Error())
+	}
+	if err := validatePayloads(); err != nil {
+		return fmt.Errorf("payloads configuration validation failed: %s", err.Error())
+	}
+	if err := validateWarmUp(); err != nil {
+		return fmt.Errorf("warmup configuration validation failed: %s", err.Error())
+	}
+	if err := validateDependencies(); err != nil {
+		return fmt.Errorf("dependencies configuration validation failed: %s", err.Error())
+	}
+	if err := validateBudgets(); err != nil {
+		return fmt.Errorf("budgets configuration validation failed: %s", err.Error())
+	}
+	if err := validateLoadShedding(); err != nil {
+		return fmt.Errorf("load_shedding configuration validation failed: %s", err.Error())
+	}
+	if err := validateFaultInjection(); err != nil {
+		return fmt.Errorf("fault_injection configuration validation failed: %s", err.Error())
+	}
+	if err := validateMaintenance(); err != nil {
+		return fmt.Errorf("maintenance configuration validation failed: %s", err.Error())
+	}
+	if err := validateCleanRecipe(); err != nil {
+		return fmt.Errorf("clean_recipe configuration validation failed: %s", err.Error())
+	}
+	if err := validateConsumables(); err != nil {
+		return fmt.Errorf("consumables configuration validation failed: %s", err.Error())
+	}
+	if err := validateDerating(); err != nil {
+		return fmt.Errorf("derating configuration validation failed: %s", err.Error())
+	}
+	if err := validateHealth(); err != nil {
+		return fmt.Errorf("health configuration validation failed: %s", err.Error())
+	}
+	if err := validateSchedule(); err != nil {
+		return fmt.Errorf("schedule configuration validation failed: %s", err.Error())
+	}
+	if err := validatePower(); err != nil {
+		return fmt.Errorf("power configuration validation failed: %s", err.Error())
+	}
+	if err := validateMetered(); err != nil {
+		return fmt.Errorf("metered configuration validation failed: %s", err.Error())
+	}
+	if err := validateExclusive(); err != nil {
+		return fmt.Errorf("exclusive configuration validation failed: %s", err.Error())
+	}
+	if err := validateProvisionWatchers(); err != nil {
+		return fmt.Errorf("provision watchers configuration validation failed: %s", err.Error())
+	}
+	if err := validateConcurrency(); err != nil {
+		return fmt.Errorf("concurrency configuration validation failed: %s", err.Error())
+	}
+	if err := validateVerbosity(); err != nil {
+		return fmt.Errorf("verbosity configuration validation failed: %s", err.Error())
+	}
+	activateFaultInjection()
+	return nil
+}
@@ -0,0 +1,144 @@
+package driver
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/common"
+
+	sdkModels "github.com/edgexfoundry/device-sdk-go/v2/pkg/models"
+)
+
+const (
+	dutyLimitResource = "DutyLimit"
+	limitReject       = "reject"
+	limitDefer        = "defer"
+	// Resolution of the search of the earliest time a request fits the limit
+	dutyLimitSteps = 120
+)
+
+var errDutyLimit = errors.New("duty cycle limit exceeded")
+
+// DutyLimit caps the time a line may be on over any rolling window, e.g. a pump on at most 70% of any
+// hour. Requests exceeding it are rejected, or deferred until they fit when action is defer.
+type DutyLimit struct {
+	Line    string  `yaml:"line"`
+	MaxDuty float64 `yaml:"max_duty"`
+	Window  string  `yaml:"window"`
+	Action  string  `yaml:"action"`
+	window  time.Duration
+}
+
+// DutyLimitError tells when a request limited by a duty cycle limit may be retried.
+type DutyLimitError struct {
+	Limit DutyLimit
+	Wait  time.Duration
+}
+
+func (e *DutyLimitError) Error() string {
+	if e.Wait < 0 {
+		return fmt.Sprintf("%s: %s can be on at most %.0f%% of %s", errDutyLimit, e.Limit.Line, e.Limit.MaxDuty*100, e.Limit.Window)
+	}
+	return fmt.Sprintf("%s: %s can be on at most %.0f%% of %s, retry in %s",
+		errDutyLimit, e.Limit.Line, e.Limit.MaxDuty*100, e.Limit.Window, e.Wait.Round(time.Second))
+}
+
+func (e *DutyLimitError) Unwrap() error {
+	return errDutyLimit
+}
+
+// validateLimits checks the limits section of the configuration file.
+func validateLimits() error {
+	names := make(map[string]bool)
+	for i := range driverConfig.Limits {
+		l := &driverConfig.Limits[i]
+		if l.Line == "" || names[l.Line] {
+			return fmt.Errorf("limit lines must be unique and not empty")
+		}
+		names[l.Line] = true
+		if l.MaxDuty <= 0 || l.MaxDuty > 1 {
+			return fmt.Errorf("limit of %s: max_duty must be in (0, 1]", l.Line)
+		}
+		window, err := time.ParseDuration(l.Window)
+		if err != nil || window <= 0 {
+			return fmt.Errorf("limit of %s: invalid window %q", l.Line, l.Window)
+		}
+		l.window = window
+		switch l.Action {
+		case "":
+			l.Action = limitReject
+		case limitReject, limitDefer:
+		default:
+			return fmt.Errorf("limit of %s: unknown action %q", l.Line, l.Action)
+		}
+	}
+	return nil
+}
+
+func limitFor(line string) (DutyLimit, bool) {
+	for _, l := range driverConfig.Limits {
+		if l.Line == line {
+			return l, true
+		}
+	}
+	return DutyLimit{}, false
+}
+
+// dutyWait returns how long to wait before turning the line on for runFor (0 when unknown) fits its
+// limit, or a negative value when it can never fit.
+func dutyWait(l DutyLimit, runFor time.Duration) time.Duration {
+	budget := time.Duration(l.MaxDuty * float64(l.window))
+	if runFor > budget {
+		return -1
+	}
+	now := time.Now()
+	step := l.window / dutyLimitSteps
+	for wait := time.Duration(0); wait <= l.window; wait += step {
+		// Past on time still inside the window ending at the end of the run
+		used := onTimeBetween(l.Line, now.Add(wait+runFor-l.window), now)
+		if used+runFor <= budget && used < budget {
+			return wait
+		}
+	}
+	return l.window
+}
+
+// checkDutyLimit returns a *DutyLimitError when turning the line on for runFor would exceed its duty
+// cycle limit, auditing and publishing the refusal.
+func (s *SimpleDriver) checkDutyLimit(line string, runFor time.Duration) error {
+	l, ok := limitFor(line)
+	if !ok {
+		return nil
+	}
+	wait := dutyWait(l, runFor)
+	if wait == 0 {
+		return nil
+	}
+	err := &DutyLimitError{Limit: l, Wait: wait}
+	audit("duty-limit", line, err.Error())
+	recordTimelineEvent(line, "duty-limit", err.Error())
+
+	payload, jsonErr := shapePayload(dutyLimitResource, map[string]interface{}{
+		"line":    line,
+		"maxDuty": l.MaxDuty,
+		"window":  l.Window,
+		"action":  l.Action,
+		"wait":    wait.String(),
+	})
+	if jsonErr != nil {
+		log.Printf("Cannot marshal duty limit event. Error: %s", jsonErr)
+		return err
+	}
+	cv, cvErr := sdkModels.NewCommandValue(dutyLimitResource, common.ValueTypeString, string(payload))
+	if cvErr != nil {
+		log.Printf("Cannot create duty limit reading. Error: %s", cvErr)
+		return err
+	}
+	s.asyncCh <- &sdkModels.AsyncValues{
+		DeviceName:    deviceName(),
+		CommandValues: []*sdkModels.CommandValue{cv},
+	}
+	return err
+}
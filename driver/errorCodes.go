@@ -0,0 +1,100 @@
+package driver
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"syscall"
+
+	"github.com/edgexfoundry/device-gpiod/gpio"
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/common"
+)
+
+const errorCodesRoute = common.ApiBase + "/errors"
+
+var errUnknownDevice = errors.New("unknown device")
+
+// errorCodes are the stable codes of the known errors, checked in order with errors.Is. The text of a
+// code is the message error.<code>, so UIs can show it in the configured locale.
+var errorCodes = []struct {
+	err  error
+	code string
+}{
+	{errUnknownDevice, "unknown-device"},
+	{errUnknownLine, "unknown-line"},
+	{errNotWritable, "not-writable"},
+	{errNotExposed, "not-exposed"},
+	{errNotPwmLine, "not-pwm-line"},
+	{errDutyLimit, "duty-limit"},
+	{errDraining, "draining"},
+	{errNoCycle, "no-cycle"},
+	{errStreamBudget, "stream-budget"},
+	{errUnsignedConfig, "unsigned-config"},
+	{errStandby, "standby"},
+	{errInterlocked, "interlocked"},
+	{errConcurrencyLimit, "concurrency-limit"},
+	{errOverrideDuration, "invalid-override"},
+	{gpio.ErrYielded, "yielded"},
+	{gpio.ErrOverridden, "overridden"},
+	{gpio.ErrInhibited, "inhibited"},
+	{gpio.ErrNotRequested, "not-requested"},
+	{gpio.ErrInjectedWrite, "injected-fault"},
+	{syscall.EBUSY, "line-busy"},
+	{syscall.EACCES, "permission-denied"},
+	{syscall.EPERM, "permission-denied"},
+}
+
+// statusCodes are the codes of the errors without a known cause, by HTTP status.
+var statusCodes = map[int]string{
+	http.StatusBadRequest:          "invalid-request",
+	http.StatusUnauthorized:        "unauthorized",
+	http.StatusForbidden:           "forbidden",
+	http.StatusNotFound:            "not-found",
+	http.StatusConflict:            "conflict",
+	http.StatusPreconditionFailed:  "precondition-failed",
+	http.StatusTooManyRequests:     "too-many-requests",
+	http.StatusNotImplemented:      "not-implemented",
+	http.StatusServiceUnavailable:  "unavailable",
+	http.StatusInternalServerError: "internal",
+}
+
+// errorCode returns the code of err, else the one of the HTTP status (0 for command errors), else
+// request-failed.
+func errorCode(err error, status int) string {
+	for _, known := range errorCodes {
+		if errors.Is(err, known.err) {
+			return known.code
+		}
+	}
+	if code, ok := statusCodes[status]; ok {
+		return code
+	}
+	return "request-failed"
+}
+
+// errorText returns the text of the code in the configured locale, empty when it has none.
+func errorText(code string) string {
+	if _, ok := messages["error."+code]; !ok {
+		return ""
+	}
+	return tr("error." + code)
+}
+
+// commandError prefixes the error of a command handler with its code in brackets, e.g.
+// "SimpleDriver.HandleWriteCommands; [not-writable] gpio is not writable Relay1", keeping err wrapped.
+func commandError(handler string, err error) error {
+	return fmt.Errorf("%s; [%s] %w", handler, errorCode(err, 0), err)
+}
+
+// handleErrorCodes returns the error codes with their text in the configured locale.
+func (s *SimpleDriver) handleErrorCodes(w http.ResponseWriter, r *http.Request) {
+	codes := make(map[string]string)
+	for _, known := range errorCodes {
+		codes[known.code] = errorText(known.code)
+	}
+	for _, code := range statusCodes {
+		codes[code] = errorText(code)
+	}
+	codes["request-failed"] = errorText("request-failed")
+	writeJSON(w, http.StatusOK, map[string]interface{}{"locale": locale, "codes": codes})
+}
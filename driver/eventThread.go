@@ -0,0 +1,70 @@
+package driver
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/edgexfoundry/device-gpiod/gpio"
+)
+
+// parseEventThread reads the placement of the threads handling the edge events: EVENT_CPUS pins them
+// to a list of CPUs (e.g. 2,3 or 2-3), EVENT_NICE sets their nice value and EVENT_RT_PRIORITY (1-99)
+// runs them with SCHED_FIFO, which requires CAP_SYS_NICE. A real-time priority starves the other
+// threads of the CPU while events keep coming, so pin them to a CPU kept free of other work.
+func parseEventThread() {
+	var t gpio.EventThread
+	if env := os.Getenv("EVENT_CPUS"); env != "" {
+		cpus, err := parseCPUList(env)
+		if err != nil {
+			log.Printf("Cannot parse EVENT_CPUS. Picking default value (any CPU)... Error: %s", err)
+		} else {
+			t.CPUs = cpus
+		}
+	}
+	if env := os.Getenv("EVENT_NICE"); env != "" {
+		nice, err := strconv.Atoi(env)
+		if err != nil || nice < -20 || nice > 19 {
+			log.Printf("Cannot parse EVENT_NICE. Picking default value 0...")
+		} else {
+			t.Nice = nice
+		}
+	}
+	if env := os.Getenv("EVENT_RT_PRIORITY"); env != "" {
+		priority, err := strconv.Atoi(env)
+		if err != nil || priority < 1 || priority > 99 {
+			log.Printf("Cannot parse EVENT_RT_PRIORITY. Picking default value (no SCHED_FIFO)...")
+		} else {
+			t.RTPriority = priority
+		}
+	}
+	if len(t.CPUs) == 0 && t.Nice == 0 && t.RTPriority == 0 {
+		return
+	}
+	gpio.SetEventThread(t)
+	log.Printf("Event threads placed on CPUs %v, nice %d, SCHED_FIFO priority %d", t.CPUs, t.Nice, t.RTPriority)
+}
+
+// parseCPUList parses a list of CPUs in the format of cpuset, e.g. 0,2-3.
+func parseCPUList(list string) ([]int, error) {
+	var cpus []int
+	for _, part := range strings.Split(list, ",") {
+		bounds := strings.SplitN(strings.TrimSpace(part), "-", 2)
+		first, err := strconv.Atoi(bounds[0])
+		if err != nil || first < 0 {
+			return nil, fmt.Errorf("invalid cpu %q", part)
+		}
+		last := first
+		if len(bounds) == 2 {
+			if last, err = strconv.Atoi(bounds[1]); err != nil || last < first {
+				return nil, fmt.Errorf("invalid cpu range %q", part)
+			}
+		}
+		for cpu := first; cpu <= last; cpu++ {
+			cpus = append(cpus, cpu)
+		}
+	}
+	return cpus, nil
+}
@@ -0,0 +1,72 @@
+package driver
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/edgexfoundry/device-gpiod/gpio"
+)
+
+var (
+	errInterlocked = errors.New("gpio is interlocked")
+
+	// exclusiveMutex serializes the check and the write of the lines of exclusive sets, so two of
+	// them are not driven on by concurrent writes
+	exclusiveMutex = sync.Mutex{}
+)
+
+// validateExclusive checks the exclusive section of the configuration file: sets of lines never on
+// together, such as the open and close coils of a valve.
+func validateExclusive() error {
+	for i, set := range driverConfig.Exclusive {
+		seen := make(map[string]bool)
+		for _, name := range set {
+			if name == "" || seen[name] {
+				return fmt.Errorf("set %d: empty or repeated line %q", i, name)
+			}
+			seen[name] = true
+		}
+		if len(set) < 2 {
+			return fmt.Errorf("set %d: at least two lines are required", i)
+		}
+	}
+	return nil
+}
+
+// exclusivePartners returns the lines declared exclusive with the named one.
+func exclusivePartners(name string) []string {
+	var partners []string
+	for _, set := range driverConfig.Exclusive {
+		for _, line := range set {
+			if line != name {
+				continue
+			}
+			for _, other := range set {
+				if other != name {
+					partners = append(partners, other)
+				}
+			}
+		}
+	}
+	return partners
+}
+
+// checkExclusive refuses to drive on a line while one of its exclusive partners is on. Must be called
+// holding exclusiveMutex.
+func (s *SimpleDriver) checkExclusive(g *gpio.GPIO) error {
+	for _, name := range exclusivePartners(g.Name) {
+		partner, ok := s.findGpio(name)
+		if !ok {
+			continue
+		}
+		on := partner.State
+		if value, err := partner.Value(); err == nil {
+			on = value == 1
+		}
+		if on {
+			return fmt.Errorf("%w: %s is on", errInterlocked, name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,212 @@
+package driver
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/common"
+)
+
+const (
+	exportRoute       = common.ApiBase + "/export"
+	exportTimeSeries  = "timeseries"
+	exportAudit       = "audit"
+	exportFormatCSV   = "csv"
+	exportTimeLayout  = "20060102T150405"
+	exportContentType = "text/csv"
+)
+
+type exportRequest struct {
+	Source   string `json:"source"`
+	Resource string `json:"resource"`
+	From     string `json:"from"`
+	To       string `json:"to"`
+	Format   string `json:"format"`
+	Path     string `json:"path"`
+}
+
+// exportAllowed reports whether dir is inside one of the directories listed in EXPORT_DIRS (comma
+// separated, e.g. the mount point of USB sticks). Exports to disk are refused when it is not set.
+func exportAllowed(dir string) bool {
+	dir = filepath.Clean(dir)
+	for _, root := range strings.Split(os.Getenv("EXPORT_DIRS"), ",") {
+		root = filepath.Clean(strings.TrimSpace(root))
+		if root == "." || root == "" {
+			continue
+		}
+		if dir == root || strings.HasPrefix(dir, root+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// auditBetween returns the audit entries between from and to, read from AUDIT_LOG_FILE when set and
+// from the entries kept in memory otherwise.
+func auditBetween(from time.Time, to time.Time) ([]AuditEntry, error) {
+	var entries []AuditEntry
+	fileName := os.Getenv("AUDIT_LOG_FILE")
+	if fileName == "" {
+		entries = recentAudit()
+	} else {
+		f, err := os.Open(fileName)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			var entry AuditEntry
+			if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+				continue
+			}
+			entries = append(entries, entry)
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+	}
+	var selected []AuditEntry
+	for _, entry := range entries {
+		t := time.Unix(0, entry.Timestamp)
+		if !t.Before(from) && !t.After(to) {
+			selected = append(selected, entry)
+		}
+	}
+	return selected, nil
+}
+
+// writeExportCSV writes the requested source between from and to as CSV.
+func writeExportCSV(out io.Writer, req exportRequest, from time.Time, to time.Time) (int, error) {
+	w := csv.NewWriter(out)
+	rows := 0
+	switch req.Source {
+	case exportTimeSeries:
+		samples, err := queryTimeSeries(req.Resource, from, to)
+		if err != nil {
+			return 0, err
+		}
+		if err := w.Write([]string{"timestamp", "resource", "value"}); err != nil {
+			return 0, err
+		}
+		for _, sample := range samples {
+			record := []string{
+				time.UnixMilli(sample.Timestamp).Format(time.RFC3339Nano),
+				sample.Resource,
+				strconv.FormatFloat(sample.Value, 'g', -1, 64),
+			}
+			if err := w.Write(record); err != nil {
+				return rows, err
+			}
+			rows++
+		}
+	case exportAudit:
+		entries, err := auditBetween(from, to)
+		if err != nil {
+			return 0, err
+		}
+		if err := w.Write([]string{"timestamp", "action", "resource", "detail"}); err != nil {
+			return 0, err
+		}
+		for _, entry := range entries {
+			if req.Resource != "" && entry.Resource != req.Resource {
+				continue
+			}
+			record := []string{time.Unix(0, entry.Timestamp).Format(time.RFC3339Nano), entry.Action, entry.Resource, entry.Detail}
+			if err := w.Write(record); err != nil {
+				return rows, err
+			}
+			rows++
+		}
+	default:
+		return 0, fmt.Errorf("unknown export source %q", req.Source)
+	}
+	w.Flush()
+	return rows, w.Error()
+}
+
+// handleExport dumps the time-series cache or the audit log for a time range as CSV. Without a path
+// the file is returned as a download, otherwise it is written in path, which must be inside one of
+// EXPORT_DIRS, for sites collecting data by hand. Parquet is not supported yet.
+func (s *SimpleDriver) handleExport(w http.ResponseWriter, r *http.Request) {
+	var req exportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if req.Format == "" {
+		req.Format = exportFormatCSV
+	}
+	if req.Format != exportFormatCSV {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("unsupported export format %q", req.Format))
+		return
+	}
+	if req.Source != exportTimeSeries && req.Source != exportAudit {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("unknown export source %q", req.Source))
+		return
+	}
+	to := time.Now()
+	from := to.Add(-24 * time.Hour)
+	var err error
+	if req.From != "" {
+		if from, err = time.Parse(time.RFC3339, req.From); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid from %q", req.From))
+			return
+		}
+	}
+	if req.To != "" {
+		if to, err = time.Parse(time.RFC3339, req.To); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid to %q", req.To))
+			return
+		}
+	}
+	fileName := fmt.Sprintf("%s-%s-%s-%s.csv", deviceName(), req.Source, from.Format(exportTimeLayout), to.Format(exportTimeLayout))
+
+	if req.Path == "" {
+		var buf bytes.Buffer
+		if _, err := writeExportCSV(&buf, req, from, to); err != nil {
+			writeError(w, http.StatusConflict, err)
+			return
+		}
+		w.Header().Set(common.ContentType, exportContentType)
+		w.Header().Set("Content-Disposition", "attachment; filename="+fileName)
+		if _, err := w.Write(buf.Bytes()); err != nil {
+			log.Printf("Cannot write export of %s. Error: %s", req.Source, err)
+		}
+		return
+	}
+
+	if !exportAllowed(req.Path) {
+		writeError(w, http.StatusForbidden, errors.New("export path is not inside EXPORT_DIRS"))
+		return
+	}
+	path := filepath.Join(req.Path, fileName)
+	f, err := os.Create(path)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	rows, err := writeExportCSV(f, req, from, to)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+	audit("export", req.Source, fmt.Sprintf("%d rows to %s", rows, path))
+	writeJSON(w, http.StatusOK, map[string]interface{}{"path": path, "rows": rows})
+}
@@ -0,0 +1,28 @@
+package driver
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/edgexfoundry/device-gpiod/gpio"
+)
+
+var errNotExposed = errors.New("gpio is not exposed to")
+
+// remoteSources are the sources reaching the service from outside the site: EdgeX core-command, with
+// the writes it queues, the cloud twin and the gRPC control API. The others, the manual override of the
+// maintenance mode and the local Modbus and OPC UA HMIs, are local control.
+var remoteSources = map[string]bool{"core-command": true, "schedule": true, "twin": true, "grpc": true}
+
+// checkExposure enforces the exposure of the line: a local-only line is neither in the device profile
+// nor reachable by remote sources, a remote-only line is not driven by local control.
+func checkExposure(g *gpio.GPIO, source string) error {
+	remote := remoteSources[source]
+	switch {
+	case g.Exposure == gpio.ExposureLocal && remote:
+		return fmt.Errorf("%w %s: %s is local-only", errNotExposed, source, g.Name)
+	case g.Exposure == gpio.ExposureRemote && !remote:
+		return fmt.Errorf("%w %s: %s is remote-only", errNotExposed, source, g.Name)
+	}
+	return nil
+}
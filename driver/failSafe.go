@@ -0,0 +1,187 @@
+package driver
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/edgexfoundry/device-gpiod/gpio"
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/common"
+
+	sdkModels "github.com/edgexfoundry/device-sdk-go/v2/pkg/models"
+)
+
+const (
+	failSafeResource          = "FailSafe"
+	DEFAULT_FAILSAFE_INTERVAL = time.Duration(1) * time.Second
+	// pipelineHeartbeat is the supervised goroutine of the pump cycle, petted at each of its sleeps
+	pipelineHeartbeat = "pipeline"
+)
+
+// FailSafeAlert is published when outputs are forced to their safe state.
+type FailSafeAlert struct {
+	Scope  string    `json:"scope"`
+	Reason string    `json:"reason"`
+	At     time.Time `json:"at"`
+}
+
+var (
+	failSafeMutex = sync.Mutex{}
+	// failSafeEngaged latches the pipeline heartbeat fail-safe until the heartbeat is back
+	failSafeEngaged bool
+	failSafeDriver  *SimpleDriver
+	// awayFromSafe holds since when the lines with a max_on are away from their safe value
+	awayFromSafe = make(map[string]time.Time)
+)
+
+// startFailSafe checks every FAILSAFE_INTERVAL (default 1s) that no output has been on longer than its
+// max_on and that the pipeline keeps petting its heartbeat. An output over its limit is turned off;
+// a missed heartbeat (pipeline hung mid-cycle) forces every output to its safe state. Both raise a
+// FailSafe alert reading.
+func (s *SimpleDriver) startFailSafe() {
+	interval := DEFAULT_FAILSAFE_INTERVAL
+	if d, err := time.ParseDuration(os.Getenv("FAILSAFE_INTERVAL")); err == nil && d > 0 {
+		interval = d
+	} else {
+		log.Printf("Cannot parse FAILSAFE_INTERVAL. Picking default value %s...", interval)
+	}
+	failSafeDriver = s
+	goBackground(func() {
+		for {
+			if !sleepUntilStop(interval) {
+				return
+			}
+			s.enforceMaxOn()
+			stalled := goroutineStalled(pipelineHeartbeat)
+			failSafeMutex.Lock()
+			engage := stalled && !failSafeEngaged
+			failSafeEngaged = stalled
+			failSafeMutex.Unlock()
+			if engage {
+				s.failSafe("all", "pipeline heartbeat missed", false)
+			}
+		}
+	})
+}
+
+// enforceMaxOn turns off the outputs away from their safe value for longer than their max_on: on for
+// the lines safe low, off for the lines with safe_state high.
+func (s *SimpleDriver) enforceMaxOn() {
+	now := time.Now()
+	for i := range s.GpioList.Gpio {
+		g := &s.GpioList.Gpio[i]
+		limit := g.MaxOnTime()
+		if limit == 0 {
+			continue
+		}
+		level := readLevel(g)
+		if level == g.SafeValue() {
+			failSafeMutex.Lock()
+			delete(awayFromSafe, g.Name)
+			failSafeMutex.Unlock()
+			continue
+		}
+		if since := leftSafeValue(g.Name, level, now); now.Sub(since) < limit {
+			continue
+		}
+
+		lock := lineLock(g.Name)
+		lock.Lock()
+		// A write may have brought the line back meanwhile
+		if readLevel(g) == g.SafeValue() {
+			lock.Unlock()
+			continue
+		}
+		var err error
+		if g.SafeValue() == 1 {
+			err = g.Up()
+		} else {
+			err = g.Down()
+		}
+		if err != nil {
+			lock.Unlock()
+			log.Printf("Cannot turn off gpio %s over its max_on. Error: %s", g.Name, err)
+			continue
+		}
+		g.State = g.SafeValue() == 1
+		s.handleAsyncCommunication(*g)
+		lock.Unlock()
+
+		failSafeMutex.Lock()
+		delete(awayFromSafe, g.Name)
+		failSafeMutex.Unlock()
+		s.pushFailSafe(g.Name, fmt.Sprintf("away from its safe state for more than %s", limit))
+	}
+}
+
+// readLevel returns the level of a line, read back from the chip when it can be.
+func readLevel(g *gpio.GPIO) int {
+	if value, err := g.ReadBack(); err == nil {
+		return value
+	}
+	return lineLevel(g.State)
+}
+
+// leftSafeValue returns when the line left its safe value for level: the time of its last transition
+// to it, else the first time it was seen away.
+func leftSafeValue(name string, level int, now time.Time) time.Time {
+	failSafeMutex.Lock()
+	defer failSafeMutex.Unlock()
+	if since, ok := awayFromSafe[name]; ok {
+		return since
+	}
+	since := now
+	if value, at, ok := lastTransition(name); ok && value == level {
+		since = at
+	}
+	awayFromSafe[name] = since
+	return since
+}
+
+// failSafe forces every output to its safe state and raises the alert.
+func (s *SimpleDriver) failSafe(scope string, reason string, exiting bool) {
+	if s.GpioList == nil {
+		return
+	}
+	elapsed := s.driveSafeState(reason, exiting)
+	log.Printf("Fail-safe engaged: %s. Outputs in safe state in %s", reason, elapsed)
+	s.pushFailSafe(scope, reason)
+}
+
+// panicFailSafe forces the outputs to their safe state before a panic takes the service down.
+func panicFailSafe(reason string) {
+	if failSafeDriver == nil {
+		return
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("Cannot engage fail-safe after panic. Error: %v", r)
+		}
+	}()
+	failSafeDriver.failSafe("all", "panic: "+reason, false)
+}
+
+// pushFailSafe publishes a fail-safe alert reading and notification.
+func (s *SimpleDriver) pushFailSafe(scope string, reason string) {
+	alert := FailSafeAlert{Scope: scope, Reason: reason, At: time.Now()}
+	audit("fail-safe", scope, reason)
+	sendNotification("fail-safe", notificationSeverityCritical, tr("notification.fail-safe", scope, reason))
+	payload, err := shapePayload(failSafeResource, alert)
+	if err != nil {
+		log.Printf("Cannot marshal fail-safe alert. Error: %s", err)
+		return
+	}
+	cv, err := sdkModels.NewCommandValue(failSafeResource, common.ValueTypeString, string(payload))
+	if err != nil {
+		log.Printf("Cannot create fail-safe reading. Error: %s", err)
+		return
+	}
+	// The service may be stopping: never block on a full channel
+	select {
+	case s.asyncCh <- &sdkModels.AsyncValues{DeviceName: deviceName(), CommandValues: []*sdkModels.CommandValue{cv}}:
+	default:
+		log.Println("Cannot publish fail-safe reading, async channel full")
+	}
+}
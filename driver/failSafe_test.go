@@ -0,0 +1,74 @@
+package driver
+
+import (
+	"testing"
+	"time"
+
+	sdkModels "github.com/edgexfoundry/device-sdk-go/v2/pkg/models"
+)
+
+const maxOnLines = `
+gpio:
+  - name: relay
+    chip: gpiochip0
+    line: 0
+    max_on: 200ms
+  - name: vent
+    chip: gpiochip0
+    line: 1
+    safe_state: high
+    max_on: 200ms
+  - name: fan
+    chip: gpiochip0
+    line: 2
+    safe_state: high
+    max_on: 200ms
+`
+
+// failSafeAlerts drains the readings and returns the number of fail-safe alerts among them.
+func failSafeAlerts(readings chan *sdkModels.AsyncValues) int {
+	alerts := 0
+	for {
+		select {
+		case values := <-readings:
+			if values.CommandValues[0].DeviceResourceName == failSafeResource {
+				alerts++
+			}
+		case <-time.After(50 * time.Millisecond):
+			return alerts
+		}
+	}
+}
+
+func TestEnforceMaxOn(t *testing.T) {
+	s, _, readings := newTestDriver(t, maxOnLines)
+	// relay and vent leave their safe value, fan is driven to it
+	for name, on := range map[string]bool{"relay": true, "vent": false, "fan": true} {
+		if err := s.writeLine(name, on, "test"); err != nil {
+			t.Fatalf("write of %s failed: %s", name, err)
+		}
+	}
+	failSafeAlerts(readings)
+	s.enforceMaxOn()
+	if alerts := failSafeAlerts(readings); alerts != 0 {
+		t.Errorf("%d fail-safe alerts within max_on", alerts)
+	}
+	time.Sleep(200 * time.Millisecond)
+
+	s.enforceMaxOn()
+	if alerts := failSafeAlerts(readings); alerts != 2 {
+		t.Errorf("%d fail-safe alerts over max_on, want 2 for relay and vent", alerts)
+	}
+	for name, expected := range map[string]int{"relay": 0, "vent": 1, "fan": 1} {
+		g, _ := s.findGpio(name)
+		if value, err := g.ReadBack(); err != nil || value != expected || g.State != (expected == 1) {
+			t.Errorf("%s reads %d (%v) with state %v over max_on, want %d", name, value, err, g.State, expected)
+		}
+	}
+
+	// Every line is at its safe value now, nothing is left to turn off
+	s.enforceMaxOn()
+	if alerts := failSafeAlerts(readings); alerts != 0 {
+		t.Errorf("%d fail-safe alerts repeated for lines at their safe value", alerts)
+	}
+}
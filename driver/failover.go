@@ -0,0 +1,142 @@
+package driver
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/edgexfoundry/device-gpiod/gpio"
+)
+
+const (
+	feedbackPoll = time.Duration(50) * time.Millisecond
+)
+
+var (
+	failoverTimeout = time.Duration(2) * time.Second
+	failedPumps     = make(map[string]string)
+)
+
+func parseFailover() {
+	if d, err := time.ParseDuration(os.Getenv("FAILOVER_TIMEOUT")); err == nil && d > 0 {
+		failoverTimeout = d
+	} else {
+		log.Printf("Cannot parse FAILOVER_TIMEOUT. Picking default value %s...", failoverTimeout)
+	}
+}
+
+// verifyActuation checks that g reached expected: through its feedback line when wired, waiting up to
+// FAILOVER_TIMEOUT (default 2s) for it to confirm, by reading back the line otherwise. Failures count
+// towards the maintenance recommendation of the line.
+func (s *SimpleDriver) verifyActuation(g *gpio.GPIO, expected int) error {
+	err := s.checkActuation(g, expected)
+	if err != nil {
+		s.observeVerifyFailure(g.Name)
+	}
+	return err
+}
+
+func (s *SimpleDriver) checkActuation(g *gpio.GPIO, expected int) error {
+	if g.Feedback == "" {
+		value, err := g.ReadBack()
+		if err != nil {
+			return fmt.Errorf("cannot verify %s: %s", g.Name, err)
+		}
+		if value != expected {
+			return fmt.Errorf("verification of %s failed: read %d, expected %d", g.Name, value, expected)
+		}
+		return nil
+	}
+	feedback, ok := s.findGpio(g.Feedback)
+	if !ok {
+		return fmt.Errorf("feedback %s of %s is not configured", g.Feedback, g.Name)
+	}
+	deadline := time.Now().Add(failoverTimeout)
+	for {
+		value, err := feedback.Value()
+		if err == nil && value == expected {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("feedback %s did not confirm %s within %s", g.Feedback, g.Name, failoverTimeout)
+		}
+		time.Sleep(feedbackPoll)
+	}
+}
+
+// pumpFailed flags the pump as failed, raising an alarm. Failed pumps are not selected again until
+// they are pinned by an operator.
+func pumpFailed(name string, err error) {
+	pumpsMutex.Lock()
+	failedPumps[name] = err.Error()
+	pumpsMutex.Unlock()
+	setFault("pump-"+name, err)
+	audit("pump-failed", name, err.Error())
+	sendNotification("pump", notificationSeverityCritical, tr("notification.pump-failed", name, err))
+}
+
+// clearPumpFailure must be called holding pumpsMutex.
+func clearPumpFailure(name string) {
+	if _, ok := failedPumps[name]; ok {
+		delete(failedPumps, name)
+		clearFault("pump-" + name)
+	}
+}
+
+// otherPump returns the pump of the pair that is not name, if usable.
+func otherPump(name string) (gpio.GPIO, bool) {
+	pumpsMutex.Lock()
+	defer pumpsMutex.Unlock()
+	other := pumps.standby
+	if name == pumps.standby.Name {
+		other = pumps.primary
+	}
+	if other.Name == "" || other.Name == name {
+		return gpio.GPIO{}, false
+	}
+	if _, failed := failedPumps[other.Name]; failed {
+		return gpio.GPIO{}, false
+	}
+	return other, true
+}
+
+// confirmPump verifies that the pump just turned on is running. When it is not and a standby pump is
+// configured, the cycle fails over to the other pump, keeping the process running. It returns the
+// pump actually running.
+func (s *SimpleDriver) confirmPump(current gpio.GPIO) (gpio.GPIO, error) {
+	pumpsMutex.Lock()
+	redundant := pumps.standby.Name != ""
+	pumpsMutex.Unlock()
+	if !redundant {
+		return current, nil
+	}
+	err := s.verifyActuation(&current, 1)
+	if err == nil {
+		return current, nil
+	}
+	log.Printf("Pump %s failed to confirm. Error: %s", current.Name, err)
+	if downErr := setPipelineLevel(&current, false); downErr != nil {
+		log.Printf("Cannot stop failed pump %s. Error: %s", current.Name, downErr)
+	}
+	pumpFailed(current.Name, err)
+
+	next, ok := otherPump(current.Name)
+	if !ok {
+		return current, err
+	}
+	audit("pump-failover", next.Name, fmt.Sprintf("from %s", current.Name))
+	if err := setPipelineLevel(&next, true); err != nil {
+		pumpFailed(next.Name, err)
+		return next, err
+	}
+	if err := s.verifyActuation(&next, 1); err != nil {
+		setPipelineLevel(&next, false)
+		pumpFailed(next.Name, err)
+		return next, err
+	}
+	pumpsMutex.Lock()
+	pumps.active = next.Name
+	pumpsMutex.Unlock()
+	return next, nil
+}
@@ -0,0 +1,34 @@
+package driver
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/edgexfoundry/device-gpiod/gpio"
+)
+
+// validateFaultInjection checks the fault_injection section, which makes line requests fail with
+// EBUSY, writes fail and events arrive late at the configured rates. Meant for test benches: the
+// retry, arbitration and failover paths see the same failures as on a misbehaving chip. It is only
+// honoured with the simulated backend, never on the lines of a board.
+func validateFaultInjection() error {
+	profile := driverConfig.FaultInjection
+	if profile == nil {
+		return nil
+	}
+	if gpio.ActiveSimulator() == nil && os.Getenv("GPIO_BACKEND") != "sim" {
+		return errors.New("fault injection requires the simulated backend, GPIO_BACKEND=sim")
+	}
+	return profile.Validate()
+}
+
+// activateFaultInjection injects the faults of the validated fault_injection section, none without it.
+func activateFaultInjection() {
+	profile := driverConfig.FaultInjection
+	gpio.SetFaults(profile)
+	if profile != nil {
+		configWarning(fmt.Sprintf("Fault injection enabled (seed %d, request_busy %.2f, write_error %.2f, event_delay %q at %.2f)",
+			profile.Seed, profile.RequestBusy, profile.WriteError, profile.EventDelay, profile.EventDelayRate))
+	}
+}
@@ -0,0 +1,63 @@
+package driver
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Optional subsystems can be left out of the binary with build tags, for constrained gateways:
+//
+//	nomqtt       system events and cloud twin (drops the paho MQTT client)
+//	nogrpc       gRPC control API (drops grpc and protobuf)
+//	nomodbus     Modbus TCP facade
+//	noopcua      OPC UA facade (drops the gopcua stack)
+//	nodashboard  embedded web dashboard
+//
+// The default build has every subsystem; `make build-minimal` sets all the tags above. A subsystem
+// that is left out behaves as if it were not configured.
+
+// feature is an optional subsystem and the environment variables that enable it.
+type feature struct {
+	tag      string
+	compiled bool
+	env      []string
+}
+
+var features = map[string]feature{
+	"mqtt":      {tag: "nomqtt", compiled: mqttCompiled, env: []string{"SYSTEM_EVENTS_BROKER", "TWIN_PROVIDER"}},
+	"grpc":      {tag: "nogrpc", compiled: grpcCompiled, env: []string{"GRPC_ADDRESS"}},
+	"modbus":    {tag: "nomodbus", compiled: modbusCompiled, env: []string{"MODBUS_SERVER_ADDRESS"}},
+	"opcua":     {tag: "noopcua", compiled: opcuaCompiled, env: []string{"OPCUA_SERVER_ADDRESS"}},
+	"dashboard": {tag: "nodashboard", compiled: dashboardCompiled, env: []string{"DASHBOARD_ENABLED"}},
+}
+
+// compiledFeatures lists the optional subsystems in the build, sorted.
+func compiledFeatures() []string {
+	compiled := []string{}
+	for name, f := range features {
+		if f.compiled {
+			compiled = append(compiled, name)
+		}
+	}
+	sort.Strings(compiled)
+	return compiled
+}
+
+// checkFeatures logs the optional subsystems in the build and warns about the configuration of the
+// ones left out, which would otherwise be silently ignored.
+func checkFeatures() {
+	log.Printf("Optional subsystems compiled in: %s", strings.Join(compiledFeatures(), ", "))
+	for name, f := range features {
+		if f.compiled {
+			continue
+		}
+		for _, env := range f.env {
+			if os.Getenv(env) != "" {
+				configWarning(fmt.Sprintf("%s is set but %s is not compiled in (%s build tag)", env, name, f.tag))
+			}
+		}
+	}
+}
@@ -0,0 +1,236 @@
+package driver
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// serialSources are the files holding the hardware serial number, by board family.
+var serialSources = []string{"/sys/firmware/devicetree/base/serial-number", "/proc/device-tree/serial-number", "/sys/class/dmi/id/product_serial"}
+
+// FleetManifest configures a fleet of identical machines from one file: every device gets the defaults
+// overlay, then the overlay of its entry, looked up by hardware serial number then hostname.
+type FleetManifest struct {
+	Defaults FleetOverlay            `yaml:"defaults"`
+	Devices  map[string]FleetOverlay `yaml:"devices"`
+}
+
+// FleetOverlay sets environment variables of the service and overlays the GPIO configuration file:
+// mappings are merged, lists of named entries, such as gpio, are merged by name and other values
+// replace the ones of the file.
+type FleetOverlay struct {
+	Env    map[string]string      `yaml:"env"`
+	Config map[string]interface{} `yaml:"config"`
+}
+
+// ApplyFleetManifest applies the entry of this device in FLEET_MANIFEST, a file or http(s) URL, before
+// the service reads its configuration. The device is FLEET_DEVICE_ID when set, else its serial number
+// or hostname. The overlaid configuration is written next to the original as <name>.fleet.yaml, in the
+// temporary directory when read-only, and GPIO_CONFIG_FILE points to it. Without FLEET_MANIFEST nothing
+// changes.
+func ApplyFleetManifest() error {
+	source := os.Getenv("FLEET_MANIFEST")
+	if source == "" {
+		return nil
+	}
+	data, err := readManifest(source)
+	if err != nil {
+		return fmt.Errorf("cannot read fleet manifest %s: %s", source, err)
+	}
+	signature, _ := readManifest(source + signatureSuffix)
+	if err := verifyConfig(source, data, signature); err != nil {
+		return err
+	}
+	manifest := FleetManifest{}
+	if err := yaml.Unmarshal(data, &manifest); err != nil {
+		return fmt.Errorf("invalid fleet manifest %s: %s", source, err)
+	}
+
+	overlays := []FleetOverlay{manifest.Defaults}
+	id, ok := "", false
+	for _, candidate := range fleetDeviceIds() {
+		var overlay FleetOverlay
+		if overlay, ok = manifest.Devices[candidate]; ok {
+			id = candidate
+			overlays = append(overlays, overlay)
+			break
+		}
+	}
+	if ok {
+		log.Printf("Applying fleet manifest entry %s", id)
+	} else {
+		log.Printf("No fleet manifest entry for this device (%s), applying the defaults only", strings.Join(fleetDeviceIds(), ", "))
+	}
+
+	config := make(map[string]interface{})
+	for _, overlay := range overlays {
+		for name, value := range overlay.Env {
+			os.Setenv(name, value)
+		}
+		if len(overlay.Config) > 0 {
+			config = mergeOverlay(config, overlay.Config).(map[string]interface{})
+		}
+	}
+	if len(config) == 0 {
+		return nil
+	}
+	return writeFleetConfig(config)
+}
+
+func readManifest(source string) ([]byte, error) {
+	if !strings.HasPrefix(source, "http://") && !strings.HasPrefix(source, "https://") {
+		return os.ReadFile(source)
+	}
+	client := http.Client{Timeout: time.Duration(30) * time.Second}
+	response, err := client.Get(source)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status code %d", response.StatusCode)
+	}
+	return io.ReadAll(response.Body)
+}
+
+// fleetDeviceIds returns the keys this device is looked up by in the manifest, in order.
+func fleetDeviceIds() []string {
+	if id := os.Getenv("FLEET_DEVICE_ID"); id != "" {
+		return []string{id}
+	}
+	var ids []string
+	if serial := hardwareSerial(); serial != "" {
+		ids = append(ids, serial)
+	}
+	if hostname, err := os.Hostname(); err == nil {
+		ids = append(ids, hostname)
+	}
+	return ids
+}
+
+// hardwareSerial reads the serial number of the board from the device tree or DMI, else from the
+// Serial field of /proc/cpuinfo of the Raspberry Pi.
+func hardwareSerial() string {
+	for _, path := range serialSources {
+		if data, err := os.ReadFile(path); err == nil {
+			if serial := strings.Trim(string(data), "\x00 \n"); serial != "" {
+				return serial
+			}
+		}
+	}
+	file, err := os.Open("/proc/cpuinfo")
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if name, value, ok := strings.Cut(scanner.Text(), ":"); ok && strings.TrimSpace(name) == "Serial" {
+			return strings.TrimSpace(value)
+		}
+	}
+	return ""
+}
+
+// mergeOverlay merges overlay onto base and returns the result.
+func mergeOverlay(base interface{}, overlay interface{}) interface{} {
+	switch o := overlay.(type) {
+	case map[string]interface{}:
+		merged := make(map[string]interface{})
+		if b, ok := base.(map[string]interface{}); ok {
+			for key, value := range b {
+				merged[key] = value
+			}
+		}
+		for key, value := range o {
+			merged[key] = mergeOverlay(merged[key], value)
+		}
+		return merged
+	case []interface{}:
+		if b, ok := base.([]interface{}); ok && namedEntries(b) && namedEntries(o) {
+			merged := append([]interface{}{}, b...)
+			for _, entry := range o {
+				i := indexOfEntry(merged, entryName(entry))
+				if i < 0 {
+					merged = append(merged, entry)
+				} else {
+					merged[i] = mergeOverlay(merged[i], entry)
+				}
+			}
+			return merged
+		}
+	}
+	return overlay
+}
+
+// entryName returns the name of a list entry, empty when it has none.
+func entryName(entry interface{}) string {
+	if e, ok := entry.(map[string]interface{}); ok {
+		if name, ok := e["name"].(string); ok {
+			return name
+		}
+	}
+	return ""
+}
+
+func namedEntries(list []interface{}) bool {
+	for _, entry := range list {
+		if entryName(entry) == "" {
+			return false
+		}
+	}
+	return true
+}
+
+func indexOfEntry(list []interface{}, name string) int {
+	for i, entry := range list {
+		if entryName(entry) == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// writeFleetConfig overlays GPIO_CONFIG_FILE with config and points GPIO_CONFIG_FILE to the result.
+func writeFleetConfig(config map[string]interface{}) error {
+	fileName := os.Getenv("GPIO_CONFIG_FILE")
+	if err := verifyConfigFile(fileName); err != nil {
+		return err
+	}
+	var base interface{}
+	if data, err := os.ReadFile(fileName); err == nil {
+		if err := yaml.Unmarshal(data, &base); err != nil {
+			return fmt.Errorf("cannot overlay %s: %s", fileName, err)
+		}
+	} else if fileName != "" {
+		return fmt.Errorf("cannot overlay %s: %s", fileName, err)
+	}
+	data, err := marshalYaml(mergeOverlay(base, config))
+	if err != nil {
+		return err
+	}
+	// Made of verified files, the overlaid configuration carries no signature
+	trustConfig(data)
+	overlaid := filepath.Join(os.TempDir(), "gpio.fleet.yaml")
+	if fileName != "" {
+		overlaid = strings.TrimSuffix(fileName, filepath.Ext(fileName)) + ".fleet.yaml"
+	}
+	if err := os.WriteFile(overlaid, data, 0644); err != nil {
+		// The configuration is often mounted read-only
+		overlaid = filepath.Join(os.TempDir(), filepath.Base(overlaid))
+		if err := os.WriteFile(overlaid, data, 0644); err != nil {
+			return err
+		}
+	}
+	log.Printf("Fleet configuration written to %s", overlaid)
+	return os.Setenv("GPIO_CONFIG_FILE", overlaid)
+}
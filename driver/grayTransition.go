@@ -0,0 +1,89 @@
+package driver
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/edgexfoundry/device-gpiod/gpio"
+)
+
+const (
+	transitionAtomic = "atomic"
+	transitionGray   = "gray"
+)
+
+// validateTransition checks the transition and step_delay of a group.
+func validateTransition(group LineGroup) error {
+	switch group.Transition {
+	case "", transitionAtomic, transitionGray:
+	default:
+		return fmt.Errorf("unknown transition %q", group.Transition)
+	}
+	if group.StepDelay == "" {
+		return nil
+	}
+	if d, err := time.ParseDuration(group.StepDelay); err != nil || d < 0 || group.Transition != transitionGray {
+		return fmt.Errorf("invalid step_delay %q", group.StepDelay)
+	}
+	return nil
+}
+
+// groupTransition returns whether the writes of the named group go one line at a time, and the delay
+// between the steps.
+func groupTransition(name string) (bool, time.Duration) {
+	for _, group := range driverConfig.Groups {
+		if group.Name == name && group.Transition == transitionGray {
+			d, _ := time.ParseDuration(group.StepDelay)
+			return true, d
+		}
+	}
+	return false, 0
+}
+
+// grayTransition returns the steps from current to target changing one line at a time, the lines
+// switched off first: every intermediate combination is then part of current or of target, so a bank
+// never goes through a combination that neither of them allows, such as two exclusive valves open.
+func grayTransition(current []int, target []int) [][]int {
+	var steps [][]int
+	step := append([]int(nil), current...)
+	for _, value := range []int{0, 1} {
+		for i := range step {
+			if step[i] == target[i] || target[i] != value {
+				continue
+			}
+			step[i] = value
+			steps = append(steps, append([]int(nil), step...))
+		}
+	}
+	return steps
+}
+
+// setGroupGray drives the members of a gray transition group to values one line at a time, updating
+// their state at each step. A failed step leaves the group at the previous one.
+func (s *SimpleDriver) setGroupGray(name string, members []*gpio.GPIO, values []int, delay time.Duration) error {
+	current := make([]int, len(members))
+	for i, g := range members {
+		value, err := g.ReadBack()
+		if err != nil {
+			value = 0
+			if g.State {
+				value = 1
+			}
+		}
+		current[i] = value
+	}
+	steps := grayTransition(current, values)
+	for i, step := range steps {
+		if i > 0 && delay > 0 {
+			time.Sleep(delay)
+		}
+		if err := s.GpioList.SetGroup(name, step); err != nil {
+			return fmt.Errorf("group %s stopped at step %d of %d: %w", name, i, len(steps), err)
+		}
+		for j, g := range members {
+			g.State = step[j] == 1
+		}
+		debugf(moduleGpio, "Group %s step %d of %d: %v", name, i+1, len(steps), step)
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package driver
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/edgexfoundry/device-gpiod/gpio"
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/common"
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/models"
+
+	sdkModels "github.com/edgexfoundry/device-sdk-go/v2/pkg/models"
+)
+
+const groupAttribute = "group"
+
+// groupProfileResources returns a resource per group of the gpio list, read and written as one value
+// per line in the order of the group.
+func groupProfileResources(gpioList *gpio.GPIOList) []models.DeviceResource {
+	var resources []models.DeviceResource
+	for _, group := range gpioList.Groups {
+		resources = append(resources, models.DeviceResource{
+			Name:        group.Name,
+			Description: fmt.Sprintf("Lines %s set together in a single request", strings.Join(group.Lines, ", ")),
+			Attributes: map[string]interface{}{
+				groupAttribute: group.Name,
+			},
+			Properties: models.ResourceProperties{
+				ValueType: common.ValueTypeBoolArray,
+				ReadWrite: common.ReadWrite_RW,
+			},
+		})
+	}
+	return resources
+}
+
+// writeGroup drives the lines of a group on behalf of an external client (source) in a single kernel
+// call, or one line at a time for a gray transition group, auditing the write and publishing the new
+// state of every line. Every line must be writable.
+func (s *SimpleDriver) writeGroup(name string, on []bool, source string) error {
+	members, ok := s.GpioList.Group(name)
+	if !ok {
+		return fmt.Errorf("%w %s", errUnknownLine, name)
+	}
+	if len(on) != len(members) {
+		return fmt.Errorf("group %s has %d lines, got %d values", name, len(members), len(on))
+	}
+	names := make([]string, len(members))
+	for i, g := range members {
+		if _, err := s.writableGpio(g.Name); err != nil {
+			return err
+		}
+		if err := checkExposure(g, source); err != nil {
+			return err
+		}
+		names[i] = g.Name
+	}
+	// Locks are taken in name order, so concurrent multi-line writes cannot deadlock
+	sort.Strings(names)
+	for _, line := range names {
+		lock := lineLock(line)
+		lock.Lock()
+		defer lock.Unlock()
+	}
+	values := make([]int, len(members))
+	for i, g := range members {
+		if !on[i] {
+			continue
+		}
+		if err := s.checkDutyLimit(g.Name, 0); err != nil {
+			return err
+		}
+		values[i] = 1
+	}
+	if gray, delay := groupTransition(name); gray {
+		if err := s.setGroupGray(name, members, values, delay); err != nil {
+			return err
+		}
+	} else if err := s.GpioList.SetGroup(name, values); err != nil {
+		return err
+	}
+	for i, g := range members {
+		g.State = on[i]
+		audit(source+"-write", g.Name, fmt.Sprintf("set to %t with group %s", on[i], name))
+		s.handleAsyncCommunication(*g)
+	}
+	return nil
+}
+
+// readGroup returns the levels of the lines of a group, in the order of the group.
+func (s *SimpleDriver) readGroup(req sdkModels.CommandRequest, name string) (*sdkModels.CommandValue, error) {
+	members, ok := s.GpioList.Group(name)
+	if !ok {
+		return nil, fmt.Errorf("%w %s", errUnknownLine, name)
+	}
+	values := make([]bool, len(members))
+	for i, g := range members {
+		value, err := g.ReadBack()
+		if err != nil {
+			return nil, fmt.Errorf("cannot read %s: %s", g.Name, err)
+		}
+		values[i] = value == 1
+	}
+	return sdkModels.NewCommandValue(req.DeviceResourceName, common.ValueTypeBoolArray, values)
+}
@@ -0,0 +1,205 @@
+//go:build !nogrpc
+
+package driver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"github.com/edgexfoundry/device-gpiod/gpio"
+)
+
+const grpcServiceName = "edgex.gpiod.v1.Gpiod"
+
+// grpcCompiled tells whether the gRPC control API is part of the build. It is left out with the nogrpc
+// build tag.
+const grpcCompiled = true
+
+// gpiodServer is the server side of the Gpiod service defined in proto/gpiod.proto.
+type gpiodServer interface {
+	GetStatus(context.Context, *emptypb.Empty) (*structpb.Struct, error)
+	ReadLine(context.Context, *wrapperspb.StringValue) (*structpb.Struct, error)
+	WriteLine(context.Context, *structpb.Struct) (*structpb.Struct, error)
+	StreamEvents(*emptypb.Empty, grpc.ServerStream) error
+}
+
+type grpcControl struct {
+	s *SimpleDriver
+}
+
+var (
+	grpcServer *grpc.Server
+)
+
+// startGrpc serves the gRPC control API on GRPC_ADDRESS. The API is disabled when unset; bind it to
+// localhost as it is meant for co-located applications and is not behind the EdgeX API gateway.
+func (s *SimpleDriver) startGrpc() error {
+	address := os.Getenv("GRPC_ADDRESS")
+	if address == "" {
+		return nil
+	}
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return fmt.Errorf("cannot listen for gRPC on %s: %s", address, err)
+	}
+	grpcServer = grpc.NewServer()
+	grpcServer.RegisterService(&gpiodServiceDesc, &grpcControl{s: s})
+	go func() {
+		if err := grpcServer.Serve(listener); err != nil {
+			logf(moduleBridges, levelError, "gRPC server stopped. Error: %s", err)
+		}
+	}()
+	logf(moduleBridges, levelInfo, "gRPC control API listening on %s", address)
+	return nil
+}
+
+func stopGrpc() {
+	if grpcServer != nil {
+		grpcServer.GracefulStop()
+	}
+}
+
+// toStruct converts v to a protobuf Struct through its JSON representation, as the REST routes do.
+func toStruct(v interface{}) (*structpb.Struct, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	m := make(map[string]interface{})
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return structpb.NewStruct(m)
+}
+
+func (c *grpcControl) GetStatus(ctx context.Context, _ *emptypb.Empty) (*structpb.Struct, error) {
+	return toStruct(map[string]interface{}{
+		"startup":   startupSnapshot(),
+		"phase":     currentPhase(),
+		"faults":    activeFaults(),
+		"inhibited": gpio.Inhibited(),
+	})
+}
+
+func (c *grpcControl) ReadLine(ctx context.Context, name *wrapperspb.StringValue) (*structpb.Struct, error) {
+	g, ok := c.s.findGpio(name.GetValue())
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "unknown gpio %s", name.GetValue())
+	}
+	read := g.ReadBack
+	if !isOutputRole(g.Role) {
+		read = g.Value
+	}
+	value, err := read()
+	if err != nil {
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
+	return toStruct(map[string]interface{}{"name": g.Name, "value": value})
+}
+
+func (c *grpcControl) WriteLine(ctx context.Context, req *structpb.Struct) (*structpb.Struct, error) {
+	fields := req.AsMap()
+	name, _ := fields["name"].(string)
+	value, ok := fields["value"].(float64)
+	if !ok || (value != 0 && value != 1) {
+		return nil, status.Error(codes.InvalidArgument, "value must be 0 or 1")
+	}
+	err := c.s.writeLine(name, value == 1, "grpc")
+	switch {
+	case errors.Is(err, errUnknownLine):
+		return nil, status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, errNotWritable), errors.Is(err, errInterlocked):
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, errDutyLimit), errors.Is(err, errConcurrencyLimit):
+		return nil, status.Error(codes.ResourceExhausted, err.Error())
+	case err != nil:
+		return nil, status.Error(codes.Aborted, err.Error())
+	}
+	return toStruct(map[string]interface{}{"name": name, "value": int(value)})
+}
+
+func (c *grpcControl) StreamEvents(_ *emptypb.Empty, stream grpc.ServerStream) error {
+	if err := admitStreamClient(); err != nil {
+		return status.Error(codes.ResourceExhausted, err.Error())
+	}
+	ch := subscribeStream()
+	defer unsubscribeStream(ch)
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case evt := <-ch:
+			msg, err := toStruct(map[string]interface{}{"kind": evt.Kind, "data": evt.Data})
+			if err != nil {
+				return err
+			}
+			if err := stream.SendMsg(msg); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func unaryHandler(method string, newRequest func() interface{}, call func(*grpcControl, context.Context, interface{}) (interface{}, error)) grpc.MethodDesc {
+	return grpc.MethodDesc{
+		MethodName: method,
+		Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+			req := newRequest()
+			if err := dec(req); err != nil {
+				return nil, err
+			}
+			handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+				return call(srv.(*grpcControl), ctx, req)
+			}
+			if interceptor == nil {
+				return handler(ctx, req)
+			}
+			info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/" + grpcServiceName + "/" + method}
+			return interceptor(ctx, req, info, handler)
+		},
+	}
+}
+
+var gpiodServiceDesc = grpc.ServiceDesc{
+	ServiceName: grpcServiceName,
+	HandlerType: (*gpiodServer)(nil),
+	Methods: []grpc.MethodDesc{
+		unaryHandler("GetStatus", func() interface{} { return new(emptypb.Empty) },
+			func(c *grpcControl, ctx context.Context, req interface{}) (interface{}, error) {
+				return c.GetStatus(ctx, req.(*emptypb.Empty))
+			}),
+		unaryHandler("ReadLine", func() interface{} { return new(wrapperspb.StringValue) },
+			func(c *grpcControl, ctx context.Context, req interface{}) (interface{}, error) {
+				return c.ReadLine(ctx, req.(*wrapperspb.StringValue))
+			}),
+		unaryHandler("WriteLine", func() interface{} { return new(structpb.Struct) },
+			func(c *grpcControl, ctx context.Context, req interface{}) (interface{}, error) {
+				return c.WriteLine(ctx, req.(*structpb.Struct))
+			}),
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName: "StreamEvents",
+			Handler: func(srv interface{}, stream grpc.ServerStream) error {
+				req := new(emptypb.Empty)
+				if err := stream.RecvMsg(req); err != nil {
+					return err
+				}
+				return srv.(*grpcControl).StreamEvents(req, stream)
+			},
+			ServerStreams: true,
+		},
+	},
+	Metadata: "proto/gpiod.proto",
+}
@@ -0,0 +1,198 @@
+package driver
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/edgexfoundry/device-gpiod/gpio"
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/common"
+)
+
+const (
+	hardwareFingerprintRoute = common.ApiBase + "/hardware/fingerprint"
+	hardwareDriftInhibit     = "hardware-drift"
+
+	driftPolicyWarn    = "warn"
+	driftPolicyInhibit = "inhibit"
+)
+
+// ChipFingerprint describes a gpiochip of the board.
+type ChipFingerprint struct {
+	Chip  string   `json:"chip"`
+	Label string   `json:"label"`
+	Lines int      `json:"lines"`
+	Names []string `json:"names"`
+}
+
+// HardwareFingerprint identifies the GPIO hardware of a board model: its chips, their labels, line
+// counts and line names. Hash sums them up.
+type HardwareFingerprint struct {
+	Chips      []ChipFingerprint `json:"chips"`
+	Hash       string            `json:"hash"`
+	RecordedAt time.Time         `json:"recordedAt"`
+}
+
+// currentFingerprint takes the fingerprint of the chips found on the board.
+func currentFingerprint() HardwareFingerprint {
+	chips := make(map[string]*ChipFingerprint)
+	for _, line := range gpio.Enumerate() {
+		chip, ok := chips[line.Chip]
+		if !ok {
+			chip = &ChipFingerprint{Chip: line.Chip, Label: line.ChipLabel}
+			chips[line.Chip] = chip
+		}
+		for len(chip.Names) <= line.Line {
+			chip.Names = append(chip.Names, "")
+		}
+		chip.Names[line.Line] = line.Name
+		chip.Lines = len(chip.Names)
+	}
+	fingerprint := HardwareFingerprint{RecordedAt: time.Now()}
+	for _, chip := range chips {
+		fingerprint.Chips = append(fingerprint.Chips, *chip)
+	}
+	sort.Slice(fingerprint.Chips, func(i, j int) bool { return fingerprint.Chips[i].Chip < fingerprint.Chips[j].Chip })
+	data, _ := json.Marshal(fingerprint.Chips)
+	sum := sha256.Sum256(data)
+	fingerprint.Hash = hex.EncodeToString(sum[:])
+	return fingerprint
+}
+
+// compareFingerprints returns the differences of current from recorded.
+func compareFingerprints(recorded HardwareFingerprint, current HardwareFingerprint) []string {
+	var drift []string
+	found := make(map[string]ChipFingerprint)
+	for _, chip := range current.Chips {
+		found[chip.Chip] = chip
+	}
+	for _, was := range recorded.Chips {
+		is, ok := found[was.Chip]
+		delete(found, was.Chip)
+		switch {
+		case !ok:
+			drift = append(drift, fmt.Sprintf("%s (%s) is missing", was.Chip, was.Label))
+			continue
+		case is.Label != was.Label:
+			drift = append(drift, fmt.Sprintf("%s is %s, was %s", was.Chip, is.Label, was.Label))
+		case is.Lines != was.Lines:
+			drift = append(drift, fmt.Sprintf("%s has %d lines, had %d", was.Chip, is.Lines, was.Lines))
+		}
+		renamed := 0
+		for i := 0; i < len(is.Names) && i < len(was.Names); i++ {
+			if is.Names[i] != was.Names[i] {
+				renamed++
+			}
+		}
+		if renamed > 0 {
+			drift = append(drift, fmt.Sprintf("%s has %d lines named differently", was.Chip, renamed))
+		}
+	}
+	for name, chip := range found {
+		drift = append(drift, fmt.Sprintf("%s (%s) is new", name, chip.Label))
+	}
+	sort.Strings(drift)
+	return drift
+}
+
+// checkHardwareFingerprint records the fingerprint of the board in HARDWARE_FINGERPRINT_FILE on the
+// first run and compares the board to it on the next ones, catching a configuration copied to another
+// board model. A drift is a configuration warning; with HARDWARE_DRIFT=inhibit actuation is also
+// refused until the board is accepted through the fingerprint route. It is a no-op when the file is
+// unset or with the simulated backend.
+func (s *SimpleDriver) checkHardwareFingerprint() {
+	fileName := os.Getenv("HARDWARE_FINGERPRINT_FILE")
+	if fileName == "" || gpio.ActiveSimulator() != nil {
+		return
+	}
+	current := currentFingerprint()
+	data, err := os.ReadFile(fileName)
+	if os.IsNotExist(err) {
+		if err := saveFingerprint(fileName, current); err != nil {
+			log.Printf("Cannot record hardware fingerprint. Error: %s", err)
+			return
+		}
+		log.Printf("Hardware fingerprint %s recorded in %s", current.Hash, fileName)
+		return
+	}
+	var recorded HardwareFingerprint
+	if err == nil {
+		err = json.Unmarshal(data, &recorded)
+	}
+	if err != nil {
+		log.Printf("Cannot read hardware fingerprint %s. Error: %s", fileName, err)
+		return
+	}
+	if recorded.Hash == current.Hash {
+		return
+	}
+	drift := compareFingerprints(recorded, current)
+	configWarning(fmt.Sprintf("GPIO hardware differs from the board recorded on %s, is the configuration for this board model? %s",
+		recorded.RecordedAt.Format(time.RFC3339), strings.Join(drift, "; ")))
+	audit("hardware-drift", current.Hash, strings.Join(drift, "; "))
+	switch policy := os.Getenv("HARDWARE_DRIFT"); policy {
+	case "", driftPolicyWarn:
+	case driftPolicyInhibit:
+		gpio.Inhibit(hardwareDriftInhibit)
+		logf(moduleGpio, levelError, "Actuation inhibited until the board is accepted on %s", hardwareFingerprintRoute)
+	default:
+		log.Printf("Cannot parse HARDWARE_DRIFT %q. Picking default value %s...", policy, driftPolicyWarn)
+	}
+}
+
+func saveFingerprint(fileName string, fingerprint HardwareFingerprint) error {
+	data, err := json.MarshalIndent(fingerprint, "", "\t")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(fileName, data, 0644)
+}
+
+// handleHardwareFingerprint returns the recorded and current fingerprints with their differences;
+// POST accepts the current board as the reference, recording its fingerprint and lifting the drift
+// inhibit.
+func (s *SimpleDriver) handleHardwareFingerprint(w http.ResponseWriter, r *http.Request) {
+	fileName := os.Getenv("HARDWARE_FINGERPRINT_FILE")
+	if fileName == "" {
+		writeError(w, http.StatusNotFound, fmt.Errorf("HARDWARE_FINGERPRINT_FILE is not set"))
+		return
+	}
+	current := currentFingerprint()
+	if r.Method == http.MethodPost {
+		if err := saveFingerprint(fileName, current); err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		gpio.ReleaseInhibit(hardwareDriftInhibit)
+		audit("hardware-accepted", current.Hash, "board accepted as the reference")
+		writeJSON(w, http.StatusOK, current)
+		return
+	}
+	var recorded *HardwareFingerprint
+	var drift []string
+	if data, err := os.ReadFile(fileName); err == nil {
+		recorded = &HardwareFingerprint{}
+		if err := json.Unmarshal(data, recorded); err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		drift = compareFingerprints(*recorded, current)
+	}
+	inhibited := false
+	for _, reason := range gpio.Inhibited() {
+		inhibited = inhibited || reason == hardwareDriftInhibit
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"recorded":  recorded,
+		"current":   current,
+		"drift":     drift,
+		"inhibited": inhibited,
+	})
+}
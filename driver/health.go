@@ -0,0 +1,50 @@
+package driver
+
+import (
+	"log"
+	"sort"
+	"sync"
+)
+
+var (
+	faultsMutex = sync.Mutex{}
+	faults      = make(map[string]string)
+)
+
+// setFault flags source as faulty. The service is healthy only while no fault is active.
+func setFault(source string, err error) {
+	faultsMutex.Lock()
+	defer faultsMutex.Unlock()
+	if _, ok := faults[source]; !ok {
+		log.Printf("Fault raised by %s. Error: %s", source, err)
+		sendTrap(trapFault, source, err.Error())
+		countFault()
+	}
+	faults[source] = err.Error()
+}
+
+func clearFault(source string) {
+	faultsMutex.Lock()
+	defer faultsMutex.Unlock()
+	if _, ok := faults[source]; ok {
+		log.Printf("Fault cleared by %s", source)
+		sendTrap(trapFaultCleared, source, "fault cleared")
+		delete(faults, source)
+	}
+}
+
+func activeFaults() []string {
+	faultsMutex.Lock()
+	defer faultsMutex.Unlock()
+	sources := make([]string, 0, len(faults))
+	for source := range faults {
+		sources = append(sources, source)
+	}
+	sort.Strings(sources)
+	return sources
+}
+
+// serviceHealthy reports whether no fault is active and every supervised goroutine is on time.
+func serviceHealthy() bool {
+	return len(activeFaults()) == 0 && len(stalledGoroutines()) == 0
+}
@@ -0,0 +1,320 @@
+package driver
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/common"
+
+	sdkModels "github.com/edgexfoundry/device-sdk-go/v2/pkg/models"
+)
+
+const (
+	healthResource = "Health"
+	healthRoute    = common.ApiBase + "/health/probes"
+
+	probeInternet     = "internet"
+	probeHTTP         = "http"
+	probeTCP          = "tcp"
+	probeModbus       = "modbus"
+	probeCoreMetadata = "core-metadata"
+
+	// Failure and recovery actions
+	healthIndicator    = "indicator"
+	healthPause        = "pause"
+	healthSafeState    = "safe-state"
+	healthReading      = "reading"
+	healthNotification = "notification"
+
+	defaultInternetProbe   = "http://clients3.google.com/generate_204"
+	DEFAULT_PROBE_INTERVAL = time.Duration(30) * time.Second
+	DEFAULT_PROBE_TIMEOUT  = time.Duration(5) * time.Second
+)
+
+// HealthProbe checks a dependency of the service every interval. Kind is internet (the HTTP 204 probe
+// by default), http (any 2xx answer of target), tcp (a connection to target host:port), modbus (the
+// Modbus device endpoint) or core-metadata (its ping route). The probe fails after threshold
+// consecutive failures, runs the on_failure actions and, when it succeeds again, the on_recovery
+// ones. The indicator action shows the offline state and pause holds the pump cycles while the probe
+// fails; safe-state forces the outputs to their safe state once; reading publishes the Health reading
+// and notification sends a notification.
+type HealthProbe struct {
+	Name       string   `yaml:"name"`
+	Kind       string   `yaml:"kind"`
+	Target     string   `yaml:"target"`
+	Interval   string   `yaml:"interval"`
+	Timeout    string   `yaml:"timeout"`
+	Threshold  int      `yaml:"threshold"`
+	OnFailure  []string `yaml:"on_failure"`
+	OnRecovery []string `yaml:"on_recovery"`
+	interval   time.Duration
+	timeout    time.Duration
+}
+
+// ProbeStatus is the last outcome of a probe.
+type ProbeStatus struct {
+	Name      string    `json:"name"`
+	Kind      string    `json:"kind"`
+	Healthy   bool      `json:"healthy"`
+	Failures  int       `json:"failures"`
+	Detail    string    `json:"detail,omitempty"`
+	CheckedAt time.Time `json:"checkedAt"`
+	Since     time.Time `json:"since"`
+}
+
+var (
+	probeMutex  = sync.Mutex{}
+	probeStatus = make(map[string]*ProbeStatus)
+
+	// legacyProbes is the connectivity check of the service before the health section: the internet
+	// probe every 30s, showing the offline state while it fails.
+	legacyProbes = []HealthProbe{{
+		Name:      "connectivity",
+		Kind:      probeInternet,
+		Threshold: 1,
+		OnFailure: []string{healthIndicator},
+		interval:  DEFAULT_PROBE_INTERVAL,
+		timeout:   DEFAULT_PROBE_TIMEOUT,
+	}}
+)
+
+// validateHealth checks the health section of the configuration file.
+func validateHealth() error {
+	names := make(map[string]bool)
+	for i := range driverConfig.Health {
+		p := &driverConfig.Health[i]
+		if p.Name == "" || names[p.Name] {
+			return fmt.Errorf("probe %d: missing or duplicated name", i)
+		}
+		names[p.Name] = true
+		switch p.Kind {
+		case probeInternet, probeModbus, probeCoreMetadata:
+		case probeHTTP, probeTCP:
+			if p.Target == "" {
+				return fmt.Errorf("probe %s: %s probes need a target", p.Name, p.Kind)
+			}
+		default:
+			return fmt.Errorf("probe %s: unknown kind %q", p.Name, p.Kind)
+		}
+		p.interval, p.timeout = DEFAULT_PROBE_INTERVAL, DEFAULT_PROBE_TIMEOUT
+		for _, setting := range []struct {
+			value  string
+			target *time.Duration
+		}{{p.Interval, &p.interval}, {p.Timeout, &p.timeout}} {
+			if setting.value == "" {
+				continue
+			}
+			d, err := time.ParseDuration(setting.value)
+			if err != nil || d <= 0 {
+				return fmt.Errorf("probe %s: invalid duration %q", p.Name, setting.value)
+			}
+			*setting.target = d
+		}
+		if p.Threshold < 0 {
+			return fmt.Errorf("probe %s: negative threshold", p.Name)
+		}
+		if p.Threshold == 0 {
+			p.Threshold = 1
+		}
+		for _, action := range append(append([]string(nil), p.OnFailure...), p.OnRecovery...) {
+			switch action {
+			case healthIndicator, healthPause, healthSafeState, healthReading, healthNotification:
+			default:
+				return fmt.Errorf("probe %s: unknown action %q", p.Name, action)
+			}
+		}
+	}
+	return nil
+}
+
+// healthProbes returns the configured probes, or the legacy connectivity check without any.
+func healthProbes() []HealthProbe {
+	if len(driverConfig.Health) > 0 {
+		return driverConfig.Health
+	}
+	return legacyProbes
+}
+
+// runProbe checks the target of the probe once.
+func runProbe(p HealthProbe) error {
+	client := &http.Client{Timeout: p.timeout}
+	target := p.Target
+	switch p.Kind {
+	case probeTCP:
+		conn, err := net.DialTimeout("tcp", target, p.timeout)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	case probeInternet:
+		if target == "" {
+			target = defaultInternetProbe
+		}
+	case probeModbus:
+		if target == "" {
+			target = modbusDeviceEndpoint()
+		}
+		if target == "" {
+			return fmt.Errorf("no Modbus device endpoint configured")
+		}
+	case probeCoreMetadata:
+		if target == "" {
+			base := os.Getenv("CORE_METADATA_URL")
+			if base == "" {
+				base = defaultCoreMetadataURL
+			}
+			target = base + "/api/v2/ping"
+		}
+	}
+	response, err := client.Get(target)
+	if err != nil {
+		return err
+	}
+	response.Body.Close()
+	if p.Kind != probeModbus && (response.StatusCode < 200 || response.StatusCode > 299) {
+		return fmt.Errorf("%s answered %s", target, response.Status)
+	}
+	return nil
+}
+
+// startHealthChecks runs every probe in its own goroutine.
+func (s *SimpleDriver) startHealthChecks() {
+	for _, p := range healthProbes() {
+		probeMutex.Lock()
+		probeStatus[p.Name] = &ProbeStatus{Name: p.Name, Kind: p.Kind, Healthy: true, Since: time.Now()}
+		probeMutex.Unlock()
+		p := p
+		goBackground(func() {
+			name := "health-" + p.Name
+			for {
+				petSupervisor(name, p.timeout)
+				err := runProbe(p)
+				debugf(moduleConnectivity, "Health probe %s checked. Error: %v", p.Name, err)
+				s.probed(p, err)
+				supervisedSleep(name, p.interval)
+			}
+		})
+	}
+}
+
+// probed records the outcome of a probe, running the actions when it fails or recovers.
+func (s *SimpleDriver) probed(p HealthProbe, err error) {
+	probeMutex.Lock()
+	status := probeStatus[p.Name]
+	wasHealthy := status.Healthy
+	status.CheckedAt = time.Now()
+	if err != nil {
+		countMetric("gpiod_health_probe_failures_total", "probe", p.Name)
+		status.Failures++
+		status.Detail = err.Error()
+		if status.Failures >= p.Threshold {
+			status.Healthy = false
+		}
+	} else {
+		status.Failures, status.Detail, status.Healthy = 0, "", true
+	}
+	if status.Healthy != wasHealthy {
+		status.Since = status.CheckedAt
+	}
+	snapshot := *status
+	probeMutex.Unlock()
+
+	setOffline(failingProbes(healthIndicator))
+	switch {
+	case wasHealthy && !snapshot.Healthy:
+		logf(moduleConnectivity, levelWarn, "Health probe %s failed. Error: %s", p.Name, snapshot.Detail)
+		sendTrap(trapConnectivityLost, p.Name, snapshot.Detail)
+		audit("health-failure", p.Name, snapshot.Detail)
+		s.runHealthActions(p, p.OnFailure, snapshot)
+	case !wasHealthy && snapshot.Healthy:
+		logf(moduleConnectivity, levelInfo, "Health probe %s recovered", p.Name)
+		sendTrap(trapConnectivityRestored, p.Name, "probe recovered")
+		audit("health-recovery", p.Name, "probe recovered")
+		s.runHealthActions(p, p.OnRecovery, snapshot)
+	}
+}
+
+func (s *SimpleDriver) runHealthActions(p HealthProbe, actions []string, status ProbeStatus) {
+	for _, action := range actions {
+		switch action {
+		case healthSafeState:
+			if !status.Healthy {
+				s.failSafe(p.Name, fmt.Sprintf("health probe %s failed: %s", p.Name, status.Detail), false)
+			}
+		case healthReading:
+			s.pushHealth()
+		case healthNotification:
+			severity, content := notificationSeverityNormal, tr("notification.health-recovered", p.Name)
+			if !status.Healthy {
+				severity, content = notificationSeverityCritical, tr("notification.health-failed", p.Name, status.Detail)
+			}
+			sendNotification("health", severity, content)
+		}
+	}
+}
+
+// failingProbes reports whether a failing probe has the action among its failure actions.
+func failingProbes(action string) bool {
+	probeMutex.Lock()
+	defer probeMutex.Unlock()
+	for _, p := range healthProbes() {
+		status, ok := probeStatus[p.Name]
+		if !ok || status.Healthy {
+			continue
+		}
+		for _, a := range p.OnFailure {
+			if a == action {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// healthPaused reports whether a failing probe holds the pump cycles.
+func healthPaused() bool {
+	return failingProbes(healthPause)
+}
+
+// probeStatuses returns the status of every probe, by name.
+func probeStatuses() []ProbeStatus {
+	probeMutex.Lock()
+	defer probeMutex.Unlock()
+	statuses := make([]ProbeStatus, 0, len(probeStatus))
+	for _, status := range probeStatus {
+		statuses = append(statuses, *status)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
+
+func healthCommandValue() (*sdkModels.CommandValue, error) {
+	payload, err := shapePayload(healthResource, probeStatuses())
+	if err != nil {
+		return nil, err
+	}
+	return sdkModels.NewCommandValue(healthResource, common.ValueTypeString, string(payload))
+}
+
+// pushHealth publishes the status of the probes as the Health reading.
+func (s *SimpleDriver) pushHealth() {
+	cv, err := healthCommandValue()
+	if err != nil {
+		log.Printf("Cannot create health reading. Error: %s", err)
+		return
+	}
+	s.asyncCh <- &sdkModels.AsyncValues{
+		DeviceName:    deviceName(),
+		CommandValues: []*sdkModels.CommandValue{cv},
+	}
+}
+
+func (s *SimpleDriver) handleHealthProbes(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, probeStatuses())
+}
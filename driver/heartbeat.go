@@ -0,0 +1,92 @@
+package driver
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/edgexfoundry/device-gpiod/gpio"
+)
+
+const (
+	RoleHeartbeat = "heartbeat"
+)
+
+var (
+	heartbeatStop = make(chan struct{})
+	heartbeatDone = make(chan struct{})
+	heartbeatLine *gpio.GPIO
+)
+
+// startHeartbeat toggles the line with role "heartbeat" every HEARTBEAT_INTERVAL (default 1s) while the
+// service is healthy, so external watchdog hardware can power-cycle the gateway when toggling stops.
+func (s *SimpleDriver) startHeartbeat() {
+	for i := range s.GpioList.Gpio {
+		if s.GpioList.Gpio[i].Role == RoleHeartbeat {
+			heartbeatLine = &s.GpioList.Gpio[i]
+			break
+		}
+	}
+	if heartbeatLine == nil {
+		close(heartbeatDone)
+		return
+	}
+
+	interval, err := time.ParseDuration(os.Getenv("HEARTBEAT_INTERVAL"))
+	if err != nil || interval <= 0 {
+		interval = time.Second
+	}
+	log.Printf("Heartbeat on gpio %s every %s", heartbeatLine.Name, interval)
+
+	go func() {
+		defer close(heartbeatDone)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		level := false
+		for {
+			select {
+			case <-heartbeatStop:
+				return
+			case <-ticker.C:
+				petSupervisor("heartbeat", interval)
+				if !serviceHealthy() {
+					continue
+				}
+				level = !level
+				if level {
+					err = heartbeatLine.Up()
+				} else {
+					err = heartbeatLine.Down()
+				}
+				if err != nil {
+					log.Printf("Cannot toggle heartbeat gpio %s. Error: %s", heartbeatLine.Name, err)
+				}
+			}
+		}
+	}()
+}
+
+// stopHeartbeat stops toggling on a clean shutdown and parks the line at HEARTBEAT_PARK_STATE
+// (default 1). Watchdog hardware wired to treat the parked level as "disarmed" then only fires when
+// the process hangs, not when it is stopped on purpose.
+func stopHeartbeat() {
+	if heartbeatLine == nil {
+		return
+	}
+	close(heartbeatStop)
+	<-heartbeatDone
+
+	park, err := strconv.ParseBool(os.Getenv("HEARTBEAT_PARK_STATE"))
+	if err != nil {
+		park = true
+	}
+	if park {
+		err = heartbeatLine.Up()
+	} else {
+		err = heartbeatLine.Down()
+	}
+	if err != nil {
+		log.Printf("Cannot park heartbeat gpio %s. Error: %s", heartbeatLine.Name, err)
+	}
+}
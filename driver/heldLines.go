@@ -0,0 +1,35 @@
+package driver
+
+import (
+	"log"
+	"os"
+	"time"
+
+	"github.com/edgexfoundry/device-gpiod/gpio"
+)
+
+const (
+	DEFAULT_RECONCILE_INTERVAL = time.Duration(30) * time.Second
+)
+
+// startHeldReconciliation checks every RECONCILE_INTERVAL (default 30s) that the output lines held by
+// the service are still requested by it, and takes back the ones lost (chip reset, released by a
+// yield gone wrong) at their last value.
+func startHeldReconciliation() {
+	interval := DEFAULT_RECONCILE_INTERVAL
+	if d, err := time.ParseDuration(os.Getenv("RECONCILE_INTERVAL")); err == nil && d > 0 {
+		interval = d
+	} else {
+		log.Printf("Cannot parse RECONCILE_INTERVAL. Picking default value %s...", interval)
+	}
+	goBackground(func() {
+		for {
+			supervisedSleep("held-lines", interval)
+			for _, name := range gpio.ReconcileHeld() {
+				log.Printf("Held gpio %s was lost and has been requested again", name)
+				recordTimelineEvent(name, "reacquired", "held line lost, requested again")
+				audit("reacquire", name, "held line lost, requested again")
+			}
+		}
+	})
+}
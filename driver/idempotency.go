@@ -0,0 +1,129 @@
+package driver
+
+import (
+	"bytes"
+	"container/list"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	idempotencyKeyAttribute = "idempotencyKey"
+	idempotencyKeyHeader    = "Idempotency-Key"
+)
+
+// idempotentResult is the outcome of the first execution of a keyed request, replayed to retries.
+type idempotentResult struct {
+	key     string
+	created time.Time
+	done    chan struct{}
+	value   interface{}
+	err     error
+}
+
+var (
+	idempotencyMutex = sync.Mutex{}
+	idempotencyIndex = make(map[string]*list.Element)
+	idempotencyOrder = list.New()
+	idempotencySize  = 256
+	idempotencyTTL   = time.Duration(24) * time.Hour
+)
+
+// parseIdempotency reads the bounds of the idempotency cache from IDEMPOTENCY_CACHE_SIZE and
+// IDEMPOTENCY_TTL.
+func parseIdempotency() {
+	if size, err := strconv.Atoi(os.Getenv("IDEMPOTENCY_CACHE_SIZE")); err == nil && size > 0 {
+		idempotencySize = size
+	} else {
+		log.Printf("Cannot parse IDEMPOTENCY_CACHE_SIZE. Picking default value %d...", idempotencySize)
+	}
+	if ttl, err := time.ParseDuration(os.Getenv("IDEMPOTENCY_TTL")); err == nil && ttl > 0 {
+		idempotencyTTL = ttl
+	} else {
+		log.Printf("Cannot parse IDEMPOTENCY_TTL. Picking default value %s...", idempotencyTTL)
+	}
+}
+
+// idempotent runs fn once per key: a retry with the same key gets the outcome of the first execution,
+// waiting for it if still in progress. An empty key always runs fn.
+func idempotent(key string, fn func() (interface{}, error)) (interface{}, error) {
+	if key == "" {
+		return fn()
+	}
+
+	idempotencyMutex.Lock()
+	if element, ok := idempotencyIndex[key]; ok {
+		result := element.Value.(*idempotentResult)
+		if time.Since(result.created) < idempotencyTTL {
+			idempotencyMutex.Unlock()
+			<-result.done
+			audit("idempotent-replay", key, "duplicate request not executed")
+			return result.value, result.err
+		}
+		idempotencyOrder.Remove(element)
+		delete(idempotencyIndex, key)
+	}
+	result := &idempotentResult{key: key, created: time.Now(), done: make(chan struct{})}
+	idempotencyIndex[key] = idempotencyOrder.PushBack(result)
+	for idempotencyOrder.Len() > idempotencySize {
+		countEvictions("idempotency", 1)
+		oldest := idempotencyOrder.Front()
+		idempotencyOrder.Remove(oldest)
+		delete(idempotencyIndex, oldest.Value.(*idempotentResult).key)
+	}
+	idempotencyMutex.Unlock()
+
+	result.value, result.err = fn()
+	close(result.done)
+	return result.value, result.err
+}
+
+// recordedResponse is an HTTP response captured to be replayed to retries of the same request.
+type recordedResponse struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (r *recordedResponse) Header() http.Header {
+	return r.header
+}
+
+func (r *recordedResponse) Write(b []byte) (int, error) {
+	return r.body.Write(b)
+}
+
+func (r *recordedResponse) WriteHeader(status int) {
+	r.status = status
+}
+
+// idempotentRoute makes a POST route honour the Idempotency-Key header, so a retried request replays
+// the first response instead of acting twice. Routes configured for two-person confirmation are
+// enforced here too, as every command route goes through it.
+func idempotentRoute(handler http.HandlerFunc) http.HandlerFunc {
+	handler = twoPersonRoute(handler)
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(idempotencyKeyHeader)
+		if key == "" {
+			handler(w, r)
+			return
+		}
+		value, _ := idempotent(r.URL.Path+" "+key, func() (interface{}, error) {
+			recorded := &recordedResponse{header: make(http.Header), status: http.StatusOK}
+			handler(recorded, r)
+			return recorded, nil
+		})
+		recorded := value.(*recordedResponse)
+		for name, values := range recorded.header {
+			w.Header()[name] = values
+		}
+		w.WriteHeader(recorded.status)
+		if _, err := w.Write(recorded.body.Bytes()); err != nil {
+			log.Printf("Cannot write HTTP response. Error: %s", err)
+		}
+	}
+}
@@ -0,0 +1,227 @@
+package driver
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/edgexfoundry/device-gpiod/gpio"
+	"gopkg.in/yaml.v3"
+)
+
+// initRoles are the roles offered by the setup wizard, output for the lines driven by the cycle.
+var initRoles = []string{"output", RoleInput, RolePwm, RoleSpare, RoleTamper, RolePowerFail, RoleHeartbeat,
+	RoleWatchdog, RoleFeedback, RoleLockout}
+
+// initLine is a line of the configuration written by the setup wizard, also the schema of its answers
+// file.
+type initLine struct {
+	Name     string `yaml:"name"`
+	Chip     string `yaml:"chip,omitempty"`
+	Line     int    `yaml:"line"`
+	LineName string `yaml:"line_name,omitempty"`
+	Role     string `yaml:"role,omitempty"`
+}
+
+type initConfig struct {
+	Gpio []initLine `yaml:"gpio"`
+}
+
+// RunInit is the init subcommand: it probes the gpiochips, lets the user assign a name and role to the
+// lines while toggling or watching them to see what they are wired to, then writes the validated gpio
+// configuration and the device profile generated from it. With -answers the lines are read from a
+// file in the configuration format instead, without touching the hardware. It returns the exit code.
+func RunInit(args []string) int {
+	flags := flag.NewFlagSet("init", flag.ContinueOnError)
+	answers := flags.String("answers", "", "Read the lines from this file instead of asking")
+	out := flags.String("out", "gpio.yaml", "Gpio configuration file to write")
+	profile := flags.String("profile", "device-gpiod-profile.json", "Device profile file to write")
+	name := flags.String("name", "device-gpiod", "Name of the device profile")
+	pulse := flags.Duration("pulse", time.Second, "How long a toggled line stays high")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	parseGpioBackend()
+
+	var config initConfig
+	var err error
+	if *answers != "" {
+		config, err = readInitAnswers(*answers)
+	} else {
+		config, err = runInitWizard(bufio.NewScanner(os.Stdin), os.Stdout, *pulse)
+	}
+	if err == nil {
+		err = writeInitConfig(config, *out, *profile, *name)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "init: %s\n", err)
+		return 1
+	}
+	fmt.Printf("Wrote %s and %s, set GPIO_CONFIG_FILE=%s\n", *out, *profile, *out)
+	return 0
+}
+
+func readInitAnswers(fileName string) (initConfig, error) {
+	var config initConfig
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return config, err
+	}
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return config, fmt.Errorf("invalid answers file: %s", err)
+	}
+	return config, nil
+}
+
+// runInitWizard walks through the free lines of the board, asking what to do with each.
+func runInitWizard(in *bufio.Scanner, out io.Writer, pulse time.Duration) (initConfig, error) {
+	var config initConfig
+	lines := gpio.Enumerate()
+	chips := make(map[string]bool)
+	for _, line := range lines {
+		chips[line.Chip] = true
+	}
+	if len(lines) == 0 {
+		return config, errors.New("no gpio line found, check GPIO_BACKEND and the access to /dev/gpiochip*")
+	}
+	fmt.Fprintf(out, "Found %d lines on %d chips. For each free line: [t]oggle it, [w]atch it as an input, [a]ssign it, [s]kip it or [q]uit\n", len(lines), len(chips))
+	ask := func(prompt string) (string, bool) {
+		fmt.Fprint(out, prompt)
+		if !in.Scan() {
+			return "", false
+		}
+		return strings.TrimSpace(in.Text()), true
+	}
+	names := make(map[string]bool)
+	for _, line := range lines {
+		if line.Used {
+			continue
+		}
+		probe := gpio.GPIO{Name: "init", Chip: line.Chip, Line: line.Line}
+	prompt:
+		for {
+			answer, ok := ask(fmt.Sprintf("Line %d of %s %q [t/w/a/s/q]: ", line.Line, line.Chip, line.Name))
+			if !ok {
+				return config, nil
+			}
+			switch answer {
+			case "t":
+				toggleInitLine(&probe, pulse, out)
+			case "w":
+				watchInitLine(&probe, out)
+			case "a":
+				name, _ := ask("Name: ")
+				if name == "" || names[name] {
+					fmt.Fprintln(out, "A unique name is required")
+					continue
+				}
+				role, _ := ask(fmt.Sprintf("Role [%s]: ", strings.Join(initRoles, "|")))
+				if role == "" {
+					role = initRoles[0]
+				}
+				if !containsString(initRoles, role) {
+					fmt.Fprintf(out, "Unknown role %q\n", role)
+					continue
+				}
+				if role == initRoles[0] {
+					role = ""
+				}
+				names[name] = true
+				config.Gpio = append(config.Gpio, initLine{Name: name, Chip: line.Chip, Line: line.Line, LineName: line.Name, Role: role})
+				break prompt
+			case "", "s":
+				break prompt
+			case "q":
+				return config, nil
+			default:
+				fmt.Fprintln(out, "Answer t, w, a, s or q")
+			}
+		}
+	}
+	return config, nil
+}
+
+// toggleInitLine drives the line high for pulse then low, so the user sees what it switches.
+func toggleInitLine(g *gpio.GPIO, pulse time.Duration, out io.Writer) {
+	defer g.Release()
+	if err := g.Up(); err != nil {
+		fmt.Fprintf(out, "Cannot drive the line: %s\n", err)
+		return
+	}
+	time.Sleep(pulse)
+	if err := g.Down(); err != nil {
+		fmt.Fprintf(out, "Cannot drive the line low: %s\n", err)
+	}
+}
+
+// watchInitLine counts the edges seen on the line for ten seconds, while the user operates the
+// switch or sensor wired to it.
+func watchInitLine(g *gpio.GPIO, out io.Writer) {
+	edges := make(chan gpio.Event, 64)
+	err := g.Watch(func(evt gpio.Event) {
+		select {
+		case edges <- evt:
+		default:
+		}
+	})
+	if err != nil {
+		fmt.Fprintf(out, "Cannot watch the line: %s\n", err)
+		return
+	}
+	defer g.Unwatch()
+	fmt.Fprintln(out, "Watching for 10 s, operate the input now...")
+	count := 0
+	timeout := time.After(time.Duration(10) * time.Second)
+	for {
+		select {
+		case evt := <-edges:
+			count++
+			fmt.Fprintf(out, "Edge to %d\n", evt.Value)
+		case <-timeout:
+			fmt.Fprintf(out, "%d edges seen\n", count)
+			return
+		}
+	}
+}
+
+// writeInitConfig writes the configuration, validates it by parsing it back, then writes the device
+// profile generated from it.
+func writeInitConfig(config initConfig, out string, profile string, name string) error {
+	if len(config.Gpio) == 0 {
+		return errors.New("no line assigned")
+	}
+	data, err := marshalYaml(config)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(out, data, 0644); err != nil {
+		return err
+	}
+	gpioList := &gpio.GPIOList{}
+	if err := gpioList.Parse(out, false); err != nil {
+		return fmt.Errorf("invalid configuration written to %s: %s", out, err)
+	}
+	if err := gpioList.ResolveLineNames(); err != nil {
+		return fmt.Errorf("invalid configuration written to %s: %s", out, err)
+	}
+	data, err = json.MarshalIndent(buildDeviceProfile(name, gpioList), "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(profile, data, 0644)
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,63 @@
+package driver
+
+import (
+	"log"
+
+	"github.com/edgexfoundry/device-gpiod/gpio"
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/common"
+
+	sdkModels "github.com/edgexfoundry/device-sdk-go/v2/pkg/models"
+)
+
+const (
+	RoleInput          = gpio.RoleInput
+	inputEventResource = "InputEvent"
+)
+
+// startInputMonitoring watches every line declared with direction input on its configured edges and
+// forwards each accepted edge as an async reading. Lines in counter mode are counted instead.
+func (s *SimpleDriver) startInputMonitoring() {
+	for i := range s.GpioList.Gpio {
+		g := &s.GpioList.Gpio[i]
+		if g.Role != RoleInput {
+			continue
+		}
+		if g.Mode == gpio.ModeCounter {
+			if err := s.startCounter(g); err != nil {
+				log.Printf("Cannot count pulses of gpio %s. Error: %s", g.Name, err)
+				continue
+			}
+			log.Printf("Counting pulses of gpio %s every %s", g.Name, g.CountPeriod(DEFAULT_COUNT_INTERVAL))
+			continue
+		}
+		if err := g.Watch(s.handleInputEvent); err != nil {
+			log.Printf("Cannot monitor input gpio %s. Error: %s", g.Name, err)
+			continue
+		}
+		edge := g.Edge
+		if edge == "" {
+			edge = gpio.EdgeBoth
+		}
+		log.Printf("Monitoring input gpio %s (line %d of %s, %s edges)", g.Name, g.Line, g.Chip, edge)
+	}
+}
+
+func (s *SimpleDriver) handleInputEvent(evt gpio.Event) {
+	recordTransition(evt.Name, evt.Value == 1)
+	recordSample(evt.Name, float64(evt.Value))
+	s.limitSwitchChanged(evt)
+	payload, err := shapePayload(inputEventResource, evt)
+	if err != nil {
+		log.Printf("Cannot marshal input event. Error: %s", err)
+		return
+	}
+	cv, err := sdkModels.NewCommandValue(inputEventResource, common.ValueTypeString, string(payload))
+	if err != nil {
+		log.Printf("Cannot create input event reading. Error: %s", err)
+		return
+	}
+	s.asyncCh <- &sdkModels.AsyncValues{
+		DeviceName:    deviceName(),
+		CommandValues: []*sdkModels.CommandValue{cv},
+	}
+}
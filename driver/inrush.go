@@ -0,0 +1,79 @@
+package driver
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/edgexfoundry/device-gpiod/gpio"
+)
+
+var (
+	energizeStagger = time.Duration(50) * time.Millisecond
+)
+
+// LineGroup is a named set of outputs switched on together, with its own inter-line stagger. With
+// transition gray the group writes go one line at a time, waiting step_delay in between, see
+// grayTransition.
+type LineGroup struct {
+	Name       string   `yaml:"name"`
+	Lines      []string `yaml:"lines"`
+	Stagger    string   `yaml:"stagger"`
+	Transition string   `yaml:"transition"`
+	StepDelay  string   `yaml:"step_delay"`
+}
+
+// validateGroups parses ENERGIZE_STAGGER and the stagger of the configured groups.
+func validateGroups() error {
+	if env := os.Getenv("ENERGIZE_STAGGER"); env != "" {
+		d, err := time.ParseDuration(env)
+		if err != nil || d < 0 {
+			return fmt.Errorf("invalid ENERGIZE_STAGGER %q", env)
+		}
+		energizeStagger = d
+	}
+	for _, group := range driverConfig.Groups {
+		if group.Name == "" {
+			return fmt.Errorf("group without name")
+		}
+		if err := validateTransition(group); err != nil {
+			return fmt.Errorf("group %s: %s", group.Name, err)
+		}
+		if group.Stagger == "" {
+			continue
+		}
+		if d, err := time.ParseDuration(group.Stagger); err != nil || d < 0 {
+			return fmt.Errorf("group %s: invalid stagger %q", group.Name, group.Stagger)
+		}
+	}
+	return nil
+}
+
+// groupStagger is the stagger of the named group, ENERGIZE_STAGGER when the group does not override it.
+func groupStagger(name string) time.Duration {
+	for _, group := range driverConfig.Groups {
+		if group.Name == name && group.Stagger != "" {
+			d, _ := time.ParseDuration(group.Stagger)
+			return d
+		}
+	}
+	return energizeStagger
+}
+
+// energizeTogether switches on lines that must come on "together" one after the other, waiting the
+// group stagger in between to limit the aggregate inrush current. It stops at the first failure.
+func energizeTogether(group string, lines []*gpio.GPIO) error {
+	stagger := groupStagger(group)
+	for i, g := range lines {
+		if i > 0 && stagger > 0 {
+			time.Sleep(stagger)
+		}
+		if err := g.Up(); err != nil {
+			log.Printf("Cannot energize gpio %s of group %s. Error: %s", g.Name, group, err)
+			return err
+		}
+		g.State = true
+	}
+	return nil
+}
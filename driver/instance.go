@@ -0,0 +1,47 @@
+package driver
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+
+	"github.com/edgexfoundry/device-gpiod/gpio"
+)
+
+const defaultDeviceName = "device-gpiod"
+
+var (
+	instanceName    = ""
+	validInstance   = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+	namespacedNames = false
+)
+
+// parseInstanceName reads INSTANCE_NAME, used to namespace device names and line consumer labels when
+// several instances of the service share one gateway.
+func parseInstanceName() error {
+	instanceName = os.Getenv("INSTANCE_NAME")
+	if instanceName == "" {
+		gpio.SetConsumer(defaultDeviceName)
+		return nil
+	}
+	if !validInstance.MatchString(instanceName) {
+		return fmt.Errorf("INSTANCE_NAME %q may only contain letters, digits, '-' and '_'", instanceName)
+	}
+	namespacedNames = true
+	gpio.SetConsumer(namespaced(defaultDeviceName))
+	return nil
+}
+
+// namespaced prefixes name with the instance name, if any.
+func namespaced(name string) string {
+	if !namespacedNames {
+		return name
+	}
+	return fmt.Sprintf("%s-%s", instanceName, name)
+}
+
+// deviceName is the name of the device instance readings are published for. As the message bus topic
+// embeds it, it also namespaces the published topics.
+func deviceName() string {
+	return namespaced(defaultDeviceName)
+}
@@ -0,0 +1,274 @@
+package driver
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/edgexfoundry/device-gpiod/gpio"
+)
+
+const (
+	journalIntent = "intent"
+	journalDone   = "done"
+	journalFailed = "failed"
+
+	recoveryReadBack = "readback"
+	recoverySafe     = "safe"
+	recoveryResume   = "resume"
+
+	journalCompactAfter = 1000
+)
+
+// JournalRecord is an entry of the actuation journal. An intent is written and synced before the line
+// is driven, its outcome after.
+type JournalRecord struct {
+	Seq    int64  `json:"seq"`
+	Line   string `json:"line"`
+	Value  int    `json:"value"`
+	Status string `json:"status"`
+	At     int64  `json:"at"`
+}
+
+// JournalRecovery is how a line found in the journal was reconciled at startup.
+type JournalRecovery struct {
+	Line      string `json:"line"`
+	Intended  int    `json:"intended"`
+	LastValue int    `json:"lastValue"`
+	Pending   bool   `json:"pending"`
+	Action    string `json:"action"`
+	Actual    *int   `json:"actual,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+var (
+	journalMutex    = sync.Mutex{}
+	journalFile     *os.File
+	journalSeq      int64
+	journalRecords  int
+	journalRecovery []JournalRecovery
+	journaledValues = make(map[string]int)
+	recoveryPolicy  = recoveryReadBack
+)
+
+// recoverJournal reconciles the outputs after a restart using the actuation journal in JOURNAL_FILE:
+// a line whose last intent has no outcome was being driven when the service died, and a line last
+// driven high may still be energized, so neither is assumed off. JOURNAL_RECOVERY selects what to do
+// with them: readback (default) reads their actual level, safe drives them low, resume applies the
+// interrupted intents again. The journal is then compacted and intents are journaled from now on.
+// It is a no-op when JOURNAL_FILE is unset.
+func (s *SimpleDriver) recoverJournal() {
+	fileName := os.Getenv("JOURNAL_FILE")
+	if fileName == "" {
+		return
+	}
+	switch policy := os.Getenv("JOURNAL_RECOVERY"); policy {
+	case recoveryReadBack, recoverySafe, recoveryResume:
+		recoveryPolicy = policy
+	default:
+		log.Printf("Cannot parse JOURNAL_RECOVERY. Picking default value %s...", recoveryPolicy)
+	}
+
+	intents, values, err := readJournal(fileName)
+	if err != nil {
+		log.Printf("Cannot read actuation journal %s. Error: %s", fileName, err)
+	}
+	for name, value := range values {
+		intent, pending := intents[name]
+		if !pending && value == 0 {
+			continue
+		}
+		if !pending {
+			intent = value
+		}
+		journalRecovery = append(journalRecovery, s.reconcileLine(name, intent, value, pending))
+	}
+	for name, intent := range intents {
+		if _, ok := values[name]; !ok {
+			journalRecovery = append(journalRecovery, s.reconcileLine(name, intent, 0, true))
+		}
+	}
+
+	journalMutex.Lock()
+	defer journalMutex.Unlock()
+	for _, r := range journalRecovery {
+		if r.Actual != nil {
+			values[r.Line] = *r.Actual
+		} else if r.Action == recoverySafe {
+			values[r.Line] = 0
+		}
+	}
+	journaledValues = values
+	if err := compactJournal(fileName, values); err != nil {
+		log.Printf("Cannot compact actuation journal %s. Error: %s", fileName, err)
+		return
+	}
+	gpio.OnIntent(s.journalIntent)
+}
+
+// readJournal returns the pending intents (without outcome) and the last value successfully driven
+// on each line. A torn last record, written while the power went away, is ignored.
+func readJournal(fileName string) (map[string]int, map[string]int, error) {
+	intents := make(map[string]int)
+	values := make(map[string]int)
+	f, err := os.Open(fileName)
+	if os.IsNotExist(err) {
+		return intents, values, nil
+	}
+	if err != nil {
+		return intents, values, err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var record JournalRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			log.Printf("Skipping corrupted journal record. Error: %s", err)
+			continue
+		}
+		if record.Seq > journalSeq {
+			journalSeq = record.Seq
+		}
+		switch record.Status {
+		case journalIntent:
+			intents[record.Line] = record.Value
+		case journalDone:
+			values[record.Line] = record.Value
+			delete(intents, record.Line)
+		case journalFailed:
+			delete(intents, record.Line)
+		}
+	}
+	return intents, values, scanner.Err()
+}
+
+// reconcileLine applies the recovery policy to a line that may not be off.
+func (s *SimpleDriver) reconcileLine(name string, intended int, lastValue int, pending bool) JournalRecovery {
+	recovery := JournalRecovery{Line: name, Intended: intended, LastValue: lastValue, Pending: pending, Action: recoveryPolicy}
+	g, ok := s.findGpio(name)
+	if !ok {
+		recovery.Error = "line no longer configured"
+		return recovery
+	}
+	var err error
+	switch {
+	case recoveryPolicy == recoverySafe:
+		err = g.Down()
+	case recoveryPolicy == recoveryResume && pending:
+		if intended == 1 {
+			err = g.Up()
+		} else {
+			err = g.Down()
+		}
+	default:
+		var actual int
+		if actual, err = g.ReadBack(); err == nil {
+			recovery.Actual = &actual
+			recovery.Action = recoveryReadBack
+		}
+	}
+	if err != nil {
+		recovery.Error = err.Error()
+	}
+	audit("journal-recovery", name, fmt.Sprintf("intended %d, last %d, pending %t: %s", intended, lastValue, pending, recovery.Action))
+	return recovery
+}
+
+// compactJournal rewrites the journal with the last known value of each line, then keeps it open for
+// appending. Must be called holding journalMutex.
+func compactJournal(fileName string, values map[string]int) error {
+	if journalFile != nil {
+		journalFile.Close()
+		journalFile = nil
+	}
+	tmp, err := os.Create(fileName + ".tmp")
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(tmp)
+	for name, value := range values {
+		journalSeq++
+		if err := encoder.Encode(JournalRecord{Seq: journalSeq, Line: name, Value: value, Status: journalDone, At: time.Now().UnixNano()}); err != nil {
+			tmp.Close()
+			return err
+		}
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	tmp.Close()
+	if err := os.Rename(fileName+".tmp", fileName); err != nil {
+		return err
+	}
+	journalFile, err = os.OpenFile(fileName, os.O_APPEND|os.O_WRONLY, 0644)
+	journalRecords = len(values)
+	return err
+}
+
+// journalIntent journals that a line is about to be driven, synced to disk before the actuation, and
+// returns the function journaling the outcome. Heartbeat, watchdog and indicator lines are not
+// journaled: they toggle often and never leave a load energized.
+func (s *SimpleDriver) journalIntent(name string, value int) func(error) {
+	if g, ok := s.findGpio(name); !ok || g.Role == RoleHeartbeat || g.Role == RoleWatchdog || isSignalLine(name) {
+		return func(error) {}
+	}
+	journalMutex.Lock()
+	defer journalMutex.Unlock()
+	journalSeq++
+	seq := journalSeq
+	appendJournal(JournalRecord{Seq: seq, Line: name, Value: value, Status: journalIntent, At: time.Now().UnixNano()}, true)
+	return func(err error) {
+		status := journalDone
+		if err != nil {
+			status = journalFailed
+		}
+		journalMutex.Lock()
+		defer journalMutex.Unlock()
+		appendJournal(JournalRecord{Seq: seq, Line: name, Value: value, Status: status, At: time.Now().UnixNano()}, false)
+	}
+}
+
+// appendJournal writes a record, compacting the journal once it grows large. Must be called holding
+// journalMutex.
+func appendJournal(record JournalRecord, sync bool) {
+	if journalFile == nil {
+		return
+	}
+	data, err := json.Marshal(record)
+	if err != nil {
+		log.Printf("Cannot marshal journal record. Error: %s", err)
+		return
+	}
+	if _, err := journalFile.Write(append(data, '\n')); err != nil {
+		log.Printf("Cannot write actuation journal. Error: %s", err)
+		return
+	}
+	if sync {
+		if err := journalFile.Sync(); err != nil {
+			log.Printf("Cannot sync actuation journal. Error: %s", err)
+		}
+	}
+	journalRecords++
+	if journalRecords < journalCompactAfter || record.Status == journalIntent {
+		return
+	}
+	fileName := journalFile.Name()
+	journalFile.Close()
+	journalFile = nil
+	journalSeq = 0
+	intents, values, err := readJournal(fileName)
+	if err == nil && len(intents) == 0 {
+		err = compactJournal(fileName, values)
+	} else if err == nil {
+		// Keep the full journal while an intent is in flight on another line
+		journalFile, err = os.OpenFile(fileName, os.O_APPEND|os.O_WRONLY, 0644)
+	}
+	if err != nil {
+		log.Printf("Cannot compact actuation journal %s. Error: %s", fileName, err)
+	}
+}
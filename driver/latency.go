@@ -0,0 +1,235 @@
+package driver
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/edgexfoundry/device-gpiod/gpio"
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/common"
+
+	sdkModels "github.com/edgexfoundry/device-sdk-go/v2/pkg/models"
+)
+
+const (
+	RoleFeedback             = "feedback"
+	actuationLatencyResource = "ActuationLatency"
+	latencyRoute             = common.ApiBase + "/diagnostics/latency"
+	// Weight of the last sample in the running mean
+	latencyWeight = 0.2
+)
+
+// LatencyStats is the observed delay between driving a line and its feedback confirming it.
+type LatencyStats struct {
+	Samples uint64        `json:"samples"`
+	Last    time.Duration `json:"last"`
+	Mean    time.Duration `json:"mean"`
+	Min     time.Duration `json:"min"`
+	Max     time.Duration `json:"max"`
+}
+
+// LineLatency holds the calibration of a line, per direction.
+type LineLatency struct {
+	Feedback string       `json:"feedback"`
+	On       LatencyStats `json:"on"`
+	Off      LatencyStats `json:"off"`
+}
+
+type pendingActuation struct {
+	output string
+	value  int
+	at     time.Time
+}
+
+var (
+	latencyMutex    = sync.Mutex{}
+	latencies       = make(map[string]*LineLatency)
+	pendingFeedback = make(map[string]pendingActuation)
+)
+
+// feedbackOf returns the feedback line wired to the output line, if any.
+func (s *SimpleDriver) feedbackOf(output string) (string, bool) {
+	g, ok := s.findGpio(output)
+	if !ok || g.Feedback == "" {
+		return "", false
+	}
+	return g.Feedback, true
+}
+
+// startFeedbackMonitoring watches the feedback lines and starts timing every actuation of an output
+// line that declares one. The calibration is kept in LATENCY_FILE when set, to survive restarts.
+func (s *SimpleDriver) startFeedbackMonitoring() {
+	loadLatencies()
+	gpio.OnActuation(s.actuated)
+	s.checkAssertionFeedback()
+	for i := range s.GpioList.Gpio {
+		g := &s.GpioList.Gpio[i]
+		if g.Role != RoleFeedback {
+			continue
+		}
+		if err := g.Watch(s.handleFeedbackEvent); err != nil {
+			log.Printf("Cannot monitor feedback gpio %s. Error: %s", g.Name, err)
+			continue
+		}
+		log.Printf("Monitoring feedback gpio %s (line %d of %s)", g.Name, g.Line, g.Chip)
+	}
+}
+
+func (s *SimpleDriver) actuated(name string, value int) {
+	countActuation(name, value)
+	s.valveActuated(name, value)
+	feedback, ok := s.feedbackOf(name)
+	if !ok {
+		return
+	}
+	if _, benchmarking := benchmarkEvents(feedback); benchmarking {
+		return
+	}
+	latencyMutex.Lock()
+	pendingFeedback[feedback] = pendingActuation{output: name, value: value, at: time.Now()}
+	latencyMutex.Unlock()
+	s.armAssertion(name, value)
+}
+
+func (s *SimpleDriver) handleFeedbackEvent(evt gpio.Event) {
+	if events, ok := benchmarkEvents(evt.Name); ok {
+		select {
+		case events <- evt:
+		default:
+		}
+		return
+	}
+	recordTransition(evt.Name, evt.Value == 1)
+	latencyMutex.Lock()
+	pending, ok := pendingFeedback[evt.Name]
+	if !ok || pending.value != evt.Value {
+		latencyMutex.Unlock()
+		return
+	}
+	delete(pendingFeedback, evt.Name)
+	latency := time.Since(pending.at)
+	l, ok := latencies[pending.output]
+	if !ok {
+		l = &LineLatency{}
+		latencies[pending.output] = l
+	}
+	l.Feedback = evt.Name
+	stats := &l.Off
+	if pending.value == 1 {
+		stats = &l.On
+	}
+	stats.add(latency)
+	snapshot := *l
+	latencyMutex.Unlock()
+	disarmAssertion(pending.output)
+	s.observeLatency(pending.output, latency)
+
+	saveLatencies()
+	s.pushLatency(pending.output, snapshot)
+}
+
+func (st *LatencyStats) add(latency time.Duration) {
+	st.Last = latency
+	if st.Samples == 0 {
+		st.Mean, st.Min, st.Max = latency, latency, latency
+	} else {
+		st.Mean = time.Duration(latencyWeight*float64(latency) + (1-latencyWeight)*float64(st.Mean))
+		if latency < st.Min {
+			st.Min = latency
+		}
+		if latency > st.Max {
+			st.Max = latency
+		}
+	}
+	st.Samples++
+}
+
+// compensated returns how long to wait between driving a line on and off so that the load is actually
+// on for d: the wait is shortened by the on latency and extended by the off latency observed so far.
+func compensated(name string, d time.Duration) time.Duration {
+	latencyMutex.Lock()
+	defer latencyMutex.Unlock()
+	l, ok := latencies[name]
+	if !ok || l.On.Samples == 0 || l.Off.Samples == 0 {
+		return d
+	}
+	adjusted := d + l.On.Mean - l.Off.Mean
+	if adjusted < 0 {
+		return 0
+	}
+	return adjusted
+}
+
+func loadLatencies() {
+	fileName := os.Getenv("LATENCY_FILE")
+	if fileName == "" {
+		return
+	}
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("Cannot read latency calibration. Error: %s", err)
+		}
+		return
+	}
+	latencyMutex.Lock()
+	defer latencyMutex.Unlock()
+	if err := json.Unmarshal(data, &latencies); err != nil {
+		log.Printf("Cannot parse latency calibration. Error: %s", err)
+	}
+}
+
+func saveLatencies() {
+	fileName := os.Getenv("LATENCY_FILE")
+	if fileName == "" {
+		return
+	}
+	latencyMutex.Lock()
+	data, err := json.MarshalIndent(latencies, "", "\t")
+	latencyMutex.Unlock()
+	if err != nil {
+		log.Printf("Cannot marshal latency calibration. Error: %s", err)
+		return
+	}
+	if err := os.WriteFile(fileName, data, 0644); err != nil {
+		log.Printf("Cannot write latency calibration. Error: %s", err)
+	}
+}
+
+// pushLatency publishes the calibration of a line as the ActuationLatency reading.
+func (s *SimpleDriver) pushLatency(name string, l LineLatency) {
+	payload, err := shapePayload(actuationLatencyResource, map[string]interface{}{"name": name, "latency": l})
+	if err != nil {
+		log.Printf("Cannot marshal actuation latency. Error: %s", err)
+		return
+	}
+	cv, err := sdkModels.NewCommandValue(actuationLatencyResource, common.ValueTypeString, string(payload))
+	if err != nil {
+		log.Printf("Cannot create actuation latency reading. Error: %s", err)
+		return
+	}
+	s.asyncCh <- &sdkModels.AsyncValues{
+		DeviceName:    deviceName(),
+		CommandValues: []*sdkModels.CommandValue{cv},
+	}
+}
+
+// handleLatency returns the calibration of every line with feedback.
+func (s *SimpleDriver) handleLatency(w http.ResponseWriter, r *http.Request) {
+	latencyMutex.Lock()
+	defer latencyMutex.Unlock()
+	result := make(map[string]interface{})
+	for name, l := range latencies {
+		result[name] = map[string]interface{}{
+			"feedback":     l.Feedback,
+			"on":           l.On,
+			"off":          l.Off,
+			"compensation": fmt.Sprint(l.On.Mean - l.Off.Mean),
+		}
+	}
+	writeJSON(w, http.StatusOK, result)
+}
@@ -0,0 +1,238 @@
+package driver
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/edgexfoundry/device-gpiod/gpio"
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/common"
+)
+
+const (
+	latencyBenchmarkRoute = common.ApiBase + "/diagnostics/latency/benchmark"
+
+	maxBenchmarkSamples = 10000
+)
+
+// LatencyDistribution is the distribution of the toggle to event latency of one direction.
+type LatencyDistribution struct {
+	Samples int           `json:"samples"`
+	Min     time.Duration `json:"min"`
+	P50     time.Duration `json:"p50"`
+	P90     time.Duration `json:"p90"`
+	P99     time.Duration `json:"p99"`
+	P999    time.Duration `json:"p999"`
+	Max     time.Duration `json:"max"`
+	Mean    time.Duration `json:"mean"`
+}
+
+// LatencyBenchmark is the result of a loopback benchmark, with the kernel and the load it ran on.
+type LatencyBenchmark struct {
+	Line     string              `json:"line"`
+	Feedback string              `json:"feedback"`
+	Kernel   string              `json:"kernel"`
+	Load     string              `json:"load"`
+	Started  time.Time           `json:"started"`
+	Duration time.Duration       `json:"duration"`
+	Missed   int                 `json:"missed"`
+	On       LatencyDistribution `json:"on"`
+	Off      LatencyDistribution `json:"off"`
+	All      LatencyDistribution `json:"all"`
+}
+
+type latencyBenchmarkRequest struct {
+	Line     string `json:"line"`
+	Samples  int    `json:"samples"`
+	Interval string `json:"interval"`
+	Timeout  string `json:"timeout"`
+}
+
+var (
+	benchmarkMutex = sync.Mutex{}
+	// benchmarks are the event channels of the benchmarks running, by feedback line. Their events
+	// and actuations are kept out of the calibration and the timing assertions.
+	benchmarks = make(map[string]chan gpio.Event)
+)
+
+// benchmarkEvents returns the channel of the benchmark running on the feedback line, if any.
+func benchmarkEvents(feedback string) (chan gpio.Event, bool) {
+	benchmarkMutex.Lock()
+	defer benchmarkMutex.Unlock()
+	events, ok := benchmarks[feedback]
+	return events, ok
+}
+
+// runLatencyBenchmark toggles the output looped to its feedback input samples times, timing each edge
+// from the write to the delivery of the event. A sample without event within timeout is missed. The
+// line is left at its level before the benchmark.
+func (s *SimpleDriver) runLatencyBenchmark(g *gpio.GPIO, feedback string, samples int, interval time.Duration, timeout time.Duration) (LatencyBenchmark, error) {
+	result := LatencyBenchmark{Line: g.Name, Feedback: feedback, Kernel: readProcFile("/proc/sys/kernel/osrelease"), Load: readProcFile("/proc/loadavg"), Started: time.Now()}
+	events := make(chan gpio.Event, 16)
+	benchmarkMutex.Lock()
+	if _, ok := benchmarks[feedback]; ok {
+		benchmarkMutex.Unlock()
+		return result, fmt.Errorf("a benchmark is already running on %s", feedback)
+	}
+	benchmarks[feedback] = events
+	benchmarkMutex.Unlock()
+	defer func() {
+		benchmarkMutex.Lock()
+		delete(benchmarks, feedback)
+		benchmarkMutex.Unlock()
+	}()
+
+	initial := g.State
+	var on, off []time.Duration
+	value := 1
+	if initial {
+		value = 0
+	}
+	for i := 0; i < samples; i++ {
+		if i > 0 && !sleepUntilStop(interval) {
+			break
+		}
+		// Drop the late events of a missed sample
+		for len(events) > 0 {
+			<-events
+		}
+		start := time.Now()
+		var err error
+		if value == 1 {
+			err = g.Up()
+		} else {
+			err = g.Down()
+		}
+		if err != nil {
+			return result, fmt.Errorf("cannot drive %s: %w", g.Name, err)
+		}
+		g.State = value == 1
+		if latency, ok := awaitBenchmarkEvent(events, value, start, timeout); !ok {
+			result.Missed++
+		} else if value == 1 {
+			on = append(on, latency)
+		} else {
+			off = append(off, latency)
+		}
+		value = 1 - value
+	}
+	if g.State != initial {
+		var err error
+		if initial {
+			err = g.Up()
+		} else {
+			err = g.Down()
+		}
+		if err != nil {
+			logf(moduleGpio, levelError, "Cannot restore gpio %s after the latency benchmark. Error: %s", g.Name, err)
+		} else {
+			g.State = initial
+		}
+	}
+	result.Duration = time.Since(result.Started)
+	result.On, result.Off = latencyDistribution(on), latencyDistribution(off)
+	result.All = latencyDistribution(append(on, off...))
+	return result, nil
+}
+
+func awaitBenchmarkEvent(events chan gpio.Event, value int, start time.Time, timeout time.Duration) (time.Duration, bool) {
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	for {
+		select {
+		case evt := <-events:
+			if evt.Value == value {
+				return time.Since(start), true
+			}
+		case <-deadline.C:
+			return 0, false
+		}
+	}
+}
+
+// latencyDistribution returns the percentiles of the samples, by the nearest rank.
+func latencyDistribution(samples []time.Duration) LatencyDistribution {
+	d := LatencyDistribution{Samples: len(samples)}
+	if len(samples) == 0 {
+		return d
+	}
+	sort.Slice(samples, func(i, j int) bool { return samples[i] < samples[j] })
+	rank := func(p float64) time.Duration {
+		i := int(math.Ceil(p*float64(len(samples)))) - 1
+		if i < 0 {
+			i = 0
+		}
+		return samples[i]
+	}
+	var sum time.Duration
+	for _, sample := range samples {
+		sum += sample
+	}
+	d.Min, d.Max, d.Mean = samples[0], samples[len(samples)-1], sum/time.Duration(len(samples))
+	d.P50, d.P90, d.P99, d.P999 = rank(0.5), rank(0.9), rank(0.99), rank(0.999)
+	return d
+}
+
+func readProcFile(fileName string) string {
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// handleLatencyBenchmark runs a loopback benchmark on an output wired to its feedback input:
+// {"line": "relay1", "samples": 200, "interval": "20ms", "timeout": "1s"}. The line is held for the
+// whole benchmark, which should run with the line disconnected from its load.
+func (s *SimpleDriver) handleLatencyBenchmark(w http.ResponseWriter, r *http.Request) {
+	req := latencyBenchmarkRequest{Samples: 100, Interval: "10ms", Timeout: "1s"}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	interval, err := time.ParseDuration(req.Interval)
+	if err != nil || interval < 0 {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid interval %q", req.Interval))
+		return
+	}
+	timeout, err := time.ParseDuration(req.Timeout)
+	if err != nil || timeout <= 0 {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid timeout %q", req.Timeout))
+		return
+	}
+	if req.Samples <= 0 || req.Samples > maxBenchmarkSamples {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("samples must be between 1 and %d", maxBenchmarkSamples))
+		return
+	}
+	g, err := s.writableGpio(req.Line)
+	if err != nil {
+		status := http.StatusConflict
+		if errors.Is(err, errUnknownLine) {
+			status = http.StatusNotFound
+		}
+		writeError(w, status, err)
+		return
+	}
+	feedback, ok := s.feedbackOf(g.Name)
+	if !ok {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("gpio %s has no feedback line to loop to", g.Name))
+		return
+	}
+	lock := lineLock(g.Name)
+	lock.Lock()
+	defer lock.Unlock()
+	audit("latency-benchmark", g.Name, fmt.Sprintf("%d samples looped to %s", req.Samples, feedback))
+	result, err := s.runLatencyBenchmark(g, feedback, req.Samples, interval, timeout)
+	if err != nil {
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
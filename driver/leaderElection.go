@@ -0,0 +1,245 @@
+package driver
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/edgexfoundry/device-gpiod/gpio"
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/common"
+
+	sdkModels "github.com/edgexfoundry/device-sdk-go/v2/pkg/models"
+)
+
+const (
+	leaderResource = "Leader"
+	leaderRoute    = common.ApiBase + "/leader"
+
+	DEFAULT_LEADER_LEASE = time.Duration(15) * time.Second
+)
+
+var (
+	errStandby = errors.New("service is the standby of a redundant pair")
+
+	leaderMutex     = sync.Mutex{}
+	leaderLease     busLease
+	leaderDuration  = DEFAULT_LEADER_LEASE
+	electionEnabled bool
+	leading         bool
+	// renewedUntil is the expiry of the last lease this service published as leader
+	renewedUntil time.Time
+	// mirroredPipeline is the last pipeline state published by the leader, taken over on promotion
+	mirroredPipeline *PipelineState
+	// publishLeader and publishPipeline send the leadership lease and the pipeline state of the
+	// leader to the other instance, set while the election is enabled
+	publishLeader   func(lease busLease) error
+	publishPipeline func(state PipelineState) error
+	pipelineMirror  = make(chan PipelineState, 1)
+)
+
+// parseLeaderElection reads LEADER_ELECTION, enabling the active/standby pair, and the leadership
+// lease LEADER_LEASE (default 15s).
+func parseLeaderElection() {
+	electionEnabled, _ = strconv.ParseBool(os.Getenv("LEADER_ELECTION"))
+	if env := os.Getenv("LEADER_LEASE"); env != "" {
+		if d, err := time.ParseDuration(env); err == nil && d > 0 {
+			leaderDuration = d
+		} else {
+			logf(moduleStateMachine, levelWarn, "Cannot parse LEADER_LEASE. Picking default value %s...", leaderDuration)
+		}
+	}
+}
+
+// standby reports whether this service is the standby of a redundant pair: it keeps watching the
+// lines and publishing readings, but holds the pipeline and refuses the writes.
+func standby() bool {
+	leaderMutex.Lock()
+	defer leaderMutex.Unlock()
+	return electionEnabled && !leading
+}
+
+// checkLeader refuses the writes of the standby instance.
+func checkLeader() error {
+	if standby() {
+		return errStandby
+	}
+	return nil
+}
+
+// startLeaderElection runs the election of the active instance of the pair every third of the
+// lease, when LEADER_ELECTION is set and the message bus reachable.
+func (s *SimpleDriver) startLeaderElection() {
+	if !electionEnabled {
+		return
+	}
+	if publishLeader == nil {
+		logf(moduleStateMachine, levelError, "LEADER_ELECTION requires COORDINATION_BROKER, staying standby")
+		return
+	}
+	logf(moduleStateMachine, levelInfo, "Leader election enabled as %s, standby until elected", coordinatorID)
+	goBackground(func() {
+		for {
+			s.electLeader()
+			supervisedSleep("leader", leaderDuration/3)
+		}
+	})
+	goBackground(func() {
+		for {
+			select {
+			case state := <-pipelineMirror:
+				if err := publishPipeline(state); err != nil {
+					logSampledf(moduleStateMachine, levelWarn, "pipeline mirror", "Cannot publish the pipeline state to the standby. Error: %s", err)
+				} else {
+					logRecovered("pipeline mirror")
+				}
+			case <-stopping:
+				return
+			}
+		}
+	})
+}
+
+// electLeader renews the lease of the leader, or claims it once free. The leader steps down by itself
+// when it could not renew the lease before its last third, ahead of a takeover by the standby once the
+// lease and the clock skew are over.
+func (s *SimpleDriver) electLeader() {
+	now := time.Now()
+	leaderMutex.Lock()
+	wasLeading := leading
+	if leading && (leaderLease.Holder != coordinatorID || now.After(renewedUntil.Add(-leaderDuration/3))) {
+		leading = false
+	}
+	isLeading, free := leading, leaderLease.free(now)
+	leaderMutex.Unlock()
+	if wasLeading && !isLeading {
+		s.leadershipChanged(false)
+		return
+	}
+	if !isLeading && !free {
+		return
+	}
+
+	lease := busLease{Holder: coordinatorID, Expires: now.Add(leaderDuration)}
+	if err := publishLeader(lease); err != nil {
+		logSampledf(moduleStateMachine, levelError, "leader lease", "Cannot publish the leader lease. Error: %s", err)
+		return
+	}
+	logRecovered("leader lease")
+	leaderMutex.Lock()
+	renewedUntil = lease.Expires
+	leaderMutex.Unlock()
+	if isLeading || !sleepUntilStop(leaseSettle) {
+		return
+	}
+	leaderMutex.Lock()
+	leading = leaderLease.Holder == coordinatorID && !leaderLease.Released
+	won := leading
+	leaderMutex.Unlock()
+	if won {
+		s.leadershipChanged(true)
+	}
+}
+
+// observeLeader applies a leadership lease seen on the message bus.
+func observeLeader(lease busLease) {
+	leaderMutex.Lock()
+	defer leaderMutex.Unlock()
+	leaderLease.observe(lease)
+}
+
+// observePipeline keeps the pipeline state published by the leader for a takeover.
+func observePipeline(state PipelineState) {
+	leaderMutex.Lock()
+	defer leaderMutex.Unlock()
+	if !leading {
+		mirroredPipeline = &state
+	}
+}
+
+// mirrorPipelineState sends the pipeline state of the leader to the standby, keeping the latest
+// only. Called holding pipelineStateMutex.
+func mirrorPipelineState() {
+	if publishPipeline == nil || standby() {
+		return
+	}
+	state := pipelineState
+	state.Lines = make(map[string]bool, len(pipelineState.Lines))
+	for name, on := range pipelineState.Lines {
+		state.Lines[name] = on
+	}
+	select {
+	case <-pipelineMirror:
+	default:
+	}
+	pipelineMirror <- state
+}
+
+func (s *SimpleDriver) leadershipChanged(leader bool) {
+	role := "standby"
+	if leader {
+		role = "leader"
+	}
+	logf(moduleStateMachine, levelWarn, "This service is now the %s", role)
+	audit("leadership", coordinatorID, role)
+	cv, err := sdkModels.NewCommandValue(leaderResource, common.ValueTypeBool, leader)
+	if err != nil {
+		logf(moduleStateMachine, levelError, "Cannot create leader reading. Error: %s", err)
+		return
+	}
+	s.asyncCh <- &sdkModels.AsyncValues{
+		DeviceName:    deviceName(),
+		CommandValues: []*sdkModels.CommandValue{cv},
+	}
+}
+
+// awaitLeadership holds the pipeline while this service is the standby. Once promoted it takes over
+// the state mirrored from the former leader without touching the lines, which the leader left
+// driven: a running pump phase or gap goes on for the time left, see resumePipeline. It returns the
+// pump and run time to continue with.
+func (s *SimpleDriver) awaitLeadership(pump gpio.GPIO, runFor int64) (gpio.GPIO, int64) {
+	if !standby() {
+		return pump, runFor
+	}
+	logf(moduleStateMachine, levelInfo, "Pipeline held, standby of %s", leaderHolder())
+	setPhase(phaseIdle, 0)
+	for standby() {
+		supervisedSleep("pipeline", leaderDuration/3)
+	}
+	leaderMutex.Lock()
+	state := mirroredPipeline
+	mirroredPipeline = nil
+	leaderMutex.Unlock()
+	if state == nil {
+		audit("leader-takeover", coordinatorID, "no pipeline state mirrored, starting from scratch")
+		return pump, runFor
+	}
+	adoptPipelineState(state)
+	audit("leader-takeover", coordinatorID, fmt.Sprintf("taking over phase %s since %s", state.Phase, state.Since.Format(time.RFC3339)))
+	return s.resumePipeline(pump, runFor)
+}
+
+// leaderHolder returns the instance holding the leadership, empty when none.
+func leaderHolder() string {
+	leaderMutex.Lock()
+	defer leaderMutex.Unlock()
+	if leaderLease.free(time.Now()) {
+		return ""
+	}
+	return leaderLease.Holder
+}
+
+func (s *SimpleDriver) handleLeader(w http.ResponseWriter, r *http.Request) {
+	leaderMutex.Lock()
+	defer leaderMutex.Unlock()
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"enabled":  electionEnabled,
+		"id":       coordinatorID,
+		"leading":  leading,
+		"lease":    leaderLease,
+		"mirrored": mirroredPipeline != nil,
+	})
+}
@@ -0,0 +1,219 @@
+package driver
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/edgexfoundry/device-gpiod/gpio"
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/common"
+)
+
+const (
+	handoffRoute       = common.ApiBase + "/handoff"
+	handoffRenewRoute  = common.ApiBase + "/handoff/renew"
+	handoffReturnRoute = common.ApiBase + "/handoff/return"
+)
+
+// Handoff is the temporary ownership of lines granted to another local process. The lines are
+// released by the service, as by a yield, until the owner returns them or the lease expires; the
+// service then re-acquires them and drives them back to their last value. The ID is the token the
+// owner renews and returns the lines with.
+type Handoff struct {
+	ID      string    `json:"id"`
+	Owner   string    `json:"owner"`
+	Lines   []string  `json:"lines"`
+	Since   time.Time `json:"since"`
+	Until   time.Time `json:"until"`
+	Renewed int       `json:"renewed"`
+}
+
+type handoffRequest struct {
+	Owner string   `json:"owner"`
+	Lines []string `json:"lines"`
+	Lease string   `json:"lease"`
+}
+
+type handoffIDRequest struct {
+	ID    string `json:"id"`
+	Lease string `json:"lease,omitempty"`
+}
+
+var (
+	handoffMutex = sync.Mutex{}
+	handoffs     = make(map[string]*Handoff)
+)
+
+// parseLease parses the lease of a handoff, bounded by YIELD_MAX like a yield.
+func parseLease(value string) (time.Duration, error) {
+	lease, err := time.ParseDuration(value)
+	if err != nil || lease <= 0 || lease > maxYield {
+		return 0, fmt.Errorf("lease must be between 0 and %s", maxYield)
+	}
+	return lease, nil
+}
+
+// handleHandoff lists the handoffs; POST hands lines over to a local process, all or none:
+// {"owner": "calibration-tool", "lines": ["valve1", "valve2"], "lease": "5m"}.
+func (s *SimpleDriver) handleHandoff(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		handoffMutex.Lock()
+		list := make([]Handoff, 0, len(handoffs))
+		for _, h := range handoffs {
+			list = append(list, *h)
+		}
+		handoffMutex.Unlock()
+		sort.Slice(list, func(i, j int) bool { return list[i].Since.Before(list[j].Since) })
+		writeJSON(w, http.StatusOK, list)
+		return
+	}
+
+	var req handoffRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if req.Owner == "" || len(req.Lines) == 0 {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("owner and lines are required"))
+		return
+	}
+	lease, err := parseLease(req.Lease)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	lines := make([]*gpio.GPIO, 0, len(req.Lines))
+	for _, name := range req.Lines {
+		g, err := s.writableGpio(name)
+		if err != nil {
+			status := http.StatusConflict
+			if errors.Is(err, errUnknownLine) {
+				status = http.StatusNotFound
+			}
+			writeError(w, status, err)
+			return
+		}
+		lines = append(lines, g)
+	}
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	h := &Handoff{ID: hex.EncodeToString(buf), Owner: req.Owner, Lines: req.Lines, Since: time.Now(), Until: time.Now().Add(lease)}
+
+	for i, g := range lines {
+		name := g.Name
+		err := g.Yield(lease, func() { handoffExpired(h.ID, name) })
+		if err != nil {
+			// All or none: take back the lines already handed over
+			for _, handed := range lines[:i] {
+				if err := handed.Resume(); err != nil {
+					logf(moduleGpio, levelError, "Cannot take back gpio %s. Error: %s", handed.Name, err)
+				}
+			}
+			writeError(w, http.StatusConflict, fmt.Errorf("cannot hand %s over: %w", name, err))
+			return
+		}
+	}
+	handoffMutex.Lock()
+	handoffs[h.ID] = h
+	handoffMutex.Unlock()
+	audit("handoff-start", strings.Join(h.Lines, ","), fmt.Sprintf("%s: handed over to %s for %s", h.ID, h.Owner, lease))
+	writeJSON(w, http.StatusOK, h)
+}
+
+// handoffExpired drops a line from its handoff once its lease expired and the service took it back.
+func handoffExpired(id string, name string) {
+	handoffMutex.Lock()
+	defer handoffMutex.Unlock()
+	h, ok := handoffs[id]
+	if !ok {
+		return
+	}
+	audit("handoff-expired", name, fmt.Sprintf("%s: lease of %s expired, line re-acquired", id, h.Owner))
+	for i, line := range h.Lines {
+		if line == name {
+			h.Lines = append(h.Lines[:i], h.Lines[i+1:]...)
+			break
+		}
+	}
+	if len(h.Lines) == 0 {
+		delete(handoffs, id)
+	}
+}
+
+// handleHandoffRenew extends the lease of a handoff: {"id": "...", "lease": "5m"}.
+func (s *SimpleDriver) handleHandoffRenew(w http.ResponseWriter, r *http.Request) {
+	var req handoffIDRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	lease, err := parseLease(req.Lease)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	handoffMutex.Lock()
+	defer handoffMutex.Unlock()
+	h, ok := handoffs[req.ID]
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("unknown handoff %s", req.ID))
+		return
+	}
+	for _, name := range h.Lines {
+		g, ok := s.findGpio(name)
+		if !ok {
+			continue
+		}
+		if err := g.ExtendYield(lease); err != nil {
+			writeError(w, http.StatusConflict, fmt.Errorf("cannot renew %s: %w", name, err))
+			return
+		}
+	}
+	h.Until = time.Now().Add(lease)
+	h.Renewed++
+	writeJSON(w, http.StatusOK, h)
+}
+
+// handleHandoffReturn gives the lines of a handoff back to the service: {"id": "..."}.
+func (s *SimpleDriver) handleHandoffReturn(w http.ResponseWriter, r *http.Request) {
+	var req handoffIDRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	handoffMutex.Lock()
+	h, ok := handoffs[req.ID]
+	delete(handoffs, req.ID)
+	handoffMutex.Unlock()
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("unknown handoff %s", req.ID))
+		return
+	}
+	var failed []string
+	for _, name := range h.Lines {
+		g, ok := s.findGpio(name)
+		if !ok {
+			continue
+		}
+		if err := g.Resume(); err != nil {
+			logf(moduleGpio, levelError, "Cannot take back gpio %s. Error: %s", name, err)
+			failed = append(failed, name)
+		}
+	}
+	audit("handoff-end", strings.Join(h.Lines, ","), fmt.Sprintf("%s: returned by %s", h.ID, h.Owner))
+	if len(failed) > 0 {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("cannot take back %s", strings.Join(failed, ", ")))
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"id": h.ID, "lines": h.Lines})
+}
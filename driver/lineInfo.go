@@ -0,0 +1,100 @@
+package driver
+
+import (
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/edgexfoundry/device-gpiod/gpio"
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/common"
+
+	sdkModels "github.com/edgexfoundry/device-sdk-go/v2/pkg/models"
+)
+
+const (
+	lineInfoResource = "LineInfoChange"
+	lineInfoRoute    = common.ApiBase + "/diagnostics/lineinfo"
+	maxLineInfo      = 128
+)
+
+// LineInfoStats counts the request state changes of a line, by this service or others.
+type LineInfoStats struct {
+	Requested    int       `json:"requested"`
+	Released     int       `json:"released"`
+	Reconfigured int       `json:"reconfigured"`
+	Foreign      int       `json:"foreign"`
+	LastConsumer string    `json:"lastConsumer"`
+	LastChange   time.Time `json:"lastChange"`
+}
+
+var (
+	lineInfoMutex  = sync.Mutex{}
+	lineInfoEvents []gpio.InfoEvent
+	lineInfoStats  = make(map[string]*LineInfoStats)
+)
+
+// startLineInfoMonitoring watches the info changes of all the configured lines. Requests by other
+// processes are published as readings, as they conflict with the lines managed by this service.
+func (s *SimpleDriver) startLineInfoMonitoring() {
+	if err := gpio.WatchLineInfo(s.GpioList.Gpio, s.handleLineInfo); err != nil {
+		log.Printf("Cannot monitor line info changes. Error: %s", err)
+	}
+}
+
+func (s *SimpleDriver) handleLineInfo(evt gpio.InfoEvent) {
+	lineInfoMutex.Lock()
+	lineInfoEvents = append(lineInfoEvents, evt)
+	if len(lineInfoEvents) > maxLineInfo {
+		lineInfoEvents = lineInfoEvents[len(lineInfoEvents)-maxLineInfo:]
+	}
+	stats, ok := lineInfoStats[evt.Name]
+	if !ok {
+		stats = &LineInfoStats{}
+		lineInfoStats[evt.Name] = stats
+	}
+	switch evt.Type {
+	case gpio.InfoRequested:
+		stats.Requested++
+	case gpio.InfoReleased:
+		stats.Released++
+	case gpio.InfoReconfigured:
+		stats.Reconfigured++
+	}
+	if evt.Foreign {
+		stats.Foreign++
+	}
+	stats.LastConsumer = evt.Consumer
+	stats.LastChange = time.Now()
+	lineInfoMutex.Unlock()
+
+	if !evt.Foreign {
+		return
+	}
+	log.Printf("WARNING: gpio %s (line %d of %s) %s by foreign consumer %q", evt.Name, evt.Line, evt.Chip, evt.Type, evt.Consumer)
+	recordTimelineEvent(evt.Name, "conflict", evt.Type+" by "+evt.Consumer)
+	audit("line-conflict", evt.Name, evt.Type+" by "+evt.Consumer)
+	payload, err := shapePayload(lineInfoResource, evt)
+	if err != nil {
+		log.Printf("Cannot marshal line info change. Error: %s", err)
+		return
+	}
+	cv, err := sdkModels.NewCommandValue(lineInfoResource, common.ValueTypeString, string(payload))
+	if err != nil {
+		log.Printf("Cannot create line info reading. Error: %s", err)
+		return
+	}
+	s.asyncCh <- &sdkModels.AsyncValues{
+		DeviceName:    deviceName(),
+		CommandValues: []*sdkModels.CommandValue{cv},
+	}
+}
+
+func (s *SimpleDriver) handleLineInfoDiagnostics(w http.ResponseWriter, r *http.Request) {
+	lineInfoMutex.Lock()
+	defer lineInfoMutex.Unlock()
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"lines":  lineInfoStats,
+		"events": lineInfoEvents,
+	})
+}
@@ -0,0 +1,181 @@
+package driver
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/edgexfoundry/device-gpiod/gpio"
+)
+
+var (
+	errUnknownLine = errors.New("unknown gpio")
+	errNotWritable = errors.New("gpio is not writable")
+
+	lineLocksMutex = sync.Mutex{}
+	lineLocks      = make(map[string]*sync.Mutex)
+)
+
+// writable reports whether a line may be driven by an external client: inputs and the heartbeat and
+// watchdog lines are owned by the service. Pwm lines are driven through ramps.
+func writable(role string) bool {
+	return isOutputRole(role) && role != RolePwm && role != RoleHeartbeat && role != RoleWatchdog
+}
+
+// lineLock is the mutex serializing the writes of a line, external ones (REST, gRPC, Modbus, twin,
+// schedule, core command) and the cycle pipeline's, so two writers never interleave on a line.
+func lineLock(name string) *sync.Mutex {
+	lineLocksMutex.Lock()
+	defer lineLocksMutex.Unlock()
+	lock, ok := lineLocks[name]
+	if !ok {
+		lock = &sync.Mutex{}
+		lineLocks[name] = lock
+	}
+	return lock
+}
+
+// setPipelineLevel drives a line on behalf of the cycle pipeline, under the same line lock as the
+// external writes.
+func setPipelineLevel(g *gpio.GPIO, on bool) error {
+	lock := lineLock(g.Name)
+	lock.Lock()
+	defer lock.Unlock()
+	return setLevel(g, on)
+}
+
+// startPipelineLine turns a line on for the cycle pipeline, admitted by its concurrency limits as the
+// external writes are. A refused or queued start returns errConcurrencyLimit and the pipeline defers.
+func (s *SimpleDriver) startPipelineLine(g *gpio.GPIO) error {
+	lock := lineLock(g.Name)
+	lock.Lock()
+	defer lock.Unlock()
+	if len(concurrencyLimitsOf(g.Name)) > 0 {
+		concurrencyMutex.Lock()
+		defer concurrencyMutex.Unlock()
+		if err := s.admitConcurrent(g.Name, pipelineSource); err != nil {
+			return err
+		}
+	}
+	return setLevel(g, true)
+}
+
+// readLevel returns the level of a line for the facades: read back for an output, sampled for an input.
+func (s *SimpleDriver) readLevel(name string) (int, error) {
+	g, ok := s.findGpio(name)
+	if !ok {
+		return -1, errUnknownLine
+	}
+	if isOutputRole(g.Role) {
+		return g.ReadBack()
+	}
+	return g.Value()
+}
+
+// writableGpio returns the named line if external clients may drive it.
+func (s *SimpleDriver) writableGpio(name string) (*gpio.GPIO, error) {
+	if err := checkLeader(); err != nil {
+		return nil, err
+	}
+	g, ok := s.findGpio(name)
+	if !ok {
+		return nil, fmt.Errorf("%w %s", errUnknownLine, name)
+	}
+	if !writable(g.Role) {
+		return nil, fmt.Errorf("%w: %s", errNotWritable, name)
+	}
+	return g, nil
+}
+
+// writeLine drives a line on behalf of an external client (source), auditing the write and
+// publishing the new state.
+func (s *SimpleDriver) writeLine(name string, on bool, source string) error {
+	g, err := s.writableGpio(name)
+	if err != nil {
+		return err
+	}
+	if err := checkExposure(g, source); err != nil {
+		return err
+	}
+	lock := lineLock(name)
+	lock.Lock()
+	defer lock.Unlock()
+	return s.driveLine(g, on, source)
+}
+
+// pulseLine drives a line to on for duration, then back. The line lock is taken for each edge, not
+// across the pulse, so a long pulse does not block the other writers of the line. The pulse is
+// followed as an operation.
+func (s *SimpleDriver) pulseLine(name string, on bool, duration time.Duration, source string) error {
+	g, err := s.writableGpio(name)
+	if err != nil {
+		return err
+	}
+	if err := checkExposure(g, source); err != nil {
+		return err
+	}
+	lock := lineLock(name)
+	op := startOperation("pulse", name, duration)
+	lock.Lock()
+	err = s.driveLine(g, on, source)
+	lock.Unlock()
+	if err == nil {
+		time.Sleep(duration)
+		lock.Lock()
+		err = s.driveLine(g, !on, source)
+		lock.Unlock()
+	}
+	op.finish(err)
+	return err
+}
+
+// driveLine drives a writable line. Must be called holding the line lock.
+func (s *SimpleDriver) driveLine(g *gpio.GPIO, on bool, source string) error {
+	if on {
+		if err := s.checkDutyLimit(g.Name, 0); err != nil {
+			return s.deferDutyLimited(g.Name, err)
+		}
+		if len(exclusivePartners(g.Name)) > 0 {
+			exclusiveMutex.Lock()
+			defer exclusiveMutex.Unlock()
+			if err := s.checkExclusive(g); err != nil {
+				return err
+			}
+		}
+		if len(concurrencyLimitsOf(g.Name)) > 0 {
+			concurrencyMutex.Lock()
+			defer concurrencyMutex.Unlock()
+			if err := s.admitConcurrent(g.Name, source); err != nil {
+				return err
+			}
+		}
+	}
+	var err error
+	if on {
+		err = g.Up()
+	} else {
+		err = g.Down()
+	}
+	if err != nil {
+		return err
+	}
+	g.State = on
+	audit(source+"-write", g.Name, fmt.Sprintf("set to %t", on))
+	s.handleAsyncCommunication(*g)
+	return nil
+}
+
+// deferDutyLimited queues a write refused by a duty cycle limit with action defer for the time it
+// fits the limit. The error is returned either way, telling the caller what happened.
+func (s *SimpleDriver) deferDutyLimited(name string, err error) error {
+	var limited *DutyLimitError
+	if !errors.As(err, &limited) || limited.Limit.Action != limitDefer || limited.Wait <= 0 {
+		return err
+	}
+	cmd, queueErr := s.enqueueCommand(name, 1, time.Now().Add(limited.Wait), defaultMaxDrift)
+	if queueErr != nil {
+		return fmt.Errorf("%w (cannot defer: %s)", err, queueErr)
+	}
+	return fmt.Errorf("%w, deferred as %s", err, cmd.ID)
+}
@@ -0,0 +1,190 @@
+package driver
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/common"
+
+	sdkModels "github.com/edgexfoundry/device-sdk-go/v2/pkg/models"
+)
+
+const (
+	loadShedResource = "LoadShedLevel"
+
+	// Shed levels, each dropping the optional work of the ones below. Actuation, interlocks, alarms and
+	// the safety monitors are never shed.
+	shedNone      = 0
+	shedRecorders = 1 // time-series samples and statistics readings
+	shedReporting = 2 // virtual resources evaluated at a quarter of their rate
+	shedClients   = 3 // dashboard and stream clients refused
+
+	shedSlowdown               = 4
+	DEFAULT_SHED_CPU_PRESSURE  = 50.0
+	DEFAULT_SHED_MEM_PRESSURE  = 20.0
+	DEFAULT_LOAD_SHED_INTERVAL = time.Duration(10) * time.Second
+)
+
+// LoadShedding sheds optional work under pressure. The pressure is the highest of the CPU and memory
+// stall percentages of the kernel PSI (avg10 of /proc/pressure/cpu and memory) against their
+// thresholds, and of the heap against the memory budget. A pressure at the threshold sheds the
+// recorders, at 1.5x the reporting, at 2x the clients. The level rises at once and falls one step per
+// interval.
+type LoadShedding struct {
+	CPU      float64 `yaml:"cpu"`
+	Memory   float64 `yaml:"memory"`
+	Interval string  `yaml:"interval"`
+	interval time.Duration
+}
+
+var (
+	shedMutex = sync.Mutex{}
+	shedLevel = shedNone
+)
+
+func validateLoadShedding() error {
+	ls := driverConfig.LoadShedding
+	if ls == nil {
+		return nil
+	}
+	if ls.CPU < 0 || ls.CPU > 100 || ls.Memory < 0 || ls.Memory > 100 {
+		return fmt.Errorf("cpu and memory must be stall percentages between 0 and 100")
+	}
+	if ls.CPU == 0 {
+		ls.CPU = DEFAULT_SHED_CPU_PRESSURE
+	}
+	if ls.Memory == 0 {
+		ls.Memory = DEFAULT_SHED_MEM_PRESSURE
+	}
+	ls.interval = DEFAULT_LOAD_SHED_INTERVAL
+	if ls.Interval != "" {
+		interval, err := time.ParseDuration(ls.Interval)
+		if err != nil || interval <= 0 {
+			return fmt.Errorf("invalid interval %q", ls.Interval)
+		}
+		ls.interval = interval
+	}
+	return nil
+}
+
+// currentShedLevel returns how much optional work is shed.
+func currentShedLevel() int {
+	shedMutex.Lock()
+	defer shedMutex.Unlock()
+	return shedLevel
+}
+
+// shedding reports whether the work of level is shed.
+func shedding(level int) bool {
+	return currentShedLevel() >= level
+}
+
+// shedInterval stretches the interval of reporting work while it is shed.
+func shedInterval(d time.Duration) time.Duration {
+	if shedding(shedReporting) {
+		return d * shedSlowdown
+	}
+	return d
+}
+
+// readPressure returns the avg10 "some" stall percentage of a PSI file.
+func readPressure(path string) (float64, bool) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, false
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || fields[0] != "some" || !strings.HasPrefix(fields[1], "avg10=") {
+			continue
+		}
+		value, err := strconv.ParseFloat(strings.TrimPrefix(fields[1], "avg10="), 64)
+		return value, err == nil
+	}
+	return 0, false
+}
+
+// pressure is the highest ratio of the observed pressures to their thresholds.
+func pressure(ls *LoadShedding) float64 {
+	highest := 0.0
+	if cpu, ok := readPressure("/proc/pressure/cpu"); ok && cpu/ls.CPU > highest {
+		highest = cpu / ls.CPU
+	}
+	if memory, ok := readPressure("/proc/pressure/memory"); ok && memory/ls.Memory > highest {
+		highest = memory / ls.Memory
+	}
+	budgetMutex.Lock()
+	limit := memoryBudget
+	budgetMutex.Unlock()
+	if limit > 0 {
+		var stats runtime.MemStats
+		runtime.ReadMemStats(&stats)
+		if heap := float64(stats.HeapInuse) / float64(limit); heap > highest {
+			highest = heap
+		}
+	}
+	return highest
+}
+
+func pressureLevel(p float64) int {
+	switch {
+	case p >= 2:
+		return shedClients
+	case p >= 1.5:
+		return shedReporting
+	case p >= 1:
+		return shedRecorders
+	}
+	return shedNone
+}
+
+// startLoadShedding evaluates the pressure every interval when the load_shedding section is set.
+func (s *SimpleDriver) startLoadShedding() {
+	ls := driverConfig.LoadShedding
+	if ls == nil {
+		return
+	}
+	goBackground(func() {
+		for {
+			supervisedSleep("load-shedding", ls.interval)
+			p := pressure(ls)
+			target := pressureLevel(p)
+
+			shedMutex.Lock()
+			previous := shedLevel
+			if target > shedLevel {
+				shedLevel = target
+			} else if target < shedLevel {
+				shedLevel--
+			}
+			level := shedLevel
+			shedMutex.Unlock()
+
+			if level != previous {
+				audit("load-shed", deviceName(), fmt.Sprintf("level %d to %d, pressure %.2f", previous, level, p))
+				s.pushLoadShedLevel(level)
+			}
+		}
+	})
+}
+
+func (s *SimpleDriver) pushLoadShedLevel(level int) {
+	cv, err := sdkModels.NewCommandValue(loadShedResource, common.ValueTypeInt8, int8(level))
+	if err != nil {
+		log.Printf("Cannot create load shed reading. Error: %s", err)
+		return
+	}
+	s.asyncCh <- &sdkModels.AsyncValues{
+		DeviceName:    deviceName(),
+		CommandValues: []*sdkModels.CommandValue{cv},
+	}
+}
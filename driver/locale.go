@@ -0,0 +1,141 @@
+package driver
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/common"
+	"gopkg.in/yaml.v3"
+)
+
+const dashboardLabelsRoute = common.ApiBase + "/dashboard/labels"
+
+// messages are the English operator-facing texts: notification contents, API error texts and dashboard
+// labels. Logs are not translated.
+var messages = map[string]string{
+	"notification.security-alarm":   "Security alarm on %s",
+	"notification.pump-failed":      "Pump %s failed: %s",
+	"notification.timing-violation": "%s: feedback did not confirm %d within %s",
+	"notification.maintenance":      "Maintenance recommended for %s: %s",
+	"notification.fail-safe":        "Fail-safe engaged on %s: %s",
+	"notification.consumable-low":   "%s low: %.1f %s left",
+	"notification.valve-fault":      "Valve %s position fault: %s",
+	"notification.health-failed":    "Health probe %s failed: %s",
+	"notification.health-recovered": "Health probe %s recovered",
+
+	"error.unknown-device":      "Unknown device",
+	"error.unknown-line":        "Unknown line",
+	"error.not-writable":        "The line cannot be written",
+	"error.not-exposed":         "The line is not exposed to this client",
+	"error.not-pwm-line":        "The line is not a PWM output",
+	"error.duty-limit":          "Duty cycle limit exceeded",
+	"error.draining":            "The service is shutting down",
+	"error.no-cycle":            "No completed cycle recorded",
+	"error.stream-budget":       "Too many stream clients",
+	"error.unsigned-config":     "The configuration is not signed by a trusted key",
+	"error.standby":             "The service is the standby instance, write to the leader",
+	"error.interlocked":         "An exclusive line is on",
+	"error.concurrency-limit":   "Too many lines of the set are running",
+	"error.invalid-override":    "The override duration is out of bounds",
+	"error.yielded":             "The line is yielded to external tools",
+	"error.overridden":          "The line is under manual override",
+	"error.inhibited":           "Actuation is inhibited",
+	"error.not-requested":       "The line is not requested",
+	"error.injected-fault":      "Injected fault",
+	"error.line-busy":           "The line is used by another process",
+	"error.permission-denied":   "Permission denied",
+	"error.invalid-request":     "Invalid request",
+	"error.unauthorized":        "Authentication required",
+	"error.forbidden":           "Not allowed",
+	"error.not-found":           "Not found",
+	"error.conflict":            "Not possible in the current state",
+	"error.precondition-failed": "Precondition failed",
+	"error.too-many-requests":   "Too many requests",
+	"error.not-implemented":     "Not available in this build",
+	"error.unavailable":         "Temporarily unavailable",
+	"error.internal":            "Internal error",
+	"error.request-failed":      "Request failed",
+
+	"dashboard.phase":        "Phase",
+	"dashboard.lines":        "Lines",
+	"dashboard.alarms":       "Alarms",
+	"dashboard.line":         "Line",
+	"dashboard.name":         "Name",
+	"dashboard.state":        "State",
+	"dashboard.since":        "Since",
+	"dashboard.manual":       "Manual",
+	"dashboard.triggered":    "Triggered",
+	"dashboard.left":         "left",
+	"dashboard.faults":       "Faults",
+	"dashboard.staged-until": "Configuration staged until",
+	"dashboard.confirm":      "Confirm",
+	"dashboard.take-over":    "Take over 10m",
+	"dashboard.give-back":    "Give back",
+	"dashboard.acknowledge":  "Acknowledge",
+	"dashboard.on":           "ON",
+	"dashboard.off":          "OFF",
+	"dashboard.active":       "ACTIVE",
+	"dashboard.latched":      "LATCHED",
+	"dashboard.ok":           "OK",
+}
+
+var (
+	locale       = "en"
+	translations = make(map[string]string)
+)
+
+// loadTranslations reads the texts of LOCALE from TRANSLATIONS_FILE, a YAML file mapping each locale
+// to its translated messages, e.g. it: {dashboard.phase: Fase}. Missing texts fall back to English.
+func loadTranslations() {
+	if value := os.Getenv("LOCALE"); value != "" {
+		locale = value
+	}
+	fileName := os.Getenv("TRANSLATIONS_FILE")
+	if fileName == "" {
+		return
+	}
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		log.Printf("Cannot read translations file. Error: %s", err)
+		return
+	}
+	all := make(map[string]map[string]string)
+	if err := yaml.Unmarshal(data, &all); err != nil {
+		log.Printf("Cannot unmarshal translations file. Error: %s", err)
+		return
+	}
+	translated, ok := all[locale]
+	if !ok {
+		log.Printf("No translations for locale %s, using English", locale)
+		return
+	}
+	for key := range translated {
+		if _, known := messages[key]; !known {
+			log.Printf("Unknown message %s in translations for locale %s", key, locale)
+		}
+	}
+	translations = translated
+}
+
+// tr returns the operator-facing message key in the configured locale, formatted with args.
+func tr(key string, args ...interface{}) string {
+	format, ok := translations[key]
+	if !ok {
+		format = messages[key]
+	}
+	return fmt.Sprintf(format, args...)
+}
+
+// handleDashboardLabels returns the dashboard labels in the configured locale.
+func (s *SimpleDriver) handleDashboardLabels(w http.ResponseWriter, r *http.Request) {
+	labels := make(map[string]string)
+	for key := range messages {
+		if strings.HasPrefix(key, "dashboard.") {
+			labels[strings.TrimPrefix(key, "dashboard.")] = tr(key)
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"locale": locale, "labels": labels})
+}
@@ -0,0 +1,117 @@
+package driver
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/edgexfoundry/device-gpiod/gpio"
+)
+
+const (
+	RoleLockout    = "lockout"
+	lockoutInhibit = "lockout"
+	lockoutFile    = "file"
+)
+
+var (
+	lockoutMutex    = sync.Mutex{}
+	lockoutSources  = make(map[string]bool)
+	lockoutInterval = time.Duration(2) * time.Second
+)
+
+// startLockoutMonitoring supports lockout/tagout procedures: while the file named by LOCKOUT_FILE
+// exists, or a line with role "lockout" (a physical lockout switch) is asserted, every actuation is
+// inhibited and the indicator shows the lockout state. LOCKOUT_INTERVAL is how often the file is checked.
+func (s *SimpleDriver) startLockoutMonitoring() {
+	for i := range s.GpioList.Gpio {
+		g := &s.GpioList.Gpio[i]
+		if g.Role != RoleLockout {
+			continue
+		}
+		if err := g.Watch(s.handleLockoutEvent); err != nil {
+			log.Printf("Cannot monitor lockout gpio %s. Error: %s", g.Name, err)
+			continue
+		}
+		if value, err := g.Value(); err == nil {
+			s.setLockout(g.Name, value == 1)
+		}
+		log.Printf("Monitoring lockout gpio %s", g.Name)
+	}
+
+	path := os.Getenv("LOCKOUT_FILE")
+	if path == "" {
+		return
+	}
+	if d, err := time.ParseDuration(os.Getenv("LOCKOUT_INTERVAL")); err == nil && d > 0 {
+		lockoutInterval = d
+	} else {
+		log.Printf("Cannot parse LOCKOUT_INTERVAL. Picking default value %s...", lockoutInterval)
+	}
+	goBackground(func() {
+		for {
+			_, err := os.Stat(path)
+			s.setLockout(lockoutFile, err == nil)
+			if !sleepUntilStop(lockoutInterval) {
+				return
+			}
+		}
+	})
+	log.Printf("Watching lockout file %s", path)
+}
+
+func (s *SimpleDriver) handleLockoutEvent(evt gpio.Event) {
+	recordTransition(evt.Name, evt.Value == 1)
+	s.setLockout(evt.Name, evt.Value == 1)
+}
+
+// setLockout records whether source holds a lockout. The first lockout inhibits actuation and drives
+// the outputs to safe state, releasing the last one allows actuation again.
+func (s *SimpleDriver) setLockout(source string, active bool) {
+	lockoutMutex.Lock()
+	if lockoutSources[source] == active {
+		lockoutMutex.Unlock()
+		return
+	}
+	wasLocked := len(lockoutSources) > 0
+	if active {
+		lockoutSources[source] = true
+	} else {
+		delete(lockoutSources, source)
+	}
+	locked := len(lockoutSources) > 0
+	lockoutMutex.Unlock()
+
+	switch {
+	case locked && !wasLocked:
+		gpio.Inhibit(lockoutInhibit)
+		s.driveSafeState("lockout by "+source, false)
+		audit("lockout", source, "actuation locked out")
+	case !locked && wasLocked:
+		gpio.ReleaseInhibit(lockoutInhibit)
+		audit("lockout-released", source, "actuation allowed")
+	case active:
+		audit("lockout", source, "lockout also held by "+source)
+	default:
+		audit("lockout-released", source, fmt.Sprintf("lockout still held by %v", lockouts()))
+	}
+}
+
+// lockouts returns the sources currently holding a lockout.
+func lockouts() []string {
+	lockoutMutex.Lock()
+	defer lockoutMutex.Unlock()
+	sources := make([]string, 0, len(lockoutSources))
+	for source := range lockoutSources {
+		sources = append(sources, source)
+	}
+	sort.Strings(sources)
+	return sources
+}
+
+func lockedOut() bool {
+	return len(lockouts()) > 0
+}
@@ -0,0 +1,120 @@
+package driver
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"sync"
+
+	"github.com/edgexfoundry/device-gpiod/config"
+	"github.com/edgexfoundry/device-gpiod/gpio"
+)
+
+// Modules with their own log level, see config.LogModules
+const (
+	moduleGpio         = "gpio"
+	moduleStateMachine = "statemachine"
+	moduleIndicators   = "indicators"
+	moduleConnectivity = "connectivity"
+	moduleBridges      = "bridges"
+)
+
+// Log levels, as indexes of config.LogLevelNames
+const (
+	levelDebug = iota
+	levelInfo
+	levelWarn
+	levelError
+)
+
+var (
+	logLevelMutex = sync.Mutex{}
+	moduleLevels  = make(map[string]int)
+)
+
+// applyLogLevels sets the level of every module: info, or debug with VERBOSE, then the levels of
+// LOG_LEVELS and at last the ones of the writable configuration, as module=level pairs.
+func (s *SimpleDriver) applyLogLevels(writable string) error {
+	base := levelInfo
+	if s.Verbose {
+		base = levelDebug
+	}
+	levels := make(map[string]int)
+	for _, module := range config.LogModules {
+		levels[module] = base
+	}
+	env, err := config.ParseLogLevels(os.Getenv("LOG_LEVELS"))
+	if err != nil {
+		log.Printf("Cannot parse LOG_LEVELS, ignoring it. Error: %s", err)
+	}
+	overrides, err := config.ParseLogLevels(writable)
+	if err != nil {
+		return err
+	}
+	for _, set := range []map[string]int{env, overrides} {
+		for module, level := range set {
+			levels[module] = level
+		}
+	}
+	logLevelMutex.Lock()
+	moduleLevels = levels
+	logLevelMutex.Unlock()
+	gpio.SetVerbose(levels[moduleGpio] == levelDebug)
+	names := make(map[string]string, len(levels))
+	for module, level := range levels {
+		names[module] = config.LogLevelNames[level]
+	}
+	log.Printf("Log levels: %v", names)
+	return nil
+}
+
+// logEnabled reports whether the module logs at level.
+func logEnabled(module string, level int) bool {
+	logLevelMutex.Lock()
+	defer logLevelMutex.Unlock()
+	min, ok := moduleLevels[module]
+	if !ok {
+		min = levelInfo
+	}
+	return level >= min
+}
+
+// logf logs on behalf of the module when its level allows, prefixed with the module name.
+func logf(module string, level int, format string, args ...interface{}) {
+	if logEnabled(module, level) {
+		log.Printf("[%s] %s", module, fmt.Sprintf(format, args...))
+	}
+}
+
+// debugf logs at debug level on behalf of the module.
+func debugf(module string, format string, args ...interface{}) {
+	logf(module, levelDebug, format, args...)
+}
+
+// logSampledf logs on behalf of the module an error that may repeat under key, the first occurrence
+// then every LOG_SAMPLE_EVERY, until logRecovered is called for the key.
+func logSampledf(module string, level int, key string, format string, args ...interface{}) {
+	if logEnabled(module, level) {
+		gpio.SampledLogf(key, "["+module+"] "+format, args...)
+	}
+}
+
+// logRecovered ends the repetition of the error logged under key.
+func logRecovered(key string) {
+	gpio.Recovered(key)
+}
+
+// loadLogSampling reads LOG_SAMPLE_EVERY, how many occurrences of a repeated error are logged once.
+func loadLogSampling() {
+	value := os.Getenv("LOG_SAMPLE_EVERY")
+	if value == "" {
+		return
+	}
+	every, err := strconv.Atoi(value)
+	if err != nil || every < 1 {
+		log.Printf("Cannot parse LOG_SAMPLE_EVERY. Picking default value %d...", gpio.DEFAULT_SAMPLE_EVERY)
+		return
+	}
+	gpio.SetLogSampling(every)
+}
@@ -0,0 +1,246 @@
+package driver
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/common"
+
+	sdkModels "github.com/edgexfoundry/device-sdk-go/v2/pkg/models"
+)
+
+const (
+	maintenanceResource   = "MaintenanceRecommended"
+	maintenanceRoute      = common.ApiBase + "/maintenance"
+	maintenanceResetRoute = common.ApiBase + "/maintenance/reset"
+
+	driftLatency      = "latency"
+	driftVerification = "verification"
+	driftOverrun      = "overrun"
+
+	defaultBaselineSamples     = 20
+	defaultLatencyDrift        = 1.5
+	defaultOverrunTolerance    = 0.2
+	DEFAULT_MAINTENANCE_WINDOW = time.Duration(24) * time.Hour
+)
+
+// Maintenance learns a baseline per actuator and recommends maintenance when its behaviour drifts:
+// the feedback latency averaged over the last samples exceeds LatencyDrift times the baseline, or
+// more than VerifyFailures verification failures or Overruns phase overruns (a phase lasting more
+// than OverrunTolerance past its expected duration) happen within Window. Zero disables a check.
+type Maintenance struct {
+	BaselineSamples  int     `yaml:"baseline_samples"`
+	LatencyDrift     float64 `yaml:"latency_drift"`
+	VerifyFailures   int     `yaml:"verify_failures"`
+	Overruns         int     `yaml:"overruns"`
+	OverrunTolerance float64 `yaml:"overrun_tolerance"`
+	Window           string  `yaml:"window"`
+	window           time.Duration
+}
+
+// ActuatorHealth is the baseline and recent behaviour of an actuator (an output line or a phase).
+type ActuatorHealth struct {
+	Name           string            `json:"name"`
+	Samples        int               `json:"samples"`
+	Baseline       time.Duration     `json:"baseline"`
+	Recent         time.Duration     `json:"recent"`
+	VerifyFailures []time.Time       `json:"verifyFailures,omitempty"`
+	Overruns       []time.Time       `json:"overruns,omitempty"`
+	Recommended    bool              `json:"recommended"`
+	Reasons        map[string]string `json:"reasons,omitempty"`
+	Since          time.Time         `json:"since,omitempty"`
+}
+
+type maintenanceResetRequest struct {
+	Name string `json:"name"`
+}
+
+var (
+	maintenanceMutex = sync.Mutex{}
+	actuatorHealth   = make(map[string]*ActuatorHealth)
+)
+
+// validateMaintenance checks the maintenance section of the configuration file.
+func validateMaintenance() error {
+	m := driverConfig.Maintenance
+	if m == nil {
+		return nil
+	}
+	if m.BaselineSamples < 0 || m.LatencyDrift < 0 || m.VerifyFailures < 0 || m.Overruns < 0 || m.OverrunTolerance < 0 {
+		return fmt.Errorf("bounds must not be negative")
+	}
+	if m.BaselineSamples == 0 {
+		m.BaselineSamples = defaultBaselineSamples
+	}
+	if m.LatencyDrift == 0 {
+		m.LatencyDrift = defaultLatencyDrift
+	} else if m.LatencyDrift <= 1 {
+		return fmt.Errorf("latency_drift %.2f must be above 1", m.LatencyDrift)
+	}
+	if m.OverrunTolerance == 0 {
+		m.OverrunTolerance = defaultOverrunTolerance
+	}
+	m.window = DEFAULT_MAINTENANCE_WINDOW
+	if m.Window != "" {
+		window, err := time.ParseDuration(m.Window)
+		if err != nil || window <= 0 {
+			return fmt.Errorf("invalid window %q", m.Window)
+		}
+		m.window = window
+	}
+	return nil
+}
+
+// healthOf returns the health record of the actuator. Must be called holding maintenanceMutex.
+func healthOf(name string) *ActuatorHealth {
+	h, ok := actuatorHealth[name]
+	if !ok {
+		h = &ActuatorHealth{Name: name}
+		actuatorHealth[name] = h
+	}
+	return h
+}
+
+// recent drops the events older than the window.
+func recent(events []time.Time, window time.Duration) []time.Time {
+	cutoff := time.Now().Add(-window)
+	for len(events) > 0 && events[0].Before(cutoff) {
+		events = events[1:]
+	}
+	return events
+}
+
+// observeLatency feeds a feedback latency of the line: the first samples make the baseline, the later
+// ones a running mean compared to it.
+func (s *SimpleDriver) observeLatency(name string, latency time.Duration) {
+	m := driverConfig.Maintenance
+	if m == nil {
+		return
+	}
+	maintenanceMutex.Lock()
+	h := healthOf(name)
+	h.Samples++
+	if h.Samples <= m.BaselineSamples {
+		h.Baseline += (latency - h.Baseline) / time.Duration(h.Samples)
+		h.Recent = h.Baseline
+		maintenanceMutex.Unlock()
+		return
+	}
+	h.Recent = time.Duration(latencyWeight*float64(latency) + (1-latencyWeight)*float64(h.Recent))
+	baseline, mean := h.Baseline, h.Recent
+	maintenanceMutex.Unlock()
+	if float64(mean) > m.LatencyDrift*float64(baseline) {
+		s.recommendMaintenance(name, driftLatency, fmt.Sprintf("feedback latency drifted to %s (baseline %s)",
+			mean.Round(time.Millisecond), baseline.Round(time.Millisecond)))
+	}
+}
+
+// observeVerifyFailure counts a failed verification of an actuation of the line.
+func (s *SimpleDriver) observeVerifyFailure(name string) {
+	m := driverConfig.Maintenance
+	if m == nil || m.VerifyFailures == 0 {
+		return
+	}
+	maintenanceMutex.Lock()
+	h := healthOf(name)
+	h.VerifyFailures = append(recent(h.VerifyFailures, m.window), time.Now())
+	count := len(h.VerifyFailures)
+	maintenanceMutex.Unlock()
+	if count > m.VerifyFailures {
+		s.recommendMaintenance(name, driftVerification, fmt.Sprintf("%d verification failures within %s", count, m.window))
+	}
+}
+
+// observePhase checks the duration of a completed phase against the expected one.
+func (s *SimpleDriver) observePhase(status PhaseStatus) {
+	m := driverConfig.Maintenance
+	if m == nil || m.Overruns == 0 || status.Until.IsZero() {
+		return
+	}
+	expected := status.Until.Sub(status.Since)
+	took := time.Since(status.Since)
+	if float64(took) <= float64(expected)*(1+m.OverrunTolerance) {
+		return
+	}
+	maintenanceMutex.Lock()
+	h := healthOf(status.Name)
+	h.Overruns = append(recent(h.Overruns, m.window), time.Now())
+	count := len(h.Overruns)
+	maintenanceMutex.Unlock()
+	if count > m.Overruns {
+		s.recommendMaintenance(status.Name, driftOverrun, fmt.Sprintf("%d phase overruns within %s, last took %s for %s",
+			count, m.window, took.Round(time.Second), expected.Round(time.Second)))
+	}
+}
+
+// recommendMaintenance flags the actuator and publishes the MaintenanceRecommended event, once per kind
+// of drift until the actuator is reset.
+func (s *SimpleDriver) recommendMaintenance(name string, kind string, reason string) {
+	maintenanceMutex.Lock()
+	h := healthOf(name)
+	if _, ok := h.Reasons[kind]; ok {
+		maintenanceMutex.Unlock()
+		return
+	}
+	if !h.Recommended {
+		h.Recommended, h.Since = true, time.Now()
+		h.Reasons = make(map[string]string)
+	}
+	h.Reasons[kind] = reason
+	snapshot := *h
+	maintenanceMutex.Unlock()
+
+	log.Printf("WARNING: maintenance recommended for %s: %s", name, reason)
+	audit("maintenance-recommended", name, reason)
+	recordTimelineEvent(name, "maintenance-recommended", reason)
+	sendNotification("maintenance", notificationSeverityNormal, tr("notification.maintenance", name, reason))
+
+	payload, err := shapePayload(maintenanceResource, snapshot)
+	if err != nil {
+		log.Printf("Cannot marshal maintenance recommendation. Error: %s", err)
+		return
+	}
+	cv, err := sdkModels.NewCommandValue(maintenanceResource, common.ValueTypeString, string(payload))
+	if err != nil {
+		log.Printf("Cannot create maintenance recommendation reading. Error: %s", err)
+		return
+	}
+	s.asyncCh <- &sdkModels.AsyncValues{
+		DeviceName:    deviceName(),
+		CommandValues: []*sdkModels.CommandValue{cv},
+	}
+}
+
+// handleMaintenance returns the health of the actuators observed so far.
+func (s *SimpleDriver) handleMaintenance(w http.ResponseWriter, r *http.Request) {
+	maintenanceMutex.Lock()
+	defer maintenanceMutex.Unlock()
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"enabled":   driverConfig.Maintenance != nil,
+		"actuators": actuatorHealth,
+	})
+}
+
+// handleMaintenanceReset forgets the baseline and the events of an actuator once it was serviced, so
+// the baseline of the new relay or valve is learned again.
+func (s *SimpleDriver) handleMaintenanceReset(w http.ResponseWriter, r *http.Request) {
+	var req maintenanceResetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	maintenanceMutex.Lock()
+	_, ok := actuatorHealth[req.Name]
+	delete(actuatorHealth, req.Name)
+	maintenanceMutex.Unlock()
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("no maintenance record for %s", req.Name))
+		return
+	}
+	audit("maintenance-reset", req.Name, "baseline reset after service")
+	writeJSON(w, http.StatusOK, map[string]interface{}{"name": req.Name})
+}
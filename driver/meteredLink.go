@@ -0,0 +1,315 @@
+package driver
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/common"
+
+	sdkModels "github.com/edgexfoundry/device-sdk-go/v2/pkg/models"
+)
+
+const (
+	reportingProfileResource = "ReportingProfile"
+	reportingRoute           = common.ApiBase + "/reporting"
+
+	// Reporting profiles: every reading as it comes, the metered one saving the bandwidth of a cellular
+	// link, and auto switching to metered while the default route goes through a metered interface
+	profileNormal  = "normal"
+	profileMetered = "metered"
+	profileAuto    = "auto"
+
+	DEFAULT_METERED_MIN_INTERVAL = time.Duration(15) * time.Minute
+	DEFAULT_METERED_BATCH        = time.Duration(1) * time.Minute
+	DEFAULT_METERED_INTERVAL     = time.Duration(30) * time.Second
+
+	procRoute = "/proc/net/route"
+)
+
+// MeteredLink is the metered reporting profile: the periodic readings, such as metrics and counters,
+// are published at most every min_interval, the ramp progress readings are dropped but the last one,
+// and the readings are batched into one event per batch window. With the profile set to auto, it
+// applies while the default route goes through one of interfaces, interface names or globs such as
+// wwan* checked every interval.
+type MeteredLink struct {
+	Interfaces  []string `yaml:"interfaces"`
+	MinInterval string   `yaml:"min_interval"`
+	Batch       string   `yaml:"batch"`
+	Interval    string   `yaml:"interval"`
+	minInterval time.Duration
+	batch       time.Duration
+	interval    time.Duration
+}
+
+type reportingRequest struct {
+	Profile string `json:"profile"`
+}
+
+var (
+	reportingMutex = sync.Mutex{}
+	// reportingProfile is the profile requested, meteredActive whether the metered one applies
+	reportingProfile = profileAuto
+	meteredActive    bool
+	meteredInterface string
+	// defaultMetered applies when the profile is switched to metered without a metered section
+	defaultMetered = MeteredLink{minInterval: DEFAULT_METERED_MIN_INTERVAL, batch: DEFAULT_METERED_BATCH, interval: DEFAULT_METERED_INTERVAL}
+)
+
+// validateMetered checks the metered section of the configuration file.
+func validateMetered() error {
+	m := driverConfig.Metered
+	if m == nil {
+		return nil
+	}
+	for _, name := range m.Interfaces {
+		if _, err := path.Match(name, ""); err != nil {
+			return fmt.Errorf("invalid interface pattern %q", name)
+		}
+	}
+	durations := []struct {
+		name   string
+		value  string
+		def    time.Duration
+		parsed *time.Duration
+	}{
+		{"min_interval", m.MinInterval, DEFAULT_METERED_MIN_INTERVAL, &m.minInterval},
+		{"batch", m.Batch, DEFAULT_METERED_BATCH, &m.batch},
+		{"interval", m.Interval, DEFAULT_METERED_INTERVAL, &m.interval},
+	}
+	for _, d := range durations {
+		*d.parsed = d.def
+		if d.value == "" {
+			continue
+		}
+		value, err := time.ParseDuration(d.value)
+		if err != nil || value < 0 || (value == 0 && d.name == "interval") {
+			return fmt.Errorf("invalid %s %q", d.name, d.value)
+		}
+		*d.parsed = value
+	}
+	return nil
+}
+
+func meteredSettings() *MeteredLink {
+	if m := driverConfig.Metered; m != nil {
+		return m
+	}
+	return &defaultMetered
+}
+
+// metered reports whether the metered reporting profile applies.
+func metered() bool {
+	reportingMutex.Lock()
+	defer reportingMutex.Unlock()
+	return meteredActive
+}
+
+// reportingInterval returns the interval of periodic reporting work, stretched while load is shed and
+// raised to the minimum interval of the metered profile.
+func reportingInterval(d time.Duration) time.Duration {
+	d = shedInterval(d)
+	if min := meteredSettings().minInterval; metered() && d < min {
+		return min
+	}
+	return d
+}
+
+// startReportingProfile applies REPORTING_PROFILE (normal, metered or auto, the default) and, with
+// the metered section set, watches the default route to switch the auto profile.
+func (s *SimpleDriver) startReportingProfile() {
+	if profile := os.Getenv("REPORTING_PROFILE"); profile != "" {
+		if !validReportingProfile(profile) {
+			log.Printf("Unknown REPORTING_PROFILE %s. Picking default value %s...", profile, profileAuto)
+		} else {
+			reportingMutex.Lock()
+			reportingProfile = profile
+			reportingMutex.Unlock()
+		}
+	}
+	s.updateReportingProfile()
+	if driverConfig.Metered == nil || len(driverConfig.Metered.Interfaces) == 0 {
+		return
+	}
+	interval := driverConfig.Metered.interval
+	goBackground(func() {
+		for {
+			supervisedSleep("metered", interval)
+			s.updateReportingProfile()
+		}
+	})
+}
+
+func validReportingProfile(profile string) bool {
+	return profile == profileNormal || profile == profileMetered || profile == profileAuto
+}
+
+// updateReportingProfile decides whether the metered profile applies and publishes the
+// ReportingProfile reading when that changes.
+func (s *SimpleDriver) updateReportingProfile() {
+	iface := ""
+	if m := driverConfig.Metered; m != nil && len(m.Interfaces) > 0 {
+		iface = meteredRoute(m.Interfaces)
+	}
+	reportingMutex.Lock()
+	active := reportingProfile == profileMetered || (reportingProfile == profileAuto && iface != "")
+	changed := active != meteredActive
+	meteredActive, meteredInterface = active, iface
+	profile := reportingProfile
+	reportingMutex.Unlock()
+	if !changed {
+		return
+	}
+	current := profileNormal
+	detail := fmt.Sprintf("%s profile", profile)
+	if active {
+		current = profileMetered
+		if profile == profileAuto {
+			detail = fmt.Sprintf("default route through %s", iface)
+		}
+	}
+	log.Printf("Reporting profile set to %s (%s)", current, detail)
+	audit("reporting-profile", deviceName(), fmt.Sprintf("%s: %s", current, detail))
+	cv, err := sdkModels.NewCommandValue(reportingProfileResource, common.ValueTypeString, current)
+	if err != nil {
+		log.Printf("Cannot create reporting profile reading. Error: %s", err)
+		return
+	}
+	s.asyncCh <- &sdkModels.AsyncValues{
+		DeviceName:    deviceName(),
+		CommandValues: []*sdkModels.CommandValue{cv},
+	}
+}
+
+// meteredRoute returns the interface of the default route when it matches one of the patterns, empty
+// otherwise. The default route of lowest metric wins.
+func meteredRoute(patterns []string) string {
+	f, err := os.Open(procRoute)
+	if err != nil {
+		logSampledf(moduleConnectivity, levelWarn, "default route", "Cannot read the routing table. Error: %s", err)
+		return ""
+	}
+	defer f.Close()
+	logRecovered("default route")
+	iface, metric := "", -1
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// Iface Destination Gateway Flags RefCnt Use Metric Mask ...
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 8 || fields[1] != "00000000" || fields[7] != "00000000" {
+			continue
+		}
+		var m int
+		if _, err := fmt.Sscanf(fields[6], "%d", &m); err != nil {
+			continue
+		}
+		if metric < 0 || m < metric {
+			iface, metric = fields[0], m
+		}
+	}
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, iface); matched && iface != "" {
+			return iface
+		}
+	}
+	return ""
+}
+
+// batchReadings forwards the readings sent to the returned channel to out. While the metered profile
+// applies, the readings are held for the batch window and sent as one event per device. Once done is
+// closed, the held readings are sent and the later ones dropped: nothing reads out after Stop.
+func batchReadings(out chan<- *sdkModels.AsyncValues, done <-chan struct{}) chan<- *sdkModels.AsyncValues {
+	in := make(chan *sdkModels.AsyncValues, cap(out))
+	stop := stopping
+	go func() {
+		var pending []*sdkModels.AsyncValues
+		var flush <-chan time.Time
+		closed := false
+		send := func() {
+			for _, values := range pending {
+				out <- values
+			}
+			pending, flush = nil, nil
+		}
+		for {
+			select {
+			case values := <-in:
+				if closed {
+					log.Printf("Reading of %s dropped, the driver is stopped", values.DeviceName)
+					continue
+				}
+				batch := meteredSettings().batch
+				if stop == nil || !metered() || batch == 0 {
+					send()
+					out <- values
+					continue
+				}
+				pending = mergeReadings(pending, values)
+				if flush == nil {
+					flush = time.After(batch)
+				}
+			case <-flush:
+				send()
+			case <-stop:
+				// Nothing is held back on shutdown, later readings go straight through
+				stop = nil
+				send()
+			case <-done:
+				done = nil
+				closed = true
+				send()
+			}
+		}
+	}()
+	return in
+}
+
+// mergeReadings adds the command values of values to the pending event of the same device and source.
+func mergeReadings(pending []*sdkModels.AsyncValues, values *sdkModels.AsyncValues) []*sdkModels.AsyncValues {
+	for _, p := range pending {
+		if p.DeviceName == values.DeviceName && p.SourceName == values.SourceName {
+			p.CommandValues = append(p.CommandValues, values.CommandValues...)
+			return pending
+		}
+	}
+	merged := *values
+	merged.CommandValues = append([]*sdkModels.CommandValue{}, values.CommandValues...)
+	return append(pending, &merged)
+}
+
+// handleReporting returns the reporting profile; POST switches it: {"profile": "metered"}.
+func (s *SimpleDriver) handleReporting(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPost {
+		var req reportingRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		if !validReportingProfile(req.Profile) {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("unknown reporting profile %s", req.Profile))
+			return
+		}
+		reportingMutex.Lock()
+		reportingProfile = req.Profile
+		reportingMutex.Unlock()
+		audit("reporting-profile-request", deviceName(), req.Profile)
+		s.updateReportingProfile()
+	}
+	m := meteredSettings()
+	reportingMutex.Lock()
+	defer reportingMutex.Unlock()
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"profile":     reportingProfile,
+		"metered":     meteredActive,
+		"interface":   meteredInterface,
+		"minInterval": m.minInterval.String(),
+		"batch":       m.batch.String(),
+	})
+}
@@ -0,0 +1,217 @@
+package driver
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/edgexfoundry/device-gpiod/gpio"
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/common"
+
+	sdkModels "github.com/edgexfoundry/device-sdk-go/v2/pkg/models"
+)
+
+const (
+	metricsResource = "Metrics"
+	// The SDK serves its own runtime metrics at /api/v2/metrics
+	metricsRoute = common.ApiBase + "/metrics/gpio"
+
+	DEFAULT_METRICS_INTERVAL = time.Duration(5) * time.Minute
+)
+
+// Metric is a counter by label set, named after the Prometheus conventions.
+type Metric struct {
+	Name   string            `json:"name"`
+	Help   string            `json:"-"`
+	Type   string            `json:"type"`
+	Labels map[string]string `json:"labels,omitempty"`
+	Value  float64           `json:"value"`
+}
+
+type metricKey struct {
+	name   string
+	labels string
+}
+
+var (
+	metricsMutex = sync.Mutex{}
+	metrics      = make(map[metricKey]*Metric)
+	// lastActuation is the last level driven on each output, to count the toggles only
+	lastActuation = make(map[string]int)
+
+	metricHelp = map[string]string{
+		"gpiod_line_toggles_total":          "Level changes driven on the output",
+		"gpiod_line_on_seconds_total":       "Time the output spent on",
+		"gpiod_input_events_total":          "Edge events received on the input",
+		"gpiod_line_errors_total":           "Failed requests and releases of the line",
+		"gpiod_phase_seconds_total":         "Time the pipeline spent in the phase",
+		"gpiod_phase_total":                 "Phases entered by the pipeline",
+		"gpiod_watchdog_trips_total":        "Times the service stopped petting the watchdog",
+		"gpiod_reconnect_attempts_total":    "Reconnection attempts of the MQTT clients",
+		"gpiod_health_probe_failures_total": "Failed health probe checks",
+		"gpiod_repeated_errors_total":       "Repetitions of an error after its first occurrence, logged once in LOG_SAMPLE_EVERY",
+	}
+)
+
+// addMetric adds delta to the counter named name with the labels, given as name/value pairs.
+func addMetric(name string, delta float64, labels ...string) {
+	key := metricKey{name: name, labels: strings.Join(labels, "\x00")}
+	metricsMutex.Lock()
+	defer metricsMutex.Unlock()
+	m, ok := metrics[key]
+	if !ok {
+		m = &Metric{Name: name, Help: metricHelp[name], Type: "counter"}
+		if len(labels) > 0 {
+			m.Labels = make(map[string]string)
+			for i := 0; i+1 < len(labels); i += 2 {
+				m.Labels[labels[i]] = labels[i+1]
+			}
+		}
+		metrics[key] = m
+	}
+	m.Value += delta
+}
+
+func countMetric(name string, labels ...string) {
+	addMetric(name, 1, labels...)
+}
+
+// startMetrics registers the gpio hooks feeding the metrics and publishes the Metrics reading every
+// METRICS_INTERVAL (default 5m, 0 disables it). The metrics are served in the Prometheus text format
+// by the metrics route.
+func (s *SimpleDriver) startMetrics() {
+	gpio.OnEdge(func(name string, value int) {
+		countMetric("gpiod_input_events_total", "line", name)
+	})
+	gpio.OnLineError(func(name string, op string, err error) {
+		countMetric("gpiod_line_errors_total", "line", name, "op", op)
+	})
+	gpio.OnRepeatedError(func(key string) {
+		countMetric("gpiod_repeated_errors_total", "error", key)
+	})
+	interval := DEFAULT_METRICS_INTERVAL
+	if value := os.Getenv("METRICS_INTERVAL"); value != "" {
+		d, err := time.ParseDuration(value)
+		if err != nil || d < 0 {
+			log.Printf("Cannot parse METRICS_INTERVAL. Picking default value %s...", interval)
+		} else {
+			interval = d
+		}
+	}
+	if interval == 0 {
+		return
+	}
+	goBackground(func() {
+		for {
+			supervisedSleep("metrics", reportingInterval(interval))
+			s.pushMetrics()
+		}
+	})
+}
+
+// countActuation counts a level driven on an output, when it changes the level driven before.
+func countActuation(name string, value int) {
+	metricsMutex.Lock()
+	previous, ok := lastActuation[name]
+	lastActuation[name] = value
+	metricsMutex.Unlock()
+	if !ok || previous != value {
+		countMetric("gpiod_line_toggles_total", "line", name)
+	}
+}
+
+// observePhase records a phase the pipeline left after d.
+func observePhase(name string, d time.Duration) {
+	countMetric("gpiod_phase_total", "phase", name)
+	addMetric("gpiod_phase_seconds_total", d.Seconds(), "phase", name)
+}
+
+// metricsSnapshot returns the metrics sorted by name and labels, with the on time of the outputs
+// up to now.
+func metricsSnapshot() []Metric {
+	now := time.Now()
+	statsMutex.Lock()
+	onTime := make(map[string]float64, len(stats))
+	for name, st := range stats {
+		runtime := st.runtime
+		if !st.onSince.IsZero() {
+			runtime += now.Sub(st.onSince)
+		}
+		onTime[name] = runtime.Seconds()
+	}
+	statsMutex.Unlock()
+
+	metricsMutex.Lock()
+	snapshot := make([]Metric, 0, len(metrics)+len(onTime))
+	for _, m := range metrics {
+		snapshot = append(snapshot, *m)
+	}
+	metricsMutex.Unlock()
+	for name, seconds := range onTime {
+		snapshot = append(snapshot, Metric{Name: "gpiod_line_on_seconds_total", Help: metricHelp["gpiod_line_on_seconds_total"],
+			Type: "counter", Labels: map[string]string{"line": name}, Value: seconds})
+	}
+	sort.Slice(snapshot, func(i, j int) bool {
+		if snapshot[i].Name != snapshot[j].Name {
+			return snapshot[i].Name < snapshot[j].Name
+		}
+		return formatLabels(snapshot[i].Labels) < formatLabels(snapshot[j].Labels)
+	})
+	return snapshot
+}
+
+func formatLabels(labels map[string]string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	pairs := make([]string, len(names))
+	for i, name := range names {
+		value := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(labels[name])
+		pairs[i] = fmt.Sprintf(`%s="%s"`, name, value)
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// pushMetrics publishes the metrics as the Metrics reading.
+func (s *SimpleDriver) pushMetrics() {
+	payload, err := shapePayload(metricsResource, metricsSnapshot())
+	if err != nil {
+		log.Printf("Cannot marshal metrics. Error: %s", err)
+		return
+	}
+	cv, err := sdkModels.NewCommandValue(metricsResource, common.ValueTypeString, string(payload))
+	if err != nil {
+		log.Printf("Cannot create metrics reading. Error: %s", err)
+		return
+	}
+	s.asyncCh <- &sdkModels.AsyncValues{
+		DeviceName:    deviceName(),
+		CommandValues: []*sdkModels.CommandValue{cv},
+	}
+}
+
+// handleMetrics serves the metrics in the Prometheus text exposition format.
+func (s *SimpleDriver) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	previous := ""
+	for _, m := range metricsSnapshot() {
+		if m.Name != previous {
+			if m.Help != "" {
+				fmt.Fprintf(w, "# HELP %s %s\n", m.Name, m.Help)
+			}
+			fmt.Fprintf(w, "# TYPE %s %s\n", m.Name, m.Type)
+			previous = m.Name
+		}
+		fmt.Fprintf(w, "%s%s %g\n", m.Name, formatLabels(m.Labels), m.Value)
+	}
+}
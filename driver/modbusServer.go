@@ -0,0 +1,212 @@
+//go:build !nomodbus
+
+package driver
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"os"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/common"
+)
+
+const (
+	modbusMapRoute = common.ApiBase + "/modbus/map"
+
+	modbusReadCoils          = 0x01
+	modbusReadDiscreteInputs = 0x02
+	modbusWriteSingleCoil    = 0x05
+	modbusWriteMultipleCoils = 0x0F
+
+	modbusIllegalFunction = 0x01
+	modbusIllegalAddress  = 0x02
+	modbusIllegalValue    = 0x03
+	modbusDeviceFailure   = 0x04
+
+	maxModbusFrame = 260
+)
+
+// modbusCompiled tells whether the Modbus facade is part of the build. It is left out with the
+// nomodbus build tag.
+const modbusCompiled = true
+
+// ModbusMapping is the address of a line in the Modbus facade.
+type ModbusMapping struct {
+	Address  int    `json:"address"`
+	Name     string `json:"name"`
+	Writable bool   `json:"writable"`
+}
+
+var (
+	modbusListener net.Listener
+)
+
+// modbusMap numbers the output lines as coils and the input lines as discrete inputs, both from 0 in
+// configuration order.
+func (s *SimpleDriver) modbusMap() (coils []ModbusMapping, inputs []ModbusMapping) {
+	coils, inputs = []ModbusMapping{}, []ModbusMapping{}
+	for _, g := range s.GpioList.Gpio {
+		if isOutputRole(g.Role) {
+			coils = append(coils, ModbusMapping{Address: len(coils), Name: g.Name, Writable: writable(g.Role)})
+		} else {
+			inputs = append(inputs, ModbusMapping{Address: len(inputs), Name: g.Name})
+		}
+	}
+	return coils, inputs
+}
+
+// startModbusServer serves the configured lines as coils and discrete inputs over Modbus TCP on
+// MODBUS_SERVER_ADDRESS. Writes follow the same rules as the other control APIs: lines owned by the
+// service are reported as illegal addresses.
+func (s *SimpleDriver) startModbusServer() error {
+	address := os.Getenv("MODBUS_SERVER_ADDRESS")
+	if address == "" {
+		return nil
+	}
+	var err error
+	modbusListener, err = net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+	logf(moduleBridges, levelInfo, "Modbus TCP facade listening on %s", address)
+	go func() {
+		for {
+			conn, err := modbusListener.Accept()
+			if err != nil {
+				if !errors.Is(err, net.ErrClosed) {
+					logf(moduleBridges, levelError, "Modbus TCP facade stopped. Error: %s", err)
+				}
+				return
+			}
+			go s.serveModbus(conn)
+		}
+	}()
+	return nil
+}
+
+func stopModbusServer() {
+	if modbusListener != nil {
+		modbusListener.Close()
+	}
+}
+
+// serveModbus answers the requests of a Modbus TCP client: a 7 bytes MBAP header (transaction,
+// protocol, length, unit) followed by the PDU.
+func (s *SimpleDriver) serveModbus(conn net.Conn) {
+	defer conn.Close()
+	header := make([]byte, 7)
+	for {
+		if _, err := io.ReadFull(conn, header); err != nil {
+			return
+		}
+		length := int(binary.BigEndian.Uint16(header[4:6]))
+		if length < 2 || length > maxModbusFrame {
+			return
+		}
+		pdu := make([]byte, length-1)
+		if _, err := io.ReadFull(conn, pdu); err != nil {
+			return
+		}
+		response := s.handleModbusPDU(pdu)
+		frame := make([]byte, 7, 7+len(response))
+		copy(frame, header[:4])
+		binary.BigEndian.PutUint16(frame[4:6], uint16(len(response)+1))
+		frame[6] = header[6]
+		if _, err := conn.Write(append(frame, response...)); err != nil {
+			return
+		}
+	}
+}
+
+func modbusException(function byte, code byte) []byte {
+	return []byte{function | 0x80, code}
+}
+
+func (s *SimpleDriver) handleModbusPDU(pdu []byte) []byte {
+	function := pdu[0]
+	if len(pdu) < 5 {
+		return modbusException(function, modbusIllegalValue)
+	}
+	address := int(binary.BigEndian.Uint16(pdu[1:3]))
+	coils, inputs := s.modbusMap()
+
+	switch function {
+	case modbusReadCoils, modbusReadDiscreteInputs:
+		quantity := int(binary.BigEndian.Uint16(pdu[3:5]))
+		table := coils
+		if function == modbusReadDiscreteInputs {
+			table = inputs
+		}
+		if quantity < 1 || quantity > 2000 {
+			return modbusException(function, modbusIllegalValue)
+		}
+		if address+quantity > len(table) {
+			return modbusException(function, modbusIllegalAddress)
+		}
+		bits := make([]byte, (quantity+7)/8)
+		for i := 0; i < quantity; i++ {
+			value, err := s.readLevel(table[address+i].Name)
+			if err != nil {
+				return modbusException(function, modbusDeviceFailure)
+			}
+			if value == 1 {
+				bits[i/8] |= 1 << (i % 8)
+			}
+		}
+		return append([]byte{function, byte(len(bits))}, bits...)
+
+	case modbusWriteSingleCoil:
+		if address >= len(coils) {
+			return modbusException(function, modbusIllegalAddress)
+		}
+		value := binary.BigEndian.Uint16(pdu[3:5])
+		if value != 0xFF00 && value != 0x0000 {
+			return modbusException(function, modbusIllegalValue)
+		}
+		if code := s.modbusWrite(coils[address].Name, value == 0xFF00); code != 0 {
+			return modbusException(function, code)
+		}
+		return pdu[:5]
+
+	case modbusWriteMultipleCoils:
+		quantity := int(binary.BigEndian.Uint16(pdu[3:5]))
+		if quantity < 1 || len(pdu) < 6 || len(pdu) < 6+int(pdu[5]) || int(pdu[5]) < (quantity+7)/8 {
+			return modbusException(function, modbusIllegalValue)
+		}
+		if address+quantity > len(coils) {
+			return modbusException(function, modbusIllegalAddress)
+		}
+		for i := 0; i < quantity; i++ {
+			on := pdu[6+i/8]&(1<<(i%8)) != 0
+			if code := s.modbusWrite(coils[address+i].Name, on); code != 0 {
+				return modbusException(function, code)
+			}
+		}
+		return pdu[:5]
+	}
+	return modbusException(function, modbusIllegalFunction)
+}
+
+func (s *SimpleDriver) modbusWrite(name string, on bool) byte {
+	err := s.writeLine(name, on, "modbus")
+	switch {
+	case err == nil:
+		return 0
+	case errors.Is(err, errUnknownLine), errors.Is(err, errNotWritable):
+		return modbusIllegalAddress
+	}
+	logf(moduleBridges, levelError, "Modbus write on gpio %s failed. Error: %s", name, err)
+	return modbusDeviceFailure
+}
+
+// handleModbusMap returns the Modbus addresses of the configured lines.
+func (s *SimpleDriver) handleModbusMap(w http.ResponseWriter, r *http.Request) {
+	coils, inputs := s.modbusMap()
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"coils":          coils,
+		"discreteInputs": inputs,
+	})
+}
@@ -0,0 +1,168 @@
+package driver
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/common"
+)
+
+const (
+	nextCycleRoute       = common.ApiBase + "/cycle/next"
+	nextCycleCancelRoute = common.ApiBase + "/cycle/next/cancel"
+)
+
+// NextCycle overrides the parameters of the next pump cycle only; the configured ones resume after it.
+type NextCycle struct {
+	PumpDuration string    `json:"pumpDuration,omitempty"`
+	SkipClean    bool      `json:"skipClean"`
+	ForceClean   bool      `json:"forceClean"`
+	Reason       string    `json:"reason,omitempty"`
+	Requested    time.Time `json:"requested"`
+}
+
+var (
+	nextCycleMutex = sync.Mutex{}
+	nextCycle      *NextCycle
+	// inCycle is set while a cycle runs, cycleCleans when it was decided to run the clean phase
+	inCycle     bool
+	cycleCleans bool
+	cycleClean  bool
+)
+
+// nextPumpDuration returns the pump run time in seconds of the next cycle, the one overridden for
+// it when pending.
+func nextPumpDuration() int64 {
+	nextCycleMutex.Lock()
+	defer nextCycleMutex.Unlock()
+	if nextCycle != nil && nextCycle.PumpDuration != "" {
+		if d, err := time.ParseDuration(nextCycle.PumpDuration); err == nil {
+			return int64(d.Seconds())
+		}
+	}
+	return scheduledPumpDuration()
+}
+
+// cleanPlanned tells whether the next cycle is set to clean, before the deferrals of beginNextCycle.
+func cleanPlanned() bool {
+	nextCycleMutex.Lock()
+	defer nextCycleMutex.Unlock()
+	if nextCycle != nil {
+		return *enableClean && (nextCycle.ForceClean || cleanDue() && !nextCycle.SkipClean)
+	}
+	return cleanDue()
+}
+
+// beginNextCycle consumes the pending override as a cycle starts and decides whether the cycle
+// pumping for runFor seconds reverses, when due by REVERSE_AFTER, and cleans: when due by
+// CLEAN_EVERY, unless skipped or deferred on battery power, or when forced. Without cleanAllowed,
+// the clean lock being held by another gateway, the clean is deferred and a forced clean stays
+// pending with its override.
+func beginNextCycle(runFor int64, cleanAllowed bool) {
+	nextCycleMutex.Lock()
+	defer nextCycleMutex.Unlock()
+	inCycle, cycleClean, cycleReversed = true, false, false
+	cycleReverses = reverseDue(runFor)
+	cycleCleans = cleanDue()
+	forced := false
+	if nextCycle != nil && (cleanAllowed || !nextCycle.ForceClean) {
+		cycleCleans = *enableClean && (nextCycle.ForceClean || cycleCleans && !nextCycle.SkipClean)
+		forced = nextCycle.ForceClean
+		audit("next-cycle-applied", "cycle", nextCycle.Reason)
+		nextCycle = nil
+	}
+	cycleCleans = powerCleans(cycleCleans, forced)
+	if cycleCleans && !cleanAllowed {
+		cycleCleans = false
+		audit("clean-deferred", "cycle", fmt.Sprintf("clean lock held by %s", cleanLockHolder()))
+	}
+}
+
+// endNextCycle restores the configured parameters once the cycle that pumped for runFor seconds is
+// over, and counts the cycles since the last clean and the runtime since the last reverse.
+func endNextCycle(runFor int64) {
+	nextCycleMutex.Lock()
+	defer nextCycleMutex.Unlock()
+	inCycle = false
+	countCleanCycle(cycleClean)
+	countReverseRuntime(runFor, cycleReversed)
+}
+
+// noteCleanDone records that the clean phase of the running cycle completed.
+func noteCleanDone() {
+	nextCycleMutex.Lock()
+	defer nextCycleMutex.Unlock()
+	cycleClean = true
+}
+
+// cleanEnabled tells whether the clean phase runs in the current cycle, or in the next one outside
+// of a cycle.
+func cleanEnabled() bool {
+	nextCycleMutex.Lock()
+	defer nextCycleMutex.Unlock()
+	if inCycle {
+		return cycleCleans
+	}
+	return cleanDue()
+}
+
+// handleNextCycle sets the override of the next cycle, replacing a pending one. The pump duration is
+// bounded like the PumpTimer resource.
+func (s *SimpleDriver) handleNextCycle(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodGet {
+		nextCycleMutex.Lock()
+		defer nextCycleMutex.Unlock()
+		writeJSON(w, http.StatusOK, map[string]interface{}{"next": nextCycle, "cleanEvery": cleanEvery, "cyclesSinceClean": sinceClean,
+			"reverseAfter": reverseAfter.String(), "runtimeSinceReverse": runtimeSince.Round(time.Second).String()})
+		return
+	}
+	var req NextCycle
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if req.PumpDuration == "" && !req.SkipClean && !req.ForceClean {
+		writeError(w, http.StatusBadRequest, errors.New("nothing to override"))
+		return
+	}
+	if req.SkipClean && req.ForceClean {
+		writeError(w, http.StatusBadRequest, errors.New("skipClean and forceClean are exclusive"))
+		return
+	}
+	if req.ForceClean && !*enableClean {
+		writeError(w, http.StatusConflict, errors.New("clean circuit not available"))
+		return
+	}
+	if req.PumpDuration != "" {
+		d, err := time.ParseDuration(req.PumpDuration)
+		min, max := pumpTimerResource.bounds()
+		if err != nil || d < min || d > max {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("pumpDuration must be between %s and %s", min, max))
+			return
+		}
+	}
+	req.Requested = time.Now()
+	nextCycleMutex.Lock()
+	nextCycle = &req
+	nextCycleMutex.Unlock()
+	audit("next-cycle", "cycle", fmt.Sprintf("pump %q, skip clean %t, force clean %t: %s", req.PumpDuration, req.SkipClean, req.ForceClean, req.Reason))
+	writeJSON(w, http.StatusOK, map[string]interface{}{"next": req})
+}
+
+// handleNextCycleCancel drops the pending override of the next cycle.
+func (s *SimpleDriver) handleNextCycleCancel(w http.ResponseWriter, r *http.Request) {
+	nextCycleMutex.Lock()
+	pending := nextCycle != nil
+	nextCycle = nil
+	nextCycleMutex.Unlock()
+	if !pending {
+		writeError(w, http.StatusNotFound, errors.New("no pending override of the next cycle"))
+		return
+	}
+	audit("next-cycle-cancel", "cycle", "configured parameters restored")
+	writeJSON(w, http.StatusOK, map[string]interface{}{"next": nil})
+}
@@ -0,0 +1,20 @@
+//go:build nodashboard
+
+package driver
+
+import (
+	"net/http"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/common"
+)
+
+const (
+	dashboardCompiled = false
+	dashboardRoute    = common.ApiBase + "/dashboard"
+)
+
+func dashboardEnabled() bool {
+	return false
+}
+
+func (s *SimpleDriver) handleDashboard(w http.ResponseWriter, r *http.Request) {}
@@ -0,0 +1,11 @@
+//go:build nogrpc
+
+package driver
+
+const grpcCompiled = false
+
+func (s *SimpleDriver) startGrpc() error {
+	return nil
+}
+
+func stopGrpc() {}
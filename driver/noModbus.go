@@ -0,0 +1,25 @@
+//go:build nomodbus
+
+package driver
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/common"
+)
+
+const (
+	modbusCompiled = false
+	modbusMapRoute = common.ApiBase + "/modbus/map"
+)
+
+func (s *SimpleDriver) startModbusServer() error {
+	return nil
+}
+
+func stopModbusServer() {}
+
+func (s *SimpleDriver) handleModbusMap(w http.ResponseWriter, r *http.Request) {
+	writeError(w, http.StatusNotImplemented, errors.New("Modbus facade not compiled in"))
+}
@@ -0,0 +1,15 @@
+//go:build nomqtt
+
+package driver
+
+const mqttCompiled = false
+
+func startSystemEvents() {}
+
+func (s *SimpleDriver) startCloudTwin() error {
+	return nil
+}
+
+func startCoordination() error {
+	return nil
+}
@@ -0,0 +1,11 @@
+//go:build noopcua
+
+package driver
+
+const opcuaCompiled = false
+
+func (s *SimpleDriver) startOpcuaServer() error {
+	return nil
+}
+
+func stopOpcuaServer() {}
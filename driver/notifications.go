@@ -0,0 +1,57 @@
+package driver
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/common"
+)
+
+const (
+	notificationSeverityNormal   = "NORMAL"
+	notificationSeverityCritical = "CRITICAL"
+)
+
+// sendNotification posts a notification to EdgeX support-notifications at NOTIFICATIONS_URL
+// (e.g. http://edgex-support-notifications:59860/api/v2/notification). It is a no-op when unset.
+func sendNotification(category string, severity string, content string) {
+	url := os.Getenv("NOTIFICATIONS_URL")
+	if url == "" {
+		return
+	}
+	request := []map[string]interface{}{
+		{
+			"apiVersion": common.ApiVersion,
+			"notification": map[string]interface{}{
+				"category":    category,
+				"content":     content,
+				"contentType": common.ContentTypeText,
+				"sender":      deviceName(),
+				"severity":    severity,
+				"labels":      []string{"gpiod", category},
+			},
+		},
+	}
+	body, err := json.Marshal(request)
+	if err != nil {
+		log.Printf("Cannot marshal notification. Error: %s", err)
+		return
+	}
+	go func() {
+		client := http.Client{Timeout: time.Duration(10) * time.Second}
+		response, err := client.Post(url, common.ContentTypeJSON, bytes.NewReader(body))
+		if err != nil {
+			log.Printf("Cannot send notification. Error: %s", err)
+			return
+		}
+		defer response.Body.Close()
+		if response.StatusCode >= 300 {
+			log.Printf("Cannot send notification. Error: %s", fmt.Sprintf("status code %d", response.StatusCode))
+		}
+	}()
+}
@@ -0,0 +1,199 @@
+//go:build !noopcua
+
+package driver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gopcua/opcua/id"
+	"github.com/gopcua/opcua/server"
+	"github.com/gopcua/opcua/ua"
+)
+
+const (
+	opcuaNamespace = "device-gpiod"
+
+	opcuaPhaseNode  = "phase"
+	opcuaAlarmsNode = "alarms"
+	opcuaModeNode   = "mode"
+	opcuaLinePrefix = "line."
+
+	opcuaModeAuto   = "auto"
+	opcuaModeManual = "manual"
+
+	opcuaRefresh = time.Second
+)
+
+// opcuaCompiled tells whether the OPC UA facade is part of the build. It is left out with the noopcua
+// build tag.
+const opcuaCompiled = true
+
+var (
+	opcuaServer    *server.Server
+	opcuaNodes     *server.MapNamespace
+	opcuaMutex     = sync.Mutex{}
+	opcuaPublished = make(map[string]interface{})
+	// opcuaMode is the mode of OPCUA_MODE, set on the service side only
+	opcuaMode = opcuaModeAuto
+)
+
+// startOpcuaServer serves the line states, the cycle phase and the latched alarms as nodes of an
+// embedded OPC UA server on OPCUA_SERVER_ADDRESS. The clients are anonymous: they may write the output
+// lines only when OPCUA_MODE is manual, which the read-only mode node publishes; in auto mode, the
+// default, their writes are reverted as the pipeline owns the plant. Writes follow the same rules as
+// the Modbus facade, the OPC UA client being local control.
+func (s *SimpleDriver) startOpcuaServer() error {
+	address := os.Getenv("OPCUA_SERVER_ADDRESS")
+	if address == "" {
+		return nil
+	}
+	switch mode := os.Getenv("OPCUA_MODE"); mode {
+	case "", opcuaModeAuto:
+		opcuaMode = opcuaModeAuto
+	case opcuaModeManual:
+		opcuaMode = opcuaModeManual
+	default:
+		return fmt.Errorf("unknown OPCUA_MODE %q, expected %s or %s", mode, opcuaModeAuto, opcuaModeManual)
+	}
+	host, portValue, err := net.SplitHostPort(address)
+	if err != nil {
+		return fmt.Errorf("cannot parse OPCUA_SERVER_ADDRESS %q: %s", address, err)
+	}
+	port, err := strconv.Atoi(portValue)
+	if err != nil {
+		return fmt.Errorf("cannot parse OPCUA_SERVER_ADDRESS %q: %s", address, err)
+	}
+	if host == "" {
+		host = "0.0.0.0"
+	}
+
+	opcuaServer = server.New(
+		server.EndPoint(host, port),
+		server.EnableSecurity("None", ua.MessageSecurityModeNone),
+		server.EnableAuthMode(ua.UserTokenTypeAnonymous),
+	)
+	opcuaNodes = server.NewMapNamespace(opcuaServer, opcuaNamespace)
+	root, err := opcuaServer.Namespace(0)
+	if err != nil {
+		return err
+	}
+	root.Objects().AddRef(opcuaNodes.Objects(), id.HasComponent, true)
+
+	opcuaMutex.Lock()
+	opcuaPublished[opcuaModeNode] = opcuaMode
+	opcuaMutex.Unlock()
+	opcuaNodes.SetValue(opcuaModeNode, opcuaMode)
+	s.refreshOpcuaNodes()
+
+	if err := opcuaServer.Start(context.Background()); err != nil {
+		return err
+	}
+	goBackground(func() {
+		for {
+			select {
+			case key := <-opcuaNodes.ExternalNotification:
+				s.handleOpcuaWrite(key)
+			case <-stopping:
+				return
+			}
+		}
+	})
+	goBackground(func() {
+		ticker := time.NewTicker(opcuaRefresh)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				s.refreshOpcuaNodes()
+			case <-stopping:
+				return
+			}
+		}
+	})
+	logf(moduleBridges, levelInfo, "OPC UA facade listening on opc.tcp://%s:%d", host, port)
+	return nil
+}
+
+func stopOpcuaServer() {
+	if opcuaServer != nil {
+		opcuaServer.Close()
+	}
+}
+
+// refreshOpcuaNodes publishes the values that changed since the last refresh.
+func (s *SimpleDriver) refreshOpcuaNodes() {
+	values := map[string]interface{}{
+		opcuaPhaseNode: currentPhase().Name,
+	}
+	alarms := latchedAlarms()
+	sort.Strings(alarms)
+	values[opcuaAlarmsNode] = strings.Join(alarms, ",")
+	for _, g := range s.GpioList.Gpio {
+		value, err := s.readLevel(g.Name)
+		if err != nil {
+			continue
+		}
+		values[opcuaLinePrefix+g.Name] = value == 1
+	}
+
+	opcuaMutex.Lock()
+	changed := make(map[string]interface{})
+	for key, value := range values {
+		if opcuaPublished[key] != value {
+			opcuaPublished[key] = value
+			changed[key] = value
+		}
+	}
+	opcuaMutex.Unlock()
+	for key, value := range changed {
+		opcuaNodes.SetValue(key, value)
+	}
+}
+
+// handleOpcuaWrite applies the value a client wrote to a node, or puts back the published one when the
+// write is refused.
+func (s *SimpleDriver) handleOpcuaWrite(key string) {
+	written := opcuaNodes.GetValue(key)
+	opcuaMutex.Lock()
+	previous := opcuaPublished[key]
+	opcuaMutex.Unlock()
+	if written == previous {
+		return
+	}
+
+	err := s.applyOpcuaWrite(key, written, opcuaMode)
+	if err != nil {
+		logf(moduleBridges, levelWarn, "OPC UA write of %s refused. Error: %s", key, err)
+		opcuaNodes.SetValue(key, previous)
+		return
+	}
+	opcuaMutex.Lock()
+	opcuaPublished[key] = written
+	opcuaMutex.Unlock()
+}
+
+// applyOpcuaWrite applies a client write to a line in the manual mode of the service. Every other
+// node, the mode included, is read-only: an anonymous client cannot grant itself the lines.
+func (s *SimpleDriver) applyOpcuaWrite(key string, written interface{}, mode string) error {
+	switch {
+	case strings.HasPrefix(key, opcuaLinePrefix):
+		if mode != opcuaModeManual {
+			return errors.New("line writes need OPCUA_MODE manual")
+		}
+		on, ok := written.(bool)
+		if !ok {
+			return fmt.Errorf("line value must be a boolean, got %T", written)
+		}
+		return s.writeLine(strings.TrimPrefix(key, opcuaLinePrefix), on, "opcua")
+	}
+	return fmt.Errorf("%s is read-only", key)
+}
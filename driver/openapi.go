@@ -0,0 +1,145 @@
+package driver
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/edgexfoundry/device-sdk-go/v2/pkg/service"
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/common"
+)
+
+const openAPIRoute = common.ApiBase + "/openapi.json"
+
+// routeDoc documents a custom route: what it does, the JSON body it accepts and its query parameters.
+type routeDoc struct {
+	Summary string
+	Request interface{}
+	Query   []string
+}
+
+type documentedRoute struct {
+	path    string
+	methods []string
+	doc     routeDoc
+}
+
+var (
+	documentedMutex  = sync.Mutex{}
+	documentedRoutes []documentedRoute
+)
+
+// addRoute registers a custom route on the SDK router and records it for the OpenAPI document, so
+// the document can't drift from the routes actually served.
+func addRoute(ds *service.DeviceService, path string, doc routeDoc, handler func(http.ResponseWriter, *http.Request), methods ...string) error {
+	if err := ds.AddRoute(path, handler, methods...); err != nil {
+		return err
+	}
+	documentedMutex.Lock()
+	defer documentedMutex.Unlock()
+	documentedRoutes = append(documentedRoutes, documentedRoute{path: path, methods: methods, doc: doc})
+	return nil
+}
+
+// openAPIDocument builds the OpenAPI 3 description of the custom routes.
+func openAPIDocument() map[string]interface{} {
+	documentedMutex.Lock()
+	defer documentedMutex.Unlock()
+	paths := make(map[string]interface{})
+	for _, route := range documentedRoutes {
+		operations := make(map[string]interface{})
+		for _, method := range route.methods {
+			operation := map[string]interface{}{
+				"summary": route.doc.Summary,
+				"responses": map[string]interface{}{
+					"200":     map[string]interface{}{"description": "OK"},
+					"default": map[string]interface{}{"description": "Error", "content": jsonContent(errorSchema())},
+				},
+			}
+			var parameters []interface{}
+			if method == http.MethodGet {
+				for _, name := range route.doc.Query {
+					parameters = append(parameters, map[string]interface{}{
+						"name": name, "in": "query", "schema": map[string]interface{}{"type": "string"},
+					})
+				}
+			}
+			if method == http.MethodPost {
+				parameters = append(parameters, map[string]interface{}{
+					"name": idempotencyKeyHeader, "in": "header", "schema": map[string]interface{}{"type": "string"},
+				})
+				if route.doc.Request != nil {
+					operation["requestBody"] = map[string]interface{}{"content": jsonContent(schemaOf(reflect.TypeOf(route.doc.Request)))}
+				}
+			}
+			if len(parameters) > 0 {
+				operation["parameters"] = parameters
+			}
+			operations[strings.ToLower(method)] = operation
+		}
+		paths[route.path] = operations
+	}
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":   "device-gpiod custom routes",
+			"version": common.ApiVersion,
+		},
+		"paths": paths,
+	}
+}
+
+func jsonContent(schema map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{common.ContentTypeJSON: map[string]interface{}{"schema": schema}}
+}
+
+func errorSchema() map[string]interface{} {
+	return schemaOf(reflect.TypeOf(struct {
+		StatusCode int    `json:"statusCode"`
+		Code       string `json:"code"`
+		Message    string `json:"message"`
+		Text       string `json:"text,omitempty"`
+	}{}))
+}
+
+// schemaOf derives the JSON schema of a request type from its json tags.
+func schemaOf(t reflect.Type) map[string]interface{} {
+	switch t.Kind() {
+	case reflect.Ptr:
+		return schemaOf(t.Elem())
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": schemaOf(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": schemaOf(t.Elem())}
+	case reflect.Struct:
+		properties := make(map[string]interface{})
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			name := strings.Split(field.Tag.Get("json"), ",")[0]
+			if field.PkgPath != "" || name == "-" {
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+			properties[name] = schemaOf(field.Type)
+		}
+		return map[string]interface{}{"type": "object", "properties": properties}
+	}
+	return map[string]interface{}{}
+}
+
+// handleOpenAPI serves the OpenAPI document of the custom routes, for integrators to generate clients.
+func (s *SimpleDriver) handleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, openAPIDocument())
+}
@@ -0,0 +1,149 @@
+package driver
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/common"
+)
+
+const (
+	operationsRoute    = common.ApiBase + "/operations"
+	operationRunning   = "running"
+	operationSucceeded = "succeeded"
+	operationFailed    = "failed"
+)
+
+// Operation is a long-running actuation (a cycle, a pulse) that callers can follow by ID instead of
+// inferring its state from telemetry.
+type Operation struct {
+	ID         string        `json:"id"`
+	Kind       string        `json:"kind"`
+	Target     string        `json:"target"`
+	State      string        `json:"state"`
+	StartedAt  time.Time     `json:"startedAt"`
+	FinishedAt time.Time     `json:"finishedAt,omitempty"`
+	Expected   time.Duration `json:"-"`
+	Result     string        `json:"result,omitempty"`
+}
+
+type operationStatus struct {
+	Operation
+	Expected  string  `json:"expected"`
+	Progress  float64 `json:"progress"`
+	Remaining string  `json:"remaining"`
+}
+
+var (
+	operationsMutex = sync.Mutex{}
+	operations      = make(map[string]*Operation)
+	operationSeq    = 0
+
+	maxFinishedOperation = defaultFinishedOperations
+)
+
+// startOperation registers a new running operation expected to last about expected.
+func startOperation(kind string, target string, expected time.Duration) *Operation {
+	operationsMutex.Lock()
+	defer operationsMutex.Unlock()
+	operationSeq++
+	op := &Operation{
+		ID:        fmt.Sprintf("%s-%d-%d", kind, time.Now().Unix(), operationSeq),
+		Kind:      kind,
+		Target:    target,
+		State:     operationRunning,
+		StartedAt: time.Now(),
+		Expected:  expected,
+	}
+	operations[op.ID] = op
+	pruneOperations()
+	return op
+}
+
+// cycleDuration is the expected duration of a whole cycle with a pump phase of runFor seconds.
+func cycleDuration(runFor int64) time.Duration {
+	expected := time.Duration(runFor) * time.Second
+	if reverseEnabled() {
+		expected += *reverseTimer
+		if cleanEnabled() {
+			expected += *cleanTimer
+		}
+	}
+	return expected
+}
+
+// finish records the final result of the operation.
+func (op *Operation) finish(err error) {
+	operationsMutex.Lock()
+	defer operationsMutex.Unlock()
+	op.FinishedAt = time.Now()
+	op.State = operationSucceeded
+	op.Result = "completed"
+	if err != nil {
+		op.State = operationFailed
+		op.Result = err.Error()
+	}
+}
+
+// pruneOperations keeps the most recent finished operations. Must be called holding operationsMutex.
+func pruneOperations() {
+	var finished []*Operation
+	for _, op := range operations {
+		if op.State != operationRunning {
+			finished = append(finished, op)
+		}
+	}
+	if len(finished) <= maxFinishedOperation {
+		return
+	}
+	sort.Slice(finished, func(i, j int) bool { return finished[i].FinishedAt.Before(finished[j].FinishedAt) })
+	countEvictions("operations", len(finished)-maxFinishedOperation)
+	for _, op := range finished[:len(finished)-maxFinishedOperation] {
+		delete(operations, op.ID)
+	}
+}
+
+// status must be called holding operationsMutex.
+func (op *Operation) status() operationStatus {
+	status := operationStatus{Operation: *op, Expected: op.Expected.String(), Progress: 1}
+	if op.State != operationRunning {
+		status.Remaining = "0s"
+		return status
+	}
+	elapsed := time.Since(op.StartedAt)
+	remaining := op.Expected - elapsed
+	if remaining < 0 {
+		remaining = 0
+	}
+	status.Remaining = remaining.Round(time.Second).String()
+	status.Progress = 0.99
+	if op.Expected > 0 && elapsed < op.Expected {
+		status.Progress = float64(elapsed) / float64(op.Expected)
+	}
+	return status
+}
+
+// handleOperations returns the operation selected by the id query parameter, or all known operations.
+func (s *SimpleDriver) handleOperations(w http.ResponseWriter, r *http.Request) {
+	operationsMutex.Lock()
+	defer operationsMutex.Unlock()
+	if id := r.URL.Query().Get("id"); id != "" {
+		op, ok := operations[id]
+		if !ok {
+			writeError(w, http.StatusNotFound, errors.New("unknown operation "+id))
+			return
+		}
+		writeJSON(w, http.StatusOK, op.status())
+		return
+	}
+	list := make([]operationStatus, 0, len(operations))
+	for _, op := range operations {
+		list = append(list, op.status())
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].StartedAt.Before(list[j].StartedAt) })
+	writeJSON(w, http.StatusOK, list)
+}
@@ -0,0 +1,145 @@
+package driver
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/edgexfoundry/device-gpiod/gpio"
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/common"
+
+	sdkModels "github.com/edgexfoundry/device-sdk-go/v2/pkg/models"
+)
+
+const (
+	overrideResource    = "Override"
+	overrideRoute       = common.ApiBase + "/override"
+	overrideCancelRoute = common.ApiBase + "/override/cancel"
+)
+
+var (
+	maxOverride = time.Duration(1) * time.Hour
+
+	errOverrideDuration = errors.New("invalid override duration")
+)
+
+type overrideRequest struct {
+	Name     string `json:"name"`
+	Value    int    `json:"value"`
+	Duration string `json:"duration"`
+	Reason   string `json:"reason"`
+}
+
+// handleOverride forces a line, pipeline lines included, to a value for a bounded duration (at most
+// OVERRIDE_MAX, default 1h), e.g. "force pump on for 2 minutes". The owner of the line keeps running
+// and gets its last requested state back when the override expires.
+func (s *SimpleDriver) handleOverride(w http.ResponseWriter, r *http.Request) {
+	var req overrideRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	g, ok := s.findGpio(req.Name)
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("unknown gpio %s", req.Name))
+		return
+	}
+	if req.Value != 0 && req.Value != 1 {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("value must be 0 or 1"))
+		return
+	}
+	duration, err := time.ParseDuration(req.Duration)
+	if err != nil {
+		duration = 0
+	}
+	until, err := s.overrideLine(g, req.Value, duration, req.Reason, "override")
+	switch {
+	case errors.Is(err, errOverrideDuration):
+		writeError(w, http.StatusBadRequest, err)
+	case errors.Is(err, errNotExposed):
+		writeError(w, http.StatusForbidden, err)
+	case err != nil:
+		writeError(w, http.StatusConflict, err)
+	default:
+		writeJSON(w, http.StatusOK, map[string]interface{}{"name": g.Name, "value": req.Value, "until": until})
+	}
+}
+
+// overrideLine forces a line to value for duration on behalf of source, for the override route and
+// the override attribute of core command writes. It returns the end of the override.
+func (s *SimpleDriver) overrideLine(g *gpio.GPIO, value int, duration time.Duration, reason string, source string) (time.Time, error) {
+	if !isOutputRole(g.Role) || g.Role == RoleHeartbeat || g.Role == RoleWatchdog {
+		return time.Time{}, fmt.Errorf("%w: %s cannot be overridden", errNotWritable, g.Name)
+	}
+	if err := checkExposure(g, source); err != nil {
+		return time.Time{}, err
+	}
+	if err := checkLeader(); err != nil {
+		return time.Time{}, err
+	}
+	if duration <= 0 || duration > maxOverride {
+		return time.Time{}, fmt.Errorf("%w: must be between 0 and %s", errOverrideDuration, maxOverride)
+	}
+	name := g.Name
+	err := g.Override(value, duration, func() {
+		audit("override-end", name, "reverted to owner state")
+		s.pushOverride(name, nil)
+	})
+	if err != nil {
+		return time.Time{}, err
+	}
+	until := time.Now().Add(duration)
+	audit("override-start", name, fmt.Sprintf("forced to %d for %s: %s", value, duration, reason))
+	recordTransition(name, value == 1)
+	s.pushOverride(name, map[string]interface{}{"value": value, "until": until, "reason": reason})
+	return until, nil
+}
+
+// handleOverrideCancel ends an override before its expiry.
+func (s *SimpleDriver) handleOverrideCancel(w http.ResponseWriter, r *http.Request) {
+	var req overrideRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	g, ok := s.findGpio(req.Name)
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("unknown gpio %s", req.Name))
+		return
+	}
+	if err := g.Revert(); err != nil {
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+	audit("override-cancel", g.Name, "reverted to owner state")
+	s.pushOverride(g.Name, nil)
+	writeJSON(w, http.StatusOK, map[string]interface{}{"name": g.Name})
+}
+
+// pushOverride publishes the override of a line, nil once reverted.
+func (s *SimpleDriver) pushOverride(name string, override map[string]interface{}) {
+	payload, err := shapePayload(overrideResource, map[string]interface{}{"name": name, "override": override})
+	if err != nil {
+		log.Printf("Cannot marshal override. Error: %s", err)
+		return
+	}
+	cv, err := sdkModels.NewCommandValue(overrideResource, common.ValueTypeString, string(payload))
+	if err != nil {
+		log.Printf("Cannot create override reading. Error: %s", err)
+		return
+	}
+	s.asyncCh <- &sdkModels.AsyncValues{
+		DeviceName:    deviceName(),
+		CommandValues: []*sdkModels.CommandValue{cv},
+	}
+}
+
+func parseOverrideMax() {
+	if d, err := time.ParseDuration(os.Getenv("OVERRIDE_MAX")); err == nil && d > 0 {
+		maxOverride = d
+	}
+}
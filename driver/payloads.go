@@ -0,0 +1,229 @@
+package driver
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/edgexfoundry/device-gpiod/gpio"
+)
+
+const (
+	schemaLegacy = "legacy"
+	schemaV2     = "v2"
+	namingCamel  = "camel"
+	namingSnake  = "snake"
+
+	encodingGzip = "gzip"
+	encodingZstd = "zstd"
+
+	DEFAULT_COMPRESS_ABOVE = 4096
+)
+
+// PayloadShape selects the format of the JSON readings, so the event format can evolve without
+// breaking deployed consumers. Schema is the default for every resource (legacy, the historical
+// ad-hoc payloads), Resources overrides it per resource, and Naming is the field naming of the v2
+// payloads (camel or snake). Compression (gzip) compresses the payloads larger than CompressAbove
+// bytes, 4096 by default, to save bandwidth over cellular links: the reading is then an envelope
+// holding the content encoding and the base64 compressed payload.
+type PayloadShape struct {
+	Schema        string            `yaml:"schema"`
+	Naming        string            `yaml:"naming"`
+	Resources     map[string]string `yaml:"resources"`
+	Compression   string            `yaml:"compression"`
+	CompressAbove int               `yaml:"compress_above"`
+}
+
+// validatePayloads checks the payloads section of the configuration file.
+func validatePayloads() error {
+	shape := driverConfig.Payloads
+	if shape == nil {
+		return nil
+	}
+	validSchema := func(schema string) bool {
+		return schema == "" || schema == schemaLegacy || schema == schemaV2
+	}
+	if !validSchema(shape.Schema) {
+		return fmt.Errorf("unknown schema %q", shape.Schema)
+	}
+	for resource, schema := range shape.Resources {
+		if !validSchema(schema) {
+			return fmt.Errorf("unknown schema %q for resource %s", schema, resource)
+		}
+	}
+	if shape.Naming != "" && shape.Naming != namingCamel && shape.Naming != namingSnake {
+		return fmt.Errorf("unknown naming %q", shape.Naming)
+	}
+	switch shape.Compression {
+	case "", encodingGzip:
+	case encodingZstd:
+		return fmt.Errorf("compression %s is not supported by this build, use %s", encodingZstd, encodingGzip)
+	default:
+		return fmt.Errorf("unknown compression %q", shape.Compression)
+	}
+	if shape.CompressAbove < 0 {
+		return fmt.Errorf("negative compress_above %d", shape.CompressAbove)
+	}
+	return nil
+}
+
+// payloadSchema returns the schema of the readings of resource.
+func payloadSchema(resource string) string {
+	shape := driverConfig.Payloads
+	if shape == nil {
+		return schemaLegacy
+	}
+	if schema, ok := shape.Resources[resource]; ok && schema != "" {
+		return schema
+	}
+	if shape.Schema != "" {
+		return shape.Schema
+	}
+	return schemaLegacy
+}
+
+// shapePayload marshals the reading v of resource in the configured schema.
+func shapePayload(resource string, v interface{}) ([]byte, error) {
+	return shape(resource, v, v)
+}
+
+// shapeGpioPayload marshals the GPIO reading: the gpio and gpioConfig pair in the legacy schema, the
+// line and cycle settings as separate, consistently named objects in v2.
+func shapeGpioPayload(g gpio.GPIO) ([]byte, error) {
+	legacy := map[string]interface{}{
+		"gpio":       g,
+		"gpioConfig": &gpioConfig,
+	}
+	structured := map[string]interface{}{
+		"line": map[string]interface{}{
+			"name":     g.Name,
+			"chip":     g.Chip,
+			"offset":   g.Line,
+			"lineName": g.LineName,
+			"role":     g.Role,
+			"labels":   g.Labels,
+			"state":    g.State,
+		},
+		"cycle": map[string]interface{}{
+			"pumpTimer":     (time.Duration(gpioConfig.PumpTimer) * time.Second).String(),
+			"enableClean":   gpioConfig.EnableClean,
+			"cleanTimer":    gpioConfig.CleanTimer.String(),
+			"enableReverse": gpioConfig.EnableReverse,
+			"reverseTimer":  gpioConfig.ReverseTimer.String(),
+			"gravityTimer":  gpioConfig.GravityTimer.String(),
+			"commandGap":    gpioConfig.CommandGap.String(),
+		},
+	}
+	return shape(gpioResourceName, legacy, structured)
+}
+
+func shape(resource string, legacy interface{}, structured interface{}) ([]byte, error) {
+	if payloadSchema(resource) == schemaLegacy {
+		data, err := json.Marshal(legacy)
+		if err != nil {
+			return nil, err
+		}
+		return compressPayload(data)
+	}
+	data, err := json.Marshal(structured)
+	if err != nil {
+		return nil, err
+	}
+	// Go through a generic value so the field names of any payload can be renamed
+	var generic interface{}
+	if err := json.Unmarshal(data, &generic); err != nil {
+		return nil, err
+	}
+	naming := namingCamel
+	if driverConfig.Payloads != nil && driverConfig.Payloads.Naming != "" {
+		naming = driverConfig.Payloads.Naming
+	}
+	data, err = json.Marshal(renameFields(map[string]interface{}{
+		"schemaVersion": 2,
+		"resource":      resource,
+		"timestamp":     time.Now().UnixNano(),
+		"data":          generic,
+	}, naming))
+	if err != nil {
+		return nil, err
+	}
+	return compressPayload(data)
+}
+
+// compressPayload compresses the marshalled payload when compression is configured and it is large
+// enough, into a {"contentEncoding": "gzip", "payload": "<base64>"} envelope.
+func compressPayload(data []byte) ([]byte, error) {
+	shape := driverConfig.Payloads
+	if shape == nil || shape.Compression == "" {
+		return data, nil
+	}
+	above := shape.CompressAbove
+	if above == 0 {
+		above = DEFAULT_COMPRESS_ABOVE
+	}
+	if len(data) <= above {
+		return data, nil
+	}
+	var compressed bytes.Buffer
+	writer := gzip.NewWriter(&compressed)
+	if _, err := writer.Write(data); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	naming := namingCamel
+	if shape.Naming != "" {
+		naming = shape.Naming
+	}
+	return json.Marshal(map[string]interface{}{
+		fieldName("contentEncoding", naming): shape.Compression,
+		fieldName("payload", naming):         base64.StdEncoding.EncodeToString(compressed.Bytes()),
+	})
+}
+
+// renameFields applies the naming to the keys of every object in v.
+func renameFields(v interface{}, naming string) interface{} {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		renamed := make(map[string]interface{}, len(value))
+		for key, field := range value {
+			renamed[fieldName(key, naming)] = renameFields(field, naming)
+		}
+		return renamed
+	case []interface{}:
+		for i := range value {
+			value[i] = renameFields(value[i], naming)
+		}
+		return value
+	}
+	return v
+}
+
+// fieldName converts a camelCase or Go (PascalCase) field name to the naming.
+func fieldName(key string, naming string) string {
+	runes := []rune(key)
+	if len(runes) == 0 {
+		return key
+	}
+	if naming == namingCamel {
+		runes[0] = unicode.ToLower(runes[0])
+		return string(runes)
+	}
+	var b strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			if i > 0 && (unicode.IsLower(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1]))) {
+				b.WriteRune('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,58 @@
+package driver
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	phaseIdle    = "idle"
+	phasePump    = "pump"
+	phaseReverse = "reverse"
+	phaseClean   = "clean"
+	phaseGap     = "gap"
+	phaseWarmUp  = "warmup"
+)
+
+// PhaseStatus is the cycle phase currently running, with its expected end when known.
+type PhaseStatus struct {
+	Name      string    `json:"name"`
+	Since     time.Time `json:"since"`
+	Until     time.Time `json:"until,omitempty"`
+	Remaining string    `json:"remaining,omitempty"`
+}
+
+var (
+	phaseMutex = sync.Mutex{}
+	phase      = PhaseStatus{Name: phaseIdle, Since: time.Now()}
+)
+
+// setPhase records the phase the pipeline entered, expected to last for d (0 when unknown).
+func setPhase(name string, d time.Duration) {
+	phaseMutex.Lock()
+	now := time.Now()
+	observePhase(phase.Name, now.Sub(phase.Since))
+	phase = PhaseStatus{Name: name, Since: now}
+	if d > 0 {
+		phase.Until = now.Add(d)
+	}
+	status := phase
+	phaseMutex.Unlock()
+	debugf(moduleStateMachine, "Entered phase %s for %s", name, d)
+	notePipelinePhase(status)
+	publishStream("phase", currentPhase())
+}
+
+func currentPhase() PhaseStatus {
+	phaseMutex.Lock()
+	defer phaseMutex.Unlock()
+	status := phase
+	if !status.Until.IsZero() {
+		remaining := time.Until(status.Until)
+		if remaining < 0 {
+			remaining = 0
+		}
+		status.Remaining = remaining.Round(time.Second).String()
+	}
+	return status
+}
@@ -0,0 +1,182 @@
+package driver
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/common"
+)
+
+const (
+	hookPre  = "pre"
+	hookPost = "post"
+
+	hookAbort    = "abort"
+	hookContinue = "continue"
+
+	defaultHookTimeout = time.Duration(10) * time.Second
+
+	defaultCoreCommandURL = "http://edgex-core-command:59882"
+)
+
+// PhaseHook is an external command, URL or EdgeX core-command invoked before or after a cycle phase
+// (pump, reverse, clean).
+type PhaseHook struct {
+	Phase       string           `yaml:"phase"`
+	When        string           `yaml:"when"`
+	URL         string           `yaml:"url"`
+	Method      string           `yaml:"method"`
+	Body        string           `yaml:"body"`
+	Command     []string         `yaml:"command"`
+	CoreCommand *CoreCommandStep `yaml:"core_command"`
+	Timeout     string           `yaml:"timeout"`
+	Retries     int              `yaml:"retries"`
+	OnFailure   string           `yaml:"on_failure"`
+}
+
+// CoreCommandStep issues a command to another EdgeX device through core-command (CORE_COMMAND_URL),
+// e.g. setting a Modbus register before the valves open. Values are set with a PUT, a step without
+// values reads the command with a GET.
+type CoreCommandStep struct {
+	Device  string            `yaml:"device"`
+	Command string            `yaml:"command"`
+	Values  map[string]string `yaml:"values"`
+}
+
+// runPhaseHooks runs the hooks configured for phase/when in declaration order. It returns an error
+// as soon as a hook with the abort policy fails, hooks with the continue policy only log failures.
+func runPhaseHooks(phase string, when string) error {
+	for _, hook := range driverConfig.Hooks {
+		if hook.Phase != phase || hook.When != when {
+			continue
+		}
+		err := hook.run()
+		for attempt := 0; err != nil && attempt < hook.Retries; attempt++ {
+			log.Printf("Hook %s-%s failed, retrying. Error: %s", when, phase, err)
+			err = hook.run()
+		}
+		if err == nil {
+			continue
+		}
+		log.Printf("Hook %s-%s failed. Error: %s", when, phase, err)
+		if hook.OnFailure == hookAbort {
+			audit("hook-abort", phase, fmt.Sprintf("%s hook failed: %s", when, err))
+			return err
+		}
+	}
+	return nil
+}
+
+func (h PhaseHook) run() error {
+	timeout := defaultHookTimeout
+	if h.Timeout != "" {
+		d, err := time.ParseDuration(h.Timeout)
+		if err != nil {
+			return fmt.Errorf("invalid timeout %q: %s", h.Timeout, err)
+		}
+		timeout = d
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	switch {
+	case h.URL != "":
+		method := h.Method
+		if method == "" {
+			method = http.MethodPost
+		}
+		req, err := http.NewRequestWithContext(ctx, strings.ToUpper(method), h.URL, bytes.NewBufferString(h.Body))
+		if err != nil {
+			return err
+		}
+		response, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return err
+		}
+		defer response.Body.Close()
+		if response.StatusCode >= 300 {
+			return fmt.Errorf("%s %s returned %d", method, h.URL, response.StatusCode)
+		}
+		return nil
+	case h.CoreCommand != nil:
+		return h.CoreCommand.run(ctx)
+	case len(h.Command) > 0:
+		output, err := exec.CommandContext(ctx, h.Command[0], h.Command[1:]...).CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("%s: %s (%s)", strings.Join(h.Command, " "), err, strings.TrimSpace(string(output)))
+		}
+		return nil
+	default:
+		return fmt.Errorf("hook has neither url, command nor core_command")
+	}
+}
+
+func (c *CoreCommandStep) run(ctx context.Context) error {
+	base := os.Getenv("CORE_COMMAND_URL")
+	if base == "" {
+		base = defaultCoreCommandURL
+	}
+	target := fmt.Sprintf("%s/api/v2/device/name/%s/%s", base, url.PathEscape(c.Device), url.PathEscape(c.Command))
+	method := http.MethodGet
+	var body []byte
+	if len(c.Values) > 0 {
+		method = http.MethodPut
+		var err error
+		if body, err = json.Marshal(c.Values); err != nil {
+			return err
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set(common.ContentType, common.ContentTypeJSON)
+	response, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode >= 300 {
+		return fmt.Errorf("core-command %s %s/%s returned %d", method, c.Device, c.Command, response.StatusCode)
+	}
+	audit("core-command", c.Device, fmt.Sprintf("%s %s %v", method, c.Command, c.Values))
+	return nil
+}
+
+// validateHooks checks the hooks declared in the configuration file.
+func validateHooks() error {
+	for i, hook := range driverConfig.Hooks {
+		switch hook.Phase {
+		case "pump", "reverse", "clean":
+		default:
+			return fmt.Errorf("hook %d: unknown phase %q", i, hook.Phase)
+		}
+		if hook.When != hookPre && hook.When != hookPost {
+			return fmt.Errorf("hook %d: when must be %q or %q", i, hookPre, hookPost)
+		}
+		if hook.CoreCommand != nil && (hook.CoreCommand.Device == "" || hook.CoreCommand.Command == "") {
+			return fmt.Errorf("hook %d: core_command needs a device and a command", i)
+		}
+		if hook.Timeout != "" {
+			if d, err := time.ParseDuration(hook.Timeout); err != nil || d <= 0 {
+				return fmt.Errorf("hook %d: invalid timeout %q, durations need a unit (e.g. 30s)", i, hook.Timeout)
+			}
+		}
+		if hook.Retries < 0 {
+			return fmt.Errorf("hook %d: retries can't be negative", i)
+		}
+		if hook.OnFailure != "" && hook.OnFailure != hookAbort && hook.OnFailure != hookContinue {
+			return fmt.Errorf("hook %d: on_failure must be %q or %q", i, hookAbort, hookContinue)
+		}
+	}
+	return nil
+}
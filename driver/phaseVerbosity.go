@@ -0,0 +1,157 @@
+package driver
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	sdkModels "github.com/edgexfoundry/device-sdk-go/v2/pkg/models"
+)
+
+const (
+	verbosityAll      = "all"
+	verbosityStart    = "start"
+	verbosityStartEnd = "start_end"
+	verbosityPeriodic = "periodic"
+	// Phase applying to the phases without their own verbosity
+	verbosityDefault = "default"
+
+	// Period of the flush of the readings held back until the end of a phase or interval
+	verbosityTick = time.Second
+)
+
+// PhaseVerbosity chooses the readings of the lines published during a phase: every change (all, the
+// default), the first change of each line (start), the first and the last (start_end) or the first
+// and then the last of every Interval (periodic). The statistics and histories see every change.
+type PhaseVerbosity struct {
+	Publish  string `yaml:"publish"`
+	Interval string `yaml:"interval"`
+	interval time.Duration
+}
+
+// phaseLine is what was published of a line during the current phase.
+type phaseLine struct {
+	published time.Time
+	pending   *sdkModels.AsyncValues
+}
+
+var (
+	verbosityMutex = sync.Mutex{}
+	// verbosityPhase is the phase the readings of phaseLines belong to
+	verbosityPhase PhaseStatus
+	phaseLines     = make(map[string]*phaseLine)
+)
+
+// validateVerbosity checks the verbosity section of the configuration file, by phase name.
+func validateVerbosity() error {
+	for name, v := range driverConfig.Verbosity {
+		if name == "" || v == nil {
+			return fmt.Errorf("empty phase")
+		}
+		switch v.Publish {
+		case "":
+			v.Publish = verbosityAll
+		case verbosityAll, verbosityStart, verbosityStartEnd, verbosityPeriodic:
+		default:
+			return fmt.Errorf("phase %s: unknown publish %q", name, v.Publish)
+		}
+		v.interval = 0
+		if v.Publish == verbosityPeriodic {
+			interval, err := time.ParseDuration(v.Interval)
+			if err != nil || interval <= 0 {
+				return fmt.Errorf("phase %s: invalid interval %q", name, v.Interval)
+			}
+			v.interval = interval
+		} else if v.Interval != "" {
+			return fmt.Errorf("phase %s: interval is only used with publish periodic", name)
+		}
+	}
+	return nil
+}
+
+func verbosityFor(phase string) *PhaseVerbosity {
+	if v, ok := driverConfig.Verbosity[phase]; ok {
+		return v
+	}
+	return driverConfig.Verbosity[verbosityDefault]
+}
+
+// phaseReadings returns the readings to publish now for a change of the named line, by the
+// verbosity of the current phase, with the readings held back from a phase that has ended.
+func phaseReadings(name string, values *sdkModels.AsyncValues) []*sdkModels.AsyncValues {
+	current := currentPhase()
+	verbosityMutex.Lock()
+	defer verbosityMutex.Unlock()
+	out := endPhaseReadings(current)
+	v := verbosityFor(current.Name)
+	if v == nil || v.Publish == verbosityAll {
+		return append(out, values)
+	}
+	now := time.Now()
+	line, ok := phaseLines[name]
+	switch {
+	case !ok:
+		phaseLines[name] = &phaseLine{published: now}
+		out = append(out, values)
+	case v.Publish == verbosityPeriodic && now.Sub(line.published) >= v.interval:
+		line.published = now
+		line.pending = nil
+		out = append(out, values)
+	case v.Publish == verbosityStartEnd, v.Publish == verbosityPeriodic:
+		line.pending = values
+	}
+	return out
+}
+
+// endPhaseReadings returns the readings held back during the previous phase when current is a new
+// one, and starts tracking it. Must be called holding verbosityMutex.
+func endPhaseReadings(current PhaseStatus) []*sdkModels.AsyncValues {
+	if current.Name == verbosityPhase.Name && current.Since.Equal(verbosityPhase.Since) {
+		return nil
+	}
+	var out []*sdkModels.AsyncValues
+	for _, line := range phaseLines {
+		if line.pending != nil {
+			out = append(out, line.pending)
+		}
+	}
+	verbosityPhase = current
+	phaseLines = make(map[string]*phaseLine)
+	return out
+}
+
+// startPhaseVerbosity publishes every second the readings held back by a phase that has ended, and
+// by the periodic phases the last reading of an interval without a newer change.
+func (s *SimpleDriver) startPhaseVerbosity() {
+	if len(driverConfig.Verbosity) == 0 {
+		return
+	}
+	goBackground(func() {
+		for {
+			supervisedSleep("verbosity", verbosityTick)
+			for _, values := range dueReadings() {
+				s.asyncCh <- values
+			}
+		}
+	})
+}
+
+func dueReadings() []*sdkModels.AsyncValues {
+	current := currentPhase()
+	verbosityMutex.Lock()
+	defer verbosityMutex.Unlock()
+	out := endPhaseReadings(current)
+	v := verbosityFor(current.Name)
+	if v == nil || v.Publish != verbosityPeriodic {
+		return out
+	}
+	now := time.Now()
+	for _, line := range phaseLines {
+		if line.pending != nil && now.Sub(line.published) >= v.interval {
+			out = append(out, line.pending)
+			line.published = now
+			line.pending = nil
+		}
+	}
+	return out
+}
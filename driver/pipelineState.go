@@ -0,0 +1,224 @@
+package driver
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/edgexfoundry/device-gpiod/gpio"
+)
+
+// PipelineState is the progress of the pipeline saved to PIPELINE_STATE_FILE at every phase change
+// and pipeline actuation, so a restarted service can pick up where the previous one stopped.
+type PipelineState struct {
+	Phase   string          `json:"phase"`
+	Since   time.Time       `json:"since"`
+	Until   time.Time       `json:"until,omitempty"`
+	Pump    string          `json:"pump,omitempty"`
+	StartTs int64           `json:"startTs,omitempty"`
+	RunFor  int64           `json:"runFor,omitempty"`
+	Lines   map[string]bool `json:"lines"`
+	// CyclesSinceClean counts the cycles run since the last clean phase
+	CyclesSinceClean int `json:"cyclesSinceClean"`
+	// RuntimeSinceReverse is the pump runtime in seconds since the last reverse phase
+	RuntimeSinceReverse int64 `json:"runtimeSinceReverse"`
+	// LastScheduledRun is the start of the last cycle started by the schedule
+	LastScheduledRun time.Time `json:"lastScheduledRun,omitempty"`
+	SavedAt          time.Time `json:"savedAt"`
+}
+
+var (
+	pipelineStateMutex = sync.Mutex{}
+	pipelineStateFile  string
+	pipelineState      = PipelineState{Phase: phaseIdle, Lines: make(map[string]bool)}
+	// restoredPipeline is the state left by the previous run, consumed when the pipeline starts
+	restoredPipeline *PipelineState
+)
+
+// loadPipelineState reads the state saved by the previous run from PIPELINE_STATE_FILE. It is a no-op
+// when the variable is unset.
+func loadPipelineState() {
+	pipelineStateFile = os.Getenv("PIPELINE_STATE_FILE")
+	if pipelineStateFile == "" {
+		return
+	}
+	data, err := os.ReadFile(pipelineStateFile)
+	if os.IsNotExist(err) {
+		return
+	}
+	var state PipelineState
+	if err == nil {
+		err = json.Unmarshal(data, &state)
+	}
+	if err != nil {
+		log.Printf("Cannot read pipeline state %s, starting from scratch. Error: %s", pipelineStateFile, err)
+		return
+	}
+	restoredPipeline = &state
+	pipelineState.CyclesSinceClean = state.CyclesSinceClean
+	pipelineState.RuntimeSinceReverse = state.RuntimeSinceReverse
+	pipelineState.LastScheduledRun = state.LastScheduledRun
+	log.Printf("Restored pipeline state: phase %s since %s", state.Phase, state.Since.Format(time.RFC3339))
+}
+
+// notePipelinePhase records the phase entered by the pipeline.
+func notePipelinePhase(status PhaseStatus) {
+	pipelineStateMutex.Lock()
+	defer pipelineStateMutex.Unlock()
+	pipelineState.Phase, pipelineState.Since, pipelineState.Until = status.Name, status.Since, status.Until
+	savePipelineState()
+}
+
+// notePipelinePump records the pump running the current cycle and its deadline.
+func notePipelinePump(name string, startTs int64, runFor int64) {
+	pipelineStateMutex.Lock()
+	defer pipelineStateMutex.Unlock()
+	pipelineState.Pump, pipelineState.StartTs, pipelineState.RunFor = name, startTs, runFor
+	pipelineState.Lines[name] = true
+	savePipelineState()
+}
+
+// notePipelineCleanCount records the cycles run since the last clean.
+func notePipelineCleanCount(n int) {
+	pipelineStateMutex.Lock()
+	defer pipelineStateMutex.Unlock()
+	pipelineState.CyclesSinceClean = n
+	savePipelineState()
+}
+
+// notePipelineReverseRuntime records the pump runtime since the last reverse.
+func notePipelineReverseRuntime(d time.Duration) {
+	pipelineStateMutex.Lock()
+	defer pipelineStateMutex.Unlock()
+	pipelineState.RuntimeSinceReverse = int64(d.Seconds())
+	savePipelineState()
+}
+
+// notePipelineScheduledRun records the start of the last scheduled cycle.
+func notePipelineScheduledRun(t time.Time) {
+	pipelineStateMutex.Lock()
+	defer pipelineStateMutex.Unlock()
+	pipelineState.LastScheduledRun = t
+	savePipelineState()
+}
+
+// notePipelineLine records the logical state of a line driven by the pipeline.
+func notePipelineLine(name string, on bool) {
+	pipelineStateMutex.Lock()
+	defer pipelineStateMutex.Unlock()
+	if on {
+		pipelineState.Lines[name] = true
+	} else {
+		delete(pipelineState.Lines, name)
+	}
+	savePipelineState()
+}
+
+// adoptPipelineState takes over the state mirrored from the former leader of a redundant pair: its
+// counters, and its phase resumed by resumePipeline.
+func adoptPipelineState(state *PipelineState) {
+	nextCycleMutex.Lock()
+	sinceClean = state.CyclesSinceClean
+	runtimeSince = time.Duration(state.RuntimeSinceReverse) * time.Second
+	nextCycleMutex.Unlock()
+	scheduleMutex.Lock()
+	scheduleStatus.LastRun = state.LastScheduledRun
+	scheduleMutex.Unlock()
+	pipelineStateMutex.Lock()
+	pipelineState.CyclesSinceClean = state.CyclesSinceClean
+	pipelineState.RuntimeSinceReverse = state.RuntimeSinceReverse
+	pipelineState.LastScheduledRun = state.LastScheduledRun
+	pipelineStateMutex.Unlock()
+	restoredPipeline = state
+}
+
+// savePipelineState writes the state atomically, and mirrors it to the standby instance of a
+// redundant pair. Must be called holding pipelineStateMutex.
+func savePipelineState() {
+	mirrorPipelineState()
+	if pipelineStateFile == "" {
+		return
+	}
+	pipelineState.SavedAt = time.Now()
+	data, err := json.Marshal(pipelineState)
+	if err != nil {
+		log.Printf("Cannot marshal pipeline state. Error: %s", err)
+		return
+	}
+	tmp, err := os.Create(pipelineStateFile + ".tmp")
+	if err == nil {
+		_, err = tmp.Write(data)
+		if err == nil {
+			err = tmp.Sync()
+		}
+		tmp.Close()
+	}
+	if err == nil {
+		err = os.Rename(pipelineStateFile+".tmp", pipelineStateFile)
+	}
+	if err != nil {
+		log.Printf("Cannot save pipeline state %s. Error: %s", pipelineStateFile, err)
+	}
+}
+
+// resumePipeline continues from the state of the previous run: a pump phase still within its run
+// time is resumed for the remaining time, a gap is waited out until its original deadline, and any
+// other interrupted phase is rolled back by driving the lines the pipeline left on to their safe
+// state, followed by a full gap. It returns the pump and run time to continue with.
+func (s *SimpleDriver) resumePipeline(pump gpio.GPIO, runFor int64) (gpio.GPIO, int64) {
+	state := restoredPipeline
+	restoredPipeline = nil
+	if state == nil || state.Phase == phaseIdle {
+		return pump, runFor
+	}
+	now := time.Now()
+	if state.Phase == phaseGap {
+		if remaining := state.Until.Sub(now); remaining > 0 {
+			audit("pipeline-resume", "pipeline", fmt.Sprintf("gap resumed, %s left", remaining.Round(time.Second)))
+			setPhase(phaseGap, remaining)
+			supervisedSleep("pipeline", remaining)
+		}
+		return pump, runFor
+	}
+	if state.Phase == phasePump && state.StartTs+state.RunFor > now.Unix() {
+		if g, ok := s.findGpio(state.Pump); ok {
+			err := s.startPipelineLine(g)
+			if err == nil {
+				remaining := state.StartTs + state.RunFor - now.Unix()
+				g.State = true
+				*startTs = now.Unix()
+				audit("pipeline-resume", g.Name, fmt.Sprintf("pump phase resumed, %d s left", remaining))
+				notePipelinePump(g.Name, *startTs, remaining)
+				setPhase(phasePump, time.Duration(remaining)*time.Second)
+				s.handleAsyncCommunication(*g)
+				return *g, remaining
+			}
+			log.Printf("Cannot resume pump phase on gpio %s, rolling back. Error: %s", g.Name, err)
+		}
+	}
+	s.rollBackPipeline(state)
+	setPhase(phaseGap, deratedGap(*commandGap))
+	supervisedSleep("pipeline", deratedGap(*commandGap))
+	return pump, runFor
+}
+
+// rollBackPipeline drives the lines left on by an interrupted phase to their safe state.
+func (s *SimpleDriver) rollBackPipeline(state *PipelineState) {
+	for name, on := range state.Lines {
+		g, ok := s.findGpio(name)
+		if !ok || !on {
+			continue
+		}
+		if err := setPipelineLevel(g, g.SafeValue() == 1); err != nil {
+			log.Printf("Cannot roll back gpio %s. Error: %s", name, err)
+			continue
+		}
+		g.State = g.SafeValue() == 1
+		notePipelineLine(name, false)
+		s.handleAsyncCommunication(*g)
+	}
+	audit("pipeline-rollback", "pipeline", fmt.Sprintf("%s phase interrupted, lines driven to safe state", state.Phase))
+}
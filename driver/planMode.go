@@ -0,0 +1,97 @@
+package driver
+
+import (
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/edgexfoundry/device-gpiod/sequence"
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/common"
+
+	sdkModels "github.com/edgexfoundry/device-sdk-go/v2/pkg/models"
+)
+
+const (
+	cyclePlanResource = "CyclePlan"
+	planRoute         = common.ApiBase + "/plan"
+)
+
+var (
+	planMode = false
+)
+
+// PlannedStep is one intended actuation of a cycle, At being the offset from the cycle start.
+type PlannedStep struct {
+	At     string `json:"at"`
+	Phase  string `json:"phase"`
+	Line   string `json:"line"`
+	Action string `json:"action"`
+}
+
+func parsePlanMode() {
+	var err error
+	planMode, err = strconv.ParseBool(os.Getenv("PLAN_MODE"))
+	if err != nil {
+		planMode = false
+	}
+	if planMode {
+		log.Println("PLAN_MODE enabled: cycles are planned and published, no line is actuated")
+	}
+}
+
+// buildCyclePlan returns the actuations a cycle with a pump phase of runFor seconds would perform:
+// the pump phase of handleStartGpio, then the steps of the cycle sequence.
+func buildCyclePlan(runFor int64) []PlannedStep {
+	plan := []PlannedStep{
+		{At: "0s", Phase: phasePump, Line: triggerLine("START_TRIGGER"), Action: "up"},
+		{At: "0s", Phase: phasePump, Line: "light G", Action: "up"},
+	}
+	pumped := time.Duration(runFor) * time.Second
+	plan = append(plan,
+		PlannedStep{At: pumped.String(), Phase: phasePump, Line: triggerLine("START_TRIGGER"), Action: "down"},
+		PlannedStep{At: pumped.String(), Phase: phasePump, Line: "light G", Action: "down"})
+
+	planner := &sequence.Recorder{Clock: pumped, Environment: sequenceEnv()}
+	if err := sequence.Run(cycleSequence, planner); err != nil {
+		log.Printf("Cannot plan cycle sequence %s. Error: %s", cycleSequence.Name, err)
+	}
+	for _, a := range planner.Actuations {
+		action := "down"
+		if a.On {
+			action = "up"
+		}
+		plan = append(plan, PlannedStep{At: a.At.String(), Phase: a.Phase, Line: a.Line, Action: action})
+	}
+	return plan
+}
+
+// publishCyclePlan logs the plan of a cycle and publishes it as a reading instead of running it.
+func (s *SimpleDriver) publishCyclePlan(runFor int64) {
+	plan := buildCyclePlan(runFor)
+	payload, err := shapePayload(cyclePlanResource, plan)
+	if err != nil {
+		log.Printf("Cannot marshal cycle plan. Error: %s", err)
+		return
+	}
+	log.Printf("Planned cycle (not executed): %s", string(payload))
+	audit("cycle-planned", deviceName(), string(payload))
+	cv, err := sdkModels.NewCommandValue(cyclePlanResource, common.ValueTypeString, string(payload))
+	if err != nil {
+		log.Printf("Cannot create cycle plan reading. Error: %s", err)
+		return
+	}
+	s.asyncCh <- &sdkModels.AsyncValues{
+		DeviceName:    deviceName(),
+		CommandValues: []*sdkModels.CommandValue{cv},
+	}
+}
+
+// handlePlan returns the plan of the next cycle with the current configuration.
+func (s *SimpleDriver) handlePlan(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"planMode": planMode,
+		"steps":    buildCyclePlan(cyclePumpDuration()),
+	})
+}
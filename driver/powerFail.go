@@ -0,0 +1,71 @@
+package driver
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/edgexfoundry/device-gpiod/gpio"
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/common"
+
+	sdkModels "github.com/edgexfoundry/device-sdk-go/v2/pkg/models"
+)
+
+const (
+	RolePowerFail     = "powerfail"
+	powerFailResource = "PowerFail"
+	powerFailInhibit  = "power-fail"
+	powerFailTarget   = time.Duration(100) * time.Millisecond
+)
+
+// startPowerFailMonitoring watches the line with role "powerfail". Its assertion means the supply is
+// about to drop: outputs are driven to safe state first, then a final event is flushed.
+func (s *SimpleDriver) startPowerFailMonitoring() {
+	for i := range s.GpioList.Gpio {
+		g := &s.GpioList.Gpio[i]
+		if g.Role != RolePowerFail {
+			continue
+		}
+		if err := g.Watch(s.handlePowerFailEvent); err != nil {
+			log.Printf("Cannot monitor power-fail gpio %s. Error: %s", g.Name, err)
+			continue
+		}
+		log.Printf("Monitoring power-fail gpio %s", g.Name)
+	}
+}
+
+func (s *SimpleDriver) handlePowerFailEvent(evt gpio.Event) {
+	received := time.Now()
+	recordTransition(evt.Name, evt.Value == 1)
+	if evt.Value == 0 {
+		gpio.ReleaseInhibit(powerFailInhibit)
+		audit("power-restored", evt.Name, "power-fail input released, actuation allowed")
+		return
+	}
+
+	gpio.Inhibit(powerFailInhibit)
+	s.driveSafeState("power fail imminent", false)
+	latency := time.Since(received)
+	if latency > powerFailTarget {
+		log.Printf("WARNING: power-fail safe state took %s, above the %s target", latency, powerFailTarget)
+	}
+
+	payload, err := shapePayload(powerFailResource, map[string]interface{}{
+		"event":     evt,
+		"latencyUs": latency.Microseconds(),
+	})
+	if err != nil {
+		log.Printf("Cannot marshal power-fail event. Error: %s", err)
+		return
+	}
+	cv, err := sdkModels.NewCommandValue(powerFailResource, common.ValueTypeString, string(payload))
+	if err != nil {
+		log.Printf("Cannot create power-fail reading. Error: %s", err)
+		return
+	}
+	s.asyncCh <- &sdkModels.AsyncValues{
+		DeviceName:    deviceName(),
+		CommandValues: []*sdkModels.CommandValue{cv},
+	}
+	audit("power-fail", evt.Name, fmt.Sprintf("safe state reached in %s", latency))
+}
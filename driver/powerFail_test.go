@@ -0,0 +1,67 @@
+package driver
+
+import (
+	"testing"
+	"time"
+
+	"github.com/edgexfoundry/device-gpiod/gpio"
+)
+
+const powerFailLines = `
+gpio:
+  - name: relay
+    chip: gpiochip0
+    line: 0
+  - name: vent
+    chip: gpiochip0
+    line: 1
+    safe_state: high
+  - name: supply
+    chip: gpiochip0
+    line: 2
+    role: powerfail
+`
+
+func TestPowerFailReachesSafeStateWithinTarget(t *testing.T) {
+	s, sim, readings := newTestDriver(t, powerFailLines)
+	if err := s.writeLine("relay", true, "test"); err != nil {
+		t.Fatalf("write of relay failed: %s", err)
+	}
+	<-readings
+	s.startPowerFailMonitoring()
+	defer gpio.ReleaseInhibit(powerFailInhibit)
+
+	// The handler runs before Inject returns, so the edge is fully handled when it does
+	sent := time.Now()
+	if err := sim.Inject("gpiochip0", 2, 1); err != nil {
+		t.Fatalf("cannot send the power-fail edge: %s", err)
+	}
+	if latency := time.Since(sent); latency > powerFailTarget {
+		t.Errorf("power-fail handled in %s, above the %s target", latency, powerFailTarget)
+	}
+
+	for name, safe := range map[string]int{"relay": 0, "vent": 1} {
+		g, _ := s.findGpio(name)
+		if value, err := g.ReadBack(); err != nil || value != safe {
+			t.Errorf("%s reads %d (%v) after the power-fail edge, want its safe state %d", name, value, err, safe)
+		}
+	}
+	select {
+	case values := <-readings:
+		if values.CommandValues[0].DeviceResourceName != powerFailResource {
+			t.Errorf("reading of %s published, want %s", values.CommandValues[0].DeviceResourceName, powerFailResource)
+		}
+	case <-time.After(time.Second):
+		t.Error("no power-fail reading published")
+	}
+	if err := s.writeLine("relay", true, "test"); err == nil {
+		t.Error("write of relay allowed while the power fails")
+	}
+
+	if err := sim.Inject("gpiochip0", 2, 0); err != nil {
+		t.Fatalf("cannot release the power-fail input: %s", err)
+	}
+	if err := s.writeLine("relay", true, "test"); err != nil {
+		t.Errorf("write of relay refused once the power is restored: %s", err)
+	}
+}
@@ -0,0 +1,265 @@
+package driver
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/edgexfoundry/device-gpiod/gpio"
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/common"
+
+	sdkModels "github.com/edgexfoundry/device-sdk-go/v2/pkg/models"
+)
+
+const (
+	powerModeResource = "PowerMode"
+	powerRoute        = common.ApiBase + "/power"
+
+	lineSourcePrefix = "line:"
+
+	// Power modes: external power available, running on battery, battery too low to run cycles
+	powerGrid    = "grid"
+	powerBattery = "battery"
+	powerLow     = "low"
+
+	DEFAULT_POWER_INTERVAL   = time.Duration(1) * time.Minute
+	DEFAULT_POWER_HYSTERESIS = 10.0
+)
+
+// PowerSupply gates the pump cycles of off-grid sites. supply tells whether external power, PV or
+// grid, is available: a nonzero line:<name> input, sysfs file or edgex:<device>/<resource> reading.
+// battery is the charge level, read the same way and multiplied by scale. While the supply is off,
+// the cleans due are deferred; below the low battery level the cycles are held until the level
+// climbs hysteresis above it. When external power returns the deferred work is caught up at once:
+// the command gap is cut short and the next cycle cleans. Cleans forced for the next cycle are
+// never deferred.
+type PowerSupply struct {
+	Supply     string  `yaml:"supply"`
+	Battery    string  `yaml:"battery"`
+	Scale      float64 `yaml:"scale"`
+	Low        float64 `yaml:"low"`
+	Hysteresis float64 `yaml:"hysteresis"`
+	Interval   string  `yaml:"interval"`
+	interval   time.Duration
+}
+
+// PowerStatus is the last state of the supply and battery read.
+type PowerStatus struct {
+	Mode           string    `json:"mode"`
+	Supply         bool      `json:"supply"`
+	Battery        *float64  `json:"battery,omitempty"`
+	DeferredCleans int       `json:"deferredCleans"`
+	Since          time.Time `json:"since"`
+	Error          string    `json:"error,omitempty"`
+}
+
+var (
+	powerMutex  = sync.Mutex{}
+	powerStatus = PowerStatus{Mode: powerGrid, Supply: true, Since: time.Now()}
+	// batteryLow holds the cycles, with hysteresis; catchUpClean makes the next cycle clean
+	batteryLow   bool
+	catchUpClean bool
+	// powerReturned cuts the command gap short when external power returns with work to catch up
+	powerReturned = make(chan struct{}, 1)
+)
+
+// validatePower checks the power section of the configuration file.
+func validatePower() error {
+	p := driverConfig.Power
+	if p == nil {
+		return nil
+	}
+	if p.Supply == "" && p.Battery == "" {
+		return errors.New("a supply or battery source is required")
+	}
+	for _, source := range []string{p.Supply, p.Battery} {
+		if err := validateSource(source); err != nil {
+			return err
+		}
+	}
+	if p.Scale == 0 {
+		p.Scale = 1
+	}
+	if p.Hysteresis == 0 {
+		p.Hysteresis = DEFAULT_POWER_HYSTERESIS
+	}
+	if p.Hysteresis < 0 {
+		return fmt.Errorf("hysteresis must be positive")
+	}
+	p.interval = DEFAULT_POWER_INTERVAL
+	if p.Interval != "" {
+		interval, err := time.ParseDuration(p.Interval)
+		if err != nil || interval <= 0 {
+			return fmt.Errorf("invalid interval %q", p.Interval)
+		}
+		p.interval = interval
+	}
+	return nil
+}
+
+// readPowerSource reads an input line, given as line:<name>, or any source read by readSource.
+func (s *SimpleDriver) readPowerSource(source string, scale float64) (float64, error) {
+	if !strings.HasPrefix(source, lineSourcePrefix) {
+		return readSource(source, scale)
+	}
+	name := strings.TrimPrefix(source, lineSourcePrefix)
+	g, ok := s.findGpio(name)
+	if !ok {
+		return 0, fmt.Errorf("%w %s", errUnknownLine, name)
+	}
+	value, err := g.Value()
+	return float64(value) * scale, err
+}
+
+// startPowerMonitoring reads the supply and battery every interval when the power section is set.
+// An unreadable source keeps its previous state.
+func (s *SimpleDriver) startPowerMonitoring() {
+	p := driverConfig.Power
+	if p == nil {
+		return
+	}
+	goBackground(func() {
+		for {
+			s.checkPower(p)
+			supervisedSleep("power", p.interval)
+		}
+	})
+}
+
+func (s *SimpleDriver) checkPower(p *PowerSupply) {
+	var failures []string
+	supply, supplyErr := 1.0, error(nil)
+	if p.Supply != "" {
+		if supply, supplyErr = s.readPowerSource(p.Supply, 1); supplyErr != nil {
+			failures = append(failures, fmt.Sprintf("supply: %s", supplyErr))
+		}
+	}
+	var level float64
+	var levelErr error
+	if p.Battery != "" {
+		if level, levelErr = s.readPowerSource(p.Battery, p.Scale); levelErr != nil {
+			failures = append(failures, fmt.Sprintf("battery: %s", levelErr))
+		}
+	}
+
+	powerMutex.Lock()
+	previous := powerStatus.Mode
+	powerStatus.Error = strings.Join(failures, "; ")
+	if p.Supply != "" && supplyErr == nil {
+		powerStatus.Supply = supply != 0
+	}
+	if p.Battery != "" && levelErr == nil {
+		powerStatus.Battery = &level
+		if level < p.Low {
+			batteryLow = true
+		} else if level >= p.Low+p.Hysteresis {
+			batteryLow = false
+		}
+	}
+	mode := powerGrid
+	if batteryLow {
+		mode = powerLow
+	} else if !powerStatus.Supply {
+		mode = powerBattery
+	}
+	powerStatus.Mode = mode
+	catchUp := false
+	if mode != previous {
+		powerStatus.Since = time.Now()
+		if mode == powerGrid && (previous == powerLow || powerStatus.DeferredCleans > 0) {
+			catchUp, catchUpClean = true, powerStatus.DeferredCleans > 0
+			powerStatus.DeferredCleans = 0
+		}
+	}
+	status := powerStatus
+	powerMutex.Unlock()
+
+	if status.Error != "" {
+		gpio.SampledLogf("power read", "Cannot read power sources. Error: %s", status.Error)
+	} else {
+		logRecovered("power read")
+	}
+	if mode == previous {
+		return
+	}
+	detail := fmt.Sprintf("%s to %s", previous, mode)
+	if status.Battery != nil {
+		detail += fmt.Sprintf(", battery at %.1f", *status.Battery)
+	}
+	log.Printf("Power mode changed from %s", detail)
+	audit("power-mode", deviceName(), detail)
+	if catchUp {
+		select {
+		case powerReturned <- struct{}{}:
+		default:
+		}
+	}
+	cv, err := sdkModels.NewCommandValue(powerModeResource, common.ValueTypeString, mode)
+	if err != nil {
+		log.Printf("Cannot create power mode reading. Error: %s", err)
+		return
+	}
+	s.asyncCh <- &sdkModels.AsyncValues{
+		DeviceName:    deviceName(),
+		CommandValues: []*sdkModels.CommandValue{cv},
+	}
+}
+
+// powerHeld reports whether the battery is too low to run cycles.
+func powerHeld() bool {
+	powerMutex.Lock()
+	defer powerMutex.Unlock()
+	return batteryLow
+}
+
+// powerMode returns the current power mode.
+func powerMode() string {
+	powerMutex.Lock()
+	defer powerMutex.Unlock()
+	return powerStatus.Mode
+}
+
+// powerCleans decides whether a cycle cleans, given whether a clean is due and whether it was forced:
+// a clean due is deferred while off external power, and the first cycle after power returns cleans
+// when one was deferred. Called holding nextCycleMutex.
+func powerCleans(due bool, forced bool) bool {
+	if driverConfig.Power == nil || forced {
+		return due
+	}
+	powerMutex.Lock()
+	defer powerMutex.Unlock()
+	if catchUpClean {
+		catchUpClean = false
+		return *enableClean
+	}
+	if due && powerStatus.Mode != powerGrid {
+		powerStatus.DeferredCleans++
+		audit("clean-deferred", "cycle", fmt.Sprintf("%s power, %d cleans deferred", powerStatus.Mode, powerStatus.DeferredCleans))
+		return false
+	}
+	return due
+}
+
+// sleepGap sleeps for the command gap, cut short when external power returns with work to catch up.
+// The pipeline ends when Stop is called meanwhile.
+func sleepGap(gap time.Duration) {
+	petSupervisor("pipeline", gap)
+	select {
+	case <-time.After(gap):
+	case <-powerReturned:
+		log.Println("External power returned, catching up on the deferred work")
+	case <-stopping:
+		exitOnStop()
+	}
+}
+
+func (s *SimpleDriver) handlePower(w http.ResponseWriter, r *http.Request) {
+	powerMutex.Lock()
+	status := powerStatus
+	powerMutex.Unlock()
+	writeJSON(w, http.StatusOK, status)
+}
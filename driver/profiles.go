@@ -0,0 +1,180 @@
+package driver
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/common"
+)
+
+const (
+	profilesRoute        = common.ApiBase + "/profiles"
+	profileActivateRoute = common.ApiBase + "/profiles/activate"
+	defaultProfile       = "default"
+)
+
+// ConfigProfile is a named set of cycle timings (summer/winter, product A/B) stored in the profiles
+// section of the configuration file. Unset timers keep the values the service started with. Requires
+// lists the lines the profile expects, a profile is only activated when they are all configured as
+// outputs.
+type ConfigProfile struct {
+	Description string        `yaml:"description"`
+	Timers      ProfileTimers `yaml:"timers"`
+	Requires    []string      `yaml:"requires"`
+}
+
+type ProfileTimers struct {
+	Pump       string `yaml:"pump"`
+	CommandGap string `yaml:"command_gap"`
+	Clean      string `yaml:"clean"`
+	Reverse    string `yaml:"reverse"`
+	Gravity    string `yaml:"gravity"`
+}
+
+type timerValues struct {
+	pump       time.Duration
+	commandGap time.Duration
+	clean      time.Duration
+	reverse    time.Duration
+	gravity    time.Duration
+}
+
+type profileRequest struct {
+	Name string `json:"name"`
+}
+
+var (
+	profileMutex  = sync.Mutex{}
+	activeProfile = defaultProfile
+	startupTimers timerValues
+)
+
+// rememberStartupTimers keeps the timers parsed at startup, restored by the default profile.
+func rememberStartupTimers() {
+	startupTimers = timerValues{
+		pump:       time.Duration(*pumpTimer) * time.Second,
+		commandGap: *commandGap,
+		clean:      *cleanTimer,
+		reverse:    *reverseTimer,
+		gravity:    *gravityTimer,
+	}
+}
+
+// timers returns the timers of the profile on top of the startup ones, checking them against the
+// minimum values enforced at startup.
+func (p ConfigProfile) timers() (timerValues, error) {
+	values := startupTimers
+	for _, timer := range []struct {
+		name  string
+		value string
+		min   time.Duration
+		dst   *time.Duration
+	}{
+		{"pump", p.Timers.Pump, time.Duration(MIN_PUMP) * time.Minute, &values.pump},
+		{"command_gap", p.Timers.CommandGap, MIN_COMMAND_GAP, &values.commandGap},
+		{"clean", p.Timers.Clean, MIN_CLEAN_TIMER, &values.clean},
+		{"reverse", p.Timers.Reverse, MIN_REVERSE_TIMER, &values.reverse},
+		{"gravity", p.Timers.Gravity, MIN_GRAVITY_TIMER, &values.gravity},
+	} {
+		if timer.value == "" {
+			continue
+		}
+		d, err := time.ParseDuration(timer.value)
+		if err != nil {
+			return values, fmt.Errorf("invalid %s timer %q", timer.name, timer.value)
+		}
+		if d < timer.min || d > DEFAULT_MAX_TIMER {
+			return values, fmt.Errorf("%s timer %s out of range [%s, %s]", timer.name, d, timer.min, DEFAULT_MAX_TIMER)
+		}
+		*timer.dst = d
+	}
+	return values, nil
+}
+
+// validateProfiles checks the profiles section of the configuration file.
+func validateProfiles() error {
+	for name, p := range driverConfig.Profiles {
+		if name == "" || name == defaultProfile {
+			return fmt.Errorf("profile name %q is reserved", name)
+		}
+		if _, err := p.timers(); err != nil {
+			return fmt.Errorf("profile %s: %s", name, err)
+		}
+	}
+	return nil
+}
+
+// activateProfile switches the cycle timings to the named profile, the default profile restoring
+// the startup ones. The new timings apply from the next phase of the cycle.
+func (s *SimpleDriver) activateProfile(name string, source string) error {
+	if name == "" {
+		name = defaultProfile
+	}
+	values := startupTimers
+	if name != defaultProfile {
+		p, ok := driverConfig.Profiles[name]
+		if !ok {
+			return fmt.Errorf("unknown profile %s", name)
+		}
+		for _, line := range p.Requires {
+			g, ok := s.findGpio(line)
+			if !ok || !isOutputRole(g.Role) {
+				return fmt.Errorf("profile %s is not compatible with the hardware map: output %s is not configured", name, line)
+			}
+		}
+		var err error
+		if values, err = p.timers(); err != nil {
+			return fmt.Errorf("profile %s: %s", name, err)
+		}
+	}
+
+	profileMutex.Lock()
+	defer profileMutex.Unlock()
+	*pumpTimer = int64(values.pump.Seconds())
+	*commandGap = values.commandGap
+	*cleanTimer = values.clean
+	*reverseTimer = values.reverse
+	*gravityTimer = values.gravity
+	gpioConfig.PumpTimer = time.Duration(*pumpTimer)
+	gpioConfig.CommandGap = values.commandGap
+	gpioConfig.CleanTimer = values.clean
+	gpioConfig.ReverseTimer = values.reverse
+	gpioConfig.GravityTimer = values.gravity
+	previous := activeProfile
+	activeProfile = name
+	audit("profile-activate", name, fmt.Sprintf("from %s by %s", previous, source))
+	return nil
+}
+
+// handleProfiles lists the configured profiles and the active one.
+func (s *SimpleDriver) handleProfiles(w http.ResponseWriter, r *http.Request) {
+	names := []string{defaultProfile}
+	for name := range driverConfig.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names[1:])
+	profileMutex.Lock()
+	defer profileMutex.Unlock()
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"active":   activeProfile,
+		"profiles": names,
+		"abTest":   currentABSet(),
+	})
+}
+
+func (s *SimpleDriver) handleProfileActivate(w http.ResponseWriter, r *http.Request) {
+	var req profileRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := s.activateProfile(req.Name, "command"); err != nil {
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"active": req.Name})
+}
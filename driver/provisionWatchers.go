@@ -0,0 +1,118 @@
+package driver
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+
+	"github.com/edgexfoundry/device-gpiod/gpio"
+	"github.com/edgexfoundry/device-sdk-go/v2/pkg/service"
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/models"
+)
+
+// ProvisionWatcherSpec is a provision watcher created in core-metadata, onboarding the discovered
+// lines whose gpio protocol properties match Identifiers and none of Blocking, e.g. every line of the
+// expanders on i2c with {bus: i2c, chipLabel: pca953x}. The values are regular expressions. Profile
+// defaults to the generic line profile.
+type ProvisionWatcherSpec struct {
+	Name        string              `yaml:"name"`
+	Identifiers map[string]string   `yaml:"identifiers"`
+	Blocking    map[string][]string `yaml:"blocking"`
+	Profile     string              `yaml:"profile"`
+	Labels      []string            `yaml:"labels"`
+}
+
+func validateProvisionWatchers() error {
+	seen := make(map[string]bool)
+	for i, w := range driverConfig.ProvisionWatchers {
+		if w.Name == "" || seen[w.Name] {
+			return fmt.Errorf("watcher %d: empty or repeated name %q", i, w.Name)
+		}
+		seen[w.Name] = true
+		if len(w.Identifiers) == 0 {
+			return fmt.Errorf("watcher %s: at least one identifier is required", w.Name)
+		}
+		for key, value := range w.Identifiers {
+			if err := validateIdentifier(key, value); err != nil {
+				return fmt.Errorf("watcher %s: %s", w.Name, err)
+			}
+		}
+		for key, values := range w.Blocking {
+			for _, value := range values {
+				if err := validateIdentifier(key, value); err != nil {
+					return fmt.Errorf("watcher %s: blocking %s", w.Name, err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func validateIdentifier(key string, value string) error {
+	if _, ok := gpioProtocol[key]; !ok {
+		return fmt.Errorf("unknown gpio protocol property %q", key)
+	}
+	if _, err := regexp.Compile(value); err != nil {
+		return fmt.Errorf("identifier %s: %s", key, err)
+	}
+	return nil
+}
+
+// provisionWatchers creates the configured provision watchers missing from core-metadata. Existing
+// ones are left as they are, edited through core-metadata.
+func provisionWatchers() error {
+	ds := service.RunningService()
+	for _, w := range driverConfig.ProvisionWatchers {
+		if _, err := ds.GetProvisionWatcherByName(w.Name); err == nil {
+			continue
+		}
+		profile := w.Profile
+		if profile == "" {
+			profile = lineProfileName
+		}
+		log.Printf("Provision watcher '%s' not found. Creating it...", w.Name)
+		watcher := models.ProvisionWatcher{
+			Name:                w.Name,
+			Labels:              append([]string{provisionLabel}, w.Labels...),
+			Identifiers:         w.Identifiers,
+			BlockingIdentifiers: w.Blocking,
+			ProfileName:         profile,
+			ServiceName:         ds.Name(),
+			AdminState:          models.Unlocked,
+		}
+		if _, err := ds.AddProvisionWatcher(watcher); err != nil {
+			return fmt.Errorf("cannot add provision watcher '%s': %s", w.Name, err)
+		}
+	}
+	return nil
+}
+
+// startChipDiscovery looks for new gpiochips every CHIP_WATCH_INTERVAL, e.g. an expander whose driver
+// was bound after the start, and runs a discovery when one appears so the provision watchers onboard
+// its lines without waiting for the scheduled discovery. It is a no-op without provision watchers.
+func (s *SimpleDriver) startChipDiscovery() {
+	interval := chipWatchInterval()
+	if interval == 0 || len(driverConfig.ProvisionWatchers) == 0 || gpio.ActiveSimulator() != nil {
+		return
+	}
+	known := make(map[string]bool)
+	for _, chip := range gpio.Chips() {
+		known[chip] = true
+	}
+	goBackground(func() {
+		for {
+			supervisedSleep("chip-discovery", interval)
+			var added []string
+			for _, chip := range gpio.Chips() {
+				if !known[chip] {
+					known[chip] = true
+					added = append(added, chip)
+				}
+			}
+			if len(added) > 0 {
+				logf(moduleGpio, levelInfo, "New gpiochips %v found, discovering their lines", added)
+				s.Discover()
+			}
+		}
+	})
+}
@@ -0,0 +1,107 @@
+package driver
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/edgexfoundry/device-gpiod/gpio"
+)
+
+const (
+	quietDim       = "dim"
+	quietFaultOnly = "fault-only"
+	defaultDim     = 0.2
+)
+
+// QuietHours is the daily period (local time, e.g. 22:00 to 07:00) during which buzzer channels are
+// muted and the indicator is either dimmed through software PWM or only shows faults.
+type QuietHours struct {
+	From string  `yaml:"from"`
+	To   string  `yaml:"to"`
+	Mode string  `yaml:"mode"`
+	Dim  float64 `yaml:"dim"`
+	from time.Duration
+	to   time.Duration
+}
+
+// validateQuietHours checks the quiet_hours section of the configuration file.
+func validateQuietHours() error {
+	q := driverConfig.QuietHours
+	if q == nil {
+		return nil
+	}
+	var err error
+	if q.from, err = timeOfDay(q.From); err != nil {
+		return fmt.Errorf("invalid start %q", q.From)
+	}
+	if q.to, err = timeOfDay(q.To); err != nil {
+		return fmt.Errorf("invalid end %q", q.To)
+	}
+	if q.from == q.to {
+		return errors.New("start and end must differ")
+	}
+	switch q.Mode {
+	case "":
+		q.Mode = quietDim
+	case quietDim, quietFaultOnly:
+	default:
+		return fmt.Errorf("unknown mode %q", q.Mode)
+	}
+	if q.Dim == 0 {
+		q.Dim = defaultDim
+	}
+	if q.Dim < 0 || q.Dim >= 1 {
+		return fmt.Errorf("dim level %.2f out of range (0, 1)", q.Dim)
+	}
+	return nil
+}
+
+// timeOfDay parses a HH:MM time into the offset from midnight.
+func timeOfDay(value string) (time.Duration, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// active reports whether now falls in the quiet hours, which may span midnight.
+func (q *QuietHours) active(now time.Time) bool {
+	return inDailyPeriod(q.from, q.to, now)
+}
+
+// inDailyPeriod reports whether now falls between the offsets from midnight from and to, the period
+// spanning midnight when to comes first.
+func inDailyPeriod(from, to time.Duration, now time.Time) bool {
+	offset := time.Duration(now.Hour())*time.Hour + time.Duration(now.Minute())*time.Minute
+	if from < to {
+		return offset >= from && offset < to
+	}
+	return offset >= from || offset < to
+}
+
+// inQuietHours returns the quiet hours when they are in effect, nil otherwise.
+func inQuietHours() *QuietHours {
+	q := driverConfig.QuietHours
+	if q == nil || !q.active(time.Now()) {
+		return nil
+	}
+	return q
+}
+
+// driveChannel sets an indicator channel to level: fully on or off through plain writes, dimmed
+// through software PWM. It returns false when the PWM of a previously dimmed channel had to be
+// stopped first, and the write must be retried.
+func driveChannel(g gpio.GPIO, level float64) (bool, error) {
+	if level > 0 && level < 1 {
+		return true, g.SetDuty(level, g.PwmPeriod(pwmPeriod))
+	}
+	if _, running := g.Duty(); running {
+		return false, g.StopPwm()
+	}
+	if level == 1 {
+		return true, g.Up()
+	}
+	return true, g.Down()
+}
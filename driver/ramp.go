@@ -0,0 +1,258 @@
+package driver
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/edgexfoundry/device-gpiod/gpio"
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/common"
+
+	sdkModels "github.com/edgexfoundry/device-sdk-go/v2/pkg/models"
+)
+
+const (
+	RolePwm               = gpio.RolePwm
+	rampProgressResource  = "RampProgress"
+	rampRoute             = common.ApiBase + "/ramp"
+	rampCancelRoute       = common.ApiBase + "/ramp/cancel"
+	rampDurationAttribute = "rampDuration"
+	rampCurveAttribute    = "rampCurve"
+
+	curveLinear  = "linear"
+	curveEaseIn  = "ease-in"
+	curveEaseOut = "ease-out"
+	curveS       = "s-curve"
+)
+
+var (
+	pwmPeriod     = time.Duration(20) * time.Millisecond
+	rampStep      = time.Duration(50) * time.Millisecond
+	rampProgress  = time.Duration(1) * time.Second
+	rampsMutex    = sync.Mutex{}
+	runningRamps  = make(map[string]chan struct{})
+	errNotPwmLine = errors.New("gpio is not a pwm output")
+)
+
+type rampRequest struct {
+	Name     string  `json:"name"`
+	Target   float64 `json:"target"`
+	Duration string  `json:"duration"`
+	Curve    string  `json:"curve"`
+}
+
+// parseRamp reads the software PWM period (PWM_PERIOD), the ramp step (RAMP_STEP) and the interval of
+// the progress readings (RAMP_PROGRESS_INTERVAL).
+func parseRamp() {
+	for _, setting := range []struct {
+		env   string
+		value *time.Duration
+	}{
+		{"PWM_PERIOD", &pwmPeriod},
+		{"RAMP_STEP", &rampStep},
+		{"RAMP_PROGRESS_INTERVAL", &rampProgress},
+	} {
+		if d, err := time.ParseDuration(os.Getenv(setting.env)); err == nil && d > 0 {
+			*setting.value = d
+		} else {
+			log.Printf("Cannot parse %s. Picking default value %s...", setting.env, *setting.value)
+		}
+	}
+}
+
+// curve maps the elapsed fraction t of a ramp to the fraction of the duty change applied.
+func curve(name string, t float64) (float64, error) {
+	switch name {
+	case "", curveLinear:
+		return t, nil
+	case curveEaseIn:
+		return t * t, nil
+	case curveEaseOut:
+		return 1 - (1-t)*(1-t), nil
+	case curveS:
+		return t * t * (3 - 2*t), nil
+	}
+	return 0, fmt.Errorf("unknown ramp curve %q", name)
+}
+
+// startRamp moves the duty cycle of a pwm line to target over duration following the named curve.
+// A ramp already running on the line is cancelled and the new one starts from the current duty cycle.
+func (s *SimpleDriver) startRamp(name string, target float64, duration time.Duration, curveName string) (*Operation, error) {
+	g, ok := s.findGpio(name)
+	if !ok {
+		return nil, fmt.Errorf("%w %s", errUnknownLine, name)
+	}
+	if g.Role != RolePwm {
+		return nil, fmt.Errorf("%w: %s", errNotPwmLine, name)
+	}
+	if target < 0 || target > 1 {
+		return nil, fmt.Errorf("target duty %.3f out of range [0, 1]", target)
+	}
+	if _, err := curve(curveName, 0); err != nil {
+		return nil, err
+	}
+
+	rampsMutex.Lock()
+	if cancel, ok := runningRamps[name]; ok {
+		close(cancel)
+	}
+	cancel := make(chan struct{})
+	runningRamps[name] = cancel
+	rampsMutex.Unlock()
+
+	from, _ := g.Duty()
+	op := startOperation("ramp", name, duration)
+	audit("ramp-start", name, fmt.Sprintf("%s: %.3f to %.3f over %s (%s)", op.ID, from, target, duration, curveName))
+	go s.runRamp(g, op, from, target, duration, curveName, cancel)
+	return op, nil
+}
+
+func (s *SimpleDriver) runRamp(g *gpio.GPIO, op *Operation, from float64, target float64, duration time.Duration, curveName string, cancel chan struct{}) {
+	start := time.Now()
+	lastProgress := time.Time{}
+	step := time.NewTicker(rampStep)
+	defer step.Stop()
+	defer func() {
+		rampsMutex.Lock()
+		if runningRamps[g.Name] == cancel {
+			delete(runningRamps, g.Name)
+		}
+		rampsMutex.Unlock()
+	}()
+
+	for {
+		t := 1.0
+		if duration > 0 {
+			t = math.Min(1, float64(time.Since(start))/float64(duration))
+		}
+		k, _ := curve(curveName, t)
+		duty := from + (target-from)*k
+		if err := g.SetDuty(duty, g.PwmPeriod(pwmPeriod)); err != nil {
+			log.Printf("Cannot set duty cycle of gpio %s. Error: %s", g.Name, err)
+			op.finish(err)
+			s.pushRampProgress(g.Name, op.ID, duty, target, t)
+			return
+		}
+		if t >= 1 || (!metered() && time.Since(lastProgress) >= rampProgress) {
+			s.pushRampProgress(g.Name, op.ID, duty, target, t)
+			lastProgress = time.Now()
+		}
+		if t >= 1 {
+			audit("ramp-end", g.Name, fmt.Sprintf("%s: reached %.3f", op.ID, target))
+			op.finish(nil)
+			return
+		}
+		select {
+		case <-cancel:
+			audit("ramp-cancel", g.Name, fmt.Sprintf("%s: stopped at %.3f", op.ID, duty))
+			op.finish(errors.New("cancelled"))
+			s.pushRampProgress(g.Name, op.ID, duty, target, t)
+			return
+		case <-step.C:
+		}
+	}
+}
+
+// setDuty sets the duty cycle of a pwm line at once, cancelling the ramp running on it.
+func (s *SimpleDriver) setDuty(name string, duty float64, source string) error {
+	g, ok := s.findGpio(name)
+	if !ok {
+		return fmt.Errorf("%w %s", errUnknownLine, name)
+	}
+	if g.Role != RolePwm {
+		return fmt.Errorf("%w: %s", errNotPwmLine, name)
+	}
+	if err := checkExposure(g, source); err != nil {
+		return err
+	}
+	if err := checkLeader(); err != nil {
+		return err
+	}
+	cancelRamp(name)
+	if err := g.SetDuty(duty, g.PwmPeriod(pwmPeriod)); err != nil {
+		return err
+	}
+	audit("duty", name, fmt.Sprintf("%.1f%% by %s", duty*100, source))
+	return nil
+}
+
+// cancelRamp stops the ramp running on the line, leaving the duty cycle where it is.
+func cancelRamp(name string) bool {
+	rampsMutex.Lock()
+	defer rampsMutex.Unlock()
+	cancel, ok := runningRamps[name]
+	if ok {
+		close(cancel)
+		delete(runningRamps, name)
+	}
+	return ok
+}
+
+// pushRampProgress publishes the progress of a ramp as the RampProgress reading.
+func (s *SimpleDriver) pushRampProgress(name string, id string, duty float64, target float64, progress float64) {
+	payload, err := shapePayload(rampProgressResource, map[string]interface{}{
+		"name":      name,
+		"operation": id,
+		"duty":      duty,
+		"target":    target,
+		"progress":  progress,
+	})
+	if err != nil {
+		log.Printf("Cannot marshal ramp progress. Error: %s", err)
+		return
+	}
+	cv, err := sdkModels.NewCommandValue(rampProgressResource, common.ValueTypeString, string(payload))
+	if err != nil {
+		log.Printf("Cannot create ramp progress reading. Error: %s", err)
+		return
+	}
+	s.asyncCh <- &sdkModels.AsyncValues{
+		DeviceName:    deviceName(),
+		CommandValues: []*sdkModels.CommandValue{cv},
+	}
+}
+
+// handleRamp starts a ramp: {"name": "fan", "target": 0.8, "duration": "10s", "curve": "s-curve"}.
+// The response carries the operation ID to follow on the operations route.
+func (s *SimpleDriver) handleRamp(w http.ResponseWriter, r *http.Request) {
+	var req rampRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	duration, err := time.ParseDuration(req.Duration)
+	if err != nil || duration < 0 {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid duration %q", req.Duration))
+		return
+	}
+	op, err := s.startRamp(req.Name, req.Target, duration, req.Curve)
+	switch {
+	case errors.Is(err, errUnknownLine):
+		writeError(w, http.StatusNotFound, err)
+	case errors.Is(err, errNotPwmLine):
+		writeError(w, http.StatusConflict, err)
+	case err != nil:
+		writeError(w, http.StatusBadRequest, err)
+	default:
+		writeJSON(w, http.StatusOK, map[string]interface{}{"operation": op.ID, "name": req.Name, "target": req.Target})
+	}
+}
+
+func (s *SimpleDriver) handleRampCancel(w http.ResponseWriter, r *http.Request) {
+	var req rampRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if !cancelRamp(req.Name) {
+		writeError(w, http.StatusNotFound, fmt.Errorf("no ramp running on gpio %s", req.Name))
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"name": req.Name})
+}
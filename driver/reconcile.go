@@ -0,0 +1,80 @@
+package driver
+
+import (
+	"log"
+	"os"
+	"strconv"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/common"
+
+	sdkModels "github.com/edgexfoundry/device-sdk-go/v2/pkg/models"
+)
+
+const stateDiscrepancyResource = "StateDiscrepancy"
+
+// StateDiscrepancy is an output line whose level at startup differs from the state assumed for it.
+type StateDiscrepancy struct {
+	Name    string `json:"name"`
+	Assumed bool   `json:"assumed"`
+	Actual  bool   `json:"actual"`
+}
+
+var (
+	stateDiscrepancies []StateDiscrepancy
+	unreadableLines    []string
+)
+
+// reconcileLineStates reads back every output line at startup and sets its State from the actual
+// level, instead of assuming it off. The assumed state is the last value journaled for the line, off
+// without a journal. Lines that can't be read back keep the assumed state. STARTUP_RECONCILE=false
+// disables it.
+func (s *SimpleDriver) reconcileLineStates() {
+	if enabled, err := strconv.ParseBool(os.Getenv("STARTUP_RECONCILE")); err == nil && !enabled {
+		return
+	}
+	for i := range s.GpioList.Gpio {
+		g := &s.GpioList.Gpio[i]
+		if !isOutputRole(g.Role) || g.Role == RoleHeartbeat || g.Role == RoleWatchdog || g.Role == RolePwm {
+			continue
+		}
+		assumed := g.State
+		if value, ok := journaledValues[g.Name]; ok {
+			assumed = value == 1
+		}
+		value, err := g.ReadBack()
+		if err != nil {
+			log.Printf("Cannot read back gpio %s at startup, assuming %t. Error: %s", g.Name, assumed, err)
+			unreadableLines = append(unreadableLines, g.Name)
+			g.State = assumed
+			continue
+		}
+		g.State = value == 1
+		recordTransition(g.Name, g.State)
+		if g.State != assumed {
+			log.Printf("gpio %s is %t at startup, %t was assumed", g.Name, g.State, assumed)
+			audit("state-discrepancy", g.Name, "startup read back differs from the assumed state")
+			stateDiscrepancies = append(stateDiscrepancies, StateDiscrepancy{Name: g.Name, Assumed: assumed, Actual: g.State})
+		}
+	}
+}
+
+// pushStateDiscrepancies publishes the discrepancies found at startup as a reading.
+func (s *SimpleDriver) pushStateDiscrepancies() {
+	if len(stateDiscrepancies) == 0 {
+		return
+	}
+	payload, err := shapePayload(stateDiscrepancyResource, stateDiscrepancies)
+	if err != nil {
+		log.Printf("Cannot marshal state discrepancies. Error: %s", err)
+		return
+	}
+	cv, err := sdkModels.NewCommandValue(stateDiscrepancyResource, common.ValueTypeString, string(payload))
+	if err != nil {
+		log.Printf("Cannot create state discrepancy reading. Error: %s", err)
+		return
+	}
+	s.asyncCh <- &sdkModels.AsyncValues{
+		DeviceName:    deviceName(),
+		CommandValues: []*sdkModels.CommandValue{cv},
+	}
+}
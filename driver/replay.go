@@ -0,0 +1,117 @@
+package driver
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/edgexfoundry/device-gpiod/sequence"
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/common"
+)
+
+const (
+	replayRoute              = common.ApiBase + "/replay"
+	DEFAULT_REPLAY_TOLERANCE = time.Duration(2) * time.Second
+)
+
+var errNoCycle = errors.New("no completed cycle recorded")
+
+// replayCycle replays the recorded cycle started at or after from (the last one when from is zero)
+// through the cycle sequence on a virtual clock, and compares the actuations it should have
+// performed with the ones in the timeline. The sequence runs with the current timers and flags.
+func replayCycle(from time.Time, tolerance time.Duration) (map[string]interface{}, error) {
+	pumps := map[string]bool{triggerLine("START_TRIGGER"): true, triggerLine("STANDBY_TRIGGER"): true}
+
+	timelineMutex.Lock()
+	var pump string
+	var run TimelineInterval
+	for line, t := range timeline {
+		if !pumps[line] {
+			continue
+		}
+		for _, interval := range t.Intervals {
+			if interval.Value != 1 || interval.To.IsZero() || interval.From.Before(from) {
+				continue
+			}
+			if pump == "" || (from.IsZero() && interval.From.After(run.From)) || (!from.IsZero() && interval.From.Before(run.From)) {
+				pump, run = line, interval
+			}
+		}
+	}
+	timelineMutex.Unlock()
+	if pump == "" {
+		return nil, errNoCycle
+	}
+
+	pumped := run.To.Sub(run.From)
+	recorder := &sequence.Recorder{Clock: pumped, Environment: sequenceEnv()}
+	if err := sequence.Run(cycleSequence, recorder); err != nil {
+		return nil, err
+	}
+	expected := append([]sequence.Actuation{
+		{At: 0, Phase: phasePump, Line: pump, On: true},
+		{At: pumped, Phase: phasePump, Line: pump, On: false},
+	}, recorder.Actuations...)
+
+	lines := make(map[string]bool)
+	for _, a := range expected {
+		lines[a.Line] = true
+	}
+	end := run.From.Add(recorder.Clock + tolerance)
+	var actual []sequence.Actuation
+	timelineMutex.Lock()
+	for line, t := range timeline {
+		if !lines[line] {
+			continue
+		}
+		for _, interval := range t.Intervals {
+			if interval.From.Before(run.From) || interval.From.After(end) {
+				continue
+			}
+			actual = append(actual, sequence.Actuation{At: interval.From.Sub(run.From), Line: line, On: interval.Value == 1})
+		}
+	}
+	timelineMutex.Unlock()
+	sort.SliceStable(actual, func(i, j int) bool { return actual[i].At < actual[j].At })
+
+	differences := sequence.Compare(expected, actual, tolerance)
+	return map[string]interface{}{
+		"sequence":    cycleSequence.Name,
+		"startedAt":   run.From,
+		"expected":    expected,
+		"actual":      actual,
+		"differences": differences,
+		"identical":   len(differences) == 0,
+	}, nil
+}
+
+// handleReplay replays a recorded cycle, chosen by the from (RFC3339) query parameter, against the
+// cycle sequence. The tolerance query parameter bounds the accepted timing drift (default 2s).
+func (s *SimpleDriver) handleReplay(w http.ResponseWriter, r *http.Request) {
+	var from time.Time
+	var err error
+	if value := r.URL.Query().Get("from"); value != "" {
+		if from, err = time.Parse(time.RFC3339, value); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid from %q", value))
+			return
+		}
+	}
+	tolerance := DEFAULT_REPLAY_TOLERANCE
+	if value := r.URL.Query().Get("tolerance"); value != "" {
+		if tolerance, err = time.ParseDuration(value); err != nil || tolerance < 0 {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid tolerance %q", value))
+			return
+		}
+	}
+	result, err := replayCycle(from, tolerance)
+	switch {
+	case errors.Is(err, errNoCycle):
+		writeError(w, http.StatusNotFound, err)
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, err)
+	default:
+		writeJSON(w, http.StatusOK, result)
+	}
+}
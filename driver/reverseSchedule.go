@@ -0,0 +1,119 @@
+package driver
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/common"
+
+	sdkModels "github.com/edgexfoundry/device-sdk-go/v2/pkg/models"
+)
+
+const (
+	runtimeSinceReverseResource = "RuntimeSinceReverse"
+	reverseResetRoute           = common.ApiBase + "/cycle/reverse/reset"
+)
+
+var (
+	// reverseAfter runs the reverse phase once the pump ran that long since the last one, every
+	// cycle when 0
+	reverseAfter  time.Duration
+	runtimeSince  time.Duration
+	cycleReverses bool
+	cycleReversed bool
+)
+
+// loadReverseSchedule reads REVERSE_AFTER (default 0, reverse every cycle). The pump runtime since
+// the last reverse is restored from the pipeline state.
+func loadReverseSchedule() {
+	if d, err := time.ParseDuration(os.Getenv("REVERSE_AFTER")); err == nil && d >= 0 {
+		reverseAfter = d
+	} else {
+		log.Printf("Cannot parse REVERSE_AFTER. Picking default value %s...", reverseAfter)
+	}
+	if restoredPipeline != nil {
+		runtimeSince = time.Duration(restoredPipeline.RuntimeSinceReverse) * time.Second
+	}
+}
+
+// reverseDue tells whether a cycle pumping for runFor seconds reaches the runtime threshold of the
+// reverse phase. Must be called holding nextCycleMutex.
+func reverseDue(runFor int64) bool {
+	return *enableReverse && runtimeSince+time.Duration(runFor)*time.Second >= reverseAfter
+}
+
+// countReverseRuntime accumulates the pump runtime of a finished cycle, restarting from zero when it
+// reversed. Must be called holding nextCycleMutex.
+func countReverseRuntime(runFor int64, reversed bool) {
+	if reversed {
+		runtimeSince = 0
+	} else {
+		runtimeSince += time.Duration(runFor) * time.Second
+	}
+	notePipelineReverseRuntime(runtimeSince)
+}
+
+// noteReverseDone records that the reverse phase of the running cycle completed.
+func noteReverseDone() {
+	nextCycleMutex.Lock()
+	defer nextCycleMutex.Unlock()
+	cycleReversed = true
+}
+
+// reverseEnabled tells whether the reverse phase runs in the current cycle, or in the next one of
+// the configured pump duration outside of a cycle.
+func reverseEnabled() bool {
+	nextCycleMutex.Lock()
+	defer nextCycleMutex.Unlock()
+	if inCycle {
+		return cycleReverses
+	}
+	return reverseDue(*pumpTimer)
+}
+
+func pumpRuntimeSinceReverse() time.Duration {
+	nextCycleMutex.Lock()
+	defer nextCycleMutex.Unlock()
+	return runtimeSince
+}
+
+// resetReverseRuntime restarts the count of the pump runtime since the last reverse, e.g. after the
+// circuit was serviced.
+func resetReverseRuntime(source string) {
+	nextCycleMutex.Lock()
+	previous := runtimeSince
+	runtimeSince = 0
+	notePipelineReverseRuntime(0)
+	nextCycleMutex.Unlock()
+	audit("reverse-runtime-reset", "cycle", fmt.Sprintf("%s reset by %s", previous.Round(time.Second), source))
+}
+
+func readRuntimeSinceReverse(req sdkModels.CommandRequest) (*sdkModels.CommandValue, error) {
+	return sdkModels.NewCommandValue(req.DeviceResourceName, common.ValueTypeFloat32, float32(pumpRuntimeSinceReverse().Hours()))
+}
+
+// writeRuntimeSinceReverse resets the runtime; 0 is the only value accepted.
+func writeRuntimeSinceReverse(param *sdkModels.CommandValue) error {
+	v, err := param.Float32Value()
+	if err != nil {
+		return err
+	}
+	if v != 0 {
+		return fmt.Errorf("%s can only be reset to 0", runtimeSinceReverseResource)
+	}
+	resetReverseRuntime("core-command")
+	return nil
+}
+
+func (s *SimpleDriver) handleReverseReset(w http.ResponseWriter, r *http.Request) {
+	if !*enableReverse {
+		writeError(w, http.StatusConflict, errors.New("reverse circuit not available"))
+		return
+	}
+	resetReverseRuntime("api")
+	writeJSON(w, http.StatusOK, map[string]interface{}{"runtimeSinceReverse": "0s", "reverseAfter": reverseAfter.String()})
+}
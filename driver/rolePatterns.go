@@ -0,0 +1,113 @@
+package driver
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"regexp"
+
+	"github.com/edgexfoundry/device-gpiod/gpio"
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/common"
+)
+
+const rolesMatchRoute = common.ApiBase + "/roles/match"
+
+// RolePattern selects lines by name with either a glob or a regular expression.
+type RolePattern struct {
+	Glob  string `yaml:"glob" json:"glob,omitempty"`
+	Regex string `yaml:"regex" json:"regex,omitempty"`
+	re    *regexp.Regexp
+}
+
+var (
+	lightPatterns []RolePattern
+)
+
+func (p *RolePattern) compile() error {
+	switch {
+	case p.Glob != "" && p.Regex != "":
+		return errors.New("pattern must define either glob or regex, not both")
+	case p.Glob != "":
+		if _, err := path.Match(p.Glob, ""); err != nil {
+			return fmt.Errorf("invalid glob %q: %s", p.Glob, err)
+		}
+	case p.Regex != "":
+		re, err := regexp.Compile(p.Regex)
+		if err != nil {
+			return fmt.Errorf("invalid regex %q: %s", p.Regex, err)
+		}
+		p.re = re
+	default:
+		return errors.New("pattern must define glob or regex")
+	}
+	return nil
+}
+
+func (p *RolePattern) match(name string) bool {
+	if p.re != nil {
+		return p.re.MatchString(name)
+	}
+	matched, _ := path.Match(p.Glob, name)
+	return matched
+}
+
+// compileLightPatterns validates the light patterns of the configuration file. When none are defined
+// the legacy LIGHT env var is used as a substring glob; when that is unset no line is a light.
+func compileLightPatterns() error {
+	lightPatterns = driverConfig.Lights
+	if len(lightPatterns) == 0 && os.Getenv("LIGHT") != "" {
+		lightPatterns = []RolePattern{{Glob: "*" + os.Getenv("LIGHT") + "*"}}
+	}
+	for i := range lightPatterns {
+		if err := lightPatterns[i].compile(); err != nil {
+			return fmt.Errorf("light pattern %d: %s", i, err)
+		}
+	}
+	return nil
+}
+
+func matchLight(name string) bool {
+	for i := range lightPatterns {
+		if lightPatterns[i].match(name) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchingLines(patterns []RolePattern, gpios []gpio.GPIO) []string {
+	matches := []string{}
+	for _, g := range gpios {
+		for i := range patterns {
+			if patterns[i].match(g.Name) {
+				matches = append(matches, g.Name)
+				break
+			}
+		}
+	}
+	return matches
+}
+
+// reportLightMatches logs which configured lines are handled as lights.
+func (s *SimpleDriver) reportLightMatches() {
+	matches := matchingLines(lightPatterns, s.GpioList.Gpio)
+	log.Printf("Light patterns %v match lines %v", lightPatterns, matches)
+	startupReport.Lights = matches
+}
+
+// handleRolesMatch is a dry run of a glob or regex pattern against the configured lines, without
+// changing the active patterns.
+func (s *SimpleDriver) handleRolesMatch(w http.ResponseWriter, r *http.Request) {
+	pattern := RolePattern{Glob: r.URL.Query().Get("glob"), Regex: r.URL.Query().Get("regex")}
+	if err := pattern.compile(); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"pattern": pattern,
+		"matches": matchingLines([]RolePattern{pattern}, s.GpioList.Gpio),
+	})
+}
@@ -0,0 +1,313 @@
+package driver
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/edgexfoundry/device-gpiod/gpio"
+	"github.com/edgexfoundry/device-sdk-go/v2/pkg/service"
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/common"
+)
+
+const (
+	yieldRoute  = common.ApiBase + "/yield"
+	resumeRoute = common.ApiBase + "/resume"
+	auditRoute  = common.ApiBase + "/audit"
+)
+
+var (
+	maxYield = time.Duration(30) * time.Minute
+)
+
+type yieldRequest struct {
+	Name     string `json:"name"`
+	Duration string `json:"duration"`
+}
+
+// registerRoutes adds the service specific REST routes to the SDK router.
+func (s *SimpleDriver) registerRoutes() error {
+	ds := service.RunningService()
+
+	if d, err := time.ParseDuration(os.Getenv("YIELD_MAX")); err == nil {
+		maxYield = d
+	}
+	parseIdempotency()
+	parseOverrideMax()
+	parseRamp()
+	parseFailover()
+
+	if err := addRoute(ds, yieldRoute, routeDoc{Summary: "Yield a line to external tools for a bounded window", Request: yieldRequest{}}, idempotentRoute(s.handleYield), http.MethodPost); err != nil {
+		return fmt.Errorf("cannot add route %s: %s", yieldRoute, err)
+	}
+	if err := addRoute(ds, resumeRoute, routeDoc{Summary: "End a yield window early", Request: yieldRequest{}}, idempotentRoute(s.handleResume), http.MethodPost); err != nil {
+		return fmt.Errorf("cannot add route %s: %s", resumeRoute, err)
+	}
+	if err := addRoute(ds, handoffRoute, routeDoc{Summary: "Lines handed over to local processes; POST hands lines over for a lease", Request: handoffRequest{}}, idempotentRoute(s.handleHandoff), http.MethodGet, http.MethodPost); err != nil {
+		return fmt.Errorf("cannot add route %s: %s", handoffRoute, err)
+	}
+	if err := addRoute(ds, handoffRenewRoute, routeDoc{Summary: "Extend the lease of a handoff", Request: handoffIDRequest{}}, idempotentRoute(s.handleHandoffRenew), http.MethodPost); err != nil {
+		return fmt.Errorf("cannot add route %s: %s", handoffRenewRoute, err)
+	}
+	if err := addRoute(ds, handoffReturnRoute, routeDoc{Summary: "Return the lines of a handoff", Request: handoffIDRequest{}}, idempotentRoute(s.handleHandoffReturn), http.MethodPost); err != nil {
+		return fmt.Errorf("cannot add route %s: %s", handoffReturnRoute, err)
+	}
+	if err := addRoute(ds, statusRoute, routeDoc{Summary: "Startup report and current status"}, s.handleStatus, http.MethodGet); err != nil {
+		return fmt.Errorf("cannot add route %s: %s", statusRoute, err)
+	}
+	if err := addRoute(ds, rolesMatchRoute, routeDoc{Summary: "Lines matched by a role pattern", Query: []string{"glob", "regex"}}, s.handleRolesMatch, http.MethodGet); err != nil {
+		return fmt.Errorf("cannot add route %s: %s", rolesMatchRoute, err)
+	}
+	if err := addRoute(ds, alarmsRoute, routeDoc{Summary: "Security alarms"}, s.handleAlarms, http.MethodGet); err != nil {
+		return fmt.Errorf("cannot add route %s: %s", alarmsRoute, err)
+	}
+	if err := addRoute(ds, alarmsAckRoute, routeDoc{Summary: "Acknowledge a latched alarm", Request: alarmAckRequest{}}, idempotentRoute(s.handleAlarmAck), http.MethodPost); err != nil {
+		return fmt.Errorf("cannot add route %s: %s", alarmsAckRoute, err)
+	}
+	if err := addRoute(ds, stateExportRoute, routeDoc{Summary: "Export the persisted state"}, s.handleStateExport, http.MethodGet); err != nil {
+		return fmt.Errorf("cannot add route %s: %s", stateExportRoute, err)
+	}
+	if err := addRoute(ds, stateImportRoute, routeDoc{Summary: "Import a state archive"}, idempotentRoute(s.handleStateImport), http.MethodPost); err != nil {
+		return fmt.Errorf("cannot add route %s: %s", stateImportRoute, err)
+	}
+	if err := addRoute(ds, auditRoute, routeDoc{Summary: "Recent audit entries"}, s.handleAudit, http.MethodGet); err != nil {
+		return fmt.Errorf("cannot add route %s: %s", auditRoute, err)
+	}
+	if err := addRoute(ds, auditVerifyRoute, routeDoc{Summary: "Verify the hash chain of the audit log"}, s.handleAuditVerify, http.MethodGet); err != nil {
+		return fmt.Errorf("cannot add route %s: %s", auditVerifyRoute, err)
+	}
+	if err := addRoute(ds, configReloadRoute, routeDoc{Summary: "Reload the configuration file"}, idempotentRoute(s.handleConfigReload), http.MethodPost); err != nil {
+		return fmt.Errorf("cannot add route %s: %s", configReloadRoute, err)
+	}
+	if err := addRoute(ds, configConfirmRoute, routeDoc{Summary: "Confirm the staged configuration"}, idempotentRoute(s.handleConfigConfirm), http.MethodPost); err != nil {
+		return fmt.Errorf("cannot add route %s: %s", configConfirmRoute, err)
+	}
+	if err := addRoute(ds, configStatusRoute, routeDoc{Summary: "Staged configuration status"}, s.handleConfigStatus, http.MethodGet); err != nil {
+		return fmt.Errorf("cannot add route %s: %s", configStatusRoute, err)
+	}
+	if err := addRoute(ds, operationsRoute, routeDoc{Summary: "Long-running operations", Query: []string{"id"}}, s.handleOperations, http.MethodGet); err != nil {
+		return fmt.Errorf("cannot add route %s: %s", operationsRoute, err)
+	}
+	if err := addRoute(ds, rampRoute, routeDoc{Summary: "Ramp a pwm line to a duty cycle", Request: rampRequest{}}, idempotentRoute(s.handleRamp), http.MethodPost); err != nil {
+		return fmt.Errorf("cannot add route %s: %s", rampRoute, err)
+	}
+	if err := addRoute(ds, rampCancelRoute, routeDoc{Summary: "Cancel a running ramp", Request: rampRequest{}}, idempotentRoute(s.handleRampCancel), http.MethodPost); err != nil {
+		return fmt.Errorf("cannot add route %s: %s", rampCancelRoute, err)
+	}
+	if err := addRoute(ds, indicatorRoute, routeDoc{Summary: "Indicator state; POST raises or clears a state", Request: indicatorRequest{}}, idempotentRoute(s.handleIndicator), http.MethodGet, http.MethodPost); err != nil {
+		return fmt.Errorf("cannot add route %s: %s", indicatorRoute, err)
+	}
+	if err := addRoute(ds, profilesRoute, routeDoc{Summary: "Configuration profiles"}, s.handleProfiles, http.MethodGet); err != nil {
+		return fmt.Errorf("cannot add route %s: %s", profilesRoute, err)
+	}
+	if err := addRoute(ds, profileActivateRoute, routeDoc{Summary: "Activate a configuration profile", Request: profileRequest{}}, idempotentRoute(s.handleProfileActivate), http.MethodPost); err != nil {
+		return fmt.Errorf("cannot add route %s: %s", profileActivateRoute, err)
+	}
+	if err := addRoute(ds, pumpsRoute, routeDoc{Summary: "Duty and standby pumps"}, s.handlePumps, http.MethodGet); err != nil {
+		return fmt.Errorf("cannot add route %s: %s", pumpsRoute, err)
+	}
+	if err := addRoute(ds, pumpPinRoute, routeDoc{Summary: "Pin the duty pump", Request: pinRequest{}}, idempotentRoute(s.handlePumpPin), http.MethodPost); err != nil {
+		return fmt.Errorf("cannot add route %s: %s", pumpPinRoute, err)
+	}
+	if err := addRoute(ds, overrideRoute, routeDoc{Summary: "Force a line for a bounded duration", Request: overrideRequest{}}, idempotentRoute(s.handleOverride), http.MethodPost); err != nil {
+		return fmt.Errorf("cannot add route %s: %s", overrideRoute, err)
+	}
+	if err := addRoute(ds, overrideCancelRoute, routeDoc{Summary: "End an override early", Request: overrideRequest{}}, idempotentRoute(s.handleOverrideCancel), http.MethodPost); err != nil {
+		return fmt.Errorf("cannot add route %s: %s", overrideCancelRoute, err)
+	}
+	if err := addRoute(ds, scheduleRoute, routeDoc{Summary: "Scheduled commands; POST schedules a write", Request: scheduleRequest{}}, idempotentRoute(s.handleSchedule), http.MethodGet, http.MethodPost); err != nil {
+		return fmt.Errorf("cannot add route %s: %s", scheduleRoute, err)
+	}
+	if err := addRoute(ds, scheduleCancelRoute, routeDoc{Summary: "Cancel a scheduled command", Request: scheduleRequest{}}, idempotentRoute(s.handleScheduleCancel), http.MethodPost); err != nil {
+		return fmt.Errorf("cannot add route %s: %s", scheduleCancelRoute, err)
+	}
+	if err := addRoute(ds, modbusMapRoute, routeDoc{Summary: "Modbus register map"}, s.handleModbusMap, http.MethodGet); err != nil {
+		return fmt.Errorf("cannot add route %s: %s", modbusMapRoute, err)
+	}
+	if err := addRoute(ds, streamRoute, routeDoc{Summary: "Server-sent event stream"}, s.handleStream, http.MethodGet); err != nil {
+		return fmt.Errorf("cannot add route %s: %s", streamRoute, err)
+	}
+	if err := addRoute(ds, timelineRoute, routeDoc{Summary: "Line transitions timeline", Query: []string{"name", "from", "to"}}, s.handleTimeline, http.MethodGet); err != nil {
+		return fmt.Errorf("cannot add route %s: %s", timelineRoute, err)
+	}
+	if err := addRoute(ds, timeSeriesRoute, routeDoc{Summary: "Recorded readings", Query: []string{"resource", "from", "to", "downsample"}}, s.handleTimeSeries, http.MethodGet); err != nil {
+		return fmt.Errorf("cannot add route %s: %s", timeSeriesRoute, err)
+	}
+	if err := addRoute(ds, exportRoute, routeDoc{Summary: "Export readings or audit entries as CSV", Request: exportRequest{}}, s.handleExport, http.MethodPost); err != nil {
+		return fmt.Errorf("cannot add route %s: %s", exportRoute, err)
+	}
+	if err := addRoute(ds, replayRoute, routeDoc{Summary: "Replay a recorded cycle against the cycle sequence", Query: []string{"from", "tolerance"}}, s.handleReplay, http.MethodGet); err != nil {
+		return fmt.Errorf("cannot add route %s: %s", replayRoute, err)
+	}
+	if err := addRoute(ds, planRoute, routeDoc{Summary: "Cycle plan"}, s.handlePlan, http.MethodGet); err != nil {
+		return fmt.Errorf("cannot add route %s: %s", planRoute, err)
+	}
+	if err := addRoute(ds, latencyRoute, routeDoc{Summary: "Actuation latency diagnostics"}, s.handleLatency, http.MethodGet); err != nil {
+		return fmt.Errorf("cannot add route %s: %s", latencyRoute, err)
+	}
+	if err := addRoute(ds, latencyBenchmarkRoute, routeDoc{Summary: "Loop an output to its feedback input and measure the toggle to event latency", Request: latencyBenchmarkRequest{}}, idempotentRoute(s.handleLatencyBenchmark), http.MethodPost); err != nil {
+		return fmt.Errorf("cannot add route %s: %s", latencyBenchmarkRoute, err)
+	}
+	if err := addRoute(ds, hardwareFingerprintRoute, routeDoc{Summary: "Hardware fingerprint of the board; POST accepts the current board as the reference"}, idempotentRoute(s.handleHardwareFingerprint), http.MethodGet, http.MethodPost); err != nil {
+		return fmt.Errorf("cannot add route %s: %s", hardwareFingerprintRoute, err)
+	}
+	if err := addRoute(ds, lineInfoRoute, routeDoc{Summary: "Line info diagnostics"}, s.handleLineInfoDiagnostics, http.MethodGet); err != nil {
+		return fmt.Errorf("cannot add route %s: %s", lineInfoRoute, err)
+	}
+	if err := addRoute(ds, budgetsRoute, routeDoc{Summary: "Memory, goroutine and history budgets"}, s.handleBudgets, http.MethodGet); err != nil {
+		return fmt.Errorf("cannot add route %s: %s", budgetsRoute, err)
+	}
+	if err := addRoute(ds, maintenanceRoute, routeDoc{Summary: "Actuator baselines and maintenance recommendations"}, s.handleMaintenance, http.MethodGet); err != nil {
+		return fmt.Errorf("cannot add route %s: %s", maintenanceRoute, err)
+	}
+	if err := addRoute(ds, maintenanceResetRoute, routeDoc{Summary: "Reset the baseline of a serviced actuator", Request: maintenanceResetRequest{}}, idempotentRoute(s.handleMaintenanceReset), http.MethodPost); err != nil {
+		return fmt.Errorf("cannot add route %s: %s", maintenanceResetRoute, err)
+	}
+	if err := addRoute(ds, nextCycleRoute, routeDoc{Summary: "Next cycle override; POST overrides the parameters of the next cycle only", Request: NextCycle{}}, idempotentRoute(s.handleNextCycle), http.MethodGet, http.MethodPost); err != nil {
+		return fmt.Errorf("cannot add route %s: %s", nextCycleRoute, err)
+	}
+	if err := addRoute(ds, nextCycleCancelRoute, routeDoc{Summary: "Drop the override of the next cycle"}, idempotentRoute(s.handleNextCycleCancel), http.MethodPost); err != nil {
+		return fmt.Errorf("cannot add route %s: %s", nextCycleCancelRoute, err)
+	}
+	if err := addRoute(ds, reverseResetRoute, routeDoc{Summary: "Reset the pump runtime counted towards the next reverse"}, idempotentRoute(s.handleReverseReset), http.MethodPost); err != nil {
+		return fmt.Errorf("cannot add route %s: %s", reverseResetRoute, err)
+	}
+	if err := addRoute(ds, consumablesRoute, routeDoc{Summary: "Estimated inventory of the cleaning agents"}, s.handleConsumables, http.MethodGet); err != nil {
+		return fmt.Errorf("cannot add route %s: %s", consumablesRoute, err)
+	}
+	if err := addRoute(ds, consumablesRefillRoute, routeDoc{Summary: "Acknowledge the refill of a cleaning agent", Request: refillRequest{}}, idempotentRoute(s.handleConsumablesRefill), http.MethodPost); err != nil {
+		return fmt.Errorf("cannot add route %s: %s", consumablesRefillRoute, err)
+	}
+	if err := addRoute(ds, cycleScheduleRoute, routeDoc{Summary: "Cycle schedule, next and last scheduled cycles"}, s.handleCycleSchedule, http.MethodGet); err != nil {
+		return fmt.Errorf("cannot add route %s: %s", cycleScheduleRoute, err)
+	}
+	if err := addRoute(ds, errorCodesRoute, routeDoc{Summary: "API error codes with their text in the configured locale"}, s.handleErrorCodes, http.MethodGet); err != nil {
+		return fmt.Errorf("cannot add route %s: %s", errorCodesRoute, err)
+	}
+	if err := addRoute(ds, metricsRoute, routeDoc{Summary: "GPIO metrics in the Prometheus text format"}, s.handleMetrics, http.MethodGet); err != nil {
+		return fmt.Errorf("cannot add route %s: %s", metricsRoute, err)
+	}
+	if err := addRoute(ds, leaderRoute, routeDoc{Summary: "Leadership of the active/standby pair"}, s.handleLeader, http.MethodGet); err != nil {
+		return fmt.Errorf("cannot add route %s: %s", leaderRoute, err)
+	}
+	if err := addRoute(ds, concurrencyRoute, routeDoc{Summary: "Lines running and queued starts of the concurrency limits"}, s.handleConcurrency, http.MethodGet); err != nil {
+		return fmt.Errorf("cannot add route %s: %s", concurrencyRoute, err)
+	}
+	if err := addRoute(ds, coordinationRoute, routeDoc{Summary: "Clean lock shared with the other gateways"}, s.handleCoordination, http.MethodGet); err != nil {
+		return fmt.Errorf("cannot add route %s: %s", coordinationRoute, err)
+	}
+	if err := addRoute(ds, reportingRoute, routeDoc{Summary: "Reporting profile; POST switches it to normal, metered or auto", Request: reportingRequest{}}, idempotentRoute(s.handleReporting), http.MethodGet, http.MethodPost); err != nil {
+		return fmt.Errorf("cannot add route %s: %s", reportingRoute, err)
+	}
+	if err := addRoute(ds, powerRoute, routeDoc{Summary: "Power mode, supply and battery level"}, s.handlePower, http.MethodGet); err != nil {
+		return fmt.Errorf("cannot add route %s: %s", powerRoute, err)
+	}
+	if err := addRoute(ds, healthRoute, routeDoc{Summary: "Status of the health probes"}, s.handleHealthProbes, http.MethodGet); err != nil {
+		return fmt.Errorf("cannot add route %s: %s", healthRoute, err)
+	}
+	if err := addRoute(ds, deratingRoute, routeDoc{Summary: "Temperature of the electronics and derating state"}, s.handleDerating, http.MethodGet); err != nil {
+		return fmt.Errorf("cannot add route %s: %s", deratingRoute, err)
+	}
+	if err := addRoute(ds, simulatorEdgeRoute, routeDoc{Summary: "Change the level of an input of the simulated GPIO backend", Request: simulatorEdgeRequest{}}, s.handleSimulatorEdge, http.MethodPost); err != nil {
+		return fmt.Errorf("cannot add route %s: %s", simulatorEdgeRoute, err)
+	}
+	if err := addRoute(ds, capabilitiesRoute, routeDoc{Summary: "Optional subsystems and their versions"}, s.handleCapabilities, http.MethodGet); err != nil {
+		return fmt.Errorf("cannot add route %s: %s", capabilitiesRoute, err)
+	}
+	if err := addRoute(ds, openAPIRoute, routeDoc{Summary: "OpenAPI document of the custom routes"}, s.handleOpenAPI, http.MethodGet); err != nil {
+		return fmt.Errorf("cannot add route %s: %s", openAPIRoute, err)
+	}
+	if dashboardEnabled() {
+		if err := addRoute(ds, dashboardRoute, routeDoc{Summary: "Dashboard"}, s.handleDashboard, http.MethodGet); err != nil {
+			return fmt.Errorf("cannot add route %s: %s", dashboardRoute, err)
+		}
+		if err := addRoute(ds, dashboardLabelsRoute, routeDoc{Summary: "Dashboard labels in the configured locale"}, s.handleDashboardLabels, http.MethodGet); err != nil {
+			return fmt.Errorf("cannot add route %s: %s", dashboardLabelsRoute, err)
+		}
+		log.Printf("Dashboard available at %s", dashboardRoute)
+	}
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set(common.ContentType, common.ContentTypeJSON)
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("Cannot encode HTTP response. Error: %s", err)
+	}
+}
+
+// writeError answers with the error, its stable code and the text of the code in the configured
+// locale, so clients branch on the code rather than on the message.
+func writeError(w http.ResponseWriter, status int, err error) {
+	code := errorCode(err, status)
+	body := map[string]interface{}{"statusCode": status, "code": code, "message": err.Error()}
+	if text := errorText(code); text != "" {
+		body["text"] = text
+	}
+	writeJSON(w, status, body)
+}
+
+func (s *SimpleDriver) findGpio(name string) (*gpio.GPIO, bool) {
+	for i := range s.GpioList.Gpio {
+		if s.GpioList.Gpio[i].Name == name {
+			return &s.GpioList.Gpio[i], true
+		}
+	}
+	return nil, false
+}
+
+// handleYield releases a line to gpioset/gpioget for a bounded window.
+func (s *SimpleDriver) handleYield(w http.ResponseWriter, r *http.Request) {
+	var req yieldRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	g, ok := s.findGpio(req.Name)
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("unknown gpio %s", req.Name))
+		return
+	}
+	duration, err := time.ParseDuration(req.Duration)
+	if err != nil || duration <= 0 || duration > maxYield {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("duration must be between 0 and %s", maxYield))
+		return
+	}
+	err = g.Yield(duration, func() {
+		audit("yield-end", req.Name, "line re-acquired")
+	})
+	if err != nil {
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+	audit("yield-start", req.Name, fmt.Sprintf("line yielded for %s", duration))
+	writeJSON(w, http.StatusOK, map[string]interface{}{"name": req.Name, "until": time.Now().Add(duration)})
+}
+
+// handleResume ends a yield window before its expiry.
+func (s *SimpleDriver) handleResume(w http.ResponseWriter, r *http.Request) {
+	var req yieldRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	g, ok := s.findGpio(req.Name)
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("unknown gpio %s", req.Name))
+		return
+	}
+	if err := g.Resume(); err != nil {
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+	audit("yield-end", req.Name, "line resumed by request")
+	writeJSON(w, http.StatusOK, map[string]interface{}{"name": req.Name})
+}
+
+func (s *SimpleDriver) handleAudit(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, recentAudit())
+}
@@ -0,0 +1,79 @@
+package driver
+
+import (
+	"log"
+	"time"
+)
+
+// isOutputRole reports whether lines with the given role are driven by the service.
+func isOutputRole(role string) bool {
+	switch role {
+	case RoleSpare, RoleTamper, RolePowerFail, RoleFeedback, RoleLockout, RoleInput:
+		return false
+	}
+	return true
+}
+
+// driveSafeState drives every output line to its safe state (low unless safe_state says otherwise) and
+// returns how long it took. When exiting, the lines with hold_on_exit keep their level.
+func (s *SimpleDriver) driveSafeState(reason string, exiting bool) time.Duration {
+	start := time.Now()
+	for i := range s.GpioList.Gpio {
+		g := &s.GpioList.Gpio[i]
+		if !isOutputRole(g.Role) || g.Role == RoleHeartbeat || g.Role == RoleWatchdog || isSignalLine(g.Name) {
+			continue
+		}
+		if exiting && g.HoldOnExit {
+			continue
+		}
+		if _, overridden := g.Overridden(); overridden {
+			// Safe state takes precedence over any override
+			if err := g.Revert(); err != nil {
+				log.Printf("Cannot revert override of gpio %s. Error: %s", g.Name, err)
+			}
+		}
+		if g.Role == RolePwm {
+			cancelRamp(g.Name)
+			if err := g.StopPwm(); err != nil {
+				log.Printf("Cannot stop pwm of gpio %s. Error: %s", g.Name, err)
+			}
+		}
+		var err error
+		if g.SafeValue() == 1 {
+			err = g.Up()
+		} else {
+			err = g.Down()
+		}
+		if err != nil {
+			log.Printf("Cannot drive gpio %s to safe state. Error: %s", g.Name, err)
+			continue
+		}
+		g.State = g.SafeValue() == 1
+	}
+	elapsed := time.Since(start)
+	audit("safe-state", "all", reason)
+	return elapsed
+}
+
+// driveInitialStates drives the output lines with an initial_state to it at startup. The others keep
+// the level found, read back by reconcileLineStates.
+func (s *SimpleDriver) driveInitialStates() {
+	for i := range s.GpioList.Gpio {
+		g := &s.GpioList.Gpio[i]
+		value, ok := g.InitialValue()
+		if !ok || !isOutputRole(g.Role) || g.Role == RoleHeartbeat || g.Role == RoleWatchdog || g.Role == RolePwm {
+			continue
+		}
+		var err error
+		if value == 1 {
+			err = g.Up()
+		} else {
+			err = g.Down()
+		}
+		if err != nil {
+			log.Printf("Cannot drive gpio %s to its initial state. Error: %s", g.Name, err)
+			continue
+		}
+		g.State = value == 1
+	}
+}
@@ -0,0 +1,291 @@
+package driver
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/common"
+
+	sdkModels "github.com/edgexfoundry/device-sdk-go/v2/pkg/models"
+)
+
+const (
+	scheduleNextRunResource = "ScheduleNextRun"
+	scheduleLastRunResource = "ScheduleLastRun"
+	scheduleSkipResource    = "ScheduleSkip"
+	cycleScheduleRoute      = common.ApiBase + "/cycle/schedule"
+
+	// schedulePoll bounds the waits for a scheduled cycle, so skips and configuration changes apply
+	schedulePoll = time.Minute
+)
+
+// Schedule starts the pump cycles at the times of cron, a five field expression in local time such
+// as "0 */2 * * *", or every period after the previous cycle started, instead of after the command
+// gap. run overrides the pump duration of the cycles and windows restrict the starts to daily periods
+// such as 06:00 to 22:00: a cron time outside is passed over, an every cycle waits for the next window.
+// Missed cron times are not caught up after a restart; an every cycle overdue runs once at startup.
+type Schedule struct {
+	Cron    string           `yaml:"cron"`
+	Every   string           `yaml:"every"`
+	Run     string           `yaml:"run"`
+	Windows []ScheduleWindow `yaml:"windows"`
+	cron    *cronExpr
+	every   time.Duration
+	run     time.Duration
+}
+
+// ScheduleWindow is a daily period of local time, which may span midnight.
+type ScheduleWindow struct {
+	From string `yaml:"from"`
+	To   string `yaml:"to"`
+	from time.Duration
+	to   time.Duration
+}
+
+// ScheduleStatus is the next cycle the schedule starts and the last ones it started and skipped.
+type ScheduleStatus struct {
+	NextRun     time.Time `json:"nextRun"`
+	LastRun     time.Time `json:"lastRun,omitempty"`
+	LastSkipped time.Time `json:"lastSkipped,omitempty"`
+	SkipNext    bool      `json:"skipNext"`
+}
+
+var (
+	scheduleMutex  = sync.Mutex{}
+	scheduleStatus = ScheduleStatus{}
+	// scheduledBy is the schedule NextRun was computed for, replaced by a configuration change
+	scheduledBy *Schedule
+)
+
+// validateSchedule checks the schedule section of the configuration file.
+func validateSchedule() error {
+	sc := driverConfig.Schedule
+	if sc == nil {
+		return nil
+	}
+	if (sc.Cron == "") == (sc.Every == "") {
+		return errors.New("exactly one of cron and every is required")
+	}
+	var err error
+	if sc.Cron != "" {
+		if sc.cron, err = parseCron(sc.Cron); err != nil {
+			return fmt.Errorf("invalid cron %q: %s", sc.Cron, err)
+		}
+	}
+	if sc.Every != "" {
+		if sc.every, err = time.ParseDuration(sc.Every); err != nil || sc.every < time.Minute {
+			return fmt.Errorf("invalid period %q, at least 1m", sc.Every)
+		}
+	}
+	if sc.Run != "" {
+		min, max := pumpTimerResource.bounds()
+		if sc.run, err = time.ParseDuration(sc.Run); err != nil || sc.run < min || sc.run > max {
+			return fmt.Errorf("run must be between %s and %s", min, max)
+		}
+	}
+	for i := range sc.Windows {
+		w := &sc.Windows[i]
+		if w.from, err = timeOfDay(w.From); err != nil {
+			return fmt.Errorf("window %d: invalid start %q", i, w.From)
+		}
+		if w.to, err = timeOfDay(w.To); err != nil {
+			return fmt.Errorf("window %d: invalid end %q", i, w.To)
+		}
+		if w.from == w.to {
+			return fmt.Errorf("window %d: start and end must differ", i)
+		}
+	}
+	return nil
+}
+
+// loadSchedule restores the start of the last scheduled cycle from the pipeline state.
+func loadSchedule() {
+	if restoredPipeline != nil {
+		scheduleStatus.LastRun = restoredPipeline.LastScheduledRun
+	}
+}
+
+// inWindows reports whether t falls in a window, always true without windows.
+func (sc *Schedule) inWindows(t time.Time) bool {
+	for _, w := range sc.Windows {
+		if inDailyPeriod(w.from, w.to, t) {
+			return true
+		}
+	}
+	return len(sc.Windows) == 0
+}
+
+// nextWindow returns the first window opening after t.
+func (sc *Schedule) nextWindow(t time.Time) time.Time {
+	var first time.Time
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	for _, w := range sc.Windows {
+		opening := midnight.Add(w.from)
+		if !opening.After(t) {
+			opening = midnight.AddDate(0, 0, 1).Add(w.from)
+		}
+		if first.IsZero() || opening.Before(first) {
+			first = opening
+		}
+	}
+	return first
+}
+
+// after returns the start of the cycle following the one started, or skipped, at previous (zero when
+// none), the zero time when the cron expression never matches again within the windows.
+func (sc *Schedule) after(previous time.Time, now time.Time) time.Time {
+	if sc.cron != nil {
+		t := previous
+		if t.Before(now) {
+			t = now
+		}
+		// A year of daily cron times outside the windows at most
+		for i := 0; i < 366*24*60; i++ {
+			if t = sc.cron.next(t); t.IsZero() || sc.inWindows(t) {
+				return t
+			}
+		}
+		return time.Time{}
+	}
+	next := now
+	if !previous.IsZero() && previous.Add(sc.every).After(now) {
+		next = previous.Add(sc.every)
+	}
+	if !sc.inWindows(next) {
+		next = sc.nextWindow(next)
+	}
+	return next
+}
+
+// scheduleWait returns how long the pipeline must wait before the next scheduled cycle, 0 when it is
+// due or without a schedule. A cycle due while skipped is passed over.
+func scheduleWait() time.Duration {
+	sc := driverConfig.Schedule
+	if sc == nil {
+		return 0
+	}
+	scheduleMutex.Lock()
+	defer scheduleMutex.Unlock()
+	now := time.Now()
+	if scheduledBy != sc {
+		scheduledBy = sc
+		scheduleNext(sc.after(scheduleStatus.LastRun, now))
+	}
+	if !scheduleStatus.NextRun.IsZero() && !scheduleStatus.NextRun.After(now) && scheduleStatus.SkipNext {
+		scheduleStatus.SkipNext = false
+		scheduleStatus.LastSkipped = scheduleStatus.NextRun
+		audit("schedule-skip", "cycle", fmt.Sprintf("cycle of %s skipped", scheduleStatus.LastSkipped.Format(time.RFC3339)))
+		scheduleNext(sc.after(scheduleStatus.LastSkipped, now))
+	}
+	if scheduleStatus.NextRun.IsZero() {
+		return schedulePoll
+	}
+	wait := scheduleStatus.NextRun.Sub(now)
+	if wait <= 0 {
+		return 0
+	}
+	if wait > schedulePoll {
+		wait = schedulePoll
+	}
+	return wait
+}
+
+// scheduleNext records the start of the next scheduled cycle. Must be called holding scheduleMutex.
+func scheduleNext(t time.Time) {
+	scheduleStatus.NextRun = t
+	if t.IsZero() {
+		log.Println("No scheduled cycle ahead")
+		return
+	}
+	log.Printf("Next scheduled cycle at %s", t.Format(time.RFC3339))
+}
+
+// scheduledCycleStarted records the start of a scheduled cycle and schedules the next one.
+func scheduledCycleStarted() {
+	sc := driverConfig.Schedule
+	if sc == nil {
+		return
+	}
+	scheduleMutex.Lock()
+	defer scheduleMutex.Unlock()
+	now := time.Now()
+	scheduleStatus.LastRun = now
+	scheduledBy = sc
+	scheduleNext(sc.after(now, now))
+	notePipelineScheduledRun(now)
+}
+
+// untilScheduledCycle returns the time left before the next scheduled cycle, 0 when unknown.
+func untilScheduledCycle() time.Duration {
+	scheduleMutex.Lock()
+	defer scheduleMutex.Unlock()
+	if scheduleStatus.NextRun.IsZero() {
+		return 0
+	}
+	return time.Until(scheduleStatus.NextRun)
+}
+
+// scheduledPumpDuration returns the pump run time in seconds of the scheduled cycles, the configured
+// one without a run override.
+func scheduledPumpDuration() int64 {
+	if sc := driverConfig.Schedule; sc != nil && sc.run > 0 {
+		return int64(sc.run.Seconds())
+	}
+	return cyclePumpDuration()
+}
+
+// skipScheduledCycle sets or clears the skip of the next scheduled cycle on behalf of source.
+func skipScheduledCycle(skip bool, source string) error {
+	if driverConfig.Schedule == nil {
+		return errors.New("no schedule configured")
+	}
+	scheduleMutex.Lock()
+	scheduleStatus.SkipNext = skip
+	next := scheduleStatus.NextRun
+	scheduleMutex.Unlock()
+	audit(source+"-schedule-skip", "cycle", fmt.Sprintf("skip of the cycle of %s set to %t", next.Format(time.RFC3339), skip))
+	return nil
+}
+
+func currentScheduleStatus() ScheduleStatus {
+	scheduleMutex.Lock()
+	defer scheduleMutex.Unlock()
+	return scheduleStatus
+}
+
+func isScheduleResource(name string) bool {
+	return name == scheduleNextRunResource || name == scheduleLastRunResource || name == scheduleSkipResource
+}
+
+// readSchedule reads the schedule resources; run times are RFC 3339, empty when unknown.
+func readSchedule(req sdkModels.CommandRequest) (*sdkModels.CommandValue, error) {
+	status := currentScheduleStatus()
+	run := status.NextRun
+	switch req.DeviceResourceName {
+	case scheduleSkipResource:
+		return sdkModels.NewCommandValue(req.DeviceResourceName, common.ValueTypeBool, status.SkipNext)
+	case scheduleLastRunResource:
+		run = status.LastRun
+	}
+	value := ""
+	if !run.IsZero() {
+		value = run.Format(time.RFC3339)
+	}
+	return sdkModels.NewCommandValue(req.DeviceResourceName, common.ValueTypeString, value)
+}
+
+func writeScheduleSkip(param *sdkModels.CommandValue) error {
+	skip, err := param.BoolValue()
+	if err != nil {
+		return err
+	}
+	return skipScheduledCycle(skip, "core-command")
+}
+
+func (s *SimpleDriver) handleCycleSchedule(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{"schedule": driverConfig.Schedule, "status": currentScheduleStatus()})
+}
@@ -0,0 +1,146 @@
+package driver
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/edgexfoundry/device-gpiod/script"
+)
+
+// Scripts are user expressions evaluated at the extension points of the pump cycle.
+type Scripts struct {
+	StartCycle   string `yaml:"start_cycle"`
+	PumpDuration string `yaml:"pump_duration"`
+}
+
+var (
+	startCycleScript   *script.Program
+	pumpDurationScript *script.Program
+)
+
+func compileScripts() error {
+	var err error
+	if src := driverConfig.Scripts.StartCycle; src != "" {
+		if startCycleScript, err = script.Compile(src); err != nil {
+			return fmt.Errorf("start_cycle: %s", err)
+		}
+	}
+	if src := driverConfig.Scripts.PumpDuration; src != "" {
+		if pumpDurationScript, err = script.Compile(src); err != nil {
+			return fmt.Errorf("pump_duration: %s", err)
+		}
+	}
+	return nil
+}
+
+// scriptEnv exposes the current configuration and line readings to the scripts. Durations are in seconds.
+func scriptEnv() script.Env {
+	now := time.Now()
+	return script.Env{
+		Vars: map[string]interface{}{
+			"pumpTimer":     *pumpTimer,
+			"commandGap":    commandGap.Seconds(),
+			"cleanTimer":    cleanTimer.Seconds(),
+			"reverseTimer":  reverseTimer.Seconds(),
+			"gravityTimer":  gravityTimer.Seconds(),
+			"enableClean":   cleanEnabled(),
+			"enableReverse": reverseEnabled(),
+			"hour":          now.Hour(),
+			"minute":        now.Minute(),
+			"weekday":       int(now.Weekday()),
+			"phase":         currentPhase().Name,
+			"healthy":       serviceHealthy(),
+		},
+		Funcs: map[string]script.Func{
+			"state": func(args ...interface{}) (interface{}, error) {
+				st, err := scriptLineStats(args)
+				return err == nil && !st.onSince.IsZero(), err
+			},
+			"count": func(args ...interface{}) (interface{}, error) {
+				st, err := scriptLineStats(args)
+				return st.count, err
+			},
+			"runtime": func(args ...interface{}) (interface{}, error) {
+				st, err := scriptLineStats(args)
+				runtime := st.runtime
+				if !st.onSince.IsZero() {
+					runtime += time.Since(st.onSince)
+				}
+				return runtime.Hours(), err
+			},
+			"level": func(args ...interface{}) (interface{}, error) {
+				name, err := scriptLineName(args)
+				value, _, _ := lastTransition(name)
+				return value == 1, err
+			},
+			"since": func(args ...interface{}) (interface{}, error) {
+				name, err := scriptLineName(args)
+				_, at, ok := lastTransition(name)
+				if !ok {
+					return now.Sub(startupReport.StartedAt).Seconds(), err
+				}
+				return now.Sub(at).Seconds(), err
+			},
+			"faults": func(args ...interface{}) (interface{}, error) {
+				return len(activeFaults()), nil
+			},
+			"alarms": func(args ...interface{}) (interface{}, error) {
+				return len(latchedAlarms()), nil
+			},
+		},
+	}
+}
+
+func scriptLineName(args []interface{}) (string, error) {
+	if len(args) != 1 {
+		return "", errors.New("expects the gpio name")
+	}
+	name, ok := args[0].(string)
+	if !ok {
+		return "", errors.New("gpio name must be a string")
+	}
+	return name, nil
+}
+
+func scriptLineStats(args []interface{}) (lineStats, error) {
+	name, err := scriptLineName(args)
+	if err != nil {
+		return lineStats{}, err
+	}
+	statsMutex.Lock()
+	defer statsMutex.Unlock()
+	if st, ok := stats[name]; ok {
+		return *st, nil
+	}
+	return lineStats{}, nil
+}
+
+// shouldStartCycle evaluates the start_cycle script. A missing or failing script never blocks the cycle.
+func shouldStartCycle() bool {
+	if startCycleScript == nil {
+		return true
+	}
+	start, err := startCycleScript.EvalBool(scriptEnv())
+	if err != nil {
+		log.Printf("Cannot evaluate start_cycle script. Error: %s", err)
+		return true
+	}
+	return start
+}
+
+// cyclePumpDuration returns the pump run time in seconds for the next cycle, computed by the
+// pump_duration script when configured and bounded as PUMP_TIMEOUT is.
+func cyclePumpDuration() int64 {
+	if pumpDurationScript == nil {
+		return *pumpTimer
+	}
+	seconds, err := pumpDurationScript.EvalFloat(scriptEnv())
+	if err != nil || seconds <= 0 {
+		log.Printf("Cannot evaluate pump_duration script, using %d s. Error: %v", *pumpTimer, err)
+		return *pumpTimer
+	}
+	run := clampTimer("PUMP_TIMEOUT", time.Duration(seconds*float64(time.Second)), "pump_duration script")
+	return int64(run.Seconds())
+}
@@ -0,0 +1,159 @@
+package driver
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/edgexfoundry/device-gpiod/gpio"
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/common"
+
+	sdkModels "github.com/edgexfoundry/device-sdk-go/v2/pkg/models"
+)
+
+const (
+	RoleTamper            = "tamper"
+	securityAlarmResource = "SecurityAlarm"
+	severityHigh          = "high"
+	alarmsRoute           = common.ApiBase + "/alarms"
+	alarmsAckRoute        = common.ApiBase + "/alarms/ack"
+)
+
+// SecurityAlarm is the latching alarm of a tamper/door input. Once triggered it stays latched until
+// acknowledged, even if the input returns to rest.
+type SecurityAlarm struct {
+	Name           string    `json:"name"`
+	Severity       string    `json:"severity"`
+	Active         bool      `json:"active"`
+	Latched        bool      `json:"latched"`
+	TriggeredAt    time.Time `json:"triggeredAt,omitempty"`
+	AcknowledgedAt time.Time `json:"acknowledgedAt,omitempty"`
+}
+
+var (
+	alarmsMutex = sync.Mutex{}
+	alarms      = make(map[string]*SecurityAlarm)
+)
+
+// startSecurityMonitoring watches the lines with role "tamper". Debounce is taken from the line
+// (or chip) configuration; an input at 1 (after active_low) is an open door or tampered enclosure.
+func (s *SimpleDriver) startSecurityMonitoring() {
+	for i := range s.GpioList.Gpio {
+		g := &s.GpioList.Gpio[i]
+		if g.Role != RoleTamper {
+			continue
+		}
+		alarmsMutex.Lock()
+		alarms[g.Name] = &SecurityAlarm{Name: g.Name, Severity: severityHigh}
+		alarmsMutex.Unlock()
+
+		if err := g.Watch(s.handleTamperEvent); err != nil {
+			log.Printf("Cannot monitor tamper gpio %s. Error: %s", g.Name, err)
+			continue
+		}
+		if value, err := g.Value(); err == nil && value == 1 {
+			go s.handleTamperEvent(gpio.Event{Name: g.Name, Chip: g.Chip, Line: g.Line, Value: value})
+		}
+	}
+}
+
+func (s *SimpleDriver) handleTamperEvent(evt gpio.Event) {
+	recordTransition(evt.Name, evt.Value == 1)
+	alarmsMutex.Lock()
+	alarm, ok := alarms[evt.Name]
+	if !ok {
+		alarmsMutex.Unlock()
+		return
+	}
+	alarm.Active = evt.Value == 1
+	triggered := alarm.Active && !alarm.Latched
+	if triggered {
+		alarm.Latched = true
+		alarm.TriggeredAt = time.Now()
+	}
+	snapshot := *alarm
+	alarmsMutex.Unlock()
+
+	if triggered {
+		recordTimelineEvent(evt.Name, "alarm", "security input asserted")
+		audit("alarm-triggered", evt.Name, "security input asserted")
+		sendNotification("security", notificationSeverityCritical, tr("notification.security-alarm", evt.Name))
+	}
+	s.pushSecurityAlarm(snapshot)
+}
+
+func (s *SimpleDriver) pushSecurityAlarm(alarm SecurityAlarm) {
+	payload, err := shapePayload(securityAlarmResource, alarm)
+	if err != nil {
+		log.Printf("Cannot marshal security alarm. Error: %s", err)
+		return
+	}
+	cv, err := sdkModels.NewCommandValue(securityAlarmResource, common.ValueTypeString, string(payload))
+	if err != nil {
+		log.Printf("Cannot create security alarm reading. Error: %s", err)
+		return
+	}
+	s.asyncCh <- &sdkModels.AsyncValues{
+		DeviceName:    deviceName(),
+		CommandValues: []*sdkModels.CommandValue{cv},
+	}
+}
+
+func (s *SimpleDriver) handleAlarms(w http.ResponseWriter, r *http.Request) {
+	alarmsMutex.Lock()
+	list := make([]SecurityAlarm, 0, len(alarms))
+	for _, alarm := range alarms {
+		list = append(list, *alarm)
+	}
+	alarmsMutex.Unlock()
+	writeJSON(w, http.StatusOK, list)
+}
+
+// latchedAlarms returns the names of the alarms waiting for acknowledgment.
+func latchedAlarms() []string {
+	alarmsMutex.Lock()
+	defer alarmsMutex.Unlock()
+	var names []string
+	for name, alarm := range alarms {
+		if alarm.Latched {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+type alarmAckRequest struct {
+	Name string `json:"name"`
+}
+
+// handleAlarmAck acknowledges a latched alarm.
+func (s *SimpleDriver) handleAlarmAck(w http.ResponseWriter, r *http.Request) {
+	var req alarmAckRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	alarmsMutex.Lock()
+	alarm, ok := alarms[req.Name]
+	if !ok {
+		alarmsMutex.Unlock()
+		writeError(w, http.StatusNotFound, fmt.Errorf("unknown alarm %s", req.Name))
+		return
+	}
+	if !alarm.Latched {
+		alarmsMutex.Unlock()
+		writeError(w, http.StatusConflict, fmt.Errorf("alarm %s is not latched", req.Name))
+		return
+	}
+	alarm.Latched = false
+	alarm.AcknowledgedAt = time.Now()
+	snapshot := *alarm
+	alarmsMutex.Unlock()
+
+	audit("alarm-acknowledged", req.Name, fmt.Sprintf("input active: %t", snapshot.Active))
+	s.pushSecurityAlarm(snapshot)
+	writeJSON(w, http.StatusOK, snapshot)
+}
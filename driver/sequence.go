@@ -0,0 +1,140 @@
+package driver
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/edgexfoundry/device-gpiod/script"
+	"github.com/edgexfoundry/device-gpiod/sequence"
+)
+
+var (
+	cycleSequence *sequence.Sequence
+)
+
+// loadSequence loads the sequence run after the pump phase of every cycle from SEQUENCE_FILE, or the
+// reverse and clean flow shipped with the service when unset.
+func loadSequence() error {
+	var err error
+	if fileName := os.Getenv("SEQUENCE_FILE"); fileName != "" {
+		cycleSequence, err = sequence.Load(fileName)
+	} else {
+		cycleSequence, err = sequence.Default()
+	}
+	if err != nil {
+		return fmt.Errorf("cannot load cycle sequence: %s", err)
+	}
+	log.Printf("Cycle sequence: %s", cycleSequence.Name)
+	return nil
+}
+
+// sequencePhases returns the phases declared by the cycle sequence.
+func sequencePhases() []string {
+	var phases []string
+	var walk func(steps []sequence.Step)
+	walk = func(steps []sequence.Step) {
+		for _, step := range steps {
+			if step.Phase != "" {
+				phases = append(phases, step.Phase)
+			}
+			walk(step.Steps)
+		}
+	}
+	if cycleSequence != nil {
+		walk(cycleSequence.Steps)
+		walk(cycleSequence.OnError)
+	}
+	return phases
+}
+
+// sequenceEnv is the script environment with the lines of the pipeline roles and the valve timers,
+// for the ${name} references of the sequence.
+func sequenceEnv() script.Env {
+	env := scriptEnv()
+	env.Vars["pump"] = triggerLine("START_TRIGGER")
+	env.Vars["reverse"] = triggerLine("REVERSE_TRIGGER")
+	env.Vars["clean"] = triggerLine("CLEAN_TRIGGER")
+	env.Vars["openValve"] = triggerLine("OPEN_VALVE")
+	env.Vars["switchingValve"] = triggerLine("SWITCHING_VALVE")
+	env.Vars["switchingTimer"] = switchingTimer.Seconds()
+	env.Vars["openingTimer"] = openingTimer.Seconds()
+	env.Vars["cleanStages"] = len(driverConfig.CleanRecipe)
+	return env
+}
+
+// sequenceActuator runs the cycle sequence on the lines, with the phase hooks, phase tracking and
+// fault reporting of the pipeline.
+type sequenceActuator struct {
+	s *SimpleDriver
+}
+
+func (a *sequenceActuator) Set(line string, on bool) error {
+	g, ok := a.s.findGpio(line)
+	if !ok {
+		return fmt.Errorf("%w %q", errUnknownLine, line)
+	}
+	if err := phaseValveFault(); err != nil {
+		return err
+	}
+	if err := setPipelineLevel(g, on); err != nil {
+		return fmt.Errorf("cannot set %s to %t: %s", line, on, err)
+	}
+	g.State = on
+	notePipelineLine(g.Name, on)
+	notePhaseValve(g.Name)
+	a.s.handleAsyncCommunication(*g)
+	return nil
+}
+
+func (a *sequenceActuator) Sleep(d time.Duration, compensateFor string) {
+	if compensateFor != "" {
+		d = compensated(compensateFor, d)
+	}
+	supervisedSleep("pipeline", d)
+}
+
+func (a *sequenceActuator) BeginPhase(name string, expected time.Duration) error {
+	if draining() {
+		return errDraining
+	}
+	if err := runPhaseHooks(name, hookPre); err != nil {
+		logf(moduleStateMachine, levelWarn, "Skipping %s phase. Error: %s", name, err)
+		return err
+	}
+	resetPhaseValves()
+	setPhase(name, expected)
+	a.s.pushCleanStage(name, "started", nil)
+	return nil
+}
+
+func (a *sequenceActuator) EndPhase(name string, err error) error {
+	if err == nil {
+		err = phaseValveFault()
+	}
+	// The on_error steps must be able to drive the valves of the failed phase
+	resetPhaseValves()
+	if err != nil {
+		a.s.pushCleanStage(name, "failed", err)
+		setFault(name, err)
+		logf(moduleStateMachine, levelError, "Phase %s failed. Error: %s", name, err)
+		return err
+	}
+	clearFault(name)
+	a.s.pushCleanStage(name, "completed", nil)
+	a.s.consumeCleanStage(name)
+	switch name {
+	case phaseClean:
+		noteCleanDone()
+	case phaseReverse:
+		noteReverseDone()
+	}
+	countPhase(name)
+	a.s.observePhase(currentPhase())
+	return runPhaseHooks(name, hookPost)
+}
+
+func (a *sequenceActuator) Env() script.Env {
+	return sequenceEnv()
+}
@@ -0,0 +1,53 @@
+package driver
+
+import "sync"
+
+var (
+	offlineMutex = sync.Mutex{}
+	offline      = false
+)
+
+// setOffline records whether a health probe with the indicator action fails.
+func setOffline(value bool) {
+	offlineMutex.Lock()
+	defer offlineMutex.Unlock()
+	offline = value
+}
+
+func isOffline() bool {
+	offlineMutex.Lock()
+	defer offlineMutex.Unlock()
+	return offline
+}
+
+// serviceConditions is the policy mapping the service state to indicator states, most severe first:
+// lockout while a lockout is held, offline while a health probe with the indicator action fails, fault while a fault
+// is active, warning while a supervised goroutine is late or a degrade dependency is unavailable,
+// then the state mapped to the cycle phase being run.
+func serviceConditions() []string {
+	var conditions []string
+	if lockedOut() {
+		conditions = append(conditions, StateLockout)
+	}
+	if isOffline() {
+		conditions = append(conditions, StateOffline)
+	}
+	if len(activeFaults()) > 0 {
+		conditions = append(conditions, StateFault)
+	}
+	if len(stalledGoroutines()) > 0 || len(unavailableDependencies(dependencyDegrade)) > 0 || overBudget() {
+		conditions = append(conditions, StateWarning)
+	}
+	if state := phaseState(currentPhase().Name); state != "" {
+		conditions = append(conditions, state)
+	}
+	return conditions
+}
+
+// serviceState is the aggregate service state: the most severe condition, or idle.
+func serviceState() string {
+	if conditions := serviceConditions(); len(conditions) > 0 {
+		return conditions[0]
+	}
+	return StateIdle
+}
@@ -0,0 +1,150 @@
+package driver
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/common"
+
+	sdkModels "github.com/edgexfoundry/device-sdk-go/v2/pkg/models"
+)
+
+const (
+	lastShutdownResource = "LastShutdown"
+
+	shutdownUnclean  = "unclean"
+	shutdownSignal   = "signal"
+	shutdownStop     = "stop"
+	shutdownForced   = "stop-forced"
+	shutdownPanic    = "panic"
+	shutdownWatchdog = "watchdog"
+	shutdownExit     = "exit"
+)
+
+// ShutdownRecord is why the service last stopped. "unclean" means the process died without recording a
+// reason: power loss, SIGKILL, OOM kill or a hardware reset.
+type ShutdownRecord struct {
+	Reason string    `json:"reason"`
+	Detail string    `json:"detail,omitempty"`
+	At     time.Time `json:"at"`
+}
+
+var (
+	shutdownMutex    = sync.Mutex{}
+	lastShutdown     *ShutdownRecord
+	shutdownRecorded = false
+	watchdogSuspect  = false
+)
+
+// loadShutdownReason reads the reason of the previous shutdown from SHUTDOWN_FILE, then marks the
+// current run as unclean until a reason is recorded. It is a no-op when SHUTDOWN_FILE is unset.
+func loadShutdownReason() {
+	fileName := os.Getenv("SHUTDOWN_FILE")
+	if fileName == "" {
+		return
+	}
+	if data, err := os.ReadFile(fileName); err == nil {
+		var record ShutdownRecord
+		if err := json.Unmarshal(data, &record); err != nil {
+			log.Printf("Cannot parse shutdown file %s. Error: %s", fileName, err)
+		} else {
+			lastShutdown = &record
+			log.Printf("Last shutdown: %s %s", record.Reason, record.Detail)
+		}
+	} else if !os.IsNotExist(err) {
+		log.Printf("Cannot read shutdown file %s. Error: %s", fileName, err)
+	}
+	writeShutdown(ShutdownRecord{Reason: shutdownUnclean, At: time.Now()})
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM, syscall.SIGINT, syscall.SIGHUP)
+	go func() {
+		sig := <-signals
+		recordShutdown(shutdownSignal, sig.String())
+	}()
+}
+
+// recordShutdown records the reason of the shutdown in progress. The first reason wins, e.g. the
+// signal that made the SDK call Stop.
+func recordShutdown(reason string, detail string) {
+	shutdownMutex.Lock()
+	defer shutdownMutex.Unlock()
+	if shutdownRecorded {
+		return
+	}
+	shutdownRecorded = true
+	writeShutdown(ShutdownRecord{Reason: reason, Detail: detail, At: time.Now()})
+}
+
+// suspectWatchdogReset records that the hardware watchdog is not being petted, so a reset it triggers
+// is reported as such. Clearing it restores the unclean marker.
+func suspectWatchdogReset(suspect bool, detail string) {
+	shutdownMutex.Lock()
+	defer shutdownMutex.Unlock()
+	if shutdownRecorded || watchdogSuspect == suspect {
+		return
+	}
+	watchdogSuspect = suspect
+	if suspect {
+		countMetric("gpiod_watchdog_trips_total")
+		writeShutdown(ShutdownRecord{Reason: shutdownWatchdog, Detail: detail, At: time.Now()})
+	} else {
+		writeShutdown(ShutdownRecord{Reason: shutdownUnclean, At: time.Now()})
+	}
+}
+
+// shutdownOnPanic records a panic of the goroutine it is deferred in, then lets the panic go on.
+func shutdownOnPanic() {
+	if r := recover(); r != nil {
+		recordShutdown(shutdownPanic, fmt.Sprint(r))
+		panicFailSafe(fmt.Sprint(r))
+		panic(r)
+	}
+}
+
+func writeShutdown(record ShutdownRecord) {
+	fileName := os.Getenv("SHUTDOWN_FILE")
+	if fileName == "" {
+		return
+	}
+	data, err := json.Marshal(record)
+	if err != nil {
+		log.Printf("Cannot marshal shutdown record. Error: %s", err)
+		return
+	}
+	// Write then rename, so a power loss can't leave a truncated record behind
+	if err := os.WriteFile(fileName+".tmp", data, 0644); err != nil {
+		log.Printf("Cannot write shutdown file %s. Error: %s", fileName, err)
+		return
+	}
+	if err := os.Rename(fileName+".tmp", fileName); err != nil {
+		log.Printf("Cannot write shutdown file %s. Error: %s", fileName, err)
+	}
+}
+
+// pushLastShutdown publishes the reason of the previous shutdown as a reading.
+func (s *SimpleDriver) pushLastShutdown() {
+	if lastShutdown == nil {
+		return
+	}
+	payload, err := shapePayload(lastShutdownResource, lastShutdown)
+	if err != nil {
+		log.Printf("Cannot marshal last shutdown. Error: %s", err)
+		return
+	}
+	cv, err := sdkModels.NewCommandValue(lastShutdownResource, common.ValueTypeString, string(payload))
+	if err != nil {
+		log.Printf("Cannot create last shutdown reading. Error: %s", err)
+		return
+	}
+	s.asyncCh <- &sdkModels.AsyncValues{
+		DeviceName:    deviceName(),
+		CommandValues: []*sdkModels.CommandValue{cv},
+	}
+}
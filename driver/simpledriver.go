@@ -10,26 +10,25 @@
 package driver
 
 import (
-	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
-	"io/ioutil"
 	"log"
-	"net/http"
 	"os"
 	"reflect"
 	"strconv"
-	"strings"
+	"sync"
 	"time"
 
+	"github.com/edgexfoundry/device-gpiod/config"
 	"github.com/edgexfoundry/device-gpiod/gpio"
+	"github.com/edgexfoundry/device-gpiod/sequence"
 	"github.com/edgexfoundry/device-sdk-go/v2/pkg/interfaces"
 	"github.com/edgexfoundry/device-sdk-go/v2/pkg/service"
 	"github.com/edgexfoundry/go-mod-core-contracts/v2/clients/logger"
 	"github.com/edgexfoundry/go-mod-core-contracts/v2/common"
 	"github.com/edgexfoundry/go-mod-core-contracts/v2/models"
 
-	"github.com/edgexfoundry/device-sdk-go/v2/example/config"
 	sdkModels "github.com/edgexfoundry/device-sdk-go/v2/pkg/models"
 )
 
@@ -40,8 +39,17 @@ type SimpleDriver struct {
 	GpioList      *gpio.GPIOList
 	Verbose       bool
 	serviceConfig *config.ServiceConfig
+	// stopped is closed at the end of Stop, after which no reading is sent to asyncCh
+	stopped  chan struct{}
+	stopOnce sync.Once
 }
 
+// The SDK only finds these through the interfaces; keep the driver satisfying them at compile time.
+var (
+	_ sdkModels.ProtocolDriver    = (*SimpleDriver)(nil)
+	_ sdkModels.ProtocolDiscovery = (*SimpleDriver)(nil)
+)
+
 type Config struct {
 	PumpTimer     time.Duration
 	EnableClean   bool
@@ -79,69 +87,76 @@ var (
 // service.
 func (s *SimpleDriver) Initialize(lc logger.LoggingClient, asyncCh chan<- *sdkModels.AsyncValues, deviceCh chan<- []sdkModels.DiscoveredDevice) error {
 	s.lc = lc
-	s.asyncCh = asyncCh
+	s.stopped = make(chan struct{})
+	s.asyncCh = batchReadings(asyncCh, s.stopped)
 	s.deviceCh = deviceCh
 	s.serviceConfig = &config.ServiceConfig{}
 	pumpChannel := make(chan gpio.GPIO)
 
-	pump, err := time.ParseDuration(os.Getenv("PUMP_TIMEOUT"))
-	if err != nil {
-		log.Printf("Cannot parse pump timeout. Picking default value...")
-		*pumpTimer = int64(time.Duration(5) * time.Minute)
-	} else {
-		*pumpTimer = int64(pump.Seconds())
-		if *pumpTimer < MIN_PUMP*int64(time.Minute.Seconds()) {
-			*pumpTimer = MIN_PUMP * int64(time.Minute.Seconds())
-		}
-	}
+	pump := parseTimer("PUMP_TIMEOUT", time.Duration(5)*time.Minute, time.Duration(MIN_PUMP)*time.Minute)
+	*pumpTimer = int64(pump.Seconds())
+	*commandGap = parseTimer("COMMAND_GAP", time.Duration(60)*time.Minute, MIN_COMMAND_GAP)
+	*cleanTimer = parseTimer("CLEAN_TIMEOUT", time.Duration(5)*time.Minute, MIN_CLEAN_TIMER)
+	*reverseTimer = parseTimer("REVERSE_TIMEOUT", time.Duration(5)*time.Minute, MIN_REVERSE_TIMER)
+	*gravityTimer = parseTimer("GRAVITY_TIMEOUT", time.Duration(5)*time.Minute, MIN_GRAVITY_TIMER)
 
-	*commandGap, err = time.ParseDuration(os.Getenv("COMMAND_GAP"))
+	var err error
+	*enableClean, err = strconv.ParseBool(os.Getenv("ENABLE_CLEAN"))
 	if err != nil {
-		log.Printf("Cannot parse command gap. Picking default value...")
-		*commandGap = time.Duration(60) * time.Minute
-	}
-	if *commandGap < MIN_COMMAND_GAP {
-		*commandGap = MIN_COMMAND_GAP
+		log.Printf("Cannot parse enable clean. Picking default value...")
+		*enableClean = false
 	}
 
-	*cleanTimer, err = time.ParseDuration(os.Getenv("CLEAN_TIMEOUT"))
+	*enableReverse, err = strconv.ParseBool(os.Getenv("ENABLE_REVERSE"))
 	if err != nil {
-		log.Printf("Cannot parse clean timeout. Picking default value...")
-		*cleanTimer = time.Duration(5) * time.Minute
-	}
-	if *cleanTimer < MIN_CLEAN_TIMER {
-		*cleanTimer = MIN_CLEAN_TIMER
+		log.Printf("Cannot parse enable reverse. Picking default value...")
+		*enableReverse = false
 	}
 
-	*reverseTimer, err = time.ParseDuration(os.Getenv("REVERSE_TIMEOUT"))
-	if err != nil {
-		log.Printf("Cannot parse reverse timeout. Picking default value...")
-		*reverseTimer = time.Duration(5) * time.Minute
+	parseAutoProvision()
+	parsePlanMode()
+	parseSnmp()
+	startSyslog()
+	restoreAuditChain()
+	s.startAuditReadings()
+	s.startAuditCheckpoints()
+	loadTranslations()
+	loadShutdownReason()
+	checkFeatures()
+	startSystemEvents()
+	startTimeSeries()
+
+	if err := parseInstanceName(); err != nil {
+		return err
+	}
+
+	parseGpioBackend()
+	parseEventThread()
+	for _, fileName := range []string{os.Getenv("GPIO_CONFIG_FILE"), os.Getenv("SEQUENCE_FILE")} {
+		if err := verifyConfigFile(fileName); err != nil {
+			return err
+		}
 	}
-	if *reverseTimer < MIN_REVERSE_TIMER {
-		*reverseTimer = MIN_REVERSE_TIMER
+	if err := s.GpioList.ResolveLineNames(); err != nil {
+		return err
 	}
+	s.checkDeviceAccess()
+	s.checkHardwareFingerprint()
 
-	*gravityTimer, err = time.ParseDuration(os.Getenv("GRAVITY_TIMEOUT"))
-	if err != nil {
-		log.Printf("Cannot parse gravity timeout. Picking default value...")
-		*gravityTimer = time.Duration(5) * time.Minute
-	}
-	if *gravityTimer < MIN_GRAVITY_TIMER {
-		*gravityTimer = MIN_GRAVITY_TIMER
+	rememberStartupTimers()
+	rememberEnvSettings()
+	if err := loadSequence(); err != nil {
+		return err
 	}
-
-	*enableClean, err = strconv.ParseBool(os.Getenv("ENABLE_CLEAN"))
-	if err != nil {
-		log.Printf("Cannot parse enable clean. Picking default value...")
-		*enableClean = false
+	cfg := &DriverConfig{}
+	if err := parseDriverConfig(os.Getenv("GPIO_CONFIG_FILE"), cfg); err != nil {
+		return err
 	}
-
-	*enableReverse, err = strconv.ParseBool(os.Getenv("ENABLE_REVERSE"))
-	if err != nil {
-		log.Printf("Cannot parse enable reverse. Picking default value...")
-		*enableReverse = false
+	if err := applyDriverConfig(cfg); err != nil {
+		return err
 	}
+	loadKnownGoodConfig()
+	s.lintWarnings()
 
 	gpioConfig = &Config{
 		PumpTimer:     time.Duration(*pumpTimer),
@@ -153,120 +168,150 @@ func (s *SimpleDriver) Initialize(lc logger.LoggingClient, asyncCh chan<- *sdkMo
 		CommandGap:    *commandGap,
 	}
 
-	pumpGpio, reverseGpio, cleanGpio, openValveGpio, switchingValveGpio := -1, -1, -1, -1, -1
-	var pumpChip, reverseChip, cleanChip, openValveChip, switchingValveChip string
-	for _, gpio := range s.GpioList.Gpio {
-		switch gpio.Name {
-		case os.Getenv("START_TRIGGER"):
-			pumpGpio = gpio.Line
-			pumpChip = gpio.Chip
-		case os.Getenv("REVERSE_TRIGGER"):
-			reverseGpio = gpio.Line
-			reverseChip = gpio.Chip
-		case os.Getenv("CLEAN_TRIGGER"):
-			cleanGpio = gpio.Line
-			cleanChip = gpio.Chip
-		case os.Getenv("OPEN_VALVE"):
-			openValveGpio = gpio.Line
-			openValveChip = gpio.Chip
-		case os.Getenv("SWITCHING_VALVE"):
-			switchingValveGpio = gpio.Line
-			switchingValveChip = gpio.Chip
-		default:
-			log.Printf("Unknown gpio %s.", gpio.Name)
-		}
+	ds := service.RunningService()
+
+	if err := ds.LoadCustomConfig(s.serviceConfig, "GpiodCustom"); err != nil {
+		return fmt.Errorf("unable to load 'GpiodCustom' custom configuration: %s", err.Error())
 	}
 
-	log.Printf(`
-	Device GPIO configuration:
-	PUMP PIN: %d, PUMP CHIP: %s
-	REVERSE PIN: %d, REVERSE CHIP: %s, REVERSE ENABLED: %t
-	CLEAN PIN: %d, CLEAN CHIP: %s, CLEAN ENABLED: %t
-	OPEN VALVE PIN: %d, OPNE VALVE CHIP: %s, OPEN VALVE AVAILABLE: %t
-	SWITCHING VALVE PIN: %d, SWITCHING VALVE CHIP: %s, SWITCHING VALVE AVAILABLE: %t
-	`, pumpGpio, pumpChip,
-		reverseGpio, reverseChip, *enableReverse,
-		cleanGpio, cleanChip, *enableClean,
-		openValveGpio, openValveChip, *enableClean,
-		switchingValveGpio, switchingValveChip, *enableClean,
-	)
+	lc.Infof("Custom config is: %v", s.serviceConfig.GpiodCustom)
 
-	ds := service.RunningService()
+	if err := s.serviceConfig.GpiodCustom.Validate(); err != nil {
+		return fmt.Errorf("'GpiodCustom' custom configuration validation failed: %s", err.Error())
+	}
+	if err := s.applyLogLevels(s.serviceConfig.GpiodCustom.Writable.LogLevels); err != nil {
+		return fmt.Errorf("'GpiodCustom.Writable.LogLevels' custom configuration rejected: %s", err.Error())
+	}
+	if err := s.applyWritable(s.serviceConfig.GpiodCustom.Writable, "configuration"); err != nil {
+		return fmt.Errorf("'GpiodCustom.Writable' custom configuration rejected: %s", err.Error())
+	}
 
-	if err := ds.LoadCustomConfig(s.serviceConfig, "SimpleCustom"); err != nil {
-		return fmt.Errorf("unable to load 'SimpleCustom' custom configuration: %s", err.Error())
+	if err := ds.ListenForCustomConfigChanges(
+		&s.serviceConfig.GpiodCustom.Writable,
+		"GpiodCustom/Writable", s.ProcessCustomConfigChanges); err != nil {
+		return fmt.Errorf("unable to listen for changes for 'GpiodCustom.Writable' custom configuration: %s", err.Error())
 	}
 
-	lc.Infof("Custom config is: %v", s.serviceConfig.SimpleCustom)
+	s.buildStartupReport()
+	s.reportLightMatches()
+	logStartupReport()
 
-	if err := s.serviceConfig.SimpleCustom.Validate(); err != nil {
-		return fmt.Errorf("'SimpleCustom' custom configuration validation failed: %s", err.Error())
+	if err := s.registerRoutes(); err != nil {
+		return err
 	}
 
-	if err := ds.ListenForCustomConfigChanges(
-		&s.serviceConfig.SimpleCustom.Writable,
-		"SimpleCustom/Writable", s.ProcessCustomConfigChanges); err != nil {
-		return fmt.Errorf("unable to listen for changes for 'SimpleCustom.Writable' custom configuration: %s", err.Error())
+	if autoProvision {
+		if err := s.provisionDevice(); err != nil {
+			log.Printf("Cannot auto provision device. Error: %s", err)
+		}
 	}
 
+	s.startMetrics()
+	s.driveInitialStates()
+	s.recoverJournal()
+	loadPipelineState()
+	loadCleanSchedule()
+	loadReverseSchedule()
+	loadSchedule()
+	s.reconcileLineStates()
+	startDependencyMonitoring()
+	waitForStartup()
+	s.startLineInfoMonitoring()
+	s.startSpareMonitoring()
+	s.startInputMonitoring()
+	s.startFeedbackMonitoring()
+	s.startValveSupervision()
+	s.startVirtualResources()
+	s.startThresholdMonitoring()
+	s.startStatistics()
+	s.startSecurityMonitoring()
+	s.startPowerFailMonitoring()
+	s.startLockoutMonitoring()
+	startBudgetMonitoring()
+	loadDrainPeriod()
+	loadLogSampling()
+	loadDailyStats()
+	loadConsumables()
+	s.startDailyReport()
+	startHeldReconciliation()
+	s.startChipRecovery()
+	s.startChipDiscovery()
+	s.startConcurrencyScheduler()
+	s.startPhaseVerbosity()
+	s.startLoadShedding()
+	s.startDerating()
+	s.startPowerMonitoring()
+	s.startReportingProfile()
+	s.startHeartbeat()
+	s.startWatchdog()
+	s.startFailSafe()
+	if err := s.startGrpc(); err != nil {
+		return err
+	}
+	if err := s.startModbusServer(); err != nil {
+		return fmt.Errorf("cannot start Modbus TCP facade: %s", err)
+	}
+	if err := s.startOpcuaServer(); err != nil {
+		return fmt.Errorf("cannot start OPC UA facade: %s", err)
+	}
+	if err := s.startCloudTwin(); err != nil {
+		return fmt.Errorf("cannot start cloud twin adapter: %s", err)
+	}
+	parseLeaderElection()
+	if err := startCoordination(); err != nil {
+		return fmt.Errorf("cannot start clean coordination: %s", err)
+	}
+	s.startLeaderElection()
 	s.gpioHandler(pumpChannel)
 
 	registered := interfaces.DeviceServiceSDK.Devices(interfaces.Service())
 	for _, device := range registered {
 		log.Printf("Device: %v", device)
+		if err := s.addGpioDevice(device.Name, device.Protocols); err != nil {
+			log.Printf("Cannot bind device %s. Error: %s", device.Name, err)
+		}
 	}
 
-	go ConnectionCheck()
+	s.startHealthChecks()
+	go s.pushConfigWarnings()
+	go s.pushLastShutdown()
+	go s.pushStateDiscrepancies()
+	go s.pushCapabilities()
 
 	return nil
 }
 
 func (s *SimpleDriver) gpioHandler(pumpChannel chan gpio.GPIO) {
 	// Handle GPIO actuation
-	var pump, reversePump, clean, openValve, switchingValve, light gpio.GPIO
+	pump := s.bindPumps()
 	for _, gpio := range s.GpioList.Gpio {
 		switch name := gpio.Name; {
-		case name == os.Getenv("START_TRIGGER"):
-			pump = gpio
-		case name == os.Getenv("REVERSE_TRIGGER"):
-			if *enableReverse {
-				reversePump = gpio
-			}
-		case name == os.Getenv("CLEAN_TRIGGER"):
-			if *enableClean {
-				clean = gpio
-			}
-		case name == os.Getenv("OPEN_VALVE"):
-			if *enableClean {
-				openValve = gpio
-			}
-		case name == os.Getenv("SWITCHING_VALVE"):
-			if *enableClean {
-				switchingValve = gpio
-			}
-		case strings.Contains(name, os.Getenv("LIGHT")):
-			light = gpio
-			HandleLight(light)
+		case name == triggerLine("START_TRIGGER"), name == triggerLine("STANDBY_TRIGGER"):
+			// Taken by bindPumps
+		case name == triggerLine("REVERSE_TRIGGER"), name == triggerLine("CLEAN_TRIGGER"),
+			name == triggerLine("OPEN_VALVE"), name == triggerLine("SWITCHING_VALVE"):
+			// Driven by the cycle sequence
+		case gpio.Role == RoleSpare, gpio.Role == RoleTamper, gpio.Role == RolePowerFail,
+			gpio.Role == RoleHeartbeat, gpio.Role == RoleWatchdog, gpio.Role == RoleFeedback, gpio.Role == RolePwm,
+			gpio.Role == RoleLockout, gpio.Role == RoleInput:
+			// Handled by their own monitoring goroutines
+		case isSignalLine(name):
+			HandleLight(gpio)
 		default:
 			log.Printf("Unknown gpio %s.", gpio.Name)
 		}
 	}
+	startIndicator()
 	// Define GPIO sequence by starting go rotutines and triggering start event
-	go s.handleStartGpio(pumpChannel, reversePump, clean, openValve, switchingValve, light)
+	goBackground(func() { s.handleStartGpio(pumpChannel) })
 	pumpChannel <- pump
 }
 
-func (s *SimpleDriver) handleStartGpio(
-	pumpChannel chan gpio.GPIO,
-	reverse gpio.GPIO,
-	clean gpio.GPIO,
-	openValve gpio.GPIO,
-	switchingValve gpio.GPIO,
-	light gpio.GPIO) {
+func (s *SimpleDriver) handleStartGpio(pumpChannel chan gpio.GPIO) {
 	gpio := <-pumpChannel
 
 	// Wait for device service to be available
-	// FA SCHIFO MA NON ABBIAMO ALTERNATIVA FIN QUANDO NON VIENE FIXATO L'ERRORE DEL CORE METADATA
+	// Polling is the only option until the core-metadata availability issue is fixed
 	attempt := 0
 	startPipeline := false
 	for !startPipeline {
@@ -280,235 +325,181 @@ func (s *SimpleDriver) handleStartGpio(
 		//	}
 		//}
 		ds := interfaces.Service()
-		_, errGpio := ds.GetDeviceByName(ds.Name())
+		_, errGpio := ds.GetDeviceByName(deviceName())
 		if errGpio != nil {
 			attempt++
-			log.Printf("Attempt: %d. Device '%s' not available", attempt, ds.Name())
+			log.Printf("Attempt: %d. Device '%s' not available", attempt, deviceName())
 			if attempt > MAX_RETRY {
+				recordShutdown(shutdownExit, fmt.Sprintf("device %s not available", deviceName()))
 				os.Exit(0)
 			}
-			time.Sleep(5 * time.Second)
-			continue
-		}
-		response, errModbus := http.Get(os.Getenv("MODBUS_DEVICE_ENDPOINT"))
-		if errModbus != nil {
-			log.Printf("Device 'Modbus-Device' not available. Error: %s", errModbus)
-			time.Sleep(5 * time.Second)
-			continue
-		}
-		body, err := ioutil.ReadAll(response.Body)
-		if err != nil {
-			log.Printf("Cannot fetch HTTP response body. Error: %s", err)
-			log.Printf("HTTP response status code: %d", response.StatusCode)
-			if response.StatusCode == 200 {
-				startPipeline = true
+			if !sleepUntilStop(5 * time.Second) {
+				return
 			}
 			continue
 		}
-		log.Printf("Modbus-Device response: %s", string(body))
 		startPipeline = true
 	}
+	// Then for the devices this service depends on
+	waitForDependencies()
+	if stagger := startupStagger(); stagger > 0 {
+		log.Printf("Staggering first actuation by %s", stagger)
+		if !sleepUntilStop(stagger) {
+			return
+		}
+	}
 	sleepForGap := false
+	runFor := *pumpTimer
+	gpio, runFor = s.awaitLeadership(gpio, runFor)
+	gpio, runFor = s.resumePipeline(gpio, runFor)
+	s.warmUp(gpio)
+	var cycle *Operation
 
 	for {
+		gpio, runFor = s.awaitLeadership(gpio, runFor)
 		if !gpio.State {
-			err := gpio.Up()
-			if err != nil {
-				err = Up('R')
-				if err != nil {
-					log.Printf("Error: %s", err)
+			if wait := scheduleWait(); wait > 0 {
+				supervisedSleep("pipeline", wait)
+				continue
+			}
+			if !shouldStartCycle() {
+				logf(moduleStateMachine, levelInfo, "Pump cycle postponed by start_cycle script")
+				supervisedSleep("pipeline", time.Minute)
+				continue
+			}
+			if planMode {
+				s.publishCyclePlan(cyclePumpDuration())
+				supervisedSleep("pipeline", *commandGap)
+				continue
+			}
+			if draining() {
+				logf(moduleStateMachine, levelInfo, "Pump cycle skipped, draining for shutdown")
+				supervisedSleep("pipeline", *commandGap)
+				continue
+			}
+			if lockedOut() {
+				logf(moduleStateMachine, levelInfo, "Pump cycle skipped, lockout held by %v", lockouts())
+				supervisedSleep("pipeline", *commandGap)
+				continue
+			}
+			if powerHeld() {
+				logf(moduleStateMachine, levelInfo, "Pump cycle held, battery level low")
+				supervisedSleep("pipeline", driverConfig.Power.interval)
+				continue
+			}
+			if healthPaused() {
+				logf(moduleStateMachine, levelInfo, "Pump cycle held by a failing health probe")
+				supervisedSleep("pipeline", dependencyInterval)
+				continue
+			}
+			if waiting := unavailableDependencies(dependencyWait); len(waiting) > 0 {
+				logf(moduleStateMachine, levelInfo, "Pump cycle held, waiting for devices %v", waiting)
+				supervisedSleep("pipeline", dependencyInterval)
+				continue
+			}
+			gpio = selectPump(gpio)
+			if err := s.checkDutyLimit(gpio.Name, time.Duration(nextPumpDuration())*time.Second); err != nil {
+				wait := *commandGap
+				var limited *DutyLimitError
+				if errors.As(err, &limited) && limited.Limit.Action == limitDefer && limited.Wait > 0 {
+					wait = limited.Wait
 				}
-				log.Printf("Cannot activate pump on gpio: %d. Error: %s", gpio.Line, err)
-				time.Sleep(time.Second)
+				logf(moduleStateMachine, levelWarn, "Pump cycle postponed by %s. Error: %s", wait, err)
+				supervisedSleep("pipeline", wait)
+				continue
+			}
+			if err := runPhaseHooks("pump", hookPre); err != nil {
+				logf(moduleStateMachine, levelWarn, "Skipping pump cycle. Error: %s", err)
+				supervisedSleep("pipeline", *commandGap)
 				continue
 			}
+			err := s.startPipelineLine(&gpio)
+			if errors.Is(err, errConcurrencyLimit) {
+				logf(moduleStateMachine, levelWarn, "Pump cycle deferred. Error: %s", err)
+				supervisedSleep("pipeline", concurrencyTick)
+				continue
+			}
+			if err == nil {
+				gpio, err = s.confirmPump(gpio)
+			}
+			if err != nil {
+				setFault("pump", err)
+				logSampledf(moduleStateMachine, levelError, "activate pump", "Cannot activate pump on gpio: %d. Error: %s", gpio.Line, err)
+				supervisedSleep("pipeline", time.Second)
+				continue
+			}
+			clearFault("pump")
+			logRecovered("activate pump")
 			gpio.State = true
+			s.nextABSet()
+			runFor = nextPumpDuration()
+			cleanAllowed := !cleanPlanned() || acquireCleanLock()
+			beginNextCycle(runFor, cleanAllowed)
+			scheduledCycleStarted()
+			cycle = startOperation("cycle", gpio.Name, cycleDuration(runFor))
+			publishSystemEvent(systemEventTypeCycle, systemEventActionStart, map[string]interface{}{"pump": gpio.Name, "duration": runFor})
+			setPhase(phasePump, time.Duration(runFor)*time.Second)
 			// Get timestamp to temporize GPIO flow control
 			*startTs = time.Now().Unix()
-			err = Up('G')
-			if err != nil {
-				log.Printf("Error: %s", err)
-			}
+			notePipelinePump(gpio.Name, *startTs, runFor)
 			// Handle async core data communication
 			s.handleAsyncCommunication(gpio)
 		} else {
-			if time.Now().Unix()-*startTs >= *pumpTimer {
-				err := gpio.Down()
+			if time.Now().Unix()-*startTs >= runFor {
+				err := setPipelineLevel(&gpio, false)
 				if err != nil {
-					err = Up('R')
-					if err != nil {
-						log.Printf("Error: %s", err)
-					}
-					log.Printf("Cannot deactivate pump on gpio: %d. Error: %s", gpio.Line, err)
-					time.Sleep(time.Second)
+					setFault("pump", err)
+					logSampledf(moduleStateMachine, levelError, "deactivate pump", "Cannot deactivate pump on gpio: %d. Error: %s", gpio.Line, err)
+					supervisedSleep("pipeline", time.Second)
 					continue
 				}
+				clearFault("pump")
+				logRecovered("deactivate pump")
 				gpio.State = false
-				err = Down('G')
-				if err != nil {
-					log.Printf("Error: %s", err)
+				notePipelineLine(gpio.Name, false)
+				// Run the sequence of the phases following the pump (reverse, clean, ...)
+				if err := runPhaseHooks("pump", hookPost); err != nil {
+					logf(moduleStateMachine, levelWarn, "Skipping cycle sequence. Error: %s", err)
+				} else if err := sequence.Run(cycleSequence, &sequenceActuator{s: s}); err != nil {
+					logf(moduleStateMachine, levelError, "Cycle sequence %s stopped. Error: %s", cycleSequence.Name, err)
 				}
-				// Add logic to handle pump reverse and electrovalves actuation
-				if *enableReverse {
-					s.handleReverseGpio(reverse, clean, openValve, switchingValve, light)
+				if cycle != nil {
+					cycle.finish(nil)
+					cycle = nil
 				}
+				endNextCycle(runFor)
+				releaseCleanLock()
+				countCycle(runFor)
 				sleepForGap = true
 				// Handle async core data communication
 				s.handleAsyncCommunication(gpio)
 			} else {
-				log.Printf("Pump will run for %d s...", *pumpTimer-(time.Now().Unix()-*startTs))
-				time.Sleep(time.Duration(*pumpTimer) * time.Second)
+				debugf(moduleStateMachine, "Pump will run for %d s...", runFor-(time.Now().Unix()-*startTs))
+				supervisedSleep("pipeline", time.Duration(runFor)*time.Second)
 			}
 		}
+		// The schedule times the next cycle instead of the command gap
+		if sleepForGap && driverConfig.Schedule != nil {
+			setPhase(phaseGap, untilScheduledCycle())
+			sleepForGap = false
+		}
 		// Sleep for the specified commandGap time...
 		if sleepForGap {
 			// Wait for commandGap timeout
-			log.Printf("Pump timeout. Sleeping for %d minutes...", int64(commandGap.Minutes()))
-			time.Sleep(*commandGap)
+			gap := deratedGap(*commandGap)
+			logf(moduleStateMachine, levelInfo, "Pump timeout. Sleeping for %d minutes...", int64(gap.Minutes()))
+			setPhase(phaseGap, gap)
+			sleepGap(gap)
 			sleepForGap = false
 		}
 	}
 }
 
-func (s *SimpleDriver) handleReverseGpio(
-	reverse gpio.GPIO,
-	clean gpio.GPIO,
-	openValve gpio.GPIO,
-	switchingValve gpio.GPIO,
-	light gpio.GPIO) {
-	log.Println("Reverting pump...")
-	err := reverse.Up()
-	if err != nil {
-		err = Up('R')
-		if err != nil {
-			log.Printf("Error: %s", err)
-		}
-		log.Printf("Cannot start cleaning process on gpio: %d. Error: %s", reverse.Line, err)
-		return
-	}
-	reverse.State = true
-	SetFlashOn('G')
-	go Flashing('G')
-	// Handle async core data communication
-	s.handleAsyncCommunication(reverse)
-	// Sleep for user defined cleaning duration
-	time.Sleep(*reverseTimer)
-	// Toggle Reverse pump GPIO
-	reverse.Down()
-	if err != nil {
-		err = Up('R')
-		if err != nil {
-			log.Printf("Error: %s", err)
-		}
-		log.Printf("Cannot stop reverting process on gpio: %d. Error: %s", reverse.Line, err)
-		return
-	}
-	reverse.State = false
-	SetFlashOff('G')
-	// Handle async core data communication
-	s.handleAsyncCommunication(reverse)
-	log.Println("Circuit is now empty!")
-	// Launch Cleaning process
-	if *enableClean {
-		s.handleCleanGpio(clean, openValve, switchingValve, light)
-	}
-}
-
-func (s *SimpleDriver) handleCleanGpio(
-	clean gpio.GPIO,
-	openValve gpio.GPIO,
-	switchingValve gpio.GPIO,
-	light gpio.GPIO) {
-	log.Printf("Step 1 -> Switching hydraulic circuit with switching valve on gpio %d", switchingValve.Line)
-	err := switchingValve.Up()
-	if err != nil {
-		err = Up('R')
-		if err != nil {
-			log.Printf("Error: %s", err)
-		}
-		log.Printf("Cannot switch the hydraulic circuit. Error: %s", err)
-		return
-	}
-	time.Sleep(switchingTimer)
-	log.Printf("Step 2 -> Enable cleaning inlet with open valve on gpio %d", openValve.Line)
-	err = openValve.Up()
-	if err != nil {
-		err = Up('R')
-		if err != nil {
-			log.Printf("Error: %s", err)
-		}
-		log.Printf("Cannot open the washing circuit. Error: %s", err)
-		return
-	}
-	time.Sleep(openingTimer)
-	log.Println("Step 3 -> Performing circuit clean up...")
-	err = clean.Up()
-	if err != nil {
-		err = Up('R')
-		if err != nil {
-			log.Printf("Error: %s", err)
-		}
-		log.Printf("Cannot start cleaning process on gpio: %d. Error: %s", clean.Line, err)
-		return
-	}
-	clean.State = true
-	err = Up('Y')
-	if err != nil {
-		log.Printf("Error: %s", err)
-	}
-	// Handle async core data communication
-	s.handleAsyncCommunication(clean)
-	// Sleep for user defined cleaning duration
-	time.Sleep(*cleanTimer)
-	// Toggle Clean pump GPIO
-	clean.Down()
-	if err != nil {
-		err = Up('R')
-		if err != nil {
-			log.Printf("Error: %s", err)
-		}
-		log.Printf("Cannot stop cleaning process on gpio: %d. Error: %s", clean.Line, err)
-		return
-	}
-	clean.State = false
-	err = Down('Y')
-	if err != nil {
-		log.Printf("Error: %s", err)
-	}
-	// Handle async core data communication
-	s.handleAsyncCommunication(clean)
-	log.Printf("Restoring circuit behaviour...")
-	err = openValve.Down()
-	if err != nil {
-		err = Up('R')
-		if err != nil {
-			log.Printf("Error: %s", err)
-		}
-		log.Printf("Cannot close the washing circuit. Error: %s", err)
-		return
-	}
-	time.Sleep(openingTimer)
-	// Add some delay to make cleaning liquid exit by gravity
-	time.Sleep(*gravityTimer)
-	err = switchingValve.Down()
-	if err != nil {
-		err = Up('R')
-		if err != nil {
-			log.Printf("Error: %s", err)
-		}
-		log.Printf("Cannot restore hydraulic circuit behaviour. Error: %s", err)
-		return
-	}
-	time.Sleep(switchingTimer)
-	log.Println("Circuit cleaned!")
-}
-
 func (s *SimpleDriver) handleAsyncCommunication(gpio gpio.GPIO) {
+	updateLineStats(gpio)
+	recordTransition(gpio.Name, gpio.State)
 	res := make([]*sdkModels.CommandValue, 1)
-	gpiod, err := json.Marshal(map[string]interface{}{
-		"gpio":       gpio,
-		"gpioConfig": &gpioConfig,
-	})
+	gpiod, err := shapeGpioPayload(gpio)
 	var cv *sdkModels.CommandValue
 
 	if err != nil {
@@ -518,33 +509,77 @@ func (s *SimpleDriver) handleAsyncCommunication(gpio gpio.GPIO) {
 		cv, _ = sdkModels.NewCommandValue("GPIO", common.ValueTypeString, string(gpiod))
 	}
 	log.Println("Pushing gpio to EdgeX Core Data")
+	if gpio.LineName != "" {
+		// Traces the chip and offset the line name resolved to on this board
+		cv.Tags = map[string]string{"lineName": gpio.LineName, "chip": gpio.Chip, "offset": strconv.Itoa(gpio.Line)}
+	}
 	res[0] = cv
+	derived := derivedCommandValues(gpio)
+	res = append(res, derived...)
+	recordSample(gpio.Name, boolSample(gpio.State))
+	for _, cv := range derived {
+		if value, ok := numericValue(cv); ok {
+			recordSample(cv.DeviceResourceName, value)
+		}
+	}
+	res = append(res, thresholdCrossings(derived)...)
+	tagABSet(res)
 	asyncValues := &sdkModels.AsyncValues{
-		DeviceName:    "device-gpiod",
+		DeviceName:    deviceName(),
 		CommandValues: res,
 	}
-	s.asyncCh <- asyncValues
+	for _, values := range phaseReadings(gpio.Name, asyncValues) {
+		s.asyncCh <- values
+	}
 	s.lc.Info(fmt.Sprintf("Data sent to core data: %s", string(gpiod)))
 }
 
 // ProcessCustomConfigChanges ...
 func (s *SimpleDriver) ProcessCustomConfigChanges(rawWritableConfig interface{}) {
-	updated, ok := rawWritableConfig.(*config.SimpleWritable)
+	updated, ok := rawWritableConfig.(*config.GpiodWritable)
 	if !ok {
-		s.lc.Error("unable to process custom config updates: Can not cast raw config to type 'SimpleWritable'")
+		s.lc.Error("unable to process custom config updates: Can not cast raw config to type 'GpiodWritable'")
 		return
 	}
 
-	s.lc.Info("Received configuration updates for 'SimpleCustom.Writable' section")
+	s.lc.Info("Received configuration updates for 'GpiodCustom.Writable' section")
 
-	previous := s.serviceConfig.SimpleCustom.Writable
-	s.serviceConfig.SimpleCustom.Writable = *updated
+	previous := s.serviceConfig.GpiodCustom.Writable
+	s.serviceConfig.GpiodCustom.Writable = *updated
 
 	if reflect.DeepEqual(previous, *updated) {
 		s.lc.Info("No changes detected")
 		return
 	}
 
+	if previous.LogLevels != updated.LogLevels {
+		if err := s.applyLogLevels(updated.LogLevels); err != nil {
+			s.lc.Errorf("Rejecting 'GpiodCustom.Writable.LogLevels' update. Error: %s", err)
+			s.serviceConfig.GpiodCustom.Writable.LogLevels = previous.LogLevels
+		}
+	}
+
+	if cycleSettingsChanged(previous, *updated) {
+		if err := s.applyWritable(*updated, "consul"); err != nil {
+			s.lc.Errorf("Rejecting 'GpiodCustom.Writable' update. Error: %s", err)
+			s.serviceConfig.GpiodCustom.Writable = previous
+			return
+		}
+	}
+
+	rollback := func() {
+		s.serviceConfig.GpiodCustom.Writable = previous
+		if err := s.applyWritable(previous, "rollback"); err != nil {
+			s.lc.Errorf("Cannot restore cycle settings of profile %s. Error: %s", previous.ActiveProfile, err)
+		}
+	}
+	if err := stageConfig("consul", rollback); err != nil {
+		s.lc.Errorf("Rejecting 'GpiodCustom.Writable' update. Error: %s", err)
+		rollback()
+		return
+	}
+	publishSystemEvent(systemEventTypeConfig, systemEventActionReload, map[string]interface{}{"source": "consul", "writable": updated})
+
 	// Now check to determine what changed.
 	// In this example we only have the one writable setting,
 	// so the check is not really need but left here as an example.
@@ -560,7 +595,25 @@ func (s *SimpleDriver) ProcessCustomConfigChanges(rawWritableConfig interface{})
 func (s *SimpleDriver) HandleReadCommands(deviceName string, protocols map[string]models.ProtocolProperties, reqs []sdkModels.CommandRequest) (res []*sdkModels.CommandValue, err error) {
 	s.lc.Debugf("SimpleDriver.HandleReadCommands: protocols: %v resource: %v attributes: %v", protocols, reqs[0].DeviceResourceName, reqs[0].Attributes)
 
-	return nil, fmt.Errorf("RestDriver.HandleReadCommands; read commands not supported")
+	res = make([]*sdkModels.CommandValue, len(reqs))
+	if !isServiceDevice(deviceName) {
+		d, ok := findDeviceLine(deviceName)
+		if !ok {
+			return nil, commandError("SimpleDriver.HandleReadCommands", fmt.Errorf("%w %s", errUnknownDevice, deviceName))
+		}
+		for i, req := range reqs {
+			if res[i], err = readDeviceLine(d, req); err != nil {
+				return nil, commandError("SimpleDriver.HandleReadCommands", err)
+			}
+		}
+		return res, nil
+	}
+	for i, req := range reqs {
+		if res[i], err = s.readResource(req); err != nil {
+			return nil, commandError("SimpleDriver.HandleReadCommands", err)
+		}
+	}
+	return res, nil
 }
 
 // HandleWriteCommands passes a slice of CommandRequest struct each representing
@@ -569,8 +622,26 @@ func (s *SimpleDriver) HandleReadCommands(deviceName string, protocols map[strin
 // command.
 func (s *SimpleDriver) HandleWriteCommands(deviceName string, protocols map[string]models.ProtocolProperties, reqs []sdkModels.CommandRequest,
 	params []*sdkModels.CommandValue) error {
+	s.lc.Debugf("SimpleDriver.HandleWriteCommands: protocols: %v resource: %v attributes: %v", protocols, reqs[0].DeviceResourceName, reqs[0].Attributes)
 
-	return fmt.Errorf("RestDriver.HandleWriteCommands; write commands not supported")
+	if !isServiceDevice(deviceName) {
+		d, ok := findDeviceLine(deviceName)
+		if !ok {
+			return commandError("SimpleDriver.HandleWriteCommands", fmt.Errorf("%w %s", errUnknownDevice, deviceName))
+		}
+		for i := range reqs {
+			if err := s.writeDeviceLine(d, params[i]); err != nil {
+				return commandError("SimpleDriver.HandleWriteCommands", err)
+			}
+		}
+		return nil
+	}
+	for i, req := range reqs {
+		if err := s.writeResource(req, params[i]); err != nil {
+			return commandError("SimpleDriver.HandleWriteCommands", err)
+		}
+	}
+	return nil
 }
 
 // Stop the protocol-specific DS code to shutdown gracefully, or
@@ -582,6 +653,31 @@ func (s *SimpleDriver) Stop(force bool) error {
 	if s.lc != nil {
 		s.lc.Debugf("SimpleDriver.Stop called: force=%v", force)
 	}
+	if force {
+		recordShutdown(shutdownForced, "")
+	} else {
+		recordShutdown(shutdownStop, "")
+		s.drain()
+	}
+	timeout := stopTimeout()
+	stopBackground(timeout)
+	releaseCleanLock()
+	stopGrpc()
+	stopModbusServer()
+	stopOpcuaServer()
+	stopWatchdog()
+	stopHeartbeat()
+	if force {
+		s.failSafe("all", "forced stop", true)
+	} else if s.GpioList != nil {
+		s.driveSafeState("stop", true)
+	}
+	s.releaseLines(timeout)
+	s.stopOnce.Do(func() {
+		if s.stopped != nil {
+			close(s.stopped)
+		}
+	})
 	return nil
 }
 
@@ -589,48 +685,30 @@ func (s *SimpleDriver) Stop(force bool) error {
 // when a new Device associated with this Device Service is added
 func (s *SimpleDriver) AddDevice(deviceName string, protocols map[string]models.ProtocolProperties, adminState models.AdminState) error {
 	s.lc.Debugf("a new Device is added: %s", deviceName)
-	return nil
+	publishSystemEvent(systemEventTypeDevice, systemEventActionAdd, map[string]interface{}{"name": deviceName, "protocols": protocols})
+	return s.addGpioDevice(deviceName, protocols)
 }
 
 // UpdateDevice is a callback function that is invoked
 // when a Device associated with this Device Service is updated
 func (s *SimpleDriver) UpdateDevice(deviceName string, protocols map[string]models.ProtocolProperties, adminState models.AdminState) error {
 	s.lc.Debugf("Device %s is updated", deviceName)
-	return nil
+	removeGpioDevice(deviceName)
+	return s.addGpioDevice(deviceName, protocols)
 }
 
 // RemoveDevice is a callback function that is invoked
 // when a Device associated with this Device Service is removed
 func (s *SimpleDriver) RemoveDevice(deviceName string, protocols map[string]models.ProtocolProperties) error {
 	s.lc.Debugf("Device %s is removed", deviceName)
+	removeGpioDevice(deviceName)
 	return nil
 }
 
 // Discover triggers protocol specific device discovery, which is an asynchronous operation.
 // Devices found as part of this discovery operation are written to the channel devices.
 func (s *SimpleDriver) Discover() {
-	proto := make(map[string]models.ProtocolProperties)
-	proto["other"] = map[string]string{"Address": "simple02", "Port": "301"}
-
-	device2 := sdkModels.DiscoveredDevice{
-		Name:        "Simple-Device02",
-		Protocols:   proto,
-		Description: "found by discovery",
-		Labels:      []string{"auto-discovery"},
-	}
-
-	proto = make(map[string]models.ProtocolProperties)
-	proto["other"] = map[string]string{"Address": "simple03", "Port": "399"}
-
-	device3 := sdkModels.DiscoveredDevice{
-		Name:        "Simple-Device03",
-		Protocols:   proto,
-		Description: "found by discovery",
-		Labels:      []string{"auto-discovery"},
-	}
-
-	res := []sdkModels.DiscoveredDevice{device2, device3}
-
-	time.Sleep(time.Duration(s.serviceConfig.SimpleCustom.Writable.DiscoverSleepDurationSecs) * time.Second)
-	s.deviceCh <- res
+	devices := s.discoveredDevices()
+	log.Printf("Discovered %d gpio lines", len(devices))
+	s.deviceCh <- devices
 }
@@ -0,0 +1,296 @@
+package driver
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/edgexfoundry/device-gpiod/config"
+	"github.com/edgexfoundry/device-gpiod/gpio"
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/clients/logger"
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/common"
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/models"
+
+	sdkModels "github.com/edgexfoundry/device-sdk-go/v2/pkg/models"
+)
+
+const testLines = `
+gpio:
+  - name: relay
+    chip: gpiochip0
+    line: 0
+  - name: button
+    chip: gpiochip0
+    line: 1
+    direction: input
+`
+
+// newTestDriver returns a driver over the lines of config on a fresh simulator, its readings sent to
+// the returned channel as Initialize would.
+func newTestDriver(t *testing.T, config string) (*SimpleDriver, *gpio.Simulator, chan *sdkModels.AsyncValues) {
+	t.Helper()
+	sim := gpio.NewSimulator()
+	gpio.SetBackend(sim)
+	list := &gpio.GPIOList{}
+	if err := list.Load([]byte(config)); err != nil {
+		t.Fatalf("cannot load test lines: %s", err)
+	}
+	saved := gpioConfig
+	gpioConfig = &Config{PumpTimer: MIN_PUMP, CommandGap: MIN_COMMAND_GAP}
+	t.Cleanup(func() { gpioConfig = saved })
+	readings := make(chan *sdkModels.AsyncValues, 16)
+	s := &SimpleDriver{lc: logger.NewMockClient(), GpioList: list, stopped: make(chan struct{})}
+	s.asyncCh = batchReadings(readings, s.stopped)
+	return s, sim, readings
+}
+
+func boolParam(t *testing.T, resource string, value bool) *sdkModels.CommandValue {
+	t.Helper()
+	cv, err := sdkModels.NewCommandValue(resource, common.ValueTypeBool, value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cv
+}
+
+func TestInitializeRejectsInvalidInstanceName(t *testing.T) {
+	sim := gpio.NewSimulator()
+	gpio.SetBackend(sim)
+	t.Setenv("INSTANCE_NAME", "not a name")
+
+	s := &SimpleDriver{GpioList: &gpio.GPIOList{}}
+	err := s.Initialize(logger.NewMockClient(), make(chan *sdkModels.AsyncValues, 1), make(chan []sdkModels.DiscoveredDevice, 1))
+	if err == nil || !strings.Contains(err.Error(), "INSTANCE_NAME") {
+		t.Fatalf("Initialize returned %v, want an INSTANCE_NAME error", err)
+	}
+	for _, line := range sim.Enumerate() {
+		if line.Used {
+			t.Errorf("line %d of %s requested by a failed Initialize", line.Line, line.Chip)
+		}
+	}
+}
+
+func TestHandleReadCommands(t *testing.T) {
+	s, _, _ := newTestDriver(t, testLines)
+	protocols := map[string]models.ProtocolProperties{}
+
+	_, err := s.HandleReadCommands("no-such-device", protocols, []sdkModels.CommandRequest{{DeviceResourceName: "relay"}})
+	if !errors.Is(err, errUnknownDevice) || !strings.Contains(err.Error(), "[unknown-device]") {
+		t.Errorf("read of unknown device returned %v", err)
+	}
+
+	_, err = s.HandleReadCommands(deviceName(), protocols, []sdkModels.CommandRequest{{DeviceResourceName: "nothing"}})
+	if !errors.Is(err, errUnknownLine) || !strings.Contains(err.Error(), "[unknown-line]") {
+		t.Errorf("read of unknown resource returned %v", err)
+	}
+
+	// Input lines are read from their watch, as Initialize starts it
+	s.startInputMonitoring()
+	res, err := s.HandleReadCommands(deviceName(), protocols, []sdkModels.CommandRequest{{DeviceResourceName: "button", Type: common.ValueTypeBool}})
+	if err != nil {
+		t.Fatalf("read of button failed: %s", err)
+	}
+	if value, err := res[0].BoolValue(); err != nil || value {
+		t.Errorf("button read %v (%v), want false", value, err)
+	}
+}
+
+func TestHandleWriteCommands(t *testing.T) {
+	s, _, readings := newTestDriver(t, testLines)
+	protocols := map[string]models.ProtocolProperties{}
+	sync := map[string]interface{}{syncAttribute: true}
+
+	write := func(resource string, on bool) error {
+		req := sdkModels.CommandRequest{DeviceResourceName: resource, Type: common.ValueTypeBool, Attributes: sync}
+		return s.HandleWriteCommands(deviceName(), protocols, []sdkModels.CommandRequest{req}, []*sdkModels.CommandValue{boolParam(t, resource, on)})
+	}
+
+	if err := write("relay", true); err != nil {
+		t.Fatalf("write of relay failed: %s", err)
+	}
+	relay, _ := s.findGpio("relay")
+	if value, err := relay.ReadBack(); err != nil || value != 1 {
+		t.Errorf("relay reads %d (%v) after the write, want 1", value, err)
+	}
+	select {
+	case values := <-readings:
+		if values.DeviceName != deviceName() {
+			t.Errorf("reading published for %s, want %s", values.DeviceName, deviceName())
+		}
+	case <-time.After(time.Second):
+		t.Error("no reading published for the write")
+	}
+
+	err := s.HandleWriteCommands("no-such-device", protocols, []sdkModels.CommandRequest{{DeviceResourceName: "relay"}},
+		[]*sdkModels.CommandValue{boolParam(t, "relay", true)})
+	if !errors.Is(err, errUnknownDevice) || !strings.Contains(err.Error(), "[unknown-device]") {
+		t.Errorf("write to unknown device returned %v", err)
+	}
+	if err := write("button", true); !errors.Is(err, errNotWritable) || !strings.Contains(err.Error(), "[not-writable]") {
+		t.Errorf("write of input returned %v", err)
+	}
+
+	gpio.Inhibit("test")
+	defer gpio.ReleaseInhibit("test")
+	if err := write("relay", false); err != nil {
+		t.Errorf("write of relay to its safe state refused while inhibited: %s", err)
+	}
+	<-readings
+	if err := write("relay", true); !errors.Is(err, gpio.ErrInhibited) || !strings.Contains(err.Error(), "[inhibited]") {
+		t.Errorf("write of relay while inhibited returned %v", err)
+	}
+}
+
+func TestWriteOverride(t *testing.T) {
+	s, _, _ := newTestDriver(t, testLines)
+	write := func(duration string) error {
+		req := sdkModels.CommandRequest{DeviceResourceName: "relay", Type: common.ValueTypeBool, Attributes: map[string]interface{}{overrideAttribute: duration}}
+		return s.HandleWriteCommands(deviceName(), map[string]models.ProtocolProperties{}, []sdkModels.CommandRequest{req}, []*sdkModels.CommandValue{boolParam(t, "relay", true)})
+	}
+
+	if err := write("2h"); !errors.Is(err, errOverrideDuration) || !strings.Contains(err.Error(), "[invalid-override]") {
+		t.Errorf("override beyond the maximum returned %v", err)
+	}
+	if err := write("1m"); err != nil {
+		t.Fatalf("override of relay failed: %s", err)
+	}
+	relay, _ := s.findGpio("relay")
+	defer relay.Revert()
+	if _, overridden := relay.Overridden(); !overridden {
+		t.Error("relay not overridden by the write")
+	}
+	if value, err := relay.ReadBack(); err != nil || value != 1 {
+		t.Errorf("relay reads %d (%v) under override, want 1", value, err)
+	}
+
+	// A standby instance neither overrides nor queues a write
+	leaderMutex.Lock()
+	electionEnabled, leading = true, false
+	leaderMutex.Unlock()
+	defer func() {
+		leaderMutex.Lock()
+		electionEnabled = false
+		leaderMutex.Unlock()
+	}()
+	for attribute, value := range map[string]string{
+		overrideAttribute:  "1m",
+		executeAtAttribute: time.Now().Add(time.Hour).Format(time.RFC3339),
+	} {
+		req := sdkModels.CommandRequest{DeviceResourceName: "relay", Type: common.ValueTypeBool, Attributes: map[string]interface{}{attribute: value}}
+		err := s.HandleWriteCommands(deviceName(), map[string]models.ProtocolProperties{}, []sdkModels.CommandRequest{req}, []*sdkModels.CommandValue{boolParam(t, "relay", false)})
+		if !errors.Is(err, errStandby) {
+			t.Errorf("write of relay with %s on the standby returned %v", attribute, err)
+		}
+	}
+}
+
+func TestApplyWritable(t *testing.T) {
+	s, _, _ := newTestDriver(t, testLines)
+	savedTimers, savedPump, savedClean := envTimers, *pumpTimer, *enableClean
+	t.Cleanup(func() {
+		envTimers, startupTimers, *pumpTimer, *enableClean = savedTimers, savedTimers, savedPump, savedClean
+		triggers, modbusEndpoint, pumps = config.Triggers{}, "", pumpPair{}
+	})
+	envTimers = timerValues{pump: 5 * time.Minute, commandGap: time.Hour, clean: 5 * time.Minute,
+		reverse: 5 * time.Minute, gravity: 5 * time.Minute}
+	t.Setenv("START_TRIGGER", "")
+	t.Setenv("MODBUS_DEVICE_ENDPOINT", "http://env:502")
+
+	enabled := true
+	w := config.GpiodWritable{
+		PumpTimeoutSecs: 600,
+		EnableClean:     &enabled,
+		Triggers:        config.Triggers{Start: "relay"},
+		ModbusEndpoint:  "http://modbus:502",
+	}
+	if err := s.applyWritable(w, "test"); err != nil {
+		t.Fatalf("applyWritable failed: %s", err)
+	}
+	if *pumpTimer != 600 || gpioConfig.CommandGap != time.Hour || !gpioConfig.EnableClean {
+		t.Errorf("pump timer %ds, command gap %s and clean %v, want 600s, the COMMAND_GAP fallback and true",
+			*pumpTimer, gpioConfig.CommandGap, gpioConfig.EnableClean)
+	}
+	if line, endpoint := triggerLine("START_TRIGGER"), modbusDeviceEndpoint(); line != "relay" || endpoint != w.ModbusEndpoint {
+		t.Errorf("start trigger %q and Modbus endpoint %q, want relay and %s", line, endpoint, w.ModbusEndpoint)
+	}
+	if pump := selectPump(gpio.GPIO{}); pump.Name != "relay" {
+		t.Errorf("next cycle runs pump %q, want relay", pump.Name)
+	}
+
+	// A rejected update changes nothing
+	w.Triggers.Start = "missing"
+	if err := s.applyWritable(w, "test"); err == nil {
+		t.Error("applyWritable accepted an unknown trigger line")
+	}
+	if triggerLine("START_TRIGGER") != "relay" {
+		t.Error("start trigger changed by a rejected update")
+	}
+
+	// Left out, the settings fall back to the environment variables
+	if err := s.applyWritable(config.GpiodWritable{}, "test"); err != nil {
+		t.Fatalf("applyWritable failed: %s", err)
+	}
+	if *pumpTimer != 300 || modbusDeviceEndpoint() != "http://env:502" {
+		t.Errorf("pump timer %ds and Modbus endpoint %q, want the environment ones", *pumpTimer, modbusDeviceEndpoint())
+	}
+}
+
+// TestStop stops the background work of the package, then resets it for the tests run after it.
+func TestStop(t *testing.T) {
+	t.Cleanup(func() {
+		stopping = make(chan struct{})
+		stopOnce = sync.Once{}
+	})
+	s, _, readings := newTestDriver(t, testLines)
+	reportingMutex.Lock()
+	meteredActive = true
+	reportingMutex.Unlock()
+	defer func() {
+		reportingMutex.Lock()
+		meteredActive = false
+		reportingMutex.Unlock()
+	}()
+
+	if err := s.writeLine("relay", true, "test"); err != nil {
+		t.Fatalf("write of relay failed: %s", err)
+	}
+	select {
+	case <-readings:
+		t.Fatal("reading published before the end of the metered batch window")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	if err := s.Stop(false); err != nil {
+		t.Fatalf("Stop failed: %s", err)
+	}
+	select {
+	case <-readings:
+	case <-time.After(time.Second):
+		t.Fatal("batched reading not flushed by Stop")
+	}
+	relay, _ := s.findGpio("relay")
+	if relay.Held() {
+		t.Error("relay still held after Stop")
+	}
+
+	// Readings after Stop are dropped, their senders never blocking on a full channel
+	sent := make(chan struct{})
+	go func() {
+		for i := 0; i <= 2*cap(readings); i++ {
+			s.handleAsyncCommunication(*relay)
+		}
+		close(sent)
+	}()
+	select {
+	case <-sent:
+	case <-time.After(time.Second):
+		t.Fatal("reading sent after Stop blocked")
+	}
+	select {
+	case <-readings:
+		t.Error("reading sent after Stop")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
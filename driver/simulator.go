@@ -0,0 +1,73 @@
+package driver
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+
+	"github.com/edgexfoundry/device-gpiod/gpio"
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/common"
+)
+
+const simulatorEdgeRoute = common.ApiBase + "/simulator/edge"
+
+// simulatorEdgeRequest sets the level of a simulated input, by resource name or by chip and line.
+type simulatorEdgeRequest struct {
+	Name  string `json:"name,omitempty"`
+	Chip  string `json:"chip,omitempty"`
+	Line  *int   `json:"line,omitempty"`
+	Value int    `json:"value"`
+}
+
+// parseGpioBackend selects the backend of the lines from GPIO_BACKEND: gpiod (the default) drives the
+// gpiochips of the board, sim keeps the lines in memory so the service runs on a laptop or in CI.
+func parseGpioBackend() {
+	switch backend := os.Getenv("GPIO_BACKEND"); backend {
+	case "", "gpiod":
+	case "sim":
+		gpio.SetBackend(gpio.NewSimulator())
+		configWarning("Simulated GPIO backend: no line of the board is driven, inputs change through " + simulatorEdgeRoute)
+	default:
+		log.Printf("Cannot parse GPIO_BACKEND %q. Picking default value gpiod...", backend)
+	}
+}
+
+// handleSimulatorEdge injects a level change on a simulated input, raising the edge event a watched
+// line would see on the board.
+func (s *SimpleDriver) handleSimulatorEdge(w http.ResponseWriter, r *http.Request) {
+	sim := gpio.ActiveSimulator()
+	if sim == nil {
+		writeError(w, http.StatusConflict, errors.New("the simulated GPIO backend is not active"))
+		return
+	}
+	var req simulatorEdgeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	chip, line := req.Chip, -1
+	if req.Line != nil {
+		line = *req.Line
+	}
+	if req.Name != "" {
+		g, ok := s.findGpio(req.Name)
+		if !ok {
+			writeError(w, http.StatusNotFound, fmt.Errorf("unknown resource %s", req.Name))
+			return
+		}
+		chip, line = g.Chip, g.Line
+	}
+	if chip == "" || line < 0 {
+		writeError(w, http.StatusBadRequest, errors.New("either name or chip and line are required"))
+		return
+	}
+	if err := sim.Inject(chip, line, req.Value); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	audit("simulator-edge", fmt.Sprintf("%s/%d", chip, line), fmt.Sprintf("level %d", req.Value))
+	writeJSON(w, http.StatusOK, map[string]interface{}{"chip": chip, "line": line, "value": req.Value})
+}
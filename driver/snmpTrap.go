@@ -0,0 +1,205 @@
+package driver
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/edgexfoundry/device-sdk-go/v2/pkg/service"
+)
+
+const (
+	snmpSecretName        = "snmp"
+	defaultSnmpEnterprise = "1.3.6.1.4.1.8072.9999.9999.1"
+
+	trapFault                = 1
+	trapFaultCleared         = 2
+	trapConnectivityLost     = 3
+	trapConnectivityRestored = 4
+)
+
+var (
+	snmpManager    = ""
+	snmpVersion    = "2c"
+	snmpCommunity  = "public"
+	snmpEnterprise = defaultSnmpEnterprise
+	snmpStarted    = time.Now()
+)
+
+// parseSnmp reads the trap destination SNMP_MANAGER (host:port, traps disabled when unset), the
+// enterprise OID SNMP_ENTERPRISE_OID and SNMP_VERSION, 2c (default) or 3. The SNMPv2c community is
+// read from the "snmp" secret of the secret store, falling back to SNMP_COMMUNITY; the SNMPv3 user is
+// set up by parseSnmpV3, traps being disabled rather than sent in clear when it cannot be.
+func parseSnmp() {
+	snmpManager = os.Getenv("SNMP_MANAGER")
+	if snmpManager == "" {
+		return
+	}
+	if !strings.Contains(snmpManager, ":") {
+		snmpManager += ":162"
+	}
+	if oid := os.Getenv("SNMP_ENTERPRISE_OID"); oid != "" {
+		if _, err := encodeOID(oid); err != nil {
+			configWarning(fmt.Sprintf("Invalid SNMP_ENTERPRISE_OID %q, using %s", oid, defaultSnmpEnterprise))
+		} else {
+			snmpEnterprise = oid
+		}
+	}
+	switch version := os.Getenv("SNMP_VERSION"); version {
+	case "", "2c":
+	case "3":
+		if err := parseSnmpV3(); err != nil {
+			configWarning(fmt.Sprintf("SNMPv3 traps to %s disabled: %s", snmpManager, err))
+			snmpManager = ""
+			return
+		}
+		snmpVersion = version
+		log.Printf("SNMPv3 traps sent to %s as %s", snmpManager, snmpV3.name)
+		return
+	default:
+		configWarning(fmt.Sprintf("SNMP_VERSION %s is not supported, sending SNMPv2c traps", version))
+	}
+	if community := os.Getenv("SNMP_COMMUNITY"); community != "" {
+		snmpCommunity = community
+	}
+	secrets, err := readSecret(snmpSecretName, "community")
+	if err == nil && secrets["community"] != "" {
+		snmpCommunity = secrets["community"]
+	} else {
+		log.Printf("Cannot read SNMP community from the secret store, using SNMP_COMMUNITY. Error: %v", err)
+	}
+	log.Printf("SNMP traps sent to %s", snmpManager)
+}
+
+// readSecret reads the keys of a secret from the secret store of the running service.
+func readSecret(path string, keys ...string) (map[string]string, error) {
+	ds := service.RunningService()
+	if ds == nil || ds.GetSecretProvider() == nil {
+		return nil, errors.New("no secret provider")
+	}
+	return ds.GetSecretProvider().GetSecret(path, keys...)
+}
+
+// sendTrap emits an SNMPv2c or SNMPv3 trap <enterprise>.<trap> carrying the source and message as
+// <enterprise>.0.1 and <enterprise>.0.2. It is a no-op when no manager is configured.
+func sendTrap(trap int, source string, message string) {
+	if snmpManager == "" {
+		return
+	}
+	pdu, err := encodeTrapPDU(trap, source, message)
+	var packet []byte
+	if err == nil && snmpV3 != nil {
+		packet, err = encodeTrapV3(snmpV3, pdu)
+	} else if err == nil {
+		packet = encodeTrapV2c(pdu)
+	}
+	if err != nil {
+		log.Printf("Cannot encode SNMP trap. Error: %s", err)
+		return
+	}
+	go func() {
+		conn, err := net.DialTimeout("udp", snmpManager, time.Duration(5)*time.Second)
+		if err != nil {
+			log.Printf("Cannot send SNMP trap. Error: %s", err)
+			return
+		}
+		defer conn.Close()
+		if _, err := conn.Write(packet); err != nil {
+			log.Printf("Cannot send SNMP trap. Error: %s", err)
+		}
+	}()
+}
+
+// encodeTrapPDU encodes the SNMPv2-Trap-PDU, the same for both versions.
+func encodeTrapPDU(trap int, source string, message string) ([]byte, error) {
+	sysUpTime, _ := encodeOID("1.3.6.1.2.1.1.3.0")
+	snmpTrapOID, _ := encodeOID("1.3.6.1.6.3.1.1.4.1.0")
+	trapOID, err := encodeOID(fmt.Sprintf("%s.%d", snmpEnterprise, trap))
+	if err != nil {
+		return nil, err
+	}
+	sourceOID, _ := encodeOID(snmpEnterprise + ".0.1")
+	messageOID, _ := encodeOID(snmpEnterprise + ".0.2")
+	ticks := uint32(time.Since(snmpStarted) / (10 * time.Millisecond))
+
+	varbinds := ber(0x30, bytes.Join([][]byte{
+		ber(0x30, append(ber(0x06, sysUpTime), ber(0x43, encodeUint(ticks))...)),
+		ber(0x30, append(ber(0x06, snmpTrapOID), ber(0x06, trapOID)...)),
+		ber(0x30, append(ber(0x06, sourceOID), ber(0x04, []byte(source))...)),
+		ber(0x30, append(ber(0x06, messageOID), ber(0x04, []byte(message))...)),
+	}, nil))
+	return ber(0xA7, bytes.Join([][]byte{
+		ber(0x02, encodeUint(uint32(rand.Int31()))),
+		ber(0x02, []byte{0}),
+		ber(0x02, []byte{0}),
+		varbinds,
+	}, nil)), nil
+}
+
+func encodeTrapV2c(pdu []byte) []byte {
+	return ber(0x30, bytes.Join([][]byte{
+		ber(0x02, []byte{1}),
+		ber(0x04, []byte(snmpCommunity)),
+		pdu,
+	}, nil))
+}
+
+// ber encodes a BER type-length-value.
+func ber(tag byte, value []byte) []byte {
+	var length []byte
+	switch n := len(value); {
+	case n < 0x80:
+		length = []byte{byte(n)}
+	case n < 0x100:
+		length = []byte{0x81, byte(n)}
+	default:
+		length = []byte{0x82, byte(n >> 8), byte(n)}
+	}
+	return append(append([]byte{tag}, length...), value...)
+}
+
+// encodeUint encodes an unsigned value as the minimal positive BER integer content.
+func encodeUint(v uint32) []byte {
+	b := []byte{byte(v >> 24), byte(v >> 16), byte(v >> 8), byte(v)}
+	for len(b) > 1 && b[0] == 0 && b[1] < 0x80 {
+		b = b[1:]
+	}
+	if b[0] >= 0x80 {
+		b = append([]byte{0}, b...)
+	}
+	return b
+}
+
+func encodeOID(oid string) ([]byte, error) {
+	parts := strings.Split(strings.TrimPrefix(oid, "."), ".")
+	if len(parts) < 2 {
+		return nil, fmt.Errorf("invalid OID %q", oid)
+	}
+	arcs := make([]uint64, len(parts))
+	for i, part := range parts {
+		arc, err := strconv.ParseUint(part, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid OID %q", oid)
+		}
+		arcs[i] = arc
+	}
+	if arcs[0] > 2 || arcs[1] >= 40 {
+		return nil, fmt.Errorf("invalid OID %q", oid)
+	}
+	encoded := []byte{byte(arcs[0]*40 + arcs[1])}
+	for _, arc := range arcs[2:] {
+		chunk := []byte{byte(arc & 0x7F)}
+		for arc >>= 7; arc > 0; arc >>= 7 {
+			chunk = append([]byte{byte(arc&0x7F) | 0x80}, chunk...)
+		}
+		encoded = append(encoded, chunk...)
+	}
+	return encoded, nil
+}
@@ -0,0 +1,206 @@
+package driver
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/md5"
+	crand "crypto/rand"
+	"crypto/sha1"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"log"
+	"math/rand"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	snmpAuthMD5 = "MD5"
+	snmpAuthSHA = "SHA"
+
+	netSnmpEnterprise   = 8072
+	snmpMaxSize         = 65507
+	usmSecurityModel    = 3
+	usmFlagAuth         = 0x01
+	usmFlagPriv         = 0x02
+	usmAuthParamsLength = 12
+	usmMinKeyLength     = 8
+)
+
+// snmpUser is the USM user of the SNMPv3 traps, its keys localized to the engine ID of the service,
+// the authoritative engine of the traps it sends.
+type snmpUser struct {
+	name    string
+	hash    func() hash.Hash
+	authKey []byte
+	// AES-128 key, nil without privacy (authNoPriv)
+	privKey []byte
+}
+
+var (
+	snmpV3       *snmpUser
+	snmpEngineID []byte
+	snmpBoots    = uint32(1)
+)
+
+// parseSnmpV3 sets up the USM user of SNMP_VERSION 3. Its name (else SNMP_USER) and passphrases,
+// authKey and privKey, are read from the "snmp" secret of the secret store, never from the
+// environment. SNMP_AUTH_PROTOCOL is MD5 or SHA (default); privacy is AES-128 when a privKey is set
+// (authPriv), none otherwise (authNoPriv). The engine boots are counted in SNMP_ENGINE_BOOTS_FILE so
+// managers accept the traps of a restarted service; without it they stay at 1.
+func parseSnmpV3() error {
+	secrets, err := readSecret(snmpSecretName)
+	if err != nil {
+		return fmt.Errorf("cannot read the %s secret: %s", snmpSecretName, err)
+	}
+	user := secrets["user"]
+	if user == "" {
+		user = os.Getenv("SNMP_USER")
+	}
+	if user == "" {
+		return errors.New("no USM user in the secret or SNMP_USER")
+	}
+	if len(secrets["authKey"]) < usmMinKeyLength {
+		return fmt.Errorf("authKey of the %s secret missing or shorter than %d characters", snmpSecretName, usmMinKeyLength)
+	}
+	if privKey := secrets["privKey"]; privKey != "" && len(privKey) < usmMinKeyLength {
+		return fmt.Errorf("privKey of the %s secret shorter than %d characters", snmpSecretName, usmMinKeyLength)
+	}
+	newHash := sha1.New
+	switch protocol := os.Getenv("SNMP_AUTH_PROTOCOL"); protocol {
+	case "", snmpAuthSHA:
+	case snmpAuthMD5:
+		newHash = md5.New
+	default:
+		return fmt.Errorf("unknown SNMP_AUTH_PROTOCOL %q, expected %s or %s", protocol, snmpAuthMD5, snmpAuthSHA)
+	}
+
+	snmpEngineID = snmpEngineIDFor(snmpEnterprise, deviceName())
+	snmpBoots = countSnmpBoots()
+	snmpV3 = &snmpUser{
+		name:    user,
+		hash:    newHash,
+		authKey: localizeKey(newHash, secrets["authKey"], snmpEngineID),
+	}
+	if privKey := secrets["privKey"]; privKey != "" {
+		snmpV3.privKey = localizeKey(newHash, privKey, snmpEngineID)[:16]
+	}
+	return nil
+}
+
+// snmpEngineIDFor returns the RFC 3411 engine ID of the service: the enterprise number of the
+// enterprise OID (net-snmp's when it is not under 1.3.6.1.4.1), then the name as text.
+func snmpEngineIDFor(enterpriseOID string, name string) []byte {
+	enterprise := uint64(netSnmpEnterprise)
+	const enterprises = "1.3.6.1.4.1."
+	if oid := strings.TrimPrefix(enterpriseOID, "."); strings.HasPrefix(oid, enterprises) {
+		if number, err := strconv.ParseUint(strings.Split(oid[len(enterprises):], ".")[0], 10, 31); err == nil {
+			enterprise = number
+		}
+	}
+	if len(name) > 27 {
+		name = name[:27]
+	}
+	id := make([]byte, 4, 5+len(name))
+	binary.BigEndian.PutUint32(id, uint32(enterprise)|0x80000000)
+	return append(append(id, 4), name...)
+}
+
+// countSnmpBoots increments the engine boots kept in SNMP_ENGINE_BOOTS_FILE and returns them.
+func countSnmpBoots() uint32 {
+	fileName := os.Getenv("SNMP_ENGINE_BOOTS_FILE")
+	if fileName == "" {
+		return 1
+	}
+	boots := uint64(0)
+	data, err := os.ReadFile(fileName)
+	if err == nil {
+		boots, err = strconv.ParseUint(strings.TrimSpace(string(data)), 10, 31)
+	}
+	if err != nil && !os.IsNotExist(err) {
+		log.Printf("Cannot read SNMP engine boots file %s. Error: %s", fileName, err)
+	}
+	boots++
+	if err := os.WriteFile(fileName, []byte(strconv.FormatUint(boots, 10)), 0644); err != nil {
+		log.Printf("Cannot write SNMP engine boots file %s. Error: %s", fileName, err)
+	}
+	return uint32(boots)
+}
+
+// localizeKey derives the key of a passphrase localized to an engine ID, RFC 3414 A.2.
+func localizeKey(newHash func() hash.Hash, passphrase string, engineID []byte) []byte {
+	h := newHash()
+	chunk := make([]byte, 64)
+	for i := 0; i < 1048576; i += len(chunk) {
+		for j := range chunk {
+			chunk[j] = passphrase[(i+j)%len(passphrase)]
+		}
+		h.Write(chunk)
+	}
+	ku := h.Sum(nil)
+	h.Reset()
+	h.Write(ku)
+	h.Write(engineID)
+	h.Write(ku)
+	return h.Sum(nil)
+}
+
+// encodeTrapV3 wraps a trap PDU in an SNMPv3 message of the user, authenticated with HMAC-96 and,
+// with a privacy key, its scoped PDU encrypted with AES-128 in CFB mode (RFC 3826).
+func encodeTrapV3(user *snmpUser, pdu []byte) ([]byte, error) {
+	engineTime := uint32(time.Since(snmpStarted) / time.Second)
+	scoped := ber(0x30, bytes.Join([][]byte{ber(0x04, snmpEngineID), ber(0x04, nil), pdu}, nil))
+	flags := byte(usmFlagAuth)
+	data, privParams := scoped, []byte{}
+	if user.privKey != nil {
+		flags |= usmFlagPriv
+		salt := make([]byte, 8)
+		if _, err := crand.Read(salt); err != nil {
+			return nil, err
+		}
+		block, err := aes.NewCipher(user.privKey)
+		if err != nil {
+			return nil, err
+		}
+		iv := make([]byte, 8, aes.BlockSize)
+		binary.BigEndian.PutUint32(iv, snmpBoots)
+		binary.BigEndian.PutUint32(iv[4:], engineTime)
+		encrypted := make([]byte, len(scoped))
+		cipher.NewCFBEncrypter(block, append(iv, salt...)).XORKeyStream(encrypted, scoped)
+		data, privParams = ber(0x04, encrypted), salt
+	}
+
+	head := bytes.Join([][]byte{
+		ber(0x02, []byte{3}),
+		ber(0x30, bytes.Join([][]byte{
+			ber(0x02, encodeUint(uint32(rand.Int31()))),
+			ber(0x02, encodeUint(snmpMaxSize)),
+			ber(0x04, []byte{flags}),
+			ber(0x02, []byte{usmSecurityModel}),
+		}, nil)),
+	}, nil)
+	usmPrefix := bytes.Join([][]byte{
+		ber(0x04, snmpEngineID),
+		ber(0x02, encodeUint(snmpBoots)),
+		ber(0x02, encodeUint(engineTime)),
+		ber(0x04, []byte(user.name)),
+	}, nil)
+	usmContent := bytes.Join([][]byte{usmPrefix, ber(0x04, make([]byte, usmAuthParamsLength)), ber(0x04, privParams)}, nil)
+	usm := ber(0x30, usmContent)
+	securityParams := ber(0x04, usm)
+	body := bytes.Join([][]byte{head, securityParams, data}, nil)
+	msg := ber(0x30, body)
+
+	// The authentication parameters are computed over the message with them zeroed, then put in place
+	offset := len(msg) - len(body) + len(head) + len(securityParams) - len(usm) + len(usm) - len(usmContent) + len(usmPrefix) + 2
+	mac := hmac.New(user.hash, user.authKey)
+	mac.Write(msg)
+	copy(msg[offset:offset+usmAuthParamsLength], mac.Sum(nil))
+	return msg, nil
+}
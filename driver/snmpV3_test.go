@@ -0,0 +1,131 @@
+package driver
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha1"
+	"encoding/hex"
+	"hash"
+	"testing"
+)
+
+// TestLocalizeKey checks the key localization against the sample keys of RFC 3414 A.3.
+func TestLocalizeKey(t *testing.T) {
+	engineID, _ := hex.DecodeString("000000000000000000000002")
+	tests := []struct {
+		name     string
+		hash     func() hash.Hash
+		expected string
+	}{
+		{"MD5", md5.New, "526f5eed9fcce26f8964c2930787d82b"},
+		{"SHA", sha1.New, "6695febc9288e36282235fc7151f128497b38f3f"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if key := hex.EncodeToString(localizeKey(tt.hash, "maplesyrup", engineID)); key != tt.expected {
+				t.Errorf("localized key %s, want %s", key, tt.expected)
+			}
+		})
+	}
+}
+
+func TestSnmpEngineID(t *testing.T) {
+	tests := []struct {
+		enterprise string
+		expected   string
+	}{
+		{defaultSnmpEnterprise, "80001f8804" + hex.EncodeToString([]byte("gpiod"))},
+		{"1.3.6.1.4.1.99999.1", "8001869f04" + hex.EncodeToString([]byte("gpiod"))},
+		{".1.3.6.1.4.1.99999", "8001869f04" + hex.EncodeToString([]byte("gpiod"))},
+		{".1.3.6.1.2.1", "80001f8804" + hex.EncodeToString([]byte("gpiod"))},
+	}
+	for _, tt := range tests {
+		if id := hex.EncodeToString(snmpEngineIDFor(tt.enterprise, "gpiod")); id != tt.expected {
+			t.Errorf("engine ID of %s is %s, want %s", tt.enterprise, id, tt.expected)
+		}
+	}
+}
+
+func TestEncodeTrapV3(t *testing.T) {
+	snmpEngineID = snmpEngineIDFor(defaultSnmpEnterprise, "gpiod")
+	user := &snmpUser{
+		name:    "monitor",
+		hash:    sha1.New,
+		authKey: localizeKey(sha1.New, "authpassphrase", snmpEngineID),
+		privKey: localizeKey(sha1.New, "privpassphrase", snmpEngineID)[:16],
+	}
+	pdu, err := encodeTrapPDU(trapFault, "pump", "stalled")
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg, err := encodeTrapV3(user, pdu)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, content, _ := tlv(t, msg)
+	_, _, rest := tlv(t, content)
+	_, _, rest = tlv(t, rest)
+	_, usm, rest := tlv(t, rest)
+	_, encrypted, _ := tlv(t, rest)
+	_, usm, _ = tlv(t, usm)
+	_, engineID, usm := tlv(t, usm)
+	_, boots, usm := tlv(t, usm)
+	_, engineTime, usm := tlv(t, usm)
+	_, name, usm := tlv(t, usm)
+	_, authParams, usm := tlv(t, usm)
+	_, salt, _ := tlv(t, usm)
+	if !bytes.Equal(engineID, snmpEngineID) || string(name) != user.name {
+		t.Fatalf("message from engine %x user %s, want %x %s", engineID, name, snmpEngineID, user.name)
+	}
+
+	// The authentication parameters are the HMAC of the message with them zeroed
+	received := append([]byte(nil), authParams...)
+	copy(authParams, make([]byte, usmAuthParamsLength))
+	mac := hmac.New(sha1.New, user.authKey)
+	mac.Write(msg)
+	if !bytes.Equal(received, mac.Sum(nil)[:usmAuthParamsLength]) {
+		t.Fatalf("authentication parameters %x do not authenticate the message", received)
+	}
+
+	// The scoped PDU decrypts with the IV of the boots, the time and the salt
+	iv := make([]byte, 0, aes.BlockSize)
+	iv = append(iv, make([]byte, 4-len(boots))...)
+	iv = append(iv, boots...)
+	iv = append(iv, make([]byte, 4-len(engineTime))...)
+	iv = append(append(iv, engineTime...), salt...)
+	block, err := aes.NewCipher(user.privKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	scoped := make([]byte, len(encrypted))
+	cipher.NewCFBDecrypter(block, iv).XORKeyStream(scoped, encrypted)
+	_, scoped, _ = tlv(t, scoped)
+	_, contextEngineID, scoped := tlv(t, scoped)
+	_, _, scoped = tlv(t, scoped)
+	if !bytes.Equal(contextEngineID, snmpEngineID) || !bytes.Equal(scoped, pdu) {
+		t.Errorf("decrypted scoped PDU of engine %x with PDU %x, want %x %x", contextEngineID, scoped, snmpEngineID, pdu)
+	}
+}
+
+// tlv splits the first BER TLV of data into its tag and value, returning the rest after it.
+func tlv(t *testing.T, data []byte) (byte, []byte, []byte) {
+	t.Helper()
+	if len(data) < 2 {
+		t.Fatalf("truncated TLV %x", data)
+	}
+	length, header := int(data[1]), 2
+	switch data[1] {
+	case 0x81:
+		length, header = int(data[2]), 3
+	case 0x82:
+		length, header = int(data[2])<<8|int(data[3]), 4
+	}
+	if len(data) < header+length {
+		t.Fatalf("truncated TLV %x", data)
+	}
+	return data[0], data[header : header+length], data[header+length:]
+}
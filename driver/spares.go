@@ -0,0 +1,56 @@
+package driver
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/edgexfoundry/device-gpiod/gpio"
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/common"
+
+	sdkModels "github.com/edgexfoundry/device-sdk-go/v2/pkg/models"
+)
+
+const (
+	RoleSpare             = "spare"
+	spareActivityResource = "SpareActivity"
+	severityLow           = "low"
+)
+
+// startSpareMonitoring requests every line with role "spare" as input and reports any activity on it,
+// which on an unused line hints at wiring faults or tampering.
+func (s *SimpleDriver) startSpareMonitoring() {
+	for i := range s.GpioList.Gpio {
+		g := &s.GpioList.Gpio[i]
+		if g.Role != RoleSpare {
+			continue
+		}
+		if err := g.Watch(s.handleSpareEvent); err != nil {
+			log.Printf("Cannot monitor spare gpio %s. Error: %s", g.Name, err)
+			continue
+		}
+		log.Printf("Monitoring spare gpio %s (line %d of %s)", g.Name, g.Line, g.Chip)
+	}
+}
+
+func (s *SimpleDriver) handleSpareEvent(evt gpio.Event) {
+	recordTransition(evt.Name, evt.Value == 1)
+	recordTimelineEvent(evt.Name, "spare-activity", fmt.Sprintf("unexpected edge to %d", evt.Value))
+	audit("spare-activity", evt.Name, fmt.Sprintf("unexpected edge to %d", evt.Value))
+	payload, err := shapePayload(spareActivityResource, map[string]interface{}{
+		"severity": severityLow,
+		"event":    evt,
+	})
+	if err != nil {
+		log.Printf("Cannot marshal spare activity. Error: %s", err)
+		return
+	}
+	cv, err := sdkModels.NewCommandValue(spareActivityResource, common.ValueTypeString, string(payload))
+	if err != nil {
+		log.Printf("Cannot create spare activity reading. Error: %s", err)
+		return
+	}
+	s.asyncCh <- &sdkModels.AsyncValues{
+		DeviceName:    deviceName(),
+		CommandValues: []*sdkModels.CommandValue{cv},
+	}
+}
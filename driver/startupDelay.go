@@ -0,0 +1,69 @@
+package driver
+
+import (
+	"hash/fnv"
+	"log"
+	"os"
+	"os/exec"
+	"time"
+)
+
+var (
+	startupPoll = time.Duration(2) * time.Second
+)
+
+// waitForStartup delays GPIO initialization by STARTUP_DELAY, then waits, up to STARTUP_WAIT_TIMEOUT
+// (default 5m), for the file STARTUP_WAIT_FILE to exist and the systemd unit STARTUP_WAIT_TARGET to be
+// active. On timeout initialization goes on with a warning rather than leaving the lines unmanaged.
+func waitForStartup() {
+	if delay, err := time.ParseDuration(os.Getenv("STARTUP_DELAY")); err == nil && delay > 0 {
+		log.Printf("Delaying GPIO initialization by %s", delay)
+		time.Sleep(delay)
+	}
+
+	file := os.Getenv("STARTUP_WAIT_FILE")
+	target := os.Getenv("STARTUP_WAIT_TARGET")
+	if file == "" && target == "" {
+		return
+	}
+	timeout, err := time.ParseDuration(os.Getenv("STARTUP_WAIT_TIMEOUT"))
+	if err != nil || timeout <= 0 {
+		timeout = time.Duration(5) * time.Minute
+	}
+	deadline := time.Now().Add(timeout)
+	for !startupReady(file, target) {
+		if time.Now().After(deadline) {
+			configWarning("startup wait timed out after " + timeout.String() + ", initializing GPIO anyway")
+			return
+		}
+		time.Sleep(startupPoll)
+	}
+	log.Printf("Startup conditions met (file %q, target %q)", file, target)
+}
+
+func startupReady(file string, target string) bool {
+	if file != "" {
+		if _, err := os.Stat(file); err != nil {
+			return false
+		}
+	}
+	if target != "" {
+		if err := exec.Command("systemctl", "is-active", "--quiet", target).Run(); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// startupStagger is the delay applied before the first actuation: a stable offset in
+// [0, STARTUP_STAGGER) derived from the device name, so services powering up together on the same
+// gateway energize their relays at different times.
+func startupStagger() time.Duration {
+	window, err := time.ParseDuration(os.Getenv("STARTUP_STAGGER"))
+	if err != nil || window <= 0 {
+		return 0
+	}
+	h := fnv.New32a()
+	h.Write([]byte(deviceName()))
+	return time.Duration(h.Sum32()) % window
+}
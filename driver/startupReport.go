@@ -0,0 +1,131 @@
+package driver
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/edgexfoundry/device-gpiod"
+	"github.com/edgexfoundry/device-gpiod/gpio"
+	"github.com/edgexfoundry/device-sdk-go/v2/pkg/service"
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/common"
+)
+
+const statusRoute = common.ApiBase + "/status"
+
+type RoleReport struct {
+	Name string `json:"name"`
+	Chip string `json:"chip"`
+	Line int    `json:"line"`
+}
+
+// StartupReport is the machine-readable summary of the resolved configuration, logged once at
+// startup and served by the status route.
+type StartupReport struct {
+	Service      string                `json:"service"`
+	Device       string                `json:"device"`
+	Version      string                `json:"version"`
+	Build        string                `json:"build"`
+	Features     []string              `json:"features"`
+	StartedAt    time.Time             `json:"startedAt"`
+	Confinement  string                `json:"confinement"`
+	Roles        map[string]RoleReport `json:"roles"`
+	Unassigned   []string              `json:"unassigned"`
+	Lights       []string              `json:"lights"`
+	Timers       map[string]string     `json:"timers"`
+	Clamped      []string              `json:"clamped"`
+	Defaulted    []string              `json:"defaulted"`
+	Capabilities map[string]bool       `json:"capabilities"`
+	LastShutdown *ShutdownRecord       `json:"lastShutdown,omitempty"`
+}
+
+var (
+	startupReport = &StartupReport{
+		Roles:        make(map[string]RoleReport),
+		Timers:       make(map[string]string),
+		Capabilities: make(map[string]bool),
+	}
+)
+
+// buildStartupReport resolves the roles of the configured lines and collects timers and flags.
+func (s *SimpleDriver) buildStartupReport() {
+	ds := service.RunningService()
+	startupReport.Service = ds.Name()
+	startupReport.Device = deviceName()
+	startupReport.Version = ds.Version()
+	startupReport.Build = device.Version + " " + device.Build
+	startupReport.Features = compiledFeatures()
+	startupReport.StartedAt = time.Now()
+	startupReport.LastShutdown = lastShutdown
+
+	roles := []string{"START_TRIGGER", "REVERSE_TRIGGER", "CLEAN_TRIGGER", "OPEN_VALVE", "SWITCHING_VALVE"}
+	for _, g := range s.GpioList.Gpio {
+		assigned := false
+		for _, role := range roles {
+			if g.Name == triggerLine(role) {
+				startupReport.Roles[role] = RoleReport{Name: g.Name, Chip: g.Chip, Line: g.Line}
+				assigned = true
+				break
+			}
+		}
+		if !assigned {
+			startupReport.Unassigned = append(startupReport.Unassigned, g.Name)
+		}
+	}
+
+	startupReport.Timers["pump"] = (time.Duration(*pumpTimer) * time.Second).String()
+	startupReport.Timers["commandGap"] = commandGap.String()
+	startupReport.Timers["clean"] = cleanTimer.String()
+	startupReport.Timers["reverse"] = reverseTimer.String()
+	startupReport.Timers["gravity"] = gravityTimer.String()
+
+	startupReport.Capabilities["clean"] = *enableClean
+	startupReport.Capabilities["reverse"] = *enableReverse
+	startupReport.Capabilities["autoProvision"] = autoProvision
+	startupReport.Capabilities["planMode"] = planMode
+	startupReport.Capabilities["grpc"] = grpcCompiled && os.Getenv("GRPC_ADDRESS") != ""
+	startupReport.Capabilities["modbusFacade"] = modbusCompiled && os.Getenv("MODBUS_SERVER_ADDRESS") != ""
+	startupReport.Capabilities["opcuaFacade"] = opcuaCompiled && os.Getenv("OPCUA_SERVER_ADDRESS") != ""
+	startupReport.Capabilities["cloudTwin"] = mqttCompiled && os.Getenv("TWIN_PROVIDER") != ""
+	startupReport.Capabilities["asyncReadings"] = ds.AsyncReadings()
+	startupReport.Capabilities["discovery"] = ds.DeviceDiscovery()
+}
+
+// startupSnapshot returns a copy of the startup report, its timer lists taken under configMutex.
+func startupSnapshot() StartupReport {
+	configMutex.Lock()
+	defer configMutex.Unlock()
+	report := *startupReport
+	report.Clamped = append([]string(nil), startupReport.Clamped...)
+	report.Defaulted = append([]string(nil), startupReport.Defaulted...)
+	return report
+}
+
+// logStartupReport writes the startup report as a single JSON line.
+func logStartupReport() {
+	report, err := json.Marshal(startupSnapshot())
+	if err != nil {
+		log.Printf("Cannot marshal startup report. Error: %s", err)
+		return
+	}
+	log.Printf("Startup report: %s", string(report))
+}
+
+func (s *SimpleDriver) handleStatus(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"startup":      startupSnapshot(),
+		"phase":        currentPhase(),
+		"faults":       activeFaults(),
+		"inhibited":    gpio.Inhibited(),
+		"journal":      journalRecovery,
+		"dependencies": dependencyReport(),
+		"budgets":      budgetReport(),
+		"loadShed":     currentShedLevel(),
+		"reconciliation": map[string]interface{}{
+			"discrepancies": stateDiscrepancies,
+			"unreadable":    unreadableLines,
+		},
+	})
+}
@@ -0,0 +1,170 @@
+package driver
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/common"
+)
+
+const (
+	stateExportRoute = common.ApiBase + "/state/export"
+	stateImportRoute = common.ApiBase + "/state/import"
+
+	archiveConfigFile   = "gpio.yaml"
+	archiveCountersFile = "counters.json"
+	archiveAuditFile    = "audit.json"
+	maxArchiveEntrySize = 16 << 20
+)
+
+// LineCounters is the exportable form of the per-line statistics.
+type LineCounters struct {
+	Count          uint64  `json:"count"`
+	RuntimeSeconds float64 `json:"runtimeSeconds"`
+}
+
+func exportCounters() map[string]LineCounters {
+	statsMutex.Lock()
+	defer statsMutex.Unlock()
+	counters := make(map[string]LineCounters)
+	for name, st := range stats {
+		runtime := st.runtime
+		if !st.onSince.IsZero() {
+			runtime += time.Since(st.onSince)
+		}
+		counters[name] = LineCounters{Count: st.count, RuntimeSeconds: runtime.Seconds()}
+	}
+	return counters
+}
+
+func importCounters(counters map[string]LineCounters) {
+	statsMutex.Lock()
+	defer statsMutex.Unlock()
+	for name, c := range counters {
+		stats[name] = &lineStats{
+			count:   c.Count,
+			runtime: time.Duration(c.RuntimeSeconds * float64(time.Second)),
+		}
+	}
+}
+
+// handleStateExport streams a tar.gz archive with the configuration file, counters and audit trail.
+func (s *SimpleDriver) handleStateExport(w http.ResponseWriter, r *http.Request) {
+	config, err := os.ReadFile(os.Getenv("GPIO_CONFIG_FILE"))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("cannot read configuration: %s", err))
+		return
+	}
+	counters, err := json.MarshalIndent(exportCounters(), "", "\t")
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	auditTrail, err := json.MarshalIndent(recentAudit(), "", "\t")
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.Header().Set(common.ContentType, "application/gzip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s-state-%d.tar.gz", deviceName(), time.Now().Unix()))
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	for name, data := range map[string][]byte{
+		archiveConfigFile:   config,
+		archiveCountersFile: counters,
+		archiveAuditFile:    auditTrail,
+	} {
+		header := &tar.Header{Name: name, Mode: 0644, Size: int64(len(data)), ModTime: time.Now()}
+		if err := tw.WriteHeader(header); err != nil {
+			log.Printf("Cannot write state archive. Error: %s", err)
+			return
+		}
+		if _, err := tw.Write(data); err != nil {
+			log.Printf("Cannot write state archive. Error: %s", err)
+			return
+		}
+	}
+	if err := tw.Close(); err != nil {
+		log.Printf("Cannot write state archive. Error: %s", err)
+	}
+	if err := gz.Close(); err != nil {
+		log.Printf("Cannot write state archive. Error: %s", err)
+	}
+	audit("state-export", "all", "state archive exported")
+}
+
+// handleStateImport restores an archive produced by the export route. Counters are applied right
+// away; the configuration file replaces GPIO_CONFIG_FILE (previous one kept as .bak) and takes effect
+// on the next restart.
+func (s *SimpleDriver) handleStateImport(w http.ResponseWriter, r *http.Request) {
+	gz, err := gzip.NewReader(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	defer gz.Close()
+
+	files := make(map[string][]byte)
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		if header.Size > maxArchiveEntrySize {
+			writeError(w, http.StatusRequestEntityTooLarge, fmt.Errorf("%s is too large", header.Name))
+			return
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		files[header.Name] = data
+	}
+
+	config, ok := files[archiveConfigFile]
+	if !ok {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("archive does not contain %s", archiveConfigFile))
+		return
+	}
+	var counters map[string]LineCounters
+	if data, ok := files[archiveCountersFile]; ok {
+		if err := json.Unmarshal(data, &counters); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid %s: %s", archiveCountersFile, err))
+			return
+		}
+	}
+
+	fileName := os.Getenv("GPIO_CONFIG_FILE")
+	if previous, err := os.ReadFile(fileName); err == nil {
+		if err := os.WriteFile(fileName+".bak", previous, 0644); err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+	}
+	if err := os.WriteFile(fileName, config, 0644); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	importCounters(counters)
+
+	audit("state-import", "all", fmt.Sprintf("imported configuration and %d line counters", len(counters)))
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"configuration":   fileName,
+		"counters":        len(counters),
+		"restartRequired": true,
+	})
+}
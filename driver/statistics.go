@@ -0,0 +1,207 @@
+package driver
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/common"
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/models"
+
+	sdkModels "github.com/edgexfoundry/device-sdk-go/v2/pkg/models"
+)
+
+const (
+	defaultStatisticSamples = 60
+	maxStatisticSamples     = 10000
+)
+
+// Statistic publishes min/max/mean/stddev of a numeric derived resource over a sliding window, sampled
+// every interval (default window/60).
+type Statistic struct {
+	Resource string `yaml:"resource"`
+	Window   string `yaml:"window"`
+	Interval string `yaml:"interval"`
+	window   time.Duration
+	interval time.Duration
+}
+
+// ring is a fixed size window of samples keeping running sums, so that mean and stddev are updated
+// in constant time.
+type ring struct {
+	values []float64
+	next   int
+	full   bool
+	sum    float64
+	sumSq  float64
+}
+
+var (
+	statisticsMutex = sync.Mutex{}
+	statisticRings  = make(map[string]*ring)
+	statisticStop   chan struct{}
+)
+
+var statisticSuffixes = []string{"Min", "Max", "Mean", "StdDev"}
+
+// validateStatistics checks the statistics section of the configuration file.
+func validateStatistics() error {
+	names := make(map[string]bool)
+	for i := range driverConfig.Statistics {
+		st := &driverConfig.Statistics[i]
+		if st.Resource == "" || names[st.Resource] {
+			return fmt.Errorf("statistics resources must be unique and not empty")
+		}
+		names[st.Resource] = true
+		window, err := time.ParseDuration(st.Window)
+		if err != nil || window <= 0 {
+			return fmt.Errorf("statistics of %s: invalid window %q", st.Resource, st.Window)
+		}
+		interval := window / defaultStatisticSamples
+		if st.Interval != "" {
+			if interval, err = time.ParseDuration(st.Interval); err != nil || interval <= 0 || interval > window {
+				return fmt.Errorf("statistics of %s: invalid interval %q", st.Resource, st.Interval)
+			}
+		}
+		if window/interval > maxStatisticSamples {
+			return fmt.Errorf("statistics of %s: more than %d samples per window", st.Resource, maxStatisticSamples)
+		}
+		st.window, st.interval = window, interval
+	}
+	return nil
+}
+
+func statisticResourceName(resource string, suffix string) string {
+	return fmt.Sprintf("%s-%s", resource, suffix)
+}
+
+// statisticsProfileResources returns the companion resources of the configured statistics.
+func statisticsProfileResources() []models.DeviceResource {
+	var resources []models.DeviceResource
+	for _, st := range driverConfig.Statistics {
+		for _, suffix := range statisticSuffixes {
+			resources = append(resources, models.DeviceResource{
+				Name:        statisticResourceName(st.Resource, suffix),
+				Description: fmt.Sprintf("%s of %s over %s", suffix, st.Resource, st.Window),
+				Attributes:  map[string]interface{}{"statistic": suffix, "resource": st.Resource},
+				Properties: models.ResourceProperties{
+					ValueType: common.ValueTypeFloat64,
+					ReadWrite: common.ReadWrite_R,
+				},
+			})
+		}
+	}
+	return resources
+}
+
+func (r *ring) add(value float64) {
+	if r.full {
+		old := r.values[r.next]
+		r.sum -= old
+		r.sumSq -= old * old
+	}
+	r.values[r.next] = value
+	r.sum += value
+	r.sumSq += value * value
+	r.next = (r.next + 1) % len(r.values)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+func (r *ring) samples() []float64 {
+	if r.full {
+		return r.values
+	}
+	return r.values[:r.next]
+}
+
+// summary returns min, max, mean and population stddev of the samples in the window.
+func (r *ring) summary() (float64, float64, float64, float64) {
+	samples := r.samples()
+	n := float64(len(samples))
+	min, max := math.Inf(1), math.Inf(-1)
+	for _, v := range samples {
+		min = math.Min(min, v)
+		max = math.Max(max, v)
+	}
+	mean := r.sum / n
+	variance := math.Max(0, r.sumSq/n-mean*mean)
+	return min, max, mean, math.Sqrt(variance)
+}
+
+// startStatistics samples the resources of the statistics section and publishes their companion
+// resources at every sample. It is restarted when the configuration changes.
+func (s *SimpleDriver) startStatistics() {
+	statisticsMutex.Lock()
+	defer statisticsMutex.Unlock()
+	if statisticStop != nil {
+		close(statisticStop)
+		statisticStop = nil
+	}
+	statisticRings = make(map[string]*ring)
+	if len(driverConfig.Statistics) == 0 {
+		return
+	}
+	stop := make(chan struct{})
+	statisticStop = stop
+	for _, st := range driverConfig.Statistics {
+		statisticRings[st.Resource] = &ring{values: make([]float64, int(st.window/st.interval))}
+		st := st
+		goBackground(func() { s.sampleStatistic(st, stop) })
+	}
+}
+
+func (s *SimpleDriver) sampleStatistic(st Statistic, stop chan struct{}) {
+	ticker := time.NewTicker(st.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-stopping:
+			return
+		case <-ticker.C:
+		}
+		value, ok := s.derivedValue(st.Resource)
+		if !ok {
+			continue
+		}
+		statisticsMutex.Lock()
+		r := statisticRings[st.Resource]
+		r.add(value)
+		min, max, mean, stddev := r.summary()
+		statisticsMutex.Unlock()
+		if shedding(shedRecorders) {
+			continue
+		}
+
+		var values []*sdkModels.CommandValue
+		for i, v := range []float64{min, max, mean, stddev} {
+			cv, err := sdkModels.NewCommandValue(statisticResourceName(st.Resource, statisticSuffixes[i]), common.ValueTypeFloat64, v)
+			if err != nil {
+				log.Printf("Cannot create statistics reading for %s. Error: %s", st.Resource, err)
+				continue
+			}
+			values = append(values, cv)
+		}
+		s.asyncCh <- &sdkModels.AsyncValues{
+			DeviceName:    deviceName(),
+			CommandValues: values,
+		}
+	}
+}
+
+// derivedValue returns the current value of a numeric derived resource.
+func (s *SimpleDriver) derivedValue(resource string) (float64, bool) {
+	for _, g := range s.GpioList.Gpio {
+		for _, cv := range derivedCommandValues(g) {
+			if cv.DeviceResourceName == resource {
+				return numericValue(cv)
+			}
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,124 @@
+package driver
+
+import (
+	"log"
+	"os"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/edgexfoundry/device-gpiod/gpio"
+)
+
+const DEFAULT_STOP_TIMEOUT = time.Duration(5) * time.Second
+
+var (
+	// stopping is closed by Stop to cancel the background goroutines, tracked by background
+	stopping   = make(chan struct{})
+	stopOnce   = sync.Once{}
+	background = sync.WaitGroup{}
+)
+
+// goBackground runs f in a goroutine Stop cancels and waits for, recording a panic as the shutdown
+// reason. f must return, or sleep through supervisedSleep or sleepUntilStop, once stopping is closed.
+// Nothing is started once Stop was called.
+func goBackground(f func()) {
+	if stopped() {
+		return
+	}
+	background.Add(1)
+	go func() {
+		defer background.Done()
+		defer shutdownOnPanic()
+		f()
+	}()
+}
+
+// stopped reports whether Stop was called.
+func stopped() bool {
+	select {
+	case <-stopping:
+		return true
+	default:
+		return false
+	}
+}
+
+// sleepUntilStop sleeps for d and reports whether it did so without Stop being called.
+func sleepUntilStop(d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-stopping:
+		return false
+	}
+}
+
+// exitOnStop ends the calling background goroutine, running its deferred calls, when Stop was called.
+// It lets the deep sleeps of the pipeline stop it without unwinding every caller.
+func exitOnStop() {
+	if stopped() {
+		runtime.Goexit()
+	}
+}
+
+// stopTimeout reads STOP_TIMEOUT, how long Stop waits for the background goroutines to end and for
+// the lines to be released.
+func stopTimeout() time.Duration {
+	value := os.Getenv("STOP_TIMEOUT")
+	if value == "" {
+		return DEFAULT_STOP_TIMEOUT
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		log.Printf("Cannot parse STOP_TIMEOUT. Picking default value %s...", DEFAULT_STOP_TIMEOUT)
+		return DEFAULT_STOP_TIMEOUT
+	}
+	return d
+}
+
+// stopBackground cancels the background goroutines and waits up to timeout for them to end, so none
+// drives a line after the safe state.
+func stopBackground(timeout time.Duration) {
+	stopOnce.Do(func() { close(stopping) })
+	done := make(chan struct{})
+	go func() {
+		background.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		log.Println("Background goroutines stopped")
+	case <-time.After(timeout):
+		log.Printf("Background goroutines still running after %s, stopping anyway", timeout)
+	}
+}
+
+// releaseLines releases the lines of the gpios, but the ones held on exit, then any line still held,
+// giving up after timeout on a line that does not close.
+func (s *SimpleDriver) releaseLines(timeout time.Duration) {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if s.GpioList != nil {
+			for i := range s.GpioList.Gpio {
+				g := &s.GpioList.Gpio[i]
+				if g.HoldOnExit {
+					continue
+				}
+				if err := g.Release(); err != nil {
+					log.Printf("Cannot release gpio %s. Error: %s", g.Name, err)
+				}
+			}
+		}
+		gpio.ReleaseHeld()
+	}()
+	select {
+	case <-done:
+		log.Println("Gpio lines released")
+	case <-time.After(timeout):
+		log.Printf("Gpio lines not released after %s", timeout)
+	}
+}
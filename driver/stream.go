@@ -0,0 +1,124 @@
+package driver
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/common"
+)
+
+const (
+	streamRoute     = common.ApiBase + "/stream"
+	streamKeepAlive = time.Duration(15) * time.Second
+)
+
+// streamEvent is a live update pushed to the stream subscribers.
+type streamEvent struct {
+	Kind string
+	Data interface{}
+}
+
+var (
+	streamMutex       = sync.Mutex{}
+	streamSubscribers = make(map[chan streamEvent]struct{})
+	streamBuffer      = defaultStreamBuffer
+)
+
+// publishStream pushes an update to every subscriber. Slow subscribers miss updates rather than
+// blocking the caller, which may be the actuation pipeline.
+func publishStream(kind string, data interface{}) {
+	streamMutex.Lock()
+	defer streamMutex.Unlock()
+	for ch := range streamSubscribers {
+		select {
+		case ch <- streamEvent{Kind: kind, Data: data}:
+		default:
+			countEvictions("stream", 1)
+		}
+	}
+}
+
+func subscribeStream() chan streamEvent {
+	streamMutex.Lock()
+	defer streamMutex.Unlock()
+	ch := make(chan streamEvent, streamBuffer)
+	streamSubscribers[ch] = struct{}{}
+	return ch
+}
+
+// admitStreamClient refuses a new external subscriber when the stream subscribers or the goroutines
+// budget is exhausted, or while clients are shed.
+func admitStreamClient() error {
+	streamMutex.Lock()
+	subscribers := len(streamSubscribers)
+	streamMutex.Unlock()
+	if subscribers >= maxStreamClients || overBudget() || shedding(shedClients) {
+		countEvictions("stream-subscribers", 1)
+		return errStreamBudget
+	}
+	return nil
+}
+
+func unsubscribeStream(ch chan streamEvent) {
+	streamMutex.Lock()
+	defer streamMutex.Unlock()
+	delete(streamSubscribers, ch)
+}
+
+// handleStream serves line changes, events and phase transitions as Server-Sent Events. The
+// connection is bounded by the service request timeout; EventSource clients reconnect on their own.
+func (s *SimpleDriver) handleStream(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("streaming not supported"))
+		return
+	}
+	if err := admitStreamClient(); err != nil {
+		writeError(w, http.StatusServiceUnavailable, err)
+		return
+	}
+	ch := subscribeStream()
+	defer unsubscribeStream(ch)
+
+	w.Header().Set(common.ContentType, "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	if err := writeStreamEvent(w, streamEvent{Kind: "phase", Data: currentPhase()}); err != nil {
+		return
+	}
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(streamKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case evt := <-ch:
+			if err := writeStreamEvent(w, evt); err != nil {
+				return
+			}
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
+
+func writeStreamEvent(w http.ResponseWriter, evt streamEvent) error {
+	data, err := json.Marshal(evt.Data)
+	if err != nil {
+		log.Printf("Cannot marshal stream event. Error: %s", err)
+		return nil
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", evt.Kind, data)
+	return err
+}
@@ -0,0 +1,61 @@
+package driver
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+const supervisionMargin = time.Duration(30) * time.Second
+
+var (
+	supervisorMutex = sync.Mutex{}
+	deadlines       = make(map[string]time.Time)
+)
+
+// petSupervisor is called by a supervised goroutine to declare it is alive and will check in again
+// within the given duration.
+func petSupervisor(name string, within time.Duration) {
+	supervisorMutex.Lock()
+	defer supervisorMutex.Unlock()
+	deadlines[name] = time.Now().Add(within + supervisionMargin)
+}
+
+// unsuperviseGoroutine stops supervising a goroutine that terminated on purpose.
+func unsuperviseGoroutine(name string) {
+	supervisorMutex.Lock()
+	defer supervisorMutex.Unlock()
+	delete(deadlines, name)
+}
+
+// supervisedSleep sleeps on behalf of a supervised goroutine, announcing the sleep beforehand. The
+// goroutine ends when Stop is called meanwhile.
+func supervisedSleep(name string, d time.Duration) {
+	petSupervisor(name, d)
+	if !sleepUntilStop(d) {
+		unsuperviseGoroutine(name)
+		exitOnStop()
+	}
+}
+
+// goroutineStalled tells whether a supervised goroutine missed its deadline.
+func goroutineStalled(name string) bool {
+	supervisorMutex.Lock()
+	defer supervisorMutex.Unlock()
+	deadline, ok := deadlines[name]
+	return ok && time.Now().After(deadline)
+}
+
+// stalledGoroutines returns the supervised goroutines that missed their deadline.
+func stalledGoroutines() []string {
+	supervisorMutex.Lock()
+	defer supervisorMutex.Unlock()
+	var stalled []string
+	now := time.Now()
+	for name, deadline := range deadlines {
+		if now.After(deadline) {
+			stalled = append(stalled, fmt.Sprintf("%s (due %s)", name, deadline.Format(time.RFC3339)))
+		}
+	}
+	return stalled
+}
@@ -0,0 +1,107 @@
+package driver
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/edgexfoundry/device-gpiod/gpio"
+)
+
+const (
+	syncAttribute      = "sync"
+	timeoutAttribute   = "timeout"
+	verifyAttribute    = "verify"
+	DEFAULT_SYNC_WRITE = time.Duration(30) * time.Second
+)
+
+// writeMode is how a write command completes: asynchronously (the default, the response is returned
+// as soon as the operation is accepted) or synchronously, answering only once the operation and the
+// optional read-back verification are done or the timeout expires.
+type writeMode struct {
+	sync    bool
+	timeout time.Duration
+	verify  bool
+}
+
+// parseWriteMode reads the "sync", "timeout" and "verify" attributes of a command request.
+func parseWriteMode(attributes map[string]interface{}) (writeMode, error) {
+	mode := writeMode{timeout: DEFAULT_SYNC_WRITE}
+	var err error
+	if mode.sync, err = attributeBool(attributes, syncAttribute); err != nil {
+		return mode, err
+	}
+	if mode.verify, err = attributeBool(attributes, verifyAttribute); err != nil {
+		return mode, err
+	}
+	if value, ok := attributes[timeoutAttribute]; ok {
+		timeout, err := time.ParseDuration(fmt.Sprintf("%v", value))
+		if err != nil || timeout <= 0 {
+			return mode, fmt.Errorf("invalid %s attribute %v", timeoutAttribute, value)
+		}
+		mode.timeout = timeout
+	}
+	return mode, nil
+}
+
+func attributeBool(attributes map[string]interface{}, name string) (bool, error) {
+	value, ok := attributes[name]
+	if !ok {
+		return false, nil
+	}
+	switch v := value.(type) {
+	case bool:
+		return v, nil
+	case string:
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return false, fmt.Errorf("invalid %s attribute %q", name, v)
+		}
+		return b, nil
+	}
+	return false, fmt.Errorf("invalid %s attribute %v", name, value)
+}
+
+// execute runs op, which leaves g at expected once completed. In synchronous mode the call blocks until
+// op and the read-back verification complete, or fails when the timeout expires first.
+func (m writeMode) execute(g *gpio.GPIO, expected int, op func() error) error {
+	run := func() error {
+		if err := op(); err != nil {
+			return err
+		}
+		if !m.verify {
+			return nil
+		}
+		value, err := g.ReadBack()
+		if err != nil {
+			return fmt.Errorf("cannot verify %s: %s", g.Name, err)
+		}
+		if value != expected {
+			return fmt.Errorf("verification of %s failed: read %d, expected %d", g.Name, value, expected)
+		}
+		return nil
+	}
+
+	if !m.sync {
+		go func() {
+			if err := run(); err != nil {
+				log.Printf("Write on gpio %s failed. Error: %s", g.Name, err)
+				audit("write-failed", g.Name, err.Error())
+			}
+		}()
+		return nil
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- run()
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(m.timeout):
+		audit("write-timeout", g.Name, m.timeout.String())
+		return fmt.Errorf("write on gpio %s did not complete within %s", g.Name, m.timeout)
+	}
+}
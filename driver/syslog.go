@@ -0,0 +1,126 @@
+package driver
+
+import (
+	"crypto/tls"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	syslogFacilityLocal0 = 16
+	syslogWarning        = 4
+	syslogNotice         = 5
+	syslogSDID           = "gpiod@32473"
+	syslogBuffer         = 256
+)
+
+var (
+	syslogQueue     chan AuditEntry
+	syslogTransport = "udp"
+	syslogAddress   = ""
+	syslogHostname  = "-"
+)
+
+// syslogWarnings are the audit actions forwarded with warning severity, the others are notices.
+var syslogWarnings = map[string]bool{
+	"alarm-triggered": true,
+	"power-fail":      true,
+	"safe-state":      true,
+	"config-rollback": true,
+	"line-conflict":   true,
+	"hook-abort":      true,
+	"write-failed":    true,
+	"write-timeout":   true,
+}
+
+// startSyslog forwards the audit entries, alarm transitions included, as RFC5424 messages to the
+// collector at SYSLOG_ADDRESS over SYSLOG_TRANSPORT (udp, tcp or tls, default udp). Forwarding never
+// blocks the audited action: entries are dropped when the collector cannot keep up.
+func startSyslog() {
+	syslogAddress = os.Getenv("SYSLOG_ADDRESS")
+	if syslogAddress == "" {
+		return
+	}
+	if transport := os.Getenv("SYSLOG_TRANSPORT"); transport != "" {
+		switch transport {
+		case "udp", "tcp", "tls":
+			syslogTransport = transport
+		default:
+			configWarning(fmt.Sprintf("Unknown SYSLOG_TRANSPORT %q, using udp", transport))
+		}
+	}
+	if hostname, err := os.Hostname(); err == nil {
+		syslogHostname = hostname
+	}
+	syslogQueue = make(chan AuditEntry, syslogBuffer)
+	go forwardSyslog()
+	log.Printf("Forwarding audit entries to syslog %s://%s", syslogTransport, syslogAddress)
+}
+
+func queueSyslog(entry AuditEntry) {
+	if syslogQueue == nil {
+		return
+	}
+	select {
+	case syslogQueue <- entry:
+	default:
+	}
+}
+
+func dialSyslog() (net.Conn, error) {
+	timeout := time.Duration(5) * time.Second
+	if syslogTransport == "tls" {
+		return tls.DialWithDialer(&net.Dialer{Timeout: timeout}, "tcp", syslogAddress, &tls.Config{})
+	}
+	return net.DialTimeout(syslogTransport, syslogAddress, timeout)
+}
+
+func forwardSyslog() {
+	var conn net.Conn
+	for entry := range syslogQueue {
+		message := formatSyslog(entry)
+		if syslogTransport != "udp" {
+			// Octet counting framing, RFC 6587
+			message = fmt.Sprintf("%d %s", len(message), message)
+		}
+		for attempt := 0; attempt < 2; attempt++ {
+			if conn == nil {
+				var err error
+				if conn, err = dialSyslog(); err != nil {
+					log.Printf("Cannot connect to syslog %s. Error: %s", syslogAddress, err)
+					break
+				}
+			}
+			if _, err := conn.Write([]byte(message)); err == nil {
+				break
+			}
+			conn.Close()
+			conn = nil
+		}
+	}
+}
+
+// formatSyslog renders an audit entry as an RFC5424 message with the entry fields as structured data.
+func formatSyslog(entry AuditEntry) string {
+	severity := syslogNotice
+	if syslogWarnings[entry.Action] {
+		severity = syslogWarning
+	}
+	escape := strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`)
+	return fmt.Sprintf(`<%d>1 %s %s %s %d %s [%s action="%s" resource="%s" device="%s"] %s`,
+		syslogFacilityLocal0*8+severity,
+		time.Unix(0, entry.Timestamp).UTC().Format(time.RFC3339Nano),
+		syslogHostname,
+		defaultDeviceName,
+		os.Getpid(),
+		entry.Action,
+		syslogSDID,
+		escape.Replace(entry.Action),
+		escape.Replace(entry.Resource),
+		escape.Replace(deviceName()),
+		entry.Detail)
+}
@@ -0,0 +1,64 @@
+package driver
+
+import (
+	"log"
+	"time"
+
+	"github.com/edgexfoundry/device-sdk-go/v2/pkg/service"
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/common"
+)
+
+const (
+	systemEventTypeDevice   = "device"
+	systemEventTypeConfig   = "config"
+	systemEventTypeCycle    = "cycle"
+	systemEventActionAdd    = "add"
+	systemEventActionReload = "reload"
+	systemEventActionStart  = "start"
+
+	defaultSystemEventsTopic = "edgex/system-events"
+	systemEventsQueue        = 64
+)
+
+// SystemEvent follows the EdgeX system event DTO, so consumers of the core system events (device
+// added, ...) can handle the ones of this service the same way.
+type SystemEvent struct {
+	ApiVersion string            `json:"apiVersion"`
+	Type       string            `json:"type"`
+	Action     string            `json:"action"`
+	Source     string            `json:"source"`
+	Owner      string            `json:"owner"`
+	Tags       map[string]string `json:"tags,omitempty"`
+	Details    interface{}       `json:"details"`
+	Timestamp  int64             `json:"timestamp"`
+}
+
+var (
+	systemEvents chan SystemEvent
+)
+
+// publishSystemEvent queues a lifecycle event. Events are dropped rather than blocking the caller
+// when the broker is unreachable for long.
+func publishSystemEvent(eventType string, action string, details interface{}) {
+	if systemEvents == nil {
+		return
+	}
+	source := "device-gpiod"
+	if ds := service.RunningService(); ds != nil && ds.Name() != "" {
+		source = ds.Name()
+	}
+	evt := SystemEvent{
+		ApiVersion: common.ApiVersion,
+		Type:       eventType,
+		Action:     action,
+		Source:     source,
+		Owner:      deviceName(),
+		Details:    details,
+		Timestamp:  time.Now().UnixNano(),
+	}
+	select {
+	case systemEvents <- evt:
+	default:
+		log.Printf("System event queue full, dropping %s/%s", eventType, action)
+	}
+}
@@ -0,0 +1,63 @@
+//go:build !nomqtt
+
+package driver
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+// mqttCompiled tells whether the MQTT clients, system events and cloud twin, are part of the build.
+// They are left out with the nomqtt build tag.
+const mqttCompiled = true
+
+// startSystemEvents publishes the lifecycle events of the service (device added, configuration
+// reloaded, cycle started) to the message bus broker SYSTEM_EVENTS_BROKER (e.g.
+// tcp://edgex-mqtt-broker:1883), on <SYSTEM_EVENTS_TOPIC>/<source>/<type>/<action>/<owner>. It is a
+// no-op when the broker is unset.
+// countReconnect returns a reconnecting handler counting the reconnection attempts of the client.
+func countReconnect(client string) mqtt.ReconnectHandler {
+	return func(mqtt.Client, *mqtt.ClientOptions) {
+		countMetric("gpiod_reconnect_attempts_total", "client", client)
+	}
+}
+
+func startSystemEvents() {
+	broker := os.Getenv("SYSTEM_EVENTS_BROKER")
+	if broker == "" {
+		return
+	}
+	prefix := os.Getenv("SYSTEM_EVENTS_TOPIC")
+	if prefix == "" {
+		prefix = defaultSystemEventsTopic
+	}
+	options := mqtt.NewClientOptions().
+		AddBroker(broker).
+		SetClientID(namespaced("device-gpiod-system-events")).
+		SetAutoReconnect(true).
+		SetReconnectingHandler(countReconnect("system-events"))
+	client := mqtt.NewClient(options)
+	token := client.Connect()
+	if !token.WaitTimeout(twinTimeout) || token.Error() != nil {
+		logf(moduleBridges, levelWarn, "Cannot connect to the system events broker, retrying in background. Error: %v", token.Error())
+	}
+
+	systemEvents = make(chan SystemEvent, systemEventsQueue)
+	go func() {
+		for evt := range systemEvents {
+			payload, err := json.Marshal(evt)
+			if err != nil {
+				logf(moduleBridges, levelError, "Cannot marshal system event. Error: %s", err)
+				continue
+			}
+			topic := fmt.Sprintf("%s/%s/%s/%s/%s", prefix, evt.Source, evt.Type, evt.Action, evt.Owner)
+			token := client.Publish(topic, 1, false, payload)
+			if !token.WaitTimeout(twinTimeout) || token.Error() != nil {
+				logf(moduleBridges, levelError, "Cannot publish system event on %s. Error: %v", topic, token.Error())
+			}
+		}
+	}()
+}
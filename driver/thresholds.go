@@ -0,0 +1,160 @@
+package driver
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/common"
+
+	sdkModels "github.com/edgexfoundry/device-sdk-go/v2/pkg/models"
+)
+
+const (
+	thresholdCrossingResource = "ThresholdCrossing"
+	levelNormal               = "normal"
+	levelHigh                 = "high"
+	levelLow                  = "low"
+)
+
+// Threshold raises a crossing event when a numeric derived resource goes above high or below low. The
+// resource only goes back to normal once it moved back past the threshold by hysteresis, so a value
+// hovering around a threshold does not flood consumers with events.
+type Threshold struct {
+	Resource   string   `yaml:"resource"`
+	High       *float64 `yaml:"high"`
+	Low        *float64 `yaml:"low"`
+	Hysteresis float64  `yaml:"hysteresis"`
+}
+
+var (
+	thresholdMutex    = sync.Mutex{}
+	thresholdLevels   = make(map[string]string)
+	thresholdInterval = time.Duration(10) * time.Second
+)
+
+// validateThresholds checks the thresholds section of the configuration file.
+func validateThresholds() error {
+	names := make(map[string]bool)
+	for _, t := range driverConfig.Thresholds {
+		if t.Resource == "" || names[t.Resource] {
+			return fmt.Errorf("threshold resources must be unique and not empty")
+		}
+		names[t.Resource] = true
+		if t.High == nil && t.Low == nil {
+			return fmt.Errorf("threshold of %s: high or low must be set", t.Resource)
+		}
+		if t.Hysteresis < 0 {
+			return fmt.Errorf("threshold of %s: hysteresis cannot be negative", t.Resource)
+		}
+		if t.High != nil && t.Low != nil && *t.Low >= *t.High {
+			return fmt.Errorf("threshold of %s: low must be below high", t.Resource)
+		}
+	}
+	return nil
+}
+
+// level returns the level of value given the previous one, applying the hysteresis on the way back.
+func (t Threshold) level(previous string, value float64) string {
+	switch {
+	case t.High != nil && value >= *t.High:
+		return levelHigh
+	case t.Low != nil && value <= *t.Low:
+		return levelLow
+	case previous == levelHigh && value > *t.High-t.Hysteresis:
+		return levelHigh
+	case previous == levelLow && value < *t.Low+t.Hysteresis:
+		return levelLow
+	}
+	return levelNormal
+}
+
+// thresholdCrossings returns a ThresholdCrossing reading for every value that changed level.
+func thresholdCrossings(values []*sdkModels.CommandValue) []*sdkModels.CommandValue {
+	var events []*sdkModels.CommandValue
+	for _, cv := range values {
+		t, ok := thresholdFor(cv.DeviceResourceName)
+		if !ok {
+			continue
+		}
+		value, ok := numericValue(cv)
+		if !ok {
+			continue
+		}
+
+		thresholdMutex.Lock()
+		previous, known := thresholdLevels[t.Resource]
+		if !known {
+			previous = levelNormal
+		}
+		current := t.level(previous, value)
+		thresholdLevels[t.Resource] = current
+		thresholdMutex.Unlock()
+		if current == previous {
+			continue
+		}
+
+		log.Printf("Resource %s crossed threshold: %s -> %s (%v)", t.Resource, previous, current, value)
+		recordTimelineEvent(t.Resource, "threshold", fmt.Sprintf("%s -> %s", previous, current))
+		payload, err := shapePayload(thresholdCrossingResource, map[string]interface{}{
+			"resource": t.Resource,
+			"from":     previous,
+			"to":       current,
+			"value":    value,
+		})
+		if err != nil {
+			log.Printf("Cannot marshal threshold crossing. Error: %s", err)
+			continue
+		}
+		event, err := sdkModels.NewCommandValue(thresholdCrossingResource, common.ValueTypeString, string(payload))
+		if err != nil {
+			log.Printf("Cannot create threshold crossing reading. Error: %s", err)
+			continue
+		}
+		events = append(events, event)
+	}
+	return events
+}
+
+func thresholdFor(resource string) (Threshold, bool) {
+	for _, t := range driverConfig.Thresholds {
+		if t.Resource == resource {
+			return t, true
+		}
+	}
+	return Threshold{}, false
+}
+
+// startThresholdMonitoring checks the derived resources every THRESHOLD_INTERVAL (default 10s), next
+// to the checks done on every state change, so that values growing while a line stays on (runtime,
+// energy) raise their crossing in time.
+func (s *SimpleDriver) startThresholdMonitoring() {
+	if len(driverConfig.Thresholds) == 0 {
+		return
+	}
+	if d, err := time.ParseDuration(os.Getenv("THRESHOLD_INTERVAL")); err == nil && d > 0 {
+		thresholdInterval = d
+	} else {
+		log.Printf("Cannot parse THRESHOLD_INTERVAL. Picking default value %s...", thresholdInterval)
+	}
+	goBackground(func() {
+		for {
+			if !sleepUntilStop(thresholdInterval) {
+				return
+			}
+			var events []*sdkModels.CommandValue
+			for _, g := range s.GpioList.Gpio {
+				events = append(events, thresholdCrossings(derivedCommandValues(g))...)
+			}
+			if len(events) == 0 {
+				continue
+			}
+			s.asyncCh <- &sdkModels.AsyncValues{
+				DeviceName:    deviceName(),
+				CommandValues: events,
+			}
+		}
+	})
+}
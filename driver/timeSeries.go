@@ -0,0 +1,291 @@
+package driver
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/common"
+)
+
+const (
+	timeSeriesRoute   = common.ApiBase + "/timeseries"
+	segmentDayLayout  = "2006-01-02"
+	segmentSuffix     = ".jsonl"
+	maxQuerySamples   = 100000
+	timeSeriesBacklog = 1024
+)
+
+// TimeSample is a sample of the local time-series cache.
+type TimeSample struct {
+	Timestamp int64   `json:"t"`
+	Resource  string  `json:"r"`
+	Value     float64 `json:"v"`
+}
+
+var (
+	timeSeriesDir       = ""
+	timeSeriesRetention = time.Duration(72) * time.Hour
+	timeSeriesCh        chan TimeSample
+)
+
+// startTimeSeries keeps line states and derived values in daily segments under TSDB_DIR, for local
+// troubleshooting when core-data retention is short or the network is down. The segment of the day is
+// a plain JSON lines file, earlier segments are gzipped and removed after TSDB_RETENTION (default 72h).
+func startTimeSeries() {
+	timeSeriesDir = os.Getenv("TSDB_DIR")
+	if timeSeriesDir == "" {
+		return
+	}
+	if d, err := time.ParseDuration(os.Getenv("TSDB_RETENTION")); err == nil && d > 0 {
+		timeSeriesRetention = d
+	} else {
+		log.Printf("Cannot parse TSDB_RETENTION. Picking default value %s...", timeSeriesRetention)
+	}
+	if err := os.MkdirAll(timeSeriesDir, 0755); err != nil {
+		log.Printf("Cannot create time-series directory %s. Error: %s", timeSeriesDir, err)
+		return
+	}
+	timeSeriesCh = make(chan TimeSample, timeSeriesBacklog)
+	go writeTimeSeries()
+}
+
+// recordSample queues a sample for the local cache, dropping it when the writer lags behind.
+func recordSample(resource string, value float64) {
+	if timeSeriesCh == nil || shedding(shedRecorders) {
+		return
+	}
+	select {
+	case timeSeriesCh <- TimeSample{Timestamp: time.Now().UnixMilli(), Resource: resource, Value: value}:
+	default:
+		log.Printf("Time-series backlog full, dropping sample of %s", resource)
+	}
+}
+
+func boolSample(value bool) float64 {
+	if value {
+		return 1
+	}
+	return 0
+}
+
+func segmentPath(day string) string {
+	return filepath.Join(timeSeriesDir, day+segmentSuffix)
+}
+
+func writeTimeSeries() {
+	day := ""
+	var f *os.File
+	for sample := range timeSeriesCh {
+		sampleDay := time.UnixMilli(sample.Timestamp).Format(segmentDayLayout)
+		if sampleDay != day {
+			if f != nil {
+				f.Close()
+			}
+			day = sampleDay
+			compactSegments(day)
+			var err error
+			if f, err = os.OpenFile(segmentPath(day), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644); err != nil {
+				log.Printf("Cannot open time-series segment. Error: %s", err)
+				f, day = nil, ""
+				continue
+			}
+		}
+		data, err := json.Marshal(sample)
+		if err != nil {
+			log.Printf("Cannot marshal time-series sample. Error: %s", err)
+			continue
+		}
+		if _, err := f.Write(append(data, '\n')); err != nil {
+			log.Printf("Cannot write time-series segment. Error: %s", err)
+		}
+	}
+}
+
+// compactSegments gzips the plain segments of days before today and removes the expired ones.
+func compactSegments(today string) {
+	entries, err := os.ReadDir(timeSeriesDir)
+	if err != nil {
+		log.Printf("Cannot list time-series segments. Error: %s", err)
+		return
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		day, err := time.ParseInLocation(segmentDayLayout, strings.SplitN(name, ".", 2)[0], time.Local)
+		if err != nil {
+			continue
+		}
+		path := filepath.Join(timeSeriesDir, name)
+		if time.Since(day.AddDate(0, 0, 1)) > timeSeriesRetention {
+			if err := os.Remove(path); err != nil {
+				log.Printf("Cannot remove expired time-series segment %s. Error: %s", name, err)
+			}
+			continue
+		}
+		if strings.HasSuffix(name, segmentSuffix) && day.Format(segmentDayLayout) != today {
+			if err := gzipFile(path); err != nil {
+				log.Printf("Cannot compress time-series segment %s. Error: %s", name, err)
+			}
+		}
+	}
+}
+
+func gzipFile(path string) error {
+	in, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(path + ".gz")
+	if err != nil {
+		return err
+	}
+	gz := gzip.NewWriter(out)
+	if _, err := io.Copy(gz, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
+
+// queryTimeSeries returns the samples of resource (all resources when empty) between from and to,
+// in time order.
+func queryTimeSeries(resource string, from time.Time, to time.Time) ([]TimeSample, error) {
+	if timeSeriesDir == "" {
+		return nil, fmt.Errorf("time-series cache disabled, set TSDB_DIR")
+	}
+	entries, err := os.ReadDir(timeSeriesDir)
+	if err != nil {
+		return nil, err
+	}
+	samples := []TimeSample{}
+	for _, entry := range entries {
+		name := entry.Name()
+		day, err := time.ParseInLocation(segmentDayLayout, strings.SplitN(name, ".", 2)[0], time.Local)
+		if err != nil || day.After(to) || day.AddDate(0, 0, 1).Before(from) {
+			continue
+		}
+		if err := scanSegment(filepath.Join(timeSeriesDir, name), func(sample TimeSample) error {
+			t := time.UnixMilli(sample.Timestamp)
+			if (resource != "" && sample.Resource != resource) || t.Before(from) || t.After(to) {
+				return nil
+			}
+			if len(samples) >= maxQuerySamples {
+				return fmt.Errorf("more than %d samples, narrow the range", maxQuerySamples)
+			}
+			samples = append(samples, sample)
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+	}
+	sort.SliceStable(samples, func(i, j int) bool { return samples[i].Timestamp < samples[j].Timestamp })
+	return samples, nil
+}
+
+func scanSegment(path string, fn func(TimeSample) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	var r io.Reader = f
+	if strings.HasSuffix(path, ".gz") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		r = gz
+	}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		var sample TimeSample
+		if err := json.Unmarshal(scanner.Bytes(), &sample); err != nil {
+			// The last line of the segment being written may be incomplete
+			continue
+		}
+		if err := fn(sample); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// downsample averages the samples of each resource over buckets of the given width. For line states
+// the mean is the fraction of the bucket the line was sampled on.
+func downsample(samples []TimeSample, width time.Duration) []TimeSample {
+	type bucket struct {
+		resource string
+		start    int64
+	}
+	step := width.Milliseconds()
+	sums := make(map[bucket]float64)
+	counts := make(map[bucket]int)
+	var order []bucket
+	for _, sample := range samples {
+		b := bucket{resource: sample.Resource, start: sample.Timestamp - sample.Timestamp%step}
+		if _, ok := counts[b]; !ok {
+			order = append(order, b)
+		}
+		sums[b] += sample.Value
+		counts[b]++
+	}
+	result := make([]TimeSample, 0, len(order))
+	for _, b := range order {
+		result = append(result, TimeSample{Timestamp: b.start, Resource: b.resource, Value: sums[b] / float64(counts[b])})
+	}
+	return result
+}
+
+// handleTimeSeries queries the local cache: resource, from/to (RFC3339, default the last hour) and
+// downsample (a duration) query parameters.
+func (s *SimpleDriver) handleTimeSeries(w http.ResponseWriter, r *http.Request) {
+	to := time.Now()
+	from := to.Add(-time.Hour)
+	var err error
+	if value := r.URL.Query().Get("from"); value != "" {
+		if from, err = time.Parse(time.RFC3339, value); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid from %q", value))
+			return
+		}
+	}
+	if value := r.URL.Query().Get("to"); value != "" {
+		if to, err = time.Parse(time.RFC3339, value); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid to %q", value))
+			return
+		}
+	}
+	var width time.Duration
+	if value := r.URL.Query().Get("downsample"); value != "" {
+		if width, err = time.ParseDuration(value); err != nil || width < time.Millisecond {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid downsample %q", value))
+			return
+		}
+	}
+	samples, err := queryTimeSeries(r.URL.Query().Get("resource"), from, to)
+	if err != nil {
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+	if width > 0 {
+		samples = downsample(samples, width)
+	}
+	writeJSON(w, http.StatusOK, samples)
+}
@@ -0,0 +1,183 @@
+package driver
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/common"
+)
+
+const timelineRoute = common.ApiBase + "/timeline"
+
+// TimelineInterval is a period during which a line held the same value. To is zero while open.
+type TimelineInterval struct {
+	From  time.Time `json:"from"`
+	To    time.Time `json:"to,omitempty"`
+	Value int       `json:"value"`
+}
+
+// TimelineEvent is a point in time event of a line, e.g. an edge on a watched input or an alarm.
+type TimelineEvent struct {
+	At     time.Time `json:"at"`
+	Kind   string    `json:"kind"`
+	Detail string    `json:"detail,omitempty"`
+}
+
+// LineTimeline is the plottable history of a line.
+type LineTimeline struct {
+	Intervals []TimelineInterval `json:"intervals"`
+	Events    []TimelineEvent    `json:"events"`
+}
+
+var (
+	timelineMutex = sync.Mutex{}
+	timeline      = make(map[string]*LineTimeline)
+
+	maxTimelineRecord = defaultTimelineRecords
+)
+
+func lineTimeline(name string) *LineTimeline {
+	t, ok := timeline[name]
+	if !ok {
+		t = &LineTimeline{}
+		timeline[name] = t
+	}
+	return t
+}
+
+// recordTransition closes the open interval of the line and opens a new one with value.
+func recordTransition(name string, value bool) {
+	timelineMutex.Lock()
+	defer timelineMutex.Unlock()
+	t := lineTimeline(name)
+	now := time.Now()
+	v := 0
+	if value {
+		v = 1
+	}
+	if n := len(t.Intervals); n > 0 {
+		last := &t.Intervals[n-1]
+		if last.To.IsZero() {
+			if last.Value == v {
+				return
+			}
+			last.To = now
+		}
+	}
+	t.Intervals = append(t.Intervals, TimelineInterval{From: now, Value: v})
+	if len(t.Intervals) > maxTimelineRecord {
+		countEvictions("timeline", len(t.Intervals)-maxTimelineRecord)
+		t.Intervals = t.Intervals[len(t.Intervals)-maxTimelineRecord:]
+	}
+	publishStream("line", map[string]interface{}{"name": name, "value": v, "at": now})
+}
+
+// lastTransition returns the current value of a line and when it was reached.
+func lastTransition(name string) (int, time.Time, bool) {
+	timelineMutex.Lock()
+	defer timelineMutex.Unlock()
+	t, ok := timeline[name]
+	if !ok || len(t.Intervals) == 0 {
+		return 0, time.Time{}, false
+	}
+	last := t.Intervals[len(t.Intervals)-1]
+	return last.Value, last.From, true
+}
+
+// onTimeBetween returns how long the line was on between from and to, as far as the timeline goes back.
+func onTimeBetween(name string, from time.Time, to time.Time) time.Duration {
+	timelineMutex.Lock()
+	defer timelineMutex.Unlock()
+	t, ok := timeline[name]
+	if !ok {
+		return 0
+	}
+	var on time.Duration
+	for _, interval := range t.Intervals {
+		if interval.Value != 1 {
+			continue
+		}
+		start, end := interval.From, interval.To
+		if end.IsZero() {
+			end = time.Now()
+		}
+		if start.Before(from) {
+			start = from
+		}
+		if end.After(to) {
+			end = to
+		}
+		if end.After(start) {
+			on += end.Sub(start)
+		}
+	}
+	return on
+}
+
+// recordTimelineEvent adds a point in time event to the history of a line.
+func recordTimelineEvent(name string, kind string, detail string) {
+	timelineMutex.Lock()
+	defer timelineMutex.Unlock()
+	t := lineTimeline(name)
+	event := TimelineEvent{At: time.Now(), Kind: kind, Detail: detail}
+	t.Events = append(t.Events, event)
+	publishStream("event", map[string]interface{}{"name": name, "event": event})
+	if len(t.Events) > maxTimelineRecord {
+		countEvictions("timeline", len(t.Events)-maxTimelineRecord)
+		t.Events = t.Events[len(t.Events)-maxTimelineRecord:]
+	}
+}
+
+// handleTimeline returns the history of every line, optionally restricted to the from/to (RFC3339)
+// query parameters and to the line given by the name query parameter.
+func (s *SimpleDriver) handleTimeline(w http.ResponseWriter, r *http.Request) {
+	from, to := time.Time{}, time.Now()
+	var err error
+	if value := r.URL.Query().Get("from"); value != "" {
+		if from, err = time.Parse(time.RFC3339, value); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid from %q", value))
+			return
+		}
+	}
+	if value := r.URL.Query().Get("to"); value != "" {
+		if to, err = time.Parse(time.RFC3339, value); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid to %q", value))
+			return
+		}
+	}
+	name := r.URL.Query().Get("name")
+
+	timelineMutex.Lock()
+	defer timelineMutex.Unlock()
+	lines := make(map[string]LineTimeline)
+	for line, t := range timeline {
+		if name != "" && line != name {
+			continue
+		}
+		window := LineTimeline{Intervals: []TimelineInterval{}, Events: []TimelineEvent{}}
+		for _, interval := range t.Intervals {
+			end := interval.To
+			if end.IsZero() {
+				end = time.Now()
+			}
+			if end.Before(from) || interval.From.After(to) {
+				continue
+			}
+			window.Intervals = append(window.Intervals, interval)
+		}
+		for _, event := range t.Events {
+			if event.At.Before(from) || event.At.After(to) {
+				continue
+			}
+			window.Events = append(window.Events, event)
+		}
+		lines[line] = window
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"from":  from,
+		"to":    to,
+		"lines": lines,
+	})
+}
@@ -0,0 +1,172 @@
+package driver
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/common"
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/models"
+
+	sdkModels "github.com/edgexfoundry/device-sdk-go/v2/pkg/models"
+)
+
+const (
+	unitAttribute = "unit"
+	unitSeconds   = "s"
+	unitMinutes   = "min"
+	unitHours     = "h"
+)
+
+// timerResource is a cycle timer exposed as a device resource, bounded like the env var it
+// overrides: MIN_<env> and MAX_<env> when set, the startup minimum and DEFAULT_MAX_TIMER otherwise.
+type timerResource struct {
+	name  string
+	env   string
+	min   time.Duration
+	value *time.Duration
+}
+
+var (
+	timerResources = []timerResource{
+		{"CommandGap", "COMMAND_GAP", MIN_COMMAND_GAP, commandGap},
+		{"CleanTimer", "CLEAN_TIMEOUT", MIN_CLEAN_TIMER, cleanTimer},
+		{"ReverseTimer", "REVERSE_TIMEOUT", MIN_REVERSE_TIMER, reverseTimer},
+		{"GravityTimer", "GRAVITY_TIMEOUT", MIN_GRAVITY_TIMER, gravityTimer},
+	}
+	pumpTimerResource = timerResource{name: "PumpTimer", env: "PUMP_TIMEOUT", min: time.Duration(MIN_PUMP) * time.Minute}
+)
+
+// findTimer returns the timer exposed as the named resource.
+func findTimer(name string) (timerResource, bool) {
+	if name == pumpTimerResource.name {
+		return pumpTimerResource, true
+	}
+	for _, t := range timerResources {
+		if t.name == name {
+			return t, true
+		}
+	}
+	return timerResource{}, false
+}
+
+// unitOf returns the duration of one unit of the unit attribute, which is mandatory so that a write
+// is never ambiguous.
+func unitOf(attributes map[string]interface{}) (time.Duration, string, error) {
+	unit, _ := attributes[unitAttribute].(string)
+	switch unit {
+	case unitSeconds:
+		return time.Second, unit, nil
+	case unitMinutes:
+		return time.Minute, unit, nil
+	case unitHours:
+		return time.Hour, unit, nil
+	case "":
+		return 0, unit, fmt.Errorf("missing %s attribute (s, min or h)", unitAttribute)
+	}
+	return 0, unit, fmt.Errorf("unknown %s %q (s, min or h)", unitAttribute, unit)
+}
+
+// bounds returns the range the timer may be set to.
+func (t timerResource) bounds() (time.Duration, time.Duration) {
+	min, max := t.min, DEFAULT_MAX_TIMER
+	if d, err := time.ParseDuration(os.Getenv("MIN_" + t.env)); err == nil && d >= 0 {
+		min = d
+	}
+	if d, err := time.ParseDuration(os.Getenv("MAX_" + t.env)); err == nil && d >= min {
+		max = d
+	}
+	return min, max
+}
+
+func (t timerResource) get() time.Duration {
+	profileMutex.Lock()
+	defer profileMutex.Unlock()
+	if t.value == nil {
+		return time.Duration(*pumpTimer) * time.Second
+	}
+	return *t.value
+}
+
+// set overrides the timer until the next profile activation. The new value applies from the next
+// phase of the cycle.
+func (t timerResource) set(d time.Duration, source string) error {
+	min, max := t.bounds()
+	if d < min || d > max {
+		return fmt.Errorf("%s %s out of range [%s, %s]", t.name, d, min, max)
+	}
+	profileMutex.Lock()
+	defer profileMutex.Unlock()
+	if t.value == nil {
+		*pumpTimer = int64(d.Seconds())
+		gpioConfig.PumpTimer = time.Duration(*pumpTimer)
+	} else {
+		*t.value = d
+		gpioConfig.CommandGap = *commandGap
+		gpioConfig.CleanTimer = *cleanTimer
+		gpioConfig.ReverseTimer = *reverseTimer
+		gpioConfig.GravityTimer = *gravityTimer
+	}
+	audit("timer-override", t.name, fmt.Sprintf("%s by %s", d, source))
+	return nil
+}
+
+// readTimer returns the timer in the unit of the resource.
+func readTimer(t timerResource, req sdkModels.CommandRequest) (*sdkModels.CommandValue, error) {
+	unit, _, err := unitOf(req.Attributes)
+	if err != nil {
+		return nil, err
+	}
+	return sdkModels.NewCommandValue(req.DeviceResourceName, common.ValueTypeFloat32, float32(float64(t.get())/float64(unit)))
+}
+
+// writeTimer sets the timer from a value in the unit of the resource.
+func writeTimer(t timerResource, req sdkModels.CommandRequest, param *sdkModels.CommandValue) error {
+	unit, _, err := unitOf(req.Attributes)
+	if err != nil {
+		return err
+	}
+	var value float64
+	switch param.Type {
+	case common.ValueTypeFloat32:
+		v, err := param.Float32Value()
+		if err != nil {
+			return err
+		}
+		value = float64(v)
+	case common.ValueTypeFloat64:
+		if value, err = param.Float64Value(); err != nil {
+			return err
+		}
+	case common.ValueTypeUint32:
+		v, err := param.Uint32Value()
+		if err != nil {
+			return err
+		}
+		value = float64(v)
+	default:
+		return fmt.Errorf("unsupported value type %s for %s", param.Type, param.DeviceResourceName)
+	}
+	return t.set(time.Duration(value*float64(unit)), "core-command")
+}
+
+// timerProfileResources returns the cycle timer resources, in minutes.
+func timerProfileResources() []models.DeviceResource {
+	var resources []models.DeviceResource
+	for _, t := range append([]timerResource{pumpTimerResource}, timerResources...) {
+		min, max := t.bounds()
+		resources = append(resources, models.DeviceResource{
+			Name:        t.name,
+			Description: fmt.Sprintf("Cycle timer overriding %s until the next profile activation", t.env),
+			Attributes:  map[string]interface{}{unitAttribute: unitMinutes},
+			Properties: models.ResourceProperties{
+				ValueType: common.ValueTypeFloat32,
+				ReadWrite: common.ReadWrite_RW,
+				Units:     unitMinutes,
+				Minimum:   fmt.Sprintf("%g", min.Minutes()),
+				Maximum:   fmt.Sprintf("%g", max.Minutes()),
+			},
+		})
+	}
+	return resources
+}
@@ -0,0 +1,138 @@
+package driver
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/common"
+
+	sdkModels "github.com/edgexfoundry/device-sdk-go/v2/pkg/models"
+)
+
+const (
+	configWarningResource = "ConfigWarning"
+	DEFAULT_MAX_TIMER     = time.Duration(24) * time.Hour
+)
+
+var (
+	// configMutex guards the config warnings and the timers clamped or defaulted in the startup
+	// report, appended at runtime too and read by the status routes
+	configMutex    = sync.Mutex{}
+	configWarnings []string
+	// Bounds of the timers read by parseTimer, by env var, and the timers clamped at runtime
+	timerBounds    = make(map[string][2]time.Duration)
+	runtimeClamped = make(map[string]bool)
+)
+
+// parseTimer reads the duration env var name, falling back to def when unset or invalid, and bounds it
+// between MIN_<name> and MAX_<name> (min and DEFAULT_MAX_TIMER when not configured). An invalid value,
+// e.g. "5" without a unit, and any clamping are reported as warnings instead of silently overriding
+// the user value.
+func parseTimer(name string, def time.Duration, min time.Duration) time.Duration {
+	min = boundFromEnv("MIN_"+name, min)
+	max := boundFromEnv("MAX_"+name, DEFAULT_MAX_TIMER)
+	if max < min {
+		configWarning(fmt.Sprintf("MAX_%s (%s) is lower than MIN_%s (%s), ignoring maximum", name, max, name, min))
+		max = DEFAULT_MAX_TIMER
+	}
+	timerBounds[name] = [2]time.Duration{min, max}
+
+	env := os.Getenv(name)
+	value, err := time.ParseDuration(env)
+	switch {
+	case env == "":
+		log.Printf("%s not set. Picking default value %s...", name, def)
+		value = def
+	case err != nil:
+		configWarning(fmt.Sprintf("Cannot parse %s=%q, durations need a unit (e.g. 5m), using the default %s", name, env, def))
+		configMutex.Lock()
+		startupReport.Defaulted = append(startupReport.Defaulted, name)
+		configMutex.Unlock()
+		value = def
+	}
+
+	switch {
+	case value < min:
+		configWarning(fmt.Sprintf("%s=%s is below the minimum %s, clamping to %s", name, value, min, min))
+		noteClamped(name)
+		value = min
+	case value > max:
+		configWarning(fmt.Sprintf("%s=%s is above the maximum %s, clamping to %s (check the unit)", name, value, max, max))
+		noteClamped(name)
+		value = max
+	}
+	return value
+}
+
+// clampTimer bounds a value of the timer name computed at runtime by source, e.g. a script, between
+// the bounds parseTimer applied to its configured value. The first clamping of each timer is reported
+// as a warning.
+func clampTimer(name string, value time.Duration, source string) time.Duration {
+	bounds, ok := timerBounds[name]
+	if !ok {
+		return value
+	}
+	clamped := value
+	if value < bounds[0] {
+		clamped = bounds[0]
+	} else if value > bounds[1] {
+		clamped = bounds[1]
+	}
+	configMutex.Lock()
+	first := clamped != value && !runtimeClamped[name]
+	runtimeClamped[name] = runtimeClamped[name] || first
+	configMutex.Unlock()
+	if first {
+		configWarning(fmt.Sprintf("%s returned %s for %s, outside %s-%s, clamping to %s", source, value, name, bounds[0], bounds[1], clamped))
+	}
+	return clamped
+}
+
+func boundFromEnv(name string, def time.Duration) time.Duration {
+	env := os.Getenv(name)
+	if env == "" {
+		return def
+	}
+	bound, err := time.ParseDuration(env)
+	if err != nil || bound < 0 {
+		configWarning(fmt.Sprintf("Cannot parse %s=%q, using %s", name, env, def))
+		return def
+	}
+	return bound
+}
+
+func noteClamped(name string) {
+	configMutex.Lock()
+	defer configMutex.Unlock()
+	startupReport.Clamped = append(startupReport.Clamped, name)
+}
+
+func configWarning(warning string) {
+	log.Printf("WARNING: %s", warning)
+	configMutex.Lock()
+	defer configMutex.Unlock()
+	configWarnings = append(configWarnings, warning)
+}
+
+// pushConfigWarnings publishes the configuration warnings collected at startup as a reading.
+func (s *SimpleDriver) pushConfigWarnings() {
+	configMutex.Lock()
+	warnings := strings.Join(configWarnings, "; ")
+	configMutex.Unlock()
+	if warnings == "" {
+		return
+	}
+	cv, err := sdkModels.NewCommandValue(configWarningResource, common.ValueTypeString, warnings)
+	if err != nil {
+		log.Printf("Cannot create config warning reading. Error: %s", err)
+		return
+	}
+	s.asyncCh <- &sdkModels.AsyncValues{
+		DeviceName:    deviceName(),
+		CommandValues: []*sdkModels.CommandValue{cv},
+	}
+}
@@ -0,0 +1,68 @@
+package driver
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseTimer(t *testing.T) {
+	tests := []struct {
+		name      string
+		env       string
+		expected  time.Duration
+		clamped   bool
+		defaulted bool
+	}{
+		{"unset", "", 5 * time.Minute, false, false},
+		{"valid", "10m", 10 * time.Minute, false, false},
+		{"without unit", "5", 5 * time.Minute, false, true},
+		{"not a duration", "five minutes", 5 * time.Minute, false, true},
+		{"below minimum", "10s", time.Minute, true, false},
+		{"above maximum", "48h", DEFAULT_MAX_TIMER, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("TEST_TIMEOUT", tt.env)
+			configMutex.Lock()
+			configWarnings = nil
+			startupReport.Clamped, startupReport.Defaulted = nil, nil
+			configMutex.Unlock()
+
+			if value := parseTimer("TEST_TIMEOUT", 5*time.Minute, time.Minute); value != tt.expected {
+				t.Errorf("parseTimer returned %s, want %s", value, tt.expected)
+			}
+			report := startupSnapshot()
+			if clamped := len(report.Clamped) > 0; clamped != tt.clamped {
+				t.Errorf("clamped %v, want %t", report.Clamped, tt.clamped)
+			}
+			if defaulted := len(report.Defaulted) > 0; defaulted != tt.defaulted {
+				t.Errorf("defaulted %v, want %t", report.Defaulted, tt.defaulted)
+			}
+			configMutex.Lock()
+			warned := len(configWarnings) > 0
+			configMutex.Unlock()
+			if warned != (tt.clamped || tt.defaulted) {
+				t.Errorf("warnings %q", configWarnings)
+			}
+		})
+	}
+}
+
+func TestClampTimer(t *testing.T) {
+	t.Setenv("TEST_CLAMP_TIMEOUT", "10m")
+	parseTimer("TEST_CLAMP_TIMEOUT", 5*time.Minute, time.Minute)
+
+	tests := []struct {
+		value    time.Duration
+		expected time.Duration
+	}{
+		{30 * time.Second, time.Minute},
+		{20 * time.Minute, 20 * time.Minute},
+		{30 * time.Hour, DEFAULT_MAX_TIMER},
+	}
+	for _, tt := range tests {
+		if value := clampTimer("TEST_CLAMP_TIMEOUT", tt.value, "test"); value != tt.expected {
+			t.Errorf("clampTimer(%s) returned %s, want %s", tt.value, value, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package driver
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/common"
+
+	sdkModels "github.com/edgexfoundry/device-sdk-go/v2/pkg/models"
+)
+
+const (
+	timingViolationResource = "TimingViolation"
+	directionOn             = "on"
+	directionOff            = "off"
+	directionBoth           = "both"
+	severityWarning         = "warning"
+	severityFault           = "fault"
+)
+
+// TimingAssertion declares how long the feedback of a line may take to confirm an actuation, e.g. a
+// valve should confirm open within 2s. Slower confirmations hint at degrading equipment and raise a
+// warning, or a fault when severity is fault.
+type TimingAssertion struct {
+	Line      string `yaml:"line"`
+	Within    string `yaml:"within"`
+	Direction string `yaml:"direction"`
+	Severity  string `yaml:"severity"`
+	within    time.Duration
+}
+
+var (
+	assertionMutex  = sync.Mutex{}
+	assertionTimers = make(map[string]*time.Timer)
+)
+
+// validateAssertions checks the assertions section of the configuration file.
+func validateAssertions() error {
+	for i := range driverConfig.Assertions {
+		a := &driverConfig.Assertions[i]
+		if a.Line == "" {
+			return fmt.Errorf("assertion line cannot be empty")
+		}
+		within, err := time.ParseDuration(a.Within)
+		if err != nil || within <= 0 {
+			return fmt.Errorf("assertion on %s: invalid within %q", a.Line, a.Within)
+		}
+		a.within = within
+		switch a.Direction {
+		case "":
+			a.Direction = directionBoth
+		case directionOn, directionOff, directionBoth:
+		default:
+			return fmt.Errorf("assertion on %s: unknown direction %q", a.Line, a.Direction)
+		}
+		switch a.Severity {
+		case "":
+			a.Severity = severityWarning
+		case severityWarning, severityFault:
+		default:
+			return fmt.Errorf("assertion on %s: unknown severity %q", a.Line, a.Severity)
+		}
+	}
+	return nil
+}
+
+func assertionFor(line string, value int) (TimingAssertion, bool) {
+	direction := directionOff
+	if value == 1 {
+		direction = directionOn
+	}
+	for _, a := range driverConfig.Assertions {
+		if a.Line == line && (a.Direction == directionBoth || a.Direction == direction) {
+			return a, true
+		}
+	}
+	return TimingAssertion{}, false
+}
+
+// armAssertion starts the timing check of an actuation of a line with feedback.
+func (s *SimpleDriver) armAssertion(line string, value int) {
+	a, ok := assertionFor(line, value)
+	assertionMutex.Lock()
+	defer assertionMutex.Unlock()
+	if timer, ok := assertionTimers[line]; ok {
+		timer.Stop()
+		delete(assertionTimers, line)
+	}
+	if !ok {
+		return
+	}
+	assertionTimers[line] = time.AfterFunc(a.within, func() { s.assertionViolated(a, value) })
+}
+
+// disarmAssertion ends the timing check of a line once its feedback confirmed the actuation, clearing
+// the fault raised by a previous violation.
+func disarmAssertion(line string) {
+	assertionMutex.Lock()
+	if timer, ok := assertionTimers[line]; ok {
+		timer.Stop()
+		delete(assertionTimers, line)
+	}
+	assertionMutex.Unlock()
+	clearFault("timing-" + line)
+}
+
+func (s *SimpleDriver) assertionViolated(a TimingAssertion, value int) {
+	assertionMutex.Lock()
+	delete(assertionTimers, a.Line)
+	assertionMutex.Unlock()
+
+	detail := fmt.Sprintf("feedback did not confirm %d within %s", value, a.within)
+	audit("timing-violation", a.Line, detail)
+	recordTimelineEvent(a.Line, "timing-violation", detail)
+	if a.Severity == severityFault {
+		setFault("timing-"+a.Line, fmt.Errorf("%s: %s", a.Line, detail))
+	} else {
+		log.Printf("WARNING: gpio %s %s", a.Line, detail)
+		sendNotification("timing", notificationSeverityNormal, tr("notification.timing-violation", a.Line, value, a.within))
+	}
+
+	payload, err := shapePayload(timingViolationResource, map[string]interface{}{
+		"line":     a.Line,
+		"value":    value,
+		"within":   a.Within,
+		"severity": a.Severity,
+	})
+	if err != nil {
+		log.Printf("Cannot marshal timing violation. Error: %s", err)
+		return
+	}
+	cv, err := sdkModels.NewCommandValue(timingViolationResource, common.ValueTypeString, string(payload))
+	if err != nil {
+		log.Printf("Cannot create timing violation reading. Error: %s", err)
+		return
+	}
+	s.asyncCh <- &sdkModels.AsyncValues{
+		DeviceName:    deviceName(),
+		CommandValues: []*sdkModels.CommandValue{cv},
+	}
+}
+
+// checkAssertionFeedback warns about assertions on lines without a feedback input, which can never
+// be checked.
+func (s *SimpleDriver) checkAssertionFeedback() {
+	for _, a := range driverConfig.Assertions {
+		if _, ok := s.feedbackOf(a.Line); !ok {
+			log.Printf("WARNING: timing assertion on gpio %s ignored, the line has no feedback", a.Line)
+		}
+	}
+}
@@ -1,141 +1,302 @@
 package driver
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"net/http"
+	"sort"
+	"sync"
 	"time"
 
 	"github.com/edgexfoundry/device-gpiod/gpio"
+	"github.com/edgexfoundry/device-gpiod/indicator"
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/common"
 )
 
-type lights struct {
-	flashing bool
-	status   bool
-	color    rune
-	gpio     gpio.GPIO
+const (
+	StateIdle        = "idle"
+	StateRunning     = "running"
+	StateReversing   = "reversing"
+	StateCleaning    = "cleaning"
+	StateWarning     = "warning"
+	StateMaintenance = "maintenance"
+	StateOffline     = "offline"
+	StateFault       = "fault"
+	StateLockout     = "lockout"
+
+	indicatorRoute       = common.ApiBase + "/indicator"
+	DEFAULT_FLASH_PERIOD = time.Duration(3) * time.Second
+)
+
+// IndicatorChannel is a segment of the indicator (an LED, a stack-light segment) driven by a line.
+// Buzzer channels are muted during quiet hours.
+type IndicatorChannel struct {
+	Name   string `yaml:"name"`
+	Line   string `yaml:"line"`
+	Buzzer bool   `yaml:"buzzer"`
+}
+
+// IndicatorState is how a semantic state is shown: the channels steadily on and the flashing ones.
+// When several states are active the one with the highest priority is shown.
+type IndicatorState struct {
+	On       []string `yaml:"on"`
+	Flash    []string `yaml:"flash"`
+	Priority int      `yaml:"priority"`
+}
+
+// Indicator maps semantic states (idle, running, warning, fault, maintenance...) to any number
+// of channels, from a single bi-color LED to a five segment stack light. The states derived from
+// the service state are raised automatically, the others are raised through the API.
+// Phases maps the cycle phases (pump, reverse, clean, gap, warmup) to the state shown while they run.
+type Indicator struct {
+	Channels []IndicatorChannel        `yaml:"channels"`
+	States   map[string]IndicatorState `yaml:"states"`
+	Phases   map[string]string         `yaml:"phases"`
+	Flash    string                    `yaml:"flash"`
+	flash    time.Duration
+}
+
+type indicatorRequest struct {
+	State  string `json:"state"`
+	Active bool   `json:"active"`
 }
 
 var (
-	green, yellow, red *lights
+	indicatorMutex = sync.Mutex{}
+	// legacyIndicator is the green/yellow/red traffic light on lines 5, 6 and 7 of the light lines, used
+	// when the configuration file has no indicator section.
+	legacyIndicator = Indicator{
+		Channels: []IndicatorChannel{{Name: "green"}, {Name: "yellow"}, {Name: "red"}},
+		States: map[string]IndicatorState{
+			StateIdle:      {Priority: 0},
+			StateRunning:   {On: []string{"green"}, Priority: 20},
+			StateCleaning:  {On: []string{"yellow"}, Priority: 30},
+			StateReversing: {Flash: []string{"green"}, Priority: 40},
+			StateWarning:   {Flash: []string{"yellow"}, Priority: 70},
+			StateOffline:   {Flash: []string{"red"}, Priority: 90},
+			StateFault:     {On: []string{"red"}, Priority: 100},
+			StateLockout:   {On: []string{"yellow", "red"}, Priority: 110},
+		},
+		flash: DEFAULT_FLASH_PERIOD,
+	}
+	legacyOffsets   = map[int]string{5: "green", 6: "yellow", 7: "red"}
+	defaultPhases   = map[string]string{phasePump: StateRunning, phaseReverse: StateReversing, phaseClean: StateCleaning}
+	indicatorPanel  = indicator.NewPanel()
+	indicatorActive = make(map[string]bool)
 )
 
-func HandleLight(g gpio.GPIO) {
-	switch g.Line {
-	case 5:
-		green.color = 'G'
-		green.gpio = g
-	case 6:
-		yellow.color = 'Y'
-		yellow.gpio = g
-	case 7:
-		red.color = 'R'
-		red.gpio = g
-	default:
-		log.Printf("Unknown light %d", g.Line)
+// activeIndicator returns the indicator of the configuration file, or the legacy traffic light.
+func activeIndicator() *Indicator {
+	if driverConfig.Indicator != nil {
+		return driverConfig.Indicator
+	}
+	return &legacyIndicator
+}
+
+// validateIndicator checks the indicator section of the configuration file.
+func validateIndicator() error {
+	ind := driverConfig.Indicator
+	if ind == nil {
+		return nil
+	}
+	channels := make(map[string]bool)
+	for _, c := range ind.Channels {
+		if c.Name == "" || c.Line == "" || channels[c.Name] {
+			return errors.New("channels must have a unique name and a line")
+		}
+		channels[c.Name] = true
+	}
+	for name, state := range ind.States {
+		for _, c := range append(append([]string{}, state.On...), state.Flash...) {
+			if !channels[c] {
+				return fmt.Errorf("state %s uses unknown channel %s", name, c)
+			}
+		}
+	}
+	phases := map[string]bool{phasePump: true, phaseGap: true, phaseWarmUp: true}
+	for _, phase := range sequencePhases() {
+		phases[phase] = true
+	}
+	for phase, state := range ind.Phases {
+		if !phases[phase] {
+			return fmt.Errorf("unknown phase %s", phase)
+		}
+		if _, ok := ind.States[state]; !ok {
+			return fmt.Errorf("phase %s uses unknown state %s", phase, state)
+		}
+	}
+	ind.flash = DEFAULT_FLASH_PERIOD
+	if ind.Flash != "" {
+		flash, err := time.ParseDuration(ind.Flash)
+		if err != nil || flash <= 0 {
+			return fmt.Errorf("invalid flash period %q", ind.Flash)
+		}
+		ind.flash = flash
+	}
+	return nil
+}
+
+// phaseState returns the indicator state shown while the cycle phase runs, "" for none.
+func phaseState(phase string) string {
+	if ind := driverConfig.Indicator; ind != nil && ind.Phases != nil {
+		return ind.Phases[phase]
 	}
+	return defaultPhases[phase]
 }
 
-func Up(color rune) error {
-	var err error
-	switch color {
-	case 'G':
-		err = green.gpio.Up()
-		green.status = true
-		yellow.status = false
-		red.status = false
-	case 'Y':
-		err = yellow.gpio.Up()
-		yellow.status = true
-		red.status = false
-		green.status = false
-	case 'R':
-		err = red.gpio.Up()
-		red.status = true
-		green.status = false
-		yellow.status = false
-	default:
-		log.Printf("Unknown color %c", color)
-	}
-	return err
+// isIndicatorLine reports whether the line drives a channel of the configured indicator.
+func isIndicatorLine(name string) bool {
+	if driverConfig.Indicator == nil {
+		return false
+	}
+	for _, c := range driverConfig.Indicator.Channels {
+		if c.Line == name {
+			return true
+		}
+	}
+	return false
 }
 
-func Down(color rune) error {
-	var err error
-	switch color {
-	case 'G':
-		err = green.gpio.Down()
-		green.status = false
-	case 'Y':
-		err = yellow.gpio.Down()
-		yellow.status = false
-	case 'R':
-		err = red.gpio.Down()
-		red.status = false
-	default:
-		log.Printf("Unknown color %c", color)
-	}
-	return err
+// isSignalLine reports whether the line drives the indicator rather than an actuator.
+func isSignalLine(name string) bool {
+	return matchLight(name) || isIndicatorLine(name)
 }
 
-func Flashing(color rune) error {
-	var err error
-	var flashingLight *lights
-
-	switch color {
-	case 'G':
-		flashingLight = green
-	case 'Y':
-		flashingLight = yellow
-	case 'R':
-		flashingLight = red
-	default:
-		log.Printf("Unknown color %c", color)
-		return fmt.Errorf("unknown color %c", color)
-	}
-	for flashingLight.flashing {
-		err = Up(color)
-		if err != nil {
-			log.Printf("Cannot start light %c. Error: %s", color, err)
-			return err
+// HandleLight binds a line to a channel of the indicator: by name for the configured indicator, by
+// offset for the legacy traffic light.
+func HandleLight(g gpio.GPIO) {
+	// The indicator must keep working while actuation is inhibited, e.g. to show a lockout
+	g.ExemptFromInhibit()
+	drive := func(level float64) (bool, error) { return driveChannel(g, level) }
+	if driverConfig.Indicator != nil {
+		for _, c := range driverConfig.Indicator.Channels {
+			if c.Line == g.Name {
+				indicatorPanel.Add(c.Name, drive)
+				return
+			}
 		}
-		time.Sleep(3 * time.Second)
-		err = Down(color)
-		if err != nil {
-			log.Printf("Cannot stop light %c. Error: %s", color, err)
-			return err
+		return
+	}
+	channel, ok := legacyOffsets[g.Line]
+	if !ok {
+		logf(moduleIndicators, levelWarn, "Unknown light %d", g.Line)
+		return
+	}
+	indicatorPanel.Add(channel, drive)
+}
+
+// setIndicator raises or clears a state of the indicator on top of the ones derived from the
+// service state.
+func setIndicator(state string, active bool) {
+	indicatorMutex.Lock()
+	defer indicatorMutex.Unlock()
+	if active {
+		indicatorActive[state] = true
+	} else {
+		delete(indicatorActive, state)
+	}
+}
+
+// shownState returns the active state with the highest priority. Must be called holding indicatorMutex.
+func shownState(ind *Indicator, conditions []string) (string, IndicatorState) {
+	active := append([]string{}, conditions...)
+	for name := range indicatorActive {
+		active = append(active, name)
+	}
+	shown, shownState := StateIdle, ind.States[StateIdle]
+	for _, name := range active {
+		state, ok := ind.States[name]
+		if ok && (state.Priority > shownState.Priority || (state.Priority == shownState.Priority && name < shown)) {
+			shown, shownState = name, state
 		}
 	}
-	return nil
+	return shown, shownState
 }
 
-func SetFlashOn(color rune) {
-	switch color {
-	case 'G':
-		green.flashing = true
-		yellow.flashing = false
-		red.flashing = false
-	case 'Y':
-		yellow.flashing = true
-		green.flashing = false
-		red.flashing = false
-	case 'R':
-		red.flashing = true
-		green.flashing = false
-		yellow.flashing = false
-	default:
-		log.Printf("Unknown color %c", color)
+// startIndicator shows the state with the highest priority on the channels of the indicator, toggling
+// the flashing ones every flash period. Without any line bound to a channel the indicator is disabled.
+func startIndicator() {
+	if indicatorPanel.Len() == 0 {
+		logf(moduleIndicators, levelInfo, "No indicator lines configured, indicator disabled")
+		return
 	}
+	goBackground(func() {
+		for {
+			ind := activeIndicator()
+			conditions := serviceConditions()
+			quiet := inQuietHours()
+			indicatorMutex.Lock()
+			shown, state := shownState(ind, conditions)
+			indicatorMutex.Unlock()
+			level := 1.0
+			if quiet != nil && quiet.Mode == quietFaultOnly && shown != StateFault {
+				state = ind.States[StateIdle]
+			} else if quiet != nil && quiet.Mode == quietDim {
+				level = quiet.Dim
+			}
+			modes := make(map[string]indicator.Mode)
+			for _, c := range state.Flash {
+				modes[c] = indicator.Flash
+			}
+			for _, c := range state.On {
+				modes[c] = indicator.Solid
+			}
+			for _, c := range ind.Channels {
+				if c.Buzzer && quiet != nil {
+					modes[c.Name] = indicator.Off
+				}
+			}
+			for _, channel := range indicatorPanel.Names() {
+				mode, ok := modes[channel]
+				if !ok {
+					mode = indicator.Off
+				}
+				if err := indicatorPanel.Set(channel, mode, level); err != nil {
+					logf(moduleIndicators, levelError, "Cannot set indicator channel %s. Error: %s", channel, err)
+				}
+			}
+			indicatorPanel.Toggle()
+			if !sleepUntilStop(ind.flash) {
+				return
+			}
+		}
+	})
 }
 
-func SetFlashOff(color rune) {
-	switch color {
-	case 'G':
-		green.flashing = false
-	case 'Y':
-		yellow.flashing = false
-	case 'R':
-		red.flashing = false
-	default:
-		log.Printf("Unknown color %c", color)
+// handleIndicator returns the service state, the states raised through the API, the state shown and
+// the channels; POST raises or clears a state, e.g. maintenance while a technician works on the machine.
+func (s *SimpleDriver) handleIndicator(w http.ResponseWriter, r *http.Request) {
+	ind := activeIndicator()
+	if r.Method == http.MethodPost {
+		var req indicatorRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		if _, ok := ind.States[req.State]; !ok {
+			writeError(w, http.StatusNotFound, fmt.Errorf("unknown indicator state %s", req.State))
+			return
+		}
+		setIndicator(req.State, req.Active)
+		audit("indicator", req.State, fmt.Sprintf("active: %t", req.Active))
+	}
+
+	conditions := serviceConditions()
+	indicatorMutex.Lock()
+	defer indicatorMutex.Unlock()
+	active := make([]string, 0, len(indicatorActive))
+	for name := range indicatorActive {
+		active = append(active, name)
 	}
+	sort.Strings(active)
+	shown, _ := shownState(ind, conditions)
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"service":  serviceState(),
+		"quiet":    inQuietHours() != nil,
+		"enabled":  indicatorPanel.Len() > 0,
+		"active":   active,
+		"shown":    shown,
+		"channels": indicatorPanel.Outputs(),
+	})
 }
@@ -0,0 +1,86 @@
+package driver
+
+import (
+	"fmt"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/common"
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/models"
+)
+
+// ResourceTransform rescales the readings of a numeric derived resource before they are published,
+// e.g. a pulse count to liters with scale 1/pulses-per-liter. With invert the reciprocal of the raw
+// value is taken first (a period becomes a rate); value = f(raw) * scale + offset.
+type ResourceTransform struct {
+	Resource string   `yaml:"resource"`
+	Scale    *float64 `yaml:"scale"`
+	Offset   float64  `yaml:"offset"`
+	Invert   bool     `yaml:"invert"`
+	Units    string   `yaml:"units"`
+}
+
+// validateTransforms checks the transforms section of the configuration file.
+func validateTransforms() error {
+	names := make(map[string]bool)
+	for _, t := range driverConfig.Transforms {
+		if t.Resource == "" || names[t.Resource] {
+			return fmt.Errorf("transform resources must be unique and not empty")
+		}
+		names[t.Resource] = true
+		if t.Scale != nil && *t.Scale == 0 {
+			return fmt.Errorf("transform of %s: scale cannot be 0", t.Resource)
+		}
+	}
+	return nil
+}
+
+func transformFor(resource string) (ResourceTransform, bool) {
+	for _, t := range driverConfig.Transforms {
+		if t.Resource == resource {
+			return t, true
+		}
+	}
+	return ResourceTransform{}, false
+}
+
+// transformProperties adapts the properties of a transformed resource: its readings become floats,
+// in the units of the transform when given.
+func transformProperties(resource string, properties models.ResourceProperties) models.ResourceProperties {
+	t, ok := transformFor(resource)
+	if !ok {
+		return properties
+	}
+	properties.ValueType = common.ValueTypeFloat64
+	if t.Units != "" {
+		properties.Units = t.Units
+	}
+	return properties
+}
+
+// transformValue applies the transform of resource, if any, to a raw numeric value and returns the
+// value type and value to publish. ok is false when the value cannot be transformed (the reciprocal
+// of 0).
+func transformValue(resource string, valueType string, raw interface{}) (string, interface{}, bool) {
+	t, ok := transformFor(resource)
+	if !ok {
+		return valueType, raw, true
+	}
+	var value float64
+	switch v := raw.(type) {
+	case uint64:
+		value = float64(v)
+	case float64:
+		value = v
+	default:
+		return valueType, raw, true
+	}
+	if t.Invert {
+		if value == 0 {
+			return valueType, raw, false
+		}
+		value = 1 / value
+	}
+	if t.Scale != nil {
+		value *= *t.Scale
+	}
+	return common.ValueTypeFloat64, value + t.Offset, true
+}
@@ -0,0 +1,180 @@
+package driver
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/common"
+)
+
+const (
+	operatorHeader       = "X-Operator"
+	confirmationHeader   = "X-Confirmation"
+	defaultConfirmWithin = time.Duration(2) * time.Minute
+)
+
+// TwoPersonRule lists the destructive command routes (relative to the API base, e.g. "/override")
+// that run only once a second operator confirms them within the window.
+type TwoPersonRule struct {
+	Routes []string `yaml:"routes"`
+	Window string   `yaml:"window"`
+	window time.Duration
+}
+
+// pendingCommand is a protected request waiting for its confirmation.
+type pendingCommand struct {
+	path     string
+	body     []byte
+	identity string
+	expires  time.Time
+}
+
+var (
+	pendingMutex    = sync.Mutex{}
+	pendingCommands = make(map[string]*pendingCommand)
+)
+
+// validateTwoPerson checks the two_person section of the configuration file.
+func validateTwoPerson() error {
+	rule := driverConfig.TwoPerson
+	if rule == nil {
+		return nil
+	}
+	if len(rule.Routes) == 0 {
+		return errors.New("no routes to protect")
+	}
+	rule.window = defaultConfirmWithin
+	if rule.Window != "" {
+		window, err := time.ParseDuration(rule.Window)
+		if err != nil || window <= 0 {
+			return fmt.Errorf("invalid window %q", rule.Window)
+		}
+		rule.window = window
+	}
+	return nil
+}
+
+// requiresConfirmation reports whether requests to path need a second operator.
+func requiresConfirmation(path string) bool {
+	rule := driverConfig.TwoPerson
+	if rule == nil {
+		return false
+	}
+	for _, route := range rule.Routes {
+		if common.ApiBase+route == path {
+			return true
+		}
+	}
+	return false
+}
+
+// requestIdentity identifies the operator of a request by the X-Operator header, or by its bearer token.
+func requestIdentity(r *http.Request) string {
+	if operator := r.Header.Get(operatorHeader); operator != "" {
+		return operator
+	}
+	if token := r.Header.Get("Authorization"); token != "" {
+		sum := sha256.Sum256([]byte(token))
+		return "token-" + hex.EncodeToString(sum[:8])
+	}
+	return ""
+}
+
+// twoPersonRoute holds the POST requests to protected routes: the first request is parked and answered
+// with a confirmation id, and runs only when a request from a different operator presents that id in
+// the X-Confirmation header before the window expires.
+func twoPersonRoute(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || !requiresConfirmation(r.URL.Path) {
+			handler(w, r)
+			return
+		}
+		identity := requestIdentity(r)
+		if identity == "" {
+			writeError(w, http.StatusUnauthorized, fmt.Errorf("%s requires an identified operator (%s header or token)", r.URL.Path, operatorHeader))
+			return
+		}
+
+		id := r.Header.Get(confirmationHeader)
+		if id == "" {
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, err)
+				return
+			}
+			id, err = parkCommand(r.URL.Path, body, identity)
+			if err != nil {
+				writeError(w, http.StatusInternalServerError, err)
+				return
+			}
+			audit("confirmation-requested", r.URL.Path, fmt.Sprintf("by %s, id %s", identity, id))
+			writeJSON(w, http.StatusAccepted, map[string]interface{}{
+				"confirmation": id,
+				"expires":      time.Now().Add(driverConfig.TwoPerson.window),
+			})
+			return
+		}
+
+		pending, err := takeCommand(id, r.URL.Path, identity)
+		if err != nil {
+			audit("confirmation-refused", r.URL.Path, fmt.Sprintf("by %s, id %s: %s", identity, id, err))
+			writeError(w, http.StatusForbidden, err)
+			return
+		}
+		audit("confirmation-granted", r.URL.Path, fmt.Sprintf("requested by %s, confirmed by %s", pending.identity, identity))
+		r.Body = io.NopCloser(bytes.NewReader(pending.body))
+		r.ContentLength = int64(len(pending.body))
+		handler(w, r)
+	}
+}
+
+// parkCommand stores a protected request and returns its confirmation id.
+func parkCommand(path string, body []byte, identity string) (string, error) {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	id := hex.EncodeToString(buf)
+	pendingMutex.Lock()
+	defer pendingMutex.Unlock()
+	now := time.Now()
+	for key, p := range pendingCommands {
+		if now.After(p.expires) {
+			delete(pendingCommands, key)
+		}
+	}
+	pendingCommands[id] = &pendingCommand{
+		path:     path,
+		body:     body,
+		identity: identity,
+		expires:  now.Add(driverConfig.TwoPerson.window),
+	}
+	return id, nil
+}
+
+// takeCommand returns the parked request confirmed by identity, which must differ from the requester.
+func takeCommand(id string, path string, identity string) (*pendingCommand, error) {
+	pendingMutex.Lock()
+	defer pendingMutex.Unlock()
+	pending, ok := pendingCommands[id]
+	if !ok || pending.path != path {
+		return nil, fmt.Errorf("unknown confirmation %s", id)
+	}
+	if time.Now().After(pending.expires) {
+		delete(pendingCommands, id)
+		return nil, fmt.Errorf("confirmation %s expired", id)
+	}
+	if pending.identity == identity {
+		return nil, errors.New("a command must be confirmed by a different operator")
+	}
+	delete(pendingCommands, id)
+	return pending, nil
+}
@@ -0,0 +1,326 @@
+package driver
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/edgexfoundry/device-gpiod/gpio"
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/common"
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/models"
+
+	sdkModels "github.com/edgexfoundry/device-sdk-go/v2/pkg/models"
+)
+
+const (
+	DEFAULT_TRAVEL_TIME    = time.Duration(10) * time.Second
+	DEFAULT_JOG_TIME       = time.Duration(1) * time.Second
+	valvePositionAttribute = "position"
+
+	PositionOpen   = "open"
+	PositionClosed = "closed"
+	PositionMoving = "moving"
+	PositionFault  = "fault"
+)
+
+var valvePositionKind = derivedKind{"Position", common.ValueTypeString, "", "Position from the limit switches (open, closed, moving or fault)"}
+
+// ValvePosition is the position of a valve derived from its open and closed limit switches.
+type ValvePosition struct {
+	Valve    string    `json:"valve"`
+	Position string    `json:"position"`
+	Target   string    `json:"target,omitempty"`
+	Reason   string    `json:"reason,omitempty"`
+	Since    time.Time `json:"since"`
+}
+
+type valveState struct {
+	ValvePosition
+	openSwitch   string
+	closedSwitch string
+	open         bool
+	closed       bool
+	travel       time.Duration
+	deadline     time.Time
+	timer        *time.Timer
+	retries      int
+	maxRetries   int
+	strategy     string
+	jog          time.Duration
+	jogging      bool
+	stuck        bool
+}
+
+var (
+	valveMutex    = sync.Mutex{}
+	valves        = make(map[string]*valveState)
+	limitSwitches = make(map[string][]string)
+	// phaseValves are the valves actuated by the running pipeline phase
+	phaseValves = make(map[string]bool)
+)
+
+// valveProfileResources returns the position resource of a valve with limit switches, to be included
+// in the generated device profile.
+func valveProfileResources(g gpio.GPIO) []models.DeviceResource {
+	if g.OpenSwitch == "" {
+		return nil
+	}
+	return []models.DeviceResource{{
+		Name:        derivedResourceName(g.Name, valvePositionKind),
+		Description: fmt.Sprintf("%s of %s", valvePositionKind.Description, lineDescription(g)),
+		Attributes: map[string]interface{}{
+			"name":                 g.Name,
+			valvePositionAttribute: fmt.Sprintf("%s,%s,%s,%s", PositionOpen, PositionClosed, PositionMoving, PositionFault),
+		},
+		Properties: models.ResourceProperties{
+			ValueType: valvePositionKind.ValueType,
+			ReadWrite: common.ReadWrite_R,
+		},
+	}}
+}
+
+// startValveSupervision derives the position of the valves declaring open_switch and closed_switch.
+// The limit switches are input lines, watched by the input monitoring, so it runs after it. A valve
+// must reach the switch of the commanded position within travel_time (default 10s) and stay there;
+// both switches active, none active past the travel time or the wrong one reached is a fault. A valve
+// missing its position is retried stuck_retries times before the fault is declared: the jog strategy
+// (default) drives it back for jog_time (default 1s) and commands it again, wait gives it another
+// travel time.
+func (s *SimpleDriver) startValveSupervision() {
+	for i := range s.GpioList.Gpio {
+		g := &s.GpioList.Gpio[i]
+		if g.OpenSwitch == "" {
+			continue
+		}
+		v := &valveState{
+			ValvePosition: ValvePosition{Valve: g.Name},
+			openSwitch:    g.OpenSwitch,
+			closedSwitch:  g.ClosedSwitch,
+			travel:        g.TravelPeriod(DEFAULT_TRAVEL_TIME),
+			maxRetries:    g.StuckRetries,
+			strategy:      g.StuckStrategy,
+			jog:           g.JogPeriod(DEFAULT_JOG_TIME),
+		}
+		if v.strategy == "" {
+			v.strategy = gpio.StuckJog
+		}
+		v.open = s.switchActive(g.OpenSwitch)
+		v.closed = s.switchActive(g.ClosedSwitch)
+
+		valveMutex.Lock()
+		valves[g.Name] = v
+		limitSwitches[g.OpenSwitch] = append(limitSwitches[g.OpenSwitch], g.Name)
+		limitSwitches[g.ClosedSwitch] = append(limitSwitches[g.ClosedSwitch], g.Name)
+		// A valve found between its switches may still be travelling from before the restart
+		s.armTravel(v)
+		s.evaluateValve(v)
+		valveMutex.Unlock()
+		log.Printf("Supervising position of valve %s (open %s, closed %s, travel %s)", g.Name, g.OpenSwitch, g.ClosedSwitch, v.travel)
+	}
+}
+
+func (s *SimpleDriver) switchActive(name string) bool {
+	g, ok := s.findGpio(name)
+	if !ok {
+		return false
+	}
+	value, err := g.Value()
+	if err != nil {
+		log.Printf("Cannot read limit switch %s. Error: %s", name, err)
+		return false
+	}
+	return value == 1
+}
+
+// valveActuated starts the travel of a valve towards the commanded position.
+func (s *SimpleDriver) valveActuated(name string, value int) {
+	valveMutex.Lock()
+	defer valveMutex.Unlock()
+	v, ok := valves[name]
+	if !ok || v.jogging {
+		return
+	}
+	v.retries = 0
+	v.Target = PositionClosed
+	if value == 1 {
+		v.Target = PositionOpen
+	}
+	s.armTravel(v)
+	s.evaluateValve(v)
+}
+
+// limitSwitchChanged updates the valves using the switch of the event.
+func (s *SimpleDriver) limitSwitchChanged(evt gpio.Event) {
+	valveMutex.Lock()
+	defer valveMutex.Unlock()
+	for _, name := range limitSwitches[evt.Name] {
+		v := valves[name]
+		if evt.Name == v.openSwitch {
+			v.open = evt.Value == 1
+		} else {
+			v.closed = evt.Value == 1
+		}
+		s.evaluateValve(v)
+	}
+}
+
+// armTravel gives the valve the travel time to reach its switch, evaluating it again once expired.
+// Called with valveMutex held.
+func (s *SimpleDriver) armTravel(v *valveState) {
+	v.deadline = time.Now().Add(v.travel)
+	if v.timer != nil {
+		v.timer.Stop()
+	}
+	v.timer = time.AfterFunc(v.travel, func() {
+		valveMutex.Lock()
+		defer valveMutex.Unlock()
+		s.evaluateValve(v)
+	})
+}
+
+// evaluateValve derives the position from the switches and the travel deadline, publishing it when it
+// changed. Called with valveMutex held.
+func (s *SimpleDriver) evaluateValve(v *valveState) {
+	travelling := time.Now().Before(v.deadline)
+	reached := ""
+	if v.open {
+		reached = PositionOpen
+	}
+	if v.closed {
+		reached = PositionClosed
+	}
+	position, reason := reached, ""
+	// A valve that did not reach the commanded position may only be stuck
+	retryable := false
+	switch {
+	case v.open && v.closed:
+		position, reason = PositionFault, "both limit switches active"
+	case reached == "" && travelling:
+		position = PositionMoving
+	case reached == "":
+		position, reason = PositionFault, fmt.Sprintf("no limit switch reached within %s", v.travel)
+		retryable = v.Target != ""
+	case v.Target != "" && reached != v.Target && travelling:
+		position = PositionMoving
+	case v.Target != "" && reached != v.Target:
+		position, reason = PositionFault, fmt.Sprintf("%s while commanded %s", reached, v.Target)
+		retryable = true
+	}
+	if retryable && !v.jogging && v.retries < v.maxRetries {
+		v.retries++
+		s.retryValve(v, reason)
+		position, reason = PositionMoving, ""
+	} else if retryable && v.retries > 0 {
+		reason = fmt.Sprintf("%s after %d retries", reason, v.retries)
+	}
+	v.stuck = retryable && position == PositionFault
+	if position == v.Position && reason == v.Reason {
+		return
+	}
+	v.Position, v.Reason, v.Since = position, reason, time.Now()
+	snapshot := v.ValvePosition
+	go s.pushValvePosition(snapshot)
+}
+
+// retryValve tries again to bring a stuck valve to its target. Called with valveMutex held.
+func (s *SimpleDriver) retryValve(v *valveState, reason string) {
+	log.Printf("Valve %s stuck (%s), retry %d of %d with strategy %s", v.Valve, reason, v.retries, v.maxRetries, v.strategy)
+	audit("valve-retry", v.Valve, fmt.Sprintf("%s, retry %d of %d (%s)", reason, v.retries, v.maxRetries, v.strategy))
+	if v.strategy == gpio.StuckWait {
+		s.armTravel(v)
+		return
+	}
+	v.jogging = true
+	v.deadline = time.Now().Add(v.jog + v.travel)
+	go s.jogValve(v, v.Target == PositionOpen)
+}
+
+// jogValve drives the valve back towards the position it left for the jog time, then commands the
+// target again with a fresh travel time. The actuations of the jog do not change the target.
+func (s *SimpleDriver) jogValve(v *valveState, target bool) {
+	defer shutdownOnPanic()
+	g, ok := s.findGpio(v.Valve)
+	if ok {
+		if err := setLevel(g, !target); err != nil {
+			log.Printf("Cannot jog valve %s. Error: %s", v.Valve, err)
+		}
+		time.Sleep(v.jog)
+		if err := setLevel(g, target); err != nil {
+			log.Printf("Cannot command valve %s again. Error: %s", v.Valve, err)
+		}
+	}
+	valveMutex.Lock()
+	defer valveMutex.Unlock()
+	v.jogging = false
+	s.armTravel(v)
+	s.evaluateValve(v)
+}
+
+func setLevel(g *gpio.GPIO, on bool) error {
+	if on {
+		return g.Up()
+	}
+	return g.Down()
+}
+
+// notePhaseValve records that the running pipeline phase actuated the line, if it is a valve.
+func notePhaseValve(name string) {
+	valveMutex.Lock()
+	defer valveMutex.Unlock()
+	if _, ok := valves[name]; ok {
+		phaseValves[name] = true
+	}
+}
+
+// phaseValveFault returns the fault of a valve actuated by the running phase that stayed stuck after
+// its retries, which aborts the phase.
+func phaseValveFault() error {
+	valveMutex.Lock()
+	defer valveMutex.Unlock()
+	for name := range phaseValves {
+		if v := valves[name]; v.stuck {
+			return fmt.Errorf("valve %s stuck: %s", name, v.Reason)
+		}
+	}
+	return nil
+}
+
+// resetPhaseValves forgets the valves of the phase that ended.
+func resetPhaseValves() {
+	valveMutex.Lock()
+	defer valveMutex.Unlock()
+	phaseValves = make(map[string]bool)
+}
+
+func (s *SimpleDriver) pushValvePosition(p ValvePosition) {
+	if p.Position == PositionFault {
+		log.Printf("Valve %s position fault: %s", p.Valve, p.Reason)
+		audit("valve-fault", p.Valve, p.Reason)
+		sendNotification("valve", notificationSeverityCritical, tr("notification.valve-fault", p.Valve, p.Reason))
+	}
+	cv, err := sdkModels.NewCommandValue(derivedResourceName(p.Valve, valvePositionKind), common.ValueTypeString, p.Position)
+	if err != nil {
+		log.Printf("Cannot create position reading for valve %s. Error: %s", p.Valve, err)
+		return
+	}
+	s.asyncCh <- &sdkModels.AsyncValues{
+		DeviceName:    deviceName(),
+		CommandValues: []*sdkModels.CommandValue{cv},
+	}
+}
+
+// valvePositionCommandValue returns the reading of the position resource of a valve.
+func valvePositionCommandValue(name string) (*sdkModels.CommandValue, error) {
+	valveMutex.Lock()
+	v, ok := valves[name]
+	position := ""
+	if ok {
+		position = v.Position
+	}
+	valveMutex.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("gpio %s has no limit switches", name)
+	}
+	return sdkModels.NewCommandValue(derivedResourceName(name, valvePositionKind), common.ValueTypeString, position)
+}
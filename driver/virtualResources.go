@@ -0,0 +1,124 @@
+package driver
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/edgexfoundry/device-gpiod/script"
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/common"
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/models"
+
+	sdkModels "github.com/edgexfoundry/device-sdk-go/v2/pkg/models"
+)
+
+// VirtualResource is a boolean resource not backed by hardware, computed by an expression over the
+// lines and the service state, e.g. `faults() > 0 || alarms() > 0` or `level("DOOR") && since("DOOR") > 60`.
+type VirtualResource struct {
+	Name        string `yaml:"name"`
+	Expr        string `yaml:"expr"`
+	Description string `yaml:"description"`
+	program     *script.Program
+}
+
+var (
+	virtualMutex    = sync.Mutex{}
+	virtualValues   = make(map[string]bool)
+	virtualInterval = time.Second
+)
+
+// compileVirtualResources compiles the expressions of the virtual section of the configuration file.
+func compileVirtualResources() error {
+	names := make(map[string]bool)
+	for i := range driverConfig.Virtual {
+		v := &driverConfig.Virtual[i]
+		if v.Name == "" || names[v.Name] {
+			return fmt.Errorf("virtual resource names must be unique and not empty")
+		}
+		names[v.Name] = true
+		program, err := script.Compile(v.Expr)
+		if err != nil {
+			return fmt.Errorf("virtual resource %s: %s", v.Name, err)
+		}
+		v.program = program
+	}
+	return nil
+}
+
+// virtualProfileResources returns the device resources of the virtual resources.
+func virtualProfileResources() []models.DeviceResource {
+	var resources []models.DeviceResource
+	for _, v := range driverConfig.Virtual {
+		description := v.Description
+		if description == "" {
+			description = v.Expr
+		}
+		resources = append(resources, models.DeviceResource{
+			Name:        v.Name,
+			Description: description,
+			Attributes:  map[string]interface{}{"virtual": v.Expr},
+			Properties: models.ResourceProperties{
+				ValueType: common.ValueTypeBool,
+				ReadWrite: common.ReadWrite_R,
+			},
+		})
+	}
+	return resources
+}
+
+// startVirtualResources evaluates the virtual resources every VIRTUAL_INTERVAL (default 1s) and
+// publishes a reading whenever a value changes.
+func (s *SimpleDriver) startVirtualResources() {
+	if len(driverConfig.Virtual) == 0 {
+		return
+	}
+	if d, err := time.ParseDuration(os.Getenv("VIRTUAL_INTERVAL")); err == nil && d > 0 {
+		virtualInterval = d
+	}
+	goBackground(func() {
+		for {
+			s.evaluateVirtualResources()
+			if !sleepUntilStop(shedInterval(virtualInterval)) {
+				return
+			}
+		}
+	})
+}
+
+func (s *SimpleDriver) evaluateVirtualResources() {
+	env := scriptEnv()
+	var changed []*sdkModels.CommandValue
+	virtualMutex.Lock()
+	for _, v := range driverConfig.Virtual {
+		if v.program == nil {
+			continue
+		}
+		value, err := v.program.EvalBool(env)
+		if err != nil {
+			log.Printf("Cannot evaluate virtual resource %s. Error: %s", v.Name, err)
+			continue
+		}
+		if previous, ok := virtualValues[v.Name]; ok && previous == value {
+			continue
+		}
+		virtualValues[v.Name] = value
+		recordTransition(v.Name, value)
+		cv, err := sdkModels.NewCommandValue(v.Name, common.ValueTypeBool, value)
+		if err != nil {
+			log.Printf("Cannot create virtual resource reading. Error: %s", err)
+			continue
+		}
+		changed = append(changed, cv)
+	}
+	virtualMutex.Unlock()
+
+	if len(changed) == 0 {
+		return
+	}
+	s.asyncCh <- &sdkModels.AsyncValues{
+		DeviceName:    deviceName(),
+		CommandValues: changed,
+	}
+}
@@ -0,0 +1,100 @@
+package driver
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/edgexfoundry/device-gpiod/gpio"
+)
+
+// PrimingPulse drives the pump on then off, to prime it before the first full cycle.
+type PrimingPulse struct {
+	On  string `yaml:"on"`
+	Off string `yaml:"off"`
+	on  time.Duration
+	off time.Duration
+}
+
+// WarmUp runs once before the first pump cycle, so pumps aren't slammed on the instant the service
+// restarts after a power cut: wait for Delay, then run the priming pulses.
+type WarmUp struct {
+	Delay   string         `yaml:"delay"`
+	Priming []PrimingPulse `yaml:"priming"`
+	delay   time.Duration
+}
+
+// validateWarmUp checks the warmup section of the configuration file.
+func validateWarmUp() error {
+	w := driverConfig.WarmUp
+	if w == nil {
+		return nil
+	}
+	if w.Delay != "" {
+		delay, err := time.ParseDuration(w.Delay)
+		if err != nil || delay < 0 {
+			return fmt.Errorf("invalid delay %q", w.Delay)
+		}
+		w.delay = delay
+	}
+	for i := range w.Priming {
+		p := &w.Priming[i]
+		on, err := time.ParseDuration(p.On)
+		if err != nil || on <= 0 {
+			return fmt.Errorf("priming pulse %d: invalid on time %q", i, p.On)
+		}
+		off, err := time.ParseDuration(p.Off)
+		if err != nil || off < 0 {
+			return fmt.Errorf("priming pulse %d: invalid off time %q", i, p.Off)
+		}
+		p.on, p.off = on, off
+	}
+	return nil
+}
+
+// warmUp runs the warm-up sequence on the pump. It is skipped when the pump was found running at
+// startup, in plan mode and while locked out.
+func (s *SimpleDriver) warmUp(pump gpio.GPIO) {
+	w := driverConfig.WarmUp
+	if w == nil || pump.State || planMode || lockedOut() {
+		return
+	}
+	var total time.Duration
+	for _, p := range w.Priming {
+		total += p.on + p.off
+	}
+	op := startOperation(phaseWarmUp, pump.Name, w.delay+total)
+	setPhase(phaseWarmUp, w.delay+total)
+	if w.delay > 0 {
+		log.Printf("Warming up, first pump start in %s", w.delay)
+		supervisedSleep("pipeline", w.delay)
+	}
+	for i, p := range w.Priming {
+		if lockedOut() {
+			op.finish(errors.New("warm-up interrupted by lockout"))
+			return
+		}
+		log.Printf("Priming pulse %d/%d on %s: %s on, %s off", i+1, len(w.Priming), pump.Name, p.on, p.off)
+		err := s.startPipelineLine(&pump)
+		for errors.Is(err, errConcurrencyLimit) && !lockedOut() {
+			supervisedSleep("pipeline", concurrencyTick)
+			err = s.startPipelineLine(&pump)
+		}
+		if err != nil {
+			log.Printf("Cannot start priming pulse on gpio %s. Error: %s", pump.Name, err)
+			op.finish(err)
+			return
+		}
+		supervisedSleep("pipeline", p.on)
+		if err := setPipelineLevel(&pump, false); err != nil {
+			setFault("pump", err)
+			log.Printf("Cannot stop priming pulse on gpio %s. Error: %s", pump.Name, err)
+			op.finish(err)
+			return
+		}
+		supervisedSleep("pipeline", p.off)
+	}
+	audit("warm-up", pump.Name, fmt.Sprintf("delay %s, %d priming pulses", w.delay, len(w.Priming)))
+	op.finish(nil)
+}
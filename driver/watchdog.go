@@ -0,0 +1,103 @@
+package driver
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/edgexfoundry/device-gpiod/gpio"
+)
+
+const (
+	RoleWatchdog = "watchdog"
+	// Writing the magic character before closing disarms watchdog drivers supporting magic close
+	watchdogMagicClose = "V"
+)
+
+var (
+	watchdogStop = make(chan struct{})
+	watchdogDone = make(chan struct{})
+	watchdogFile *os.File
+	watchdogLine *gpio.GPIO
+)
+
+// startWatchdog pets the hardware watchdog at WATCHDOG_DEVICE (e.g. /dev/watchdog) or the GPIO line with
+// role "watchdog" every WATCHDOG_INTERVAL (default 5s), but only while no fault is active and every
+// supervised goroutine is on time. Silent internal failures then end in a clean hardware reset.
+func (s *SimpleDriver) startWatchdog() {
+	for i := range s.GpioList.Gpio {
+		if s.GpioList.Gpio[i].Role == RoleWatchdog {
+			watchdogLine = &s.GpioList.Gpio[i]
+			break
+		}
+	}
+	if device := os.Getenv("WATCHDOG_DEVICE"); device != "" {
+		f, err := os.OpenFile(device, os.O_WRONLY, 0)
+		if err != nil {
+			log.Printf("Cannot open watchdog %s. Error: %s", device, err)
+		} else {
+			watchdogFile = f
+		}
+	}
+	if watchdogFile == nil && watchdogLine == nil {
+		close(watchdogDone)
+		return
+	}
+
+	interval, err := time.ParseDuration(os.Getenv("WATCHDOG_INTERVAL"))
+	if err != nil || interval <= 0 {
+		interval = time.Duration(5) * time.Second
+	}
+
+	go func() {
+		defer close(watchdogDone)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		level := false
+		for {
+			select {
+			case <-watchdogStop:
+				return
+			case <-ticker.C:
+				if !serviceHealthy() {
+					log.Printf("Service unhealthy, not petting the watchdog. Faults: %v", activeFaults())
+					suspectWatchdogReset(true, fmt.Sprintf("faults %v, stalled %v", activeFaults(), stalledGoroutines()))
+					continue
+				}
+				suspectWatchdogReset(false, "")
+				if watchdogFile != nil {
+					if _, err := watchdogFile.Write([]byte{0}); err != nil {
+						log.Printf("Cannot pet watchdog. Error: %s", err)
+					}
+				}
+				if watchdogLine != nil {
+					level = !level
+					if level {
+						err = watchdogLine.Up()
+					} else {
+						err = watchdogLine.Down()
+					}
+					if err != nil {
+						log.Printf("Cannot pet watchdog gpio %s. Error: %s", watchdogLine.Name, err)
+					}
+				}
+			}
+		}
+	}()
+}
+
+// stopWatchdog stops petting and disarms the device watchdog on a clean shutdown.
+func stopWatchdog() {
+	if watchdogFile == nil && watchdogLine == nil {
+		return
+	}
+	close(watchdogStop)
+	<-watchdogDone
+	if watchdogFile != nil {
+		if _, err := watchdogFile.Write([]byte(watchdogMagicClose)); err != nil {
+			log.Printf("Cannot disarm watchdog. Error: %s", err)
+		}
+		watchdogFile.Close()
+	}
+}
@@ -0,0 +1,139 @@
+package driver
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/edgexfoundry/device-gpiod/gpio"
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/common"
+)
+
+const (
+	pumpsRoute   = common.ApiBase + "/pumps"
+	pumpPinRoute = common.ApiBase + "/pumps/pin"
+)
+
+// pumpPair holds the duty and standby pumps. When a standby pump is configured (STANDBY_TRIGGER) each
+// cycle runs the pump with the lowest accumulated runtime, unless one is pinned.
+type pumpPair struct {
+	primary gpio.GPIO
+	standby gpio.GPIO
+	pinned  string
+	active  string
+}
+
+var (
+	pumpsMutex = sync.Mutex{}
+	pumps      = pumpPair{}
+)
+
+type pinRequest struct {
+	Name string `json:"name"`
+}
+
+// lineRuntime returns the accumulated on time of a line.
+func lineRuntime(name string) time.Duration {
+	statsMutex.Lock()
+	defer statsMutex.Unlock()
+	st, ok := stats[name]
+	if !ok {
+		return 0
+	}
+	runtime := st.runtime
+	if !st.onSince.IsZero() {
+		runtime += time.Since(st.onSince)
+	}
+	return runtime
+}
+
+func setPumps(primary gpio.GPIO, standby gpio.GPIO) {
+	pumpsMutex.Lock()
+	defer pumpsMutex.Unlock()
+	pumps.primary, pumps.standby = primary, standby
+	pumps.active = primary.Name
+}
+
+// selectPump returns the pump to use for the next cycle.
+func selectPump(current gpio.GPIO) gpio.GPIO {
+	pumpsMutex.Lock()
+	defer pumpsMutex.Unlock()
+	if pumps.standby.Name == "" {
+		// The primary pump may have been remapped by the custom configuration
+		if pumps.primary.Name != "" {
+			return pumps.primary
+		}
+		return current
+	}
+	next := pumps.primary
+	_, primaryFailed := failedPumps[pumps.primary.Name]
+	_, standbyFailed := failedPumps[pumps.standby.Name]
+	switch {
+	case pumps.pinned == pumps.standby.Name:
+		next = pumps.standby
+	case pumps.pinned == pumps.primary.Name:
+	case primaryFailed != standbyFailed:
+		if primaryFailed {
+			next = pumps.standby
+		}
+	case lineRuntime(pumps.standby.Name) < lineRuntime(pumps.primary.Name):
+		next = pumps.standby
+	}
+	if next.Name != pumps.active {
+		log.Printf("Switching pump from %s to %s", pumps.active, next.Name)
+		audit("pump-switch", next.Name, fmt.Sprintf("from %s", pumps.active))
+		pumps.active = next.Name
+	}
+	return next
+}
+
+// handlePumps returns the duty and standby pumps with their runtime.
+func (s *SimpleDriver) handlePumps(w http.ResponseWriter, r *http.Request) {
+	pumpsMutex.Lock()
+	defer pumpsMutex.Unlock()
+	list := []map[string]interface{}{}
+	for _, p := range []gpio.GPIO{pumps.primary, pumps.standby} {
+		if p.Name == "" {
+			continue
+		}
+		list = append(list, map[string]interface{}{
+			"name":         p.Name,
+			"runtimeHours": lineRuntime(p.Name).Hours(),
+			"active":       p.Name == pumps.active,
+			"failed":       failedPumps[p.Name],
+		})
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"pumps": list, "pinned": pumps.pinned})
+}
+
+// handlePumpPin pins the pump used by the next cycles, an empty name restores wear-leveling. Pinning a
+// failed pump acknowledges its failure.
+func (s *SimpleDriver) handlePumpPin(w http.ResponseWriter, r *http.Request) {
+	var req pinRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	pumpsMutex.Lock()
+	defer pumpsMutex.Unlock()
+	if pumps.standby.Name == "" {
+		writeError(w, http.StatusConflict, errors.New("no standby pump configured, set STANDBY_TRIGGER"))
+		return
+	}
+	if req.Name != "" && req.Name != pumps.primary.Name && req.Name != pumps.standby.Name {
+		writeError(w, http.StatusNotFound, fmt.Errorf("%s is not one of the pumps", req.Name))
+		return
+	}
+	pumps.pinned = req.Name
+	clearPumpFailure(req.Name)
+	detail := "wear-leveling restored"
+	if req.Name != "" {
+		detail = "pinned"
+	}
+	audit("pump-pin", triggerLine("START_TRIGGER"), fmt.Sprintf("%s %s", req.Name, detail))
+	writeJSON(w, http.StatusOK, map[string]interface{}{"pinned": pumps.pinned})
+}
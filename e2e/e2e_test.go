@@ -0,0 +1,251 @@
+//go:build e2e
+
+// Package e2e verifies the full path through a running deployment of examples/e2e/docker-compose.yml:
+// a command sent to core-command drives the output line, the device service publishes the change to
+// core-data, and an edge on the input line reaches core-data as an InputEvent reading. It serves as
+// the acceptance test of a deployment and as a reference of the APIs involved. Run it with the e2e
+// build tag, its flags after -args:
+//
+//	go test -tags e2e -count=1 ./e2e -args -device device-gpiod
+//
+// With the simulated backend the output is read back through core-command and the input is changed
+// through the simulator route of the service. With -gpio-sim, the sysfs directory of a gpio-sim chip
+// printed by gpio-sim.sh, the output level is read and the input pulled on the simulated chip itself.
+package e2e
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var (
+	commandURL  = flag.String("command", "http://localhost:59882", "core-command URL")
+	dataURL     = flag.String("data", "http://localhost:59880", "core-data URL")
+	metadataURL = flag.String("metadata", "http://localhost:59881", "core-metadata URL")
+	serviceURL  = flag.String("service", "http://localhost:60000", "device service URL")
+	device      = flag.String("device", "device-gpiod", "device of the service")
+	output      = flag.String("output", "light", "output line driven through core-command")
+	input       = flag.String("input", "button", "input line raising edge readings")
+	outputLine  = flag.Int("output-line", 0, "offset of the output line on the gpio-sim chip")
+	inputLine   = flag.Int("input-line", 1, "offset of the input line on the gpio-sim chip")
+	gpioSim     = flag.String("gpio-sim", "", "sysfs directory of the gpio-sim chip, empty with the simulated backend")
+	wait        = flag.Duration("wait", 2*time.Minute, "time waited for the services and for each check")
+)
+
+var client = &http.Client{Timeout: 10 * time.Second}
+
+// reading is the part of a core-data reading checked by the harness.
+type reading struct {
+	ResourceName string `json:"resourceName"`
+	Value        string `json:"value"`
+	Origin       int64  `json:"origin"`
+}
+
+// TestDeployment runs the checks in order, stopping at the first failed one.
+func TestDeployment(t *testing.T) {
+	steps := []struct {
+		name string
+		run  func() error
+	}{
+		{"services are up", waitForServices},
+		{"device is provisioned", waitForDevice},
+		{"output driven on", func() error { return checkOutput(true) }},
+		{"output driven off", func() error { return checkOutput(false) }},
+		{"input edge reaches core-data", checkInput},
+	}
+	for _, step := range steps {
+		start := time.Now()
+		if err := step.run(); err != nil {
+			t.Fatalf("%s: %s", step.name, err)
+		}
+		t.Logf("%s (%s)", step.name, time.Since(start).Round(time.Millisecond))
+	}
+}
+
+func waitForServices() error {
+	for _, url := range []string{*metadataURL, *dataURL, *commandURL, *serviceURL} {
+		err := eventually(func() error { return call(http.MethodGet, url+"/api/v2/ping", nil, nil) })
+		if err != nil {
+			return fmt.Errorf("%s: %s", url, err)
+		}
+	}
+	return nil
+}
+
+func waitForDevice() error {
+	return eventually(func() error {
+		return call(http.MethodGet, fmt.Sprintf("%s/api/v2/device/name/%s", *metadataURL, *device), nil, nil)
+	})
+}
+
+// checkOutput drives the output through core-command and checks the level of the line and the reading
+// published to core-data.
+func checkOutput(on bool) error {
+	since := time.Now().UnixNano()
+	url := fmt.Sprintf("%s/api/v2/device/name/%s/%s", *commandURL, *device, *output)
+	if err := call(http.MethodPut, url, map[string]string{*output: fmt.Sprint(on)}, nil); err != nil {
+		return fmt.Errorf("command: %s", err)
+	}
+	err := eventually(func() error {
+		level, err := outputLevel()
+		if err != nil {
+			return err
+		}
+		if level != on {
+			return fmt.Errorf("line is %t", level)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("actuation: %s", err)
+	}
+	return eventually(func() error {
+		return findReading(since, "GPIO", func(value string) bool {
+			return lineState(value, *output) == fmt.Sprint(on)
+		})
+	})
+}
+
+// outputLevel reads the level of the output, on the gpio-sim chip or through core-command.
+func outputLevel() (bool, error) {
+	if *gpioSim != "" {
+		data, err := os.ReadFile(filepath.Join(*gpioSim, fmt.Sprintf("sim_gpio%d", *outputLine), "value"))
+		if err != nil {
+			return false, err
+		}
+		return strings.TrimSpace(string(data)) == "1", nil
+	}
+	var response struct {
+		Event struct {
+			Readings []reading `json:"readings"`
+		} `json:"event"`
+	}
+	url := fmt.Sprintf("%s/api/v2/device/name/%s/%s", *commandURL, *device, *output)
+	if err := call(http.MethodGet, url, nil, &response); err != nil {
+		return false, err
+	}
+	for _, r := range response.Event.Readings {
+		if r.ResourceName == *output {
+			return r.Value == "true", nil
+		}
+	}
+	return false, fmt.Errorf("no reading of %s", *output)
+}
+
+// checkInput raises a rising edge on the input and waits for its reading in core-data.
+func checkInput() error {
+	since := time.Now().UnixNano()
+	var err error
+	if *gpioSim != "" {
+		pull := filepath.Join(*gpioSim, fmt.Sprintf("sim_gpio%d", *inputLine), "pull")
+		if err = os.WriteFile(pull, []byte("pull-down"), 0); err == nil {
+			time.Sleep(100 * time.Millisecond)
+			err = os.WriteFile(pull, []byte("pull-up"), 0)
+		}
+	} else {
+		err = call(http.MethodPost, *serviceURL+"/api/v2/simulator/edge", map[string]interface{}{"name": *input, "value": 1}, nil)
+	}
+	if err != nil {
+		return fmt.Errorf("edge: %s", err)
+	}
+	return eventually(func() error {
+		return findReading(since, "InputEvent", func(value string) bool {
+			return strings.Contains(value, fmt.Sprintf("%q", *input))
+		})
+	})
+}
+
+// findReading looks for a reading of resource published after since whose value matches.
+func findReading(since int64, resource string, match func(value string) bool) error {
+	var response struct {
+		Readings []reading `json:"readings"`
+	}
+	url := fmt.Sprintf("%s/api/v2/reading/device/name/%s?limit=100", *dataURL, *device)
+	if err := call(http.MethodGet, url, nil, &response); err != nil {
+		return err
+	}
+	for _, r := range response.Readings {
+		if r.ResourceName == resource && r.Origin >= since && match(r.Value) {
+			return nil
+		}
+	}
+	return fmt.Errorf("no matching %s reading in core-data", resource)
+}
+
+// lineState returns the state of the named line in a GPIO reading, in the legacy or the structured
+// payload, empty when the reading is of another line.
+func lineState(value string, name string) string {
+	var payload struct {
+		Gpio struct {
+			Name  string
+			State bool
+		} `json:"gpio"`
+		Line struct {
+			Name  string `json:"name"`
+			State bool   `json:"state"`
+		} `json:"line"`
+	}
+	if err := json.Unmarshal([]byte(value), &payload); err != nil {
+		return ""
+	}
+	switch name {
+	case payload.Gpio.Name:
+		return fmt.Sprint(payload.Gpio.State)
+	case payload.Line.Name:
+		return fmt.Sprint(payload.Line.State)
+	}
+	return ""
+}
+
+// eventually retries check every second until it succeeds or the wait expires.
+func eventually(check func() error) error {
+	deadline := time.Now().Add(*wait)
+	for {
+		err := check()
+		if err == nil || time.Now().After(deadline) {
+			return err
+		}
+		time.Sleep(time.Second)
+	}
+}
+
+func call(method string, url string, body interface{}, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, url, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("%s %s: %s %s", method, url, resp.Status, strings.TrimSpace(string(data)))
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(data, out)
+}
@@ -0,0 +1,28 @@
+#!/bin/sh
+# Creates a chip of the kernel gpio-sim module (Linux 5.17+, CONFIG_GPIO_SIM) with the two lines of the
+# harness, and writes gpio-sim.yaml for it. Run as root on the host; ./gpio-sim.sh remove deletes it.
+set -e
+
+CONFIGFS=/sys/kernel/config/gpio-sim
+SIM=$CONFIGFS/device-gpiod-e2e
+DIR=$(dirname "$0")
+
+if [ "$1" = "remove" ]; then
+	[ -d "$SIM" ] || exit 0
+	echo 0 > "$SIM/live"
+	rmdir "$SIM/bank0/line0" "$SIM/bank0/line1" "$SIM/bank0" "$SIM"
+	exit 0
+fi
+
+modprobe gpio-sim
+mountpoint -q /sys/kernel/config || mount -t configfs none /sys/kernel/config
+mkdir -p "$SIM/bank0/line0" "$SIM/bank0/line1"
+echo 2 > "$SIM/bank0/num_lines"
+echo e2e-light > "$SIM/bank0/line0/name"
+echo e2e-button > "$SIM/bank0/line1/name"
+echo 1 > "$SIM/live"
+
+CHIP=$(cat "$SIM/bank0/chip_name")
+sed "s/gpiochip0/$CHIP/" "$DIR/gpio.yaml" > "$DIR/gpio-sim.yaml"
+echo "GPIO_SIM_CHIP=$CHIP"
+echo "GPIO_SIM_SYSFS=/sys/devices/platform/$(cat "$SIM/dev_name")/$CHIP"
@@ -0,0 +1,116 @@
+package gpio
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+const (
+	confinementNone      = "none"
+	confinementDocker    = "container"
+	confinementSnap      = "snap"
+	gpioControlInterface = "gpio-control"
+)
+
+// Confinement detects whether the process runs inside a container or a snap.
+func Confinement() string {
+	if os.Getenv("SNAP") != "" {
+		return confinementSnap
+	}
+	for _, marker := range []string{"/.dockerenv", "/run/.containerenv"} {
+		if _, err := os.Stat(marker); err == nil {
+			return confinementDocker
+		}
+	}
+	if cgroup, err := os.ReadFile("/proc/1/cgroup"); err == nil {
+		for _, runtime := range []string{"docker", "kubepods", "containerd", "libpod"} {
+			if strings.Contains(string(cgroup), runtime) {
+				return confinementDocker
+			}
+		}
+	}
+	return confinementNone
+}
+
+// chipPath resolves a chip name as used in the configuration ("gpiochip0" or "/dev/gpiochip0").
+func chipPath(chip string) string {
+	if filepath.IsAbs(chip) {
+		return chip
+	}
+	return filepath.Join("/dev", chip)
+}
+
+// CheckAccess verifies that the given chips can be opened and returns one remediation hint for each
+// problem found, tailored to the detected confinement.
+func CheckAccess(chips []string) []string {
+	var hints []string
+	confinement := Confinement()
+	checked := make(map[string]bool)
+	for _, chip := range chips {
+		path := chipPath(chip)
+		if e := expanderFor(chip); e != nil {
+			path = e.Bus
+		}
+		if chip == "" || checked[path] {
+			continue
+		}
+		checked[path] = true
+
+		info, err := os.Stat(path)
+		if err != nil {
+			hints = append(hints, missingChipHint(path, confinement))
+			continue
+		}
+		f, err := os.OpenFile(path, os.O_RDWR, 0)
+		if err == nil {
+			f.Close()
+			continue
+		}
+		if errors.Is(err, os.ErrPermission) {
+			hints = append(hints, permissionHint(path, info, confinement))
+			continue
+		}
+		hints = append(hints, fmt.Sprintf("%s cannot be opened: %s", path, err))
+	}
+	return hints
+}
+
+func missingChipHint(path string, confinement string) string {
+	switch confinement {
+	case confinementDocker:
+		return fmt.Sprintf("%s is not available in the container: pass it with '--device %s' "+
+			"(compose: devices: [\"%s:%s\"]) or allow all chips with \"--device-cgroup-rule='c 254:* rmw'\" and a /dev bind mount",
+			path, path, path, path)
+	case confinementSnap:
+		return fmt.Sprintf("%s is not visible to the snap: connect the interface with 'snap connect %s:%s'",
+			path, os.Getenv("SNAP_NAME"), gpioControlInterface)
+	}
+	return fmt.Sprintf("%s does not exist: check the chip name and that the GPIO driver is loaded (ls /dev/gpiochip*)", path)
+}
+
+func permissionHint(path string, info os.FileInfo, confinement string) string {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return fmt.Sprintf("%s: permission denied", path)
+	}
+	gid := int(st.Gid)
+	groups, _ := os.Getgroups()
+	for _, g := range groups {
+		if g == gid {
+			return fmt.Sprintf("%s: permission denied although the process is in group %d: check the mode %s", path, gid, info.Mode())
+		}
+	}
+	switch confinement {
+	case confinementDocker:
+		return fmt.Sprintf("%s: permission denied, run the container with '--group-add %d' (group owning the chip)", path, gid)
+	case confinementSnap:
+		return fmt.Sprintf("%s: permission denied, connect the interface with 'snap connect %s:%s'",
+			path, os.Getenv("SNAP_NAME"), gpioControlInterface)
+	}
+	return fmt.Sprintf("%s: permission denied, add the service user (uid %d) to the group %d owning the chip or install a udev rule",
+		path, os.Getuid(), gid)
+}
@@ -0,0 +1,239 @@
+package gpio
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"syscall"
+
+	"github.com/warthog618/gpiod"
+)
+
+// Line is a line requested from a backend. Close releases it.
+type Line interface {
+	Value() (int, error)
+	SetValue(value int) error
+	Close() error
+}
+
+// Backend requests the lines. The gpiod backend drives the gpiochip character devices of the board, the
+// simulator keeps the lines in memory so the service runs and is tested without any gpiochip. The lines
+// of the expanders registered by SetExpanders are requested from them instead.
+type Backend interface {
+	// RequestInput requests the line as an input.
+	RequestInput(gpio *GPIO) (Line, error)
+	// RequestOutput requests the line as an output driven to value.
+	RequestOutput(gpio *GPIO, value int) (Line, error)
+	// RequestOutputs requests lines of one chip together as outputs driven to values, one per line.
+	RequestOutputs(gpios []*GPIO, values []int) (Lines, error)
+	// RequestAsIs requests the line keeping its direction and level, to read back an actuation.
+	RequestAsIs(gpio *GPIO) (Line, error)
+	// WatchEvents requests the line as an input calling handler on the edges configured for it.
+	WatchEvents(gpio *GPIO, handler func(gpiod.LineEvent)) (Line, error)
+	// OwnsOutput tells whether the line is still requested as an output with the label of the line.
+	OwnsOutput(gpio *GPIO) (bool, error)
+	// Enumerate lists the lines the backend knows about.
+	Enumerate() []LineDescriptor
+}
+
+// ErrNotRequested is returned by the handles of a released line.
+var ErrNotRequested = errors.New("line not requested")
+
+var (
+	backendMutex sync.RWMutex
+	baseBackend  Backend = gpiodBackend{}
+	backend      Backend = faultBackend{gpiodBackend{}}
+)
+
+// SetBackend replaces the backend of the lines requested from now on. Lines already requested stay with
+// the previous backend, so it is meant to be called before any line is set up. The fault profile applies
+// to any backend.
+func SetBackend(b Backend) {
+	backendMutex.Lock()
+	defer backendMutex.Unlock()
+	baseBackend = b
+	backend = wrapBackend()
+}
+
+// wrapBackend puts the expanders and the fault profile in front of the base backend. Called with the
+// backend mutex held.
+func wrapBackend() Backend {
+	if _, simulated := baseBackend.(*Simulator); simulated || len(expanders) == 0 {
+		return faultBackend{baseBackend}
+	}
+	return faultBackend{expanderBackend{Backend: baseBackend, chips: expanders}}
+}
+
+func currentBackend() Backend {
+	backendMutex.RLock()
+	defer backendMutex.RUnlock()
+	return backend
+}
+
+// ActiveSimulator returns the simulator when it is the backend in use, nil otherwise.
+func ActiveSimulator() *Simulator {
+	backendMutex.RLock()
+	defer backendMutex.RUnlock()
+	sim, _ := baseBackend.(*Simulator)
+	return sim
+}
+
+// Enumerate lists every line known to the backend, for the gpiod backend every line of every gpiochip
+// of the system.
+func Enumerate() []LineDescriptor {
+	return currentBackend().Enumerate()
+}
+
+// label is the consumer label the line is requested with.
+func (gpio *GPIO) label() string {
+	if gpio.Consumer != "" {
+		return gpio.Consumer
+	}
+	return consumer
+}
+
+// gpiodBackend requests the lines from the kernel through the gpiod character device API.
+type gpiodBackend struct{}
+
+// lineOrNil keeps a failed request from returning a non-nil Line wrapping a nil *gpiod.Line.
+func lineOrNil(line *gpiod.Line, err error) (Line, error) {
+	if err != nil {
+		return nil, err
+	}
+	return line, nil
+}
+
+func (gpiodBackend) RequestInput(gpio *GPIO) (Line, error) {
+	return lineOrNil(gpiod.RequestLine(gpio.Chip, gpio.Line, append(gpio.requestOptions(true), gpiod.AsInput)...))
+}
+
+func (gpiodBackend) RequestOutput(gpio *GPIO, value int) (Line, error) {
+	return lineOrNil(gpiod.RequestLine(gpio.Chip, gpio.Line, append(gpio.requestOptions(false), gpiod.AsOutput(value))...))
+}
+
+func (gpiodBackend) RequestOutputs(gpios []*GPIO, values []int) (Lines, error) {
+	offsets := make([]int, len(gpios))
+	for i, gpio := range gpios {
+		offsets[i] = gpio.Line
+	}
+	lines, err := gpiod.RequestLines(gpios[0].Chip, offsets, append(gpios[0].requestOptions(false), gpiod.AsOutput(values...))...)
+	if err != nil {
+		return nil, err
+	}
+	return lines, nil
+}
+
+func (gpiodBackend) RequestAsIs(gpio *GPIO) (Line, error) {
+	options := []gpiod.LineReqOption{gpiod.WithConsumer(consumer)}
+	if gpio.ActiveLow != nil && *gpio.ActiveLow {
+		options = append(options, gpiod.AsActiveLow)
+	}
+	return lineOrNil(gpiod.RequestLine(gpio.Chip, gpio.Line, options...))
+}
+
+func (gpiodBackend) WatchEvents(gpio *GPIO, handler func(gpiod.LineEvent)) (Line, error) {
+	options := append(gpio.requestOptions(true), gpiod.AsInput, gpio.edgeOption(), gpiod.WithEventHandler(placeEventHandler(gpio.Name, handler)))
+	return lineOrNil(gpiod.RequestLine(gpio.Chip, gpio.Line, options...))
+}
+
+func (gpiodBackend) OwnsOutput(gpio *GPIO) (bool, error) {
+	chip, err := gpiod.NewChip(gpio.Chip, gpiod.WithConsumer(consumer))
+	if err != nil {
+		return false, err
+	}
+	defer chip.Close()
+	info, err := chip.LineInfo(gpio.Line)
+	if err != nil {
+		return false, err
+	}
+	return info.Used && info.Consumer == gpio.label() && info.Config.Direction == gpiod.LineDirectionOutput, nil
+}
+
+// Enumerate lists every line of every gpiochip of the system. Chips that cannot be opened are skipped.
+func (gpiodBackend) Enumerate() []LineDescriptor {
+	var lines []LineDescriptor
+	for _, chipName := range gpiod.Chips() {
+		chip, err := gpiod.NewChip(chipName, gpiod.WithConsumer(consumer))
+		if err != nil {
+			logger.Printf("Cannot open chip %s to enumerate its lines. Error: %s", chipName, err)
+			continue
+		}
+		for offset := 0; offset < chip.Lines(); offset++ {
+			info, err := chip.LineInfo(offset)
+			if err != nil {
+				logger.Printf("Cannot read info of line %d from chip %s. Error: %s", offset, chipName, err)
+				continue
+			}
+			lines = append(lines, LineDescriptor{
+				Chip:      chipName,
+				ChipLabel: chip.Label,
+				Line:      offset,
+				Name:      info.Name,
+				Consumer:  info.Consumer,
+				Used:      info.Used,
+				Output:    info.Config.Direction == gpiod.LineDirectionOutput,
+			})
+		}
+		chip.Close()
+	}
+	return lines
+}
+
+// faultBackend fails the requests of the wrapped backend with EBUSY as configured by the fault profile,
+// and reports the failed requests to the line error hook. Write errors are injected by the callers
+// driving the line.
+type faultBackend struct {
+	Backend
+}
+
+func requestFault() error {
+	if injectFault(func(p *FaultProfile) float64 { return p.RequestBusy }) {
+		return fmt.Errorf("injected request failure: %w", syscall.EBUSY)
+	}
+	return nil
+}
+
+func (b faultBackend) RequestInput(gpio *GPIO) (Line, error) {
+	if err := requestFault(); err != nil {
+		return nil, lineFailed(gpio.Name, LineOpRequest, err)
+	}
+	line, err := b.Backend.RequestInput(gpio)
+	return line, lineFailed(gpio.Name, LineOpRequest, err)
+}
+
+func (b faultBackend) RequestOutput(gpio *GPIO, value int) (Line, error) {
+	if err := requestFault(); err != nil {
+		return nil, lineFailed(gpio.Name, LineOpRequest, err)
+	}
+	line, err := b.Backend.RequestOutput(gpio, value)
+	return line, lineFailed(gpio.Name, LineOpRequest, err)
+}
+
+func (b faultBackend) RequestOutputs(gpios []*GPIO, values []int) (Lines, error) {
+	lines, err := Lines(nil), requestFault()
+	if err == nil {
+		lines, err = b.Backend.RequestOutputs(gpios, values)
+	}
+	if err != nil {
+		for _, gpio := range gpios {
+			lineFailed(gpio.Name, LineOpRequest, err)
+		}
+	}
+	return lines, err
+}
+
+func (b faultBackend) RequestAsIs(gpio *GPIO) (Line, error) {
+	if err := requestFault(); err != nil {
+		return nil, lineFailed(gpio.Name, LineOpRequest, err)
+	}
+	line, err := b.Backend.RequestAsIs(gpio)
+	return line, lineFailed(gpio.Name, LineOpRequest, err)
+}
+
+func (b faultBackend) WatchEvents(gpio *GPIO, handler func(gpiod.LineEvent)) (Line, error) {
+	if err := requestFault(); err != nil {
+		return nil, lineFailed(gpio.Name, LineOpRequest, err)
+	}
+	line, err := b.Backend.WatchEvents(gpio, handler)
+	return line, lineFailed(gpio.Name, LineOpRequest, err)
+}
@@ -0,0 +1,86 @@
+package gpio
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// ChipIdentity returns the inode of the device node of the chip, which changes when the node is
+// created again by a driver reload, and false while the node does not exist.
+func ChipIdentity(chip string) (uint64, bool) {
+	info, err := os.Stat(chipPath(chip))
+	if err != nil {
+		return 0, false
+	}
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, true
+	}
+	return stat.Ino, true
+}
+
+// Watched reports whether the line is requested by Watch.
+func (gpio *GPIO) Watched() bool {
+	return gpio.watchHandler != nil
+}
+
+// Rewatch requests a watched line again with its handler, after its chip was reloaded. The previous
+// handle is closed, its error ignored: it points to a device that is gone.
+func (gpio *GPIO) Rewatch() error {
+	handler := gpio.watchHandler
+	if handler == nil {
+		return errors.New("line is not watched")
+	}
+	if gpio.gpioLine != nil {
+		gpio.gpioLine.Close()
+		gpio.gpioLine = nil
+	}
+	return gpio.Watch(handler)
+}
+
+// RecoverHeld requests again the held output lines of a reloaded chip, at the value given for them
+// in values, or else the last value set by the service. It returns the names of the lines recovered
+// and of the ones that could not be, which are no longer held and are requested again on their next
+// write.
+func RecoverHeld(chip string, values map[string]int) ([]string, []string) {
+	yieldMutex.Lock()
+	last := make(map[lineKey]int, len(lastValue))
+	for key, value := range lastValue {
+		last[key] = value
+	}
+	yieldMutex.Unlock()
+
+	heldMutex.Lock()
+	var recovered, failed []string
+	restored := make(map[lineKey]int)
+	for key, h := range held {
+		if key.chip != chip {
+			continue
+		}
+		h.line.Close()
+		value, ok := values[h.settings.Name]
+		if !ok {
+			value = last[key]
+		}
+		line, err := currentBackend().RequestOutput(&h.settings, value)
+		if err != nil {
+			SampledLogf(h.settings.sampleKey("re-acquire"), "Cannot re-acquire held resource %d from chip %s. Error: %s", key.line, key.chip, err)
+			delete(held, key)
+			failed = append(failed, h.settings.Name)
+			continue
+		}
+		Recovered(h.settings.sampleKey("re-acquire"))
+		h.line = line
+		restored[key] = value
+		recovered = append(recovered, h.settings.Name)
+	}
+	heldMutex.Unlock()
+
+	yieldMutex.Lock()
+	defer yieldMutex.Unlock()
+	for key, value := range restored {
+		lastValue[key] = value
+	}
+	return recovered, failed
+}
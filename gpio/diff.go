@@ -0,0 +1,66 @@
+package gpio
+
+import (
+	"reflect"
+)
+
+// ListDiff is the line by line difference between two GPIO lists, keyed by line name.
+type ListDiff struct {
+	Added     []string `json:"added"`
+	Removed   []string `json:"removed"`
+	Changed   []string `json:"changed"`
+	Unchanged []string `json:"unchanged"`
+}
+
+// settings returns a copy of the line without its requested handles, for comparison.
+func (gpio GPIO) settings() GPIO {
+	gpio.gpioLine = nil
+	gpio.gpioSensorLine = nil
+	gpio.watchHandler = nil
+	return gpio
+}
+
+// Diff compares the configured lines of previous and next.
+func Diff(previous *GPIOList, next *GPIOList) ListDiff {
+	diff := ListDiff{}
+	old := make(map[string]GPIO)
+	for _, line := range previous.Gpio {
+		old[line.Name] = line
+	}
+	for _, line := range next.Gpio {
+		prev, ok := old[line.Name]
+		switch {
+		case !ok:
+			diff.Added = append(diff.Added, line.Name)
+		case reflect.DeepEqual(prev.settings(), line.settings()):
+			diff.Unchanged = append(diff.Unchanged, line.Name)
+		default:
+			diff.Changed = append(diff.Changed, line.Name)
+		}
+		delete(old, line.Name)
+	}
+	for _, line := range previous.Gpio {
+		if _, ok := old[line.Name]; ok {
+			diff.Removed = append(diff.Removed, line.Name)
+		}
+	}
+	return diff
+}
+
+// Adopt takes over the lines held by previous, so an unchanged line stays requested across a reload.
+func (gpio *GPIO) Adopt(previous *GPIO) {
+	gpio.gpioLine = previous.gpioLine
+	gpio.gpioSensorLine = previous.gpioSensorLine
+	gpio.watchHandler = previous.watchHandler
+}
+
+// Unwatch releases a line requested by Watch.
+func (gpio *GPIO) Unwatch() error {
+	if gpio.gpioLine == nil {
+		return nil
+	}
+	err := gpio.gpioLine.Close()
+	gpio.gpioLine = nil
+	gpio.watchHandler = nil
+	return err
+}
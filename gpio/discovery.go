@@ -0,0 +1,56 @@
+package gpio
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// LineDescriptor is a line found on a gpiochip of the board.
+type LineDescriptor struct {
+	Chip      string `json:"chip"`
+	ChipLabel string `json:"chipLabel"`
+	Line      int    `json:"line"`
+	Name      string `json:"name"`
+	Consumer  string `json:"consumer"`
+	Used      bool   `json:"used"`
+	Output    bool   `json:"output"`
+}
+
+// ChipParent returns the sysfs path of the device providing the chip, relative to /sys/devices, e.g.
+// platform/soc/fe804000.i2c/i2c-1/1-0020 for an I2C expander, empty when unknown.
+func ChipParent(chip string) string {
+	path, err := filepath.EvalSymlinks(filepath.Join("/sys/bus/gpio/devices", filepath.Base(chip)))
+	if err != nil {
+		return ""
+	}
+	parent, err := filepath.Rel("/sys/devices", filepath.Dir(path))
+	if err != nil {
+		return ""
+	}
+	return parent
+}
+
+// ChipBus returns the bus of the device providing the chip, i2c, spi or usb for expanders, platform
+// for the GPIO controller of the SoC, empty when unknown.
+func ChipBus(parent string) string {
+	for _, part := range strings.Split(parent, "/") {
+		switch {
+		case strings.HasPrefix(part, "i2c-"):
+			return "i2c"
+		case strings.HasPrefix(part, "spi"):
+			return "spi"
+		case strings.HasPrefix(part, "usb"):
+			return "usb"
+		}
+	}
+	if strings.HasPrefix(parent, "platform/") {
+		return "platform"
+	}
+	return ""
+}
+
+// Chips returns the device nodes of the chips present, e.g. /dev/gpiochip0.
+func Chips() []string {
+	chips, _ := filepath.Glob("/dev/gpiochip*")
+	return chips
+}
@@ -0,0 +1,33 @@
+// Package gpio drives the GPIO lines of a Linux board through the gpiochip character devices, with
+// the gpiod library. It has no dependency on the device service or EdgeX and is imported on its own
+// as github.com/edgexfoundry/device-gpiod/gpio.
+//
+// A line is a GPIO, configured in YAML and loaded by GPIOList.Parse or Load, or built in code and
+// checked by Validate. Outputs are driven by Up, Down and SetDuty (software PWM), inputs read by
+// Value or watched for edges by Watch, and every line is released by Release. Lines of one chip are
+// driven together as a group by GPIOList.SetGroup.
+//
+// The lines are requested from a Backend: the gpiochips of the board by default, or a Simulator set
+// by SetBackend to run without hardware. I2C and SPI expanders are gpiochips of their kernel driver
+// and are used like any other chip; PCF8574/PCF8575 expanders without one are driven from user space
+// once listed in the expanders section (SetExpanders).
+//
+// The package logs to the standard logger, replaced by SetLogger. The hooks (OnActuation, OnIntent,
+// OnEdge, OnLineError, OnRepeatedError) let the caller follow the lines without wrapping them.
+//
+// A minimal blinker:
+//
+//	led := gpio.GPIO{Name: "led", Chip: "gpiochip0", Line: 17, Direction: "output"}
+//	if err := led.Validate(); err != nil {
+//		log.Fatal(err)
+//	}
+//	defer led.Release()
+//	for i := 0; i < 10; i++ {
+//		led.Up()
+//		time.Sleep(500 * time.Millisecond)
+//		led.Down()
+//		time.Sleep(500 * time.Millisecond)
+//	}
+//
+// More examples are in the examples directory.
+package gpio
@@ -0,0 +1,89 @@
+package gpio
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"syscall"
+	"unsafe"
+
+	"github.com/warthog618/gpiod"
+)
+
+const schedFifo = 1
+
+// EventThread is the placement of the OS threads handling the edge events of the watched lines, to keep
+// the event latency stable on a busy gateway. CPUs pins them to those CPUs, Nice sets their nice
+// value and RTPriority, when above 0, runs them with SCHED_FIFO at that priority. The zero value
+// leaves them as scheduled by the runtime.
+type EventThread struct {
+	CPUs       []int
+	Nice       int
+	RTPriority int
+}
+
+var (
+	eventThreadMutex sync.Mutex
+	eventThread      EventThread
+)
+
+// SetEventThread sets the placement of the event threads of the lines watched from now on.
+func SetEventThread(t EventThread) {
+	eventThreadMutex.Lock()
+	defer eventThreadMutex.Unlock()
+	eventThread = t
+}
+
+func (t EventThread) placed() bool {
+	return len(t.CPUs) > 0 || t.Nice != 0 || t.RTPriority > 0
+}
+
+// placeEventHandler wraps the handler of a watched line so the first event locks the goroutine of the
+// gpiod watcher to its OS thread and places that thread. The watcher goroutine ends with the request,
+// taking its thread with it.
+func placeEventHandler(name string, handler func(gpiod.LineEvent)) func(gpiod.LineEvent) {
+	eventThreadMutex.Lock()
+	t := eventThread
+	eventThreadMutex.Unlock()
+	if !t.placed() {
+		return handler
+	}
+	var once sync.Once
+	return func(evt gpiod.LineEvent) {
+		once.Do(func() {
+			runtime.LockOSThread()
+			if err := t.apply(); err != nil {
+				logger.Printf("Cannot place the event thread of %s. Error: %s", name, err)
+			}
+		})
+		handler(evt)
+	}
+}
+
+// apply places the calling thread.
+func (t EventThread) apply() error {
+	if len(t.CPUs) > 0 {
+		var mask [16]uint64
+		for _, cpu := range t.CPUs {
+			if cpu < 0 || cpu >= len(mask)*64 {
+				return fmt.Errorf("invalid cpu %d", cpu)
+			}
+			mask[cpu/64] |= 1 << (uint(cpu) % 64)
+		}
+		if _, _, errno := syscall.RawSyscall(syscall.SYS_SCHED_SETAFFINITY, 0, unsafe.Sizeof(mask), uintptr(unsafe.Pointer(&mask))); errno != 0 {
+			return fmt.Errorf("sched_setaffinity: %w", errno)
+		}
+	}
+	if t.Nice != 0 {
+		if err := syscall.Setpriority(syscall.PRIO_PROCESS, syscall.Gettid(), t.Nice); err != nil {
+			return fmt.Errorf("setpriority: %w", err)
+		}
+	}
+	if t.RTPriority > 0 {
+		param := struct{ priority int32 }{int32(t.RTPriority)}
+		if _, _, errno := syscall.RawSyscall(syscall.SYS_SCHED_SETSCHEDULER, 0, schedFifo, uintptr(unsafe.Pointer(&param))); errno != 0 {
+			return fmt.Errorf("sched_setscheduler: %w", errno)
+		}
+	}
+	return nil
+}
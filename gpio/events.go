@@ -0,0 +1,76 @@
+package gpio
+
+import (
+	"errors"
+	"time"
+
+	"github.com/warthog618/gpiod"
+)
+
+// Event is an edge detected on a watched input line.
+type Event struct {
+	Name      string        `json:"name"`
+	Chip      string        `json:"chip"`
+	Line      int           `json:"line"`
+	Value     int           `json:"value"`
+	Timestamp time.Duration `json:"timestamp"`
+	Seqno     uint32        `json:"seqno"`
+}
+
+// Watch requests the line as input with edge detection on the configured edges (both by default) and
+// calls handler for every event. With soft_debounce, the edges following an accepted one within the
+// debounce period are dropped, for chips without hardware debounce. The line stays requested until
+// Release is called.
+func (gpio *GPIO) Watch(handler func(Event)) error {
+	if gpio.Yielded() {
+		return ErrYielded
+	}
+	if handler == nil {
+		return errors.New("missing event handler")
+	}
+	name, chip, line := gpio.Name, gpio.Chip, gpio.Line
+	softDebounce, _ := time.ParseDuration(gpio.SoftDebounce)
+	var last time.Duration
+	accepted := false
+	eventHandler := func(evt gpiod.LineEvent) {
+		if softDebounce > 0 && accepted && evt.Timestamp-last < softDebounce {
+			return
+		}
+		last, accepted = evt.Timestamp, true
+		value := 0
+		if evt.Type == gpiod.LineEventRisingEdge {
+			value = 1
+		}
+		if delay := eventDelay(); delay > 0 {
+			time.Sleep(delay)
+		}
+		debugf("Edge %d on %s (line %d of %s)", value, name, line, chip)
+		if edgeHook != nil {
+			edgeHook(name, value)
+		}
+		handler(Event{
+			Name:      name,
+			Chip:      chip,
+			Line:      line,
+			Value:     value,
+			Timestamp: evt.Timestamp,
+			Seqno:     evt.Seqno,
+		})
+	}
+	var err error
+	gpio.gpioLine, err = currentBackend().WatchEvents(gpio, eventHandler)
+	if err != nil {
+		logger.Printf("Error watching resource %d from chip %s. Error: %s", gpio.Line, gpio.Chip, err)
+		return err
+	}
+	gpio.watchHandler = handler
+	return nil
+}
+
+// Value reads the current value of a watched line without releasing it.
+func (gpio *GPIO) Value() (int, error) {
+	if gpio.gpioLine == nil {
+		return -1, errors.New("resource is not available")
+	}
+	return gpio.gpioLine.Value()
+}
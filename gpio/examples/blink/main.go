@@ -0,0 +1,45 @@
+// Blink drives an output line on and off, using the gpio package without the device service.
+//
+//	go run ./gpio/examples/blink -chip gpiochip0 -line 17 -count 10
+//
+// With -simulate the line is driven in memory, on any machine.
+package main
+
+import (
+	"flag"
+	"log"
+	"time"
+
+	"github.com/edgexfoundry/device-gpiod/gpio"
+)
+
+func main() {
+	chip := flag.String("chip", "gpiochip0", "gpiochip of the line")
+	line := flag.Int("line", 17, "offset of the line")
+	count := flag.Int("count", 10, "number of blinks")
+	period := flag.Duration("period", time.Second, "blink period")
+	simulate := flag.Bool("simulate", false, "drive a simulated line")
+	flag.Parse()
+
+	if *simulate {
+		gpio.SetBackend(gpio.NewSimulator())
+	}
+	gpio.SetConsumer("blink")
+
+	led := gpio.GPIO{Name: "led", Chip: *chip, Line: *line, Direction: gpio.DirectionOutput}
+	if err := led.Validate(); err != nil {
+		log.Fatal(err)
+	}
+	defer led.Release()
+
+	for i := 0; i < *count; i++ {
+		if err := led.Up(); err != nil {
+			log.Fatalf("Cannot drive %s on. Error: %s", led.Name, err)
+		}
+		time.Sleep(*period / 2)
+		if err := led.Down(); err != nil {
+			log.Fatalf("Cannot drive %s off. Error: %s", led.Name, err)
+		}
+		time.Sleep(*period / 2)
+	}
+}
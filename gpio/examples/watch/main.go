@@ -0,0 +1,47 @@
+// Watch prints the edges of the input lines of a configuration file, in the format of the device
+// service, using the gpio package without the device service.
+//
+//	go run ./gpio/examples/watch -file gpio.yaml
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/edgexfoundry/device-gpiod/gpio"
+)
+
+func main() {
+	file := flag.String("file", "gpio.yaml", "configuration file with the gpio section")
+	flag.Parse()
+
+	var list gpio.GPIOList
+	if err := list.Parse(*file, false); err != nil {
+		log.Fatal(err)
+	}
+	if err := list.ResolveLineNames(); err != nil {
+		log.Fatal(err)
+	}
+
+	for i := range list.Gpio {
+		g := &list.Gpio[i]
+		if g.Role != gpio.RoleInput {
+			continue
+		}
+		err := g.Watch(func(evt gpio.Event) {
+			log.Printf("%s (line %d of %s): %d", evt.Name, evt.Line, evt.Chip, evt.Value)
+		})
+		if err != nil {
+			log.Fatalf("Cannot watch %s. Error: %s", g.Name, err)
+		}
+		defer g.Release()
+		log.Printf("Watching %s", g.Name)
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	<-signals
+}
@@ -0,0 +1,461 @@
+package gpio
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"syscall"
+
+	"github.com/warthog618/gpiod"
+)
+
+// i2cSlave is the I2C_SLAVE ioctl of i2c-dev, selecting the address of the next transfers.
+const i2cSlave = 0x0703
+
+// Expander is an I2C port expander of the PCF8574 (8 lines) or PCF8575 (16 lines) family driven from
+// user space through i2c-dev, for boards whose kernel has no driver for it. Lines refer to it by Chip
+// like to a gpiochip. Its outputs are quasi-bidirectional: a line is driven low by a 0 in the port
+// register and released high, or read as an input, by a 1.
+type Expander struct {
+	Chip    string `yaml:"chip"`
+	Bus     string `yaml:"bus"`
+	Address int    `yaml:"address"`
+	Lines   int    `yaml:"lines"`
+}
+
+// expanderChip is the state of an expander. The port register is written whole, so a line write is a
+// read-modify-write of the latch kept here: the latch is changed under mutex, and the writes arriving
+// while one is on the bus are batched into the next one. No write can clobber the bits of another line.
+type expanderChip struct {
+	Expander
+
+	mutex   sync.Mutex
+	written *sync.Cond
+	bus     io.ReadWriteCloser
+	latch   uint16
+	used    uint16
+	outputs uint16
+	labels  map[int]string
+	// changes counts the latch changes, flushed the ones already on the bus.
+	changes uint64
+	flushed uint64
+	writing bool
+}
+
+var expanders = make(map[string]*expanderChip)
+
+// openExpanderBus opens the bus of an expander, addressed to it. Replaced by the tests.
+var openExpanderBus = openI2CDevice
+
+// openI2CDevice opens the i2c-dev bus and selects the address of the next transfers.
+func openI2CDevice(bus string, address int) (io.ReadWriteCloser, error) {
+	f, err := os.OpenFile(bus, os.O_RDWR, 0)
+	if err != nil {
+		return nil, err
+	}
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), i2cSlave, uintptr(address)); errno != 0 {
+		f.Close()
+		return nil, fmt.Errorf("cannot address 0x%02x on %s: %w", address, bus, errno)
+	}
+	return f, nil
+}
+
+// Validate checks the settings of the expander.
+func (e *Expander) Validate() error {
+	switch {
+	case e.Chip == "":
+		return errors.New("expander chip name is required")
+	case e.Bus == "":
+		return fmt.Errorf("expander %s: bus is required", e.Chip)
+	case e.Address < 0x03 || e.Address > 0x77:
+		return fmt.Errorf("expander %s: address 0x%02x out of range [0x03, 0x77]", e.Chip, e.Address)
+	case e.Lines != 8 && e.Lines != 16:
+		return fmt.Errorf("expander %s: lines must be 8 or 16, got %d", e.Chip, e.Lines)
+	}
+	return nil
+}
+
+// SetExpanders registers the user-space expanders, whose lines are then requested from them instead
+// of the backend, except on the simulator which keeps every line in memory. An expander keeps its
+// state when registered again with the same settings, so lines already requested stay valid.
+func SetExpanders(list []Expander) error {
+	chips := make(map[string]*expanderChip, len(list))
+	for _, e := range list {
+		if err := e.Validate(); err != nil {
+			return err
+		}
+		if _, ok := chips[e.Chip]; ok {
+			return fmt.Errorf("expander %s is defined twice", e.Chip)
+		}
+		chips[e.Chip] = nil
+	}
+
+	backendMutex.Lock()
+	defer backendMutex.Unlock()
+	for _, e := range list {
+		if c, ok := expanders[e.Chip]; ok && c.Expander == e {
+			chips[e.Chip] = c
+			continue
+		}
+		c := &expanderChip{Expander: e, labels: make(map[int]string)}
+		c.written = sync.NewCond(&c.mutex)
+		chips[e.Chip] = c
+	}
+	for name, c := range expanders {
+		if chips[name] != c {
+			c.close()
+		}
+	}
+	expanders = chips
+	backend = wrapBackend()
+	return nil
+}
+
+// expanderFor returns the user-space expander of the chip, nil for a chip of the backend.
+func expanderFor(chip string) *expanderChip {
+	backendMutex.RLock()
+	defer backendMutex.RUnlock()
+	return expanders[chip]
+}
+
+// expanderBackend requests the lines of the expanders from them, the others from the wrapped backend.
+type expanderBackend struct {
+	Backend
+	chips map[string]*expanderChip
+}
+
+func (b expanderBackend) RequestInput(gpio *GPIO) (Line, error) {
+	c, ok := b.chips[gpio.Chip]
+	if !ok {
+		return b.Backend.RequestInput(gpio)
+	}
+	return c.request(gpio, false, 1)
+}
+
+func (b expanderBackend) RequestOutput(gpio *GPIO, value int) (Line, error) {
+	c, ok := b.chips[gpio.Chip]
+	if !ok {
+		return b.Backend.RequestOutput(gpio, value)
+	}
+	return c.request(gpio, true, value)
+}
+
+func (b expanderBackend) RequestOutputs(gpios []*GPIO, values []int) (Lines, error) {
+	c, ok := b.chips[gpios[0].Chip]
+	if !ok {
+		return b.Backend.RequestOutputs(gpios, values)
+	}
+	return c.requestGroup(gpios, values)
+}
+
+func (b expanderBackend) RequestAsIs(gpio *GPIO) (Line, error) {
+	c, ok := b.chips[gpio.Chip]
+	if !ok {
+		return b.Backend.RequestAsIs(gpio)
+	}
+	return c.request(gpio, false, -1)
+}
+
+func (b expanderBackend) WatchEvents(gpio *GPIO, handler func(gpiod.LineEvent)) (Line, error) {
+	if _, ok := b.chips[gpio.Chip]; !ok {
+		return b.Backend.WatchEvents(gpio, handler)
+	}
+	return nil, fmt.Errorf("edge events are not supported on expander %s, poll the line instead: %w", gpio.Chip, syscall.ENOTSUP)
+}
+
+func (b expanderBackend) OwnsOutput(gpio *GPIO) (bool, error) {
+	c, ok := b.chips[gpio.Chip]
+	if !ok {
+		return b.Backend.OwnsOutput(gpio)
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	bit := uint16(1) << gpio.Line
+	return c.used&bit != 0 && c.outputs&bit != 0 && c.labels[gpio.Line] == gpio.label(), nil
+}
+
+// Enumerate lists the lines of the wrapped backend, then the lines of the expanders.
+func (b expanderBackend) Enumerate() []LineDescriptor {
+	lines := b.Backend.Enumerate()
+	for name, c := range b.chips {
+		c.mutex.Lock()
+		for offset := 0; offset < c.Lines; offset++ {
+			bit := uint16(1) << offset
+			lines = append(lines, LineDescriptor{
+				Chip:      name,
+				ChipLabel: fmt.Sprintf("pcf857x@%s:0x%02x", c.Bus, c.Address),
+				Line:      offset,
+				Consumer:  c.labels[offset],
+				Used:      c.used&bit != 0,
+				Output:    c.outputs&bit != 0,
+			})
+		}
+		c.mutex.Unlock()
+	}
+	return lines
+}
+
+// request marks the line requested, failing with EBUSY like the kernel when it already is. An input
+// releases its bit high, an output is driven to value, -1 keeps the latch as found.
+func (c *expanderChip) request(gpio *GPIO, output bool, value int) (Line, error) {
+	if err := c.claim([]*GPIO{gpio}, output); err != nil {
+		return nil, err
+	}
+	handle := &expanderLine{chip: c, offset: gpio.Line, activeLow: gpio.ActiveLow != nil && *gpio.ActiveLow}
+	var err error
+	switch {
+	case !output && value == 1:
+		err = c.set(uint16(1)<<gpio.Line, 0xFFFF)
+	case output:
+		err = handle.SetValue(value)
+	}
+	if err != nil {
+		handle.Close()
+		return nil, err
+	}
+	return handle, nil
+}
+
+func (c *expanderChip) requestGroup(gpios []*GPIO, values []int) (Lines, error) {
+	if err := c.claim(gpios, true); err != nil {
+		return nil, err
+	}
+	group := &expanderLines{chip: c}
+	for _, gpio := range gpios {
+		group.lines = append(group.lines, expanderLine{chip: c, offset: gpio.Line, activeLow: gpio.ActiveLow != nil && *gpio.ActiveLow})
+	}
+	if err := group.SetValues(values); err != nil {
+		group.Close()
+		return nil, err
+	}
+	return group, nil
+}
+
+// claim opens the bus on the first request and marks the lines used.
+func (c *expanderChip) claim(gpios []*GPIO, output bool) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if err := c.open(); err != nil {
+		return err
+	}
+	var bits uint16
+	for _, gpio := range gpios {
+		if gpio.Line < 0 || gpio.Line >= c.Lines {
+			return fmt.Errorf("line %d out of range of expander %s: %w", gpio.Line, c.Chip, syscall.EINVAL)
+		}
+		bit := uint16(1) << gpio.Line
+		if c.used&bit != 0 {
+			return fmt.Errorf("line %d of %s requested by %s: %w", gpio.Line, c.Chip, c.labels[gpio.Line], syscall.EBUSY)
+		}
+		bits |= bit
+	}
+	c.used |= bits
+	if output {
+		c.outputs |= bits
+	} else {
+		c.outputs &^= bits
+	}
+	for _, gpio := range gpios {
+		c.labels[gpio.Line] = gpio.label()
+	}
+	return nil
+}
+
+func (c *expanderChip) release(bits uint16) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.used &^= bits
+	c.outputs &^= bits
+	for offset := 0; offset < c.Lines; offset++ {
+		if bits&(uint16(1)<<offset) != 0 {
+			delete(c.labels, offset)
+		}
+	}
+}
+
+// open addresses the expander on its bus and takes the levels found as the latch, so a restart does not
+// release the outputs left low. Called with the mutex held.
+func (c *expanderChip) open() error {
+	if c.bus != nil {
+		return nil
+	}
+	bus, err := openExpanderBus(c.Bus, c.Address)
+	if err != nil {
+		return fmt.Errorf("cannot open expander %s: %w", c.Chip, err)
+	}
+	c.bus = bus
+	port, err := c.readPort()
+	if err != nil {
+		c.bus = nil
+		bus.Close()
+		return err
+	}
+	c.latch = port
+	return nil
+}
+
+func (c *expanderChip) close() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.bus != nil {
+		c.bus.Close()
+		c.bus = nil
+	}
+}
+
+// set changes the latch bits in mask to bits and returns once the change is on the bus. The first
+// caller finding the bus idle writes the latch, with the changes of every caller that arrived until
+// then; the others wait for a write carrying theirs.
+func (c *expanderChip) set(mask uint16, bits uint16) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.latch = c.latch&^mask | bits&mask
+	c.changes++
+	mine := c.changes
+	for c.flushed < mine {
+		if c.writing {
+			c.written.Wait()
+			continue
+		}
+		c.writing = true
+		latch, batch := c.latch, c.changes
+		c.mutex.Unlock()
+		err := c.writePort(latch)
+		c.mutex.Lock()
+		c.writing = false
+		if err == nil {
+			c.flushed = batch
+		}
+		c.written.Broadcast()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writePort writes the latch, low byte first. Called by a single writer at a time.
+func (c *expanderChip) writePort(latch uint16) error {
+	if c.bus == nil {
+		return ErrNotRequested
+	}
+	_, err := c.bus.Write([]byte{byte(latch), byte(latch >> 8)}[:c.Lines/8])
+	return err
+}
+
+// readPort reads the levels of the pins, low byte first.
+func (c *expanderChip) readPort() (uint16, error) {
+	buf := make([]byte, c.Lines/8)
+	if _, err := c.bus.Read(buf); err != nil {
+		return 0, err
+	}
+	port := uint16(buf[0])
+	if len(buf) > 1 {
+		port |= uint16(buf[1]) << 8
+	}
+	return port, nil
+}
+
+func (c *expanderChip) level(offset int) (int, error) {
+	c.mutex.Lock()
+	bus := c.bus
+	c.mutex.Unlock()
+	if bus == nil {
+		return 0, ErrNotRequested
+	}
+	port, err := c.readPort()
+	if err != nil {
+		return 0, err
+	}
+	return int(port>>offset) & 1, nil
+}
+
+// expanderLine is a line requested from an expander.
+type expanderLine struct {
+	chip      *expanderChip
+	offset    int
+	activeLow bool
+	closed    bool
+}
+
+func (l *expanderLine) physical(value int) uint16 {
+	if (value != 0) != l.activeLow {
+		return 1
+	}
+	return 0
+}
+
+func (l *expanderLine) Value() (int, error) {
+	if l.closed {
+		return 0, ErrNotRequested
+	}
+	value, err := l.chip.level(l.offset)
+	if err != nil {
+		return 0, err
+	}
+	if l.activeLow {
+		value ^= 1
+	}
+	return value, nil
+}
+
+func (l *expanderLine) SetValue(value int) error {
+	if l.closed {
+		return ErrNotRequested
+	}
+	bit := uint16(1) << l.offset
+	return l.chip.set(bit, l.physical(value)<<l.offset)
+}
+
+func (l *expanderLine) Close() error {
+	if !l.closed {
+		l.closed = true
+		l.chip.release(uint16(1) << l.offset)
+	}
+	return nil
+}
+
+// expanderLines is a group of lines of one expander, written in a single transfer.
+type expanderLines struct {
+	chip   *expanderChip
+	lines  []expanderLine
+	closed bool
+}
+
+func (g *expanderLines) Values(values []int) error {
+	for i := range g.lines {
+		value, err := g.lines[i].Value()
+		if err != nil {
+			return err
+		}
+		values[i] = value
+	}
+	return nil
+}
+
+func (g *expanderLines) SetValues(values []int) error {
+	if g.closed {
+		return ErrNotRequested
+	}
+	var mask, bits uint16
+	for i, l := range g.lines {
+		mask |= uint16(1) << l.offset
+		bits |= l.physical(values[i]) << l.offset
+	}
+	return g.chip.set(mask, bits)
+}
+
+func (g *expanderLines) Close() error {
+	if g.closed {
+		return nil
+	}
+	g.closed = true
+	var bits uint16
+	for _, l := range g.lines {
+		bits |= uint16(1) << l.offset
+	}
+	g.chip.release(bits)
+	return nil
+}
@@ -0,0 +1,204 @@
+package gpio
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// fakeBus is the i2c bus of a PCF857x: a write sets the port register, a read returns it. It counts
+// the transfers and the writes overlapping on the bus.
+type fakeBus struct {
+	mutex       sync.Mutex
+	port        uint16
+	writes      int
+	writing     bool
+	overlapping int
+}
+
+func (b *fakeBus) Write(p []byte) (int, error) {
+	b.mutex.Lock()
+	if b.writing {
+		b.overlapping++
+	}
+	b.writing = true
+	b.mutex.Unlock()
+
+	// Hold the bus like a 100kHz transfer does, for the other writers to pile up
+	time.Sleep(100 * time.Microsecond)
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.writing = false
+	b.writes++
+	b.port = uint16(p[0])
+	if len(p) > 1 {
+		b.port |= uint16(p[1]) << 8
+	}
+	return len(p), nil
+}
+
+func (b *fakeBus) Read(p []byte) (int, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	p[0] = byte(b.port)
+	if len(p) > 1 {
+		p[1] = byte(b.port >> 8)
+	}
+	return len(p), nil
+}
+
+func (b *fakeBus) Close() error {
+	return nil
+}
+
+func (b *fakeBus) state() (port uint16, writes int, overlapping int) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.port, b.writes, b.overlapping
+}
+
+// useFakeExpander registers a 16 lines expander on a fake bus, its pins released high at power-up.
+func useFakeExpander(t *testing.T) (*expanderChip, *fakeBus) {
+	t.Helper()
+	bus := &fakeBus{port: 0xFFFF}
+	openExpanderBus = func(string, int) (io.ReadWriteCloser, error) {
+		return bus, nil
+	}
+	if err := SetExpanders([]Expander{{Chip: "pcf0", Bus: "/dev/i2c-1", Address: 0x20, Lines: 16}}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		SetExpanders(nil)
+		openExpanderBus = openI2CDevice
+	})
+	return expanderFor("pcf0"), bus
+}
+
+func TestExpanderConcurrentWrites(t *testing.T) {
+	c, bus := useFakeExpander(t)
+	lines := make([]Line, c.Lines)
+	for offset := range lines {
+		line, err := c.request(&GPIO{Name: fmt.Sprintf("out%d", offset), Chip: "pcf0", Line: offset}, true, 0)
+		if err != nil {
+			t.Fatalf("request of line %d failed: %s", offset, err)
+		}
+		defer line.Close()
+		lines[offset] = line
+	}
+
+	// Every line toggles on its own goroutine and ends on its bit of expected: a write rewriting the
+	// port from a stale latch would leave the bit of another line wrong.
+	const expected = 0xA5C3
+	var wg sync.WaitGroup
+	for offset, line := range lines {
+		wg.Add(1)
+		go func(offset int, line Line) {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				if err := line.SetValue(i % 2); err != nil {
+					t.Errorf("write of line %d failed: %s", offset, err)
+					return
+				}
+			}
+			if err := line.SetValue(expected >> offset & 1); err != nil {
+				t.Errorf("write of line %d failed: %s", offset, err)
+			}
+		}(offset, line)
+	}
+	wg.Wait()
+
+	port, writes, overlapping := bus.state()
+	if port != expected {
+		t.Errorf("port register %016b after the writes, want %016b", port, expected)
+	}
+	if overlapping > 0 {
+		t.Errorf("%d writes overlapped on the bus", overlapping)
+	}
+	if total := len(lines) * 51; writes >= total {
+		t.Errorf("%d transfers for %d line writes, want them batched", writes, total)
+	}
+	for offset, line := range lines {
+		if value, err := line.Value(); err != nil || value != expected>>offset&1 {
+			t.Errorf("line %d reads %d (%v), want %d", offset, value, err, expected>>offset&1)
+		}
+	}
+}
+
+func TestExpanderKeepsOtherBits(t *testing.T) {
+	c, bus := useFakeExpander(t)
+	activeLow := true
+	relay, err := c.request(&GPIO{Name: "relay", Chip: "pcf0", Line: 3, ActiveLow: &activeLow}, true, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer relay.Close()
+	if port, _, _ := bus.state(); port != 0xFFF7 {
+		t.Fatalf("port register %016b after the request, want only line 3 low", port)
+	}
+
+	// A group write changes its bits in a single transfer, the relay stays low
+	gpios := []*GPIO{{Name: "a", Chip: "pcf0", Line: 8}, {Name: "b", Chip: "pcf0", Line: 9}}
+	group, err := c.requestGroup(gpios, []int{0, 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer group.Close()
+	_, before, _ := bus.state()
+	if err := group.SetValues([]int{1, 0}); err != nil {
+		t.Fatal(err)
+	}
+	port, after, _ := bus.state()
+	if port != 0xFDF7 {
+		t.Errorf("port register %016b after the group write, want %016b", port, 0xFDF7)
+	}
+	if after-before != 1 {
+		t.Errorf("group write done in %d transfers, want 1", after-before)
+	}
+}
+
+func TestExpanderRequest(t *testing.T) {
+	c, _ := useFakeExpander(t)
+	line, err := c.request(&GPIO{Name: "pump", Chip: "pcf0", Line: 0}, true, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.request(&GPIO{Name: "valve", Chip: "pcf0", Line: 0}, true, 0); !errors.Is(err, syscall.EBUSY) {
+		t.Errorf("second request of line 0 returned %v, want EBUSY", err)
+	}
+	if _, err := c.request(&GPIO{Name: "valve", Chip: "pcf0", Line: 16}, true, 0); !errors.Is(err, syscall.EINVAL) {
+		t.Errorf("request of line 16 returned %v, want EINVAL", err)
+	}
+
+	line.Close()
+	if err := line.SetValue(1); !errors.Is(err, ErrNotRequested) {
+		t.Errorf("write of a closed line returned %v", err)
+	}
+	if _, err := c.request(&GPIO{Name: "valve", Chip: "pcf0", Line: 0}, true, 0); err != nil {
+		t.Errorf("request of released line 0 failed: %s", err)
+	}
+}
+
+func TestExpanderValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		expander Expander
+		valid    bool
+	}{
+		{"PCF8574", Expander{Chip: "pcf0", Bus: "/dev/i2c-1", Address: 0x20, Lines: 8}, true},
+		{"no bus", Expander{Chip: "pcf0", Address: 0x20, Lines: 8}, false},
+		{"reserved address", Expander{Chip: "pcf0", Bus: "/dev/i2c-1", Address: 0x78, Lines: 8}, false},
+		{"12 lines", Expander{Chip: "pcf0", Bus: "/dev/i2c-1", Address: 0x20, Lines: 12}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.expander.Validate(); (err == nil) != tt.valid {
+				t.Errorf("Validate returned %v, want valid %v", err, tt.valid)
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package gpio
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// FaultProfile injects failures in the line requests, writes and events, so the retry, arbitration and
+// failover paths can be exercised on demand. Rates are probabilities between 0 and 1; the same seed
+// replays the same sequence of faults.
+type FaultProfile struct {
+	Seed           int64   `yaml:"seed"`
+	RequestBusy    float64 `yaml:"request_busy"`
+	WriteError     float64 `yaml:"write_error"`
+	EventDelay     string  `yaml:"event_delay"`
+	EventDelayRate float64 `yaml:"event_delay_rate"`
+
+	eventDelay time.Duration
+}
+
+// ErrInjectedWrite is returned by the writes failed by the fault profile.
+var ErrInjectedWrite = fmt.Errorf("injected write error: %w", syscall.EIO)
+
+var (
+	faultMutex   sync.Mutex
+	faultProfile *FaultProfile
+	faultRand    *rand.Rand
+)
+
+// Validate checks the rates and the event delay of the profile.
+func (profile *FaultProfile) Validate() error {
+	for name, rate := range map[string]float64{
+		"request_busy":     profile.RequestBusy,
+		"write_error":      profile.WriteError,
+		"event_delay_rate": profile.EventDelayRate,
+	} {
+		if rate < 0 || rate > 1 {
+			return fmt.Errorf("%s must be a probability between 0 and 1", name)
+		}
+	}
+	if profile.EventDelay != "" {
+		d, err := time.ParseDuration(profile.EventDelay)
+		if err != nil || d < 0 {
+			return fmt.Errorf("invalid event_delay %q", profile.EventDelay)
+		}
+		profile.eventDelay = d
+	}
+	return nil
+}
+
+// SetFaults activates the fault profile, checked by Validate beforehand; nil disables the injection.
+func SetFaults(profile *FaultProfile) {
+	faultMutex.Lock()
+	defer faultMutex.Unlock()
+	faultProfile = profile
+	if profile != nil {
+		faultRand = rand.New(rand.NewSource(profile.Seed))
+	}
+}
+
+// injectFault draws whether the fault of the given rate happens.
+func injectFault(rate func(*FaultProfile) float64) bool {
+	faultMutex.Lock()
+	defer faultMutex.Unlock()
+	if faultProfile == nil {
+		return false
+	}
+	r := rate(faultProfile)
+	return r > 0 && faultRand.Float64() < r
+}
+
+// eventDelay returns how long the next event is held back, zero when it is delivered at once.
+func eventDelay() time.Duration {
+	faultMutex.Lock()
+	delay := time.Duration(0)
+	if faultProfile != nil {
+		delay = faultProfile.eventDelay
+	}
+	faultMutex.Unlock()
+	if delay == 0 || !injectFault(func(p *FaultProfile) float64 { return p.EventDelayRate }) {
+		return 0
+	}
+	return delay
+}
@@ -3,73 +3,186 @@ package gpio
 import (
 	"errors"
 	"log"
+	"sync/atomic"
+)
+
+// Operations reported to the line error hook
+const (
+	LineOpRequest = "request"
+	LineOpRelease = "release"
+)
 
-	"github.com/warthog618/gpiod"
+var (
+	consumer      = "device-gpiod"
+	actuatedHook  func(name string, value int)
+	intentHook    func(name string, value int) func(error)
+	edgeHook      func(name string, value int)
+	lineErrorHook func(name string, op string, err error)
+	// verbose enables the debug logs, 1 when set
+	verbose int32
+	logger  = log.Default()
 )
 
+// SetConsumer sets the consumer label reported by the kernel for the lines requested by this process.
+func SetConsumer(label string) {
+	consumer = label
+}
+
+// OnActuation sets a function called every time an output line is driven by the service.
+func OnActuation(hook func(name string, value int)) {
+	actuatedHook = hook
+}
+
+// OnIntent sets a function called before an output line is driven, e.g. to journal the intent. The
+// function it returns is called with the outcome once the line has been driven.
+func OnIntent(hook func(name string, value int) func(error)) {
+	intentHook = hook
+}
+
+// SetLogger sets the logger of the package, the standard logger by default.
+func SetLogger(l *log.Logger) {
+	logger = l
+}
+
+// SetVerbose enables or disables the debug logs of the package.
+func SetVerbose(on bool) {
+	var value int32
+	if on {
+		value = 1
+	}
+	atomic.StoreInt32(&verbose, value)
+}
+
+func debugf(format string, args ...interface{}) {
+	if atomic.LoadInt32(&verbose) == 1 {
+		logger.Printf("[gpio] "+format, args...)
+	}
+}
+
+// OnEdge sets a function called for every edge event of a watched line, before its handler.
+func OnEdge(hook func(name string, value int)) {
+	edgeHook = hook
+}
+
+// OnLineError sets a function called every time requesting or releasing a line fails.
+func OnLineError(hook func(name string, op string, err error)) {
+	lineErrorHook = hook
+}
+
+// lineFailed reports a failed operation on the named line to the line error hook. It returns err.
+func lineFailed(name string, op string, err error) error {
+	if err != nil && lineErrorHook != nil {
+		lineErrorHook(name, op, err)
+	}
+	return err
+}
+
+// intent announces that the line is about to be driven to value.
+func (gpio *GPIO) intent(value int) func(error) {
+	if intentHook == nil {
+		return func(error) {}
+	}
+	return intentHook(gpio.Name, value)
+}
+
+// GPIO is a line, configured from the gpio section of the configuration file or built by the caller,
+// then checked by Validate. The line is requested on its first use and stays requested until Release.
 type GPIO struct {
-	Name           string `yaml:"name"`
-	Chip           string `yaml:"chip"`
-	Line           int    `yaml:"line"`
+	Name           string   `yaml:"name"`
+	Chip           string   `yaml:"chip"`
+	Line           int      `yaml:"line"`
+	LineName       string   `yaml:"line_name"`
+	Chips          []string `yaml:"chips"`
+	Role           string   `yaml:"role"`
+	Description    string   `yaml:"description"`
+	Labels         []string `yaml:"labels"`
+	Power          float64  `yaml:"power"`
+	Bias           string   `yaml:"bias"`
+	Drive          string   `yaml:"drive"`
+	InitialState   string   `yaml:"initial_state"`
+	SafeState      string   `yaml:"safe_state"`
+	Debounce       string   `yaml:"debounce"`
+	SoftDebounce   string   `yaml:"soft_debounce"`
+	Direction      string   `yaml:"direction"`
+	Edge           string   `yaml:"edge"`
+	Mode           string   `yaml:"mode"`
+	CountInterval  string   `yaml:"count_interval"`
+	Consumer       string   `yaml:"consumer"`
+	ActiveLow      *bool    `yaml:"active_low"`
+	Feedback       string   `yaml:"feedback"`
+	HoldOnExit     bool     `yaml:"hold_on_exit"`
+	MaxOn          string   `yaml:"max_on"`
+	OpenSwitch     string   `yaml:"open_switch"`
+	ClosedSwitch   string   `yaml:"closed_switch"`
+	TravelTime     string   `yaml:"travel_time"`
+	StuckRetries   int      `yaml:"stuck_retries"`
+	StuckStrategy  string   `yaml:"stuck_strategy"`
+	JogTime        string   `yaml:"jog_time"`
+	Pwm            bool     `yaml:"pwm"`
+	PwmFrequency   float64  `yaml:"pwm_frequency"`
+	Exposure       string   `yaml:"exposure"`
 	State          bool
-	gpioLine       *gpiod.Line
-	gpioSensorLine *gpiod.Line
+	gpioLine       Line
+	gpioSensorLine Line
+	// watchHandler is the handler of a watched line, to request it again after a chip reload
+	watchHandler func(Event)
 }
 
+// Up drives the line to its active level, requesting it as output when needed.
 func (gpio *GPIO) Up() error {
 
 	var err error
 
-	err = gpio.setupOutputLine(1)
-	if err != nil {
-		log.Printf("Error setting up resource %d from chip %s. Error: %s", gpio.Line, gpio.Chip, err)
-		return err
+	if gpio.deferToOverride(1) {
+		return nil
 	}
 
-	err = gpio.releaseLine()
+	err = gpio.setupOutputLine(1)
 	if err != nil {
-		log.Printf("Error releasing resource %d from chip %s. Error: %s", gpio.Line, gpio.Chip, err)
+		SampledLogf(gpio.sampleKey("setup"), "Error setting up resource %d from chip %s. Error: %s", gpio.Line, gpio.Chip, err)
 		return err
 	}
+	Recovered(gpio.sampleKey("setup"))
 
 	return nil
 }
 
+// Down drives the line to its inactive level, requesting it as output when needed.
 func (gpio *GPIO) Down() error {
 
 	var err error
 
-	err = gpio.setupOutputLine(0)
-	if err != nil {
-		log.Printf("Error setting up resource %d from chip %s. Error: %s", gpio.Line, gpio.Chip, err)
-		return err
+	if gpio.deferToOverride(0) {
+		return nil
 	}
 
-	err = gpio.releaseLine()
+	err = gpio.setupOutputLine(0)
 	if err != nil {
-		log.Printf("Error releasing resource %d from chip %s. Error: %s", gpio.Line, gpio.Chip, err)
+		SampledLogf(gpio.sampleKey("setup"), "Error setting up resource %d from chip %s. Error: %s", gpio.Line, gpio.Chip, err)
 		return err
 	}
+	Recovered(gpio.sampleKey("setup"))
 
 	return nil
 }
 
+// ReadGpio reads the level of a line already requested.
 func (gpio *GPIO) ReadGpio() (int, error) {
 
 	if gpio.gpioLine == nil {
-		log.Printf("Resource %d of %s is not available", gpio.Line, gpio.Chip)
+		logger.Printf("Resource %d of %s is not available", gpio.Line, gpio.Chip)
 		return -1, errors.New("resource is not available")
 	}
 
 	value, err := gpio.gpioLine.Value()
 	if err != nil {
-		log.Printf("Error reading status of resource %d from chip %s. Error: %s", gpio.Line, gpio.Chip, err)
+		logger.Printf("Error reading status of resource %d from chip %s. Error: %s", gpio.Line, gpio.Chip, err)
 		return -1, err
 	}
 
 	err = gpio.releaseLine()
 	if err != nil {
-		log.Printf("Error releasing resource %d from chip %s. Error: %s", gpio.Line, gpio.Chip, err)
+		logger.Printf("Error releasing resource %d from chip %s. Error: %s", gpio.Line, gpio.Chip, err)
 		return -1, err
 	}
 
@@ -77,35 +190,62 @@ func (gpio *GPIO) ReadGpio() (int, error) {
 }
 
 func (gpio *GPIO) setupOutputLine(state int) error {
-	var err error
-	gpio.gpioLine, err = gpiod.RequestLine(gpio.Chip, gpio.Line, gpiod.AsOutput(state)) // Setup lines to default starting state
+	if gpio.Yielded() {
+		return ErrYielded
+	}
+	if gpio.inhibited(state) {
+		return ErrInhibited
+	}
+	done := gpio.intent(state)
+	err := gpio.setHeld(state)
+	done(err)
 	if err != nil {
-		log.Printf("Error setting up required resources. Error: %s", err)
+		logger.Printf("Error setting up required resources. Error: %s", err)
 		return err
 	}
+	debugf("Drove %s (line %d of %s) to %d", gpio.Name, gpio.Line, gpio.Chip, state)
+	gpio.setLastValue(state)
+	if actuatedHook != nil {
+		actuatedHook(gpio.Name, state)
+	}
 	return nil
 }
 
 func (gpio *GPIO) setupInputLine() error {
+	if gpio.Yielded() {
+		return ErrYielded
+	}
+	gpio.releaseHeld()
 	var err error
-	gpio.gpioLine, err = gpiod.RequestLine(gpio.Chip, gpio.Line, gpiod.AsInput) // Setup lines to default starting state
+	gpio.gpioLine, err = currentBackend().RequestInput(gpio) // Setup lines to default starting state
 	if err != nil {
-		log.Printf("Error setting up required resources. Error: %s", err)
+		logger.Printf("Error setting up required resources. Error: %s", err)
 		return err
 	}
 	return nil
 }
 
+// SetAsInput requests the line as an input.
 func (gpio *GPIO) SetAsInput() error {
 	return gpio.setupInputLine()
 }
 
+// SetAsOutput requests the line as an output driven to state.
 func (gpio *GPIO) SetAsOutput(state int) error {
 	return gpio.setupOutputLine(state)
 }
 
+// Release closes the handles of the line: the held output one and the one of an input request.
 func (gpio *GPIO) Release() error {
-	return gpio.releaseLine()
+	err := gpio.releaseHeld()
+	if gpio.gpioLine != nil {
+		if closeErr := gpio.gpioLine.Close(); err == nil {
+			err = closeErr
+		}
+		gpio.gpioLine = nil
+	}
+	gpio.watchHandler = nil
+	return lineFailed(gpio.Name, LineOpRelease, err)
 }
 
 func (gpio *GPIO) releaseLine() error {
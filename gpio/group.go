@@ -0,0 +1,223 @@
+package gpio
+
+import "fmt"
+
+// LineGroup is a named set of output lines of one chip that are set together in a single request, so
+// lines that must switch at the same instant (H-bridge direction pins, relay banks) never go through
+// an intermediate state.
+type LineGroup struct {
+	Name  string   `yaml:"name"`
+	Lines []string `yaml:"lines"`
+}
+
+// Lines are several lines of a chip requested together. Values and SetValues take one value per line,
+// in the order of the request.
+type Lines interface {
+	Values(values []int) error
+	SetValues(values []int) error
+	Close() error
+}
+
+// heldGroup is the request of a group while it is held. The members are held through groupLine views,
+// so single writes to a member change only its value within the group request.
+type heldGroup struct {
+	name   string
+	lines  Lines
+	values []int
+	closed bool
+}
+
+// groupLine is a member of a held group seen as a single line. Closing a member releases the whole
+// request: the other members find their handle rejected on the next write and are requested alone.
+// A member write is a read-modify-write of the values of the whole request, so, like any write of a
+// held line, it is done holding heldMutex and concurrent writes to two members cannot clobber each
+// other's values.
+type groupLine struct {
+	group *heldGroup
+	index int
+}
+
+func (l *groupLine) Value() (int, error) {
+	if l.group.closed {
+		return 0, ErrNotRequested
+	}
+	values := make([]int, len(l.group.values))
+	if err := l.group.lines.Values(values); err != nil {
+		return 0, err
+	}
+	return values[l.index], nil
+}
+
+func (l *groupLine) SetValue(value int) error {
+	if l.group.closed {
+		return ErrNotRequested
+	}
+	values := append([]int(nil), l.group.values...)
+	values[l.index] = value
+	if err := l.group.lines.SetValues(values); err != nil {
+		return err
+	}
+	l.group.values = values
+	return nil
+}
+
+func (l *groupLine) Close() error {
+	if l.group.closed {
+		return nil
+	}
+	l.group.closed = true
+	return l.group.lines.Close()
+}
+
+// validateGroups checks that the groups name distinct output lines of a single chip, sharing the
+// settings that apply to the whole request, and that no line belongs to two groups.
+func (gpio *GPIOList) validateGroups() error {
+	lines := make(map[string]*GPIO)
+	for i := range gpio.Gpio {
+		lines[gpio.Gpio[i].Name] = &gpio.Gpio[i]
+	}
+	names := make(map[string]bool)
+	member := make(map[string]string)
+	for _, group := range gpio.Groups {
+		if group.Name == "" || names[group.Name] || lines[group.Name] != nil {
+			return fmt.Errorf("group %q: missing or duplicated name", group.Name)
+		}
+		names[group.Name] = true
+		if len(group.Lines) < 2 {
+			return fmt.Errorf("group %s: at least two lines are required", group.Name)
+		}
+		first := lines[group.Lines[0]]
+		for _, name := range group.Lines {
+			line, ok := lines[name]
+			if !ok {
+				return fmt.Errorf("group %s: unknown line %s", group.Name, name)
+			}
+			if other, ok := member[name]; ok {
+				return fmt.Errorf("group %s: line %s already belongs to group %s", group.Name, name, other)
+			}
+			member[name] = group.Name
+			if line.Role == RoleInput || line.Pwm {
+				return fmt.Errorf("group %s: line %s is not a plain output", group.Name, name)
+			}
+			if line.Chip != first.Chip || line.label() != first.label() || line.Drive != first.Drive ||
+				line.activeLow() != first.activeLow() {
+				return fmt.Errorf("group %s: line %s differs from %s in chip, consumer, drive or active_low", group.Name, name, first.Name)
+			}
+		}
+	}
+	return nil
+}
+
+func (gpio *GPIO) activeLow() bool {
+	return gpio.ActiveLow != nil && *gpio.ActiveLow
+}
+
+// Group returns the lines of the named group, in the order of the group.
+func (gpio *GPIOList) Group(name string) ([]*GPIO, bool) {
+	for _, group := range gpio.Groups {
+		if group.Name != name {
+			continue
+		}
+		members := make([]*GPIO, 0, len(group.Lines))
+		for _, line := range group.Lines {
+			for i := range gpio.Gpio {
+				if gpio.Gpio[i].Name == line {
+					members = append(members, &gpio.Gpio[i])
+				}
+			}
+		}
+		return members, true
+	}
+	return nil, false
+}
+
+// SetGroup drives the lines of the named group to values, one per line, in a single kernel call. The
+// write is refused as a whole when a member is yielded, overridden or inhibited. The group request is
+// held between writes like a single output.
+func (gpio *GPIOList) SetGroup(name string, values []int) error {
+	members, ok := gpio.Group(name)
+	if !ok {
+		return fmt.Errorf("unknown group %s", name)
+	}
+	if len(values) != len(members) {
+		return fmt.Errorf("group %s has %d lines, got %d values", name, len(members), len(values))
+	}
+	for i, member := range members {
+		if member.Yielded() {
+			return fmt.Errorf("%s: %w", member.Name, ErrYielded)
+		}
+		if member.overridden() {
+			return fmt.Errorf("%s: %w", member.Name, ErrOverridden)
+		}
+		if member.inhibited(values[i]) {
+			return fmt.Errorf("%s: %w", member.Name, ErrInhibited)
+		}
+	}
+	dones := make([]func(error), len(members))
+	for i, member := range members {
+		dones[i] = member.intent(values[i])
+	}
+	err := setHeldGroup(name, members, values)
+	for _, done := range dones {
+		done(err)
+	}
+	if err != nil {
+		return err
+	}
+	for i, member := range members {
+		member.setLastValue(values[i])
+		if actuatedHook != nil {
+			actuatedHook(member.Name, values[i])
+		}
+	}
+	return nil
+}
+
+// setHeldGroup writes the values through the held request of the group, requesting the group again
+// when its members are held otherwise.
+func setHeldGroup(name string, members []*GPIO, values []int) error {
+	heldMutex.Lock()
+	defer heldMutex.Unlock()
+	if injectFault(func(p *FaultProfile) float64 { return p.WriteError }) {
+		return ErrInjectedWrite
+	}
+	if group := heldGroupOf(name, members); group != nil {
+		if err := group.lines.SetValues(values); err == nil {
+			group.values = append([]int(nil), values...)
+			return nil
+		}
+	}
+	for _, member := range members {
+		if h, ok := held[member.key()]; ok {
+			h.line.Close()
+			delete(held, member.key())
+		}
+	}
+	lines, err := currentBackend().RequestOutputs(members, values)
+	if err != nil {
+		return err
+	}
+	group := &heldGroup{name: name, lines: lines, values: append([]int(nil), values...)}
+	for i, member := range members {
+		held[member.key()] = &heldLine{line: &groupLine{group: group, index: i}, settings: member.settings()}
+	}
+	return nil
+}
+
+// heldGroupOf returns the open request of the group when every member is held through it. Called
+// with heldMutex held.
+func heldGroupOf(name string, members []*GPIO) *heldGroup {
+	var group *heldGroup
+	for i, member := range members {
+		h, ok := held[member.key()]
+		if !ok {
+			return nil
+		}
+		view, ok := h.line.(*groupLine)
+		if !ok || view.index != i || view.group.closed || view.group.name != name || (group != nil && view.group != group) {
+			return nil
+		}
+		group = view.group
+	}
+	return group
+}
@@ -0,0 +1,133 @@
+package gpio
+
+import (
+	"sync"
+)
+
+// heldLine is an output line kept requested between writes, so it never floats or reverts between
+// two actuations and a write is a single ioctl.
+type heldLine struct {
+	line     Line
+	settings GPIO
+}
+
+var (
+	heldMutex = sync.Mutex{}
+	held      = make(map[lineKey]*heldLine)
+)
+
+// setHeld drives the output line to value, requesting it on first use and keeping it requested. A
+// handle that fails the write is assumed stale (chip reset, released behind our back) and the line is
+// requested again.
+func (gpio *GPIO) setHeld(value int) error {
+	heldMutex.Lock()
+	defer heldMutex.Unlock()
+	if injectFault(func(p *FaultProfile) float64 { return p.WriteError }) {
+		return ErrInjectedWrite
+	}
+	key := gpio.key()
+	if h, ok := held[key]; ok {
+		err := h.line.SetValue(value)
+		if err == nil {
+			Recovered(gpio.sampleKey("write"))
+			return nil
+		}
+		SampledLogf(gpio.sampleKey("write"), "Held resource %d from chip %s rejected the write, requesting it again. Error: %s", gpio.Line, gpio.Chip, err)
+		h.line.Close()
+		delete(held, key)
+	}
+	line, err := currentBackend().RequestOutput(gpio, value)
+	if err != nil {
+		return err
+	}
+	debugf("Requested line %d of %s as output %s", gpio.Line, gpio.Chip, gpio.Name)
+	held[key] = &heldLine{line: line, settings: gpio.settings()}
+	return nil
+}
+
+// heldValue reads the level of a held line, reporting whether the line is held.
+func (gpio *GPIO) heldValue() (int, bool, error) {
+	heldMutex.Lock()
+	defer heldMutex.Unlock()
+	h, ok := held[gpio.key()]
+	if !ok {
+		return 0, false, nil
+	}
+	value, err := h.line.Value()
+	return value, true, err
+}
+
+// releaseHeld closes the held handle of the line, if any. The line then keeps or loses its level as
+// the chip decides.
+func (gpio *GPIO) releaseHeld() error {
+	heldMutex.Lock()
+	defer heldMutex.Unlock()
+	key := gpio.key()
+	h, ok := held[key]
+	if !ok {
+		return nil
+	}
+	delete(held, key)
+	return h.line.Close()
+}
+
+// Held reports whether the service keeps the line requested as an output.
+func (gpio *GPIO) Held() bool {
+	heldMutex.Lock()
+	defer heldMutex.Unlock()
+	_, ok := held[gpio.key()]
+	return ok
+}
+
+// ReleaseHeld closes the held output lines, at driver stop. Lines with hold_on_exit stay requested so
+// they keep their level until the process exits.
+func ReleaseHeld() {
+	heldMutex.Lock()
+	defer heldMutex.Unlock()
+	for key, h := range held {
+		if h.settings.HoldOnExit {
+			continue
+		}
+		if err := h.line.Close(); err != nil {
+			logger.Printf("Error releasing resource %d from chip %s. Error: %s", key.line, key.chip, err)
+		}
+		delete(held, key)
+	}
+}
+
+// ReconcileHeld checks with the kernel that every held line is still requested by this process as an
+// output and requests again, at the last value set by the service, the ones that are not. It returns the
+// names of the lines re-acquired.
+func ReconcileHeld() []string {
+	yieldMutex.Lock()
+	values := make(map[lineKey]int, len(lastValue))
+	for key, value := range lastValue {
+		values[key] = value
+	}
+	yieldMutex.Unlock()
+
+	heldMutex.Lock()
+	defer heldMutex.Unlock()
+	var reacquired []string
+	for key, h := range held {
+		owned, err := currentBackend().OwnsOutput(&h.settings)
+		if err != nil {
+			logger.Printf("Cannot read info of line %d from chip %s. Error: %s", key.line, key.chip, err)
+			continue
+		}
+		if owned {
+			continue
+		}
+		h.line.Close()
+		line, err := currentBackend().RequestOutput(&h.settings, values[key])
+		if err != nil {
+			SampledLogf(h.settings.sampleKey("re-acquire"), "Cannot re-acquire held resource %d from chip %s. Error: %s", key.line, key.chip, err)
+			delete(held, key)
+			continue
+		}
+		Recovered(h.settings.sampleKey("re-acquire"))
+		h.line = line
+		reacquired = append(reacquired, h.settings.Name)
+	}
+	return reacquired
+}
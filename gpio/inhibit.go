@@ -0,0 +1,62 @@
+package gpio
+
+import (
+	"errors"
+	"sync"
+)
+
+var ErrInhibited = errors.New("actuation is inhibited")
+
+var (
+	inhibitMutex = sync.RWMutex{}
+	inhibitors   = make(map[string]bool)
+	exempted     = make(map[lineKey]bool)
+)
+
+// Inhibit blocks driving any output away from its safe state until ReleaseInhibit is called with the
+// same reason. Driving a line to its safe state (SafeValue) stays allowed so it can always be reached.
+func Inhibit(reason string) {
+	inhibitMutex.Lock()
+	defer inhibitMutex.Unlock()
+	inhibitors[reason] = true
+}
+
+func ReleaseInhibit(reason string) {
+	inhibitMutex.Lock()
+	defer inhibitMutex.Unlock()
+	delete(inhibitors, reason)
+}
+
+// Inhibited returns the reasons currently blocking actuation.
+func Inhibited() []string {
+	inhibitMutex.RLock()
+	defer inhibitMutex.RUnlock()
+	reasons := make([]string, 0, len(inhibitors))
+	for reason := range inhibitors {
+		reasons = append(reasons, reason)
+	}
+	return reasons
+}
+
+// ExemptFromInhibit keeps the line drivable while actuation is inhibited. Meant for signaling lines
+// (indicators) that must keep showing why the machine is stopped.
+func (gpio *GPIO) ExemptFromInhibit() {
+	inhibitMutex.Lock()
+	defer inhibitMutex.Unlock()
+	exempted[gpio.key()] = true
+}
+
+// inhibited reports whether driving the line to state is blocked.
+func (gpio *GPIO) inhibited(state int) bool {
+	if state == gpio.SafeValue() {
+		return false
+	}
+	return gpio.inhibitActive()
+}
+
+// inhibitActive reports whether an inhibitor applies to the line, whatever the level driven.
+func (gpio *GPIO) inhibitActive() bool {
+	inhibitMutex.RLock()
+	defer inhibitMutex.RUnlock()
+	return len(inhibitors) > 0 && !exempted[gpio.key()]
+}
@@ -0,0 +1,77 @@
+package gpio
+
+import (
+	"time"
+
+	"github.com/warthog618/gpiod"
+)
+
+const (
+	InfoRequested    = "requested"
+	InfoReleased     = "released"
+	InfoReconfigured = "reconfigured"
+)
+
+// InfoEvent is a change of a line's request state made by any process on the system.
+type InfoEvent struct {
+	Name      string        `json:"name"`
+	Chip      string        `json:"chip"`
+	Line      int           `json:"line"`
+	Type      string        `json:"type"`
+	Consumer  string        `json:"consumer"`
+	Output    bool          `json:"output"`
+	Foreign   bool          `json:"foreign"`
+	Timestamp time.Duration `json:"timestamp"`
+}
+
+// WatchLineInfo subscribes to the info change events of the configured lines. Events from requests made
+// with a consumer label other than the ones of this process are flagged as foreign. The simulator has no
+// other processes, so nothing is watched.
+func WatchLineInfo(lines []GPIO, handler func(InfoEvent)) error {
+	if ActiveSimulator() != nil {
+		logger.Printf("Line info is not watched with the simulated backend")
+		return nil
+	}
+	byChip := make(map[string][]GPIO)
+	for _, line := range lines {
+		byChip[line.Chip] = append(byChip[line.Chip], line)
+	}
+	for chipName, chipLines := range byChip {
+		chip, err := gpiod.NewChip(chipName, gpiod.WithConsumer(consumer))
+		if err != nil {
+			logger.Printf("Cannot open chip %s to watch line info. Error: %s", chipName, err)
+			return err
+		}
+		for _, line := range chipLines {
+			own := map[string]bool{consumer: true}
+			if line.Consumer != "" {
+				own[line.Consumer] = true
+			}
+			name, chipName := line.Name, chipName
+			infoHandler := func(evt gpiod.LineInfoChangeEvent) {
+				event := InfoEvent{
+					Name:      name,
+					Chip:      chipName,
+					Line:      evt.Info.Offset,
+					Consumer:  evt.Info.Consumer,
+					Output:    evt.Info.Config.Direction == gpiod.LineDirectionOutput,
+					Timestamp: evt.Timestamp,
+				}
+				switch evt.Type {
+				case gpiod.LineRequested:
+					event.Type = InfoRequested
+				case gpiod.LineReleased:
+					event.Type = InfoReleased
+				case gpiod.LineReconfigured:
+					event.Type = InfoReconfigured
+				}
+				event.Foreign = evt.Info.Used && !own[evt.Info.Consumer]
+				handler(event)
+			}
+			if _, err := chip.WatchLineInfo(line.Line, infoHandler); err != nil {
+				logger.Printf("Cannot watch info of line %d from chip %s. Error: %s", line.Line, chipName, err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package gpio
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ResolveLineNames sets the chip and offset of the lines configured by line_name, looking the name up
+// in the chips listed by chips, or chip, every chip of the system when none is given. It fails when a
+// name matches no line, or several without a chip telling them apart. The resolved lines then inherit
+// the defaults of their chip, as the others did in Load. The simulator knows no line names: with it
+// the lines keep their chip and line.
+func (gpio *GPIOList) ResolveLineNames() error {
+	pending := false
+	for _, line := range gpio.Gpio {
+		pending = pending || line.LineName != ""
+	}
+	if !pending || ActiveSimulator() != nil {
+		return nil
+	}
+	lines := currentBackend().Enumerate()
+	for i := range gpio.Gpio {
+		if err := gpio.Gpio[i].resolveLineName(lines); err != nil {
+			return err
+		}
+	}
+	gpio.applyChipDefaults()
+	for _, line := range gpio.Gpio {
+		if err := line.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (gpio *GPIO) resolveLineName(lines []LineDescriptor) error {
+	if gpio.LineName == "" {
+		return nil
+	}
+	chips := gpio.Chips
+	if len(chips) == 0 && gpio.Chip != "" {
+		chips = []string{gpio.Chip}
+	}
+	var candidates []LineDescriptor
+	for _, line := range lines {
+		if line.Name == gpio.LineName && searchedChip(chips, line) {
+			candidates = append(candidates, line)
+		}
+	}
+	switch len(candidates) {
+	case 0:
+		if len(chips) > 0 {
+			return fmt.Errorf("gpio %s: no line named %s on %s", gpio.Name, gpio.LineName, strings.Join(chips, ", "))
+		}
+		return fmt.Errorf("gpio %s: no line named %s", gpio.Name, gpio.LineName)
+	case 1:
+		gpio.Chip, gpio.Line = candidates[0].Chip, candidates[0].Line
+		debugf("Line name %s of %s resolved to line %d of %s", gpio.LineName, gpio.Name, gpio.Line, gpio.Chip)
+		return nil
+	}
+	names := make([]string, len(candidates))
+	for i, line := range candidates {
+		names[i] = fmt.Sprintf("line %d of %s (%s)", line.Line, line.Chip, line.ChipLabel)
+	}
+	return fmt.Errorf("gpio %s: line name %s is ambiguous, set chip or chips to one of %s", gpio.Name, gpio.LineName, strings.Join(names, ", "))
+}
+
+// searchedChip tells whether the line is on one of the chips, given by name or label; any chip when
+// there are none.
+func searchedChip(chips []string, line LineDescriptor) bool {
+	for _, chip := range chips {
+		if chip == line.Chip || chip == line.ChipLabel {
+			return true
+		}
+	}
+	return len(chips) == 0
+}
@@ -0,0 +1,67 @@
+package gpio
+
+import (
+	"fmt"
+	"sync"
+)
+
+const DEFAULT_SAMPLE_EVERY = 60
+
+var (
+	sampleMutex = sync.Mutex{}
+	sampleEvery = DEFAULT_SAMPLE_EVERY
+	// occurrences counts the errors logged under each key since it last recovered
+	occurrences = make(map[string]int)
+	repeatHook  func(key string)
+)
+
+// SetLogSampling logs the first occurrence of a repeated error then every nth, 1 logs all of them.
+func SetLogSampling(every int) {
+	if every < 1 {
+		every = 1
+	}
+	sampleMutex.Lock()
+	defer sampleMutex.Unlock()
+	sampleEvery = every
+}
+
+// OnRepeatedError sets a function called for every occurrence of a sampled error past the first.
+func OnRepeatedError(hook func(key string)) {
+	repeatHook = hook
+}
+
+// SampledLogf logs an error that may repeat under key, e.g. in a retry loop: the first occurrence,
+// then every nth with the number of occurrences, until Recovered is called for the key.
+func SampledLogf(key string, format string, args ...interface{}) {
+	sampleMutex.Lock()
+	occurrences[key]++
+	count := occurrences[key]
+	every := sampleEvery
+	sampleMutex.Unlock()
+	if count == 1 {
+		logger.Printf(format, args...)
+		return
+	}
+	if repeatHook != nil {
+		repeatHook(key)
+	}
+	if count%every == 0 {
+		logger.Printf("%s (%d occurrences)", fmt.Sprintf(format, args...), count)
+	}
+}
+
+// Recovered ends the repetition of the error logged under key, logging its count when it repeated.
+func Recovered(key string) {
+	sampleMutex.Lock()
+	count, ok := occurrences[key]
+	delete(occurrences, key)
+	sampleMutex.Unlock()
+	if ok && count > 1 {
+		logger.Printf("Recovered from %s after %d occurrences", key, count)
+	}
+}
+
+// sampleKey is the key of the errors of an operation on the line.
+func (gpio *GPIO) sampleKey(op string) string {
+	return fmt.Sprintf("%s of resource %d from chip %s", op, gpio.Line, gpio.Chip)
+}
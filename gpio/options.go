@@ -0,0 +1,309 @@
+package gpio
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/warthog618/gpiod"
+)
+
+const (
+	BiasPullUp   = "pull-up"
+	BiasPullDown = "pull-down"
+	BiasDisabled = "disabled"
+
+	DirectionInput  = "input"
+	DirectionOutput = "output"
+
+	// RoleInput is the role of the lines watched and never driven, given to the lines with direction input
+	RoleInput = "input"
+
+	EdgeRising  = "rising"
+	EdgeFalling = "falling"
+	EdgeBoth    = "both"
+
+	ModeCounter = "counter"
+
+	StuckJog  = "jog"
+	StuckWait = "wait"
+
+	DrivePushPull   = "push-pull"
+	DriveOpenDrain  = "open-drain"
+	DriveOpenSource = "open-source"
+
+	StateLow  = "low"
+	StateHigh = "high"
+
+	ExposureLocal  = "local-only"
+	ExposureRemote = "remote-only"
+)
+
+// ChipDefaults holds the settings inherited by every line of a chip unless the line overrides them.
+type ChipDefaults struct {
+	Name      string `yaml:"name"`
+	Bias      string `yaml:"bias"`
+	Debounce  string `yaml:"debounce"`
+	Consumer  string `yaml:"consumer"`
+	ActiveLow *bool  `yaml:"active_low"`
+}
+
+// applyChipDefaults copies the chip level settings to the lines not overriding them.
+func (gpio *GPIOList) applyChipDefaults() {
+	defaults := make(map[string]ChipDefaults)
+	for _, chip := range gpio.Chips {
+		defaults[chip.Name] = chip
+	}
+	for i := range gpio.Gpio {
+		line := &gpio.Gpio[i]
+		chip, ok := defaults[line.Chip]
+		if !ok {
+			continue
+		}
+		if line.Bias == "" {
+			line.Bias = chip.Bias
+		}
+		if line.Debounce == "" {
+			line.Debounce = chip.Debounce
+		}
+		if line.Consumer == "" {
+			line.Consumer = chip.Consumer
+		}
+		if line.ActiveLow == nil {
+			line.ActiveLow = chip.ActiveLow
+		}
+	}
+}
+
+// Validate checks the line settings that are translated to gpiod request options.
+func (gpio *GPIO) Validate() error {
+	switch gpio.Bias {
+	case "", BiasPullUp, BiasPullDown, BiasDisabled:
+	default:
+		return fmt.Errorf("gpio %s: unknown bias %q", gpio.Name, gpio.Bias)
+	}
+	switch gpio.Drive {
+	case "", DrivePushPull, DriveOpenDrain, DriveOpenSource:
+	default:
+		return fmt.Errorf("gpio %s: unknown drive %q", gpio.Name, gpio.Drive)
+	}
+	for key, state := range map[string]string{"initial_state": gpio.InitialState, "safe_state": gpio.SafeState} {
+		switch state {
+		case "", StateLow, StateHigh:
+		default:
+			return fmt.Errorf("gpio %s: unknown %s %q", gpio.Name, key, state)
+		}
+	}
+	if gpio.Debounce != "" {
+		if d, err := time.ParseDuration(gpio.Debounce); err != nil || d < 0 {
+			return fmt.Errorf("gpio %s: invalid debounce %q", gpio.Name, gpio.Debounce)
+		}
+	}
+	if gpio.SoftDebounce != "" {
+		if d, err := time.ParseDuration(gpio.SoftDebounce); err != nil || d < 0 {
+			return fmt.Errorf("gpio %s: invalid soft_debounce %q", gpio.Name, gpio.SoftDebounce)
+		}
+	}
+	if len(gpio.Chips) > 0 && gpio.LineName == "" {
+		return fmt.Errorf("gpio %s: chips requires line_name", gpio.Name)
+	}
+	switch gpio.Direction {
+	case "", DirectionInput, DirectionOutput:
+	default:
+		return fmt.Errorf("gpio %s: unknown direction %q", gpio.Name, gpio.Direction)
+	}
+	if gpio.Direction == DirectionInput && gpio.Role != RoleInput {
+		return fmt.Errorf("gpio %s: input direction conflicts with role %q", gpio.Name, gpio.Role)
+	}
+	if gpio.Pwm && gpio.Role != RolePwm {
+		return fmt.Errorf("gpio %s: pwm conflicts with role %q", gpio.Name, gpio.Role)
+	}
+	if gpio.PwmFrequency < 0 || (gpio.PwmFrequency > 0 && gpio.Role != RolePwm) {
+		return fmt.Errorf("gpio %s: invalid pwm_frequency %g", gpio.Name, gpio.PwmFrequency)
+	}
+	switch gpio.Exposure {
+	case "", ExposureLocal, ExposureRemote:
+	default:
+		return fmt.Errorf("gpio %s: unknown exposure %q", gpio.Name, gpio.Exposure)
+	}
+	switch gpio.Edge {
+	case "", EdgeRising, EdgeFalling, EdgeBoth:
+	default:
+		return fmt.Errorf("gpio %s: unknown edge %q", gpio.Name, gpio.Edge)
+	}
+	if gpio.MaxOn != "" {
+		if d, err := time.ParseDuration(gpio.MaxOn); err != nil || d <= 0 || gpio.Role == RoleInput {
+			return fmt.Errorf("gpio %s: invalid max_on %q", gpio.Name, gpio.MaxOn)
+		}
+	}
+	switch gpio.Mode {
+	case "":
+	case ModeCounter:
+		if gpio.Role != RoleInput {
+			return fmt.Errorf("gpio %s: counter mode requires direction input", gpio.Name)
+		}
+	default:
+		return fmt.Errorf("gpio %s: unknown mode %q", gpio.Name, gpio.Mode)
+	}
+	if gpio.CountInterval != "" {
+		if d, err := time.ParseDuration(gpio.CountInterval); err != nil || d <= 0 || gpio.Mode != ModeCounter {
+			return fmt.Errorf("gpio %s: invalid count_interval %q", gpio.Name, gpio.CountInterval)
+		}
+	}
+	if (gpio.OpenSwitch == "") != (gpio.ClosedSwitch == "") {
+		return fmt.Errorf("gpio %s: open_switch and closed_switch go together", gpio.Name)
+	}
+	if gpio.OpenSwitch != "" && (gpio.Role == RoleInput || gpio.OpenSwitch == gpio.ClosedSwitch) {
+		return fmt.Errorf("gpio %s: invalid limit switches %q and %q", gpio.Name, gpio.OpenSwitch, gpio.ClosedSwitch)
+	}
+	if gpio.TravelTime != "" {
+		if d, err := time.ParseDuration(gpio.TravelTime); err != nil || d <= 0 || gpio.OpenSwitch == "" {
+			return fmt.Errorf("gpio %s: invalid travel_time %q", gpio.Name, gpio.TravelTime)
+		}
+	}
+	if gpio.StuckRetries < 0 || (gpio.StuckRetries > 0 && gpio.OpenSwitch == "") {
+		return fmt.Errorf("gpio %s: invalid stuck_retries %d", gpio.Name, gpio.StuckRetries)
+	}
+	switch gpio.StuckStrategy {
+	case "", StuckJog, StuckWait:
+	default:
+		return fmt.Errorf("gpio %s: unknown stuck_strategy %q", gpio.Name, gpio.StuckStrategy)
+	}
+	if gpio.JogTime != "" {
+		if d, err := time.ParseDuration(gpio.JogTime); err != nil || d <= 0 || gpio.OpenSwitch == "" {
+			return fmt.Errorf("gpio %s: invalid jog_time %q", gpio.Name, gpio.JogTime)
+		}
+	}
+	return nil
+}
+
+// validateLimitSwitches checks that the limit switches of the valves are input lines of the list.
+func (gpio *GPIOList) validateLimitSwitches() error {
+	inputs := make(map[string]bool)
+	for _, line := range gpio.Gpio {
+		inputs[line.Name] = line.Role == RoleInput && line.Mode == ""
+	}
+	for _, line := range gpio.Gpio {
+		for _, name := range []string{line.OpenSwitch, line.ClosedSwitch} {
+			if name != "" && !inputs[name] {
+				return fmt.Errorf("gpio %s: limit switch %s is not an input line", line.Name, name)
+			}
+		}
+	}
+	return nil
+}
+
+// applyDirections gives the role "input" to the lines declared with direction input and no role, so
+// they are watched and never driven, and the role "pwm" to the lines flagged pwm.
+func (gpio *GPIOList) applyDirections() {
+	for i := range gpio.Gpio {
+		line := &gpio.Gpio[i]
+		if line.Direction == DirectionInput && line.Role == "" {
+			line.Role = RoleInput
+		}
+		if line.Pwm && line.Role == "" {
+			line.Role = RolePwm
+		}
+	}
+}
+
+// InitialValue returns the level the line is driven to at startup, and whether one is configured.
+func (gpio *GPIO) InitialValue() (int, bool) {
+	switch gpio.InitialState {
+	case StateHigh:
+		return 1, true
+	case StateLow:
+		return 0, true
+	}
+	return 0, false
+}
+
+// SafeValue returns the level of the safe state of the line, low unless configured otherwise. Validate
+// rejects any safe_state but low and high; one slipping through is logged and the line driven low.
+func (gpio *GPIO) SafeValue() int {
+	switch gpio.SafeState {
+	case StateHigh:
+		return 1
+	case "", StateLow:
+		return 0
+	}
+	SampledLogf(gpio.sampleKey("safe state"), "Unknown safe_state %q of gpio %s, driving it low", gpio.SafeState, gpio.Name)
+	return 0
+}
+
+// MaxOnTime returns how long the line may stay on before the fail-safe turns it off, 0 for no limit.
+func (gpio *GPIO) MaxOnTime() time.Duration {
+	d, _ := time.ParseDuration(gpio.MaxOn)
+	return d
+}
+
+// TravelPeriod returns how long the valve may take to move between its limit switches, fallback when unset.
+func (gpio *GPIO) TravelPeriod(fallback time.Duration) time.Duration {
+	if d, err := time.ParseDuration(gpio.TravelTime); err == nil && d > 0 {
+		return d
+	}
+	return fallback
+}
+
+// JogPeriod returns how long a stuck valve is driven back before it is commanded again, fallback when unset.
+func (gpio *GPIO) JogPeriod(fallback time.Duration) time.Duration {
+	if d, err := time.ParseDuration(gpio.JogTime); err == nil && d > 0 {
+		return d
+	}
+	return fallback
+}
+
+// CountPeriod returns the period over which the pulses of a counter line are counted, fallback when unset.
+func (gpio *GPIO) CountPeriod(fallback time.Duration) time.Duration {
+	if d, err := time.ParseDuration(gpio.CountInterval); err == nil && d > 0 {
+		return d
+	}
+	return fallback
+}
+
+// edgeOption translates the edge setting to the gpiod edge detection option, both edges by default.
+func (gpio *GPIO) edgeOption() gpiod.LineReqOption {
+	switch gpio.Edge {
+	case EdgeRising:
+		return gpiod.WithRisingEdge
+	case EdgeFalling:
+		return gpiod.WithFallingEdge
+	}
+	return gpiod.WithBothEdges
+}
+
+// requestOptions translates the line settings to gpiod request options. Debounce only applies to inputs,
+// drive only to outputs.
+func (gpio *GPIO) requestOptions(input bool) []gpiod.LineReqOption {
+	label := consumer
+	if gpio.Consumer != "" {
+		label = gpio.Consumer
+	}
+	options := []gpiod.LineReqOption{gpiod.WithConsumer(label)}
+	if gpio.ActiveLow != nil && *gpio.ActiveLow {
+		options = append(options, gpiod.AsActiveLow)
+	}
+	switch gpio.Bias {
+	case BiasPullUp:
+		options = append(options, gpiod.WithPullUp)
+	case BiasPullDown:
+		options = append(options, gpiod.WithPullDown)
+	case BiasDisabled:
+		options = append(options, gpiod.WithBiasDisabled)
+	}
+	if !input {
+		switch gpio.Drive {
+		case DrivePushPull:
+			options = append(options, gpiod.AsPushPull)
+		case DriveOpenDrain:
+			options = append(options, gpiod.AsOpenDrain)
+		case DriveOpenSource:
+			options = append(options, gpiod.AsOpenSource)
+		}
+	}
+	if input && gpio.Debounce != "" {
+		if period, err := time.ParseDuration(gpio.Debounce); err == nil {
+			options = append(options, gpiod.WithDebounce(period))
+		}
+	}
+	return options
+}
@@ -0,0 +1,97 @@
+package gpio
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name  string
+		gpio  GPIO
+		valid bool
+	}{
+		{"output", GPIO{Name: "relay"}, true},
+		{"debounce", GPIO{Name: "button", Debounce: "5ms"}, true},
+		{"negative debounce", GPIO{Name: "button", Debounce: "-5ms"}, false},
+		{"debounce without unit", GPIO{Name: "button", Debounce: "5"}, false},
+		{"input", GPIO{Name: "button", Direction: DirectionInput, Role: RoleInput}, true},
+		{"input with an output role", GPIO{Name: "button", Direction: DirectionInput, Role: "heartbeat"}, false},
+		{"safe_state high", GPIO{Name: "vent", SafeState: StateHigh}, true},
+		{"misspelled safe_state", GPIO{Name: "vent", SafeState: "hgih"}, false},
+		{"upper case initial_state", GPIO{Name: "vent", InitialState: "HIGH"}, false},
+		{"counter on an output", GPIO{Name: "meter", Mode: ModeCounter}, false},
+		{"max_on of an input", GPIO{Name: "button", Role: RoleInput, MaxOn: "1m"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.gpio.Validate(); (err == nil) != tt.valid {
+				t.Errorf("Validate returned %v, want valid %v", err, tt.valid)
+			}
+		})
+	}
+}
+
+// namedLines is a backend knowing only the names of its lines.
+type namedLines struct {
+	Backend
+	lines []LineDescriptor
+}
+
+func (b namedLines) Enumerate() []LineDescriptor {
+	return b.lines
+}
+
+func TestResolveLineNamesAppliesChipDefaults(t *testing.T) {
+	SetBackend(namedLines{lines: []LineDescriptor{
+		{Chip: "gpiochip0", ChipLabel: "pinctrl-bcm2711", Line: 17, Name: "GPIO17"},
+		{Chip: "gpiochip1", ChipLabel: "pcf8574", Line: 3, Name: "RELAY3"},
+	}})
+	defer SetBackend(gpiodBackend{})
+
+	list := &GPIOList{}
+	err := list.Load([]byte(`
+chips:
+  - name: gpiochip0
+    bias: pull-up
+    debounce: 10ms
+gpio:
+  - name: button
+    line_name: GPIO17
+    direction: input
+  - name: relay
+    line_name: RELAY3
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := list.ResolveLineNames(); err != nil {
+		t.Fatal(err)
+	}
+	button, relay := list.Gpio[0], list.Gpio[1]
+	if button.Chip != "gpiochip0" || button.Line != 17 || button.Bias != BiasPullUp || button.Debounce != "10ms" {
+		t.Errorf("button resolved to line %d of %s with bias %q and debounce %q, want the gpiochip0 defaults",
+			button.Line, button.Chip, button.Bias, button.Debounce)
+	}
+	if relay.Chip != "gpiochip1" || relay.Line != 3 || relay.Bias != "" {
+		t.Errorf("relay resolved to line %d of %s with bias %q, want no defaults", relay.Line, relay.Chip, relay.Bias)
+	}
+
+	// The inherited defaults are validated like the configured ones
+	list = &GPIOList{}
+	err = list.Load([]byte(`
+chips:
+  - name: gpiochip0
+    debounce: -10ms
+gpio:
+  - name: button
+    line_name: GPIO17
+    direction: input
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := list.ResolveLineNames(); err == nil || !strings.Contains(err.Error(), "debounce") {
+		t.Errorf("ResolveLineNames returned %v, want an invalid debounce", err)
+	}
+}
@@ -0,0 +1,120 @@
+package gpio
+
+import (
+	"errors"
+	"time"
+)
+
+var ErrOverridden = errors.New("resource is overridden")
+
+// override is a line forced to a value for a bounded time.
+type override struct {
+	value int
+	timer *time.Timer
+}
+
+var (
+	overrides = make(map[lineKey]*override)
+)
+
+// Override forces the line to value for the given duration. While overridden, Up and Down from the
+// owner of the line are not applied but remembered: when the override expires the line reverts to
+// the last value requested by the owner and onRevert is called.
+func (gpio *GPIO) Override(value int, duration time.Duration, onRevert func()) error {
+	if gpio.Yielded() {
+		return ErrYielded
+	}
+	if gpio.inhibited(value) {
+		return ErrInhibited
+	}
+	yieldMutex.Lock()
+	key := gpio.key()
+	if _, ok := overrides[key]; ok {
+		yieldMutex.Unlock()
+		return ErrOverridden
+	}
+	yieldMutex.Unlock()
+
+	if err := gpio.drive(value); err != nil {
+		return err
+	}
+
+	g := *gpio
+	yieldMutex.Lock()
+	defer yieldMutex.Unlock()
+	overrides[key] = &override{
+		value: value,
+		timer: time.AfterFunc(duration, func() {
+			if err := g.Revert(); err != nil {
+				logger.Printf("Cannot revert resource %d from chip %s. Error: %s", g.Line, g.Chip, err)
+			}
+			if onRevert != nil {
+				onRevert()
+			}
+		}),
+	}
+	return nil
+}
+
+// Revert ends an override early and drives the line back to the last value requested by its owner.
+func (gpio *GPIO) Revert() error {
+	yieldMutex.Lock()
+	key := gpio.key()
+	o, ok := overrides[key]
+	if ok {
+		o.timer.Stop()
+		delete(overrides, key)
+	}
+	value := lastValue[key]
+	yieldMutex.Unlock()
+
+	if !ok {
+		return errors.New("resource is not overridden")
+	}
+	if value == 1 {
+		return gpio.Up()
+	}
+	return gpio.Down()
+}
+
+// Overridden reports whether the line is currently forced, and to which value.
+func (gpio *GPIO) Overridden() (int, bool) {
+	yieldMutex.Lock()
+	defer yieldMutex.Unlock()
+	o, ok := overrides[gpio.key()]
+	if !ok {
+		return 0, false
+	}
+	return o.value, true
+}
+
+// deferToOverride remembers the value requested by the owner of an overridden line, reporting
+// whether the request must not be applied now.
+func (gpio *GPIO) deferToOverride(value int) bool {
+	yieldMutex.Lock()
+	defer yieldMutex.Unlock()
+	if _, ok := overrides[gpio.key()]; !ok {
+		return false
+	}
+	lastValue[gpio.key()] = value
+	return true
+}
+
+// overridden reports whether the line is currently overridden.
+func (gpio *GPIO) overridden() bool {
+	yieldMutex.Lock()
+	defer yieldMutex.Unlock()
+	_, ok := overrides[gpio.key()]
+	return ok
+}
+
+// drive sets the line to value, without recording it as the value of the owner.
+func (gpio *GPIO) drive(value int) error {
+	done := gpio.intent(value)
+	err := gpio.setHeld(value)
+	done(err)
+	if err != nil {
+		logger.Printf("Error forcing resource %d from chip %s. Error: %s", gpio.Line, gpio.Chip, err)
+	}
+	return err
+}
@@ -1,34 +1,71 @@
 package gpio
 
 import (
-	"log"
 	"os"
 
-	"gopkg.in/yaml.v2"
+	"gopkg.in/yaml.v3"
 )
 
+// GPIOList is the lines of the configuration file, with the defaults of their chips and the groups
+// of lines driven together.
 type GPIOList struct {
-	Gpio []GPIO `yaml:"gpio"`
+	Chips     []ChipDefaults `yaml:"chips"`
+	Expanders []Expander     `yaml:"expanders"`
+	Gpio      []GPIO         `yaml:"gpio"`
+	Groups    []LineGroup    `yaml:"groups"`
 }
 
+// Parse loads the configuration file fileName, see Load. verbose enables the debug logs.
 func (gpio *GPIOList) Parse(fileName string, verbose bool) error {
 
+	SetVerbose(verbose)
 	if verbose {
-		log.Println(`Parser default options:
+		logger.Println(`Parser default options:
 	Name: "",
 	Chip: "",
-	Line: -1
+	Line: -1,
+	LineName: "", Chips: [] (line_name resolved at startup to the chip and line, searching chips or chip, else all)
+	Description: "",
+	Labels: [],
+	Bias: "", Debounce: "", Consumer: "", ActiveLow: false (inherited from chips section when unset)
 	`)
 	}
 
 	yamlFile, err := os.ReadFile(fileName)
 	if err != nil {
-		log.Printf("yamlFile.Get err   #%v ", err)
+		logger.Printf("Cannot read GPIO configuration file %s. Error: %s", fileName, err)
+		return err
 	}
+	return gpio.Load(yamlFile)
+}
 
-	err = yaml.Unmarshal(yamlFile, &gpio)
+// Load reads a configuration in YAML, applies the defaults of the chips and the directions of the
+// roles to its lines and validates them.
+func (gpio *GPIOList) Load(data []byte) error {
+	err := yaml.Unmarshal(data, &gpio)
 	if err != nil {
-		log.Printf("Cannot unmarshal YAML file. Error: %s", err)
+		logger.Printf("Cannot unmarshal YAML file. Error: %s", err)
+		return err
+	}
+
+	if err := SetExpanders(gpio.Expanders); err != nil {
+		logger.Printf("Invalid GPIO configuration. Error: %s", err)
+		return err
+	}
+	gpio.applyChipDefaults()
+	gpio.applyDirections()
+	for _, line := range gpio.Gpio {
+		if err := line.Validate(); err != nil {
+			logger.Printf("Invalid GPIO configuration. Error: %s", err)
+			return err
+		}
+	}
+	if err := gpio.validateLimitSwitches(); err != nil {
+		logger.Printf("Invalid GPIO configuration. Error: %s", err)
+		return err
+	}
+	if err := gpio.validateGroups(); err != nil {
+		logger.Printf("Invalid GPIO configuration. Error: %s", err)
 		return err
 	}
 
@@ -0,0 +1,157 @@
+package gpio
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// RolePwm is the role of the lines driven by a software PWM generator, also set by pwm: true.
+const RolePwm = "pwm"
+
+// pwm is a software PWM generator holding its line requested as an output.
+type pwm struct {
+	line   Line
+	key    string
+	period time.Duration
+	duty   float64
+	update chan float64
+	done   chan struct{}
+}
+
+var (
+	pwms = make(map[lineKey]*pwm)
+	// dutyCap limits the duty cycle of every generator, 1 for no limit
+	dutyCap = 1.0
+)
+
+// SetDuty drives the line with a software PWM of the given period and duty cycle (0 to 1). The first
+// call requests the line and starts the generator, later calls only change the duty cycle. Software PWM
+// is subject to scheduling jitter and is meant for slow loads (dimmers, fans, heaters), not servos.
+func (gpio *GPIO) SetDuty(duty float64, period time.Duration) error {
+	if duty < 0 || duty > 1 {
+		return fmt.Errorf("duty cycle %.3f out of range [0, 1]", duty)
+	}
+	if period <= 0 {
+		return errors.New("pwm period must be positive")
+	}
+	if gpio.Yielded() {
+		return ErrYielded
+	}
+	if duty != float64(gpio.SafeValue()) && gpio.inhibitActive() {
+		return ErrInhibited
+	}
+
+	yieldMutex.Lock()
+	defer yieldMutex.Unlock()
+	key := gpio.key()
+	if p, ok := pwms[key]; ok {
+		p.duty = duty
+		p.apply()
+		return nil
+	}
+	// The generator takes over the line from a previous plain write
+	gpio.releaseHeld()
+	var line Line
+	err := ErrInjectedWrite
+	if !injectFault(func(p *FaultProfile) float64 { return p.WriteError }) {
+		line, err = currentBackend().RequestOutput(gpio, 0)
+	}
+	if err != nil {
+		logger.Printf("Error setting up pwm on resource %d from chip %s. Error: %s", gpio.Line, gpio.Chip, err)
+		return err
+	}
+	p := &pwm{line: line, key: gpio.sampleKey("pwm"), period: period, duty: duty, update: make(chan float64, 1), done: make(chan struct{})}
+	pwms[key] = p
+	go p.run(math.Min(duty, dutyCap))
+	return nil
+}
+
+// SetDutyCap limits the duty cycle of every generator to limit (0 to 1), 1 lifting the limit. The duty
+// cycles set are kept and reached again once the limit allows them.
+func SetDutyCap(limit float64) error {
+	if limit < 0 || limit > 1 {
+		return fmt.Errorf("duty cycle cap %.3f out of range [0, 1]", limit)
+	}
+	yieldMutex.Lock()
+	defer yieldMutex.Unlock()
+	dutyCap = limit
+	for _, p := range pwms {
+		p.apply()
+	}
+	return nil
+}
+
+// apply hands the duty cycle, within the cap, to the generator. Called with yieldMutex held.
+func (p *pwm) apply() {
+	select {
+	case <-p.update:
+	default:
+	}
+	p.update <- math.Min(p.duty, dutyCap)
+}
+
+// PwmPeriod returns the period of the generator of the line from its pwm_frequency, fallback when unset.
+func (gpio *GPIO) PwmPeriod(fallback time.Duration) time.Duration {
+	if gpio.PwmFrequency > 0 {
+		return time.Duration(float64(time.Second) / gpio.PwmFrequency)
+	}
+	return fallback
+}
+
+// Duty returns the duty cycle set on the line, before any cap, and whether a PWM is running on it.
+func (gpio *GPIO) Duty() (float64, bool) {
+	yieldMutex.Lock()
+	defer yieldMutex.Unlock()
+	p, ok := pwms[gpio.key()]
+	if !ok {
+		return 0, false
+	}
+	return p.duty, true
+}
+
+// StopPwm stops the generator, leaves the line low and releases it.
+func (gpio *GPIO) StopPwm() error {
+	yieldMutex.Lock()
+	key := gpio.key()
+	p, ok := pwms[key]
+	delete(pwms, key)
+	yieldMutex.Unlock()
+	if !ok {
+		return nil
+	}
+	close(p.done)
+	return nil
+}
+
+func (p *pwm) run(duty float64) {
+	defer func() {
+		if err := p.line.SetValue(0); err != nil {
+			logger.Printf("Cannot drive pwm line low. Error: %s", err)
+		}
+		p.line.Close()
+		Recovered(p.key)
+	}()
+	for {
+		select {
+		case <-p.done:
+			return
+		case duty = <-p.update:
+		default:
+		}
+		high := time.Duration(duty * float64(p.period))
+		if high > 0 {
+			if err := p.line.SetValue(1); err != nil {
+				SampledLogf(p.key, "Cannot drive pwm line. Error: %s", err)
+			}
+			time.Sleep(high)
+		}
+		if high < p.period {
+			if err := p.line.SetValue(0); err != nil {
+				SampledLogf(p.key, "Cannot drive pwm line. Error: %s", err)
+			}
+			time.Sleep(p.period - high)
+		}
+	}
+}
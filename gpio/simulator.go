@@ -0,0 +1,282 @@
+package gpio
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/warthog618/gpiod"
+)
+
+// Simulator is an in-memory backend for development and CI on machines without gpiochips. Outputs keep
+// the value last driven, inputs the value last injected; injecting a change on a watched input raises the
+// edge event the kernel would. Levels are logical, active_low is not modelled.
+type Simulator struct {
+	mutex sync.Mutex
+	start time.Time
+	seqno uint32
+	lines map[lineKey]*simLine
+}
+
+type simLine struct {
+	name      string
+	label     string
+	value     int
+	output    bool
+	used      bool
+	edge      string
+	handler   func(gpiod.LineEvent)
+	lineSeqno uint32
+	// generation tells the handles of an earlier request, which must not touch the current one.
+	generation int
+}
+
+// simHandle is a line requested from the simulator.
+type simHandle struct {
+	sim        *Simulator
+	key        lineKey
+	generation int
+}
+
+// NewSimulator returns an empty simulator, lines are created on their first request.
+func NewSimulator() *Simulator {
+	return &Simulator{start: time.Now(), lines: make(map[lineKey]*simLine)}
+}
+
+// request marks the line requested, failing with EBUSY like the kernel when it already is.
+func (s *Simulator) request(gpio *GPIO, output bool, value int, handler func(gpiod.LineEvent)) (Line, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if err := s.free(gpio); err != nil {
+		return nil, err
+	}
+	return s.take(gpio, output, value, handler), nil
+}
+
+// free fails with EBUSY when the line is requested. Called with the mutex held.
+func (s *Simulator) free(gpio *GPIO) error {
+	if l, ok := s.lines[gpio.key()]; ok && l.used {
+		return fmt.Errorf("line %d of %s requested by %s: %w", gpio.Line, gpio.Chip, l.label, syscall.EBUSY)
+	}
+	return nil
+}
+
+// take marks a free line requested. Called with the mutex held.
+func (s *Simulator) take(gpio *GPIO, output bool, value int, handler func(gpiod.LineEvent)) *simHandle {
+	key := gpio.key()
+	l, ok := s.lines[key]
+	if !ok {
+		l = &simLine{}
+		if gpio.Bias == BiasPullUp {
+			l.value = 1
+		}
+		s.lines[key] = l
+	}
+	l.name, l.label, l.used, l.output, l.handler = gpio.Name, gpio.label(), true, output, handler
+	l.edge = gpio.Edge
+	if output {
+		l.value = value
+	}
+	l.generation++
+	return &simHandle{sim: s, key: key, generation: l.generation}
+}
+
+func (s *Simulator) RequestInput(gpio *GPIO) (Line, error) {
+	return s.request(gpio, false, 0, nil)
+}
+
+func (s *Simulator) RequestOutput(gpio *GPIO, value int) (Line, error) {
+	return s.request(gpio, true, value, nil)
+}
+
+// RequestOutputs requests the lines together: none is taken unless all are free.
+func (s *Simulator) RequestOutputs(gpios []*GPIO, values []int) (Lines, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for _, gpio := range gpios {
+		if err := s.free(gpio); err != nil {
+			return nil, err
+		}
+	}
+	group := &simGroup{sim: s}
+	for i, gpio := range gpios {
+		group.handles = append(group.handles, s.take(gpio, true, values[i], nil))
+	}
+	return group, nil
+}
+
+// RequestAsIs requests the line with the direction of its previous request, input for unknown lines.
+func (s *Simulator) RequestAsIs(gpio *GPIO) (Line, error) {
+	s.mutex.Lock()
+	l, ok := s.lines[gpio.key()]
+	output, value := ok && l.output, 0
+	if ok {
+		value = l.value
+	}
+	s.mutex.Unlock()
+	return s.request(gpio, output, value, nil)
+}
+
+func (s *Simulator) WatchEvents(gpio *GPIO, handler func(gpiod.LineEvent)) (Line, error) {
+	return s.request(gpio, false, 0, handler)
+}
+
+func (s *Simulator) OwnsOutput(gpio *GPIO) (bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	l, ok := s.lines[gpio.key()]
+	return ok && l.used && l.output && l.label == gpio.label(), nil
+}
+
+// Enumerate lists the lines requested at least once, by chip and offset.
+func (s *Simulator) Enumerate() []LineDescriptor {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	lines := make([]LineDescriptor, 0, len(s.lines))
+	for key, l := range s.lines {
+		descriptor := LineDescriptor{Chip: key.chip, ChipLabel: "simulator", Line: key.line, Name: l.name, Used: l.used, Output: l.output}
+		if l.used {
+			descriptor.Consumer = l.label
+		}
+		lines = append(lines, descriptor)
+	}
+	sort.Slice(lines, func(i, j int) bool {
+		if lines[i].Chip != lines[j].Chip {
+			return lines[i].Chip < lines[j].Chip
+		}
+		return lines[i].Line < lines[j].Line
+	})
+	return lines
+}
+
+// Inject sets the level of an input line as if driven from outside, calling the event handler of a
+// watched line when the change matches its edges. The handler runs before Inject returns. Lines driven
+// as outputs by the service cannot be injected.
+func (s *Simulator) Inject(chip string, offset int, value int) error {
+	if value != 0 && value != 1 {
+		return fmt.Errorf("invalid level %d, expected 0 or 1", value)
+	}
+	s.mutex.Lock()
+	key := lineKey{chip: chip, line: offset}
+	l, ok := s.lines[key]
+	if !ok {
+		l = &simLine{}
+		s.lines[key] = l
+	}
+	if l.used && l.output {
+		s.mutex.Unlock()
+		return fmt.Errorf("line %d of %s is an output", offset, chip)
+	}
+	changed := l.value != value
+	l.value = value
+	handler := l.handler
+	if !changed || handler == nil || (value == 1 && l.edge == EdgeFalling) || (value == 0 && l.edge == EdgeRising) {
+		s.mutex.Unlock()
+		return nil
+	}
+	s.seqno++
+	l.lineSeqno++
+	evt := gpiod.LineEvent{
+		Offset:    offset,
+		Timestamp: time.Since(s.start),
+		Type:      gpiod.LineEventFallingEdge,
+		Seqno:     s.seqno,
+		LineSeqno: l.lineSeqno,
+	}
+	if value == 1 {
+		evt.Type = gpiod.LineEventRisingEdge
+	}
+	s.mutex.Unlock()
+	handler(evt)
+	return nil
+}
+
+// line returns the state behind the handle, nil once the handle was closed or the line requested again.
+func (h *simHandle) line() *simLine {
+	l := h.sim.lines[h.key]
+	if l == nil || !l.used || l.generation != h.generation {
+		return nil
+	}
+	return l
+}
+
+func (h *simHandle) Value() (int, error) {
+	h.sim.mutex.Lock()
+	defer h.sim.mutex.Unlock()
+	l := h.line()
+	if l == nil {
+		return 0, ErrNotRequested
+	}
+	return l.value, nil
+}
+
+func (h *simHandle) SetValue(value int) error {
+	h.sim.mutex.Lock()
+	defer h.sim.mutex.Unlock()
+	l := h.line()
+	if l == nil {
+		return ErrNotRequested
+	}
+	if !l.output {
+		return fmt.Errorf("line %d of %s is an input: %w", h.key.line, h.key.chip, syscall.EPERM)
+	}
+	l.value = value
+	return nil
+}
+
+func (h *simHandle) Close() error {
+	h.sim.mutex.Lock()
+	defer h.sim.mutex.Unlock()
+	l := h.line()
+	if l == nil {
+		return ErrNotRequested
+	}
+	l.used, l.handler = false, nil
+	return nil
+}
+
+// simGroup is a set of lines requested together from the simulator, read and set under one lock.
+type simGroup struct {
+	sim     *Simulator
+	handles []*simHandle
+}
+
+func (g *simGroup) Values(values []int) error {
+	g.sim.mutex.Lock()
+	defer g.sim.mutex.Unlock()
+	for i, h := range g.handles {
+		l := h.line()
+		if l == nil {
+			return ErrNotRequested
+		}
+		values[i] = l.value
+	}
+	return nil
+}
+
+func (g *simGroup) SetValues(values []int) error {
+	g.sim.mutex.Lock()
+	defer g.sim.mutex.Unlock()
+	for _, h := range g.handles {
+		if h.line() == nil {
+			return ErrNotRequested
+		}
+	}
+	for i, h := range g.handles {
+		h.line().value = values[i]
+	}
+	return nil
+}
+
+func (g *simGroup) Close() error {
+	g.sim.mutex.Lock()
+	defer g.sim.mutex.Unlock()
+	for _, h := range g.handles {
+		if l := h.line(); l != nil {
+			l.used = false
+		}
+	}
+	return nil
+}
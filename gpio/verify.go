@@ -0,0 +1,19 @@
+package gpio
+
+// ReadBack reads the level of a line without changing its direction, to verify that an actuation
+// reached the hardware. Held lines are read through their handle.
+func (gpio *GPIO) ReadBack() (int, error) {
+	if gpio.Yielded() {
+		return -1, ErrYielded
+	}
+	if value, ok, err := gpio.heldValue(); ok {
+		return value, err
+	}
+	line, err := currentBackend().RequestAsIs(gpio)
+	if err != nil {
+		logger.Printf("Error reading back resource %d from chip %s. Error: %s", gpio.Line, gpio.Chip, err)
+		return -1, err
+	}
+	defer line.Close()
+	return line.Value()
+}
@@ -0,0 +1,102 @@
+package gpio
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+var ErrYielded = errors.New("resource is yielded to external tools")
+
+type lineKey struct {
+	chip string
+	line int
+}
+
+var (
+	yieldMutex = sync.Mutex{}
+	yielded    = make(map[lineKey]*time.Timer)
+	lastValue  = make(map[lineKey]int)
+)
+
+func (gpio *GPIO) key() lineKey {
+	return lineKey{chip: gpio.Chip, line: gpio.Line}
+}
+
+// Yield hands the line over to external tools (gpioset/gpioget) for the given duration. While yielded
+// the service does not request the line; once the window expires the line is re-acquired, driven back
+// to the last value set by the service and onResume is called.
+func (gpio *GPIO) Yield(duration time.Duration, onResume func()) error {
+	yieldMutex.Lock()
+	defer yieldMutex.Unlock()
+
+	key := gpio.key()
+	if _, ok := yielded[key]; ok {
+		return ErrYielded
+	}
+	if gpio.gpioLine != nil {
+		// The handle may be stale, nothing to do if it was already released
+		gpio.gpioLine.Close()
+	}
+	gpio.releaseHeld()
+	g := *gpio
+	yielded[key] = time.AfterFunc(duration, func() {
+		if err := g.Resume(); err != nil {
+			logger.Printf("Cannot re-acquire resource %d from chip %s. Error: %s", g.Line, g.Chip, err)
+		}
+		if onResume != nil {
+			onResume()
+		}
+	})
+	return nil
+}
+
+// Resume ends a yield window early and restores the last value set by the service.
+func (gpio *GPIO) Resume() error {
+	yieldMutex.Lock()
+	key := gpio.key()
+	timer, ok := yielded[key]
+	if ok {
+		timer.Stop()
+		delete(yielded, key)
+	}
+	value, known := lastValue[key]
+	yieldMutex.Unlock()
+
+	if !ok {
+		return errors.New("resource is not yielded")
+	}
+	if !known {
+		return nil
+	}
+	if value == 1 {
+		return gpio.Up()
+	}
+	return gpio.Down()
+}
+
+// ExtendYield moves the end of the yield window to duration from now.
+func (gpio *GPIO) ExtendYield(duration time.Duration) error {
+	yieldMutex.Lock()
+	defer yieldMutex.Unlock()
+	timer, ok := yielded[gpio.key()]
+	if !ok || !timer.Stop() {
+		return errors.New("resource is not yielded")
+	}
+	timer.Reset(duration)
+	return nil
+}
+
+// Yielded reports whether the line is currently handed over to external tools.
+func (gpio *GPIO) Yielded() bool {
+	yieldMutex.Lock()
+	defer yieldMutex.Unlock()
+	_, ok := yielded[gpio.key()]
+	return ok
+}
+
+func (gpio *GPIO) setLastValue(value int) {
+	yieldMutex.Lock()
+	defer yieldMutex.Unlock()
+	lastValue[gpio.key()] = value
+}
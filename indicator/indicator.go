@@ -0,0 +1,132 @@
+package indicator
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+)
+
+// Mode is how a channel of the panel is shown.
+type Mode string
+
+const (
+	Off   Mode = "off"
+	Solid Mode = "solid"
+	Flash Mode = "flash"
+)
+
+// DriveFunc drives a channel to a level between 0 (off) and 1 (fully on). It returns false when the
+// level could not be applied yet, in which case it is tried again on the next change or flash.
+type DriveFunc func(level float64) (bool, error)
+
+// Panel is a set of indicator channels (LEDs, stack-light segments, buzzers), each shown solid,
+// flashing or off at a level. Channels are only written when their output changes. A Panel is safe
+// for concurrent use.
+type Panel struct {
+	mutex    sync.Mutex
+	channels map[string]*channel
+	phase    bool
+}
+
+type channel struct {
+	drive   DriveFunc
+	mode    Mode
+	level   float64
+	output  float64
+	applied bool
+}
+
+// NewPanel returns a panel without channels.
+func NewPanel() *Panel {
+	return &Panel{channels: make(map[string]*channel)}
+}
+
+// Add binds the channel name to the function driving it. The channel starts off.
+func (p *Panel) Add(name string, drive DriveFunc) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.channels[name] = &channel{drive: drive, mode: Off}
+}
+
+// Len returns the number of channels; a panel without channels has nothing to show.
+func (p *Panel) Len() int {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return len(p.channels)
+}
+
+// Names returns the channels of the panel, sorted.
+func (p *Panel) Names() []string {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	names := make([]string, 0, len(p.channels))
+	for name := range p.channels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Set shows the channel in mode at level, applying it right away.
+func (p *Panel) Set(name string, mode Mode, level float64) error {
+	switch mode {
+	case Off, Solid, Flash:
+	default:
+		return fmt.Errorf("unknown indicator mode %q", mode)
+	}
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	c, ok := p.channels[name]
+	if !ok {
+		return fmt.Errorf("unknown indicator channel %s", name)
+	}
+	c.mode, c.level = mode, level
+	if err := p.apply(name, c); err != nil {
+		return fmt.Errorf("cannot drive indicator channel %s: %s", name, err)
+	}
+	return nil
+}
+
+// Toggle flips the flash phase and applies it to the flashing channels. It is called every flash
+// period.
+func (p *Panel) Toggle() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.phase = !p.phase
+	for name, c := range p.channels {
+		if err := p.apply(name, c); err != nil {
+			log.Printf("Cannot drive indicator channel %s. Error: %s", name, err)
+		}
+	}
+}
+
+// Outputs returns the level last applied to each channel.
+func (p *Panel) Outputs() map[string]float64 {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	outputs := make(map[string]float64)
+	for name, c := range p.channels {
+		if c.applied {
+			outputs[name] = c.output
+		}
+	}
+	return outputs
+}
+
+// apply drives the channel when its output changed. Must be called holding the mutex.
+func (p *Panel) apply(name string, c *channel) error {
+	output := 0.0
+	if c.mode == Solid || (c.mode == Flash && p.phase) {
+		output = c.level
+	}
+	if c.applied && c.output == output {
+		return nil
+	}
+	done, err := c.drive(output)
+	if err != nil {
+		return err
+	}
+	c.output, c.applied = output, done
+	return nil
+}
@@ -0,0 +1,491 @@
+// Package script implements a small expression language used to customize the driver behavior at
+// defined extension points without code changes.
+//
+// Supported syntax: numbers, true/false, "strings", identifiers, function calls f(a, b),
+// unary ! and -, arithmetic + - * / %, comparisons == != < <= > >=, logical && || and parentheses.
+package script
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+type Func func(args ...interface{}) (interface{}, error)
+
+// Env provides the variables and functions visible to an expression.
+type Env struct {
+	Vars  map[string]interface{}
+	Funcs map[string]Func
+}
+
+type Program struct {
+	source string
+	root   node
+}
+
+// Compile parses src into a reusable program.
+func Compile(src string) (*Program, error) {
+	tokens, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	root, err := p.parseExpr(0)
+	if err != nil {
+		return nil, err
+	}
+	if p.peek().kind != tokEOF {
+		return nil, fmt.Errorf("unexpected %q at position %d", p.peek().text, p.peek().pos)
+	}
+	return &Program{source: src, root: root}, nil
+}
+
+func (p *Program) String() string {
+	return p.source
+}
+
+// Eval evaluates the program. Numbers are returned as float64.
+func (p *Program) Eval(env Env) (interface{}, error) {
+	return p.root.eval(env)
+}
+
+func (p *Program) EvalBool(env Env) (bool, error) {
+	v, err := p.Eval(env)
+	if err != nil {
+		return false, err
+	}
+	return toBool(v)
+}
+
+func (p *Program) EvalFloat(env Env) (float64, error) {
+	v, err := p.Eval(env)
+	if err != nil {
+		return 0, err
+	}
+	return toFloat(v)
+}
+
+// Lexer
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokNumber
+	tokString
+	tokIdent
+	tokOp
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+func lex(src string) ([]token, error) {
+	var tokens []token
+	runes := []rune(src)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case unicode.IsDigit(r) || (r == '.' && i+1 < len(runes) && unicode.IsDigit(runes[i+1])):
+			start := i
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, token{tokNumber, string(runes[start:i]), start})
+		case unicode.IsLetter(r) || r == '_':
+			start := i
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_' || runes[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, token{tokIdent, string(runes[start:i]), start})
+		case r == '"' || r == '\'':
+			start := i
+			i++
+			for i < len(runes) && runes[i] != r {
+				i++
+			}
+			if i >= len(runes) {
+				return nil, fmt.Errorf("unterminated string at position %d", start)
+			}
+			tokens = append(tokens, token{tokString, string(runes[start+1 : i]), start})
+			i++
+		default:
+			if i+1 < len(runes) {
+				two := string(runes[i : i+2])
+				switch two {
+				case "&&", "||", "==", "!=", "<=", ">=":
+					tokens = append(tokens, token{tokOp, two, i})
+					i += 2
+					continue
+				}
+			}
+			if !strings.ContainsRune("+-*/%<>!(),", r) {
+				return nil, fmt.Errorf("unexpected character %q at position %d", r, i)
+			}
+			tokens = append(tokens, token{tokOp, string(r), i})
+			i++
+		}
+	}
+	return append(tokens, token{tokEOF, "", len(runes)}), nil
+}
+
+// Parser
+
+var precedence = map[string]int{
+	"||": 1,
+	"&&": 2,
+	"==": 3, "!=": 3,
+	"<": 4, "<=": 4, ">": 4, ">=": 4,
+	"+": 5, "-": 5,
+	"*": 6, "/": 6, "%": 6,
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) expect(text string) error {
+	t := p.next()
+	if t.kind != tokOp || t.text != text {
+		return fmt.Errorf("expected %q at position %d", text, t.pos)
+	}
+	return nil
+}
+
+func (p *parser) parseExpr(minPrec int) (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t := p.peek()
+		prec, ok := precedence[t.text]
+		if t.kind != tokOp || !ok || prec <= minPrec {
+			return left, nil
+		}
+		p.next()
+		right, err := p.parseExpr(prec)
+		if err != nil {
+			return nil, err
+		}
+		left = binaryNode{op: t.text, left: left, right: right}
+	}
+}
+
+func (p *parser) parseUnary() (node, error) {
+	t := p.peek()
+	if t.kind == tokOp && (t.text == "!" || t.text == "-") {
+		p.next()
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return unaryNode{op: t.text, operand: operand}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	t := p.next()
+	switch t.kind {
+	case tokNumber:
+		v, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q at position %d", t.text, t.pos)
+		}
+		return literalNode{v}, nil
+	case tokString:
+		return literalNode{t.text}, nil
+	case tokIdent:
+		switch t.text {
+		case "true":
+			return literalNode{true}, nil
+		case "false":
+			return literalNode{false}, nil
+		}
+		if p.peek().kind == tokOp && p.peek().text == "(" {
+			p.next()
+			call := callNode{name: t.text}
+			if p.peek().kind == tokOp && p.peek().text == ")" {
+				p.next()
+				return call, nil
+			}
+			for {
+				arg, err := p.parseExpr(0)
+				if err != nil {
+					return nil, err
+				}
+				call.args = append(call.args, arg)
+				if p.peek().kind == tokOp && p.peek().text == "," {
+					p.next()
+					continue
+				}
+				if err := p.expect(")"); err != nil {
+					return nil, err
+				}
+				return call, nil
+			}
+		}
+		return identNode{t.text}, nil
+	case tokOp:
+		if t.text == "(" {
+			inner, err := p.parseExpr(0)
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(")"); err != nil {
+				return nil, err
+			}
+			return inner, nil
+		}
+	case tokEOF:
+		return nil, errors.New("unexpected end of expression")
+	}
+	return nil, fmt.Errorf("unexpected %q at position %d", t.text, t.pos)
+}
+
+// Evaluation
+
+type node interface {
+	eval(env Env) (interface{}, error)
+}
+
+type literalNode struct {
+	value interface{}
+}
+
+func (n literalNode) eval(env Env) (interface{}, error) {
+	return n.value, nil
+}
+
+type identNode struct {
+	name string
+}
+
+func (n identNode) eval(env Env) (interface{}, error) {
+	v, ok := env.Vars[n.name]
+	if !ok {
+		return nil, fmt.Errorf("unknown variable %q", n.name)
+	}
+	return normalize(v), nil
+}
+
+type callNode struct {
+	name string
+	args []node
+}
+
+func (n callNode) eval(env Env) (interface{}, error) {
+	f, ok := env.Funcs[n.name]
+	if !ok {
+		f, ok = builtins[n.name]
+	}
+	if !ok {
+		return nil, fmt.Errorf("unknown function %q", n.name)
+	}
+	args := make([]interface{}, len(n.args))
+	for i, arg := range n.args {
+		v, err := arg.eval(env)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = v
+	}
+	v, err := f(args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", n.name, err)
+	}
+	return normalize(v), nil
+}
+
+type unaryNode struct {
+	op      string
+	operand node
+}
+
+func (n unaryNode) eval(env Env) (interface{}, error) {
+	v, err := n.operand.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	if n.op == "!" {
+		b, err := toBool(v)
+		return !b, err
+	}
+	f, err := toFloat(v)
+	return -f, err
+}
+
+type binaryNode struct {
+	op          string
+	left, right node
+}
+
+func (n binaryNode) eval(env Env) (interface{}, error) {
+	l, err := n.left.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	// Short-circuit logical operators
+	if n.op == "&&" || n.op == "||" {
+		lb, err := toBool(l)
+		if err != nil {
+			return nil, err
+		}
+		if (n.op == "&&" && !lb) || (n.op == "||" && lb) {
+			return lb, nil
+		}
+		r, err := n.right.eval(env)
+		if err != nil {
+			return nil, err
+		}
+		return toBool(r)
+	}
+	r, err := n.right.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	switch n.op {
+	case "==":
+		return equal(l, r), nil
+	case "!=":
+		return !equal(l, r), nil
+	}
+	lf, err := toFloat(l)
+	if err != nil {
+		return nil, err
+	}
+	rf, err := toFloat(r)
+	if err != nil {
+		return nil, err
+	}
+	switch n.op {
+	case "+":
+		return lf + rf, nil
+	case "-":
+		return lf - rf, nil
+	case "*":
+		return lf * rf, nil
+	case "/":
+		if rf == 0 {
+			return nil, errors.New("division by zero")
+		}
+		return lf / rf, nil
+	case "%":
+		if rf == 0 {
+			return nil, errors.New("division by zero")
+		}
+		return math.Mod(lf, rf), nil
+	case "<":
+		return lf < rf, nil
+	case "<=":
+		return lf <= rf, nil
+	case ">":
+		return lf > rf, nil
+	case ">=":
+		return lf >= rf, nil
+	}
+	return nil, fmt.Errorf("unknown operator %q", n.op)
+}
+
+var builtins = map[string]Func{
+	"min": func(args ...interface{}) (interface{}, error) {
+		return fold(args, math.Min)
+	},
+	"max": func(args ...interface{}) (interface{}, error) {
+		return fold(args, math.Max)
+	},
+	"abs": func(args ...interface{}) (interface{}, error) {
+		if len(args) != 1 {
+			return nil, errors.New("expects 1 argument")
+		}
+		f, err := toFloat(args[0])
+		return math.Abs(f), err
+	},
+}
+
+func fold(args []interface{}, f func(a, b float64) float64) (interface{}, error) {
+	if len(args) == 0 {
+		return nil, errors.New("expects at least 1 argument")
+	}
+	acc, err := toFloat(args[0])
+	if err != nil {
+		return nil, err
+	}
+	for _, arg := range args[1:] {
+		v, err := toFloat(arg)
+		if err != nil {
+			return nil, err
+		}
+		acc = f(acc, v)
+	}
+	return acc, nil
+}
+
+func normalize(v interface{}) interface{} {
+	switch t := v.(type) {
+	case int:
+		return float64(t)
+	case int64:
+		return float64(t)
+	case uint64:
+		return float64(t)
+	case float32:
+		return float64(t)
+	}
+	return v
+}
+
+func equal(a, b interface{}) bool {
+	af, aerr := toFloat(a)
+	bf, berr := toFloat(b)
+	if aerr == nil && berr == nil {
+		return af == bf
+	}
+	return a == b
+}
+
+func toBool(v interface{}) (bool, error) {
+	switch t := v.(type) {
+	case bool:
+		return t, nil
+	case float64:
+		return t != 0, nil
+	}
+	return false, fmt.Errorf("cannot use %v as bool", v)
+}
+
+func toFloat(v interface{}) (float64, error) {
+	switch t := normalize(v).(type) {
+	case float64:
+		return t, nil
+	case bool:
+		if t {
+			return 1, nil
+		}
+		return 0, nil
+	}
+	return 0, fmt.Errorf("cannot use %v as number", v)
+}
@@ -0,0 +1,133 @@
+package script
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+var testEnv = Env{
+	Vars: map[string]interface{}{
+		"runs":    12,
+		"enabled": true,
+		"mode":    "eco",
+	},
+	Funcs: map[string]Func{
+		"fail": func(args ...interface{}) (interface{}, error) {
+			return nil, errors.New("failed")
+		},
+	},
+}
+
+func TestEval(t *testing.T) {
+	tests := []struct {
+		src      string
+		expected interface{}
+	}{
+		{"1 + 2 * 3", 7.0},
+		{"(1 + 2) * 3", 9.0},
+		{"10 - 4 - 3", 3.0},
+		{"24 / 4 / 2", 3.0},
+		{"7 % 4 + 1", 4.0},
+		{"-2 * 3", -6.0},
+		{"-(2 + 3)", -5.0},
+		{"1 + 2 < 4", true},
+		{"1 < 2 == true", true},
+		{"false || true && false", false},
+		{"!false && true", true},
+		{"runs > 10 && enabled", true},
+		{`mode == "eco"`, true},
+		{`mode != "eco" || runs % 2 == 0`, true},
+		{"min(3, runs, 5) + max(1, 2)", 5.0},
+		{"abs(-2.5)", 2.5},
+		// The right operand is not evaluated once the result is known
+		{"false && fail()", false},
+		{"true || fail()", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			program, err := Compile(tt.src)
+			if err != nil {
+				t.Fatalf("cannot compile: %s", err)
+			}
+			value, err := program.Eval(testEnv)
+			if err != nil {
+				t.Fatalf("cannot evaluate: %s", err)
+			}
+			if value != tt.expected {
+				t.Errorf("evaluates to %v (%T), want %v (%T)", value, value, tt.expected, tt.expected)
+			}
+		})
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	tests := []struct {
+		src string
+		err string
+	}{
+		{"", "unexpected end of expression"},
+		{"1 +", "unexpected end of expression"},
+		{"(1 + 2", `expected ")"`},
+		{"1 2", `unexpected "2" at position 2`},
+		{`"open`, "unterminated string at position 0"},
+		{"1 # 2", `unexpected character '#' at position 2`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			_, err := Compile(tt.src)
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("Compile returned %v, want an error containing %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestEvalErrors(t *testing.T) {
+	tests := []struct {
+		src string
+		err string
+	}{
+		{"1 / 0", "division by zero"},
+		{"runs / (runs - 12)", "division by zero"},
+		{"5 % 0", "division by zero"},
+		{"missing + 1", `unknown variable "missing"`},
+		{"nothing(1)", `unknown function "nothing"`},
+		{"fail()", "fail: failed"},
+		{"abs(1, 2)", "abs: expects 1 argument"},
+		{"min()", "min: expects at least 1 argument"},
+		{`mode + 1`, "cannot use eco as number"},
+		{"mode && true", "cannot use eco as bool"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			program, err := Compile(tt.src)
+			if err != nil {
+				t.Fatalf("cannot compile: %s", err)
+			}
+			_, err = program.Eval(testEnv)
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("Eval returned %v, want an error containing %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestEvalFloat(t *testing.T) {
+	program, err := Compile("runs * 30")
+	if err != nil {
+		t.Fatal(err)
+	}
+	seconds, err := program.EvalFloat(testEnv)
+	if err != nil || seconds != 360 {
+		t.Errorf("EvalFloat returned %v (%v), want 360", seconds, err)
+	}
+
+	program, err = Compile(`mode`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := program.EvalFloat(testEnv); err == nil {
+		t.Error("EvalFloat of a string succeeded")
+	}
+}
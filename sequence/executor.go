@@ -0,0 +1,173 @@
+package sequence
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/edgexfoundry/device-gpiod/script"
+)
+
+// Actuator is what a sequence acts on: the driver drives real lines, a planner only records the steps.
+type Actuator interface {
+	// Set drives a line on or off.
+	Set(line string, on bool) error
+	// Sleep waits for d, adjusted by the actuation latency of compensateFor when set.
+	Sleep(d time.Duration, compensateFor string)
+	// BeginPhase is called before the steps of a phase expected to last about expected. An error
+	// stops the sequence without running the on_error steps, as nothing was actuated.
+	BeginPhase(name string, expected time.Duration) error
+	// EndPhase is called with the outcome of the steps of a phase. An error stops the sequence.
+	EndPhase(name string, err error) error
+	// Env provides the variables and functions of the conditions and of ${name} references.
+	Env() script.Env
+}
+
+// Aborted is returned when a phase could not begin.
+type Aborted struct {
+	Phase string
+	Err   error
+}
+
+func (e *Aborted) Error() string {
+	return fmt.Sprintf("phase %s not started: %s", e.Phase, e.Err)
+}
+
+func (e *Aborted) Unwrap() error {
+	return e.Err
+}
+
+// Run executes the steps of the sequence. When a step fails the on_error steps run, e.g. to bring the
+// lines back to a safe state, and the error of the failed step is returned.
+func Run(seq *Sequence, a Actuator) error {
+	err := runSteps(seq.Steps, a)
+	var aborted *Aborted
+	if err == nil || errors.As(err, &aborted) || len(seq.OnError) == 0 {
+		return err
+	}
+	log.Printf("Sequence %s failed, running on_error steps. Error: %s", seq.Name, err)
+	if onErr := runSteps(seq.OnError, a); onErr != nil {
+		log.Printf("Sequence %s on_error steps failed. Error: %s", seq.Name, onErr)
+	}
+	return err
+}
+
+func runSteps(steps []Step, a Actuator) error {
+	for i := range steps {
+		if err := runStep(&steps[i], a); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func runStep(step *Step, a Actuator) error {
+	env := a.Env()
+	if step.condition != nil {
+		ok, err := step.condition.EvalBool(env)
+		if err != nil {
+			return fmt.Errorf("cannot evaluate condition %q: %s", step.If, err)
+		}
+		if !ok {
+			return nil
+		}
+	}
+
+	switch {
+	case step.Set != "":
+		return a.Set(expand(step.Set, env), true)
+	case step.Clear != "":
+		return a.Set(expand(step.Clear, env), false)
+	case step.Pulse != nil:
+		d, err := duration(step.Pulse.Duration, env)
+		if err != nil {
+			return err
+		}
+		line := expand(step.Pulse.Line, env)
+		if err := a.Set(line, true); err != nil {
+			return err
+		}
+		a.Sleep(d, "")
+		return a.Set(line, false)
+	case step.Sleep != "":
+		d, err := duration(step.Sleep, env)
+		if err != nil {
+			return err
+		}
+		a.Sleep(d, expand(step.CompensateFor, env))
+		return nil
+	case step.Log != "":
+		log.Println(expand(step.Log, env))
+		return nil
+	}
+
+	repeat := step.Repeat
+	if repeat == 0 {
+		repeat = 1
+	}
+	if step.Phase == "" {
+		for i := 0; i < repeat; i++ {
+			if err := runSteps(step.Steps, a); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	expected, err := Expected(step.Steps, env)
+	if err != nil {
+		return err
+	}
+	if err := a.BeginPhase(step.Phase, time.Duration(repeat)*expected); err != nil {
+		return &Aborted{Phase: step.Phase, Err: err}
+	}
+	for i := 0; i < repeat && err == nil; i++ {
+		err = runSteps(step.Steps, a)
+	}
+	return a.EndPhase(step.Phase, err)
+}
+
+// Expected is the duration of the sleeps and pulses of steps, conditions aside.
+func Expected(steps []Step, env script.Env) (time.Duration, error) {
+	total := time.Duration(0)
+	for _, step := range steps {
+		var d time.Duration
+		var err error
+		switch {
+		case step.Sleep != "":
+			d, err = duration(step.Sleep, env)
+		case step.Pulse != nil:
+			d, err = duration(step.Pulse.Duration, env)
+		case len(step.Steps) > 0:
+			d, err = Expected(step.Steps, env)
+			if step.Repeat > 1 {
+				d *= time.Duration(step.Repeat)
+			}
+		}
+		if err != nil {
+			return 0, err
+		}
+		total += d
+	}
+	return total, nil
+}
+
+// expand replaces the ${name} references with the variables of env.
+func expand(s string, env script.Env) string {
+	return os.Expand(s, func(name string) string {
+		if v, ok := env.Vars[name]; ok {
+			return fmt.Sprintf("%v", v)
+		}
+		return ""
+	})
+}
+
+func duration(s string, env script.Env) (time.Duration, error) {
+	d, err := time.ParseDuration(expand(s, env))
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	return d, nil
+}
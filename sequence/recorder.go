@@ -0,0 +1,77 @@
+package sequence
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/edgexfoundry/device-gpiod/script"
+)
+
+// Actuation is a line change at an offset from the start of a run.
+type Actuation struct {
+	At    time.Duration `json:"at"`
+	Phase string        `json:"phase,omitempty"`
+	Line  string        `json:"line"`
+	On    bool          `json:"on"`
+}
+
+// Recorder is an Actuator that performs nothing: it records the actuations of a run against a virtual
+// clock starting at Clock, so the same sequence and environment always give the same actuations.
+type Recorder struct {
+	Clock       time.Duration
+	Environment script.Env
+	Actuations  []Actuation
+	phase       string
+}
+
+func (r *Recorder) Set(line string, on bool) error {
+	r.Actuations = append(r.Actuations, Actuation{At: r.Clock, Phase: r.phase, Line: line, On: on})
+	return nil
+}
+
+func (r *Recorder) Sleep(d time.Duration, compensateFor string) {
+	r.Clock += d
+}
+
+func (r *Recorder) BeginPhase(name string, expected time.Duration) error {
+	r.phase = name
+	return nil
+}
+
+func (r *Recorder) EndPhase(name string, err error) error {
+	return err
+}
+
+func (r *Recorder) Env() script.Env {
+	return r.Environment
+}
+
+// Compare matches the actuations of a run against the expected ones, in order, allowing their times
+// to differ by tolerance. It returns the differences, none when the run is identical.
+func Compare(expected []Actuation, actual []Actuation, tolerance time.Duration) []string {
+	var differences []string
+	for i := 0; i < len(expected) || i < len(actual); i++ {
+		switch {
+		case i >= len(actual):
+			differences = append(differences, fmt.Sprintf("#%d: missing %s", i, expected[i]))
+		case i >= len(expected):
+			differences = append(differences, fmt.Sprintf("#%d: unexpected %s", i, actual[i]))
+		case expected[i].Line != actual[i].Line || expected[i].On != actual[i].On:
+			differences = append(differences, fmt.Sprintf("#%d: expected %s, got %s", i, expected[i], actual[i]))
+		default:
+			drift := actual[i].At - expected[i].At
+			if drift < -tolerance || drift > tolerance {
+				differences = append(differences, fmt.Sprintf("#%d: %s drifted by %s", i, actual[i], drift))
+			}
+		}
+	}
+	return differences
+}
+
+func (a Actuation) String() string {
+	state := "off"
+	if a.On {
+		state = "on"
+	}
+	return fmt.Sprintf("%s %s at %s", a.Line, state, a.At)
+}
@@ -0,0 +1,175 @@
+package sequence
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/edgexfoundry/device-gpiod/script"
+)
+
+const pumped = 10 * time.Minute
+
+// cycleEnv is the environment of the default sequence as the service builds it, timers in seconds.
+func cycleEnv(reverse bool, clean bool, cleanStages int) script.Env {
+	return script.Env{Vars: map[string]interface{}{
+		"enableReverse":  reverse,
+		"enableClean":    clean,
+		"reverse":        "reverse",
+		"clean":          "clean",
+		"openValve":      "open",
+		"switchingValve": "switching",
+		"reverseTimer":   300.0,
+		"cleanTimer":     300.0,
+		"gravityTimer":   300.0,
+		"switchingTimer": 15.0,
+		"openingTimer":   5.0,
+		"cleanStages":    cleanStages,
+	}}
+}
+
+// replay runs seq on a recorder whose clock starts at the end of the pump phase, as the replay of a
+// recorded cycle does.
+func replay(t *testing.T, seq *Sequence, env script.Env) *Recorder {
+	t.Helper()
+	recorder := &Recorder{Clock: pumped, Environment: env}
+	if err := Run(seq, recorder); err != nil {
+		t.Fatalf("replay failed: %s", err)
+	}
+	return recorder
+}
+
+func at(d time.Duration) time.Duration {
+	return pumped + d
+}
+
+func TestReplayDefaultSequence(t *testing.T) {
+	reverseRun := []Actuation{
+		{At: at(0), Phase: "reverse", Line: "reverse", On: true},
+		{At: at(5 * time.Minute), Phase: "reverse", Line: "reverse", On: false},
+	}
+	cleanRun := []Actuation{
+		{At: at(5 * time.Minute), Phase: "clean", Line: "switching", On: true},
+		{At: at(5*time.Minute + 15*time.Second), Phase: "clean", Line: "open", On: true},
+		{At: at(5*time.Minute + 20*time.Second), Phase: "clean", Line: "clean", On: true},
+		{At: at(10*time.Minute + 20*time.Second), Phase: "clean", Line: "clean", On: false},
+		{At: at(10*time.Minute + 20*time.Second), Phase: "clean", Line: "open", On: false},
+		{At: at(15*time.Minute + 25*time.Second), Phase: "clean", Line: "switching", On: false},
+	}
+
+	tests := []struct {
+		name     string
+		reverse  bool
+		clean    bool
+		expected []Actuation
+		end      time.Duration
+	}{
+		{"pump only", false, false, nil, at(0)},
+		{"clean without reverse", false, true, nil, at(0)},
+		{"reverse", true, false, reverseRun, at(5 * time.Minute)},
+		{"reverse and clean", true, true, append(append([]Actuation{}, reverseRun...), cleanRun...), at(15*time.Minute + 40*time.Second)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			seq, err := Default()
+			if err != nil {
+				t.Fatal(err)
+			}
+			recorder := replay(t, seq, cycleEnv(tt.reverse, tt.clean, 0))
+			if differences := Compare(tt.expected, recorder.Actuations, 0); len(differences) > 0 {
+				t.Errorf("replay differs from the recorded cycle:\n%s", strings.Join(differences, "\n"))
+			}
+			for i, a := range recorder.Actuations {
+				if i < len(tt.expected) && a.Phase != tt.expected[i].Phase {
+					t.Errorf("#%d: %s recorded in phase %q, want %q", i, a, a.Phase, tt.expected[i].Phase)
+				}
+			}
+			if recorder.Clock != tt.end {
+				t.Errorf("replay ended at %s, want %s", recorder.Clock, tt.end)
+			}
+		})
+	}
+}
+
+func TestReplayIsDeterministic(t *testing.T) {
+	seq, err := Default()
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := replay(t, seq, cycleEnv(true, true, 0))
+	second := replay(t, seq, cycleEnv(true, true, 0))
+	if differences := Compare(first.Actuations, second.Actuations, 0); len(differences) > 0 {
+		t.Errorf("two replays of the same cycle differ:\n%s", strings.Join(differences, "\n"))
+	}
+}
+
+func TestReplayCleanRecipe(t *testing.T) {
+	seq, err := Default()
+	if err != nil {
+		t.Fatal(err)
+	}
+	recipe := []Step{
+		{Set: "rinse"},
+		{Sleep: "1m"},
+		{Clear: "rinse"},
+	}
+	if err := seq.Define("clean-recipe", recipe); err != nil {
+		t.Fatal(err)
+	}
+	recorder := replay(t, seq, cycleEnv(true, true, 1))
+
+	expected := []Actuation{
+		{At: at(0), Line: "reverse", On: true},
+		{At: at(5 * time.Minute), Line: "reverse", On: false},
+		{At: at(5 * time.Minute), Line: "switching", On: true},
+		{At: at(5*time.Minute + 15*time.Second), Line: "open", On: true},
+		{At: at(5*time.Minute + 20*time.Second), Line: "clean", On: true},
+		{At: at(5*time.Minute + 20*time.Second), Line: "rinse", On: true},
+		{At: at(6*time.Minute + 20*time.Second), Line: "rinse", On: false},
+		// The single clean timer is skipped when the recipe has stages
+		{At: at(6*time.Minute + 20*time.Second), Line: "clean", On: false},
+		{At: at(6*time.Minute + 20*time.Second), Line: "open", On: false},
+		{At: at(11*time.Minute + 25*time.Second), Line: "switching", On: false},
+	}
+	if differences := Compare(expected, recorder.Actuations, 0); len(differences) > 0 {
+		t.Errorf("replay differs from the recorded cycle:\n%s", strings.Join(differences, "\n"))
+	}
+}
+
+func TestCompare(t *testing.T) {
+	recorded := []Actuation{
+		{At: 0, Line: "pump", On: true},
+		{At: 5 * time.Minute, Line: "pump", On: false},
+	}
+	tests := []struct {
+		name        string
+		actual      []Actuation
+		tolerance   time.Duration
+		differences []string
+	}{
+		{"identical", recorded, 0, nil},
+		{"within tolerance", []Actuation{
+			{At: time.Second, Line: "pump", On: true},
+			{At: 5*time.Minute - time.Second, Line: "pump", On: false},
+		}, 2 * time.Second, nil},
+		{"drifted", []Actuation{
+			{At: 0, Line: "pump", On: true},
+			{At: 5*time.Minute + 3*time.Second, Line: "pump", On: false},
+		}, 2 * time.Second, []string{"#1: pump off at 5m3s drifted by 3s"}},
+		{"missing", recorded[:1], 0, []string{"#1: missing pump off at 5m0s"}},
+		{"unexpected", append(append([]Actuation{}, recorded...), Actuation{At: 6 * time.Minute, Line: "reverse", On: true}), 0,
+			[]string{"#2: unexpected reverse on at 6m0s"}},
+		{"wrong line", []Actuation{
+			{At: 0, Line: "standby", On: true},
+			{At: 5 * time.Minute, Line: "pump", On: false},
+		}, 0, []string{"#0: expected pump on at 0s, got standby on at 0s"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			differences := Compare(recorded, tt.actual, tt.tolerance)
+			if strings.Join(differences, "\n") != strings.Join(tt.differences, "\n") {
+				t.Errorf("Compare returned %q, want %q", differences, tt.differences)
+			}
+		})
+	}
+}
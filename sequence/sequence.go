@@ -0,0 +1,147 @@
+// Package sequence runs declarative actuation pipelines. A sequence is a list of steps loaded from
+// YAML: set, clear or pulse a line, sleep, log, and groups of steps run as a named phase, repeated or
+// guarded by a condition in the script expression language. Line names and durations may reference
+// the variables of the environment as ${name}, e.g. "${cleanTimer}s".
+package sequence
+
+import (
+	_ "embed"
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/edgexfoundry/device-gpiod/script"
+)
+
+// defaultSequence is the reverse and clean flow of the pump cycle.
+//
+//go:embed default.yaml
+var defaultSequence []byte
+
+type Sequence struct {
+	Name    string `yaml:"name"`
+	Steps   []Step `yaml:"steps"`
+	OnError []Step `yaml:"on_error"`
+}
+
+// Step performs exactly one of the actions set, clear, pulse, sleep, log, steps or include. A step
+// with steps runs them in order, as the phase named by phase when set, repeat times (once by
+// default). An include step runs the steps defined under its name by the service, none when
+// undefined. A step with a condition (if) is skipped when it evaluates to false.
+type Step struct {
+	Name          string `yaml:"name"`
+	If            string `yaml:"if"`
+	Set           string `yaml:"set"`
+	Clear         string `yaml:"clear"`
+	Pulse         *Pulse `yaml:"pulse"`
+	Sleep         string `yaml:"sleep"`
+	CompensateFor string `yaml:"compensate_for"`
+	Log           string `yaml:"log"`
+	Phase         string `yaml:"phase"`
+	Repeat        int    `yaml:"repeat"`
+	Steps         []Step `yaml:"steps"`
+	Include       string `yaml:"include"`
+	condition     *script.Program
+}
+
+// Pulse drives a line on for a duration, then off.
+type Pulse struct {
+	Line     string `yaml:"line"`
+	Duration string `yaml:"duration"`
+}
+
+// Default returns the sequence shipped with the service.
+func Default() (*Sequence, error) {
+	return Parse(defaultSequence)
+}
+
+// Load reads and validates the sequence of a YAML file.
+func Load(fileName string) (*Sequence, error) {
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
+
+// Parse decodes and validates a YAML sequence.
+func Parse(data []byte) (*Sequence, error) {
+	seq := &Sequence{}
+	if err := yaml.Unmarshal(data, seq); err != nil {
+		return nil, err
+	}
+	if seq.Name == "" {
+		return nil, fmt.Errorf("sequence without name")
+	}
+	if err := validateSteps(seq.Steps, "steps"); err != nil {
+		return nil, err
+	}
+	if err := validateSteps(seq.OnError, "on_error"); err != nil {
+		return nil, err
+	}
+	return seq, nil
+}
+
+func validateSteps(steps []Step, path string) error {
+	for i := range steps {
+		step := &steps[i]
+		where := fmt.Sprintf("%s[%d]", path, i)
+		if step.Name != "" {
+			where = fmt.Sprintf("%s (%s)", where, step.Name)
+		}
+		actions := 0
+		for _, set := range []bool{step.Set != "", step.Clear != "", step.Pulse != nil, step.Sleep != "", step.Log != "", len(step.Steps) > 0, step.Include != ""} {
+			if set {
+				actions++
+			}
+		}
+		if actions != 1 {
+			return fmt.Errorf("%s: expected exactly one of set, clear, pulse, sleep, log, steps or include", where)
+		}
+		if step.Pulse != nil && (step.Pulse.Line == "" || step.Pulse.Duration == "") {
+			return fmt.Errorf("%s: pulse needs a line and a duration", where)
+		}
+		if step.CompensateFor != "" && step.Sleep == "" {
+			return fmt.Errorf("%s: compensate_for applies to sleep only", where)
+		}
+		if (step.Phase != "" || step.Repeat != 0) && (len(step.Steps) == 0 || step.Include != "") {
+			return fmt.Errorf("%s: phase and repeat apply to steps only", where)
+		}
+		if step.Repeat < 0 {
+			return fmt.Errorf("%s: repeat cannot be negative", where)
+		}
+		if strings.TrimSpace(step.If) != "" {
+			program, err := script.Compile(step.If)
+			if err != nil {
+				return fmt.Errorf("%s: invalid condition %q: %s", where, step.If, err)
+			}
+			step.condition = program
+		}
+		if err := validateSteps(step.Steps, where+".steps"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Define sets the steps run by the include steps named name, replacing the ones defined before.
+func (seq *Sequence) Define(name string, included []Step) error {
+	if err := validateSteps(included, name); err != nil {
+		return err
+	}
+	var walk func(steps []Step)
+	walk = func(steps []Step) {
+		for i := range steps {
+			if steps[i].Include == name {
+				steps[i].Steps = included
+				continue
+			}
+			walk(steps[i].Steps)
+		}
+	}
+	walk(seq.Steps)
+	walk(seq.OnError)
+	return nil
+}
@@ -8,3 +8,6 @@ package device
 
 // Global version for device-sdk-go
 var Version string = "to be replaced by makefile"
+
+// Build describes the target and linking of the binary, set by the makefile static targets
+var Build string = "dynamic"